@@ -2,21 +2,24 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/MohamedElashri/snipo/internal/api"
 	"github.com/MohamedElashri/snipo/internal/api/middleware"
+	"github.com/MohamedElashri/snipo/internal/app"
 	"github.com/MohamedElashri/snipo/internal/auth"
 	"github.com/MohamedElashri/snipo/internal/config"
-	"github.com/MohamedElashri/snipo/internal/database"
-	"github.com/MohamedElashri/snipo/internal/demo"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/replica"
 	"github.com/MohamedElashri/snipo/internal/repository"
 	"github.com/MohamedElashri/snipo/internal/services"
 	"github.com/MohamedElashri/snipo/internal/version"
@@ -24,10 +27,17 @@ import (
 
 // Build-time variables
 var (
-	Version = "dev"
-	Commit  = "unknown"
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
 )
 
+// configPath is the configuration file given with --config
+var configPath string
+
+// logLevel is the level of the logger from setupLogger, changed by reloads
+var logLevel = new(slog.LevelVar)
+
 func main() {
 	// If version is dev, use the constant from version package
 	if Version == "dev" {
@@ -36,6 +46,8 @@ func main() {
 	// Ensure version doesn't have "v" prefix (standardize storage as 1.2.3)
 	Version = strings.TrimPrefix(Version, "v")
 
+	os.Args = parseConfigFlag(os.Args)
+
 	// Check for subcommands
 	if len(os.Args) > 1 {
 		switch os.Args[1] {
@@ -44,15 +56,24 @@ func main() {
 		case "migrate":
 			runMigrations()
 		case "version":
-			fmt.Printf("snipo %s (commit: %s)\n", Version, Commit)
+			fmt.Printf("snipo %s (commit: %s, built: %s)\n", Version, Commit, BuildDate)
 			os.Exit(0)
 		case "health":
 			checkHealth()
 		case "hash-password":
 			hashPassword()
+		case "config":
+			showConfig()
+		case "reindex-fts", "reindex":
+			reindexSearch()
+		case "restore":
+			restoreReplica()
+		case "db":
+			maintainDatabase()
 		default:
 			fmt.Printf("Unknown command: %s\n", os.Args[1])
-			fmt.Println("Available commands: serve, migrate, version, health, hash-password")
+			fmt.Println("Available commands: serve, migrate, version, health, hash-password, config show, reindex-fts, restore, db maintain")
+			fmt.Println("Options: --config <file>")
 			os.Exit(1)
 		}
 	} else {
@@ -62,16 +83,17 @@ func main() {
 
 func runServer() {
 	// Setup logger
-	logger := setupLogger()
-
-	logger.Info("starting snipo", "version", Version, "commit", Commit)
+	logger := setupLogger(os.Getenv("SNIPO_LOG_LEVEL"), os.Getenv("SNIPO_LOG_FORMAT"))
 
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := loadConfig()
 	if err != nil {
 		logger.Error("failed to load configuration", "error", err)
 		os.Exit(1)
 	}
+	logger = setupLogger(cfg.Logging.Level, cfg.Logging.Format)
+
+	logger.Info("starting snipo", "version", Version, "commit", Commit)
 
 	// Configure proxy trust setting
 	middleware.TrustProxy = cfg.Server.TrustProxy
@@ -97,112 +119,28 @@ func runServer() {
 			"note", "Auto-generated salt is saved to .encryption_salt in the data directory and will persist across restarts if the data volume is mounted")
 	}
 
-	// Connect to database
-	db, err := database.New(database.Config{
-		Path:            cfg.Database.Path,
-		MaxOpenConns:    cfg.Database.MaxOpenConns,
-		BusyTimeout:     cfg.Database.BusyTimeout,
-		JournalMode:     cfg.Database.JournalMode,
-		SynchronousMode: cfg.Database.SynchronousMode,
-		MMapSize:        cfg.Database.MMapSize,
-		CacheSize:       cfg.Database.CacheSize,
-	}, logger)
+	// Open database and wire services
+	ctx := context.Background()
+	application, err := app.Build(ctx, cfg, logger)
 	if err != nil {
-		logger.Error("failed to connect to database", "error", err)
+		logger.Error("failed to initialize application", "error", err)
 		os.Exit(1)
 	}
 	defer func() {
-		if err := db.Close(); err != nil {
+		if err := application.Close(); err != nil {
 			logger.Error("failed to close database", "error", err)
 		}
 	}()
 
-	// Run migrations
-	ctx := context.Background()
-	if err := db.Migrate(ctx); err != nil {
-		logger.Error("failed to run migrations", "error", err)
-		os.Exit(1)
-	}
-
-	// Create auth service
-	// Use pre-hashed password if available, otherwise use plain password
-	masterPasswordForAuth := cfg.Auth.MasterPasswordHash
-	if masterPasswordForAuth == "" {
-		masterPasswordForAuth = cfg.Auth.MasterPassword
-	}
-
-	authService := auth.NewService(
-		db.DB,
-		masterPasswordForAuth,
-		cfg.Auth.SessionSecret,
-		cfg.Auth.SessionDuration,
-		logger,
-		cfg.Auth.Disabled,
-	)
-
-	// Start session cleanup goroutine
-	go func() {
-		ticker := time.NewTicker(1 * time.Hour)
-		for range ticker.C {
-			if err := authService.CleanupExpiredSessions(); err != nil {
-				logger.Warn("failed to cleanup sessions", "error", err)
-			}
-		}
-	}()
-
-	// Initialize gist sync worker
-	var gistSyncWorker *services.GistSyncWorker
-	gistSyncRepo := repository.NewGistSyncRepository(db.DB)
-	snippetRepo := repository.NewSnippetRepository(db.DB)
-	fileRepo := repository.NewSnippetFileRepository(db.DB)
-
-	legacyEncryptionKey := services.DeriveEncryptionKey(cfg.Auth.EncryptionSalt)
-	encryptionKey := services.DeriveEncryptionKeyWithSecret(cfg.Auth.EncryptionSalt, cfg.Auth.SessionSecret)
-	if cfg.Auth.SessionSecretGenerated {
-		encryptionKey = legacyEncryptionKey
-	}
-	if encryptionSvc, err := services.NewEncryptionServiceWithFallback(encryptionKey, legacyEncryptionKey); err == nil {
-		gistSyncWorker = services.NewGistSyncWorker(gistSyncRepo, snippetRepo, fileRepo, encryptionSvc, logger)
-		if err := gistSyncWorker.Start(ctx); err != nil {
-			logger.Warn("failed to start gist sync worker", "error", err)
-		}
-	}
-
-	// Initialize demo mode if enabled
-	if cfg.Demo.Enabled {
-		// Create repositories and services for demo mode
-		snippetRepo := repository.NewSnippetRepository(db.DB)
-		tagRepo := repository.NewTagRepository(db.DB)
-		folderRepo := repository.NewFolderRepository(db.DB)
-		fileRepo := repository.NewSnippetFileRepository(db.DB)
-		historyRepo := repository.NewHistoryRepository(db.DB)
-		settingsRepo := repository.NewSettingsRepository(db.DB)
-
-		snippetService := services.NewSnippetService(snippetRepo, logger).
-			WithTagRepo(tagRepo).
-			WithFolderRepo(folderRepo).
-			WithFileRepo(fileRepo).
-			WithHistoryRepo(historyRepo).
-			WithSettingsRepo(settingsRepo).
-			WithMaxFiles(cfg.Server.MaxFilesPerSnippet)
-
-		demoService := demo.NewService(db.DB, snippetService, logger, cfg.Demo.ResetInterval, cfg.Demo.Enabled)
-		demoService.StartPeriodicReset(ctx)
-	}
+	// Start background workers
+	application.Start(ctx)
 
 	// Create router
 	router := api.NewRouter(api.RouterConfig{
-		DB:                 db.DB,
-		Logger:             logger,
-		AuthService:        authService,
-		Config:             cfg, // Pass full config
-		Version:            Version,
-		Commit:             Commit,
-		RateLimit:          cfg.Auth.RateLimit,
-		RateLimitWindow:    int(cfg.Auth.RateLimitWindow.Seconds()),
-		MaxFilesPerSnippet: cfg.Server.MaxFilesPerSnippet,
-		S3Config:           &cfg.S3,
-		BasePath:           cfg.Server.BasePath,
+		App:       application,
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
 	})
 
 	// Create server
@@ -223,6 +161,15 @@ func runServer() {
 		}
 	}()
 
+	// Reload settings on SIGHUP, keeping connections open
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			reloadConfig(application, logger)
+		}
+	}()
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -230,12 +177,8 @@ func runServer() {
 
 	logger.Info("shutting down server...")
 
-	// Stop gist sync worker if running
-	if gistSyncWorker != nil {
-		if err := gistSyncWorker.Stop(); err != nil {
-			logger.Warn("failed to stop gist sync worker", "error", err)
-		}
-	}
+	// Stop background workers
+	application.Stop()
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -248,41 +191,96 @@ func runServer() {
 	logger.Info("server stopped")
 }
 
+// reloadConfig loads the configuration again and applies the settings that
+// can change while serving: the log level, rate limits, CORS origins and S3
+// storage. An invalid configuration is logged and the current one kept.
+func reloadConfig(application *app.App, logger *slog.Logger) {
+	cfg, err := loadConfig()
+	if err != nil {
+		logger.Error("failed to reload configuration, keeping the current one", "error", err)
+		return
+	}
+
+	application.Reload(cfg)
+	// Logged before the level changes, so raising it doesn't hide the message
+	level := parseLogLevel(cfg.Logging.Level)
+	logger.Info("configuration reloaded", "log_level", level)
+	logLevel.Set(level)
+}
+
 func runMigrations() {
-	logger := setupLogger()
+	logger := setupLogger(os.Getenv("SNIPO_LOG_LEVEL"), os.Getenv("SNIPO_LOG_FORMAT"))
 
-	cfg, err := config.Load()
+	cfg, err := loadConfig()
 	if err != nil {
 		logger.Error("failed to load configuration", "error", err)
 		os.Exit(1)
 	}
+	logger = setupLogger(cfg.Logging.Level, cfg.Logging.Format)
 
-	db, err := database.New(database.Config{
-		Path:            cfg.Database.Path,
-		MaxOpenConns:    cfg.Database.MaxOpenConns,
-		BusyTimeout:     cfg.Database.BusyTimeout,
-		JournalMode:     cfg.Database.JournalMode,
-		SynchronousMode: cfg.Database.SynchronousMode,
-		MMapSize:        cfg.Database.MMapSize,
-		CacheSize:       cfg.Database.CacheSize,
-	}, logger)
+	db, err := app.OpenDatabase(context.Background(), cfg, logger)
 	if err != nil {
-		logger.Error("failed to connect to database", "error", err)
+		logger.Error("failed to run migrations", "error", err)
 		os.Exit(1)
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			logger.Error("failed to close database", "error", err)
-		}
-	}()
+	if err := db.Close(); err != nil {
+		logger.Error("failed to close database", "error", err)
+	}
+
+	logger.Info("migrations completed successfully")
+}
+
+// reindexSearch rebuilds the search index with the search_tokenizer setting,
+// or with --check only compares it with the snippets table, exiting with 1
+// when they differ
+func reindexSearch() {
+	checkOnly := len(os.Args) == 3 && os.Args[2] == "--check"
+	if len(os.Args) > 2 && !checkOnly {
+		fmt.Println("Usage: snipo reindex-fts [--check] [--config <file>]")
+		os.Exit(1)
+	}
+
+	logger := setupLogger(os.Getenv("SNIPO_LOG_LEVEL"), os.Getenv("SNIPO_LOG_FORMAT"))
+
+	cfg, err := loadConfig()
+	if err != nil {
+		logger.Error("failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+	logger = setupLogger(cfg.Logging.Level, cfg.Logging.Format)
 
 	ctx := context.Background()
-	if err := db.Migrate(ctx); err != nil {
-		logger.Error("failed to run migrations", "error", err)
+	db, err := app.OpenDatabase(ctx, cfg, logger)
+	if err != nil {
+		logger.Error("failed to open database", "error", err)
 		os.Exit(1)
 	}
+	searchIndex := services.NewSearchIndexService(repository.NewSnippetRepository(db.DB), repository.NewSettingsRepository(db.DB), logger)
 
-	logger.Info("migrations completed successfully")
+	var status *models.SearchIndexStatus
+	action := "rebuild"
+	if checkOnly {
+		action = "check"
+		status, err = searchIndex.Status(ctx)
+	} else {
+		status, err = searchIndex.Rebuild(ctx)
+	}
+	if closeErr := db.Close(); closeErr != nil {
+		logger.Error("failed to close database", "error", closeErr)
+	}
+	if err != nil {
+		logger.Error("failed to "+action+" search index", "error", err)
+		os.Exit(1)
+	}
+
+	if !status.Consistent {
+		logger.Error("search index is out of date, run snipo reindex-fts",
+			"tokenizer", status.Tokenizer, "snippets", status.Snippets, "indexed", status.Indexed)
+		os.Exit(1)
+	}
+	if checkOnly {
+		logger.Info("search index is consistent", "tokenizer", status.Tokenizer, "snippets", status.Snippets)
+	}
 }
 
 func checkHealth() {
@@ -334,23 +332,223 @@ func hashPassword() {
 	fmt.Println("\nNote: Remove SNIPO_MASTER_PASSWORD if you're using SNIPO_MASTER_PASSWORD_HASH")
 }
 
-func setupLogger() *slog.Logger {
-	logLevel := os.Getenv("SNIPO_LOG_LEVEL")
-	logFormat := os.Getenv("SNIPO_LOG_FORMAT")
+// maintainDatabase runs the database maintenance tasks, those listed by
+// --tasks or all of them, and prints what each did and the database size
+// before and after. It exits with 1 if a task failed, including an
+// integrity check that found problems.
+func maintainDatabase() {
+	usage := func() {
+		fmt.Printf("Usage: snipo db maintain [--tasks <%s>] [--config <file>]\n", strings.Join(models.MaintenanceTasks, ","))
+		os.Exit(1)
+	}
+	if len(os.Args) < 3 || os.Args[2] != "maintain" {
+		usage()
+	}
+	var tasks []string
+	for i := 3; i < len(os.Args); i++ {
+		name, value, hasValue := strings.Cut(os.Args[i], "=")
+		if name != "--tasks" {
+			usage()
+		}
+		if !hasValue {
+			if i+1 == len(os.Args) {
+				usage()
+			}
+			i++
+			value = os.Args[i]
+		}
+		for _, task := range strings.Split(value, ",") {
+			if task = strings.TrimSpace(task); task == "" {
+				continue
+			}
+			if !slices.Contains(models.MaintenanceTasks, task) {
+				fmt.Printf("Error: unknown maintenance task %q\n", task)
+				usage()
+			}
+			tasks = append(tasks, task)
+		}
+	}
 
-	var level slog.Level
-	switch logLevel {
-	case "debug":
-		level = slog.LevelDebug
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo
+	logger := setupLogger(os.Getenv("SNIPO_LOG_LEVEL"), os.Getenv("SNIPO_LOG_FORMAT"))
+
+	cfg, err := loadConfig()
+	if err != nil {
+		logger.Error("failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+	logger = setupLogger(cfg.Logging.Level, cfg.Logging.Format)
+
+	ctx := context.Background()
+	db, err := app.OpenDatabase(ctx, cfg, logger)
+	if err != nil {
+		logger.Error("failed to open database", "error", err)
+		os.Exit(1)
+	}
+	// A running server with replication on ships the WAL and checkpoints it
+	maintenance := services.NewMaintenanceService(db.DB, cfg.Database.Path, logger).
+		WithReplication(cfg.Replica.Enabled)
+	report, err := maintenance.Run(ctx, tasks)
+	if closeErr := db.Close(); closeErr != nil {
+		logger.Error("failed to close database", "error", closeErr)
+	}
+	if err != nil {
+		logger.Error("failed to maintain database", "error", err)
+		os.Exit(1)
+	}
+
+	for _, task := range report.Tasks {
+		line := fmt.Sprintf("%-16s %-8s %s", task.Task, task.Status, task.Duration)
+		if task.Detail != "" {
+			line += "  " + task.Detail
+		}
+		fmt.Println(line)
+		for _, problem := range task.Problems {
+			fmt.Println("    " + problem)
+		}
+	}
+	fmt.Printf("Database: %d bytes (%d free) before, %d bytes (%d free) after\n",
+		report.Before.Bytes, report.Before.FreeBytes, report.After.Bytes, report.After.FreeBytes)
+	fmt.Printf("WAL: %d bytes before, %d bytes after\n", report.Before.WALBytes, report.After.WALBytes)
+	if report.Failed() {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// restoreReplica rebuilds the database from its replica in the backup
+// storage, as it was at --timestamp or as recently as possible. The
+// database must not exist, so snipo has to be stopped and the old file
+// moved away first, unless --output names another file.
+func restoreReplica() {
+	usage := func() {
+		fmt.Println("Usage: snipo restore [--timestamp <RFC 3339 time>] [--output <file>] [--config <file>]")
+		os.Exit(1)
+	}
+	var timestamp, output string
+	for i := 2; i < len(os.Args); i++ {
+		name, value, hasValue := strings.Cut(os.Args[i], "=")
+		if name != "--timestamp" && name != "--output" {
+			usage()
+		}
+		if !hasValue {
+			if i+1 == len(os.Args) {
+				usage()
+			}
+			i++
+			value = os.Args[i]
+		}
+		if name == "--timestamp" {
+			timestamp = value
+		} else {
+			output = value
+		}
+	}
+
+	at := time.Now()
+	if timestamp != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, timestamp); err != nil {
+			fmt.Printf("Error: --timestamp must be an RFC 3339 time like 2026-01-02T15:04:05Z: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading configuration: %v\n", err)
+		os.Exit(1)
+	}
+	if output == "" {
+		output = cfg.Database.Path
+	}
+
+	backupStorage, _, err := app.NewBackupStorage(cfg)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if backupStorage == nil {
+		fmt.Println("Error: replicas are read from S3 or SFTP storage, and neither is enabled")
+		os.Exit(1)
+	}
+	// Encrypted replicas need the key they were written with
+	var encrypter replica.Encrypter
+	if encryption, err := app.NewEncryption(cfg); err == nil {
+		encrypter = encryption
 	}
 
-	opts := &slog.HandlerOptions{Level: level}
+	result, err := replica.Restore(context.Background(), backupStorage, encrypter, output, at)
+	if err != nil {
+		fmt.Printf("Error restoring database: %v\n", err)
+		if strings.Contains(err.Error(), "already exists") {
+			fmt.Println("Stop snipo and move the database and its -wal file away first, or restore to another file with --output")
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("Restored %s as of %s\n", output, result.RestoredTo.Format(time.RFC3339))
+	fmt.Printf("Generation %s: snapshot from %s and %d WAL segments\n", result.Generation, result.Snapshot.Format(time.RFC3339), result.Segments)
+	os.Exit(0)
+}
+
+// showConfig prints the configuration in effect as JSON, with secrets
+// redacted, and the warnings the server would log at startup
+func showConfig() {
+	if len(os.Args) != 3 || os.Args[2] != "show" {
+		fmt.Println("Usage: snipo config show [--config <file>]")
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	out, err := json.MarshalIndent(map[string]any{
+		"config":   cfg.Effective(),
+		"warnings": cfg.Warnings(),
+	}, "", "  ")
+	if err != nil {
+		fmt.Printf("Error encoding configuration: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(out))
+	os.Exit(0)
+}
+
+// parseConfigFlag takes --config <file> or --config=<file> out of args,
+// storing the file in configPath
+func parseConfigFlag(args []string) []string {
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--config" || arg == "-config":
+			if i+1 == len(args) {
+				fmt.Println("Error: --config requires a file")
+				os.Exit(1)
+			}
+			i++
+			configPath = args[i]
+		case strings.HasPrefix(arg, "--config="):
+			configPath = strings.TrimPrefix(arg, "--config=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest
+}
+
+// loadConfig loads the configuration, from the --config file if one was given
+func loadConfig() (*config.Config, error) {
+	if configPath != "" {
+		return config.LoadFile(configPath)
+	}
+	return config.Load()
+}
+
+func setupLogger(level, logFormat string) *slog.Logger {
+	logLevel.Set(parseLogLevel(level))
+	opts := &slog.HandlerOptions{Level: logLevel}
 
 	var handler slog.Handler
 	if logFormat == "text" {
@@ -361,3 +559,16 @@ func setupLogger() *slog.Logger {
 
 	return slog.New(handler)
 }
+
+func parseLogLevel(level string) slog.Level {
+	switch level {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,313 @@
+//go:build e2e
+
+// Package e2e boots the full HTTP server against a temporary SQLite file and
+// exercises it over real HTTP. Run with: go test -tags e2e ./e2e/...
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/api"
+	"github.com/MohamedElashri/snipo/internal/app"
+	"github.com/MohamedElashri/snipo/internal/config"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+const masterPassword = "e2e-master-password"
+
+// server is a running snipo instance plus a client that keeps its session cookie
+type server struct {
+	url    string
+	client *http.Client
+}
+
+// startServer wires the application the same way cmd/server does and serves it over TLS,
+// since the session cookie is marked Secure
+func startServer(t *testing.T, githubURL string) *server {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("SNIPO_DB_PATH", filepath.Join(dir, "snipo.db"))
+	t.Setenv("SNIPO_MASTER_PASSWORD", masterPassword)
+	t.Setenv("SNIPO_SESSION_SECRET", "e2e-session-secret-0123456789abcdef")
+	t.Setenv("SNIPO_ENCRYPTION_SALT", "e2e-encryption-salt")
+	t.Setenv("SNIPO_GITHUB_API_URL", githubURL)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	application, err := app.Build(context.Background(), cfg, testutil.TestLogger())
+	if err != nil {
+		t.Fatalf("failed to build application: %v", err)
+	}
+	t.Cleanup(func() { _ = application.Close() })
+
+	router := api.NewRouter(api.RouterConfig{
+		App:     application,
+		Version: "e2e",
+		Commit:  "e2e",
+	})
+
+	ts := httptest.NewTLSServer(router)
+	t.Cleanup(ts.Close)
+
+	client := ts.Client()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("failed to create cookie jar: %v", err)
+	}
+	client.Jar = jar
+
+	return &server{url: ts.URL, client: client}
+}
+
+// anonymous returns a client for the same server without the session cookie
+func (s *server) anonymous() *server {
+	client := *s.client
+	client.Jar = nil
+	return &server{url: s.url, client: &client}
+}
+
+// do sends a JSON request and decodes the envelope's data into out when non-nil
+func (s *server) do(t *testing.T, method, path string, body interface{}, wantStatus int, out interface{}) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("failed to encode body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, s.url+path, reader)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	s.send(t, req, wantStatus, out)
+}
+
+func (s *server) send(t *testing.T, req *http.Request, wantStatus int, out interface{}) {
+	t.Helper()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", req.Method, req.URL.Path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s: expected status %d, got %d: %s", req.Method, req.URL.Path, wantStatus, resp.StatusCode, data)
+	}
+	if out == nil {
+		return
+	}
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		t.Fatalf("failed to decode %s %s: %v: %s", req.Method, req.URL.Path, err, data)
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		t.Fatalf("failed to decode data of %s %s: %v: %s", req.Method, req.URL.Path, err, envelope.Data)
+	}
+}
+
+func TestEndToEnd(t *testing.T) {
+	github := testutil.NewFakeGitHub(t)
+	s := startServer(t, github.URL())
+
+	t.Run("health", func(t *testing.T) {
+		s.do(t, http.MethodGet, "/health", nil, http.StatusOK, nil)
+	})
+
+	t.Run("login", func(t *testing.T) {
+		s.do(t, http.MethodGet, "/api/v1/snippets", nil, http.StatusUnauthorized, nil)
+		s.do(t, http.MethodPost, "/api/v1/auth/login", map[string]string{"password": "wrong"}, http.StatusUnauthorized, nil)
+
+		// A failed attempt locks the client out briefly, even with the right password
+		s.do(t, http.MethodPost, "/api/v1/auth/login", map[string]string{"password": masterPassword}, http.StatusTooManyRequests, nil)
+		time.Sleep(time.Second)
+
+		s.do(t, http.MethodPost, "/api/v1/auth/login", map[string]string{"password": masterPassword}, http.StatusOK, nil)
+		s.do(t, http.MethodGet, "/api/v1/snippets", nil, http.StatusOK, nil)
+	})
+
+	t.Run("settings page", func(t *testing.T) {
+		var settings models.Settings
+		s.do(t, http.MethodGet, "/api/v1/settings", nil, http.StatusOK, &settings)
+
+		// Send back what the settings page would: the current values with one change
+		var input models.SettingsInput
+		data, _ := json.Marshal(settings)
+		if err := json.Unmarshal(data, &input); err != nil {
+			t.Fatalf("failed to convert settings: %v", err)
+		}
+		input.AppName = "E2E Snipo"
+
+		var updated models.Settings
+		s.do(t, http.MethodPut, "/api/v1/settings", input, http.StatusOK, &updated)
+		if updated.AppName != "E2E Snipo" {
+			t.Errorf("expected app name to be saved, got %q", updated.AppName)
+		}
+
+		resp, err := s.client.Get(s.url + "/")
+		if err != nil {
+			t.Fatalf("GET / failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected app page to render, got %d", resp.StatusCode)
+		}
+	})
+
+	var snippet models.Snippet
+	t.Run("crud", func(t *testing.T) {
+		s.do(t, http.MethodPost, "/api/v1/snippets", models.SnippetInput{
+			Title:    "E2E hello",
+			Content:  "fmt.Println(\"hello e2e\")",
+			Language: "go",
+			Tags:     []string{"e2e"},
+			Files: []models.SnippetFileInput{
+				{Filename: "main.go", Content: "package main", Language: "go"},
+			},
+		}, http.StatusCreated, &snippet)
+
+		var fetched models.Snippet
+		s.do(t, http.MethodGet, "/api/v1/snippets/"+snippet.ID, nil, http.StatusOK, &fetched)
+		if len(fetched.Tags) != 1 || len(fetched.Files) != 1 {
+			t.Errorf("expected tag and file to round-trip, got %+v", fetched)
+		}
+
+		s.do(t, http.MethodPut, "/api/v1/snippets/"+snippet.ID, models.SnippetInput{
+			Title:    "E2E hello updated",
+			Content:  "fmt.Println(\"hello e2e\")",
+			Language: "go",
+		}, http.StatusOK, &fetched)
+		if fetched.Title != "E2E hello updated" {
+			t.Errorf("expected updated title, got %q", fetched.Title)
+		}
+
+		var list []models.Snippet
+		s.do(t, http.MethodGet, "/api/v1/snippets", nil, http.StatusOK, &list)
+		if len(list) != 1 {
+			t.Errorf("expected 1 snippet, got %d", len(list))
+		}
+
+		var scratch models.Snippet
+		s.do(t, http.MethodPost, "/api/v1/snippets", models.SnippetInput{Title: "Scratch", Content: "x"}, http.StatusCreated, &scratch)
+		s.do(t, http.MethodDelete, "/api/v1/snippets/"+scratch.ID+"?permanent=true", nil, http.StatusNoContent, nil)
+		s.do(t, http.MethodGet, "/api/v1/snippets/"+scratch.ID, nil, http.StatusNotFound, nil)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		var results []models.Snippet
+		s.do(t, http.MethodGet, "/api/v1/snippets/search?q=e2e", nil, http.StatusOK, &results)
+		if len(results) == 0 {
+			t.Error("expected search to find the snippet")
+		}
+	})
+
+	t.Run("share", func(t *testing.T) {
+		visitor := s.anonymous()
+		visitor.do(t, http.MethodGet, "/api/v1/snippets/public/"+snippet.ID, nil, http.StatusNotFound, nil)
+
+		var updated models.Snippet
+		s.do(t, http.MethodPut, "/api/v1/snippets/"+snippet.ID, models.SnippetInput{
+			Title:    "E2E hello updated",
+			Content:  "fmt.Println(\"hello e2e\")",
+			Language: "go",
+			IsPublic: true,
+		}, http.StatusOK, &updated)
+		if updated.PublicToken == nil {
+			t.Fatal("expected sharing to create a public token")
+		}
+
+		var shared models.Snippet
+		visitor.do(t, http.MethodGet, "/api/v1/snippets/public/"+*updated.PublicToken, nil, http.StatusOK, &shared)
+		if shared.Title != "E2E hello updated" || shared.ID != *updated.PublicToken {
+			t.Errorf("expected shared snippet, got %+v", shared)
+		}
+	})
+
+	t.Run("backup", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, s.url+"/api/v1/backup/export", nil)
+		resp, err := s.client.Do(req)
+		if err != nil {
+			t.Fatalf("export failed: %v", err)
+		}
+		backup, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected export to succeed, got %d: %s", resp.StatusCode, backup)
+		}
+		if !strings.Contains(string(backup), snippet.ID) {
+			t.Fatal("expected export to contain the snippet")
+		}
+
+		var form bytes.Buffer
+		mw := multipart.NewWriter(&form)
+		part, _ := mw.CreateFormFile("file", "backup.json")
+		_, _ = part.Write(backup)
+		_ = mw.WriteField("strategy", "replace")
+		_ = mw.Close()
+
+		req, _ = http.NewRequest(http.MethodPost, s.url+"/api/v1/backup/import", &form)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		s.send(t, req, http.StatusOK, nil)
+
+		// A replace import recreates snippets, so look it up again by title
+		var list []models.Snippet
+		s.do(t, http.MethodGet, "/api/v1/snippets", nil, http.StatusOK, &list)
+		if len(list) != 1 || list[0].Title != "E2E hello updated" {
+			t.Fatalf("expected snippet to survive a replace import, got %+v", list)
+		}
+		snippet = list[0]
+	})
+
+	t.Run("gist sync", func(t *testing.T) {
+		s.do(t, http.MethodPost, "/api/v1/gist/config", map[string]interface{}{
+			"enabled":                      true,
+			"github_token":                 "ghp_e2e",
+			"auto_sync_enabled":            false,
+			"sync_interval_minutes":        15,
+			"conflict_resolution_strategy": models.ConflictStrategyManual,
+		}, http.StatusOK, nil)
+
+		s.do(t, http.MethodPost, "/api/v1/gist/sync/enable/"+snippet.ID, nil, http.StatusOK, nil)
+		if github.GistCount() != 1 {
+			t.Fatalf("expected a gist to be created, got %d", github.GistCount())
+		}
+
+		var mappings []models.SnippetGistMapping
+		s.do(t, http.MethodGet, "/api/v1/gist/mappings", nil, http.StatusOK, &mappings)
+		if len(mappings) != 1 || mappings[0].SnippetID != snippet.ID {
+			t.Errorf("expected a mapping for the snippet, got %+v", mappings)
+		}
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		s.do(t, http.MethodPost, "/api/v1/auth/logout", nil, http.StatusOK, nil)
+		s.do(t, http.MethodGet, "/api/v1/snippets", nil, http.StatusUnauthorized, nil)
+	})
+}
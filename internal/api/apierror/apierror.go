@@ -0,0 +1,208 @@
+// Package apierror is the catalog of machine-readable error codes returned in
+// the "code" field of API error responses, together with the HTTP statuses
+// each code is sent with. Clients can fetch it from GET /api/v1/errors.
+package apierror
+
+import "net/http"
+
+// Code is a machine-readable API error code
+type Code string
+
+// Request errors
+const (
+	ValidationError         Code = "VALIDATION_ERROR"
+	InvalidJSON             Code = "INVALID_JSON"
+	InvalidRequest          Code = "INVALID_REQUEST"
+	MissingID               Code = "MISSING_ID"
+	InvalidID               Code = "INVALID_ID"
+	MissingKey              Code = "MISSING_KEY"
+	MissingFile             Code = "MISSING_FILE"
+	MissingFilename         Code = "MISSING_FILENAME"
+	MissingPassword         Code = "MISSING_PASSWORD"
+	MissingHistoryID        Code = "MISSING_HISTORY_ID"
+	InvalidHistoryID        Code = "INVALID_HISTORY_ID"
+	InvalidCursor           Code = "INVALID_CURSOR"
+	InvalidURL              Code = "INVALID_URL"
+	InvalidFormat           Code = "INVALID_FORMAT"
+	InvalidStatus           Code = "INVALID_STATUS"
+	InvalidAction           Code = "INVALID_ACTION"
+	InvalidMode             Code = "INVALID_MODE"
+	InvalidRepo             Code = "INVALID_REPO"
+	InvalidInterval         Code = "INVALID_INTERVAL"
+	InvalidStrategy         Code = "INVALID_STRATEGY"
+	InvalidConflictStrategy Code = "INVALID_CONFLICT_STRATEGY"
+	InvalidResolution       Code = "INVALID_RESOLUTION"
+	InvalidRemoteSource     Code = "INVALID_REMOTE_SOURCE"
+	InvalidPayload          Code = "INVALID_PAYLOAD"
+	PayloadTooLarge         Code = "PAYLOAD_TOO_LARGE"
+	PasswordInURL           Code = "PASSWORD_IN_URL"
+	ReadError               Code = "READ_ERROR"
+	DecryptionFailed        Code = "DECRYPTION_FAILED"
+	NoToken                 Code = "NO_TOKEN"
+	InvalidToken            Code = "INVALID_TOKEN"
+	SyncNotConfigured       Code = "SYNC_NOT_CONFIGURED"
+	MailNotConfigured       Code = "MAIL_NOT_CONFIGURED"
+	NoRecipients            Code = "NO_RECIPIENTS"
+	UnsupportedAPIVersion   Code = "UNSUPPORTED_API_VERSION"
+	UnsupportedFormat       Code = "UNSUPPORTED_FORMAT"
+)
+
+// Authentication and authorization errors
+const (
+	Unauthorized            Code = "UNAUTHORIZED"
+	InvalidCredentials      Code = "INVALID_CREDENTIALS"
+	ChallengeRequired       Code = "CHALLENGE_REQUIRED"
+	AdminPasswordRequired   Code = "ADMIN_PASSWORD_REQUIRED"
+	PasswordRequired        Code = "PASSWORD_REQUIRED"
+	InvalidPassword         Code = "INVALID_PASSWORD"
+	Forbidden               Code = "FORBIDDEN"
+	InsufficientPermissions Code = "INSUFFICIENT_PERMISSIONS"
+	InsufficientScope       Code = "INSUFFICIENT_SCOPE"
+	InsufficientRole        Code = "INSUFFICIENT_ROLE"
+	ReadOnly                Code = "READ_ONLY"
+	DemoModeRestriction     Code = "DEMO_MODE_RESTRICTION"
+)
+
+// Resource state errors
+const (
+	NotFound           Code = "NOT_FOUND"
+	FeatureDisabled    Code = "FEATURE_DISABLED"
+	MethodNotAllowed   Code = "METHOD_NOT_ALLOWED"
+	AlreadyExists      Code = "ALREADY_EXISTS"
+	TagExists          Code = "TAG_EXISTS"
+	DuplicateContent   Code = "DUPLICATE_CONTENT"
+	IncompatibleBackup Code = "INCOMPATIBLE_BACKUP"
+	SyncInProgress     Code = "SYNC_IN_PROGRESS"
+	GistDeleted        Code = "GIST_DELETED"
+	RateLimited        Code = "RATE_LIMITED"
+	RateLimitExceeded  Code = "RATE_LIMIT_EXCEEDED"
+)
+
+// Server and upstream errors
+const (
+	InternalError      Code = "INTERNAL_ERROR"
+	BackupFailed       Code = "BACKUP_FAILED"
+	ImportFailed       Code = "IMPORT_FAILED"
+	ExportFailed       Code = "EXPORT_FAILED"
+	RestoreFailed      Code = "RESTORE_FAILED"
+	SyncFailed         Code = "SYNC_FAILED"
+	ListFailed         Code = "LIST_FAILED"
+	DeleteFailed       Code = "DELETE_FAILED"
+	PresignFailed      Code = "PRESIGN_FAILED"
+	PresignUnsupported Code = "PRESIGN_UNSUPPORTED"
+	EnableFailed       Code = "ENABLE_FAILED"
+	DisableFailed      Code = "DISABLE_FAILED"
+	FetchFailed        Code = "FETCH_FAILED"
+	VerifyFailed       Code = "VERIFY_FAILED"
+	ResolveFailed      Code = "RESOLVE_FAILED"
+	ForkFailed         Code = "FORK_FAILED"
+	SendFailed         Code = "SEND_FAILED"
+)
+
+// Entry describes an error code. Statuses lists every HTTP status the code is
+// returned with, the usual one first.
+type Entry struct {
+	Code        Code   `json:"code"`
+	Statuses    []int  `json:"statuses"`
+	Description string `json:"description"`
+}
+
+var catalog = []Entry{
+	{ValidationError, []int{http.StatusBadRequest}, "The request payload failed validation; details lists the offending fields"},
+	{InvalidJSON, []int{http.StatusBadRequest}, "The request body is not valid JSON or contains unknown fields"},
+	{InvalidRequest, []int{http.StatusBadRequest}, "The request body or form could not be parsed"},
+	{MissingID, []int{http.StatusBadRequest}, "The resource ID is missing from the path"},
+	{InvalidID, []int{http.StatusBadRequest}, "The resource ID is not a valid number"},
+	{MissingKey, []int{http.StatusBadRequest}, "The backup key is missing"},
+	{MissingFile, []int{http.StatusBadRequest}, "No file was uploaded"},
+	{MissingFilename, []int{http.StatusBadRequest}, "The file name is missing from the path"},
+	{MissingPassword, []int{http.StatusBadRequest}, "The password is missing"},
+	{MissingHistoryID, []int{http.StatusBadRequest}, "The history entry ID is missing from the path"},
+	{InvalidHistoryID, []int{http.StatusBadRequest}, "The history entry ID is not a valid number"},
+	{InvalidCursor, []int{http.StatusBadRequest}, "The list cursor is malformed or was issued for another sort order"},
+	{InvalidURL, []int{http.StatusBadRequest}, "The URL is not a public snippet share or API link"},
+	{InvalidFormat, []int{http.StatusBadRequest}, "The uploaded backup isn't in a recognised format"},
+	{InvalidStatus, []int{http.StatusBadRequest}, "The status filter is not one of the allowed values"},
+	{InvalidAction, []int{http.StatusBadRequest}, "The requested action is not allowed"},
+	{InvalidMode, []int{http.StatusBadRequest}, "The folder delete mode must be move_to_parent, orphan or trash"},
+	{InvalidRepo, []int{http.StatusBadRequest}, "The GitHub repository name is missing or malformed"},
+	{InvalidInterval, []int{http.StatusBadRequest}, "The sync interval is too short"},
+	{InvalidStrategy, []int{http.StatusBadRequest}, "The conflict resolution strategy is unknown"},
+	{InvalidConflictStrategy, []int{http.StatusBadRequest}, "conflict_strategy must be skip, overwrite or duplicate"},
+	{InvalidResolution, []int{http.StatusBadRequest}, "The conflict resolution choice is unknown"},
+	{InvalidRemoteSource, []int{http.StatusBadRequest}, "The remote source URL or settings are invalid"},
+	{InvalidPayload, []int{http.StatusBadRequest}, "The webhook payload is empty or not valid UTF-8 text"},
+	{PayloadTooLarge, []int{http.StatusRequestEntityTooLarge}, "The webhook payload is larger than 1MB"},
+	{PasswordInURL, []int{http.StatusBadRequest}, "Passwords must be sent in the request body, not the URL"},
+	{ReadError, []int{http.StatusBadRequest}, "The uploaded file could not be read"},
+	{DecryptionFailed, []int{http.StatusBadRequest}, "The backup could not be decrypted, usually because of a wrong password"},
+	{NoToken, []int{http.StatusBadRequest}, "No GitHub token is configured"},
+	{InvalidToken, []int{http.StatusBadRequest}, "The GitHub token is invalid or expired"},
+	{SyncNotConfigured, []int{http.StatusBadRequest}, "Gist sync is not configured"},
+	{MailNotConfigured, []int{http.StatusBadRequest}, "No SMTP server is configured for sending e-mail"},
+	{NoRecipients, []int{http.StatusBadRequest}, "No digest recipients are set in the settings"},
+	{UnsupportedAPIVersion, []int{http.StatusNotAcceptable, http.StatusBadRequest}, "The server does not provide the API version asked for in Accept-Version"},
+	{UnsupportedFormat, []int{http.StatusNotImplemented}, "The response format asked for, such as oEmbed XML, isn't offered"},
+
+	{Unauthorized, []int{http.StatusUnauthorized}, "Authentication is required"},
+	{InvalidCredentials, []int{http.StatusUnauthorized}, "The login password is wrong"},
+	{ChallengeRequired, []int{http.StatusUnauthorized}, "The login challenge must be solved before retrying"},
+	{AdminPasswordRequired, []int{http.StatusUnauthorized}, "The master password is required for admin operations when login is disabled"},
+	{PasswordRequired, []int{http.StatusUnauthorized, http.StatusForbidden}, "The operation must be confirmed with the master password"},
+	{InvalidPassword, []int{http.StatusUnauthorized, http.StatusForbidden}, "The confirmation password is wrong"},
+	{Forbidden, []int{http.StatusForbidden}, "Access is denied"},
+	{InsufficientPermissions, []int{http.StatusForbidden}, "The API token lacks the required permission"},
+	{InsufficientScope, []int{http.StatusForbidden}, "The API token is not scoped for this operation"},
+	{InsufficientRole, []int{http.StatusForbidden}, "The user's workspace role does not allow this operation"},
+	{ReadOnly, []int{http.StatusForbidden}, "The snippet is mirrored from a remote source and cannot be changed"},
+	{DemoModeRestriction, []int{http.StatusForbidden}, "The operation is disabled in demo mode"},
+
+	{NotFound, []int{http.StatusNotFound}, "The resource does not exist"},
+	{FeatureDisabled, []int{http.StatusNotFound}, "The feature has been switched off"},
+	{MethodNotAllowed, []int{http.StatusMethodNotAllowed}, "The endpoint does not support this HTTP method"},
+	{AlreadyExists, []int{http.StatusConflict}, "A resource with the same identity already exists"},
+	{TagExists, []int{http.StatusConflict}, "A tag with this name already exists"},
+	{DuplicateContent, []int{http.StatusConflict}, "A snippet with identical content already exists; existing_id points at it"},
+	{IncompatibleBackup, []int{http.StatusConflict}, "The backup was made by an incompatible version of Snipo"},
+	{SyncInProgress, []int{http.StatusConflict}, "An S3 backup upload is already running"},
+	{GistDeleted, []int{http.StatusGone}, "The gist was deleted on GitHub and its sync mapping removed"},
+	{RateLimited, []int{http.StatusTooManyRequests}, "Too many requests or failed attempts; wait for Retry-After seconds"},
+	{RateLimitExceeded, []int{http.StatusTooManyRequests}, "The API token's rate limit is exhausted; wait for Retry-After seconds"},
+
+	{InternalError, []int{http.StatusInternalServerError}, "An unexpected server error occurred"},
+	{BackupFailed, []int{http.StatusInternalServerError}, "Creating the backup failed"},
+	{ImportFailed, []int{http.StatusInternalServerError}, "Importing the backup failed"},
+	{ExportFailed, []int{http.StatusBadGateway}, "Exporting to GitHub failed"},
+	{RestoreFailed, []int{http.StatusInternalServerError, http.StatusBadRequest}, "Restoring a backup or history entry failed"},
+	{SyncFailed, []int{http.StatusInternalServerError, http.StatusBadGateway}, "Syncing with S3, GitHub or a remote source failed"},
+	{ListFailed, []int{http.StatusInternalServerError}, "Listing S3 backups failed"},
+	{DeleteFailed, []int{http.StatusInternalServerError}, "Deleting the S3 backup failed"},
+	{PresignFailed, []int{http.StatusInternalServerError}, "Creating a download link for the S3 backup failed"},
+	{PresignUnsupported, []int{http.StatusNotImplemented}, "The backup storage, such as SFTP, can't create download links"},
+	{EnableFailed, []int{http.StatusInternalServerError}, "Enabling gist sync for the snippet failed"},
+	{DisableFailed, []int{http.StatusInternalServerError}, "Disabling gist sync for the snippet failed"},
+	{FetchFailed, []int{http.StatusInternalServerError}, "Loading the snippets to sync failed"},
+	{VerifyFailed, []int{http.StatusInternalServerError}, "Verifying gist sync mappings failed"},
+	{ResolveFailed, []int{http.StatusInternalServerError}, "Resolving the sync conflict failed"},
+	{ForkFailed, []int{http.StatusBadGateway}, "The snippet to fork could not be fetched"},
+	{SendFailed, []int{http.StatusBadGateway}, "The SMTP server did not accept the e-mail"},
+}
+
+var byCode = func() map[Code]Entry {
+	m := make(map[Code]Entry, len(catalog))
+	for _, e := range catalog {
+		m[e.Code] = e
+	}
+	return m
+}()
+
+// Catalog returns every error code in a stable order
+func Catalog() []Entry {
+	return append([]Entry(nil), catalog...)
+}
+
+// Lookup returns the catalog entry for a code
+func Lookup(code Code) (Entry, bool) {
+	e, ok := byCode[code]
+	return e, ok
+}
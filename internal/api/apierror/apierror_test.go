@@ -0,0 +1,143 @@
+package apierror
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	seen := make(map[Code]bool)
+	for _, e := range Catalog() {
+		if seen[e.Code] {
+			t.Errorf("%s is listed twice", e.Code)
+		}
+		seen[e.Code] = true
+		if len(e.Statuses) == 0 || e.Description == "" {
+			t.Errorf("%s needs a status and a description", e.Code)
+		}
+		for _, status := range e.Statuses {
+			if http.StatusText(status) == "" {
+				t.Errorf("%s has an invalid status %d", e.Code, status)
+			}
+		}
+	}
+}
+
+// statusNames maps the net/http constants used by error call sites
+var statusNames = map[string]int{
+	"StatusBadRequest":            http.StatusBadRequest,
+	"StatusUnauthorized":          http.StatusUnauthorized,
+	"StatusForbidden":             http.StatusForbidden,
+	"StatusNotFound":              http.StatusNotFound,
+	"StatusMethodNotAllowed":      http.StatusMethodNotAllowed,
+	"StatusNotAcceptable":         http.StatusNotAcceptable,
+	"StatusConflict":              http.StatusConflict,
+	"StatusGone":                  http.StatusGone,
+	"StatusRequestEntityTooLarge": http.StatusRequestEntityTooLarge,
+	"StatusTooManyRequests":       http.StatusTooManyRequests,
+	"StatusInternalServerError":   http.StatusInternalServerError,
+	"StatusNotImplemented":        http.StatusNotImplemented,
+	"StatusBadGateway":            http.StatusBadGateway,
+}
+
+// TestCallSites checks that every error written by the API uses a status
+// its catalog entry lists
+func TestCallSites(t *testing.T) {
+	codes := constCodes(t)
+	fset := token.NewFileSet()
+	var files []string
+	for _, pattern := range []string{"../handlers/*.go", "../middleware/*.go", "../*.go"} {
+		matches, _ := filepath.Glob(pattern)
+		files = append(files, matches...)
+	}
+
+	calls := 0
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) != 5 || !isErrorFunc(call.Fun) {
+				return true
+			}
+			status, ok1 := selector(call.Args[2], "http")
+			code, ok2 := selector(call.Args[3], "apierror")
+			if !ok1 || !ok2 {
+				return true
+			}
+			calls++
+			pos := fset.Position(call.Pos())
+			entry, ok := Lookup(codes[code])
+			if !ok {
+				t.Errorf("%s: apierror.%s is not in the catalog", pos, code)
+				return true
+			}
+			if s, known := statusNames[status]; !known || !slices.Contains(entry.Statuses, s) {
+				t.Errorf("%s: %s is sent with http.%s, which its catalog entry doesn't list", pos, entry.Code, status)
+			}
+			return true
+		})
+	}
+	if calls == 0 {
+		t.Fatal("found no error call sites")
+	}
+}
+
+func isErrorFunc(fun ast.Expr) bool {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		return f.Name == "Error" || f.Name == "writeError"
+	case *ast.SelectorExpr:
+		return f.Sel.Name == "Error"
+	}
+	return false
+}
+
+func selector(e ast.Expr, pkg string) (string, bool) {
+	sel, ok := e.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok || ident.Name != pkg {
+		return "", false
+	}
+	return sel.Sel.Name, true
+}
+
+// constCodes maps the names of the Code constants to their values
+func constCodes(t *testing.T) map[string]Code {
+	f, err := parser.ParseFile(token.NewFileSet(), "apierror.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes := make(map[string]Code)
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				if lit, ok := vs.Values[i].(*ast.BasicLit); ok {
+					value, _ := strconv.Unquote(lit.Value)
+					codes[name.Name] = Code(value)
+				}
+			}
+		}
+	}
+	return codes
+}
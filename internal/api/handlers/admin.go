@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/auth"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/telemetry"
+)
+
+// AdminHandler serves the admin overview, search index and database
+// maintenance and the usage report
+type AdminHandler struct {
+	authService *auth.Service
+	searchIndex *services.SearchIndexService
+	maintenance *services.MaintenanceService
+	usage       *telemetry.Recorder // nil unless telemetry is enabled
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(authService *auth.Service) *AdminHandler {
+	return &AdminHandler{authService: authService}
+}
+
+// WithUsage enables the usage report. A nil recorder reports telemetry as
+// disabled.
+func (h *AdminHandler) WithUsage(usage *telemetry.Recorder) *AdminHandler {
+	h.usage = usage
+	return h
+}
+
+// WithSearchIndex enables the search index endpoints
+func (h *AdminHandler) WithSearchIndex(searchIndex *services.SearchIndexService) *AdminHandler {
+	h.searchIndex = searchIndex
+	return h
+}
+
+// WithMaintenance enables the database maintenance endpoint
+func (h *AdminHandler) WithMaintenance(maintenance *services.MaintenanceService) *AdminHandler {
+	h.maintenance = maintenance
+	return h
+}
+
+// OverviewResponse is the admin overview
+type OverviewResponse struct {
+	Access *auth.AccessInsights `json:"access"`
+}
+
+// Overview handles GET /api/v1/admin/overview
+func (h *AdminHandler) Overview(w http.ResponseWriter, r *http.Request) {
+	access, err := h.authService.AccessInsights()
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, OverviewResponse{Access: access})
+}
+
+// SearchIndexStatus handles GET /api/v1/admin/search-index
+func (h *AdminHandler) SearchIndexStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.searchIndex.Status(r.Context())
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, status)
+}
+
+// RebuildSearchIndex handles POST /api/v1/admin/search-index/rebuild
+func (h *AdminHandler) RebuildSearchIndex(w http.ResponseWriter, r *http.Request) {
+	status, err := h.searchIndex.Rebuild(r.Context())
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, status)
+}
+
+// MaintainDatabase handles POST /api/v1/admin/database/maintain. The body
+// may name the tasks to run; without one every task runs.
+func (h *AdminHandler) MaintainDatabase(w http.ResponseWriter, r *http.Request) {
+	var req models.MaintenanceRequest
+	if r.Body != nil {
+		if err := DecodeJSON(r, &req); err != nil && err != io.EOF {
+			Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid request body")
+			return
+		}
+	}
+
+	report, err := h.maintenance.Run(r.Context(), req.Tasks)
+	if errors.Is(err, services.ErrUnknownMaintenanceTask) {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, report)
+}
+
+// Usage handles GET /api/v1/admin/usage
+func (h *AdminHandler) Usage(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed < 1 || parsed > telemetry.RetentionDays {
+			Error(w, r, http.StatusBadRequest, apierror.InvalidRequest, "days must be between 1 and 90")
+			return
+		}
+		days = parsed
+	}
+
+	if h.usage == nil {
+		OK(w, r, models.UsageReport{Days: days, Features: []models.UsageCount{}, Routes: []models.UsageCount{}})
+		return
+	}
+
+	report, err := h.usage.Report(r.Context(), days)
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, report)
+}
@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
 	"github.com/MohamedElashri/snipo/internal/api/middleware"
 	"github.com/MohamedElashri/snipo/internal/auth"
+	"github.com/MohamedElashri/snipo/internal/models"
 )
 
 // AuthHandler handles authentication-related HTTP requests
@@ -30,7 +36,9 @@ func (h *AuthHandler) WithDemoMode(enabled bool) *AuthHandler {
 
 // LoginRequest represents a login request
 type LoginRequest struct {
-	Password string `json:"password"`
+	Username   string `json:"username,omitempty"` // Multi-user mode only; empty logs in as the owner
+	Password   string `json:"password"`
+	RememberMe bool   `json:"remember_me,omitempty"`
 }
 
 // LoginResponse represents a login response
@@ -43,12 +51,25 @@ type LoginResponse struct {
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := DecodeJSON(r, &req); err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON payload")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
 		return
 	}
 
 	if req.Password == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_PASSWORD", "Password is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingPassword, "Password is required")
+		return
+	}
+
+	// Once too many logins have failed, every attempt must carry a solved challenge
+	if h.authService.ChallengeRequired() && !h.authService.VerifyChallenge(r.Header.Get(auth.ChallengeResponseHeader)) {
+		challenge, difficulty, err := h.authService.IssueChallenge()
+		if err != nil {
+			InternalError(w, r)
+			return
+		}
+		w.Header().Set(auth.ChallengeHeader, challenge)
+		w.Header().Set(auth.ChallengeDifficultyHeader, strconv.Itoa(difficulty))
+		Error(w, r, http.StatusUnauthorized, apierror.ChallengeRequired, "Solve the login challenge and retry")
 		return
 	}
 
@@ -56,28 +77,28 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	clientIP := getClientIPForAuth(r)
 
 	// Verify password with progressive delay enforcement
-	valid, delay := h.authService.VerifyPasswordWithDelay(req.Password, clientIP)
+	userID, valid, delay := h.authService.VerifyUserWithDelay(req.Username, req.Password, clientIP)
 	if delay > 0 {
 		w.Header().Set("Retry-After", fmt.Sprintf("%d", int(delay.Seconds())+1))
-		Error(w, r, http.StatusTooManyRequests, "RATE_LIMITED",
+		Error(w, r, http.StatusTooManyRequests, apierror.RateLimited,
 			fmt.Sprintf("Too many failed attempts. Please wait %d seconds.", int(delay.Seconds())+1))
 		return
 	}
 
 	if !valid {
-		Error(w, r, http.StatusUnauthorized, "INVALID_CREDENTIALS", "Invalid password")
+		Error(w, r, http.StatusUnauthorized, apierror.InvalidCredentials, "Invalid password")
 		return
 	}
 
 	// Create session
-	token, err := h.authService.CreateSession()
+	token, err := h.authService.CreateUserSession(userID, req.RememberMe, clientIP)
 	if err != nil {
 		InternalError(w, r)
 		return
 	}
 
 	// Set session cookie
-	h.authService.SetSessionCookie(w, token)
+	h.authService.SetSessionCookie(w, token, req.RememberMe)
 
 	OK(w, r, LoginResponse{
 		Success: true,
@@ -115,3 +136,52 @@ func (h *AuthHandler) Check(w http.ResponseWriter, r *http.Request) {
 
 	OK(w, r, map[string]bool{"authenticated": true})
 }
+
+// RevokeSessionsResponse reports how many sessions were revoked
+type RevokeSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// ListSessions handles GET /api/v1/auth/sessions
+// Lists the caller's active sessions; the one making the request is marked
+// current.
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, _ := models.UserIDFromContext(r.Context())
+	sessions, err := h.authService.ListSessions(userID, auth.GetSessionFromRequest(r))
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, sessions)
+}
+
+// RevokeSession handles DELETE /api/v1/auth/sessions/{id}
+// Only the caller's own sessions can be revoked.
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, _ := models.UserIDFromContext(r.Context())
+	if err := h.authService.RevokeSession(userID, chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			NotFound(w, r, "Session not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	NoContent(w)
+}
+
+// RevokeOtherSessions handles POST /api/v1/auth/sessions/revoke-others
+// Logs out every session of the caller but the one making the request.
+// Called with an API token, all of the caller's sessions are revoked.
+func (h *AuthHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID, _ := models.UserIDFromContext(r.Context())
+	revoked, err := h.authService.RevokeOtherSessions(userID, auth.GetSessionFromRequest(r))
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, RevokeSessionsResponse{Revoked: revoked})
+}
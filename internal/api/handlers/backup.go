@@ -1,26 +1,36 @@
 package handlers
 
 import (
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
 
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
 	"github.com/MohamedElashri/snipo/internal/models"
 	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/storage"
 )
 
 // BackupHandler handles backup-related HTTP requests
 type BackupHandler struct {
 	backupSvc *services.BackupService
-	s3SyncSvc *services.S3SyncService // May be nil if S3 is not configured
+	s3SyncSvc atomic.Pointer[services.S3SyncService] // nil if S3 is not configured
 }
 
 // NewBackupHandler creates a new backup handler
 func NewBackupHandler(backupSvc *services.BackupService, s3SyncSvc *services.S3SyncService) *BackupHandler {
-	return &BackupHandler{
-		backupSvc: backupSvc,
-		s3SyncSvc: s3SyncSvc,
-	}
+	h := &BackupHandler{backupSvc: backupSvc}
+	h.SetS3Sync(s3SyncSvc)
+	return h
+}
+
+// SetS3Sync replaces the S3 sync service, nil if S3 is no longer configured
+func (h *BackupHandler) SetS3Sync(s3SyncSvc *services.S3SyncService) {
+	h.s3SyncSvc.Store(s3SyncSvc)
 }
 
 // Export handles GET /api/v1/backup/export
@@ -33,13 +43,13 @@ func (h *BackupHandler) Export(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method == http.MethodGet && opts.Password != "" {
-		Error(w, r, http.StatusBadRequest, "PASSWORD_IN_URL", "Use POST /api/v1/backup/export to encrypt backups with a password")
+		Error(w, r, http.StatusBadRequest, apierror.PasswordInURL, "Use POST /api/v1/backup/export to encrypt backups with a password")
 		return
 	}
 
 	if r.Method == http.MethodPost && r.Body != nil {
 		if err := DecodeJSON(r, &opts); err != nil && err != io.EOF {
-			Error(w, r, http.StatusBadRequest, "INVALID_JSON", "Invalid request body")
+			Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid request body")
 			return
 		}
 	}
@@ -50,7 +60,7 @@ func (h *BackupHandler) Export(w http.ResponseWriter, r *http.Request) {
 
 	content, filename, err := h.backupSvc.Export(r.Context(), opts)
 	if err != nil {
-		Error(w, r, http.StatusInternalServerError, "BACKUP_FAILED", err.Error())
+		Error(w, r, http.StatusInternalServerError, apierror.BackupFailed, err.Error())
 		return
 	}
 
@@ -70,17 +80,19 @@ func (h *BackupHandler) Export(w http.ResponseWriter, r *http.Request) {
 }
 
 // Import handles POST /api/v1/backup/import
-// Form data: file (multipart), strategy (replace|merge|skip), password (optional)
+// Form data: file (multipart), strategy (replace|merge|skip), password (optional),
+// conflict_strategy (skip|overwrite|duplicate, optional)
+// Query params: dry_run (true to preview changes without importing)
 func (h *BackupHandler) Import(w http.ResponseWriter, r *http.Request) {
 	// Parse multipart form (max 50MB)
 	if err := r.ParseMultipartForm(50 << 20); err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_REQUEST", "Failed to parse form data")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidRequest, "Failed to parse form data")
 		return
 	}
 
 	file, _, err := r.FormFile("file")
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "MISSING_FILE", "No backup file provided")
+		Error(w, r, http.StatusBadRequest, apierror.MissingFile, "No backup file provided")
 		return
 	}
 	defer func() {
@@ -91,30 +103,48 @@ func (h *BackupHandler) Import(w http.ResponseWriter, r *http.Request) {
 
 	content, err := io.ReadAll(file)
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "READ_ERROR", "Failed to read backup file")
+		Error(w, r, http.StatusBadRequest, apierror.ReadError, "Failed to read backup file")
 		return
 	}
 
+	// FormValue covers both the query string and the multipart form
+	dryRun := r.FormValue("dry_run")
+
 	opts := models.ImportOptions{
-		Strategy: r.FormValue("strategy"),
-		Password: r.FormValue("password"),
+		Strategy:         r.FormValue("strategy"),
+		ConflictStrategy: r.FormValue("conflict_strategy"),
+		Password:         r.FormValue("password"),
+		DryRun:           dryRun == "true" || dryRun == "1",
 	}
 
 	if opts.Strategy == "" {
 		opts.Strategy = "merge"
 	}
 
+	if !validConflictStrategy(opts.ConflictStrategy) {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidConflictStrategy, "conflict_strategy must be one of: skip, overwrite, duplicate")
+		return
+	}
+
 	result, err := h.backupSvc.Import(r.Context(), content, opts)
 	if err != nil {
 		if err == services.ErrDecryptionFailed {
-			Error(w, r, http.StatusBadRequest, "DECRYPTION_FAILED", "Failed to decrypt backup - wrong password?")
+			Error(w, r, http.StatusBadRequest, apierror.DecryptionFailed, "Failed to decrypt backup - wrong password?")
+			return
+		}
+		if err == services.ErrPasswordRequired {
+			Error(w, r, http.StatusBadRequest, apierror.MissingPassword, "The backup is encrypted, provide its password")
 			return
 		}
 		if err == services.ErrInvalidBackupFormat {
-			Error(w, r, http.StatusBadRequest, "INVALID_FORMAT", "Invalid backup file format")
+			Error(w, r, http.StatusBadRequest, apierror.InvalidFormat, "Invalid backup file format")
+			return
+		}
+		if errors.Is(err, services.ErrIncompatibleBackup) {
+			Error(w, r, http.StatusConflict, apierror.IncompatibleBackup, err.Error())
 			return
 		}
-		Error(w, r, http.StatusInternalServerError, "IMPORT_FAILED", err.Error())
+		Error(w, r, http.StatusInternalServerError, apierror.ImportFailed, err.Error())
 		return
 	}
 
@@ -124,7 +154,8 @@ func (h *BackupHandler) Import(w http.ResponseWriter, r *http.Request) {
 // S3Sync handles POST /api/v1/backup/s3/sync
 // Body: { "format": "json|zip", "password": "optional" }
 func (h *BackupHandler) S3Sync(w http.ResponseWriter, r *http.Request) {
-	if h.s3SyncSvc == nil {
+	s3SyncSvc := h.s3SyncSvc.Load()
+	if s3SyncSvc == nil {
 		Error(w, r, http.StatusServiceUnavailable, "S3_NOT_CONFIGURED", "S3 storage is not configured")
 		return
 	}
@@ -135,9 +166,13 @@ func (h *BackupHandler) S3Sync(w http.ResponseWriter, r *http.Request) {
 		opts.Format = "json"
 	}
 
-	result, err := h.s3SyncSvc.SyncToS3(r.Context(), opts)
+	result, err := s3SyncSvc.SyncToS3(r.Context(), opts)
 	if err != nil {
-		Error(w, r, http.StatusInternalServerError, "SYNC_FAILED", err.Error())
+		if errors.Is(err, services.ErrSyncInProgress) {
+			Error(w, r, http.StatusConflict, apierror.SyncInProgress, err.Error())
+			return
+		}
+		Error(w, r, http.StatusInternalServerError, apierror.SyncFailed, err.Error())
 		return
 	}
 
@@ -146,14 +181,15 @@ func (h *BackupHandler) S3Sync(w http.ResponseWriter, r *http.Request) {
 
 // S3List handles GET /api/v1/backup/s3/list
 func (h *BackupHandler) S3List(w http.ResponseWriter, r *http.Request) {
-	if h.s3SyncSvc == nil {
+	s3SyncSvc := h.s3SyncSvc.Load()
+	if s3SyncSvc == nil {
 		Error(w, r, http.StatusServiceUnavailable, "S3_NOT_CONFIGURED", "S3 storage is not configured")
 		return
 	}
 
-	backups, err := h.s3SyncSvc.ListBackups(r.Context())
+	backups, err := s3SyncSvc.ListBackups(r.Context())
 	if err != nil {
-		Error(w, r, http.StatusInternalServerError, "LIST_FAILED", err.Error())
+		Error(w, r, http.StatusInternalServerError, apierror.ListFailed, err.Error())
 		return
 	}
 
@@ -161,62 +197,131 @@ func (h *BackupHandler) S3List(w http.ResponseWriter, r *http.Request) {
 }
 
 // S3Restore handles POST /api/v1/backup/s3/restore
-// Body: { "key": "backups/snipo-backup-xxx.json", "strategy": "replace|merge|skip", "password": "optional" }
+// Body: { "key": "backups/snipo-backup-xxx.json", "strategy": "replace|merge|skip",
+// "conflict_strategy": "skip|overwrite|duplicate", "password": "optional",
+// "dry_run": true to preview changes without restoring }
 func (h *BackupHandler) S3Restore(w http.ResponseWriter, r *http.Request) {
-	if h.s3SyncSvc == nil {
+	s3SyncSvc := h.s3SyncSvc.Load()
+	if s3SyncSvc == nil {
 		Error(w, r, http.StatusServiceUnavailable, "S3_NOT_CONFIGURED", "S3 storage is not configured")
 		return
 	}
 
 	var req struct {
-		Key      string `json:"key"`
-		Strategy string `json:"strategy"`
-		Password string `json:"password"`
+		Key              string `json:"key"`
+		Strategy         string `json:"strategy"`
+		ConflictStrategy string `json:"conflict_strategy"`
+		Password         string `json:"password"`
+		DryRun           bool   `json:"dry_run"`
 	}
 
 	if err := DecodeJSON(r, &req); err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidRequest, "Invalid request body")
 		return
 	}
 
 	if req.Key == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_KEY", "Backup key is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingKey, "Backup key is required")
+		return
+	}
+
+	if !validConflictStrategy(req.ConflictStrategy) {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidConflictStrategy, "conflict_strategy must be one of: skip, overwrite, duplicate")
 		return
 	}
 
 	opts := models.ImportOptions{
-		Strategy: req.Strategy,
-		Password: req.Password,
+		Strategy:         req.Strategy,
+		ConflictStrategy: req.ConflictStrategy,
+		Password:         req.Password,
+		DryRun:           req.DryRun,
 	}
 
 	if opts.Strategy == "" {
 		opts.Strategy = "merge"
 	}
 
-	result, err := h.s3SyncSvc.RestoreFromS3(r.Context(), req.Key, opts)
+	result, err := s3SyncSvc.RestoreFromS3(r.Context(), req.Key, opts)
 	if err != nil {
-		Error(w, r, http.StatusInternalServerError, "RESTORE_FAILED", err.Error())
+		if errors.Is(err, services.ErrDecryptionFailed) {
+			Error(w, r, http.StatusBadRequest, apierror.DecryptionFailed, "Failed to decrypt backup - wrong password?")
+			return
+		}
+		if errors.Is(err, services.ErrPasswordRequired) {
+			Error(w, r, http.StatusBadRequest, apierror.MissingPassword, "The backup is encrypted, provide its password")
+			return
+		}
+		if errors.Is(err, services.ErrIncompatibleBackup) {
+			Error(w, r, http.StatusConflict, apierror.IncompatibleBackup, err.Error())
+			return
+		}
+		Error(w, r, http.StatusInternalServerError, apierror.RestoreFailed, err.Error())
 		return
 	}
 
 	OK(w, r, result)
 }
 
+// S3Presign handles GET /api/v1/backup/s3/presign
+// Query params: key, expires_in (seconds, default 900, between 60 and 604800)
+func (h *BackupHandler) S3Presign(w http.ResponseWriter, r *http.Request) {
+	s3SyncSvc := h.s3SyncSvc.Load()
+	if s3SyncSvc == nil {
+		Error(w, r, http.StatusServiceUnavailable, "S3_NOT_CONFIGURED", "S3 storage is not configured")
+		return
+	}
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		Error(w, r, http.StatusBadRequest, apierror.MissingKey, "Backup key is required")
+		return
+	}
+
+	expiry := 15 * time.Minute
+	if v := r.URL.Query().Get("expires_in"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		expiry = time.Duration(seconds) * time.Second
+		if err != nil || expiry < services.MinPresignExpiry || expiry > services.MaxPresignExpiry {
+			Error(w, r, http.StatusBadRequest, apierror.InvalidRequest, "expires_in must be between 60 and 604800 seconds")
+			return
+		}
+	}
+
+	link, err := s3SyncSvc.PresignBackup(r.Context(), key, expiry)
+	if err != nil {
+		if errors.Is(err, services.ErrBackupNotFound) {
+			NotFound(w, r, "Backup not found")
+			return
+		}
+		if errors.Is(err, storage.ErrPresignUnsupported) {
+			Error(w, r, http.StatusNotImplemented, apierror.PresignUnsupported, "Download links aren't available for SFTP storage")
+			return
+		}
+		Error(w, r, http.StatusInternalServerError, apierror.PresignFailed, err.Error())
+		return
+	}
+
+	// The link grants access on its own until it expires
+	w.Header().Set("Cache-Control", "no-store")
+	OK(w, r, link)
+}
+
 // S3Delete handles DELETE /api/v1/backup/s3/{key}
 func (h *BackupHandler) S3Delete(w http.ResponseWriter, r *http.Request) {
-	if h.s3SyncSvc == nil {
+	s3SyncSvc := h.s3SyncSvc.Load()
+	if s3SyncSvc == nil {
 		Error(w, r, http.StatusServiceUnavailable, "S3_NOT_CONFIGURED", "S3 storage is not configured")
 		return
 	}
 
 	key := r.URL.Query().Get("key")
 	if key == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_KEY", "Backup key is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingKey, "Backup key is required")
 		return
 	}
 
-	if err := h.s3SyncSvc.DeleteBackup(r.Context(), key); err != nil {
-		Error(w, r, http.StatusInternalServerError, "DELETE_FAILED", err.Error())
+	if err := s3SyncSvc.DeleteBackup(r.Context(), key); err != nil {
+		Error(w, r, http.StatusInternalServerError, apierror.DeleteFailed, err.Error())
 		return
 	}
 
@@ -227,9 +332,24 @@ func (h *BackupHandler) S3Delete(w http.ResponseWriter, r *http.Request) {
 
 // S3Status handles GET /api/v1/backup/s3/status
 func (h *BackupHandler) S3Status(w http.ResponseWriter, r *http.Request) {
+	s3SyncSvc := h.s3SyncSvc.Load()
 	status := map[string]interface{}{
-		"enabled": h.s3SyncSvc != nil,
+		"enabled": s3SyncSvc != nil,
+	}
+	if s3SyncSvc != nil {
+		if progress := s3SyncSvc.UploadProgress(); progress != nil {
+			status["upload"] = progress
+		}
 	}
 
 	OK(w, r, status)
 }
+
+// validConflictStrategy reports whether s is empty or a known import conflict strategy
+func validConflictStrategy(s string) bool {
+	switch s {
+	case "", models.ConflictSkip, models.ConflictOverwrite, models.ConflictDuplicate:
+		return true
+	}
+	return false
+}
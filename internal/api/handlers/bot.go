@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+// BotHandler handles chat bot configuration endpoints
+type BotHandler struct {
+	service *services.BotService
+}
+
+// NewBotHandler creates a new bot handler
+func NewBotHandler(service *services.BotService) *BotHandler {
+	return &BotHandler{service: service}
+}
+
+// GetConfig handles GET /api/v1/bot
+func (h *BotHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	config, err := h.service.GetConfig(r.Context())
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, config)
+}
+
+// UpdateConfig handles PUT /api/v1/bot
+// An empty token keeps the stored one
+func (h *BotHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
+	var input models.BotConfigInput
+	if err := DecodeJSON(r, &input); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	config, err := h.service.UpdateConfig(r.Context(), &input)
+	if err != nil {
+		var verrs validation.ValidationErrors
+		if errors.As(err, &verrs) {
+			ValidationErrors(w, r, verrs)
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, config)
+}
+
+// DeleteConfig handles DELETE /api/v1/bot
+func (h *BotHandler) DeleteConfig(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteConfig(r.Context()); err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	NoContent(w)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/services"
+)
+
+// DigestHandler previews and sends the digest e-mail
+type DigestHandler struct {
+	digest *services.DigestService // nil unless SMTP is configured
+}
+
+// NewDigestHandler creates a new digest handler
+func NewDigestHandler(digest *services.DigestService) *DigestHandler {
+	return &DigestHandler{digest: digest}
+}
+
+// Preview handles GET /api/v1/digest
+// Returns the digest that would be sent now
+func (h *DigestHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	if h.digest == nil {
+		Error(w, r, http.StatusBadRequest, apierror.MailNotConfigured, "E-mail is not configured")
+		return
+	}
+
+	digest, err := h.digest.Preview(r.Context())
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, digest)
+}
+
+// Send handles POST /api/v1/digest/send
+// Sends the digest to its recipients right away
+func (h *DigestHandler) Send(w http.ResponseWriter, r *http.Request) {
+	if h.digest == nil {
+		Error(w, r, http.StatusBadRequest, apierror.MailNotConfigured, "E-mail is not configured")
+		return
+	}
+
+	result, err := h.digest.Send(r.Context())
+	if err != nil {
+		if errors.Is(err, services.ErrNoDigestRecipients) {
+			Error(w, r, http.StatusBadRequest, apierror.NoRecipients, "No digest recipients are set")
+			return
+		}
+		Error(w, r, http.StatusBadGateway, apierror.SendFailed, "Failed to send the digest: "+err.Error())
+		return
+	}
+
+	OK(w, r, result)
+}
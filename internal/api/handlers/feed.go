@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/services"
+)
+
+const (
+	// feedEntries caps the snippets listed in a feed
+	feedEntries = 50
+	// feedCodeLimit caps the code of each file shown in an entry; the
+	// share page has the rest
+	feedCodeLimit = 16 * 1024
+)
+
+// FeedHandler serves Atom feeds of recently updated public snippets, linking
+// to their share pages
+type FeedHandler struct {
+	snippets *services.SnippetService
+	basePath string
+}
+
+// NewFeedHandler creates a new feed handler. basePath is prefixed to the
+// links in the feeds.
+func NewFeedHandler(snippets *services.SnippetService, basePath string) *FeedHandler {
+	return &FeedHandler{snippets: snippets, basePath: basePath}
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Links   []atomLink  `xml:"link"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    atomText       `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Feed handles GET /feed.xml and GET /tags/{tag}/feed.xml
+// Lists the public snippets most recently updated, those with the tag for
+// the tag feed, newest first
+func (h *FeedHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	tag := chi.URLParam(r, "tag")
+	entries, err := h.snippets.PublicFeedEntries(r.Context(), tag, feedEntries)
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	// The path already has the base path when serving under one
+	self := scheme(r) + "://" + r.Host + r.URL.Path
+	site := scheme(r) + "://" + r.Host + h.basePath
+	feed := atomFeed{
+		Title:  "Snipo public snippets",
+		ID:     self,
+		Author: atomAuthor{Name: "Snipo"},
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: self},
+			{Rel: "alternate", Type: "text/html", Href: site + "/"},
+		},
+		Entries: []atomEntry{},
+	}
+	if tag != "" {
+		feed.Title += " tagged " + tag
+	}
+	// The newest entry dates the feed. An empty one gets a fixed date, so
+	// its ETag doesn't change.
+	updated := time.Unix(0, 0)
+	if len(entries) > 0 {
+		updated = entries[0].UpdatedAt
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	for _, snippet := range entries {
+		link := site + "/s/" + url.PathEscape(snippet.ID)
+		entry := atomEntry{
+			Title:     snippet.Title,
+			ID:        link,
+			Link:      atomLink{Rel: "alternate", Type: "text/html", Href: link},
+			Published: snippet.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   snippet.UpdatedAt.UTC().Format(time.RFC3339),
+			Content:   atomText{Type: "html", Body: entryHTML(&snippet)},
+		}
+		if snippet.Description != "" {
+			entry.Summary = &atomText{Type: "text", Body: snippet.Description}
+		}
+		for _, t := range snippet.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: t.Name})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(feed); err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	body := buf.Bytes()
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// entryHTML shows a snippet's rendered markdown or its code, with a
+// heading for each file of a multi-file snippet
+func entryHTML(snippet *models.Snippet) string {
+	var b strings.Builder
+	if snippet.Description != "" {
+		b.WriteString("<p>" + html.EscapeString(snippet.Description) + "</p>\n")
+	}
+	if len(snippet.Files) == 0 {
+		writeCodeHTML(&b, snippet.Content, snippet.Language, snippet.RenderedHTML)
+		return b.String()
+	}
+	for _, file := range snippet.Files {
+		if len(snippet.Files) > 1 {
+			b.WriteString("<h3>" + html.EscapeString(file.Filename) + "</h3>\n")
+		}
+		writeCodeHTML(&b, file.Content, file.Language, file.RenderedHTML)
+	}
+	return b.String()
+}
+
+func writeCodeHTML(b *strings.Builder, content, language, rendered string) {
+	if rendered != "" {
+		b.WriteString(rendered)
+		b.WriteString("\n")
+		return
+	}
+	if len(content) > feedCodeLimit {
+		content = content[:feedCodeLimit]
+		if i := strings.LastIndexByte(content, '\n'); i > 0 {
+			content = content[:i]
+		}
+		// Drops a character cut in half when there was no line break
+		content = strings.ToValidUTF8(content, "") + "\n…"
+	}
+	b.WriteString(`<pre><code class="language-` + html.EscapeString(language) + `">`)
+	b.WriteString(html.EscapeString(content))
+	b.WriteString("</code></pre>\n")
+}
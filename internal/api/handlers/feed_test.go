@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestFeedHandler_Feed(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	service := services.NewSnippetService(repository.NewSnippetRepository(db), testutil.TestLogger()).
+		WithTagRepo(repository.NewTagRepository(db))
+
+	inputs := []models.SnippetInput{
+		{Title: "Private", Content: "secret", Language: "go"},
+		{Title: "Prune images", Content: "docker image prune -a <none>", Language: "bash", Description: "Frees disk", Tags: []string{"docker"}, IsPublic: true},
+		{Title: "Notes", Content: "# Heading\n\nSome *text*", Language: "markdown", IsPublic: true},
+	}
+	for i := range inputs {
+		if _, err := service.Create(ctx, &inputs[i]); err != nil {
+			t.Fatalf("failed to create snippet: %v", err)
+		}
+	}
+
+	router := chi.NewRouter()
+	handler := NewFeedHandler(service, "")
+	router.Get("/feed.xml", handler.Feed)
+	router.Get("/tags/{tag}/feed.xml", handler.Feed)
+
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		req := withRequestID(httptest.NewRequest(http.MethodGet, "http://snipo.example"+path, nil))
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	parse := func(w *httptest.ResponseRecorder) atomFeed {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+			t.Errorf("expected an Atom content type, got %q", ct)
+		}
+		var feed atomFeed
+		if err := xml.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+			t.Fatalf("expected an Atom document: %v", err)
+		}
+		return feed
+	}
+
+	w := get("/feed.xml", "")
+	feed := parse(w)
+	if len(feed.Entries) != 2 {
+		t.Fatalf("expected the 2 public snippets, got %+v", feed.Entries)
+	}
+	byTitle := make(map[string]atomEntry)
+	for _, entry := range feed.Entries {
+		if !strings.HasPrefix(entry.Link.Href, "http://snipo.example/s/") {
+			t.Errorf("expected a link to the share page, got %q", entry.Link.Href)
+		}
+		byTitle[entry.Title] = entry
+	}
+	if !strings.Contains(byTitle["Notes"].Content.Body, "<h1") || !strings.Contains(byTitle["Notes"].Content.Body, "<em>text</em>") {
+		t.Errorf("expected rendered markdown, got %q", byTitle["Notes"].Content.Body)
+	}
+	prune := byTitle["Prune images"]
+	if !strings.Contains(prune.Content.Body, `<code class="language-bash">docker image prune -a &lt;none&gt;</code>`) {
+		t.Errorf("expected escaped code, got %q", prune.Content.Body)
+	}
+	if prune.Summary == nil || prune.Summary.Body != "Frees disk" || len(prune.Categories) != 1 || prune.Categories[0].Term != "docker" {
+		t.Errorf("expected the description and tag, got %+v", prune)
+	}
+
+	if w := get("/feed.xml", w.Header().Get("ETag")); w.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for a matching ETag, got %d", w.Code)
+	}
+
+	tagged := parse(get("/tags/Docker/feed.xml", ""))
+	if len(tagged.Entries) != 1 || tagged.Entries[0].Title != "Prune images" {
+		t.Errorf("expected only the docker snippet, got %+v", tagged.Entries)
+	}
+	if !strings.HasSuffix(tagged.ID, "/tags/Docker/feed.xml") {
+		t.Errorf("expected the tag feed's own URL as its ID, got %q", tagged.ID)
+	}
+	if empty := parse(get("/tags/none/feed.xml", "")); len(empty.Entries) != 0 {
+		t.Errorf("expected an empty feed, got %+v", empty.Entries)
+	}
+}
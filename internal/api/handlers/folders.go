@@ -7,6 +7,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
 	"github.com/MohamedElashri/snipo/internal/models"
 	"github.com/MohamedElashri/snipo/internal/repository"
 	"github.com/MohamedElashri/snipo/internal/validation"
@@ -14,16 +15,27 @@ import (
 
 // FolderHandler handles folder-related HTTP requests
 type FolderHandler struct {
-	repo *repository.FolderRepository
+	repo repository.FolderStore
 }
 
 // NewFolderHandler creates a new folder handler
-func NewFolderHandler(repo *repository.FolderRepository) *FolderHandler {
+func NewFolderHandler(repo repository.FolderStore) *FolderHandler {
 	return &FolderHandler{repo: repo}
 }
 
-// List handles GET /api/v1/folders
+// List handles GET /api/v1/folders. With is_deleted=true it lists the
+// folders in the trash instead.
 func (h *FolderHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("is_deleted") == "true" {
+		folders, err := h.repo.ListDeleted(r.Context())
+		if err != nil {
+			InternalError(w, r)
+			return
+		}
+		OK(w, r, folders)
+		return
+	}
+
 	// Check if tree format is requested
 	tree := r.URL.Query().Get("tree") == "true"
 
@@ -58,7 +70,7 @@ func (h *FolderHandler) List(w http.ResponseWriter, r *http.Request) {
 func (h *FolderHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var input models.FolderInput
 	if err := DecodeJSON(r, &input); err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON payload")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
 		return
 	}
 
@@ -99,7 +111,7 @@ func (h *FolderHandler) Create(w http.ResponseWriter, r *http.Request) {
 func (h *FolderHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_ID", "Invalid folder ID")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid folder ID")
 		return
 	}
 
@@ -126,13 +138,13 @@ func (h *FolderHandler) Get(w http.ResponseWriter, r *http.Request) {
 func (h *FolderHandler) Update(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_ID", "Invalid folder ID")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid folder ID")
 		return
 	}
 
 	var input models.FolderInput
 	if err := DecodeJSON(r, &input); err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON payload")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
 		return
 	}
 
@@ -178,15 +190,39 @@ func (h *FolderHandler) Update(w http.ResponseWriter, r *http.Request) {
 	OK(w, r, folder)
 }
 
-// Delete handles DELETE /api/v1/folders/{id}
+// Delete handles DELETE /api/v1/folders/{id}. A folder in the trash is
+// deleted for good with permanent=true.
 func (h *FolderHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_ID", "Invalid folder ID")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid folder ID")
+		return
+	}
+
+	if r.URL.Query().Get("permanent") == "true" {
+		if err := h.repo.Purge(r.Context(), id); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				NotFound(w, r, "Folder not found in the trash")
+				return
+			}
+			InternalError(w, r)
+			return
+		}
+		NoContent(w)
 		return
 	}
 
-	err = h.repo.Delete(r.Context(), id)
+	mode := r.URL.Query().Get("mode")
+	switch mode {
+	case "":
+		mode = models.FolderDeleteMoveToParent
+	case models.FolderDeleteMoveToParent, models.FolderDeleteOrphan, models.FolderDeleteTrash:
+	default:
+		Error(w, r, http.StatusBadRequest, apierror.InvalidMode, "mode must be move_to_parent, orphan or trash")
+		return
+	}
+
+	err = h.repo.Delete(r.Context(), id, mode)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			NotFound(w, r, "Folder not found")
@@ -199,6 +235,36 @@ func (h *FolderHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	NoContent(w)
 }
 
+// Restore handles POST /api/v1/folders/{id}/restore, bringing back the
+// subfolders and snippets deleted with the folder
+func (h *FolderHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid folder ID")
+		return
+	}
+
+	if err := h.repo.Restore(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			NotFound(w, r, "Folder not found in the trash")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	folder, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+	if count, err := h.repo.GetFolderSnippetCount(r.Context(), folder.ID); err == nil {
+		folder.SnippetCount = count
+	}
+
+	OK(w, r, folder)
+}
+
 // MoveRequest represents a request to move a folder
 type MoveRequest struct {
 	ParentID *int64 `json:"parent_id"`
@@ -208,13 +274,13 @@ type MoveRequest struct {
 func (h *FolderHandler) Move(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_ID", "Invalid folder ID")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid folder ID")
 		return
 	}
 
 	var req MoveRequest
 	if err := DecodeJSON(r, &req); err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON payload")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
 		return
 	}
 
@@ -254,3 +320,67 @@ func (h *FolderHandler) Move(w http.ResponseWriter, r *http.Request) {
 
 	OK(w, r, folder)
 }
+
+// GetAccess handles GET /api/v1/folders/{id}/access
+func (h *FolderHandler) GetAccess(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid folder ID")
+		return
+	}
+
+	access, err := h.repo.GetAccess(r.Context(), id)
+	if err != nil {
+		folderAccessError(w, r, err)
+		return
+	}
+
+	OK(w, r, access)
+}
+
+// SetAccess handles PUT /api/v1/folders/{id}/access
+// Restricts the folder to the given users and roles; empty lists open it up
+// to every member again.
+func (h *FolderHandler) SetAccess(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid folder ID")
+		return
+	}
+
+	var input models.FolderAccess
+	if err := DecodeJSON(r, &input); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	if errs := validation.ValidateFolderAccess(&input); errs.HasErrors() {
+		ValidationErrors(w, r, errs)
+		return
+	}
+
+	if err := h.repo.SetAccess(r.Context(), id, &input); err != nil {
+		folderAccessError(w, r, err)
+		return
+	}
+
+	access, err := h.repo.GetAccess(r.Context(), id)
+	if err != nil {
+		folderAccessError(w, r, err)
+		return
+	}
+
+	OK(w, r, access)
+}
+
+// folderAccessError writes the response for a failed folder access lookup or change
+func folderAccessError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		NotFound(w, r, "Folder not found")
+	case errors.Is(err, repository.ErrForbidden):
+		Error(w, r, http.StatusForbidden, apierror.Forbidden, "Only the folder's creator and workspace admins can manage its access")
+	default:
+		InternalError(w, r)
+	}
+}
@@ -2,12 +2,19 @@ package handlers
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
 	"github.com/MohamedElashri/snipo/internal/models"
 	"github.com/MohamedElashri/snipo/internal/repository"
 	"github.com/MohamedElashri/snipo/internal/services"
@@ -20,6 +27,8 @@ type GistSyncHandler struct {
 	snippetRepo   *repository.SnippetRepository
 	fileRepo      *repository.SnippetFileRepository
 	encryptionSvc *services.EncryptionService
+	githubAPIURL  string
+	webhookSecret string
 }
 
 // NewGistSyncHandler creates a new gist sync handler
@@ -37,6 +46,18 @@ func NewGistSyncHandler(
 	}
 }
 
+// WithGitHubAPIURL overrides the GitHub API root used for syncing
+func (h *GistSyncHandler) WithGitHubAPIURL(url string) *GistSyncHandler {
+	h.githubAPIURL = url
+	return h
+}
+
+// WithWebhookSecret sets the secret gist webhook deliveries are signed with
+func (h *GistSyncHandler) WithWebhookSecret(secret string) *GistSyncHandler {
+	h.webhookSecret = secret
+	return h
+}
+
 // ConfigInput represents the input for configuring gist sync
 type ConfigInput struct {
 	Enabled                    bool   `json:"enabled"`
@@ -48,13 +69,14 @@ type ConfigInput struct {
 
 // ConfigResponse represents the gist sync configuration response (token masked)
 type ConfigResponse struct {
-	Enabled                    bool   `json:"enabled"`
-	GithubUsername             string `json:"github_username"`
-	HasToken                   bool   `json:"has_token"`
-	AutoSyncEnabled            bool   `json:"auto_sync_enabled"`
-	SyncIntervalMinutes        int    `json:"sync_interval_minutes"`
-	ConflictResolutionStrategy string `json:"conflict_resolution_strategy"`
-	LastFullSyncAt             string `json:"last_full_sync_at,omitempty"`
+	Enabled                    bool                    `json:"enabled"`
+	GithubUsername             string                  `json:"github_username"`
+	HasToken                   bool                    `json:"has_token"`
+	AutoSyncEnabled            bool                    `json:"auto_sync_enabled"`
+	SyncIntervalMinutes        int                     `json:"sync_interval_minutes"`
+	ConflictResolutionStrategy string                  `json:"conflict_resolution_strategy"`
+	LastFullSyncAt             string                  `json:"last_full_sync_at,omitempty"`
+	RateLimit                  *models.GitHubRateLimit `json:"rate_limit,omitempty"` // GitHub API quota as of the last sync
 }
 
 // GetConfig retrieves the gist sync configuration
@@ -83,6 +105,7 @@ func (h *GistSyncHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
 		AutoSyncEnabled:            config.AutoSyncEnabled,
 		SyncIntervalMinutes:        config.SyncIntervalMinutes,
 		ConflictResolutionStrategy: config.ConflictResolutionStrategy,
+		RateLimit:                  config.RateLimit,
 	}
 
 	if config.LastFullSyncAt != nil {
@@ -96,12 +119,12 @@ func (h *GistSyncHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
 func (h *GistSyncHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 	var input ConfigInput
 	if err := DecodeJSON(r, &input); err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_JSON", "Invalid request body")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid request body")
 		return
 	}
 
 	if input.SyncIntervalMinutes < 5 {
-		Error(w, r, http.StatusBadRequest, "INVALID_INTERVAL", "Sync interval must be at least 5 minutes")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidInterval, "Sync interval must be at least 5 minutes")
 		return
 	}
 
@@ -112,7 +135,7 @@ func (h *GistSyncHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 		models.ConflictStrategyNewestWins: true,
 	}
 	if !validStrategies[input.ConflictResolutionStrategy] {
-		Error(w, r, http.StatusBadRequest, "INVALID_STRATEGY", "Invalid conflict resolution strategy")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidStrategy, "Invalid conflict resolution strategy")
 		return
 	}
 
@@ -120,7 +143,7 @@ func (h *GistSyncHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 	var username string
 
 	if input.GithubToken != "" {
-		githubClient := services.NewGitHubClient(input.GithubToken)
+		githubClient := services.NewGitHubClient(input.GithubToken).WithBaseURL(h.githubAPIURL)
 		var err error
 		username, err = githubClient.GetAuthenticatedUser(r.Context())
 		if err != nil {
@@ -130,7 +153,7 @@ func (h *GistSyncHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 					"error", err,
 					"token_prefix", input.GithubToken[:min(10, len(input.GithubToken))])
 			}
-			Error(w, r, http.StatusBadRequest, "INVALID_TOKEN", fmt.Sprintf("Failed to validate GitHub token: %v", err))
+			Error(w, r, http.StatusBadRequest, apierror.InvalidToken, fmt.Sprintf("Failed to validate GitHub token: %v", err))
 			return
 		}
 
@@ -183,7 +206,7 @@ func (h *GistSyncHandler) TestConnection(w http.ResponseWriter, r *http.Request)
 	}
 
 	if config == nil || config.GithubTokenEncrypted == "" {
-		Error(w, r, http.StatusBadRequest, "NO_TOKEN", "No GitHub token configured")
+		Error(w, r, http.StatusBadRequest, apierror.NoToken, "No GitHub token configured")
 		return
 	}
 
@@ -193,17 +216,18 @@ func (h *GistSyncHandler) TestConnection(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	githubClient := services.NewGitHubClient(token)
+	githubClient := services.NewGitHubClient(token).WithBaseURL(h.githubAPIURL)
 	username, err := githubClient.GetAuthenticatedUser(r.Context())
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_TOKEN", "GitHub token is invalid or expired")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidToken, "GitHub token is invalid or expired")
 		return
 	}
 
 	OK(w, r, map[string]interface{}{
-		"valid":    true,
-		"username": username,
-		"message":  "Connection successful",
+		"valid":      true,
+		"username":   username,
+		"message":    "Connection successful",
+		"rate_limit": githubClient.RateLimit(),
 	})
 }
 
@@ -223,22 +247,22 @@ func (h *GistSyncHandler) ClearConfig(w http.ResponseWriter, r *http.Request) {
 func (h *GistSyncHandler) SyncSnippet(w http.ResponseWriter, r *http.Request) {
 	snippetID := chi.URLParam(r, "id")
 	if snippetID == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_ID", "Snippet ID is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
 		return
 	}
 
 	syncService, err := h.createSyncService(r.Context())
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "SYNC_NOT_CONFIGURED", err.Error())
+		Error(w, r, http.StatusBadRequest, apierror.SyncNotConfigured, err.Error())
 		return
 	}
 
 	if err := syncService.SyncSnippetToGist(r.Context(), snippetID); err != nil {
 		if strings.Contains(err.Error(), "was deleted on GitHub") {
-			Error(w, r, http.StatusGone, "GIST_DELETED", "The gist was deleted on GitHub. The sync mapping has been removed.")
+			Error(w, r, http.StatusGone, apierror.GistDeleted, "The gist was deleted on GitHub. The sync mapping has been removed.")
 			return
 		}
-		Error(w, r, http.StatusInternalServerError, "SYNC_FAILED", err.Error())
+		Error(w, r, http.StatusInternalServerError, apierror.SyncFailed, err.Error())
 		return
 	}
 
@@ -251,13 +275,13 @@ func (h *GistSyncHandler) SyncSnippet(w http.ResponseWriter, r *http.Request) {
 func (h *GistSyncHandler) SyncAll(w http.ResponseWriter, r *http.Request) {
 	syncService, err := h.createSyncService(r.Context())
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "SYNC_NOT_CONFIGURED", err.Error())
+		Error(w, r, http.StatusBadRequest, apierror.SyncNotConfigured, err.Error())
 		return
 	}
 
 	result, err := syncService.SyncAll(r.Context())
 	if err != nil {
-		Error(w, r, http.StatusInternalServerError, "SYNC_FAILED", err.Error())
+		Error(w, r, http.StatusInternalServerError, apierror.SyncFailed, err.Error())
 		return
 	}
 
@@ -268,18 +292,18 @@ func (h *GistSyncHandler) SyncAll(w http.ResponseWriter, r *http.Request) {
 func (h *GistSyncHandler) EnableSync(w http.ResponseWriter, r *http.Request) {
 	snippetID := chi.URLParam(r, "id")
 	if snippetID == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_ID", "Snippet ID is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
 		return
 	}
 
 	syncService, err := h.createSyncService(r.Context())
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "SYNC_NOT_CONFIGURED", err.Error())
+		Error(w, r, http.StatusBadRequest, apierror.SyncNotConfigured, err.Error())
 		return
 	}
 
 	if err := syncService.EnableSyncForSnippet(r.Context(), snippetID); err != nil {
-		Error(w, r, http.StatusInternalServerError, "ENABLE_FAILED", err.Error())
+		Error(w, r, http.StatusInternalServerError, apierror.EnableFailed, err.Error())
 		return
 	}
 
@@ -292,18 +316,18 @@ func (h *GistSyncHandler) EnableSync(w http.ResponseWriter, r *http.Request) {
 func (h *GistSyncHandler) DisableSync(w http.ResponseWriter, r *http.Request) {
 	snippetID := chi.URLParam(r, "id")
 	if snippetID == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_ID", "Snippet ID is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
 		return
 	}
 
 	syncService, err := h.createSyncService(r.Context())
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "SYNC_NOT_CONFIGURED", err.Error())
+		Error(w, r, http.StatusBadRequest, apierror.SyncNotConfigured, err.Error())
 		return
 	}
 
 	if err := syncService.DisableSyncForSnippet(r.Context(), snippetID); err != nil {
-		Error(w, r, http.StatusInternalServerError, "DISABLE_FAILED", err.Error())
+		Error(w, r, http.StatusInternalServerError, apierror.DisableFailed, err.Error())
 		return
 	}
 
@@ -316,7 +340,7 @@ func (h *GistSyncHandler) DisableSync(w http.ResponseWriter, r *http.Request) {
 func (h *GistSyncHandler) EnableSyncForAll(w http.ResponseWriter, r *http.Request) {
 	syncService, err := h.createSyncService(r.Context())
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "SYNC_NOT_CONFIGURED", err.Error())
+		Error(w, r, http.StatusBadRequest, apierror.SyncNotConfigured, err.Error())
 		return
 	}
 
@@ -325,7 +349,7 @@ func (h *GistSyncHandler) EnableSyncForAll(w http.ResponseWriter, r *http.Reques
 		Limit: 10000, // High limit to get all snippets
 	})
 	if err != nil {
-		Error(w, r, http.StatusInternalServerError, "FETCH_FAILED", "Failed to fetch snippets")
+		Error(w, r, http.StatusInternalServerError, apierror.FetchFailed, "Failed to fetch snippets")
 		return
 	}
 
@@ -364,7 +388,7 @@ func (h *GistSyncHandler) VerifyMappings(w http.ResponseWriter, r *http.Request)
 
 	removed, err := syncService.VerifyMappings(r.Context())
 	if err != nil {
-		Error(w, r, http.StatusInternalServerError, "VERIFY_FAILED", err.Error())
+		Error(w, r, http.StatusInternalServerError, apierror.VerifyFailed, err.Error())
 		return
 	}
 
@@ -374,7 +398,7 @@ func (h *GistSyncHandler) VerifyMappings(w http.ResponseWriter, r *http.Request)
 	})
 }
 
-// ListMappings lists all snippet-gist mappings
+// ListMappings lists the caller's snippet-gist mappings
 func (h *GistSyncHandler) ListMappings(w http.ResponseWriter, r *http.Request) {
 	mappings, err := h.syncRepo.ListMappings(r.Context())
 	if err != nil {
@@ -390,11 +414,15 @@ func (h *GistSyncHandler) DeleteMapping(w http.ResponseWriter, r *http.Request)
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_ID", "Invalid mapping ID")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid mapping ID")
 		return
 	}
 
 	if err := h.syncRepo.DeleteMapping(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			NotFound(w, r, "Mapping not found")
+			return
+		}
 		InternalError(w, r)
 		return
 	}
@@ -404,7 +432,7 @@ func (h *GistSyncHandler) DeleteMapping(w http.ResponseWriter, r *http.Request)
 	})
 }
 
-// ListConflicts lists all unresolved conflicts
+// ListConflicts lists the unresolved conflicts of the caller's snippets
 func (h *GistSyncHandler) ListConflicts(w http.ResponseWriter, r *http.Request) {
 	conflicts, err := h.syncRepo.ListConflicts(r.Context(), false)
 	if err != nil {
@@ -420,7 +448,7 @@ func (h *GistSyncHandler) ResolveConflict(w http.ResponseWriter, r *http.Request
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_ID", "Invalid conflict ID")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid conflict ID")
 		return
 	}
 
@@ -428,7 +456,7 @@ func (h *GistSyncHandler) ResolveConflict(w http.ResponseWriter, r *http.Request
 		Resolution string `json:"resolution"`
 	}
 	if err := DecodeJSON(r, &input); err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_JSON", "Invalid request body")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid request body")
 		return
 	}
 
@@ -437,18 +465,22 @@ func (h *GistSyncHandler) ResolveConflict(w http.ResponseWriter, r *http.Request
 		models.ConflictStrategyGistWins:  true,
 	}
 	if !validResolutions[input.Resolution] {
-		Error(w, r, http.StatusBadRequest, "INVALID_RESOLUTION", "Invalid resolution choice")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidResolution, "Invalid resolution choice")
 		return
 	}
 
 	syncService, err := h.createSyncService(r.Context())
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "SYNC_NOT_CONFIGURED", err.Error())
+		Error(w, r, http.StatusBadRequest, apierror.SyncNotConfigured, err.Error())
 		return
 	}
 
 	if err := syncService.ResolveConflict(r.Context(), id, input.Resolution); err != nil {
-		Error(w, r, http.StatusInternalServerError, "RESOLVE_FAILED", err.Error())
+		if errors.Is(err, services.ErrConflictNotFound) {
+			NotFound(w, r, "Conflict not found")
+			return
+		}
+		Error(w, r, http.StatusInternalServerError, apierror.ResolveFailed, err.Error())
 		return
 	}
 
@@ -491,6 +523,104 @@ func (h *GistSyncHandler) createSyncService(ctx context.Context) (*services.Gist
 		return nil, fmt.Errorf("failed to decrypt token: %w", err)
 	}
 
-	githubClient := services.NewGitHubClient(token)
+	githubClient := services.NewGitHubClient(token).WithBaseURL(h.githubAPIURL)
 	return services.NewGistSyncService(githubClient, h.snippetRepo, h.fileRepo, h.syncRepo, h.encryptionSvc), nil
 }
+
+// gistWebhookMaxBody caps webhook payloads; only the gist ID is read from them
+const gistWebhookMaxBody = 1 << 20
+
+// gistWebhookPayload names the gist that changed. GitHub gist events carry
+// it as gist.id; relays can send gist_id instead.
+type gistWebhookPayload struct {
+	Gist struct {
+		ID string `json:"id"`
+	} `json:"gist"`
+	GistID string `json:"gist_id"`
+}
+
+// gistWebhookResults describes what a webhook delivery did to the mapping
+var gistWebhookResults = map[models.SyncDirection]string{
+	models.NoSync:      "unchanged",
+	models.SnipoToGist: "pushed",
+	models.GistToSnipo: "pulled",
+	models.Conflict:    "conflict",
+	models.GistDeleted: "unlinked",
+	models.Merged:      "merged",
+}
+
+// Webhook handles POST /api/v1/gist/webhook
+// Public endpoint authenticated by the X-Hub-Signature-256 HMAC of the body.
+// Syncs the snippet mapped to the gist right away instead of at the next
+// sync interval.
+func (h *GistSyncHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, gistWebhookMaxBody))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			Error(w, r, http.StatusRequestEntityTooLarge, apierror.PayloadTooLarge, "Payload must be less than 1MB")
+			return
+		}
+		Error(w, r, http.StatusBadRequest, apierror.ReadError, "Failed to read payload")
+		return
+	}
+
+	if !h.validSignature(r.Header.Get("X-Hub-Signature-256"), body) {
+		Error(w, r, http.StatusUnauthorized, apierror.Unauthorized, "Invalid webhook signature")
+		return
+	}
+
+	// GitHub pings a webhook once when it is created
+	if r.Header.Get("X-GitHub-Event") == "ping" {
+		OK(w, r, map[string]string{"message": "pong"})
+		return
+	}
+
+	var payload gistWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+	gistID := payload.Gist.ID
+	if gistID == "" {
+		gistID = payload.GistID
+	}
+	if gistID == "" {
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Gist ID is required")
+		return
+	}
+
+	syncService, err := h.createSyncService(r.Context())
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.SyncNotConfigured, err.Error())
+		return
+	}
+
+	direction, err := syncService.SyncGist(r.Context(), gistID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrGistSyncDisabled):
+			Error(w, r, http.StatusBadRequest, apierror.SyncNotConfigured, "Gist sync is not enabled")
+		case errors.Is(err, services.ErrGistNotMapped):
+			NotFound(w, r, "No snippet is synced with this gist")
+		default:
+			Error(w, r, http.StatusInternalServerError, apierror.SyncFailed, "Failed to sync gist")
+		}
+		return
+	}
+
+	OK(w, r, map[string]string{
+		"gist_id": gistID,
+		"result":  gistWebhookResults[direction],
+	})
+}
+
+// validSignature checks a GitHub X-Hub-Signature-256 header against body
+func (h *GistSyncHandler) validSignature(signature string, body []byte) bool {
+	if h.webhookSecret == "" || signature == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(h.webhookSecret))
+	mac.Write(body)
+	return hmac.Equal([]byte(signature), []byte("sha256="+hex.EncodeToString(mac.Sum(nil))))
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestGistSyncHandler_Webhook(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	snippetRepo := repository.NewSnippetRepository(db)
+	fileRepo := repository.NewSnippetFileRepository(db)
+	syncRepo := repository.NewGistSyncRepository(db)
+	encryptionSvc, err := services.NewEncryptionService(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("NewEncryptionService failed: %v", err)
+	}
+
+	github := testutil.NewFakeGitHub(t)
+	token, err := encryptionSvc.Encrypt("ghp_test")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if err := syncRepo.CreateOrUpdateConfig(ctx, &models.GistSyncConfig{
+		Enabled:                    true,
+		GithubTokenEncrypted:       token,
+		SyncIntervalMinutes:        15,
+		ConflictResolutionStrategy: models.ConflictStrategyManual,
+	}); err != nil {
+		t.Fatalf("CreateOrUpdateConfig failed: %v", err)
+	}
+
+	snippetSvc := services.NewSnippetService(snippetRepo, testutil.TestLogger()).WithFileRepo(fileRepo)
+	snippet, err := snippetSvc.Create(ctx, &models.SnippetInput{
+		Title: "Synced",
+		Files: []models.SnippetFileInput{{Filename: "main.go", Content: "package main", Language: "go"}},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	client := services.NewGitHubClient("ghp_test").WithBaseURL(github.URL())
+	if err := services.NewGistSyncService(client, snippetRepo, fileRepo, syncRepo, encryptionSvc).SyncSnippetToGist(ctx, snippet.ID); err != nil {
+		t.Fatalf("SyncSnippetToGist failed: %v", err)
+	}
+	mapping, err := syncRepo.GetMapping(ctx, snippet.ID)
+	if err != nil || mapping == nil {
+		t.Fatalf("expected a mapping, got %v (err %v)", mapping, err)
+	}
+
+	handler := NewGistSyncHandler(syncRepo, snippetRepo, fileRepo, encryptionSvc).
+		WithGitHubAPIURL(github.URL()).
+		WithWebhookSecret("hook-secret")
+
+	deliver := func(event, body, secret string) *httptest.ResponseRecorder {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/gist/webhook", strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		rec := httptest.NewRecorder()
+		handler.Webhook(rec, withRequestID(req))
+		return rec
+	}
+	payload := `{"action":"updated","gist":{"id":"` + mapping.GistID + `"}}`
+
+	if rec := deliver("gist", payload, "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a bad signature, got %d", rec.Code)
+	}
+	if rec := deliver("ping", `{"zen":"Keep it simple."}`, "hook-secret"); rec.Code != http.StatusOK {
+		t.Errorf("expected ping to succeed, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := deliver("gist", `{"gist":{"id":"unknown"}}`, "hook-secret"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a gist without a mapping, got %d", rec.Code)
+	}
+	if rec := deliver("gist", `{}`, "hook-secret"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a gist ID, got %d", rec.Code)
+	}
+
+	github.EditFile(mapping.GistID, "main.go", "package main // remote")
+	rec := deliver("gist", payload, "hook-secret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Data["result"] != "pulled" {
+		t.Errorf("expected the gist edit to be pulled, got %q", resp.Data["result"])
+	}
+	updated, err := snippetSvc.GetByID(ctx, snippet.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if len(updated.Files) != 1 || updated.Files[0].Content != "package main // remote" {
+		t.Errorf("expected the snippet to have the gist edit, got %+v", updated.Files)
+	}
+
+	// A second delivery for the same change finds nothing to do
+	rec = deliver("gist", payload, "hook-secret")
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Data["result"] != "unchanged" {
+		t.Errorf("expected the repeat delivery to change nothing, got %s", rec.Body)
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/services"
+)
+
+// GitHubExportHandler handles publishing snippets to a GitHub repository
+type GitHubExportHandler struct {
+	syncRepo      *repository.GistSyncRepository
+	snippetSvc    services.SnippetManager
+	folderRepo    *repository.FolderRepository
+	encryptionSvc *services.EncryptionService
+	githubAPIURL  string
+	logger        *slog.Logger
+}
+
+// NewGitHubExportHandler creates a new GitHub export handler
+func NewGitHubExportHandler(
+	syncRepo *repository.GistSyncRepository,
+	snippetSvc services.SnippetManager,
+	folderRepo *repository.FolderRepository,
+	encryptionSvc *services.EncryptionService,
+	logger *slog.Logger,
+) *GitHubExportHandler {
+	return &GitHubExportHandler{
+		syncRepo:      syncRepo,
+		snippetSvc:    snippetSvc,
+		folderRepo:    folderRepo,
+		encryptionSvc: encryptionSvc,
+		logger:        logger,
+	}
+}
+
+// WithGitHubAPIURL overrides the GitHub API root used for exports
+func (h *GitHubExportHandler) WithGitHubAPIURL(url string) *GitHubExportHandler {
+	h.githubAPIURL = url
+	return h
+}
+
+// Export handles POST /api/v1/export/github
+// Publishes all snippets to a GitHub repository using the configured gist sync token
+func (h *GitHubExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	var req models.GitHubExportRequest
+	if err := DecodeJSON(r, &req); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON body")
+		return
+	}
+
+	if req.Repo == "" {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidRepo, "Repository name is required")
+		return
+	}
+
+	config, err := h.syncRepo.GetConfig(r.Context())
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+	if config == nil || config.GithubTokenEncrypted == "" {
+		Error(w, r, http.StatusBadRequest, apierror.NoToken, "No GitHub token configured")
+		return
+	}
+
+	token, err := h.encryptionSvc.Decrypt(config.GithubTokenEncrypted)
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	exportSvc := services.NewGitHubExportService(services.NewGitHubClient(token).WithBaseURL(h.githubAPIURL), h.snippetSvc, h.folderRepo, h.logger)
+	result, err := exportSvc.Export(r.Context(), config.GithubUsername, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRepoName) {
+			Error(w, r, http.StatusBadRequest, apierror.InvalidRepo, "Repository must be \"name\" or \"owner/name\"")
+			return
+		}
+		Error(w, r, http.StatusBadGateway, apierror.ExportFailed, err.Error())
+		return
+	}
+
+	OK(w, r, result)
+}
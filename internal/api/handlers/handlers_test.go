@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -42,6 +43,7 @@ type testPagination struct {
 	Limit      int                  `json:"limit"`
 	Total      int                  `json:"total"`
 	TotalPages int                  `json:"totalPages"`
+	NextCursor string               `json:"next_cursor,omitempty"`
 	Links      *testPaginationLinks `json:"links,omitempty"`
 }
 
@@ -352,6 +354,95 @@ func TestSnippetHandler_List_WithPagination(t *testing.T) {
 	}
 }
 
+func TestSnippetHandler_List_WithCursor(t *testing.T) {
+	handler, repo := setupSnippetHandler(t)
+	ctx := testutil.TestContext()
+
+	for i := 0; i < 5; i++ {
+		if _, err := repo.Create(ctx, &models.SnippetInput{Title: "Snippet", Content: "content", Language: "plaintext"}); err != nil {
+			t.Fatalf("failed to create snippet: %v", err)
+		}
+	}
+
+	list := func(query string) (*httptest.ResponseRecorder, testListResponse) {
+		req := withRequestID(httptest.NewRequest(http.MethodGet, "/api/v1/snippets?"+query, nil))
+		w := httptest.NewRecorder()
+		handler.List(w, req)
+		var envelope testListResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &envelope)
+		return w, envelope
+	}
+
+	w, first := list("limit=3")
+	if w.Code != http.StatusOK || first.Pagination == nil || first.Pagination.NextCursor == "" {
+		t.Fatalf("expected a next cursor on the first page, got %d %s", w.Code, w.Body.String())
+	}
+
+	w, second := list("limit=3&cursor=" + first.Pagination.NextCursor)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if items, _ := second.Data.([]interface{}); len(items) != 2 {
+		t.Errorf("expected the remaining 2 snippets, got %d", len(items))
+	}
+	if second.Pagination.NextCursor != "" || second.Pagination.Links.Next != nil {
+		t.Error("expected no next cursor or link on the last page")
+	}
+
+	w, _ = list("limit=3&cursor=bogus")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_CURSOR") {
+		t.Errorf("expected INVALID_CURSOR, got %d %s", w.Code, w.Body.String())
+	}
+}
+func TestSnippetHandler_List_Sorting(t *testing.T) {
+	handler, repo := setupSnippetHandler(t)
+	ctx := testutil.TestContext()
+
+	for _, title := range []string{"Beta", "alpha", "Gamma"} {
+		if _, err := repo.Create(ctx, &models.SnippetInput{Title: title, Content: "content", Language: "plaintext"}); err != nil {
+			t.Fatalf("failed to create snippet: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		first string
+	}{
+		{"sort_by ascending", "sort_by=title&sort_order=asc", "alpha"},
+		{"sort_by descending", "sort_by=title&sort_order=desc", "Gamma"},
+		{"legacy params", "sort=title&order=asc", "alpha"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/snippets?"+tt.query, nil)
+			req = withRequestID(req)
+			w := httptest.NewRecorder()
+
+			handler.List(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+
+			var envelope testListResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			dataBytes, _ := json.Marshal(envelope.Data)
+			var snippets []models.Snippet
+			if err := json.Unmarshal(dataBytes, &snippets); err != nil {
+				t.Fatalf("failed to unmarshal data: %v", err)
+			}
+
+			if len(snippets) != 3 || snippets[0].Title != tt.first {
+				t.Errorf("expected %q first, got %+v", tt.first, snippets)
+			}
+		})
+	}
+}
+
 func TestSnippetHandler_Update(t *testing.T) {
 	handler, repo := setupSnippetHandler(t)
 	ctx := testutil.TestContext()
@@ -436,6 +527,52 @@ func TestSnippetHandler_Delete(t *testing.T) {
 	}
 }
 
+func TestSnippetHandler_PublicFeed(t *testing.T) {
+	handler, repo := setupSnippetHandler(t)
+	ctx := testutil.TestContext()
+
+	for _, input := range []models.SnippetInput{
+		{Title: "Shared", Content: "a", Language: "go", IsPublic: true},
+		{Title: "Private", Content: "b", Language: "go"},
+	} {
+		if _, err := repo.Create(ctx, &input); err != nil {
+			t.Fatalf("failed to create snippet: %v", err)
+		}
+	}
+
+	req := withRequestID(httptest.NewRequest(http.MethodGet, "/api/v1/snippets/public", nil))
+	w := httptest.NewRecorder()
+	handler.PublicFeed(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp struct {
+		Data []models.Snippet `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].Title != "Shared" {
+		t.Errorf("expected only the public snippet, got %+v", resp.Data)
+	}
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag header")
+	}
+
+	req = withRequestID(httptest.NewRequest(http.MethodGet, "/api/v1/snippets/public", nil))
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	handler.PublicFeed(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status %d, got %d", http.StatusNotModified, w.Code)
+	}
+}
+
 func TestSnippetHandler_ToggleFavorite(t *testing.T) {
 	handler, repo := setupSnippetHandler(t)
 	ctx := testutil.TestContext()
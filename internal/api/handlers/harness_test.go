@@ -0,0 +1,613 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/api/middleware"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+	"github.com/MohamedElashri/snipo/internal/testutil/apitest"
+)
+
+// The tests in this file mount handlers on the apitest harness with the same
+// permission middleware as the real router, backed by in-memory fakes.
+
+func newSnippetHarness(t *testing.T) (*apitest.Harness, *apitest.SnippetManager) {
+	t.Helper()
+	h := apitest.New(t)
+	svc := apitest.NewSnippetManager()
+	handler := NewSnippetHandler(svc)
+	snippetsRead := middleware.RequireScope(models.ScopeSnippetsRead)
+	snippetsWrite := middleware.RequireScope(models.ScopeSnippetsWrite)
+
+	h.Router.Route("/api/v1/snippets", func(r chi.Router) {
+		r.Get("/public/{id}/files/{filename}", handler.GetPublicFile)
+		r.With(snippetsRead).Get("/", handler.List)
+		r.With(snippetsWrite).Post("/", handler.Create)
+		r.With(snippetsRead).Post("/check-duplicates", handler.CheckDuplicates)
+		r.With(snippetsWrite).Post("/bulk", handler.Bulk)
+		r.With(snippetsWrite).Put("/external/{external_id}", handler.Upsert)
+		r.Route("/{id}", func(r chi.Router) {
+			r.With(snippetsRead).Get("/", handler.Get)
+			r.With(snippetsWrite).Put("/", handler.Update)
+			r.With(snippetsWrite).Delete("/", handler.Delete)
+			r.With(snippetsWrite).Post("/favorite", handler.ToggleFavorite)
+			r.With(snippetsRead).Post("/used", handler.MarkUsed)
+			r.With(snippetsRead).Get("/views", handler.GetViewStats)
+			r.With(snippetsRead).Get("/neighbors", handler.Neighbors)
+			r.With(snippetsRead).Get("/history/{from}/diff/{to}", handler.DiffHistory)
+		})
+	})
+	return h, svc
+}
+
+func TestHarness_SnippetCRUD(t *testing.T) {
+	h, _ := newSnippetHarness(t)
+
+	var created models.Snippet
+	h.Post("/api/v1/snippets", models.SnippetInput{Title: "Hello", Content: "echo hi", Language: "bash"}).
+		ExpectStatus(http.StatusCreated).
+		Decode(&created)
+
+	var fetched models.Snippet
+	h.Get("/api/v1/snippets/" + created.ID).ExpectStatus(http.StatusOK).Decode(&fetched)
+	if fetched.Title != "Hello" {
+		t.Errorf("expected title Hello, got %q", fetched.Title)
+	}
+
+	h.Put("/api/v1/snippets/"+created.ID, models.SnippetInput{Title: "Renamed", Content: "echo hi"}).
+		ExpectStatus(http.StatusOK)
+
+	var favorite models.Snippet
+	h.Post("/api/v1/snippets/"+created.ID+"/favorite", nil).ExpectStatus(http.StatusOK).Decode(&favorite)
+	if !favorite.IsFavorite {
+		t.Error("expected snippet to be favorited")
+	}
+
+	var list []models.Snippet
+	h.Get("/api/v1/snippets").ExpectStatus(http.StatusOK).Decode(&list)
+	if len(list) != 1 || list[0].Title != "Renamed" {
+		t.Errorf("expected renamed snippet in list, got %+v", list)
+	}
+
+	h.Delete("/api/v1/snippets/" + created.ID).ExpectStatus(http.StatusNoContent)
+	h.Get("/api/v1/snippets/does-not-exist").ExpectStatus(http.StatusNotFound)
+}
+
+func TestHarness_SnippetUpsert(t *testing.T) {
+	h, _ := newSnippetHarness(t)
+
+	var created models.Snippet
+	h.Put("/api/v1/snippets/external/ops%2Frunbook", models.SnippetInput{Title: "Runbook", Content: "v1"}).
+		ExpectStatus(http.StatusCreated).
+		Decode(&created)
+	if created.ExternalID == nil || *created.ExternalID != "ops/runbook" {
+		t.Fatalf("expected external ID ops/runbook, got %v", created.ExternalID)
+	}
+
+	var updated models.Snippet
+	h.Put("/api/v1/snippets/external/ops%2Frunbook", models.SnippetInput{Title: "Runbook", Content: "v2"}).
+		ExpectStatus(http.StatusOK).
+		Decode(&updated)
+	if updated.ID != created.ID || updated.Content != "v2" {
+		t.Errorf("expected %s to be updated, got %+v", created.ID, updated)
+	}
+
+	var list []models.Snippet
+	h.Get("/api/v1/snippets").ExpectStatus(http.StatusOK).Decode(&list)
+	if len(list) != 1 {
+		t.Errorf("expected one snippet after two upserts, got %d", len(list))
+	}
+
+	h.Put("/api/v1/snippets/external/ops%2Frunbook", models.SnippetInput{Content: "no title"}).
+		ExpectStatus(http.StatusBadRequest)
+}
+
+func TestHarness_PublicFileRange(t *testing.T) {
+	h, svc := newSnippetHarness(t)
+	shared, err := svc.Create(testutil.TestContext(), &models.SnippetInput{Title: "Shared", Content: "0123456789", IsPublic: true})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	target := "/api/v1/snippets/public/" + shared.ID + "/files/main.txt"
+
+	full := h.Get(target).ExpectStatus(http.StatusOK)
+	etag := full.Header().Get("ETag")
+	if full.Body.String() != "0123456789" || full.Header().Get("Accept-Ranges") != "bytes" || etag == "" {
+		t.Fatalf("unexpected full download %q with headers %v", full.Body.String(), full.Header())
+	}
+
+	req := apitest.NewRequest(t, http.MethodGet, target, nil)
+	req.Header.Set("Range", "bytes=4-")
+	req.Header.Set("If-Range", etag)
+	partial := h.Do(req).ExpectStatus(http.StatusPartialContent)
+	if partial.Body.String() != "456789" || partial.Header().Get("Content-Range") != "bytes 4-9/10" {
+		t.Errorf("unexpected partial download %q, Content-Range %q", partial.Body.String(), partial.Header().Get("Content-Range"))
+	}
+
+	// A resume against an older version starts over
+	req.Header.Set("If-Range", `"stale"`)
+	h.Do(req).ExpectStatus(http.StatusOK)
+
+	req = apitest.NewRequest(t, http.MethodGet, target, nil)
+	req.Header.Set("Range", "bytes=20-")
+	h.Do(req).ExpectStatus(http.StatusRequestedRangeNotSatisfiable)
+}
+
+func TestHarness_SnippetViewStats(t *testing.T) {
+	h, svc := newSnippetHarness(t)
+	ctx := testutil.TestContext()
+	shared, err := svc.Create(ctx, &models.SnippetInput{Title: "Shared", Content: "x", IsPublic: true})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := svc.RecordPublicView(ctx, shared.ID, "example.com"); err != nil {
+			t.Fatalf("RecordPublicView failed: %v", err)
+		}
+	}
+
+	var stats models.SnippetViewStats
+	h.Get("/api/v1/snippets/" + shared.ID + "/views").ExpectStatus(http.StatusOK).Decode(&stats)
+	if stats.PublicViewCount != 2 || stats.ViewCount != 0 {
+		t.Errorf("expected 2 public views and no API views, got %+v", stats)
+	}
+
+	h.Get("/api/v1/snippets/does-not-exist/views").ExpectStatus(http.StatusNotFound)
+}
+
+func TestHarness_SnippetNeighbors(t *testing.T) {
+	h, svc := newSnippetHarness(t)
+	ctx := testutil.TestContext()
+	var ids []string
+	for _, title := range []string{"One", "Two", "Three"} {
+		s, err := svc.Create(ctx, &models.SnippetInput{Title: title, Content: "x"})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		ids = append(ids, s.ID)
+	}
+
+	var neighbors models.SnippetNeighbors
+	h.Get("/api/v1/snippets/" + ids[1] + "/neighbors").ExpectStatus(http.StatusOK).Decode(&neighbors)
+	if neighbors.PrevID == nil || *neighbors.PrevID != ids[0] || neighbors.NextID == nil || *neighbors.NextID != ids[2] ||
+		neighbors.Position != 2 || neighbors.Total != 3 {
+		t.Errorf("unexpected neighbors %+v", neighbors)
+	}
+
+	h.Get("/api/v1/snippets/" + ids[0] + "/neighbors?q=t").ExpectStatus(http.StatusNotFound)
+}
+
+func TestHarness_SnippetDuplicates(t *testing.T) {
+	h, svc := newSnippetHarness(t)
+	existing, err := svc.Create(testutil.TestContext(), &models.SnippetInput{Title: "Deploy", Content: "make deploy\n"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	var matches []models.DuplicateMatch
+	h.Post("/api/v1/snippets/check-duplicates", models.DuplicateCheckInput{Title: "deploy", Content: "other"}).
+		ExpectStatus(http.StatusOK).Decode(&matches)
+	if len(matches) != 1 || matches[0].ID != existing.ID || matches[0].MatchedBy != "title" {
+		t.Errorf("expected a title match, got %+v", matches)
+	}
+
+	input := models.SnippetInput{Title: "Copy", Content: "make deploy"}
+	h.Post("/api/v1/snippets", input).ExpectStatus(http.StatusCreated)
+
+	svc.RejectDuplicateContent = true
+	resp := h.Post("/api/v1/snippets", input).ExpectStatus(http.StatusConflict)
+	var body struct {
+		Error struct {
+			Code       string `json:"code"`
+			ExistingID string `json:"existing_id"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error.Code != "DUPLICATE_CONTENT" || body.Error.ExistingID != existing.ID {
+		t.Errorf("unexpected error %+v", body.Error)
+	}
+}
+
+func TestHarness_SnippetValidation(t *testing.T) {
+	h, _ := newSnippetHarness(t)
+
+	resp := h.Post("/api/v1/snippets", models.SnippetInput{}).ExpectStatus(http.StatusBadRequest)
+	if code := resp.ErrorCode(); code != "VALIDATION_ERROR" {
+		t.Errorf("expected VALIDATION_ERROR, got %q", code)
+	}
+
+	resp = h.Post("/api/v1/snippets", `{"title":"x","unknown":true}`).ExpectStatus(http.StatusBadRequest)
+	if code := resp.ErrorCode(); code != "INVALID_JSON" {
+		t.Errorf("expected INVALID_JSON, got %q", code)
+	}
+}
+
+func TestHarness_SnippetBulk(t *testing.T) {
+	h, svc := newSnippetHarness(t)
+
+	var created models.BulkSnippetResult
+	h.Post("/api/v1/snippets/bulk", models.BulkSnippetInput{
+		Action:   models.BulkCreate,
+		Snippets: []models.SnippetInput{{Title: "One", Content: "1"}, {Title: "Two", Content: "2"}},
+	}).ExpectStatus(http.StatusCreated).Decode(&created)
+	if created.Affected != 2 || len(created.Snippets) != 2 {
+		t.Fatalf("expected 2 created snippets, got %+v", created)
+	}
+
+	var archived models.BulkSnippetResult
+	h.Post("/api/v1/snippets/bulk", models.BulkSnippetInput{Action: models.BulkArchive, IDs: created.IDs}).
+		ExpectStatus(http.StatusOK).Decode(&archived)
+	if archived.Affected != 2 {
+		t.Errorf("expected 2 archived snippets, got %+v", archived)
+	}
+	for _, id := range created.IDs {
+		if s, _ := svc.GetByID(testutil.TestContext(), id); !s.IsArchived {
+			t.Errorf("expected snippet %s to be archived", id)
+		}
+	}
+
+	h.Post("/api/v1/snippets/bulk", models.BulkSnippetInput{Action: models.BulkDelete, IDs: []string{created.IDs[0], "missing"}}).
+		ExpectStatus(http.StatusNotFound)
+	if s, _ := svc.GetByID(testutil.TestContext(), created.IDs[0]); s.DeletedAt != nil {
+		t.Error("expected nothing to be deleted when a snippet is missing")
+	}
+
+	resp := h.Post("/api/v1/snippets/bulk", models.BulkSnippetInput{Action: "rename", IDs: created.IDs}).
+		ExpectStatus(http.StatusBadRequest)
+	if code := resp.ErrorCode(); code != "VALIDATION_ERROR" {
+		t.Errorf("expected VALIDATION_ERROR, got %q", code)
+	}
+}
+
+func TestHarness_SnippetDiffHistory(t *testing.T) {
+	h, svc := newSnippetHarness(t)
+	existing, err := svc.Create(testutil.TestContext(), &models.SnippetInput{Title: "Seed", Content: "x"})
+	if err != nil {
+		t.Fatalf("seed snippet: %v", err)
+	}
+
+	resp := h.Get("/api/v1/snippets/" + existing.ID + "/history/abc/diff/2").ExpectStatus(http.StatusBadRequest)
+	if code := resp.ErrorCode(); code != "INVALID_HISTORY_ID" {
+		t.Errorf("expected INVALID_HISTORY_ID, got %q", code)
+	}
+	h.Get("/api/v1/snippets/" + existing.ID + "/history/1/diff/2").ExpectStatus(http.StatusNotFound)
+	h.Get("/api/v1/snippets/missing/history/1/diff/2").ExpectStatus(http.StatusNotFound)
+}
+
+func TestHarness_SnippetPermissions(t *testing.T) {
+	h, svc := newSnippetHarness(t)
+	existing, err := svc.Create(testutil.TestContext(), &models.SnippetInput{Title: "Seed", Content: "x"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	input := models.SnippetInput{Title: "New", Content: "x"}
+
+	tests := []struct {
+		name       string
+		permission string
+		send       func() *apitest.Response
+		want       int
+	}{
+		{"read token can list", middleware.PermissionRead, func() *apitest.Response { return h.Get("/api/v1/snippets") }, http.StatusOK},
+		{"read token cannot create", middleware.PermissionRead, func() *apitest.Response { return h.Post("/api/v1/snippets", input) }, http.StatusForbidden},
+		{"read token can mark used", middleware.PermissionRead, func() *apitest.Response { return h.Post("/api/v1/snippets/"+existing.ID+"/used", nil) }, http.StatusOK},
+		{"read token cannot delete", middleware.PermissionRead, func() *apitest.Response { return h.Delete("/api/v1/snippets/" + existing.ID) }, http.StatusForbidden},
+		{"write token can create", middleware.PermissionWrite, func() *apitest.Response { return h.Post("/api/v1/snippets", input) }, http.StatusCreated},
+		{"admin token can update", middleware.PermissionAdmin, func() *apitest.Response { return h.Put("/api/v1/snippets/"+existing.ID, input) }, http.StatusOK},
+		{"unknown permission is denied", "bogus", func() *apitest.Response { return h.Get("/api/v1/snippets") }, http.StatusForbidden},
+		{"session has full access", apitest.Session, func() *apitest.Response { return h.Post("/api/v1/snippets", input) }, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h.As(tt.permission)
+			tt.send().ExpectStatus(tt.want)
+		})
+	}
+}
+
+func TestHarness_SnippetScopes(t *testing.T) {
+	h, svc := newSnippetHarness(t)
+	existing, err := svc.Create(testutil.TestContext(), &models.SnippetInput{Title: "Seed", Content: "x"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	input := models.SnippetInput{Title: "New", Content: "x"}
+
+	// A create-only automation token cannot read, even with a read level
+	h.AsScoped(middleware.PermissionRead, models.ScopeSnippetsWrite)
+	h.Post("/api/v1/snippets", input).ExpectStatus(http.StatusCreated)
+	h.Get("/api/v1/snippets").ExpectStatus(http.StatusForbidden)
+
+	// Scopes narrow an admin token too
+	h.AsScoped(middleware.PermissionAdmin, models.ScopeSnippetsRead)
+	h.Get("/api/v1/snippets/" + existing.ID).ExpectStatus(http.StatusOK)
+	h.Delete("/api/v1/snippets/" + existing.ID).ExpectStatus(http.StatusForbidden)
+}
+
+func newTagHarness(t *testing.T) *apitest.Harness {
+	t.Helper()
+	h := apitest.New(t)
+	handler := NewTagHandler(apitest.NewTagStore())
+
+	h.Router.Route("/api/v1/tags", func(r chi.Router) {
+		r.With(middleware.RequireRead).Get("/", handler.List)
+		r.With(middleware.RequireWrite).Post("/", handler.Create)
+		r.Route("/{id}", func(r chi.Router) {
+			r.With(middleware.RequireRead).Get("/", handler.Get)
+			r.With(middleware.RequireWrite).Put("/", handler.Update)
+			r.With(middleware.RequireWrite).Delete("/", handler.Delete)
+		})
+	})
+	return h
+}
+
+func TestHarness_Tags(t *testing.T) {
+	h := newTagHarness(t)
+
+	var tag models.Tag
+	h.Post("/api/v1/tags", models.TagInput{Name: "go"}).ExpectStatus(http.StatusCreated).Decode(&tag)
+	if want := models.TagColor("go", models.DefaultTagPalette); tag.Color != want {
+		t.Errorf("expected palette color %q, got %q", want, tag.Color)
+	}
+
+	resp := h.Post("/api/v1/tags", models.TagInput{Name: "go"}).ExpectStatus(http.StatusConflict)
+	if code := resp.ErrorCode(); code != "TAG_EXISTS" {
+		t.Errorf("expected TAG_EXISTS, got %q", code)
+	}
+
+	h.Get("/api/v1/tags/abc").ExpectStatus(http.StatusBadRequest)
+	h.Get("/api/v1/tags/999").ExpectStatus(http.StatusNotFound)
+
+	h.As(middleware.PermissionRead)
+	h.Get("/api/v1/tags").ExpectStatus(http.StatusOK)
+	h.Delete("/api/v1/tags/1").ExpectStatus(http.StatusForbidden)
+
+	h.As(middleware.PermissionWrite)
+	h.Delete("/api/v1/tags/1").ExpectStatus(http.StatusNoContent)
+}
+
+func newFolderHarness(t *testing.T) *apitest.Harness {
+	t.Helper()
+	h := apitest.New(t)
+	handler := NewFolderHandler(apitest.NewFolderStore())
+
+	h.Router.Route("/api/v1/folders", func(r chi.Router) {
+		r.With(middleware.RequireRead).Get("/", handler.List)
+		r.With(middleware.RequireWrite).Post("/", handler.Create)
+		r.Route("/{id}", func(r chi.Router) {
+			r.With(middleware.RequireRead).Get("/", handler.Get)
+			r.With(middleware.RequireWrite).Delete("/", handler.Delete)
+			r.With(middleware.RequireWrite).Put("/move", handler.Move)
+		})
+	})
+	return h
+}
+
+func TestHarness_Folders(t *testing.T) {
+	h := newFolderHarness(t)
+
+	var parent, child models.Folder
+	h.Post("/api/v1/folders", models.FolderInput{Name: "parent"}).ExpectStatus(http.StatusCreated).Decode(&parent)
+	h.Post("/api/v1/folders", models.FolderInput{Name: "child", ParentID: &parent.ID}).ExpectStatus(http.StatusCreated).Decode(&child)
+
+	missing := int64(999)
+	h.Post("/api/v1/folders", models.FolderInput{Name: "orphan", ParentID: &missing}).ExpectStatus(http.StatusBadRequest)
+
+	var tree []models.Folder
+	h.Get("/api/v1/folders?tree=true").ExpectStatus(http.StatusOK).Decode(&tree)
+	if len(tree) != 1 || len(tree[0].Children) != 1 {
+		t.Fatalf("expected one root with one child, got %+v", tree)
+	}
+
+	// Moving a folder under its own child is rejected
+	resp := h.Put("/api/v1/folders/1/move", map[string]interface{}{"parent_id": child.ID}).
+		ExpectStatus(http.StatusBadRequest)
+	if code := resp.ErrorCode(); code != "VALIDATION_ERROR" {
+		t.Errorf("expected VALIDATION_ERROR, got %q", code)
+	}
+
+	resp = h.Delete("/api/v1/folders/1?mode=shred").ExpectStatus(http.StatusBadRequest)
+	if code := resp.ErrorCode(); code != "INVALID_MODE" {
+		t.Errorf("expected INVALID_MODE, got %q", code)
+	}
+
+	// Orphaning the parent turns its child into a root folder
+	h.Delete("/api/v1/folders/1?mode=orphan").ExpectStatus(http.StatusNoContent)
+	h.Get("/api/v1/folders?tree=true").ExpectStatus(http.StatusOK).Decode(&tree)
+	if len(tree) != 1 || tree[0].ID != child.ID {
+		t.Fatalf("expected the child to be the only root, got %+v", tree)
+	}
+
+	h.As(middleware.PermissionRead)
+	h.Post("/api/v1/folders", models.FolderInput{Name: "denied"}).ExpectStatus(http.StatusForbidden)
+}
+
+func newSettingsHarness(t *testing.T) *apitest.Harness {
+	t.Helper()
+	h := apitest.New(t)
+	handler := NewSettingsHandler(apitest.NewSettingsStore(models.Settings{AppName: "Snipo", Theme: "auto"}), nil)
+
+	h.Router.Route("/api/v1/settings", func(r chi.Router) {
+		r.Use(middleware.RequireAdminWithPassword(nil))
+		r.Get("/", handler.Get)
+		r.Put("/", handler.Update)
+	})
+	return h
+}
+
+func TestHarness_Settings(t *testing.T) {
+	h := newSettingsHarness(t)
+
+	var settings models.Settings
+	h.Get("/api/v1/settings").ExpectStatus(http.StatusOK).Decode(&settings)
+	if settings.AppName != "Snipo" {
+		t.Errorf("expected app name Snipo, got %q", settings.AppName)
+	}
+
+	h.Put("/api/v1/settings", models.SettingsInput{AppName: "Renamed", Theme: "dark", DefaultLanguage: "go"}).
+		ExpectStatus(http.StatusOK).
+		Decode(&settings)
+	if settings.AppName != "Renamed" || settings.Theme != "dark" {
+		t.Errorf("expected updated settings, got %+v", settings)
+	}
+
+	h.As(middleware.PermissionWrite)
+	h.Get("/api/v1/settings").ExpectStatus(http.StatusForbidden)
+
+	h.Anonymous()
+	resp := h.Get("/api/v1/settings").ExpectStatus(http.StatusForbidden)
+	if resp.ErrorCode() != "INSUFFICIENT_PERMISSIONS" {
+		t.Errorf("expected INSUFFICIENT_PERMISSIONS, got %q", resp.ErrorCode())
+	}
+
+	h.As(middleware.PermissionAdmin)
+	h.Get("/api/v1/settings").ExpectStatus(http.StatusOK)
+}
+
+func TestHarness_GistSync(t *testing.T) {
+	db := testutil.TestDB(t)
+	encryptionSvc, err := services.NewEncryptionService(services.DeriveEncryptionKey("test-salt"))
+	if err != nil {
+		t.Fatalf("failed to create encryption service: %v", err)
+	}
+	handler := NewGistSyncHandler(
+		repository.NewGistSyncRepository(db),
+		repository.NewSnippetRepository(db),
+		repository.NewSnippetFileRepository(db),
+		encryptionSvc,
+	)
+
+	h := apitest.New(t)
+	h.Router.Route("/api/v1/gist", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireAdminWithPassword(nil))
+			r.Get("/config", handler.GetConfig)
+			r.Post("/config", handler.UpdateConfig)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireWrite)
+			r.Post("/sync/all", handler.SyncAll)
+		})
+		r.With(middleware.RequireRead).Get("/mappings", handler.ListMappings)
+	})
+
+	var cfg ConfigResponse
+	h.Get("/api/v1/gist/config").ExpectStatus(http.StatusOK).Decode(&cfg)
+	if cfg.Enabled || cfg.HasToken || cfg.SyncIntervalMinutes != 15 {
+		t.Errorf("expected default config, got %+v", cfg)
+	}
+
+	resp := h.Post("/api/v1/gist/config", ConfigInput{SyncIntervalMinutes: 1, ConflictResolutionStrategy: models.ConflictStrategyManual}).
+		ExpectStatus(http.StatusBadRequest)
+	if resp.ErrorCode() != "INVALID_INTERVAL" {
+		t.Errorf("expected INVALID_INTERVAL, got %q", resp.ErrorCode())
+	}
+
+	resp = h.Post("/api/v1/gist/config", ConfigInput{SyncIntervalMinutes: 15, ConflictResolutionStrategy: "coin_flip"}).
+		ExpectStatus(http.StatusBadRequest)
+	if resp.ErrorCode() != "INVALID_STRATEGY" {
+		t.Errorf("expected INVALID_STRATEGY, got %q", resp.ErrorCode())
+	}
+
+	resp = h.Post("/api/v1/gist/sync/all", nil).ExpectStatus(http.StatusBadRequest)
+	if resp.ErrorCode() != "SYNC_NOT_CONFIGURED" {
+		t.Errorf("expected SYNC_NOT_CONFIGURED, got %q", resp.ErrorCode())
+	}
+
+	h.As(middleware.PermissionRead)
+	h.Get("/api/v1/gist/mappings").ExpectStatus(http.StatusOK)
+	h.Post("/api/v1/gist/sync/all", nil).ExpectStatus(http.StatusForbidden)
+	h.Get("/api/v1/gist/config").ExpectStatus(http.StatusForbidden)
+}
+
+func TestHarness_Stats(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	snippets := repository.NewSnippetRepository(db)
+	tags := repository.NewTagRepository(db)
+	for _, lang := range []string{"go", "go", "python"} {
+		snippet, err := snippets.Create(ctx, &models.SnippetInput{Title: lang, Content: "x", Language: lang})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if err := tags.SetSnippetTags(ctx, snippet.ID, []string{lang + "-tag"}); err != nil {
+			t.Fatalf("SetSnippetTags failed: %v", err)
+		}
+	}
+
+	stats := NewStatsHandler(repository.NewStatsRepository(db))
+	health := NewHealthHandler(db).WithFeatures(map[string]bool{"language_stats": true})
+
+	h := apitest.New(t)
+	h.Router.With(middleware.RequireRead).Get("/api/v1/stats/languages", stats.Languages)
+	h.Router.Get("/health", health.Health)
+
+	var got models.SnippetStats
+	h.As("read").Get("/api/v1/stats/languages").ExpectStatus(http.StatusOK).Decode(&got)
+	if got.Total != 3 {
+		t.Errorf("expected 3 snippets, got %d", got.Total)
+	}
+	if len(got.Languages) != 2 || got.Languages[0] != (models.LanguageStat{Language: "go", Count: 2}) {
+		t.Errorf("unexpected language counts: %+v", got.Languages)
+	}
+	if len(got.Tags) != 2 || got.Tags[0].Name != "go-tag" || got.Tags[0].Count != 2 {
+		t.Errorf("unexpected tag counts: %+v", got.Tags)
+	}
+
+	var status HealthResponse
+	h.Get("/health").ExpectStatus(http.StatusOK).Decode(&status)
+	if !status.Features["language_stats"] {
+		t.Errorf("expected language_stats feature, got %v", status.Features)
+	}
+}
+
+func TestHarness_RuntimeFeatures(t *testing.T) {
+	store := apitest.NewSettingsStore(models.Settings{AppName: "Snipo", Theme: "auto"})
+	settings := NewSettingsHandler(store, nil)
+	health := NewHealthHandler(testutil.TestDB(t)).
+		WithFeatures(map[string]bool{models.FeaturePublicSnippets: true, models.FeatureGistSync: false}).
+		WithRuntimeFeatures(store)
+
+	h := apitest.New(t)
+	h.Router.Get("/health", health.Health)
+	h.Router.With(middleware.RequireFeature(store, models.FeaturePublicSnippets)).
+		Get("/api/v1/snippets/public", func(w http.ResponseWriter, r *http.Request) { OK(w, r, []models.Snippet{}) })
+	h.Router.With(middleware.RequireAdminWithPassword(nil)).Put("/api/v1/settings", settings.Update)
+
+	var status HealthResponse
+	h.Get("/health").ExpectStatus(http.StatusOK).Decode(&status)
+	if !status.Features[models.FeaturePublicSnippets] || status.Features[models.FeatureGistSync] {
+		t.Errorf("unexpected features before toggling: %v", status.Features)
+	}
+	h.Get("/api/v1/snippets/public").ExpectStatus(http.StatusOK)
+
+	h.As(middleware.PermissionAdmin)
+	h.Put("/api/v1/settings", models.SettingsInput{
+		AppName:  "Snipo",
+		Features: map[string]bool{models.FeaturePublicSnippets: false, models.FeatureGistSync: true},
+	}).ExpectStatus(http.StatusOK)
+
+	resp := h.Get("/api/v1/snippets/public").ExpectStatus(http.StatusNotFound)
+	if resp.ErrorCode() != "FEATURE_DISABLED" {
+		t.Errorf("expected FEATURE_DISABLED, got %q", resp.ErrorCode())
+	}
+
+	// Gist sync stays off because the configuration disables it
+	h.Get("/health").ExpectStatus(http.StatusOK).Decode(&status)
+	if status.Features[models.FeaturePublicSnippets] || status.Features[models.FeatureGistSync] {
+		t.Errorf("unexpected features after toggling: %v", status.Features)
+	}
+
+	h.Put("/api/v1/settings", models.SettingsInput{Features: map[string]bool{"no_such_feature": true}}).
+		ExpectStatus(http.StatusBadRequest)
+}
@@ -1,25 +1,87 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
+	"maps"
 	"net/http"
+	"runtime"
+
+	"github.com/MohamedElashri/snipo/internal/api/middleware"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/outbound"
+	"github.com/MohamedElashri/snipo/internal/repository"
 )
 
 // HealthHandler handles health check requests
 type HealthHandler struct {
-	db *sql.DB
+	db           *sql.DB
+	features     map[string]bool
+	runtimeFlags repository.FeatureFlags
+	checkProxy   func(ctx context.Context) (configured bool, err error)
+	build        BuildInfo
+	schema       func(ctx context.Context) (int, error)
+}
+
+// BuildInfo identifies the running build, as set at link time
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	BuildDate string
 }
 
 // NewHealthHandler creates a new health handler
 func NewHealthHandler(db *sql.DB) *HealthHandler {
 	return &HealthHandler{
-		db: db,
+		db:         db,
+		checkProxy: outbound.CheckProxy,
 	}
 }
 
+// WithFeatures sets the feature flags advertised to clients
+func (h *HealthHandler) WithFeatures(features map[string]bool) *HealthHandler {
+	h.features = features
+	return h
+}
+
+// WithRuntimeFeatures reports runtime feature flags from settings. A feature
+// switched off in the configuration stays off whatever the setting says.
+func (h *HealthHandler) WithRuntimeFeatures(flags repository.FeatureFlags) *HealthHandler {
+	h.runtimeFlags = flags
+	return h
+}
+
+// WithBuildInfo sets the build reported by Version, and the function reading
+// the database schema version
+func (h *HealthHandler) WithBuildInfo(build BuildInfo, schemaVersion func(ctx context.Context) (int, error)) *HealthHandler {
+	h.build = build
+	h.schema = schemaVersion
+	return h
+}
+
+// currentFeatures combines the configured features with runtime flags
+func (h *HealthHandler) currentFeatures(r *http.Request) map[string]bool {
+	if h.runtimeFlags == nil {
+		return h.features
+	}
+	features := maps.Clone(h.features)
+	if features == nil {
+		features = make(map[string]bool)
+	}
+	for _, name := range models.RuntimeFeatures {
+		if configured, ok := features[name]; ok && !configured {
+			continue
+		}
+		enabled, err := h.runtimeFlags.FeatureEnabled(r.Context(), name)
+		features[name] = err == nil && enabled
+	}
+	return features
+}
+
 // HealthResponse represents the health check response
 type HealthResponse struct {
-	Status string `json:"status"`
+	Status   string          `json:"status"`
+	Features map[string]bool `json:"features,omitempty"`
 }
 
 // Health handles GET /health
@@ -32,7 +94,8 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := HealthResponse{
-		Status: status,
+		Status:   status,
+		Features: h.currentFeatures(r),
 	}
 
 	if status == "healthy" {
@@ -42,9 +105,82 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ReadyResponse represents the readiness check response
+type ReadyResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Ready handles GET /readyz
+// Responds 503 when the database is unavailable. An unreachable outbound
+// proxy only degrades integrations, so it is reported without failing the
+// check.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	response := ReadyResponse{Status: "ready", Checks: map[string]string{"database": "ok"}}
+
+	if err := h.db.PingContext(r.Context()); err != nil {
+		response.Status = "unavailable"
+		response.Checks["database"] = "unavailable"
+	}
+
+	if configured, err := h.checkProxy(r.Context()); configured {
+		response.Checks["proxy"] = "ok"
+		if err != nil {
+			response.Checks["proxy"] = "unreachable"
+			if response.Status == "ready" {
+				response.Status = "degraded"
+			}
+		}
+	}
+
+	if response.Status == "unavailable" {
+		JSON(w, http.StatusServiceUnavailable, response)
+		return
+	}
+	OK(w, r, response)
+}
+
 // Ping handles GET /ping - simple liveness check
 func (h *HealthHandler) Ping(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("pong"))
 }
+
+// VersionResponse describes the running server, for clients deciding which
+// endpoints they can use
+type VersionResponse struct {
+	Version       string          `json:"version"`
+	Commit        string          `json:"commit"`
+	BuildDate     string          `json:"build_date"`
+	GoVersion     string          `json:"go_version"`
+	APIVersion    string          `json:"api_version"`
+	SchemaVersion int             `json:"schema_version"`
+	Features      map[string]bool `json:"features"`
+}
+
+// Version handles GET /api/v1/system/version
+func (h *HealthHandler) Version(w http.ResponseWriter, r *http.Request) {
+	response := VersionResponse{
+		Version:    h.build.Version,
+		Commit:     h.build.Commit,
+		BuildDate:  h.build.BuildDate,
+		GoVersion:  runtime.Version(),
+		APIVersion: middleware.APIVersion,
+		Features:   h.currentFeatures(r),
+	}
+	if response.Features == nil {
+		response.Features = map[string]bool{}
+	}
+
+	if h.schema != nil {
+		version, err := h.schema(r.Context())
+		if err != nil {
+			InternalError(w, r)
+			return
+		}
+		response.SchemaVersion = version
+	}
+
+	OK(w, r, response)
+}
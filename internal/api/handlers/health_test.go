@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestHealthHandler_Ready(t *testing.T) {
+	db := testutil.TestDB(t)
+	handler := NewHealthHandler(db)
+
+	ready := func() (int, ReadyResponse) {
+		w := httptest.NewRecorder()
+		handler.Ready(w, withRequestID(httptest.NewRequest(http.MethodGet, "/readyz", nil)))
+		// Like /health, a 200 is wrapped in the data envelope and a 503 isn't
+		var body struct {
+			Data ReadyResponse `json:"data"`
+			ReadyResponse
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if body.Data.Status != "" {
+			return w.Code, body.Data
+		}
+		return w.Code, body.ReadyResponse
+	}
+
+	handler.checkProxy = func(context.Context) (bool, error) { return false, nil }
+	code, resp := ready()
+	if code != http.StatusOK || resp.Status != "ready" || resp.Checks["database"] != "ok" {
+		t.Errorf("expected ready, got %d %+v", code, resp)
+	}
+	if _, ok := resp.Checks["proxy"]; ok {
+		t.Errorf("expected no proxy check without a proxy, got %+v", resp.Checks)
+	}
+
+	handler.checkProxy = func(context.Context) (bool, error) { return true, errors.New("connection refused") }
+	code, resp = ready()
+	if code != http.StatusOK || resp.Status != "degraded" || resp.Checks["proxy"] != "unreachable" {
+		t.Errorf("expected a degraded status for an unreachable proxy, got %d %+v", code, resp)
+	}
+
+	_ = db.Close()
+	code, resp = ready()
+	if code != http.StatusServiceUnavailable || resp.Status != "unavailable" {
+		t.Errorf("expected 503 without a database, got %d %+v", code, resp)
+	}
+}
+
+func TestHealthHandler_Version(t *testing.T) {
+	db := testutil.TestDB(t)
+	handler := NewHealthHandler(db).
+		WithFeatures(map[string]bool{"public_snippets": true}).
+		WithBuildInfo(BuildInfo{Version: "1.6.0", Commit: "abc1234", BuildDate: "2026-10-17T09:00:00Z"},
+			func(context.Context) (int, error) { return 40, nil })
+
+	w := httptest.NewRecorder()
+	handler.Version(w, withRequestID(httptest.NewRequest(http.MethodGet, "/api/v1/system/version", nil)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var body struct {
+		Data VersionResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	resp := body.Data
+	if resp.Version != "1.6.0" || resp.Commit != "abc1234" || resp.BuildDate != "2026-10-17T09:00:00Z" {
+		t.Errorf("expected the build info, got %+v", resp)
+	}
+	if resp.GoVersion != runtime.Version() || resp.APIVersion == "" {
+		t.Errorf("expected the Go and API versions, got %q and %q", resp.GoVersion, resp.APIVersion)
+	}
+	if resp.SchemaVersion != 40 {
+		t.Errorf("expected schema version 40, got %d", resp.SchemaVersion)
+	}
+	if !resp.Features["public_snippets"] {
+		t.Errorf("expected the enabled features, got %v", resp.Features)
+	}
+
+	handler.schema = func(context.Context) (int, error) { return 0, errors.New("no such table") }
+	w = httptest.NewRecorder()
+	handler.Version(w, withRequestID(httptest.NewRequest(http.MethodGet, "/api/v1/system/version", nil)))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 when the schema version can't be read, got %d", w.Code)
+	}
+}
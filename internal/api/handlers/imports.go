@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/repository"
+)
+
+// ImportReportHandler serves the saved reports of past imports
+type ImportReportHandler struct {
+	repo *repository.ImportReportRepository
+}
+
+// NewImportReportHandler creates a new import report handler
+func NewImportReportHandler(repo *repository.ImportReportRepository) *ImportReportHandler {
+	return &ImportReportHandler{repo: repo}
+}
+
+// List handles GET /api/v1/imports, newest first without the per-item results
+// Query params: limit (default 20, max 100)
+func (h *ImportReportHandler) List(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if l := r.URL.Query().Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed < 1 || parsed > 100 {
+			Error(w, r, http.StatusBadRequest, apierror.InvalidRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = parsed
+	}
+
+	reports, err := h.repo.List(r.Context(), limit)
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, reports)
+}
+
+// Report handles GET /api/v1/imports/{id}/report
+// Query params: format (json, the default, or csv to download one row per item)
+func (h *ImportReportHandler) Report(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid import ID")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidRequest, "format must be json or csv")
+		return
+	}
+
+	report, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			NotFound(w, r, "Import report not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	if format != "csv" {
+		OK(w, r, report)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"import-%d-report.csv\"", report.ID))
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"source_id", "title", "action", "snippet_id", "matched_by", "error"})
+	for _, item := range report.Items {
+		_ = cw.Write([]string{item.SourceID, item.Title, item.Action, item.SnippetID, item.MatchedBy, item.Error})
+	}
+	cw.Flush()
+}
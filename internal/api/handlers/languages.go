@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"sort"
 
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
 	"github.com/MohamedElashri/snipo/internal/validation"
 )
 
@@ -26,3 +27,10 @@ func (h *LanguageHandler) GetLanguages(w http.ResponseWriter, r *http.Request) {
 
 	Success(w, r, http.StatusOK, response)
 }
+
+// GetErrorCodes returns the catalog of API error codes for client authors
+func (h *LanguageHandler) GetErrorCodes(w http.ResponseWriter, r *http.Request) {
+	Success(w, r, http.StatusOK, map[string][]apierror.Entry{
+		"errors": apierror.Catalog(),
+	})
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/services"
+)
+
+const (
+	oembedWidth     = 720
+	oembedMaxHeight = 600
+	// Pixels per line of code, and for the footer and file names around it
+	oembedLineHeight   = 20
+	oembedFooterHeight = 40
+	oembedFileHeight   = 30
+)
+
+// oembedPathPattern matches the share and embed pages of a snippet
+var oembedPathPattern = regexp.MustCompile(`^/(?:s|embed)/([A-Za-z0-9_-]+)/?$`)
+
+// OEmbedHandler describes share links to oEmbed consumers such as blogs,
+// Notion and Discourse, which then show the snippet's embed page in an iframe
+type OEmbedHandler struct {
+	snippets *services.SnippetService
+	basePath string
+}
+
+// NewOEmbedHandler creates a new oEmbed handler. basePath is prefixed to the
+// pages it links to.
+func NewOEmbedHandler(snippets *services.SnippetService, basePath string) *OEmbedHandler {
+	return &OEmbedHandler{snippets: snippets, basePath: basePath}
+}
+
+// OEmbedResponse is an oEmbed "rich" response
+type OEmbedResponse struct {
+	Type         string `json:"type"`
+	Version      string `json:"version"`
+	Title        string `json:"title"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
+
+// OEmbed handles GET /oembed?url=
+// Responds with a bare oEmbed object, as the spec requires, whose HTML is an
+// iframe of /embed/{id}. Only JSON is offered; format=xml gets 501.
+func (h *OEmbedHandler) OEmbed(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if format := query.Get("format"); format != "" && format != "json" {
+		Error(w, r, http.StatusNotImplemented, apierror.UnsupportedFormat, "Only the json format is supported")
+		return
+	}
+
+	link, err := url.Parse(query.Get("url"))
+	if err != nil || link.Host == "" {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidURL, "url must be a snippet share link")
+		return
+	}
+	// Links to other sites or pages have nothing to embed here
+	path, underBase := strings.CutPrefix(link.Path, h.basePath)
+	match := oembedPathPattern.FindStringSubmatch(path)
+	if !strings.EqualFold(link.Host, r.Host) || !underBase || match == nil {
+		NotFound(w, r, "No snippet at this URL")
+		return
+	}
+	id := match[1]
+
+	snippet, err := h.snippets.LookupPublic(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrSnippetNotFound) {
+			NotFound(w, r, "No snippet at this URL")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	// Tall enough for the code, up to a limit after which the frame scrolls
+	lines := strings.Count(snippet.Content, "\n") + 1
+	height := oembedFooterHeight
+	if len(snippet.Files) > 0 {
+		lines = 0
+		for _, file := range snippet.Files {
+			lines += strings.Count(file.Content, "\n") + 1
+		}
+		if len(snippet.Files) > 1 {
+			height += oembedFileHeight * len(snippet.Files)
+		}
+	}
+	height = min(height+lines*oembedLineHeight, oembedMaxHeight)
+	width := oembedWidth
+	if maxWidth, err := strconv.Atoi(query.Get("maxwidth")); err == nil && maxWidth > 0 {
+		width = min(width, maxWidth)
+	}
+	if maxHeight, err := strconv.Atoi(query.Get("maxheight")); err == nil && maxHeight > 0 {
+		height = min(height, maxHeight)
+	}
+
+	site := scheme(r) + "://" + r.Host + h.basePath
+	src := site + "/embed/" + url.PathEscape(id)
+	JSON(w, http.StatusOK, OEmbedResponse{
+		Type:         "rich",
+		Version:      "1.0",
+		Title:        snippet.Title,
+		ProviderName: "Snipo",
+		ProviderURL:  site + "/",
+		HTML: fmt.Sprintf(`<iframe src="%s" width="%d" height="%d" title="%s" style="border:0;max-width:100%%" loading="lazy"></iframe>`,
+			html.EscapeString(src), width, height, html.EscapeString(snippet.Title)),
+		Width:  width,
+		Height: height,
+	})
+}
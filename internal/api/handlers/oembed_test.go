@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestOEmbedHandler_OEmbed(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	service := services.NewSnippetService(repository.NewSnippetRepository(db), testutil.TestLogger())
+	public, err := service.Create(ctx, &models.SnippetInput{Title: "Retry <loop>", Content: "for {\n\tbreak\n}", Language: "go", IsPublic: true})
+	if err != nil {
+		t.Fatalf("failed to create snippet: %v", err)
+	}
+	private, err := service.Create(ctx, &models.SnippetInput{Title: "Private", Content: "x", Language: "go"})
+	if err != nil {
+		t.Fatalf("failed to create snippet: %v", err)
+	}
+	handler := NewOEmbedHandler(service, "/snipo")
+
+	get := func(query url.Values) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.OEmbed(w, withRequestID(httptest.NewRequest(http.MethodGet, "https://snipo.example/snipo/oembed?"+query.Encode(), nil)))
+		return w
+	}
+
+	share := "https://snipo.example/snipo/s/" + *public.PublicToken
+	w := get(url.Values{"url": {share}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp OEmbedResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("expected a bare oEmbed object: %v", err)
+	}
+	if resp.Type != "rich" || resp.Version != "1.0" || resp.Title != "Retry <loop>" || resp.ProviderURL != "https://snipo.example/snipo/" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if !strings.Contains(resp.HTML, `src="https://snipo.example/snipo/embed/`+*public.PublicToken+`"`) || !strings.Contains(resp.HTML, `title="Retry &lt;loop&gt;"`) {
+		t.Errorf("expected an escaped iframe of the embed page, got %s", resp.HTML)
+	}
+	if resp.Height != oembedFooterHeight+3*oembedLineHeight || resp.Width != oembedWidth {
+		t.Errorf("expected a frame sized to 3 lines, got %dx%d", resp.Width, resp.Height)
+	}
+
+	w = get(url.Values{"url": {share}, "maxwidth": {"300"}, "maxheight": {"50"}})
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Width != 300 || resp.Height != 50 {
+		t.Errorf("expected the size capped to 300x50, got %dx%d (%v)", resp.Width, resp.Height, err)
+	}
+
+	for name, tc := range map[string]struct {
+		query url.Values
+		want  int
+	}{
+		"embed link":    {url.Values{"url": {"https://snipo.example/snipo/embed/" + *public.PublicToken}}, http.StatusOK},
+		"xml":           {url.Values{"url": {share}, "format": {"xml"}}, http.StatusNotImplemented},
+		"missing url":   {url.Values{}, http.StatusBadRequest},
+		"other host":    {url.Values{"url": {"https://gist.example/snipo/s/" + *public.PublicToken}}, http.StatusNotFound},
+		"no base path":  {url.Values{"url": {"https://snipo.example/s/" + *public.PublicToken}}, http.StatusNotFound},
+		"other page":    {url.Values{"url": {"https://snipo.example/snipo/login"}}, http.StatusNotFound},
+		"private":       {url.Values{"url": {"https://snipo.example/snipo/s/" + private.ID}}, http.StatusNotFound},
+		"unknown token": {url.Values{"url": {"https://snipo.example/snipo/s/nope"}}, http.StatusNotFound},
+	} {
+		if w := get(tc.query); w.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d: %s", name, tc.want, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"unicode/utf8"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+// quickCaptureMaxBody matches the snippet content limit
+const quickCaptureMaxBody = 1024 * 1024
+
+// QuickCaptureHandler saves piped text as snippets for shell aliases
+type QuickCaptureHandler struct {
+	service  *services.QuickCaptureService
+	basePath string
+}
+
+// NewQuickCaptureHandler creates a new quick capture handler. basePath is
+// prefixed to the snippet links it returns.
+func NewQuickCaptureHandler(service *services.QuickCaptureService, basePath string) *QuickCaptureHandler {
+	return &QuickCaptureHandler{service: service, basePath: basePath}
+}
+
+// Capture handles POST /api/v1/quick
+// The body is the snippet content, or {"content": "..."} when sent as JSON.
+// Responds with the snippet's URL as plain text: 201 for a new snippet, 200
+// when the same content was captured moments ago.
+func (h *QuickCaptureHandler) Capture(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, quickCaptureMaxBody))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			Error(w, r, http.StatusRequestEntityTooLarge, apierror.PayloadTooLarge, "Payload must be less than 1MB")
+			return
+		}
+		Error(w, r, http.StatusBadRequest, apierror.ReadError, "Failed to read payload")
+		return
+	}
+
+	content := string(body)
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		var input struct {
+			Content string `json:"content"`
+		}
+		if err := json.Unmarshal(body, &input); err != nil {
+			Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+			return
+		}
+		content = input.Content
+	}
+	if !utf8.ValidString(content) {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidPayload, "Content must be UTF-8 text")
+		return
+	}
+
+	snippet, duplicate, err := h.service.Capture(r.Context(), content)
+	if err != nil {
+		var validationErrs validation.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			ValidationErrors(w, r, validationErrs)
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	status := http.StatusCreated
+	if duplicate {
+		status = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, scheme(r)+"://"+r.Host+h.basePath+"/?snippet="+url.QueryEscape(snippet.ID)+"\n")
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestQuickCaptureHandler_Capture(t *testing.T) {
+	db := testutil.TestDB(t)
+	snippets := services.NewSnippetService(repository.NewSnippetRepository(db), testutil.TestLogger())
+	handler := NewQuickCaptureHandler(services.NewQuickCaptureService(snippets, testutil.TestLogger()), "/snipo")
+
+	post := func(body, contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "http://snipo.example/snipo/api/v1/quick", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		handler.Capture(w, withRequestID(req))
+		return w
+	}
+
+	// curl --data-binary sends form encoding, which must not be parsed
+	w := post("a=1&b=2\n", "application/x-www-form-urlencoded")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	link := w.Body.String()
+	if !strings.HasPrefix(link, "http://snipo.example/snipo/?snippet=") || !strings.HasSuffix(link, "\n") {
+		t.Errorf("unexpected snippet link %q", link)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("expected a plain text response, got %q", ct)
+	}
+
+	w = post(`{"content": "a=1&b=2"}`, "application/json")
+	if w.Code != http.StatusOK || w.Body.String() != link {
+		t.Errorf("expected the repeated capture to return %q with 200, got %d %q", link, w.Code, w.Body.String())
+	}
+
+	if w := post("", "text/plain"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for empty content, got %d", w.Code)
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/services"
+)
+
+// rawCSP keeps a raw file opened in a browser from running anything
+const rawCSP = "default-src 'none'; sandbox"
+
+// rawContentTypes maps snippet languages to the media types raw files are
+// served with. Languages not listed, including HTML and XML that a browser
+// would render on this origin, are served as text/plain.
+var rawContentTypes = map[string]string{
+	"javascript": "text/javascript",
+	"typescript": "application/typescript",
+	"python":     "text/x-python",
+	"go":         "text/x-go",
+	"rust":       "text/x-rust",
+	"java":       "text/x-java",
+	"c":          "text/x-c",
+	"cpp":        "text/x-c++",
+	"csharp":     "text/x-csharp",
+	"php":        "text/x-php",
+	"ruby":       "text/x-ruby",
+	"swift":      "text/x-swift",
+	"kotlin":     "text/x-kotlin",
+	"scala":      "text/x-scala",
+	"css":        "text/css",
+	"scss":       "text/x-scss",
+	"json":       "application/json",
+	"yaml":       "application/yaml",
+	"markdown":   "text/markdown",
+	"sql":        "application/sql",
+	"bash":       "text/x-shellscript",
+	"shell":      "text/x-shellscript",
+	"powershell": "text/x-powershell",
+	"dockerfile": "text/x-dockerfile",
+	"toml":       "application/toml",
+	"makefile":   "text/x-makefile",
+	"lua":        "text/x-lua",
+	"perl":       "text/x-perl",
+	"r":          "text/x-r",
+	"haskell":    "text/x-haskell",
+	"elixir":     "text/x-elixir",
+	"clojure":    "text/x-clojure",
+	"graphql":    "application/graphql",
+	"protobuf":   "text/x-protobuf",
+	"terraform":  "text/x-terraform",
+}
+
+// rawContentType returns the Content-Type for a file in language
+func rawContentType(language string) string {
+	mediaType, ok := rawContentTypes[language]
+	if !ok {
+		mediaType = "text/plain"
+	}
+	return mime.FormatMediaType(mediaType, map[string]string{"charset": "utf-8"})
+}
+
+// GetPublicRaw handles GET /s/{id}/raw
+// Returns a public snippet's content as is, or its first file for a
+// multi-file snippet, so shared scripts can be piped into a shell
+func (h *SnippetHandler) GetPublicRaw(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := h.publicForRaw(w, r)
+	if !ok {
+		return
+	}
+
+	file := &models.SnippetFile{Content: snippet.Content, Language: snippet.Language}
+	if len(snippet.Files) > 0 {
+		file = &snippet.Files[0]
+	}
+	serveRaw(w, r, snippet, file)
+}
+
+// GetPublicFileRaw handles GET /s/{id}/{filename}/raw
+// Returns one file of a public snippet as is
+func (h *SnippetHandler) GetPublicFileRaw(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := h.publicForRaw(w, r)
+	if !ok {
+		return
+	}
+
+	filename := chi.URLParam(r, "filename")
+	file := findPublicFile(snippet, filename)
+	if file == nil {
+		NotFound(w, r, "File not found in snippet")
+		return
+	}
+	serveRaw(w, r, snippet, file)
+}
+
+// publicForRaw loads the public snippet in the path, counting a view
+func (h *SnippetHandler) publicForRaw(w http.ResponseWriter, r *http.Request) (*models.Snippet, bool) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
+		return nil, false
+	}
+
+	snippet, err := h.service.GetByIDPublic(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrSnippetNotFound) {
+			NotFound(w, r, "Snippet not found")
+			return nil, false
+		}
+		InternalError(w, r)
+		return nil, false
+	}
+	return snippet, true
+}
+
+// serveRaw writes file's content with the media type of its language
+func serveRaw(w http.ResponseWriter, r *http.Request, snippet *models.Snippet, file *models.SnippetFile) {
+	w.Header().Set("Content-Type", rawContentType(file.Language))
+	if file.Filename != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": file.Filename}))
+	}
+	w.Header().Set("Content-Security-Policy", rawCSP)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	Download(w, r, snippet.UpdatedAt, []byte(file.Content))
+}
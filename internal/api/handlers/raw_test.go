@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestSnippetHandler_PublicRaw(t *testing.T) {
+	handler, _ := setupSnippetHandler(t)
+	ctx := testutil.TestContext()
+
+	script, err := handler.service.Create(ctx, &models.SnippetInput{Title: "Install", Content: "#!/bin/sh\necho hi\n", Language: "bash", IsPublic: true})
+	if err != nil {
+		t.Fatalf("failed to create snippet: %v", err)
+	}
+	multi, err := handler.service.Create(ctx, &models.SnippetInput{Title: "Page", IsPublic: true, Files: []models.SnippetFileInput{
+		{Filename: "config.yaml", Content: "a: 1\n", Language: "yaml"},
+		{Filename: "index.html", Content: "<script>alert(1)</script>", Language: "html"},
+	}})
+	if err != nil {
+		t.Fatalf("failed to create snippet: %v", err)
+	}
+	private, err := handler.service.Create(ctx, &models.SnippetInput{Title: "Private", Content: "secret", Language: "bash"})
+	if err != nil {
+		t.Fatalf("failed to create snippet: %v", err)
+	}
+
+	router := chi.NewRouter()
+	router.Get("/s/{id}/raw", handler.GetPublicRaw)
+	router.Get("/s/{id}/{filename}/raw", handler.GetPublicFileRaw)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, withRequestID(httptest.NewRequest(http.MethodGet, path, nil)))
+		return w
+	}
+
+	for _, tc := range []struct {
+		path, contentType, body string
+	}{
+		{"/s/" + *script.PublicToken + "/raw", "text/x-shellscript; charset=utf-8", "#!/bin/sh\necho hi\n"},
+		{"/s/" + *multi.PublicToken + "/raw", "application/yaml; charset=utf-8", "a: 1\n"},
+		{"/s/" + *multi.PublicToken + "/config.yaml/raw", "application/yaml; charset=utf-8", "a: 1\n"},
+		// Never rendered as a page on this origin
+		{"/s/" + *multi.PublicToken + "/index.html/raw", "text/plain; charset=utf-8", "<script>alert(1)</script>"},
+	} {
+		w := get(tc.path)
+		if w.Code != http.StatusOK || w.Body.String() != tc.body {
+			t.Errorf("%s: expected the content as is, got %d: %q", tc.path, w.Code, w.Body.String())
+		}
+		if ct := w.Header().Get("Content-Type"); ct != tc.contentType {
+			t.Errorf("%s: expected Content-Type %q, got %q", tc.path, tc.contentType, ct)
+		}
+		if csp := w.Header().Get("Content-Security-Policy"); csp != rawCSP {
+			t.Errorf("%s: expected a sandboxing CSP, got %q", tc.path, csp)
+		}
+	}
+
+	for _, path := range []string{
+		"/s/" + *multi.PublicToken + "/missing.txt/raw",
+		"/s/" + private.ID + "/raw",
+		"/s/nope/raw",
+	} {
+		if w := get(path); w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, w.Code)
+		}
+	}
+}
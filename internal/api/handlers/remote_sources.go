@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/services"
+)
+
+// RemoteSourceHandler handles remote source (federation) endpoints
+type RemoteSourceHandler struct {
+	service *services.RemoteSourceService
+}
+
+// NewRemoteSourceHandler creates a new remote source handler
+func NewRemoteSourceHandler(service *services.RemoteSourceService) *RemoteSourceHandler {
+	return &RemoteSourceHandler{service: service}
+}
+
+// List handles GET /api/v1/remote-sources
+func (h *RemoteSourceHandler) List(w http.ResponseWriter, r *http.Request) {
+	sources, err := h.service.List(r.Context())
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, sources)
+}
+
+// Create handles POST /api/v1/remote-sources
+func (h *RemoteSourceHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input models.RemoteSourceInput
+	if err := DecodeJSON(r, &input); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	source, err := h.service.Create(r.Context(), &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidRemoteSource):
+			Error(w, r, http.StatusBadRequest, apierror.InvalidRemoteSource, strings.TrimPrefix(err.Error(), services.ErrInvalidRemoteSource.Error()+": "))
+		case errors.Is(err, repository.ErrAlreadyExists):
+			Error(w, r, http.StatusConflict, apierror.AlreadyExists, "A remote source with this URL already exists")
+		default:
+			InternalError(w, r)
+		}
+		return
+	}
+
+	Created(w, r, source)
+}
+
+// Delete handles DELETE /api/v1/remote-sources/{id}
+// Removes the source together with its mirrored snippets
+func (h *RemoteSourceHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid remote source ID")
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, services.ErrRemoteSourceNotFound) || errors.Is(err, repository.ErrNotFound) {
+			NotFound(w, r, "Remote source not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	NoContent(w)
+}
+
+// Sync handles POST /api/v1/remote-sources/{id}/sync
+func (h *RemoteSourceHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid remote source ID")
+		return
+	}
+
+	result, err := h.service.Sync(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrRemoteSourceNotFound) {
+			NotFound(w, r, "Remote source not found")
+			return
+		}
+		Error(w, r, http.StatusBadGateway, apierror.SyncFailed, err.Error())
+		return
+	}
+
+	OK(w, r, result)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+// RenderHandler renders markdown for clients that want the server's output
+type RenderHandler struct {
+	snippets *services.SnippetService
+}
+
+// NewRenderHandler creates a new render handler
+func NewRenderHandler(snippets *services.SnippetService) *RenderHandler {
+	return &RenderHandler{snippets: snippets}
+}
+
+// Markdown handles POST /api/v1/render/markdown
+// Returns the HTML public pages would show for the content
+func (h *RenderHandler) Markdown(w http.ResponseWriter, r *http.Request) {
+	var input models.MarkdownRenderInput
+	if err := DecodeJSON(r, &input); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	result, err := h.snippets.RenderMarkdown(r.Context(), &input)
+	if err != nil {
+		var validationErrs validation.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			ValidationErrors(w, r, validationErrs)
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, result)
+}
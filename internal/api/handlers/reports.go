@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+// ReportHandler handles abuse reports and the moderation queue
+type ReportHandler struct {
+	service *services.ReportService
+}
+
+// NewReportHandler creates a new report handler
+func NewReportHandler(service *services.ReportService) *ReportHandler {
+	return &ReportHandler{service: service}
+}
+
+// Submit handles POST /s/{id}/report
+// Anyone who can see a public snippet can report it.
+func (h *ReportHandler) Submit(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
+		return
+	}
+
+	var input models.ReportInput
+	if err := DecodeJSON(r, &input); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	report, err := h.service.Submit(r.Context(), id, &input)
+	if err != nil {
+		var validationErrs validation.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			ValidationErrors(w, r, validationErrs)
+			return
+		}
+		if errors.Is(err, services.ErrSnippetNotFound) {
+			NotFound(w, r, "Snippet not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	Created(w, r, report)
+}
+
+// List handles GET /api/v1/reports
+// Returns open reports by default; status=dismissed, actioned or all selects others.
+func (h *ReportHandler) List(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = models.ReportStatusOpen
+	case "all":
+		status = ""
+	case models.ReportStatusOpen, models.ReportStatusDismissed, models.ReportStatusActioned:
+	default:
+		Error(w, r, http.StatusBadRequest, apierror.InvalidStatus, "Status must be open, dismissed, actioned or all")
+		return
+	}
+
+	reports, err := h.service.List(r.Context(), status)
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, reports)
+}
+
+// Resolve handles POST /api/v1/reports/{id}/resolve
+func (h *ReportHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid report ID")
+		return
+	}
+
+	var input models.ReportResolution
+	if err := DecodeJSON(r, &input); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	report, err := h.service.Resolve(r.Context(), id, input.Action)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidResolution):
+			Error(w, r, http.StatusBadRequest, apierror.InvalidAction, err.Error())
+		case errors.Is(err, services.ErrReportNotFound):
+			NotFound(w, r, "Open report not found")
+		default:
+			InternalError(w, r)
+		}
+		return
+	}
+
+	OK(w, r, report)
+}
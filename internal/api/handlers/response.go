@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
 
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
 	"github.com/MohamedElashri/snipo/internal/api/middleware"
 	"github.com/MohamedElashri/snipo/internal/validation"
 )
@@ -53,6 +57,7 @@ type Meta struct {
 	RequestID string    `json:"request_id"`
 	Timestamp time.Time `json:"timestamp"`
 	Version   string    `json:"version"`
+	Warnings  []string  `json:"warnings,omitempty"` // Deprecation notices for the endpoint called
 }
 
 // PaginationLinks contains navigation links for paginated responses
@@ -68,6 +73,7 @@ type Pagination struct {
 	Limit      int              `json:"limit"`
 	Total      int              `json:"total"`
 	TotalPages int              `json:"totalPages"`
+	NextCursor string           `json:"next_cursor,omitempty"`
 	Links      *PaginationLinks `json:"links,omitempty"`
 }
 
@@ -85,21 +91,26 @@ type ErrorResponse struct {
 
 // ErrorDetail contains error details
 type ErrorDetail struct {
-	Code      string                       `json:"code"`
-	Message   string                       `json:"message"`
-	Details   []validation.ValidationError `json:"details,omitempty"`
-	RequestID string                       `json:"request_id,omitempty"`
-	Timestamp time.Time                    `json:"timestamp,omitempty"`
+	Code       apierror.Code                `json:"code"`
+	Message    string                       `json:"message"`
+	Details    []validation.ValidationError `json:"details,omitempty"`
+	ExistingID string                       `json:"existing_id,omitempty"` // Resource a conflict is with, when there is one
+	RequestID  string                       `json:"request_id,omitempty"`
+	Timestamp  time.Time                    `json:"timestamp,omitempty"`
 }
 
 // getMeta extracts metadata from request context
 func getMeta(r *http.Request) *Meta {
 	requestID := middleware.GetRequestID(r.Context())
-	return &Meta{
+	meta := &Meta{
 		RequestID: requestID,
 		Timestamp: time.Now().UTC(),
 		Version:   APIVersion,
 	}
+	if deprecation, ok := middleware.DeprecationOf(r); ok {
+		meta.Warnings = []string{deprecation.Warning()}
+	}
+	return meta
 }
 
 // buildPaginationLinks generates navigation links for pagination
@@ -189,12 +200,59 @@ func SuccessList(w http.ResponseWriter, r *http.Request, data interface{}, page,
 	JSON(w, http.StatusOK, response)
 }
 
-// Error sends an error response
-func Error(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+// SuccessCursorList sends a list response that can also be paged by
+// cursor. Requests that used a cursor get links that follow cursors rather
+// than page numbers.
+func SuccessCursorList(w http.ResponseWriter, r *http.Request, data interface{}, page, limit, total int, nextCursor string) {
+	totalPages := (total + limit - 1) / limit
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	links := buildPaginationLinks(r, page, limit, total)
+	if r.URL.Query().Get("cursor") != "" {
+		links = buildCursorLinks(r, nextCursor)
+	}
+
+	JSON(w, http.StatusOK, ListResponse{
+		Data: data,
+		Pagination: &Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+			NextCursor: nextCursor,
+			Links:      links,
+		},
+		Meta: getMeta(r),
+	})
+}
+
+// buildCursorLinks links to the current request and the page after it
+func buildCursorLinks(r *http.Request, nextCursor string) *PaginationLinks {
+	baseURL := fmt.Sprintf("%s://%s%s", scheme(r), r.Host, r.URL.Path)
+	query := r.URL.Query()
+	query.Del("page")
+
+	links := &PaginationLinks{Self: fmt.Sprintf("%s?%s", baseURL, query.Encode())}
+	if nextCursor != "" {
+		query.Set("cursor", nextCursor)
+		next := fmt.Sprintf("%s?%s", baseURL, query.Encode())
+		links.Next = &next
+	}
+	return links
+}
+
+// Error sends an error response. The request ID is included so users can
+// quote it when reporting a problem.
+func Error(w http.ResponseWriter, r *http.Request, status int, code apierror.Code, message string) {
+	meta := getMeta(r)
 	JSON(w, status, ErrorResponse{
 		Error: ErrorDetail{
-			Code:    code,
-			Message: message,
+			Code:      code,
+			Message:   message,
+			RequestID: meta.RequestID,
+			Timestamp: meta.Timestamp,
 		},
 	})
 }
@@ -204,7 +262,7 @@ func ValidationErrors(w http.ResponseWriter, r *http.Request, errors validation.
 	meta := getMeta(r)
 	JSON(w, http.StatusBadRequest, ErrorResponse{
 		Error: ErrorDetail{
-			Code:      "VALIDATION_ERROR",
+			Code:      apierror.ValidationError,
 			Message:   "Invalid request payload",
 			Details:   errors,
 			RequestID: meta.RequestID,
@@ -218,22 +276,22 @@ func NotFound(w http.ResponseWriter, r *http.Request, message string) {
 	if message == "" {
 		message = "Resource not found"
 	}
-	Error(w, r, http.StatusNotFound, "NOT_FOUND", message)
+	Error(w, r, http.StatusNotFound, apierror.NotFound, message)
 }
 
 // Unauthorized sends a 401 response
 func Unauthorized(w http.ResponseWriter, r *http.Request) {
-	Error(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required")
+	Error(w, r, http.StatusUnauthorized, apierror.Unauthorized, "Authentication required")
 }
 
 // Forbidden sends a 403 response
 func Forbidden(w http.ResponseWriter, r *http.Request) {
-	Error(w, r, http.StatusForbidden, "FORBIDDEN", "Access denied")
+	Error(w, r, http.StatusForbidden, apierror.Forbidden, "Access denied")
 }
 
 // InternalError sends a 500 response
 func InternalError(w http.ResponseWriter, r *http.Request) {
-	Error(w, r, http.StatusInternalServerError, "INTERNAL_ERROR", "An internal error occurred")
+	Error(w, r, http.StatusInternalServerError, apierror.InternalError, "An internal error occurred")
 }
 
 // Created sends a 201 response with the created resource
@@ -250,3 +308,14 @@ func NoContent(w http.ResponseWriter) {
 func OK(w http.ResponseWriter, r *http.Request, data interface{}) {
 	Success(w, r, http.StatusOK, data)
 }
+
+// Download sends content with support for Range and conditional requests, so
+// large downloads can be resumed and streamed as 206 Partial Content. The
+// caller sets Content-Type and Content-Disposition. The strong ETag lets
+// clients send If-Range, which makes a resume start over rather than splice
+// two versions together. A zero modtime omits Last-Modified.
+func Download(w http.ResponseWriter, r *http.Request, modtime time.Time, content []byte) {
+	sum := sha256.Sum256(content)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	http.ServeContent(w, r, "", modtime, bytes.NewReader(content))
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
 	"github.com/MohamedElashri/snipo/internal/auth"
 	"github.com/MohamedElashri/snipo/internal/models"
 	"github.com/MohamedElashri/snipo/internal/repository"
@@ -11,12 +12,12 @@ import (
 
 // SettingsHandler handles settings related endpoints
 type SettingsHandler struct {
-	repo        *repository.SettingsRepository
+	repo        repository.SettingsStore
 	authService *auth.Service
 }
 
 // NewSettingsHandler creates a new settings handler
-func NewSettingsHandler(repo *repository.SettingsRepository, authService *auth.Service) *SettingsHandler {
+func NewSettingsHandler(repo repository.SettingsStore, authService *auth.Service) *SettingsHandler {
 	return &SettingsHandler{repo: repo, authService: authService}
 }
 
@@ -35,7 +36,7 @@ func (h *SettingsHandler) Get(w http.ResponseWriter, r *http.Request) {
 func (h *SettingsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	var input models.SettingsInput
 	if err := DecodeJSON(r, &input); err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_JSON", "Invalid request body")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid request body")
 		return
 	}
 
@@ -49,11 +50,11 @@ func (h *SettingsHandler) Update(w http.ResponseWriter, r *http.Request) {
 		current, err := h.repo.Get(r.Context())
 		if err == nil && current.DisableLogin != input.DisableLogin {
 			if input.Password == "" {
-				Error(w, r, http.StatusForbidden, "PASSWORD_REQUIRED", "Password is required to change the login setting")
+				Error(w, r, http.StatusForbidden, apierror.PasswordRequired, "Password is required to change the login setting")
 				return
 			}
 			if !h.authService.VerifyPassword(input.Password) {
-				Error(w, r, http.StatusForbidden, "INVALID_PASSWORD", "Invalid password")
+				Error(w, r, http.StatusForbidden, apierror.InvalidPassword, "Invalid password")
 				return
 			}
 		}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/services"
+)
+
+const (
+	// slashCommandMaxBody caps the form body; Slack and Mattermost send a few KB
+	slashCommandMaxBody = 64 << 10
+	// slashCommandMaxAge rejects replayed Slack requests, as Slack recommends
+	slashCommandMaxAge = 5 * time.Minute
+	// slashCommandResults is how many matches a reply lists
+	slashCommandResults = 5
+	// slashCommandMaxCode keeps each code block under the chat message limits
+	slashCommandMaxCode = 1500
+)
+
+// SlashCommandHandler answers Slack and Mattermost slash commands with quick
+// search results from the snippet library
+type SlashCommandHandler struct {
+	snippets           *services.SnippetService
+	slackSigningSecret string
+	mattermostToken    string
+	now                func() time.Time
+}
+
+// NewSlashCommandHandler creates a new slash command handler. Requests are
+// accepted when they carry a valid Slack signature or the Mattermost token;
+// an empty secret disables that platform.
+func NewSlashCommandHandler(snippets *services.SnippetService, slackSigningSecret, mattermostToken string) *SlashCommandHandler {
+	return &SlashCommandHandler{
+		snippets:           snippets,
+		slackSigningSecret: slackSigningSecret,
+		mattermostToken:    mattermostToken,
+		now:                time.Now,
+	}
+}
+
+// slashResponse is the slash command response shared by Slack and
+// Mattermost. Mattermost renders text; Slack renders blocks and uses text as
+// the notification fallback.
+type slashResponse struct {
+	ResponseType string       `json:"response_type"`
+	Text         string       `json:"text"`
+	Blocks       []slackBlock `json:"blocks,omitempty"`
+}
+
+type slackBlock map[string]any
+
+// Command handles POST /api/v1/integrations/slack/command
+// Public endpoint authenticated by the Slack request signature or the
+// Mattermost command token
+func (h *SlashCommandHandler) Command(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, slashCommandMaxBody))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			Error(w, r, http.StatusRequestEntityTooLarge, apierror.PayloadTooLarge, "Payload must be less than 64KB")
+			return
+		}
+		Error(w, r, http.StatusBadRequest, apierror.ReadError, "Failed to read payload")
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidPayload, "Payload must be form encoded")
+		return
+	}
+	if !h.authorized(r, body, form) {
+		Error(w, r, http.StatusUnauthorized, apierror.Unauthorized, "Invalid request signature or token")
+		return
+	}
+
+	query := strings.TrimSpace(form.Get("text"))
+	command := form.Get("command")
+	if command == "" {
+		command = "/snipo"
+	}
+	if query == "" || query == "help" {
+		JSON(w, http.StatusOK, slashResponse{
+			ResponseType: "ephemeral",
+			Text:         fmt.Sprintf("Usage: `%s <query>` searches the snippet library. Only you see the results.", command),
+		})
+		return
+	}
+
+	results, err := h.snippets.Search(r.Context(), query, slashCommandResults)
+	if err != nil {
+		JSON(w, http.StatusOK, slashResponse{ResponseType: "ephemeral", Text: "Search failed, please try again later."})
+		return
+	}
+
+	JSON(w, http.StatusOK, slashResults(query, results))
+}
+
+// authorized checks the Slack signature when one is sent, and the
+// Mattermost token otherwise
+func (h *SlashCommandHandler) authorized(r *http.Request, body []byte, form url.Values) bool {
+	if signature := r.Header.Get("X-Slack-Signature"); signature != "" {
+		if h.slackSigningSecret == "" {
+			return false
+		}
+		timestamp := r.Header.Get("X-Slack-Request-Timestamp")
+		sent, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return false
+		}
+		if age := h.now().Sub(time.Unix(sent, 0)); age > slashCommandMaxAge || age < -slashCommandMaxAge {
+			return false
+		}
+		mac := hmac.New(sha256.New, []byte(h.slackSigningSecret))
+		mac.Write([]byte("v0:" + timestamp + ":"))
+		mac.Write(body)
+		return hmac.Equal([]byte(signature), []byte("v0="+hex.EncodeToString(mac.Sum(nil))))
+	}
+
+	if h.mattermostToken == "" {
+		return false
+	}
+	token := form.Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Token ") {
+		token = strings.TrimPrefix(auth, "Token ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.mattermostToken)) == 1
+}
+
+// slashResults lists each match with its content in a code block, which
+// both clients show with a copy button
+func slashResults(query string, results []models.SearchResult) slashResponse {
+	if len(results) == 0 {
+		return slashResponse{ResponseType: "ephemeral", Text: fmt.Sprintf("No snippets match *%s*.", query)}
+	}
+
+	header := fmt.Sprintf("Top %d matches for *%s*", len(results), query)
+	text := []string{header}
+	blocks := []slackBlock{{"type": "section", "text": slackMarkdown(header)}}
+	for _, result := range results {
+		code := result.Content
+		if runes := []rune(code); len(runes) > slashCommandMaxCode {
+			code = string(runes[:slashCommandMaxCode]) + "\n…"
+		}
+		title := fmt.Sprintf("*%s* · %s", result.Title, result.Language)
+		if result.Description != "" {
+			title += "\n" + result.Description
+		}
+
+		text = append(text, title+"\n```"+result.Language+"\n"+code+"\n```")
+		blocks = append(blocks,
+			slackBlock{"type": "divider"},
+			slackBlock{"type": "section", "text": slackMarkdown(title)},
+			slackBlock{"type": "rich_text", "elements": []any{
+				map[string]any{"type": "rich_text_preformatted", "elements": []any{
+					map[string]any{"type": "text", "text": code},
+				}},
+			}},
+		)
+	}
+
+	return slashResponse{ResponseType: "ephemeral", Text: strings.Join(text, "\n\n"), Blocks: blocks}
+}
+
+func slackMarkdown(text string) map[string]string {
+	return map[string]string{"type": "mrkdwn", "text": text}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestSlashCommandHandler(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := repository.NewSnippetRepository(db)
+	service := services.NewSnippetService(repo, testutil.TestLogger())
+	if _, err := repo.Create(testutil.TestContext(), &models.SnippetInput{
+		Title:    "Undo last commit",
+		Content:  "git reset --soft HEAD~1",
+		Language: "shell",
+	}); err != nil {
+		t.Fatalf("failed to create snippet: %v", err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	handler := NewSlashCommandHandler(service, "signing-secret", "mm-token")
+	handler.now = func() time.Time { return now }
+
+	slackRequest := func(body string, sentAt time.Time, secret string) *http.Request {
+		timestamp := strconv.FormatInt(sentAt.Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("v0:" + timestamp + ":" + body))
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/integrations/slack/command", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+		req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+		return withRequestID(req)
+	}
+	form := url.Values{"command": {"/snipo"}, "text": {"undo commit"}}.Encode()
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"signed slack request", slackRequest(form, now, "signing-secret"), http.StatusOK},
+		{"wrong signing secret", slackRequest(form, now, "other"), http.StatusUnauthorized},
+		{"stale timestamp", slackRequest(form, now.Add(-10*time.Minute), "signing-secret"), http.StatusUnauthorized},
+		{"mattermost token", withRequestID(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form+"&token=mm-token"))), http.StatusOK},
+		{"wrong mattermost token", withRequestID(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form+"&token=nope"))), http.StatusUnauthorized},
+		{"unauthenticated", withRequestID(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form))), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.Command(w, tt.req)
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+
+			var resp slashResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if resp.ResponseType != "ephemeral" {
+				t.Errorf("expected an ephemeral response, got %q", resp.ResponseType)
+			}
+			if !strings.Contains(resp.Text, "```shell\ngit reset --soft HEAD~1\n```") {
+				t.Errorf("expected the match in a code block, got %q", resp.Text)
+			}
+			if len(resp.Blocks) != 4 {
+				t.Errorf("expected header, divider, title and code blocks, got %d", len(resp.Blocks))
+			}
+		})
+	}
+}
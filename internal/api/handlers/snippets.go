@@ -1,30 +1,57 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
 	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
 	"github.com/MohamedElashri/snipo/internal/services"
 	"github.com/MohamedElashri/snipo/internal/validation"
 )
 
 // SnippetHandler handles snippet-related HTTP requests
 type SnippetHandler struct {
-	service *services.SnippetService
+	service services.SnippetManager
 }
 
 // NewSnippetHandler creates a new snippet handler
-func NewSnippetHandler(service *services.SnippetService) *SnippetHandler {
+func NewSnippetHandler(service services.SnippetManager) *SnippetHandler {
 	return &SnippetHandler{service: service}
 }
 
 // List handles GET /api/v1/snippets
 func (h *SnippetHandler) List(w http.ResponseWriter, r *http.Request) {
+	filter := parseSnippetFilter(r)
+
+	result, err := h.service.List(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			Error(w, r, http.StatusBadRequest, apierror.InvalidCursor, err.Error())
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	p := result.Pagination
+	SuccessCursorList(w, r, result.Data, p.Page, p.Limit, p.Total, p.NextCursor)
+}
+
+// parseSnippetFilter reads the list filter, paging and sort parameters
+// shared by List and Neighbors
+func parseSnippetFilter(r *http.Request) models.SnippetFilter {
 	filter := models.DefaultSnippetFilter()
 
 	// Parse query parameters
@@ -44,6 +71,8 @@ func (h *SnippetHandler) List(w http.ResponseWriter, r *http.Request) {
 		filter.Query = q
 	}
 
+	filter.Cursor = r.URL.Query().Get("cursor")
+
 	if lang := r.URL.Query().Get("language"); lang != "" {
 		filter.Language = lang
 	}
@@ -95,7 +124,12 @@ func (h *SnippetHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if sortBy := r.URL.Query().Get("sort"); sortBy != "" {
+	// sort_by/sort_order are preferred; sort/order remain for older clients
+	sortBy := r.URL.Query().Get("sort_by")
+	if sortBy == "" {
+		sortBy = r.URL.Query().Get("sort")
+	}
+	if sortBy != "" {
 		validSortColumns := map[string]bool{
 			"id":          true,
 			"title":       true,
@@ -105,6 +139,8 @@ func (h *SnippetHandler) List(w http.ResponseWriter, r *http.Request) {
 			"is_favorite": true,
 			"is_public":   true,
 			"view_count":  true,
+			"use_count":   true,
+			"last_used":   true,
 			"created_at":  true,
 			"updated_at":  true,
 		}
@@ -113,27 +149,57 @@ func (h *SnippetHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if order := r.URL.Query().Get("order"); order != "" {
+	order := r.URL.Query().Get("sort_order")
+	if order == "" {
+		order = r.URL.Query().Get("order")
+	}
+	if order != "" {
 		if order == "asc" || order == "desc" {
 			filter.SortOrder = order
 		}
 	}
+	return filter
+}
 
-	result, err := h.service.List(r.Context(), filter)
+// Neighbors handles GET /api/v1/snippets/{id}/neighbors. It accepts the
+// same filter and sort parameters as List and returns the IDs of the
+// snippets around id, so detail views can step through a list without
+// fetching it.
+func (h *SnippetHandler) Neighbors(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
+		return
+	}
+
+	neighbors, err := h.service.Neighbors(r.Context(), id, parseSnippetFilter(r))
 	if err != nil {
+		if errors.Is(err, services.ErrSnippetNotFound) {
+			NotFound(w, r, "Snippet not found in the filtered list")
+			return
+		}
 		InternalError(w, r)
 		return
 	}
 
-	// Use SuccessList to include pagination metadata
-	SuccessList(w, r, result.Data, result.Pagination.Page, result.Pagination.Limit, result.Pagination.Total)
+	OK(w, r, neighbors)
 }
 
 // Create handles POST /api/v1/snippets
 func (h *SnippetHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var input models.SnippetInput
 	if err := DecodeJSON(r, &input); err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON payload")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	if err := h.service.CheckDuplicateContent(r.Context(), &input); err != nil {
+		var duplicate *services.DuplicateContentError
+		if errors.As(err, &duplicate) {
+			duplicateContent(w, r, duplicate.Existing)
+			return
+		}
+		InternalError(w, r)
 		return
 	}
 
@@ -152,11 +218,43 @@ func (h *SnippetHandler) Create(w http.ResponseWriter, r *http.Request) {
 	Created(w, r, snippet)
 }
 
+// duplicateContent sends a 409 pointing at the snippet that already has the content
+func duplicateContent(w http.ResponseWriter, r *http.Request, existing models.DuplicateMatch) {
+	meta := getMeta(r)
+	JSON(w, http.StatusConflict, ErrorResponse{
+		Error: ErrorDetail{
+			Code:       apierror.DuplicateContent,
+			Message:    fmt.Sprintf("A snippet with identical content already exists: %q", existing.Title),
+			ExistingID: existing.ID,
+			RequestID:  meta.RequestID,
+			Timestamp:  meta.Timestamp,
+		},
+	})
+}
+
+// CheckDuplicates handles POST /api/v1/snippets/check-duplicates, listing
+// snippets with the same title or content as one about to be created
+func (h *SnippetHandler) CheckDuplicates(w http.ResponseWriter, r *http.Request) {
+	var input models.DuplicateCheckInput
+	if err := DecodeJSON(r, &input); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	matches, err := h.service.FindDuplicates(r.Context(), &input)
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, matches)
+}
+
 // Get handles GET /api/v1/snippets/{id}
 func (h *SnippetHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_ID", "Snippet ID is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
 		return
 	}
 
@@ -177,13 +275,13 @@ func (h *SnippetHandler) Get(w http.ResponseWriter, r *http.Request) {
 func (h *SnippetHandler) Update(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_ID", "Snippet ID is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
 		return
 	}
 
 	var input models.SnippetInput
 	if err := DecodeJSON(r, &input); err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON payload")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
 		return
 	}
 
@@ -193,6 +291,10 @@ func (h *SnippetHandler) Update(w http.ResponseWriter, r *http.Request) {
 			NotFound(w, r, "Snippet not found")
 			return
 		}
+		if errors.Is(err, services.ErrSnippetReadOnly) {
+			Error(w, r, http.StatusForbidden, apierror.ReadOnly, "Snippet is mirrored from a remote source and cannot be modified")
+			return
+		}
 		var validationErrs validation.ValidationErrors
 		if errors.As(err, &validationErrs) {
 			ValidationErrors(w, r, validationErrs)
@@ -205,11 +307,49 @@ func (h *SnippetHandler) Update(w http.ResponseWriter, r *http.Request) {
 	OK(w, r, snippet)
 }
 
+// Upsert handles PUT /api/v1/snippets/external/{external_id}
+// Creates the snippet on the first call and updates it on later ones
+func (h *SnippetHandler) Upsert(w http.ResponseWriter, r *http.Request) {
+	// Escaped slashes stay escaped in the route, so "team%2Frunbook" is allowed
+	externalID, err := url.PathUnescape(chi.URLParam(r, "external_id"))
+	if err != nil || externalID == "" {
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "External ID is required")
+		return
+	}
+
+	var input models.SnippetInput
+	if err := DecodeJSON(r, &input); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	snippet, created, err := h.service.Upsert(r.Context(), externalID, &input)
+	if err != nil {
+		if errors.Is(err, services.ErrSnippetReadOnly) {
+			Error(w, r, http.StatusForbidden, apierror.ReadOnly, "Snippet is mirrored from a remote source and cannot be modified")
+			return
+		}
+		var validationErrs validation.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			ValidationErrors(w, r, validationErrs)
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	if created {
+		Created(w, r, snippet)
+		return
+	}
+	OK(w, r, snippet)
+}
+
 // Delete handles DELETE /api/v1/snippets/{id}
 func (h *SnippetHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_ID", "Snippet ID is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
 		return
 	}
 
@@ -220,6 +360,10 @@ func (h *SnippetHandler) Delete(w http.ResponseWriter, r *http.Request) {
 			NotFound(w, r, "Snippet not found")
 			return
 		}
+		if errors.Is(err, services.ErrSnippetReadOnly) {
+			Error(w, r, http.StatusForbidden, apierror.ReadOnly, "Snippet is mirrored from a remote source and cannot be deleted")
+			return
+		}
 		InternalError(w, r)
 		return
 	}
@@ -231,7 +375,7 @@ func (h *SnippetHandler) Delete(w http.ResponseWriter, r *http.Request) {
 func (h *SnippetHandler) Restore(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_ID", "Snippet ID is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
 		return
 	}
 
@@ -248,11 +392,46 @@ func (h *SnippetHandler) Restore(w http.ResponseWriter, r *http.Request) {
 	OK(w, r, map[string]string{"status": "restored"})
 }
 
+// Bulk handles POST /api/v1/snippets/bulk, applying one action to many
+// snippets in a single transaction
+func (h *SnippetHandler) Bulk(w http.ResponseWriter, r *http.Request) {
+	var input models.BulkSnippetInput
+	if err := DecodeJSON(r, &input); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	result, err := h.service.Bulk(r.Context(), &input)
+	if err != nil {
+		if errors.Is(err, services.ErrSnippetNotFound) {
+			NotFound(w, r, "One or more snippets were not found; nothing was changed")
+			return
+		}
+		if errors.Is(err, services.ErrSnippetReadOnly) {
+			Error(w, r, http.StatusForbidden, apierror.ReadOnly, "One or more snippets are mirrored from a remote source and cannot be modified")
+			return
+		}
+		var validationErrs validation.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			ValidationErrors(w, r, validationErrs)
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	if input.Action == models.BulkCreate {
+		Created(w, r, result)
+		return
+	}
+	OK(w, r, result)
+}
+
 // ToggleFavorite handles POST /api/v1/snippets/{id}/favorite
 func (h *SnippetHandler) ToggleFavorite(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_ID", "Snippet ID is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
 		return
 	}
 
@@ -269,11 +448,57 @@ func (h *SnippetHandler) ToggleFavorite(w http.ResponseWriter, r *http.Request)
 	OK(w, r, snippet)
 }
 
+// MarkUsed handles POST /api/v1/snippets/{id}/used
+// Clients call it when a snippet is copied or pasted, which is tracked
+// separately from views so frequently used snippets can be sorted first.
+func (h *SnippetHandler) MarkUsed(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
+		return
+	}
+
+	snippet, err := h.service.RecordUse(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrSnippetNotFound) {
+			NotFound(w, r, "Snippet not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, snippet)
+}
+
+// GetViewStats handles GET /api/v1/snippets/{id}/views
+// Reports total views, share page visits by signed-out visitors and the
+// domains those visitors came from.
+func (h *SnippetHandler) GetViewStats(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
+		return
+	}
+
+	stats, err := h.service.GetViewStats(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrSnippetNotFound) {
+			NotFound(w, r, "Snippet not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, stats)
+}
+
 // ToggleArchive handles POST /api/v1/snippets/{id}/archive
 func (h *SnippetHandler) ToggleArchive(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_ID", "Snippet ID is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
 		return
 	}
 
@@ -294,7 +519,7 @@ func (h *SnippetHandler) ToggleArchive(w http.ResponseWriter, r *http.Request) {
 func (h *SnippetHandler) Duplicate(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_ID", "Snippet ID is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
 		return
 	}
 
@@ -311,11 +536,41 @@ func (h *SnippetHandler) Duplicate(w http.ResponseWriter, r *http.Request) {
 	Created(w, r, snippet)
 }
 
+// Fork handles POST /api/v1/snippets/fork
+// Copies a public snippet by its share or public API URL and records its provenance
+func (h *SnippetHandler) Fork(w http.ResponseWriter, r *http.Request) {
+	var input models.ForkInput
+	if err := DecodeJSON(r, &input); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	snippet, err := h.service.Fork(r.Context(), input.URL)
+	if err != nil {
+		var validationErrs validation.ValidationErrors
+		switch {
+		case errors.Is(err, services.ErrInvalidForkURL):
+			Error(w, r, http.StatusBadRequest, apierror.InvalidURL, "URL must be a public snippet share or API link")
+		case errors.Is(err, services.ErrBlockedAddress):
+			Error(w, r, http.StatusBadRequest, apierror.InvalidURL, "URL must point to a public address")
+		case errors.Is(err, services.ErrForkSourceNotFound):
+			NotFound(w, r, "Source snippet not found or not public")
+		case errors.As(err, &validationErrs):
+			ValidationErrors(w, r, validationErrs)
+		default:
+			Error(w, r, http.StatusBadGateway, apierror.ForkFailed, "Failed to fetch source snippet")
+		}
+		return
+	}
+
+	Created(w, r, snippet)
+}
+
 // Search handles GET /api/v1/snippets/search
 func (h *SnippetHandler) Search(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 	if query == "" {
-		OK(w, r, []models.Snippet{})
+		OK(w, r, []models.SearchResult{})
 		return
 	}
 
@@ -338,11 +593,35 @@ func (h *SnippetHandler) Search(w http.ResponseWriter, r *http.Request) {
 	OK(w, r, snippets)
 }
 
+// SearchIndex handles GET /api/v1/snippets/search-index
+// Exports the titles and tags of the caller's snippets for local filtering.
+// With since, only the changes from that time on are returned.
+func (h *SnippetHandler) SearchIndex(w http.ResponseWriter, r *http.Request) {
+	var since *time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			Error(w, r, http.StatusBadRequest, apierror.InvalidRequest, "since must be an RFC 3339 timestamp")
+			return
+		}
+		since = &t
+	}
+
+	export, err := h.service.ExportSearchIndex(r.Context(), since)
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, no-cache")
+	OK(w, r, export)
+}
+
 // GetPublic handles GET /api/v1/snippets/public/{id}
 func (h *SnippetHandler) GetPublic(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_ID", "Snippet ID is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
 		return
 	}
 
@@ -359,18 +638,45 @@ func (h *SnippetHandler) GetPublic(w http.ResponseWriter, r *http.Request) {
 	OK(w, r, snippet)
 }
 
+// PublicFeed handles GET /api/v1/snippets/public
+// Lists all public snippets for other instances to mirror. Supports conditional
+// requests via ETag/If-None-Match so unchanged feeds cost a 304.
+func (h *SnippetHandler) PublicFeed(w http.ResponseWriter, r *http.Request) {
+	snippets, err := h.service.ListPublic(r.Context())
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	data, err := json.Marshal(snippets)
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	OK(w, r, snippets)
+}
+
 // GetPublicFile handles GET /api/v1/snippets/public/{id}/files/{filename}
 // Returns raw file content for downloading individual files from public snippets
 func (h *SnippetHandler) GetPublicFile(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_ID", "Snippet ID is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
 		return
 	}
 
 	filename := chi.URLParam(r, "filename")
 	if filename == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_FILENAME", "Filename is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingFilename, "Filename is required")
 		return
 	}
 
@@ -385,60 +691,53 @@ func (h *SnippetHandler) GetPublicFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Find the requested file
-	var targetFile *models.SnippetFile
+	targetFile := findPublicFile(snippet, filename)
+	if targetFile == nil {
+		NotFound(w, r, "File not found in snippet")
+		return
+	}
+
+	// Set appropriate headers for raw file download
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", "inline; filename=\""+filename+"\"")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	Download(w, r, snippet.UpdatedAt, []byte(targetFile.Content))
+}
+
+// findPublicFile returns the file of a public snippet named filename, or the
+// content of a legacy single-file snippet under any name, or nil
+func findPublicFile(snippet *models.Snippet, filename string) *models.SnippetFile {
 	for i := range snippet.Files {
 		if snippet.Files[i].Filename == filename {
-			targetFile = &snippet.Files[i]
-			break
+			return &snippet.Files[i]
 		}
 	}
 
-	// If not found, try with sanitized filename (to handle legacy files with spaces)
-	if targetFile == nil {
-		sanitizedFilename := validation.SanitizeFilename(filename)
-		for i := range snippet.Files {
-			if validation.SanitizeFilename(snippet.Files[i].Filename) == sanitizedFilename {
-				targetFile = &snippet.Files[i]
-				break
-			}
+	// Try with the sanitized filename (to handle legacy files with spaces)
+	sanitizedFilename := validation.SanitizeFilename(filename)
+	for i := range snippet.Files {
+		if validation.SanitizeFilename(snippet.Files[i].Filename) == sanitizedFilename {
+			return &snippet.Files[i]
 		}
 	}
 
-	// If not found in files array, check if it's the legacy single-file content
-	if targetFile == nil && len(snippet.Files) == 0 {
-		// For legacy single-file snippets, use the main content
-		targetFile = &models.SnippetFile{
+	// For legacy single-file snippets, use the main content
+	if len(snippet.Files) == 0 {
+		return &models.SnippetFile{
 			Filename: filename,
 			Content:  snippet.Content,
 			Language: snippet.Language,
 		}
 	}
-
-	if targetFile == nil {
-		NotFound(w, r, "File not found in snippet")
-		return
-	}
-
-	// Set appropriate headers for raw file download
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	w.Header().Set("Content-Disposition", "inline; filename=\""+filename+"\"")
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-
-	// Write the file content
-	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write([]byte(targetFile.Content)); err != nil {
-		// Log the error but don't return as headers are already sent
-		// This is best effort - connection may have been closed
-		return
-	}
+	return nil
 }
 
 // GetHistory handles GET /api/v1/snippets/{id}/history
 func (h *SnippetHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_ID", "Snippet ID is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
 		return
 	}
 
@@ -463,23 +762,135 @@ func (h *SnippetHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 	OK(w, r, history)
 }
 
+// Activity handles GET /api/v1/activity
+// Lists recent changes to the snippets the caller can see, newest first
+func (h *SnippetHandler) Activity(w http.ResponseWriter, r *http.Request) {
+	filter, ok := activityFilter(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := h.service.Activity(r.Context(), filter)
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, items)
+}
+
+// SnippetActivity handles GET /api/v1/snippets/{id}/activity
+func (h *SnippetHandler) SnippetActivity(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
+		return
+	}
+
+	filter, ok := activityFilter(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := h.service.SnippetActivity(r.Context(), id, filter)
+	if err != nil {
+		if errors.Is(err, services.ErrSnippetNotFound) {
+			NotFound(w, r, "Snippet not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, items)
+}
+
+// activityFilter parses the since, user_id and limit query parameters,
+// writing an error if one is invalid
+func activityFilter(w http.ResponseWriter, r *http.Request) (models.ActivityFilter, bool) {
+	filter := models.ActivityFilter{Limit: 50}
+	query := r.URL.Query()
+
+	if since := query.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			Error(w, r, http.StatusBadRequest, apierror.InvalidRequest, "since must be an RFC 3339 timestamp")
+			return filter, false
+		}
+		filter.Since = &t
+	}
+	if userID := query.Get("user_id"); userID != "" {
+		id, err := strconv.ParseInt(userID, 10, 64)
+		if err != nil || id < 0 {
+			Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid user ID")
+			return filter, false
+		}
+		filter.UserID = &id
+	}
+	if limit := query.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 1 || l > 200 {
+			Error(w, r, http.StatusBadRequest, apierror.InvalidRequest, "limit must be between 1 and 200")
+			return filter, false
+		}
+		filter.Limit = l
+	}
+
+	return filter, true
+}
+
+// DiffHistory handles GET /api/v1/snippets/{id}/history/{from}/diff/{to}
+func (h *SnippetHandler) DiffHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
+		return
+	}
+
+	fromID, err := strconv.ParseInt(chi.URLParam(r, "from"), 10, 64)
+	if err != nil || fromID <= 0 {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidHistoryID, "Invalid history ID")
+		return
+	}
+	toID, err := strconv.ParseInt(chi.URLParam(r, "to"), 10, 64)
+	if err != nil || toID <= 0 {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidHistoryID, "Invalid history ID")
+		return
+	}
+
+	result, err := h.service.DiffHistory(r.Context(), id, fromID, toID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSnippetNotFound):
+			NotFound(w, r, "Snippet not found")
+		case errors.Is(err, services.ErrHistoryNotFound):
+			NotFound(w, r, "History entry not found")
+		default:
+			InternalError(w, r)
+		}
+		return
+	}
+
+	OK(w, r, result)
+}
+
 // RestoreFromHistory handles POST /api/v1/snippets/{id}/history/{history_id}/restore
 func (h *SnippetHandler) RestoreFromHistory(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_ID", "Snippet ID is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingID, "Snippet ID is required")
 		return
 	}
 
 	historyIDStr := chi.URLParam(r, "history_id")
 	if historyIDStr == "" {
-		Error(w, r, http.StatusBadRequest, "MISSING_HISTORY_ID", "History ID is required")
+		Error(w, r, http.StatusBadRequest, apierror.MissingHistoryID, "History ID is required")
 		return
 	}
 
 	historyID, err := strconv.ParseInt(historyIDStr, 10, 64)
 	if err != nil || historyID <= 0 {
-		Error(w, r, http.StatusBadRequest, "INVALID_HISTORY_ID", "Invalid history ID")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidHistoryID, "Invalid history ID")
 		return
 	}
 
@@ -489,7 +900,7 @@ func (h *SnippetHandler) RestoreFromHistory(w http.ResponseWriter, r *http.Reque
 			NotFound(w, r, "Snippet not found")
 			return
 		}
-		Error(w, r, http.StatusBadRequest, "RESTORE_FAILED", err.Error())
+		Error(w, r, http.StatusBadRequest, apierror.RestoreFailed, err.Error())
 		return
 	}
 
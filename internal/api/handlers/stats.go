@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/MohamedElashri/snipo/internal/repository"
+)
+
+// StatsHandler serves aggregate snippet statistics
+type StatsHandler struct {
+	repo repository.StatsReader
+}
+
+// NewStatsHandler creates a new stats handler
+func NewStatsHandler(repo repository.StatsReader) *StatsHandler {
+	return &StatsHandler{repo: repo}
+}
+
+// Languages handles GET /api/v1/stats/languages
+func (h *StatsHandler) Languages(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.repo.SnippetStats(r.Context())
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, stats)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
 	"github.com/MohamedElashri/snipo/internal/models"
 	"github.com/MohamedElashri/snipo/internal/repository"
 	"github.com/MohamedElashri/snipo/internal/validation"
@@ -14,16 +15,27 @@ import (
 
 // TagHandler handles tag-related HTTP requests
 type TagHandler struct {
-	repo *repository.TagRepository
+	repo repository.TagStore
 }
 
 // NewTagHandler creates a new tag handler
-func NewTagHandler(repo *repository.TagRepository) *TagHandler {
+func NewTagHandler(repo repository.TagStore) *TagHandler {
 	return &TagHandler{repo: repo}
 }
 
-// List handles GET /api/v1/tags
+// List handles GET /api/v1/tags. With is_deleted=true it lists the tags in
+// the trash instead.
 func (h *TagHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("is_deleted") == "true" {
+		tags, err := h.repo.ListDeleted(r.Context())
+		if err != nil {
+			InternalError(w, r)
+			return
+		}
+		OK(w, r, tags)
+		return
+	}
+
 	tags, err := h.repo.List(r.Context())
 	if err != nil {
 		InternalError(w, r)
@@ -45,7 +57,7 @@ func (h *TagHandler) List(w http.ResponseWriter, r *http.Request) {
 func (h *TagHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var input models.TagInput
 	if err := DecodeJSON(r, &input); err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON payload")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
 		return
 	}
 
@@ -60,15 +72,10 @@ func (h *TagHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set default color if not provided
-	if input.Color == "" {
-		input.Color = "#6366f1"
-	}
-
 	// Check if tag already exists
 	existing, err := h.repo.GetByName(r.Context(), input.Name)
 	if err == nil && existing != nil {
-		Error(w, r, http.StatusConflict, "TAG_EXISTS", "A tag with this name already exists")
+		Error(w, r, http.StatusConflict, apierror.TagExists, "A tag with this name already exists")
 		return
 	}
 
@@ -85,7 +92,7 @@ func (h *TagHandler) Create(w http.ResponseWriter, r *http.Request) {
 func (h *TagHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_ID", "Invalid tag ID")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid tag ID")
 		return
 	}
 
@@ -112,13 +119,13 @@ func (h *TagHandler) Get(w http.ResponseWriter, r *http.Request) {
 func (h *TagHandler) Update(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_ID", "Invalid tag ID")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid tag ID")
 		return
 	}
 
 	var input models.TagInput
 	if err := DecodeJSON(r, &input); err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON payload")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
 		return
 	}
 
@@ -136,21 +143,20 @@ func (h *TagHandler) Update(w http.ResponseWriter, r *http.Request) {
 	// Check if another tag with same name exists
 	existing, err := h.repo.GetByName(r.Context(), input.Name)
 	if err == nil && existing != nil && existing.ID != id {
-		Error(w, r, http.StatusConflict, "TAG_EXISTS", "A tag with this name already exists")
+		Error(w, r, http.StatusConflict, apierror.TagExists, "A tag with this name already exists")
 		return
 	}
 
-	// Set default color if not provided
-	if input.Color == "" {
-		input.Color = "#6366f1"
-	}
-
 	tag, err := h.repo.Update(r.Context(), id, &input)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			NotFound(w, r, "Tag not found")
 			return
 		}
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			Error(w, r, http.StatusConflict, apierror.TagExists, "A tag with this name is in the trash")
+			return
+		}
 		InternalError(w, r)
 		return
 	}
@@ -158,15 +164,20 @@ func (h *TagHandler) Update(w http.ResponseWriter, r *http.Request) {
 	OK(w, r, tag)
 }
 
-// Delete handles DELETE /api/v1/tags/{id}
+// Delete handles DELETE /api/v1/tags/{id}. A tag in the trash is deleted
+// for good with permanent=true.
 func (h *TagHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_ID", "Invalid tag ID")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid tag ID")
 		return
 	}
 
-	err = h.repo.Delete(r.Context(), id)
+	if r.URL.Query().Get("permanent") == "true" {
+		err = h.repo.Purge(r.Context(), id)
+	} else {
+		err = h.repo.Delete(r.Context(), id)
+	}
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
 			NotFound(w, r, "Tag not found")
@@ -178,3 +189,32 @@ func (h *TagHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	NoContent(w)
 }
+
+// Restore handles POST /api/v1/tags/{id}/restore
+func (h *TagHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid tag ID")
+		return
+	}
+
+	if err := h.repo.Restore(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			NotFound(w, r, "Tag not found in the trash")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	tag, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+	if count, err := h.repo.GetTagSnippetCount(r.Context(), tag.ID); err == nil {
+		tag.SnippetCount = count
+	}
+
+	OK(w, r, tag)
+}
@@ -4,10 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
 	"github.com/MohamedElashri/snipo/internal/auth"
 	"github.com/MohamedElashri/snipo/internal/models"
 	"github.com/MohamedElashri/snipo/internal/repository"
@@ -53,26 +57,27 @@ func (h *TokenHandler) List(w http.ResponseWriter, r *http.Request) {
 func (h *TokenHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// Block API token creation in demo mode
 	if h.demoMode {
-		Error(w, r, http.StatusForbidden, "DEMO_MODE_RESTRICTION", "API token creation is disabled in demo mode")
+		Error(w, r, http.StatusForbidden, apierror.DemoModeRestriction, "API token creation is disabled in demo mode")
 		return
 	}
 
 	var input models.APITokenInput
 	if err := DecodeJSON(r, &input); err != nil {
 		// Provide more detailed error message for debugging
-		Error(w, r, http.StatusBadRequest, "INVALID_JSON", fmt.Sprintf("Invalid JSON payload: %v", err))
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, fmt.Sprintf("Invalid JSON payload: %v", err))
 		return
 	}
 
 	// Always require password for token creation (unless auth is completely disabled)
 	if h.authService != nil && !h.authService.IsAuthDisabled() {
 		if input.Password == "" {
-			Error(w, r, http.StatusUnauthorized, "PASSWORD_REQUIRED", "Password is required to create API tokens")
+			Error(w, r, http.StatusUnauthorized, apierror.PasswordRequired, "Password is required to create API tokens")
 			return
 		}
 		// Verify password
-		if !h.authService.VerifyPassword(input.Password) {
-			Error(w, r, http.StatusUnauthorized, "INVALID_PASSWORD", "Invalid password")
+		userID, _ := models.UserIDFromContext(r.Context())
+		if !h.authService.VerifyUserPassword(userID, input.Password) {
+			Error(w, r, http.StatusUnauthorized, apierror.InvalidPassword, "Invalid password")
 			return
 		}
 	}
@@ -94,6 +99,19 @@ func (h *TokenHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Validate scopes
+	scopes, ok := normalizeScopes(input.Scopes)
+	if !ok {
+		ValidationErrors(w, r, validation.ValidationErrors{validation.ValidationError{Field: "scopes", Message: "Scopes must be any of: " + strings.Join(models.AllScopes, ", ")}})
+		return
+	}
+	input.Scopes = scopes
+
+	if verrs := validateTokenExpiry(input.ExpiresInDays, input.ExpiresAt); verrs.HasErrors() {
+		ValidationErrors(w, r, verrs)
+		return
+	}
+
 	token, err := h.repo.Create(r.Context(), &input)
 	if err != nil {
 		InternalError(w, r)
@@ -108,7 +126,7 @@ func (h *TokenHandler) Create(w http.ResponseWriter, r *http.Request) {
 func (h *TokenHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_ID", "Invalid token ID")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid token ID")
 		return
 	}
 
@@ -125,11 +143,34 @@ func (h *TokenHandler) Get(w http.ResponseWriter, r *http.Request) {
 	OK(w, r, token)
 }
 
+// Stats handles GET /api/v1/tokens/{id}/stats
+// Returns the token's request count and last use, with requests per day and
+// per client address over the last 30 days.
+func (h *TokenHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid token ID")
+		return
+	}
+
+	stats, err := h.repo.Stats(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			NotFound(w, r, "Token not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, stats)
+}
+
 // Delete handles DELETE /api/v1/tokens/{id}
 func (h *TokenHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		Error(w, r, http.StatusBadRequest, "INVALID_ID", "Invalid token ID")
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid token ID")
 		return
 	}
 
@@ -139,12 +180,13 @@ func (h *TokenHandler) Delete(w http.ResponseWriter, r *http.Request) {
 			Password string `json:"password"`
 		}
 		if err := DecodeJSON(r, &input); err != nil || input.Password == "" {
-			Error(w, r, http.StatusUnauthorized, "PASSWORD_REQUIRED", "Password is required to delete API tokens")
+			Error(w, r, http.StatusUnauthorized, apierror.PasswordRequired, "Password is required to delete API tokens")
 			return
 		}
 		// Verify password
-		if !h.authService.VerifyPassword(input.Password) {
-			Error(w, r, http.StatusUnauthorized, "INVALID_PASSWORD", "Invalid password")
+		userID, _ := models.UserIDFromContext(r.Context())
+		if !h.authService.VerifyUserPassword(userID, input.Password) {
+			Error(w, r, http.StatusUnauthorized, apierror.InvalidPassword, "Invalid password")
 			return
 		}
 	}
@@ -161,3 +203,116 @@ func (h *TokenHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	NoContent(w)
 }
+
+// Rotate handles POST /api/v1/tokens/{id}/rotate
+// The token gets a new secret and the old one stops working immediately;
+// name, permissions and scopes are kept. Unless a new expiry is given, the
+// new token lives as long as the old one was issued for.
+func (h *TokenHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	if h.demoMode {
+		Error(w, r, http.StatusForbidden, apierror.DemoModeRestriction, "API token rotation is disabled in demo mode")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid token ID")
+		return
+	}
+
+	var input models.APITokenRotateInput
+	if r.ContentLength != 0 {
+		if err := DecodeJSON(r, &input); err != nil {
+			Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+			return
+		}
+	}
+
+	// Always require password for token rotation (unless auth is completely disabled)
+	if h.authService != nil && !h.authService.IsAuthDisabled() {
+		if input.Password == "" {
+			Error(w, r, http.StatusUnauthorized, apierror.PasswordRequired, "Password is required to rotate API tokens")
+			return
+		}
+		userID, _ := models.UserIDFromContext(r.Context())
+		if !h.authService.VerifyUserPassword(userID, input.Password) {
+			Error(w, r, http.StatusUnauthorized, apierror.InvalidPassword, "Invalid password")
+			return
+		}
+	}
+
+	if verrs := validateTokenExpiry(input.ExpiresInDays, input.ExpiresAt); verrs.HasErrors() {
+		ValidationErrors(w, r, verrs)
+		return
+	}
+
+	existing, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			NotFound(w, r, "Token not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	var expiresAt *time.Time
+	switch {
+	case input.ExpiresAt != nil:
+		expiration := input.ExpiresAt.UTC()
+		expiresAt = &expiration
+	case input.ExpiresInDays != nil:
+		if *input.ExpiresInDays > 0 {
+			expiration := time.Now().UTC().AddDate(0, 0, *input.ExpiresInDays)
+			expiresAt = &expiration
+		}
+	case existing.ExpiresAt != nil:
+		expiration := time.Now().UTC().Add(existing.ExpiresAt.Sub(existing.CreatedAt))
+		expiresAt = &expiration
+	}
+
+	token, err := h.repo.Rotate(r.Context(), id, expiresAt)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			NotFound(w, r, "Token not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	// Like creation, this is the only time the new token is shown
+	OK(w, r, token)
+}
+
+// validateTokenExpiry checks that at most one expiry form is given and that
+// an explicit expiry is in the future
+func validateTokenExpiry(days *int, at *time.Time) validation.ValidationErrors {
+	var errs validation.ValidationErrors
+	if days != nil && at != nil {
+		errs = append(errs, validation.ValidationError{Field: "expires_at", Message: "Set either expires_at or expires_in_days, not both"})
+	}
+	if days != nil && *days < 0 {
+		errs = append(errs, validation.ValidationError{Field: "expires_in_days", Message: "expires_in_days must not be negative"})
+	}
+	if at != nil && !at.After(time.Now()) {
+		errs = append(errs, validation.ValidationError{Field: "expires_at", Message: "expires_at must be in the future"})
+	}
+	return errs
+}
+
+// normalizeScopes lowercases and de-duplicates requested scopes, reporting
+// false if any scope is unknown
+func normalizeScopes(requested []string) ([]string, bool) {
+	var scopes models.TokenScopes
+	for _, scope := range requested {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !slices.Contains(models.AllScopes, scope) {
+			return nil, false
+		}
+		if !scopes.Has(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, true
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/services"
+)
+
+const (
+	triggerDefaultLimit = 50
+	triggerMaxLimit     = 100
+)
+
+// TriggerHandler serves polling triggers for low-code tools. Responses are
+// bare JSON arrays of flat records, newest first, without the usual envelope.
+type TriggerHandler struct {
+	snippets *services.SnippetService
+}
+
+// NewTriggerHandler creates a new trigger handler
+func NewTriggerHandler(snippets *services.SnippetService) *TriggerHandler {
+	return &TriggerHandler{snippets: snippets}
+}
+
+// NewSnippets handles GET /api/v1/triggers/snippets
+// Lists snippets created at or after ?since, ordered by creation time and
+// then insertion order so repeated polls return the same sequence
+func (h *TriggerHandler) NewSnippets(w http.ResponseWriter, r *http.Request) {
+	filter := models.SnippetFilter{
+		Page:      1,
+		Limit:     triggerDefaultLimit,
+		SortBy:    "created_at",
+		SortOrder: "desc",
+	}
+
+	if since := r.URL.Query().Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			Error(w, r, http.StatusBadRequest, apierror.InvalidRequest, "since must be an RFC 3339 timestamp")
+			return
+		}
+		filter.CreatedSince = &t
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l < 1 || l > triggerMaxLimit {
+			Error(w, r, http.StatusBadRequest, apierror.InvalidRequest, "limit must be between 1 and 100")
+			return
+		}
+		filter.Limit = l
+	}
+
+	response, err := h.snippets.List(r.Context(), filter)
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	records := make([]models.TriggerSnippet, len(response.Data))
+	for i := range response.Data {
+		records[i] = models.NewTriggerSnippet(&response.Data[i])
+	}
+	JSON(w, http.StatusOK, records)
+}
+
+// Sample handles GET /api/v1/triggers/snippets/sample
+// Returns a canned record so tools can map fields before any snippet exists
+func (h *TriggerHandler) Sample(w http.ResponseWriter, r *http.Request) {
+	created := time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)
+	JSON(w, http.StatusOK, []models.TriggerSnippet{{
+		ID:          "sample-snippet-id",
+		Title:       "Undo the last commit",
+		Description: "Keeps the changes staged",
+		Content:     "git reset --soft HEAD~1",
+		Language:    "shell",
+		Tags:        "git,cli",
+		Folders:     "Cheatsheets",
+		IsPublic:    false,
+		IsFavorite:  true,
+		CreatedAt:   created,
+		UpdatedAt:   created,
+	}})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestTriggerHandler_NewSnippets(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	tagRepo := repository.NewTagRepository(db)
+	service := services.NewSnippetService(repository.NewSnippetRepository(db), testutil.TestLogger()).
+		WithTagRepo(tagRepo)
+	handler := NewTriggerHandler(service)
+
+	for _, title := range []string{"old", "first", "second"} {
+		if _, err := service.Create(ctx, &models.SnippetInput{Title: title, Content: "x", Language: "go", Tags: []string{"a", "b"}}); err != nil {
+			t.Fatalf("failed to create snippet: %v", err)
+		}
+	}
+	if _, err := db.Exec(`UPDATE snippets SET created_at = '2020-01-01 00:00:00' WHERE title = 'old'`); err != nil {
+		t.Fatalf("failed to backdate snippet: %v", err)
+	}
+
+	get := func(query string) ([]models.TriggerSnippet, int) {
+		w := httptest.NewRecorder()
+		handler.NewSnippets(w, withRequestID(httptest.NewRequest(http.MethodGet, "/api/v1/triggers/snippets"+query, nil)))
+		var records []models.TriggerSnippet
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &records); err != nil {
+				t.Fatalf("expected a bare JSON array: %v", err)
+			}
+		}
+		return records, w.Code
+	}
+
+	records, code := get("?since=2021-01-01T00:00:00Z")
+	if code != http.StatusOK || len(records) != 2 {
+		t.Fatalf("expected 2 new snippets, got %d (status %d)", len(records), code)
+	}
+	if records[0].Title != "second" || records[1].Title != "first" {
+		t.Errorf("expected newest first, got %q then %q", records[0].Title, records[1].Title)
+	}
+	if records[0].Tags != "a,b" {
+		t.Errorf("expected flattened tags, got %q", records[0].Tags)
+	}
+
+	if records, _ := get("?limit=1"); len(records) != 1 || records[0].Title != "second" {
+		t.Errorf("expected the newest snippet only, got %+v", records)
+	}
+	if _, code := get("?since=yesterday"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid since, got %d", code)
+	}
+	if _, code := get("?limit=500"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a limit over 100, got %d", code)
+	}
+}
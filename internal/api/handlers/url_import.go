@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+// URLImportHandler handles importing snippets from raw file URLs
+type URLImportHandler struct {
+	service *services.URLImportService
+}
+
+// NewURLImportHandler creates a new URL import handler
+func NewURLImportHandler(service *services.URLImportService) *URLImportHandler {
+	return &URLImportHandler{service: service}
+}
+
+// Import handles POST /api/v1/import/urls
+// Responds 200 with a result per URL even when some of them failed
+func (h *URLImportHandler) Import(w http.ResponseWriter, r *http.Request) {
+	var input models.URLImportInput
+	if err := DecodeJSON(r, &input); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	result, err := h.service.Import(r.Context(), &input)
+	if err != nil {
+		var validationErrs validation.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			ValidationErrors(w, r, validationErrs)
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, result)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/auth"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+// UserHandler handles user account endpoints in multi-user mode
+type UserHandler struct {
+	repo *repository.UserRepository
+}
+
+// NewUserHandler creates a new user handler
+func NewUserHandler(repo *repository.UserRepository) *UserHandler {
+	return &UserHandler{repo: repo}
+}
+
+// List handles GET /api/v1/users
+func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
+	users, err := h.repo.List(r.Context())
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, users)
+}
+
+// Create handles POST /api/v1/users
+func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input models.UserInput
+	if err := DecodeJSON(r, &input); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	if errs := validation.ValidateUserInput(&input); errs.HasErrors() {
+		ValidationErrors(w, r, errs)
+		return
+	}
+
+	passwordHash, err := auth.HashPassword(input.Password)
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	user, err := h.repo.Create(r.Context(), input.Username, passwordHash, input.IsAdmin)
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			Error(w, r, http.StatusConflict, apierror.AlreadyExists, "A user with this username already exists")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	Created(w, r, user)
+}
+
+// Delete handles DELETE /api/v1/users/{id}
+// Deletes the user and everything they own
+func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id == models.OwnerUserID {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid user ID")
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			NotFound(w, r, "User not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	NoContent(w)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+// WebhookHandler handles inbound webhook management and delivery endpoints
+type WebhookHandler struct {
+	service *services.InboundWebhookService
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(service *services.InboundWebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// List handles GET /api/v1/webhooks
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.service.List(r.Context())
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, hooks)
+}
+
+// Create handles POST /api/v1/webhooks
+// The secret is only included in this response
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input models.InboundWebhookInput
+	if err := DecodeJSON(r, &input); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	hook, err := h.service.Create(r.Context(), &input)
+	if err != nil {
+		var verrs validation.ValidationErrors
+		if errors.As(err, &verrs) {
+			ValidationErrors(w, r, verrs)
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	Created(w, r, hook)
+}
+
+// Delete handles DELETE /api/v1/webhooks/{id}
+// Snippets the webhook created are kept
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid webhook ID")
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, services.ErrWebhookNotFound) {
+			NotFound(w, r, "Webhook not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	NoContent(w)
+}
+
+// RotateSecret handles POST /api/v1/webhooks/{id}/rotate-secret
+func (h *WebhookHandler) RotateSecret(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid webhook ID")
+		return
+	}
+
+	hook, err := h.service.RotateSecret(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookNotFound) {
+			NotFound(w, r, "Webhook not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, hook)
+}
+
+// Receive handles POST /api/v1/hooks/{slug}
+// Public endpoint authenticated by the webhook secret, sent in the
+// X-Webhook-Secret header or as a bearer token
+func (h *WebhookHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	secret := r.Header.Get("X-Webhook-Secret")
+	if secret == "" {
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			secret = strings.TrimPrefix(auth, "Bearer ")
+		}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, services.MaxWebhookPayloadSize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			Error(w, r, http.StatusRequestEntityTooLarge, apierror.PayloadTooLarge, "Payload must be less than 1MB")
+			return
+		}
+		Error(w, r, http.StatusBadRequest, apierror.ReadError, "Failed to read payload")
+		return
+	}
+
+	delivery := &models.InboundDelivery{
+		ContentType: r.Header.Get("Content-Type"),
+		Body:        body,
+		Title:       r.URL.Query().Get("title"),
+		Language:    r.URL.Query().Get("language"),
+	}
+
+	snippet, err := h.service.Receive(r.Context(), chi.URLParam(r, "slug"), secret, delivery)
+	if err != nil {
+		var verrs validation.ValidationErrors
+		switch {
+		case errors.Is(err, services.ErrWebhookNotFound):
+			NotFound(w, r, "Webhook not found")
+		case errors.Is(err, services.ErrWebhookUnauthorized):
+			Error(w, r, http.StatusUnauthorized, apierror.Unauthorized, "Invalid webhook secret")
+		case errors.Is(err, services.ErrInvalidWebhookPayload):
+			Error(w, r, http.StatusBadRequest, apierror.InvalidPayload, strings.TrimPrefix(err.Error(), services.ErrInvalidWebhookPayload.Error()+": "))
+		case errors.As(err, &verrs):
+			ValidationErrors(w, r, verrs)
+		default:
+			InternalError(w, r)
+		}
+		return
+	}
+
+	Created(w, r, snippet)
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/auth"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+// WorkspaceHandler handles workspace and membership endpoints in
+// multi-user mode
+type WorkspaceHandler struct {
+	repo        *repository.WorkspaceRepository
+	authService *auth.Service
+}
+
+// NewWorkspaceHandler creates a new workspace handler
+func NewWorkspaceHandler(repo *repository.WorkspaceRepository, authService *auth.Service) *WorkspaceHandler {
+	return &WorkspaceHandler{repo: repo, authService: authService}
+}
+
+// List handles GET /api/v1/workspaces
+// Admins see every workspace, other users the ones they are a member of.
+func (h *WorkspaceHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, _ := models.UserIDFromContext(r.Context())
+
+	var workspaces []models.Workspace
+	var err error
+	if h.authService.IsAdmin(userID) {
+		workspaces, err = h.repo.List(r.Context())
+	} else {
+		workspaces, err = h.repo.ListForUser(r.Context(), userID)
+	}
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, workspaces)
+}
+
+// Create handles POST /api/v1/workspaces
+func (h *WorkspaceHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input models.WorkspaceInput
+	if err := DecodeJSON(r, &input); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	if errs := validation.ValidateWorkspaceInput(&input); errs.HasErrors() {
+		ValidationErrors(w, r, errs)
+		return
+	}
+
+	workspace, err := h.repo.Create(r.Context(), &input)
+	if err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			Error(w, r, http.StatusConflict, apierror.AlreadyExists, "A workspace with this slug already exists")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	Created(w, r, workspace)
+}
+
+// Update handles PUT /api/v1/workspaces/{id}
+func (h *WorkspaceHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := workspaceID(w, r)
+	if !ok {
+		return
+	}
+
+	var input models.WorkspaceInput
+	if err := DecodeJSON(r, &input); err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+		return
+	}
+
+	if errs := validation.ValidateWorkspaceInput(&input); errs.HasErrors() {
+		ValidationErrors(w, r, errs)
+		return
+	}
+
+	workspace, err := h.repo.Update(r.Context(), id, &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			NotFound(w, r, "Workspace not found")
+		case errors.Is(err, repository.ErrAlreadyExists):
+			Error(w, r, http.StatusConflict, apierror.AlreadyExists, "A workspace with this slug already exists")
+		default:
+			InternalError(w, r)
+		}
+		return
+	}
+
+	OK(w, r, workspace)
+}
+
+// Delete handles DELETE /api/v1/workspaces/{id}
+// Deletes the workspace and everything in it
+func (h *WorkspaceHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := workspaceID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			NotFound(w, r, "Workspace not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	NoContent(w)
+}
+
+// ListMembers handles GET /api/v1/workspaces/{id}/members
+func (h *WorkspaceHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	id, ok := workspaceID(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := h.repo.GetByID(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			NotFound(w, r, "Workspace not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	members, err := h.repo.ListMembers(r.Context(), id)
+	if err != nil {
+		InternalError(w, r)
+		return
+	}
+
+	OK(w, r, members)
+}
+
+// AddMember handles PUT /api/v1/workspaces/{id}/members/{userId}
+// Adds the user with the role in the body, editor by default, or changes
+// the role of an existing member.
+func (h *WorkspaceHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	id, ok := workspaceID(w, r)
+	if !ok {
+		return
+	}
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil || userID == models.OwnerUserID {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid user ID")
+		return
+	}
+
+	var input models.WorkspaceMemberInput
+	if r.ContentLength != 0 {
+		if err := DecodeJSON(r, &input); err != nil {
+			Error(w, r, http.StatusBadRequest, apierror.InvalidJSON, "Invalid JSON payload")
+			return
+		}
+	}
+
+	if errs := validation.ValidateWorkspaceMemberInput(&input); errs.HasErrors() {
+		ValidationErrors(w, r, errs)
+		return
+	}
+
+	if _, err := h.repo.GetByID(r.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			NotFound(w, r, "Workspace not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	if err := h.repo.AddMember(r.Context(), id, userID, input.Role); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			NotFound(w, r, "User not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	NoContent(w)
+}
+
+// RemoveMember handles DELETE /api/v1/workspaces/{id}/members/{userId}
+// The member's tokens bound to the workspace are revoked.
+func (h *WorkspaceHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	id, ok := workspaceID(w, r)
+	if !ok {
+		return
+	}
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid user ID")
+		return
+	}
+
+	if err := h.repo.RemoveMember(r.Context(), id, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			NotFound(w, r, "Member not found")
+			return
+		}
+		InternalError(w, r)
+		return
+	}
+
+	NoContent(w)
+}
+
+// workspaceID parses the {id} URL parameter, writing an error if it is invalid
+func workspaceID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		Error(w, r, http.StatusBadRequest, apierror.InvalidID, "Invalid workspace ID")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+)
+
+// errorBody mirrors the API error envelope written by handlers.Error
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Code      apierror.Code `json:"code"`
+	Message   string        `json:"message"`
+	RequestID string        `json:"request_id,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// writeError writes an API error response carrying the request ID, so
+// errors raised before a handler runs look like the ones handlers return
+func writeError(w http.ResponseWriter, r *http.Request, status int, code apierror.Code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorBody{Error: errorDetail{
+		Code:      code,
+		Message:   message,
+		RequestID: GetRequestID(r.Context()),
+		Timestamp: time.Now().UTC(),
+	}})
+}
+
+// writePlainError answers non-API requests with text that still quotes the
+// request ID for bug reports
+func writePlainError(w http.ResponseWriter, r *http.Request, status int) {
+	message := http.StatusText(status)
+	if requestID := GetRequestID(r.Context()); requestID != "" {
+		message += "\nRequest ID: " + requestID
+	}
+	http.Error(w, message, status)
+}
+
+// isAPIRequest reports whether r targets the JSON API, with or without a
+// base path in front
+func isAPIRequest(r *http.Request) bool {
+	return strings.Contains(r.URL.Path, "/api/")
+}
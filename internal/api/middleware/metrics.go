@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/metrics"
+)
+
+// unmatchedRoute labels requests no route matched, so unknown paths can't
+// grow the number of series
+const unmatchedRoute = "unmatched"
+
+// Metrics counts requests and their latency per route, and the requests
+// turned away by a rate limiter
+func Metrics(reg *metrics.Registry) func(http.Handler) http.Handler {
+	requests := reg.Counter("snipo_http_requests_total",
+		"HTTP requests by route pattern, method and status.", "method", "route", "status")
+	latency := reg.Histogram("snipo_http_request_duration_seconds",
+		"HTTP request latency by route pattern and method.", metrics.DefaultBuckets, "method", "route")
+	rejected := reg.Counter("snipo_rate_limit_rejections_total",
+		"Requests rejected by a rate limiter, by route pattern.", "route")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next.ServeHTTP(wrapped, r)
+
+			route := unmatchedRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			requests.Inc(r.Method, route, strconv.Itoa(wrapped.statusCode))
+			latency.Observe(time.Since(start).Seconds(), r.Method, route)
+			if wrapped.statusCode == http.StatusTooManyRequests {
+				rejected.Inc(route)
+			}
+		})
+	}
+}
+
+// BearerTokenOr lets requests carrying token as a bearer token through and
+// sends every other request through fallback. An empty token always falls
+// back.
+func BearerTokenOr(token string, fallback func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		guarded := fallback(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token != "" {
+				if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok &&
+					subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1 {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			guarded.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/metrics"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/telemetry"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	r := chi.NewRouter()
+	r.Use(Metrics(reg))
+	r.Get("/snippets/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/limited", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	for _, path := range []string{"/snippets/a", "/snippets/b", "/limited", "/nope"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	requests := reg.Counter("snipo_http_requests_total", "", "method", "route", "status")
+	if got := requests.Value("GET", "/snippets/{id}", "200"); got != 2 {
+		t.Errorf("expected 2 requests for the route pattern, got %v", got)
+	}
+	if got := requests.Value("GET", unmatchedRoute, "404"); got != 1 {
+		t.Errorf("expected 1 unmatched request, got %v", got)
+	}
+	rejected := reg.Counter("snipo_rate_limit_rejections_total", "", "route")
+	if got := rejected.Value("/limited"); got != 1 {
+		t.Errorf("expected 1 rate limit rejection, got %v", got)
+	}
+}
+
+// usageStore keeps usage counts in memory
+type usageStore map[string]int
+
+func (s usageStore) AddUsage(_ context.Context, _ string, counts map[string]int) error {
+	for route, count := range counts {
+		s[route] += count
+	}
+	return nil
+}
+
+func (s usageStore) UsageSince(context.Context, string) ([]models.UsageCount, error) {
+	return nil, nil
+}
+
+func (s usageStore) PruneUsage(context.Context, string) error { return nil }
+
+func TestUsage(t *testing.T) {
+	store := usageStore{}
+	rec := telemetry.NewRecorder(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	r := chi.NewRouter()
+	r.Use(Usage(rec))
+	r.Get("/api/v1/snippets/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/api/v1/limited", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	r.Get("/s/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, path := range []string{"/api/v1/snippets/a", "/api/v1/snippets/b", "/api/v1/limited", "/api/v1/nope", "/s/a"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	if err := rec.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	// Only successful API requests count, by pattern
+	if len(store) != 1 || store["GET /api/v1/snippets/{id}"] != 2 {
+		t.Errorf("expected 2 requests to the snippet route only, got %v", store)
+	}
+}
+
+func TestBearerTokenOr(t *testing.T) {
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		})
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{"matching token", "s3cret", "Bearer s3cret", http.StatusOK},
+		{"wrong token", "s3cret", "Bearer other", http.StatusUnauthorized},
+		{"no header", "s3cret", "", http.StatusUnauthorized},
+		{"no token configured", "", "Bearer ", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rr := httptest.NewRecorder()
+			BearerTokenOr(tt.token, deny)(ok).ServeHTTP(rr, req)
+			if rr.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, rr.Code)
+			}
+		})
+	}
+}
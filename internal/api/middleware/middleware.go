@@ -7,10 +7,12 @@ import (
 	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
 	"github.com/MohamedElashri/snipo/internal/auth"
 	"github.com/MohamedElashri/snipo/internal/models"
 	"github.com/MohamedElashri/snipo/internal/repository"
@@ -34,9 +36,10 @@ const APIVersion = "1.0"
 // RequestID generates a unique request ID for tracking
 func RequestID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Check if request already has an ID (from proxy/load balancer)
+		// Check if request already has an ID (from proxy/load balancer). It is
+		// echoed in responses and error pages, so only plain IDs are kept.
 		requestID := r.Header.Get("X-Request-ID")
-		if requestID == "" {
+		if !validRequestID(requestID) {
 			// Generate new UUID
 			requestID = uuid.New().String()
 		}
@@ -51,6 +54,22 @@ func RequestID(next http.Handler) http.Handler {
 	})
 }
 
+// validRequestID accepts IDs of up to 128 letters, digits, '-', '_', '.' and ':'
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // GetRequestID retrieves the request ID from context
 func GetRequestID(ctx context.Context) string {
 	if requestID, ok := ctx.Value(ContextKeyRequestID).(string); ok {
@@ -158,7 +177,11 @@ func Recovery(logger *slog.Logger) func(http.Handler) http.Handler {
 						"stack", string(debug.Stack()),
 						"path", r.URL.Path,
 					)
-					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					if isAPIRequest(r) {
+						writeError(w, r, http.StatusInternalServerError, apierror.InternalError, "An internal error occurred")
+					} else {
+						writePlainError(w, r, http.StatusInternalServerError)
+					}
 				}
 			}()
 			next.ServeHTTP(w, r)
@@ -180,9 +203,10 @@ func RequireAuthWithTokenRepo(authService *auth.Service, tokenRepo *repository.T
 func RequireAuthWithSettings(authService *auth.Service, tokenRepo *repository.TokenRepository, settingsRepo *repository.SettingsRepository) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// If authentication is completely disabled via env var, allow all requests
+			// If authentication is completely disabled via env var, allow all
+			// requests as the owner
 			if authService.IsAuthDisabled() {
-				next.ServeHTTP(w, r)
+				next.ServeHTTP(w, r.WithContext(models.WithUserID(r.Context(), models.OwnerUserID)))
 				return
 			}
 
@@ -192,10 +216,11 @@ func RequireAuthWithSettings(authService *auth.Service, tokenRepo *repository.To
 				authHeader := r.Header.Get("Authorization")
 				if strings.HasPrefix(authHeader, "Bearer ") {
 					token := strings.TrimPrefix(authHeader, "Bearer ")
-					apiToken, err := tokenRepo.ValidateToken(r.Context(), token)
+					apiToken, err := tokenRepo.ValidateToken(r.Context(), token, ClientIP(r))
 					if err == nil && apiToken != nil {
 						// Valid API token, add to context and continue
 						ctx := context.WithValue(r.Context(), ContextKeyAPIToken, apiToken)
+						ctx = models.WithUserID(ctx, apiToken.UserID)
 						next.ServeHTTP(w, r.WithContext(ctx))
 						return
 					}
@@ -204,10 +229,11 @@ func RequireAuthWithSettings(authService *auth.Service, tokenRepo *repository.To
 				// Check X-API-Key header
 				apiKey := r.Header.Get("X-API-Key")
 				if apiKey != "" {
-					apiToken, err := tokenRepo.ValidateToken(r.Context(), apiKey)
+					apiToken, err := tokenRepo.ValidateToken(r.Context(), apiKey, ClientIP(r))
 					if err == nil && apiToken != nil {
 						// Valid API token, add to context and continue
 						ctx := context.WithValue(r.Context(), ContextKeyAPIToken, apiToken)
+						ctx = models.WithUserID(ctx, apiToken.UserID)
 						next.ServeHTTP(w, r.WithContext(ctx))
 						return
 					}
@@ -216,8 +242,9 @@ func RequireAuthWithSettings(authService *auth.Service, tokenRepo *repository.To
 
 			// Fall back to session authentication
 			sessionToken := auth.GetSessionFromRequest(r)
-			if sessionToken != "" && authService.ValidateSession(sessionToken) {
-				next.ServeHTTP(w, r)
+			if userID, ok := authService.SessionUser(sessionToken); ok {
+				authService.ResignSessionCookie(w, r)
+				next.ServeHTTP(w, r.WithContext(models.WithUserID(r.Context(), userID)))
 				return
 			}
 
@@ -233,14 +260,15 @@ func RequireAuthWithSettings(authService *auth.Service, tokenRepo *repository.To
 						Name:        "anonymous-disable-login",
 						Permissions: PermissionWrite,
 					})
+					ctx = models.WithUserID(ctx, models.OwnerUserID)
 					next.ServeHTTP(w, r.WithContext(ctx))
 					return
 				}
 			}
 
 			// No valid authentication found
-			if strings.HasPrefix(r.URL.Path, "/api/") {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			if isAPIRequest(r) {
+				writeError(w, r, http.StatusUnauthorized, apierror.Unauthorized, "Authentication required")
 			} else {
 				http.Redirect(w, r, "/login", http.StatusSeeOther)
 			}
@@ -248,6 +276,30 @@ func RequireAuthWithSettings(authService *auth.Service, tokenRepo *repository.To
 	}
 }
 
+// RequireFeature rejects requests while a runtime feature is switched off in
+// settings. Routes behave as if they did not exist, as when the feature is
+// disabled in the configuration.
+func RequireFeature(flags repository.FeatureFlags, name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			enabled, err := flags.FeatureEnabled(r.Context(), name)
+			if err != nil {
+				writeError(w, r, http.StatusInternalServerError, apierror.InternalError, "An internal error occurred")
+				return
+			}
+			if !enabled {
+				if isAPIRequest(r) {
+					writeError(w, r, http.StatusNotFound, apierror.FeatureDisabled, "This feature is disabled")
+				} else {
+					writePlainError(w, r, http.StatusNotFound)
+				}
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // RateLimiter implements a simple in-memory rate limiter
 type RateLimiter struct {
 	requests map[string][]time.Time
@@ -270,6 +322,13 @@ func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
 	return rl
 }
 
+// SetLimit replaces the number of requests allowed per window
+func (rl *RateLimiter) SetLimit(limit int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.limit = limit
+}
+
 // Middleware returns the rate limiting middleware
 func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -289,7 +348,11 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 		if len(recent) >= rl.limit {
 			rl.mu.Unlock()
 			w.Header().Set("Retry-After", "60")
-			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			if isAPIRequest(r) {
+				writeError(w, r, http.StatusTooManyRequests, apierror.RateLimited, "Too many requests")
+			} else {
+				writePlainError(w, r, http.StatusTooManyRequests)
+			}
 			return
 		}
 
@@ -365,49 +428,72 @@ func ClientIP(r *http.Request) string {
 // For local-first deployment, CORS is restrictive by default.
 // Configure SNIPO_ALLOWED_ORIGINS to allow specific cross-origin requests.
 func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			origin := r.Header.Get("Origin")
-
-			// Check if origin is allowed
-			if origin != "" {
-				allowed := false
-
-				// Check if wildcard is configured (development mode)
-				for _, allowedOrigin := range allowedOrigins {
-					if allowedOrigin == "*" {
-						w.Header().Set("Access-Control-Allow-Origin", "*")
-						allowed = true
-						break
-					} else if allowedOrigin == origin {
-						w.Header().Set("Access-Control-Allow-Origin", origin)
-						w.Header().Set("Access-Control-Allow-Credentials", "true")
-						allowed = true
-						break
-					}
+	return NewCORSPolicy(allowedOrigins).Middleware
+}
+
+// CORSPolicy holds the origins allowed to make cross-origin requests, which
+// can be replaced while serving
+type CORSPolicy struct {
+	origins atomic.Pointer[[]string]
+}
+
+// NewCORSPolicy creates a CORS policy allowing allowedOrigins
+func NewCORSPolicy(allowedOrigins []string) *CORSPolicy {
+	p := &CORSPolicy{}
+	p.SetOrigins(allowedOrigins)
+	return p
+}
+
+// SetOrigins replaces the allowed origins
+func (p *CORSPolicy) SetOrigins(allowedOrigins []string) {
+	p.origins.Store(&allowedOrigins)
+}
+
+// Middleware adds CORS headers for the allowed origins
+func (p *CORSPolicy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowedOrigins := *p.origins.Load()
+
+		// Check if origin is allowed
+		if origin != "" {
+			allowed := false
+
+			// Check if wildcard is configured (development mode)
+			for _, allowedOrigin := range allowedOrigins {
+				if allowedOrigin == "*" {
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+					allowed = true
+					break
+				} else if allowedOrigin == origin {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+					allowed = true
+					break
 				}
+			}
 
-				// If not explicitly allowed, check if same-origin
-				if !allowed {
-					requestHost := r.Host
-					if origin == "http://"+requestHost || origin == "https://"+requestHost {
-						w.Header().Set("Access-Control-Allow-Origin", origin)
-						w.Header().Set("Access-Control-Allow-Credentials", "true")
-					}
-					// Otherwise, don't set CORS headers (browser will block cross-origin requests)
+			// If not explicitly allowed, check if same-origin
+			if !allowed {
+				requestHost := r.Host
+				if origin == "http://"+requestHost || origin == "https://"+requestHost {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
 				}
+				// Otherwise, don't set CORS headers (browser will block cross-origin requests)
 			}
+		}
 
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
-			w.Header().Set("Access-Control-Max-Age", "86400")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Snipo-Challenge-Response, Accept-Version")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Snipo-Challenge, X-Snipo-Challenge-Difficulty, X-API-Version, Deprecation, Sunset, Link, Warning")
+		w.Header().Set("Access-Control-Max-Age", "86400")
 
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusNoContent)
-				return
-			}
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
 
-			next.ServeHTTP(w, r)
-		})
-	}
+		next.ServeHTTP(w, r)
+	})
 }
@@ -2,6 +2,9 @@ package middleware
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -63,6 +66,53 @@ func TestRequestID_PreExisting(t *testing.T) {
 	}
 }
 
+func TestRequestID_Invalid(t *testing.T) {
+	for _, id := range []string{"has spaces", "<script>", strings.Repeat("a", 129)} {
+		handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Request-ID", id)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if got := rr.Header().Get("X-Request-ID"); got == id || len(got) != 36 {
+			t.Errorf("expected %q to be replaced by a UUID, got %q", id, got)
+		}
+	}
+}
+
+func TestRecovery_RequestIDInErrors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := RequestID(Recovery(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+
+	t.Run("api", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/snippets", nil)
+		req.Header.Set("X-Request-ID", "req-123")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var body errorBody
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("expected a JSON error, got %q", rr.Body.String())
+		}
+		if rr.Code != http.StatusInternalServerError || body.Error.Code != "INTERNAL_ERROR" || body.Error.RequestID != "req-123" {
+			t.Errorf("unexpected error response %d: %+v", rr.Code, body)
+		}
+	})
+
+	t.Run("web", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Request-ID", "req-456")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if !strings.Contains(rr.Body.String(), "Request ID: req-456") {
+			t.Errorf("expected the request ID in the error page, got %q", rr.Body.String())
+		}
+	})
+}
+
 func TestGetRequestID(t *testing.T) {
 	// Test with request ID in context
 	ctx := context.WithValue(context.Background(), ContextKeyRequestID, "test-id-123")
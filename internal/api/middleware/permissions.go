@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
 	"github.com/MohamedElashri/snipo/internal/auth"
 	"github.com/MohamedElashri/snipo/internal/models"
 )
@@ -19,6 +20,37 @@ const (
 	PermissionAdmin = "admin"
 )
 
+// scopeLevels is the permission level an unscoped token needs for the
+// routes a scope guards, so existing tokens keep working unchanged
+var scopeLevels = map[string]string{
+	models.ScopeSnippetsRead:  PermissionRead,
+	models.ScopeSnippetsWrite: PermissionWrite,
+	models.ScopeTagsWrite:     PermissionWrite,
+	models.ScopeFoldersWrite:  PermissionWrite,
+	models.ScopeBackupRun:     PermissionAdmin,
+	models.ScopeSyncManage:    PermissionWrite,
+}
+
+// roleLevels is the permission level each workspace role allows. Inside a
+// workspace it caps what sessions and tokens can do.
+var roleLevels = map[string]string{
+	models.WorkspaceRoleViewer: PermissionRead,
+	models.WorkspaceRoleEditor: PermissionWrite,
+	models.WorkspaceRoleAdmin:  PermissionAdmin,
+}
+
+// scopeRoleLevels is the permission level a workspace role needs for the
+// routes a scope guards. Unlike for tokens, syncing to GitHub is left to
+// workspace admins.
+var scopeRoleLevels = map[string]string{
+	models.ScopeSnippetsRead:  PermissionRead,
+	models.ScopeSnippetsWrite: PermissionWrite,
+	models.ScopeTagsWrite:     PermissionWrite,
+	models.ScopeFoldersWrite:  PermissionWrite,
+	models.ScopeBackupRun:     PermissionAdmin,
+	models.ScopeSyncManage:    PermissionAdmin,
+}
+
 // GetTokenFromContext retrieves the API token from context
 func GetTokenFromContext(ctx context.Context) *models.APIToken {
 	if token, ok := ctx.Value(ContextKeyAPIToken).(*models.APIToken); ok {
@@ -37,6 +69,11 @@ func IsAnonymousAccess(ctx context.Context) bool {
 func CheckPermission(required string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !roleAllows(r.Context(), required) {
+				writeRoleError(w, r)
+				return
+			}
+
 			// Get token from context (set by RequireAuthWithTokenRepo middleware)
 			token := GetTokenFromContext(r.Context())
 
@@ -46,9 +83,15 @@ func CheckPermission(required string) func(http.Handler) http.Handler {
 				return
 			}
 
+			// Scoped tokens may only use routes guarded by one of their scopes
+			if len(token.Scopes) > 0 {
+				writeError(w, r, http.StatusForbidden, apierror.InsufficientScope, "Token is not scoped for this operation")
+				return
+			}
+
 			// Check if token has required permission
 			if !hasPermission(token.Permissions, required) {
-				http.Error(w, `{"error":{"code":"INSUFFICIENT_PERMISSIONS","message":"Token does not have required permissions"}}`, http.StatusForbidden)
+				writeError(w, r, http.StatusForbidden, apierror.InsufficientPermissions, "Token does not have required permissions")
 				return
 			}
 
@@ -77,6 +120,56 @@ func hasPermission(tokenPermission, required string) bool {
 	return false
 }
 
+// roleAllows reports whether the user's role in the workspace a request acts
+// in reaches the required permission level. Outside of workspaces there is
+// no role to check.
+func roleAllows(ctx context.Context, required string) bool {
+	role := models.WorkspaceRoleFromContext(ctx)
+	if role == "" {
+		return true
+	}
+	return hasPermission(roleLevels[role], required)
+}
+
+// writeRoleError turns away a request the user's workspace role doesn't allow
+func writeRoleError(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusForbidden, apierror.InsufficientRole, "Workspace role does not allow this operation")
+}
+
+// hasScope checks a token against a scope. Unscoped tokens fall back to the
+// permission level the scope corresponds to.
+func hasScope(token *models.APIToken, scope string) bool {
+	if len(token.Scopes) == 0 {
+		return hasPermission(token.Permissions, scopeLevels[scope])
+	}
+	return token.Scopes.Has(scope)
+}
+
+// RequireScope returns middleware that lets sessions, unscoped tokens with a
+// sufficient permission level and tokens granted scope through, as long as
+// the user's workspace role allows the scope
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !roleAllows(r.Context(), scopeRoleLevels[scope]) {
+				writeRoleError(w, r)
+				return
+			}
+
+			token := GetTokenFromContext(r.Context())
+			if token != nil && !hasScope(token, scope) {
+				if len(token.Scopes) > 0 {
+					writeError(w, r, http.StatusForbidden, apierror.InsufficientScope, "Token is not scoped for this operation")
+				} else {
+					writeError(w, r, http.StatusForbidden, apierror.InsufficientPermissions, "Token does not have required permissions")
+				}
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // RequireRead is a convenience middleware for read operations
 func RequireRead(next http.Handler) http.Handler {
 	return CheckPermission(PermissionRead)(next)
@@ -94,16 +187,77 @@ func RequireAdmin(next http.Handler) http.Handler {
 
 // RequireAdminWithPassword allows normal admin sessions/tokens, but requires
 // the master password when the request is anonymous because login is disabled.
+// Scoped tokens are rejected since admin routes carry no scope.
 func RequireAdminWithPassword(authService *auth.Service) func(http.Handler) http.Handler {
+	return requireWithPassword(authService, false, requireAdminToken)
+}
+
+// RequireWorkspaceAdminWithPassword is RequireAdminWithPassword for routes
+// that only touch the selected workspace, such as its tokens and settings.
+// Members with the workspace's admin role may use them too.
+func RequireWorkspaceAdminWithPassword(authService *auth.Service) func(http.Handler) http.Handler {
+	return requireWithPassword(authService, true, requireAdminToken)
+}
+
+// requireAdminToken lets unscoped admin tokens through
+func requireAdminToken(w http.ResponseWriter, r *http.Request, token *models.APIToken) bool {
+	if len(token.Scopes) > 0 {
+		writeError(w, r, http.StatusForbidden, apierror.InsufficientScope, "Token is not scoped for this operation")
+		return false
+	}
+	if !hasPermission(token.Permissions, PermissionAdmin) {
+		writeError(w, r, http.StatusForbidden, apierror.InsufficientPermissions, "Admin permission required")
+		return false
+	}
+	return true
+}
+
+// RequireScopeWithPassword is RequireAdminWithPassword for admin routes that
+// a token can also be scoped to
+func RequireScopeWithPassword(authService *auth.Service, scope string) func(http.Handler) http.Handler {
+	return requireWithPassword(authService, false, requireScopeToken(scope))
+}
+
+// RequireWorkspaceScopeWithPassword is RequireWorkspaceAdminWithPassword for
+// routes a token can also be scoped to
+func RequireWorkspaceScopeWithPassword(authService *auth.Service, scope string) func(http.Handler) http.Handler {
+	return requireWithPassword(authService, true, requireScopeToken(scope))
+}
+
+// requireScopeToken lets tokens granted scope through
+func requireScopeToken(scope string) func(http.ResponseWriter, *http.Request, *models.APIToken) bool {
+	return func(w http.ResponseWriter, r *http.Request, token *models.APIToken) bool {
+		if !hasScope(token, scope) {
+			writeError(w, r, http.StatusForbidden, apierror.InsufficientPermissions, "Admin permission or the "+scope+" scope required")
+			return false
+		}
+		return true
+	}
+}
+
+// requireWithPassword checks tokens with allowToken, lets sessions through and
+// asks anonymous requests for the master password. Users without admin
+// rights are turned away whichever way they authenticated, unless
+// workspaceAdmins lets the selected workspace's admins in. Inside a
+// workspace the user's role must be admin.
+func requireWithPassword(authService *auth.Service, workspaceAdmins bool, allowToken func(http.ResponseWriter, *http.Request, *models.APIToken) bool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !roleAllows(r.Context(), PermissionAdmin) {
+				writeRoleError(w, r)
+				return
+			}
+			workspaceAdmin := workspaceAdmins && models.WorkspaceRoleFromContext(r.Context()) == models.WorkspaceRoleAdmin
+			if userID, ok := models.UserIDFromContext(r.Context()); ok && !workspaceAdmin && !authService.IsAdmin(userID) {
+				writeError(w, r, http.StatusForbidden, apierror.InsufficientPermissions, "Admin account required")
+				return
+			}
+
 			token := GetTokenFromContext(r.Context())
 			if token != nil {
-				if !hasPermission(token.Permissions, PermissionAdmin) {
-					http.Error(w, `{"error":{"code":"INSUFFICIENT_PERMISSIONS","message":"Admin permission required"}}`, http.StatusForbidden)
-					return
+				if allowToken(w, r, token) {
+					next.ServeHTTP(w, r)
 				}
-				next.ServeHTTP(w, r)
 				return
 			}
 
@@ -114,18 +268,18 @@ func RequireAdminWithPassword(authService *auth.Service) func(http.Handler) http
 
 			password := r.Header.Get(adminPasswordHeader)
 			if password == "" {
-				http.Error(w, `{"error":{"code":"ADMIN_PASSWORD_REQUIRED","message":"Master password is required for admin operations when login is disabled"}}`, http.StatusUnauthorized)
+				writeError(w, r, http.StatusUnauthorized, apierror.AdminPasswordRequired, "Master password is required for admin operations when login is disabled")
 				return
 			}
 
 			valid, delay := authService.VerifyPasswordWithDelay(password, ClientIP(r))
 			if delay > 0 {
 				w.Header().Set("Retry-After", fmt.Sprintf("%d", int(delay.Seconds())+1))
-				http.Error(w, `{"error":{"code":"RATE_LIMITED","message":"Too many failed attempts. Please wait before retrying."}}`, http.StatusTooManyRequests)
+				writeError(w, r, http.StatusTooManyRequests, apierror.RateLimited, "Too many failed attempts. Please wait before retrying.")
 				return
 			}
 			if !valid {
-				http.Error(w, `{"error":{"code":"INVALID_PASSWORD","message":"Invalid password"}}`, http.StatusForbidden)
+				writeError(w, r, http.StatusForbidden, apierror.InvalidPassword, "Invalid password")
 				return
 			}
 
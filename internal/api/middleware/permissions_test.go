@@ -10,6 +10,7 @@ import (
 
 	"github.com/MohamedElashri/snipo/internal/auth"
 	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
 )
 
 func TestCheckPermission(t *testing.T) {
@@ -239,3 +240,134 @@ func TestRequireAdminWithPassword_AnonymousAccess(t *testing.T) {
 		}
 	})
 }
+
+func TestRequireAdminWithPassword_NonAdminUser(t *testing.T) {
+	db := testutil.TestDB(t)
+	authService := auth.NewService(db, "correct-password", "test-secret", time.Hour, slog.Default(), false).
+		WithMultiUser(true)
+	var userID int64
+	if err := db.QueryRow("INSERT INTO users (username, password_hash) VALUES ('alice', 'x') RETURNING id").Scan(&userID); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	handler := RequireAdminWithPassword(authService)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range []struct {
+		name   string
+		userID int64
+		want   int
+	}{
+		{"owner", models.OwnerUserID, http.StatusOK},
+		{"non-admin user", userID, http.StatusForbidden},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			req = req.WithContext(models.WithUserID(req.Context(), tt.userID))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, rr.Code)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	authService := auth.NewService(nil, "correct-password", "test-secret", time.Hour, slog.Default(), false)
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name         string
+		middleware   func(http.Handler) http.Handler
+		tokenPerm    string
+		scopes       models.TokenScopes
+		expectStatus int
+	}{
+		// Unscoped tokens keep their permission level semantics
+		{"unscoped read can read snippets", RequireScope(models.ScopeSnippetsRead), PermissionRead, nil, http.StatusOK},
+		{"unscoped read cannot write snippets", RequireScope(models.ScopeSnippetsWrite), PermissionRead, nil, http.StatusForbidden},
+		{"unscoped write cannot run backups", RequireScopeWithPassword(authService, models.ScopeBackupRun), PermissionWrite, nil, http.StatusForbidden},
+		{"unscoped admin can run backups", RequireScopeWithPassword(authService, models.ScopeBackupRun), PermissionAdmin, nil, http.StatusOK},
+
+		// Scoped tokens are limited to their scopes regardless of level
+		{"scoped token uses its scope", RequireScope(models.ScopeSnippetsWrite), PermissionRead, models.TokenScopes{models.ScopeSnippetsWrite}, http.StatusOK},
+		{"scoped token cannot use other scopes", RequireScope(models.ScopeTagsWrite), PermissionAdmin, models.TokenScopes{models.ScopeSnippetsWrite}, http.StatusForbidden},
+		{"scoped token can run backups with scope", RequireScopeWithPassword(authService, models.ScopeBackupRun), PermissionRead, models.TokenScopes{models.ScopeBackupRun}, http.StatusOK},
+		{"scoped admin token cannot run backups without scope", RequireScopeWithPassword(authService, models.ScopeBackupRun), PermissionAdmin, models.TokenScopes{models.ScopeSnippetsWrite}, http.StatusForbidden},
+		{"scoped admin token cannot use level-only routes", RequireRead, PermissionAdmin, models.TokenScopes{models.ScopeSnippetsRead}, http.StatusForbidden},
+		{"scoped admin token cannot use admin routes", RequireAdminWithPassword(authService), PermissionAdmin, models.TokenScopes{models.ScopeBackupRun}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := &models.APIToken{ID: 1, Name: "test", Permissions: tt.tokenPerm, Scopes: tt.scopes}
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req = req.WithContext(context.WithValue(req.Context(), ContextKeyAPIToken, token))
+			rr := httptest.NewRecorder()
+			tt.middleware(testHandler).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestWorkspaceRoles(t *testing.T) {
+	db := testutil.TestDB(t)
+	authService := auth.NewService(db, "correct-password", "test-secret", time.Hour, slog.Default(), false).
+		WithMultiUser(true)
+	var userID int64
+	if err := db.QueryRow("INSERT INTO users (username, password_hash) VALUES ('alice', 'x') RETURNING id").Scan(&userID); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name         string
+		middleware   func(http.Handler) http.Handler
+		role         string
+		token        *models.APIToken
+		expectStatus int
+	}{
+		{"viewer can read", RequireScope(models.ScopeSnippetsRead), models.WorkspaceRoleViewer, nil, http.StatusOK},
+		{"viewer cannot write", RequireScope(models.ScopeSnippetsWrite), models.WorkspaceRoleViewer, nil, http.StatusForbidden},
+		{"viewer's write token cannot write", RequireWrite, models.WorkspaceRoleViewer, &models.APIToken{Permissions: PermissionWrite}, http.StatusForbidden},
+		{"editor can write", RequireScope(models.ScopeTagsWrite), models.WorkspaceRoleEditor, nil, http.StatusOK},
+		{"editor cannot manage sync", RequireScope(models.ScopeSyncManage), models.WorkspaceRoleEditor, nil, http.StatusForbidden},
+		{"editor cannot manage tokens", RequireWorkspaceAdminWithPassword(authService), models.WorkspaceRoleEditor, nil, http.StatusForbidden},
+		{"admin can manage sync", RequireScope(models.ScopeSyncManage), models.WorkspaceRoleAdmin, nil, http.StatusOK},
+		{"admin can manage tokens", RequireWorkspaceAdminWithPassword(authService), models.WorkspaceRoleAdmin, nil, http.StatusOK},
+		{"admin can run backups", RequireWorkspaceScopeWithPassword(authService, models.ScopeBackupRun), models.WorkspaceRoleAdmin, nil, http.StatusOK},
+		{"admin's read token cannot manage tokens", RequireWorkspaceAdminWithPassword(authService), models.WorkspaceRoleAdmin, &models.APIToken{Permissions: PermissionRead}, http.StatusForbidden},
+		{"admin cannot use instance admin routes", RequireAdminWithPassword(authService), models.WorkspaceRoleAdmin, nil, http.StatusForbidden},
+		{"role is not checked outside workspaces", RequireWrite, "", nil, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := models.WithUserID(context.Background(), userID)
+			if tt.role != "" {
+				ctx = models.WithWorkspaceRole(models.WithWorkspaceID(ctx, 1), tt.role)
+			}
+			if tt.token != nil {
+				ctx = context.WithValue(ctx, ContextKeyAPIToken, tt.token)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/test", nil).WithContext(ctx)
+			rr := httptest.NewRecorder()
+			tt.middleware(testHandler).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
 )
 
 // APIRateLimiter implements rate limiting for API endpoints with proper headers
@@ -27,6 +29,19 @@ type RateLimitConfig struct {
 
 // NewAPIRateLimiter creates a new API rate limiter with permission-based limits
 func NewAPIRateLimiter(config RateLimitConfig) *APIRateLimiter {
+	rl := &APIRateLimiter{
+		requests: make(map[string][]time.Time),
+	}
+	rl.SetLimits(config)
+
+	// Start cleanup goroutine
+	go rl.cleanup()
+
+	return rl
+}
+
+// SetLimits replaces the limits, keeping the requests already counted
+func (rl *APIRateLimiter) SetLimits(config RateLimitConfig) {
 	if config.ReadLimit == 0 {
 		config.ReadLimit = 1000
 	}
@@ -40,24 +55,24 @@ func NewAPIRateLimiter(config RateLimitConfig) *APIRateLimiter {
 		config.Window = time.Hour
 	}
 
-	rl := &APIRateLimiter{
-		requests:   make(map[string][]time.Time),
-		readLimit:  config.ReadLimit,
-		writeLimit: config.WriteLimit,
-		adminLimit: config.AdminLimit,
-		window:     config.Window,
-	}
-
-	// Start cleanup goroutine
-	go rl.cleanup()
-
-	return rl
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.readLimit = config.ReadLimit
+	rl.writeLimit = config.WriteLimit
+	rl.adminLimit = config.AdminLimit
+	rl.window = config.Window
 }
 
 // RateLimitByPermission returns middleware that rate limits based on permission level
 func (rl *APIRateLimiter) RateLimitByPermission(permission string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Get identifier (token ID or IP)
+			identifier := rl.getIdentifier(r)
+			now := time.Now()
+
+			rl.mu.Lock()
+
 			// Determine the limit based on permission
 			var limit int
 			switch permission {
@@ -70,17 +85,12 @@ func (rl *APIRateLimiter) RateLimitByPermission(permission string) func(http.Han
 			default:
 				limit = rl.readLimit
 			}
-
-			// Get identifier (token ID or IP)
-			identifier := rl.getIdentifier(r)
-			now := time.Now()
-
-			rl.mu.Lock()
+			window := rl.window
 
 			// Clean old requests for this identifier
 			var recent []time.Time
 			for _, t := range rl.requests[identifier] {
-				if now.Sub(t) < rl.window {
+				if now.Sub(t) < window {
 					recent = append(recent, t)
 				}
 			}
@@ -88,8 +98,8 @@ func (rl *APIRateLimiter) RateLimitByPermission(permission string) func(http.Han
 			// Check if limit is exceeded
 			if len(recent) >= limit {
 				rl.mu.Unlock()
-				retryAfter := int(rl.window.Seconds())
-				reset := now.Add(rl.window).Unix()
+				retryAfter := int(window.Seconds())
+				reset := now.Add(window).Unix()
 				
 				// Set rate limit headers
 				w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
@@ -97,7 +107,7 @@ func (rl *APIRateLimiter) RateLimitByPermission(permission string) func(http.Han
 				w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", reset))
 				w.Header().Set("Retry-After", fmt.Sprintf("%d", retryAfter))
 				
-				http.Error(w, `{"error":{"code":"RATE_LIMIT_EXCEEDED","message":"Rate limit exceeded. Please try again later."}}`, http.StatusTooManyRequests)
+				writeError(w, r, http.StatusTooManyRequests, apierror.RateLimitExceeded, "Rate limit exceeded. Please try again later.")
 				return
 			}
 
@@ -108,7 +118,7 @@ func (rl *APIRateLimiter) RateLimitByPermission(permission string) func(http.Han
 			remaining := limit - len(rl.requests[identifier])
 			
 			// Set rate limit headers
-			reset := now.Add(rl.window).Unix()
+			reset := now.Add(window).Unix()
 			w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
 			w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", max(0, remaining)))
 			w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", reset))
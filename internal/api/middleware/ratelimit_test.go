@@ -273,3 +273,32 @@ func TestAPIRateLimiter_DefaultConfig(t *testing.T) {
 		t.Errorf("expected default window 1h, got %v", rl.window)
 	}
 }
+
+func TestAPIRateLimiter_SetLimits(t *testing.T) {
+	rl := NewAPIRateLimiter(RateLimitConfig{ReadLimit: 1, Window: time.Minute})
+	handler := rl.RateLimitRead(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	get := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+		return rr
+	}
+
+	if rr := get(); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if rr := get(); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 over the limit, got %d", rr.Code)
+	}
+
+	// Requests already counted still count against the new limit
+	rl.SetLimits(RateLimitConfig{ReadLimit: 2, Window: time.Minute})
+	rr := get()
+	if rr.Code != http.StatusOK || rr.Header().Get("X-RateLimit-Limit") != "2" {
+		t.Errorf("expected 200 under the raised limit, got %d (limit %s)", rr.Code, rr.Header().Get("X-RateLimit-Limit"))
+	}
+	if rr := get(); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 over the raised limit, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/telemetry"
+)
+
+// Usage counts successful API requests by method and route pattern for the
+// telemetry report. Patterns keep IDs out of the counts, and unmatched paths
+// and failed requests aren't counted.
+func Usage(rec *telemetry.Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next.ServeHTTP(wrapped, r)
+
+			if wrapped.statusCode >= http.StatusBadRequest {
+				return
+			}
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && strings.HasPrefix(rctx.RoutePattern(), "/api/v1/") {
+				rec.Record(r.Method + " " + rctx.RoutePattern())
+			}
+		})
+	}
+}
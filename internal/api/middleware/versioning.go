@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+)
+
+// Deprecation describes an endpoint that is due to change or be removed
+type Deprecation struct {
+	Since     time.Time // When the endpoint was deprecated
+	Sunset    time.Time // When it stops working, zero until decided
+	Successor string    // Path of the endpoint replacing it, if any
+	Message   string    // What clients should do instead
+}
+
+// Warning is the text sent to clients calling the endpoint
+func (d Deprecation) Warning() string {
+	warning := "This endpoint is deprecated"
+	if !d.Sunset.IsZero() {
+		warning += " and will be removed after " + d.Sunset.UTC().Format(time.DateOnly)
+	}
+	if d.Message != "" {
+		warning += ": " + d.Message
+	}
+	return warning
+}
+
+const contextKeyDeprecations contextKey = "deprecations"
+
+// APIVersioning checks the version clients ask for in Accept-Version and
+// marks responses from deprecated endpoints. deprecations is keyed by method
+// and route pattern, such as "GET /api/v1/snippets/{id}".
+//
+// Accept-Version names the oldest API version the client works with, as
+// "1" or "1.0". Requests the server can't serve are refused with 406, so a
+// client built for a newer server finds out before acting on a response it
+// misreads.
+//
+// Responses from deprecated endpoints carry a Deprecation header with the
+// date it was deprecated, a Sunset header once removal is planned, a Link to
+// the successor and a Warning, and the warning is repeated in the response
+// meta.
+func APIVersioning(deprecations map[string]Deprecation) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isAPIRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if requested := r.Header.Get("Accept-Version"); requested != "" {
+				supported, err := supportsVersion(requested)
+				if err != nil {
+					writeError(w, r, http.StatusBadRequest, apierror.UnsupportedAPIVersion, err.Error())
+					return
+				}
+				if !supported {
+					writeError(w, r, http.StatusNotAcceptable, apierror.UnsupportedAPIVersion,
+						fmt.Sprintf("API version %s is not supported, this server provides %s", requested, APIVersion))
+					return
+				}
+			}
+
+			if len(deprecations) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), contextKeyDeprecations, deprecations))
+			next.ServeHTTP(&deprecationWriter{ResponseWriter: w, r: r}, r)
+		})
+	}
+}
+
+// supportsVersion reports whether a client asking for version requested can
+// use this server: the same major version, at a minor version no newer than
+// the server's
+func supportsVersion(requested string) (bool, error) {
+	major, minor, err := parseVersion(requested)
+	if err != nil {
+		return false, fmt.Errorf("invalid Accept-Version %q, expected a version such as %s", requested, APIVersion)
+	}
+	serverMajor, serverMinor, _ := parseVersion(APIVersion)
+	return major == serverMajor && minor <= serverMinor, nil
+}
+
+// parseVersion reads "1", "1.0" or "v1.0"
+func parseVersion(version string) (major, minor int, err error) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	majorText, minorText, hasMinor := strings.Cut(version, ".")
+	if major, err = strconv.Atoi(majorText); err != nil || major < 0 {
+		return 0, 0, fmt.Errorf("invalid version %q", version)
+	}
+	if hasMinor {
+		if minor, err = strconv.Atoi(minorText); err != nil || minor < 0 {
+			return 0, 0, fmt.Errorf("invalid version %q", version)
+		}
+	}
+	return major, minor, nil
+}
+
+// DeprecationOf returns the deprecation of the endpoint r was routed to, if
+// it is deprecated. The route is only known once the router has matched it,
+// so this is for handlers and the responses they write.
+func DeprecationOf(r *http.Request) (Deprecation, bool) {
+	deprecations, ok := r.Context().Value(contextKeyDeprecations).(map[string]Deprecation)
+	if !ok {
+		return Deprecation{}, false
+	}
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return Deprecation{}, false
+	}
+	deprecation, ok := deprecations[r.Method+" "+rctx.RoutePattern()]
+	return deprecation, ok
+}
+
+// deprecationWriter adds the deprecation headers when the response starts,
+// by which time the route has been matched
+type deprecationWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	wroteHeader bool
+}
+
+func (dw *deprecationWriter) WriteHeader(code int) {
+	if !dw.wroteHeader {
+		dw.wroteHeader = true
+		if deprecation, ok := DeprecationOf(dw.r); ok {
+			setDeprecationHeaders(dw.Header(), deprecation)
+		}
+	}
+	dw.ResponseWriter.WriteHeader(code)
+}
+
+func (dw *deprecationWriter) Write(b []byte) (int, error) {
+	if !dw.wroteHeader {
+		dw.WriteHeader(http.StatusOK)
+	}
+	return dw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (dw *deprecationWriter) Unwrap() http.ResponseWriter {
+	return dw.ResponseWriter
+}
+
+// setDeprecationHeaders writes the headers of RFC 9745 (Deprecation) and
+// RFC 8594 (Sunset), with a Warning for clients that only log those
+func setDeprecationHeaders(h http.Header, d Deprecation) {
+	h.Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+	if !d.Sunset.IsZero() {
+		h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Successor != "" {
+		h.Add("Link", "<"+d.Successor+`>; rel="successor-version"`)
+	}
+	h.Add("Warning", `299 snipo "`+strings.ReplaceAll(d.Warning(), `"`, `'`)+`"`)
+}
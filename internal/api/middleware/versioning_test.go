@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestAPIVersioning(t *testing.T) {
+	since := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 3, 1, 0, 0, 0, 0, time.UTC)
+	var warning string
+	r := chi.NewRouter()
+	r.Use(APIVersioning(map[string]Deprecation{
+		"GET /api/v1/things/{id}": {Since: since, Sunset: sunset, Successor: "/api/v1/items/{id}", Message: "use /api/v1/items/{id}"},
+	}))
+	r.Route("/api/v1/things", func(r chi.Router) {
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			if deprecation, ok := DeprecationOf(r); ok {
+				warning = deprecation.Warning()
+			}
+			_, _ = w.Write([]byte("ok"))
+		})
+		r.Put("/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	get := func(method, path, version string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if version != "" {
+			req.Header.Set("Accept-Version", version)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("deprecated route", func(t *testing.T) {
+		w := get("GET", "/api/v1/things/1", "")
+		if got := w.Header().Get("Deprecation"); got != "@1788220800" {
+			t.Errorf("expected the deprecation date, got %q", got)
+		}
+		if got := w.Header().Get("Sunset"); got != "Mon, 01 Mar 2027 00:00:00 GMT" {
+			t.Errorf("expected the sunset date, got %q", got)
+		}
+		if got := w.Header().Get("Link"); got != `</api/v1/items/{id}>; rel="successor-version"` {
+			t.Errorf("expected a link to the successor, got %q", got)
+		}
+		if got := w.Header().Get("Warning"); !strings.HasPrefix(got, `299 snipo "This endpoint is deprecated and will be removed after 2027-03-01`) {
+			t.Errorf("expected a warning, got %q", got)
+		}
+		if !strings.Contains(warning, "use /api/v1/items/{id}") {
+			t.Errorf("expected handlers to see the deprecation, got %q", warning)
+		}
+	})
+
+	t.Run("other method", func(t *testing.T) {
+		w := get("PUT", "/api/v1/things/1", "")
+		if got := w.Header().Get("Deprecation"); got != "" {
+			t.Errorf("expected no deprecation for PUT, got %q", got)
+		}
+	})
+
+	t.Run("accept version", func(t *testing.T) {
+		for version, status := range map[string]int{
+			"1":    http.StatusOK,
+			"1.0":  http.StatusOK,
+			"v1":   http.StatusOK,
+			"1.1":  http.StatusNotAcceptable,
+			"2":    http.StatusNotAcceptable,
+			"0.9":  http.StatusNotAcceptable,
+			"next": http.StatusBadRequest,
+		} {
+			if w := get("GET", "/api/v1/things/1", version); w.Code != status {
+				t.Errorf("Accept-Version %s: expected %d, got %d", version, status, w.Code)
+			}
+		}
+	})
+}
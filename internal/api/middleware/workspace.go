@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
+	"github.com/MohamedElashri/snipo/internal/auth"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+)
+
+// ContextKeyWorkspaceSlug is the context key for the workspace a request
+// selected by path prefix or subdomain
+const ContextKeyWorkspaceSlug contextKey = "workspace_slug"
+
+// workspacePathPrefix selects a workspace by path, as in /w/team/api/v1/snippets
+const workspacePathPrefix = "/w/"
+
+// WorkspaceSelector reads the workspace a request asks for from a /w/{slug}
+// path prefix, which is stripped before routing, or else from the subdomain
+// of domain when one is configured. RequireWorkspace then checks the
+// workspace exists and the user may enter it.
+func WorkspaceSelector(domain string) func(http.Handler) http.Handler {
+	domain = strings.ToLower(strings.Trim(domain, "."))
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			slug, path := workspaceFromPath(r)
+			if slug != "" {
+				// Mounted under a base path, chi routes on RoutePath instead
+				if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+					rctx.RoutePath = path
+				} else {
+					u := *r.URL
+					u.Path, u.RawPath = path, ""
+					r = r.WithContext(r.Context())
+					r.URL = &u
+				}
+			} else if domain != "" {
+				slug = workspaceFromHost(r.Host, domain)
+			}
+
+			if slug != "" {
+				r = r.WithContext(context.WithValue(r.Context(), ContextKeyWorkspaceSlug, slug))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// workspaceFromPath splits a /w/{slug} prefix off the path being routed
+func workspaceFromPath(r *http.Request) (slug, path string) {
+	path = r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+		path = rctx.RoutePath
+	}
+	rest, ok := strings.CutPrefix(path, workspacePathPrefix)
+	if !ok {
+		return "", path
+	}
+	slug, rest, _ = strings.Cut(rest, "/")
+	return strings.ToLower(slug), "/" + rest
+}
+
+// workspaceFromHost returns the subdomain of domain that host names, if it
+// is a single label
+func workspaceFromHost(host, domain string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	sub, ok := strings.CutSuffix(strings.ToLower(host), "."+domain)
+	if !ok || sub == "" || strings.Contains(sub, ".") {
+		return ""
+	}
+	return sub
+}
+
+// RequireWorkspace moves authenticated requests into the workspace they
+// selected, or the one their API token is bound to, together with the
+// user's role there; admins act as workspace admins. Unknown workspaces are
+// not found; users who aren't members, other than admins, are turned away,
+// as are tokens used outside their workspace. Requests that select nothing
+// stay in the default workspace.
+func RequireWorkspace(workspaces repository.WorkspaceResolver, authService *auth.Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			slug, _ := ctx.Value(ContextKeyWorkspaceSlug).(string)
+			var boundID int64
+			if token := GetTokenFromContext(ctx); token != nil {
+				boundID = token.WorkspaceID
+			}
+			if slug == "" && boundID == models.DefaultWorkspaceID {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var workspace *models.Workspace
+			var err error
+			if slug != "" {
+				workspace, err = workspaces.GetBySlug(ctx, slug)
+			} else {
+				workspace, err = workspaces.GetByID(ctx, boundID)
+			}
+			if errors.Is(err, repository.ErrNotFound) {
+				writeError(w, r, http.StatusNotFound, apierror.NotFound, "Workspace not found")
+				return
+			}
+			if err != nil {
+				writeError(w, r, http.StatusInternalServerError, apierror.InternalError, "An internal error occurred")
+				return
+			}
+			if workspace.ID != boundID && boundID != models.DefaultWorkspaceID {
+				writeError(w, r, http.StatusForbidden, apierror.Forbidden, "Token is bound to another workspace")
+				return
+			}
+
+			role := models.WorkspaceRoleAdmin
+			userID, _ := models.UserIDFromContext(ctx)
+			if !authService.IsAdmin(userID) {
+				role, err = workspaces.MemberRole(ctx, workspace.ID, userID)
+				if errors.Is(err, repository.ErrNotFound) {
+					writeError(w, r, http.StatusForbidden, apierror.Forbidden, "Not a member of this workspace")
+					return
+				}
+				if err != nil {
+					writeError(w, r, http.StatusInternalServerError, apierror.InternalError, "An internal error occurred")
+					return
+				}
+			}
+
+			ctx = models.WithWorkspaceRole(models.WithWorkspaceID(ctx, workspace.ID), role)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
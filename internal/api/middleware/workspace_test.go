@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/auth"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestWorkspaceSelector(t *testing.T) {
+	var gotSlug string
+	r := chi.NewRouter()
+	r.Use(WorkspaceSelector("snipo.example.com"))
+	r.Get("/api/v1/snippets", func(w http.ResponseWriter, r *http.Request) {
+		gotSlug, _ = r.Context().Value(ContextKeyWorkspaceSlug).(string)
+	})
+	mounted := chi.NewRouter()
+	mounted.Mount("/snipo", r)
+
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		host     string
+		path     string
+		wantSlug string
+	}{
+		{"no workspace", r, "snipo.example.com", "/api/v1/snippets", ""},
+		{"path prefix", r, "localhost", "/w/Team/api/v1/snippets", "team"},
+		{"subdomain", r, "team.snipo.example.com:8080", "/api/v1/snippets", "team"},
+		{"nested subdomain", r, "a.team.snipo.example.com", "/api/v1/snippets", ""},
+		{"other domain", r, "team.example.org", "/api/v1/snippets", ""},
+		{"path prefix wins", r, "team.snipo.example.com", "/w/ops/api/v1/snippets", "ops"},
+		{"under base path", mounted, "localhost", "/snipo/w/team/api/v1/snippets", "team"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSlug = ""
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Host = tt.host
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected the route to match once the prefix is stripped, got %d", rr.Code)
+			}
+			if gotSlug != tt.wantSlug {
+				t.Errorf("expected slug %q, got %q", tt.wantSlug, gotSlug)
+			}
+		})
+	}
+}
+
+func TestRequireWorkspace(t *testing.T) {
+	db := testutil.TestDB(t)
+	authService := auth.NewService(db, "master-password", "test-secret", time.Hour, testutil.TestLogger(), false).
+		WithMultiUser(true)
+	users := repository.NewUserRepository(db)
+	workspaces := repository.NewWorkspaceRepository(db)
+
+	alice, err := users.Create(testutil.TestContext(), "alice", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	bob, err := users.Create(testutil.TestContext(), "bob", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	team, err := workspaces.Create(testutil.TestContext(), &models.WorkspaceInput{Slug: "team", Name: "Team"})
+	if err != nil {
+		t.Fatalf("Create workspace failed: %v", err)
+	}
+	ops, err := workspaces.Create(testutil.TestContext(), &models.WorkspaceInput{Slug: "ops", Name: "Ops"})
+	if err != nil {
+		t.Fatalf("Create workspace failed: %v", err)
+	}
+	if err := workspaces.AddMember(testutil.TestContext(), team.ID, alice.ID, models.WorkspaceRoleViewer); err != nil {
+		t.Fatalf("AddMember failed: %v", err)
+	}
+
+	var gotWorkspace int64
+	var gotRole string
+	handler := RequireWorkspace(workspaces, authService)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotWorkspace = models.WorkspaceIDFromContext(r.Context())
+		gotRole = models.WorkspaceRoleFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name          string
+		userID        int64
+		slug          string
+		token         *models.APIToken
+		wantStatus    int
+		wantWorkspace int64
+		wantRole      string
+	}{
+		{"no workspace", alice.ID, "", nil, http.StatusOK, models.DefaultWorkspaceID, ""},
+		{"member", alice.ID, "team", nil, http.StatusOK, team.ID, models.WorkspaceRoleViewer},
+		{"non-member", bob.ID, "team", nil, http.StatusForbidden, 0, ""},
+		{"admin", models.OwnerUserID, "ops", nil, http.StatusOK, ops.ID, models.WorkspaceRoleAdmin},
+		{"unknown workspace", alice.ID, "nope", nil, http.StatusNotFound, 0, ""},
+		{"bound token", alice.ID, "", &models.APIToken{Permissions: PermissionWrite, WorkspaceID: team.ID}, http.StatusOK, team.ID, models.WorkspaceRoleViewer},
+		{"bound token elsewhere", models.OwnerUserID, "ops", &models.APIToken{Permissions: PermissionAdmin, WorkspaceID: team.ID}, http.StatusForbidden, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotWorkspace, gotRole = -1, ""
+			ctx := models.WithUserID(context.Background(), tt.userID)
+			if tt.slug != "" {
+				ctx = context.WithValue(ctx, ContextKeyWorkspaceSlug, tt.slug)
+			}
+			if tt.token != nil {
+				ctx = context.WithValue(ctx, ContextKeyAPIToken, tt.token)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/snippets", nil).WithContext(ctx)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && (gotWorkspace != tt.wantWorkspace || gotRole != tt.wantRole) {
+				t.Errorf("expected workspace %d as %q, got %d as %q", tt.wantWorkspace, tt.wantRole, gotWorkspace, gotRole)
+			}
+		})
+	}
+}
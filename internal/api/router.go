@@ -1,150 +1,138 @@
 package api
 
 import (
-	"database/sql"
-	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/MohamedElashri/snipo/internal/api/apierror"
 	"github.com/MohamedElashri/snipo/internal/api/handlers"
 	"github.com/MohamedElashri/snipo/internal/api/middleware"
-	"github.com/MohamedElashri/snipo/internal/auth"
-	"github.com/MohamedElashri/snipo/internal/config"
-	"github.com/MohamedElashri/snipo/internal/repository"
-	"github.com/MohamedElashri/snipo/internal/services"
-	"github.com/MohamedElashri/snipo/internal/storage"
+	"github.com/MohamedElashri/snipo/internal/app"
+	"github.com/MohamedElashri/snipo/internal/models"
 	"github.com/MohamedElashri/snipo/internal/web"
 )
 
 // RouterConfig holds router configuration
 type RouterConfig struct {
-	DB                 *sql.DB
-	Logger             *slog.Logger
-	AuthService        *auth.Service
-	Config             *config.Config // Full application config
-	Version            string
-	Commit             string
-	RateLimit          int
-	RateLimitWindow    int // in seconds
-	MaxFilesPerSnippet int
-	S3Config           *config.S3Config
-	SnippetService     *services.SnippetService // For demo mode
-	BasePath           string                   // Base path for reverse proxy
+	App       *app.App // Repositories, services and configuration
+	Version   string
+	Commit    string
+	BuildDate string
 }
 
+// deprecatedRoutes lists the endpoints due to change or be removed, keyed by
+// method and route pattern as in "GET /api/v1/snippets/{id}". Responses from
+// them carry Deprecation, Sunset and Warning headers so clients have a
+// release or more to move to the successor.
+var deprecatedRoutes = map[string]middleware.Deprecation{}
+
 // NewRouter creates and configures the HTTP router
 func NewRouter(cfg RouterConfig) http.Handler {
+	a := cfg.App
+	logger := a.Logger
+	basePath := a.Config.Server.BasePath
+
 	r := chi.NewRouter()
 
 	// Global middleware (order matters!)
-	r.Use(middleware.RequestID)            // Generate request IDs first
-	r.Use(middleware.Recovery(cfg.Logger)) // Catch panics
-	r.Use(middleware.Logger(cfg.Logger))   // Log requests (includes request ID)
-	r.Use(middleware.SecurityHeaders)      // Security headers (includes X-API-Version)
-
-	// Use configured CORS
-	allowedOrigins := []string{"*"} // default
-	if cfg.Config != nil {
-		allowedOrigins = cfg.Config.API.AllowedOrigins
+	r.Use(middleware.RequestID)        // Generate request IDs first
+	r.Use(middleware.Recovery(logger)) // Catch panics
+	r.Use(middleware.Logger(logger))   // Log requests (includes request ID)
+	r.Use(middleware.SecurityHeaders)  // Security headers (includes X-API-Version)
+	// Accept-Version and deprecation notices
+	r.Use(middleware.APIVersioning(deprecatedRoutes))
+	if a.Metrics != nil {
+		r.Use(middleware.Metrics(a.Metrics)) // Request counts and latency per route
+	}
+	if a.Telemetry != nil {
+		r.Use(middleware.Usage(a.Telemetry)) // Local usage report, opt-in
 	}
-	r.Use(middleware.CORS(allowedOrigins)) // CORS handling
 
-	// Rate limiting for auth endpoints
-	authRateLimiter := middleware.NewRateLimiter(cfg.RateLimit, 60*1000*1000*1000) // 1 minute in nanoseconds
-
-	// API rate limiter with permission-based limits (use config values or defaults)
-	readLimit, writeLimit, adminLimit := 1000, 500, 100
-	if cfg.Config != nil {
-		readLimit = cfg.Config.API.RateLimitRead
-		writeLimit = cfg.Config.API.RateLimitWrite
-		adminLimit = cfg.Config.API.RateLimitAdmin
+	cors := middleware.NewCORSPolicy(a.Config.API.AllowedOrigins)
+	r.Use(cors.Middleware) // CORS handling
+	if a.Auth.MultiUserEnabled() {
+		r.Use(middleware.WorkspaceSelector(a.Config.Auth.WorkspaceDomain)) // /w/{slug} prefix or subdomain
 	}
+
+	// Rate limiting for auth endpoints
+	authRateLimiter := middleware.NewRateLimiter(a.Config.Auth.RateLimit, 60*1000*1000*1000) // 1 minute in nanoseconds
+
+	// API rate limiter with permission-based limits
 	apiRateLimiter := middleware.NewAPIRateLimiter(middleware.RateLimitConfig{
-		ReadLimit:  readLimit,
-		WriteLimit: writeLimit,
-		AdminLimit: adminLimit,
+		ReadLimit:  a.Config.API.RateLimitRead,
+		WriteLimit: a.Config.API.RateLimitWrite,
+		AdminLimit: a.Config.API.RateLimitAdmin,
 		Window:     time.Hour,
 	})
 
-	// Create repositories
-	snippetRepo := repository.NewSnippetRepository(cfg.DB)
-	tagRepo := repository.NewTagRepository(cfg.DB)
-	folderRepo := repository.NewFolderRepository(cfg.DB)
-	tokenRepo := repository.NewTokenRepository(cfg.DB)
-	fileRepo := repository.NewSnippetFileRepository(cfg.DB)
-	settingsRepo := repository.NewSettingsRepository(cfg.DB)
-	historyRepo := repository.NewHistoryRepository(cfg.DB)
-	gistSyncRepo := repository.NewGistSyncRepository(cfg.DB)
-
-	// Create services
-	var snippetService *services.SnippetService
-	if cfg.SnippetService != nil {
-		// Use provided snippet service (for demo mode)
-		snippetService = cfg.SnippetService
-	} else {
-		// Create new snippet service
-		snippetService = services.NewSnippetService(snippetRepo, cfg.Logger).
-			WithTagRepo(tagRepo).
-			WithFolderRepo(folderRepo).
-			WithFileRepo(fileRepo).
-			WithHistoryRepo(historyRepo).
-			WithSettingsRepo(settingsRepo).
-			WithMaxFiles(cfg.MaxFilesPerSnippet)
-	}
-
-	// Create backup service
-	backupService := services.NewBackupService(cfg.DB, snippetService, tagRepo, folderRepo, fileRepo, cfg.Logger, cfg.Config.Auth.EncryptionSalt)
-
-	// Create S3 sync service if configured
-	var s3SyncService *services.S3SyncService
-	if cfg.S3Config != nil && cfg.S3Config.Enabled {
-		s3Storage, err := storage.NewS3Storage(storage.S3Config{
-			Endpoint:        cfg.S3Config.Endpoint,
-			AccessKeyID:     cfg.S3Config.AccessKeyID,
-			SecretAccessKey: cfg.S3Config.SecretAccessKey,
-			Bucket:          cfg.S3Config.Bucket,
-			Region:          cfg.S3Config.Region,
-			UseSSL:          cfg.S3Config.UseSSL,
-		})
-		if err != nil {
-			cfg.Logger.Warn("failed to initialize S3 storage", "error", err)
-		} else {
-			s3SyncService = services.NewS3SyncService(s3Storage, backupService, cfg.Logger)
-			cfg.Logger.Info("S3 storage initialized", "bucket", cfg.S3Config.Bucket)
-		}
-	}
+	// Token scope guards. Sessions always pass and unscoped tokens are checked
+	// against the permission level matching each scope.
+	snippetsRead := middleware.RequireScope(models.ScopeSnippetsRead)
+	snippetsWrite := middleware.RequireScope(models.ScopeSnippetsWrite)
+	tagsWrite := middleware.RequireScope(models.ScopeTagsWrite)
+	foldersWrite := middleware.RequireScope(models.ScopeFoldersWrite)
+	syncManage := middleware.RequireScope(models.ScopeSyncManage)
 
 	// Create handlers
-	snippetHandler := handlers.NewSnippetHandler(snippetService)
-	tagHandler := handlers.NewTagHandler(tagRepo)
-	folderHandler := handlers.NewFolderHandler(folderRepo)
-	tokenHandler := handlers.NewTokenHandler(tokenRepo, settingsRepo, cfg.AuthService).WithDemoMode(cfg.Config.Demo.Enabled)
-	authHandler := handlers.NewAuthHandler(cfg.AuthService).WithDemoMode(cfg.Config.Demo.Enabled)
+	snippetHandler := handlers.NewSnippetHandler(a.Snippets)
+	tagHandler := handlers.NewTagHandler(a.TagRepo)
+	folderHandler := handlers.NewFolderHandler(a.FolderRepo)
+	tokenHandler := handlers.NewTokenHandler(a.TokenRepo, a.SettingsRepo, a.Auth).WithDemoMode(a.Config.Demo.Enabled)
+	authHandler := handlers.NewAuthHandler(a.Auth).WithDemoMode(a.Config.Demo.Enabled)
 
 	// Create health handler
-	healthHandler := handlers.NewHealthHandler(cfg.DB)
-
-	backupHandler := handlers.NewBackupHandler(backupService, s3SyncService)
-	settingsHandler := handlers.NewSettingsHandler(settingsRepo, cfg.AuthService)
+	features := a.Config.Features.Map()
+	features["language_stats"] = true
+	features[models.FeatureGistSync] = a.Encryption != nil
+	healthHandler := handlers.NewHealthHandler(a.DB.DB).WithFeatures(features).WithRuntimeFeatures(a.SettingsRepo).
+		WithBuildInfo(handlers.BuildInfo{Version: cfg.Version, Commit: cfg.Commit, BuildDate: cfg.BuildDate}, a.DB.SchemaVersion)
+
+	// Runtime feature guards, toggled through the settings API
+	publicSharing := middleware.RequireFeature(a.SettingsRepo, models.FeaturePublicSnippets)
+	gistSync := middleware.RequireFeature(a.SettingsRepo, models.FeatureGistSync)
+
+	backupHandler := handlers.NewBackupHandler(a.Backup, a.S3Sync)
+
+	// Settings reloaded on SIGHUP
+	a.OnReload(func() {
+		cors.SetOrigins(a.Config.API.AllowedOrigins)
+		authRateLimiter.SetLimit(a.Config.Auth.RateLimit)
+		apiRateLimiter.SetLimits(middleware.RateLimitConfig{
+			ReadLimit:  a.Config.API.RateLimitRead,
+			WriteLimit: a.Config.API.RateLimitWrite,
+			AdminLimit: a.Config.API.RateLimitAdmin,
+			Window:     time.Hour,
+		})
+		backupHandler.SetS3Sync(a.S3Sync)
+	})
+	settingsHandler := handlers.NewSettingsHandler(a.SettingsRepo, a.Auth)
+	digestHandler := handlers.NewDigestHandler(a.Digest)
+	remoteSourceHandler := handlers.NewRemoteSourceHandler(a.RemoteSources)
 	languageHandler := handlers.NewLanguageHandler()
-
-	// Create encryption service for gist sync (using encryption salt as key for persistence)
-	legacyEncryptionKey := services.DeriveEncryptionKey(cfg.Config.Auth.EncryptionSalt)
-	encryptionKey := services.DeriveEncryptionKeyWithSecret(cfg.Config.Auth.EncryptionSalt, cfg.Config.Auth.SessionSecret)
-	if cfg.Config.Auth.SessionSecretGenerated {
-		encryptionKey = legacyEncryptionKey
-	}
-	encryptionSvc, err := services.NewEncryptionServiceWithFallback(encryptionKey, legacyEncryptionKey)
-	if err != nil {
-		cfg.Logger.Warn("failed to initialize encryption service", "error", err)
-	}
+	statsHandler := handlers.NewStatsHandler(a.StatsRepo)
+	reportHandler := handlers.NewReportHandler(a.Reports)
+	webhookHandler := handlers.NewWebhookHandler(a.Webhooks)
+	triggerHandler := handlers.NewTriggerHandler(a.Snippets)
+	renderHandler := handlers.NewRenderHandler(a.Snippets)
+	feedHandler := handlers.NewFeedHandler(a.Snippets, basePath)
+	oembedHandler := handlers.NewOEmbedHandler(a.Snippets, basePath)
+	quickCaptureHandler := handlers.NewQuickCaptureHandler(a.QuickCapture, basePath)
+	urlImportHandler := handlers.NewURLImportHandler(a.URLImport)
+	importReportHandler := handlers.NewImportReportHandler(a.ImportReportRepo)
+	adminHandler := handlers.NewAdminHandler(a.Auth).WithSearchIndex(a.SearchIndex).WithMaintenance(a.Maintenance).WithUsage(a.Telemetry)
 
 	// Create gist sync handler
 	var gistSyncHandler *handlers.GistSyncHandler
-	if encryptionSvc != nil {
-		gistSyncHandler = handlers.NewGistSyncHandler(gistSyncRepo, snippetRepo, fileRepo, encryptionSvc)
+	var githubExportHandler *handlers.GitHubExportHandler
+	if a.Encryption != nil {
+		gistSyncHandler = handlers.NewGistSyncHandler(a.GistSyncRepo, a.SnippetRepo, a.FileRepo, a.Encryption).
+			WithGitHubAPIURL(a.Config.GitHub.APIURL).
+			WithWebhookSecret(a.Config.GitHub.WebhookSecret)
+		githubExportHandler = handlers.NewGitHubExportHandler(a.GistSyncRepo, a.Snippets, a.FolderRepo, a.Encryption, logger).
+			WithGitHubAPIURL(a.Config.GitHub.APIURL)
 	}
 
 	// Public routes (no auth required)
@@ -152,6 +140,7 @@ func NewRouter(cfg RouterConfig) http.Handler {
 		// Health checks
 		r.Get("/health", healthHandler.Health)
 		r.Get("/ping", healthHandler.Ping)
+		r.Get("/readyz", healthHandler.Ready)
 
 		// OpenAPI specification
 		r.Get("/api/v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
@@ -159,13 +148,44 @@ func NewRouter(cfg RouterConfig) http.Handler {
 		})
 
 		// Public snippet access
-		if cfg.Config == nil || cfg.Config.Features.PublicSnippets {
-			r.With(apiRateLimiter.RateLimitRead).Get("/api/v1/snippets/public/{id}", snippetHandler.GetPublic)
-			r.With(apiRateLimiter.RateLimitRead).Get("/api/v1/snippets/public/{id}/files/{filename}", snippetHandler.GetPublicFile)
+		if a.Config.Features.PublicSnippets {
+			r.With(publicSharing, apiRateLimiter.RateLimitRead).Get("/api/v1/snippets/public", snippetHandler.PublicFeed)
+			r.With(publicSharing, apiRateLimiter.RateLimitRead).Get("/api/v1/snippets/public/{id}", snippetHandler.GetPublic)
+			r.With(publicSharing, apiRateLimiter.RateLimitRead).Get("/api/v1/snippets/public/{id}/files/{filename}", snippetHandler.GetPublicFile)
+			r.With(publicSharing, apiRateLimiter.RateLimitWrite).Post("/s/{id}/report", reportHandler.Submit)
+			r.With(publicSharing, apiRateLimiter.RateLimitRead).Get("/s/{id}/raw", snippetHandler.GetPublicRaw)
+			r.With(publicSharing, apiRateLimiter.RateLimitRead).Get("/s/{id}/{filename}/raw", snippetHandler.GetPublicFileRaw)
+			r.With(publicSharing, apiRateLimiter.RateLimitRead).Get("/feed.xml", feedHandler.Feed)
+			r.With(publicSharing, apiRateLimiter.RateLimitRead).Get("/tags/{tag}/feed.xml", feedHandler.Feed)
+			r.With(publicSharing, apiRateLimiter.RateLimitRead).Get("/oembed", oembedHandler.OEmbed)
+		}
+
+		// Inbound webhook deliveries, authenticated by the webhook's own secret
+		r.With(apiRateLimiter.RateLimitWrite).Post("/api/v1/hooks/{slug}", webhookHandler.Receive)
+
+		// Slack/Mattermost slash command search, authenticated by the request signature or command token
+		if a.Config.Slash.Enabled() {
+			slashHandler := handlers.NewSlashCommandHandler(a.Snippets, a.Config.Slash.SlackSigningSecret, a.Config.Slash.MattermostToken)
+			r.With(apiRateLimiter.RateLimitRead).Post("/api/v1/integrations/slack/command", slashHandler.Command)
+		}
+
+		// GitHub gist webhook, authenticated by the X-Hub-Signature-256 of the payload
+		if gistSyncHandler != nil && a.Config.GitHub.WebhookSecret != "" {
+			r.With(gistSync, apiRateLimiter.RateLimitWrite).Post("/api/v1/gist/webhook", gistSyncHandler.Webhook)
+		}
+
+		// Prometheus metrics: the metrics token, or admin credentials
+		if a.Metrics != nil {
+			adminOnly := func(next http.Handler) http.Handler {
+				return middleware.RequireAuthWithSettings(a.Auth, a.TokenRepo, a.SettingsRepo)(
+					middleware.RequireAdminWithPassword(a.Auth)(next))
+			}
+			r.With(middleware.BearerTokenOr(a.Config.Metrics.Token, adminOnly)).Handle("/metrics", a.Metrics)
 		}
 
 		// Public metadata
 		r.Get("/api/v1/metadata/languages", languageHandler.GetLanguages)
+		r.Get("/api/v1/errors", languageHandler.GetErrorCodes)
 
 		// Auth endpoints (with rate limiting)
 		r.Group(func(r chi.Router) {
@@ -179,68 +199,130 @@ func NewRouter(cfg RouterConfig) http.Handler {
 
 	// Protected routes (auth required + rate limiting)
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.RequireAuthWithSettings(cfg.AuthService, tokenRepo, settingsRepo))
+		r.Use(middleware.RequireAuthWithSettings(a.Auth, a.TokenRepo, a.SettingsRepo))
+		if a.Auth.MultiUserEnabled() {
+			r.Use(middleware.RequireWorkspace(a.WorkspaceRepo, a.Auth))
+		}
 
 		// Auth management (protected, requires any auth)
+		// Session management (admin only)
+		r.Route("/api/v1/auth/sessions", func(r chi.Router) {
+			r.Use(middleware.RequireAdminWithPassword(a.Auth))
+			r.Use(apiRateLimiter.RateLimitAdmin)
+			r.Get("/", authHandler.ListSessions)
+			r.Post("/revoke-others", authHandler.RevokeOtherSessions)
+			r.Delete("/{id}", authHandler.RevokeSession)
+		})
 
-		// Settings management (admin only)
+		// Settings management (admin or workspace admin)
 		r.Route("/api/v1/settings", func(r chi.Router) {
-			r.Use(middleware.RequireAdminWithPassword(cfg.AuthService))
+			r.Use(middleware.RequireWorkspaceAdminWithPassword(a.Auth))
 			r.Use(apiRateLimiter.RateLimitAdmin)
 			r.Get("/", settingsHandler.Get)
 			r.Put("/", settingsHandler.Update)
 		})
 
+		// Digest e-mail (admin only)
+		r.Route("/api/v1/digest", func(r chi.Router) {
+			r.Use(middleware.RequireAdminWithPassword(a.Auth))
+			r.Use(apiRateLimiter.RateLimitAdmin)
+			r.Get("/", digestHandler.Preview)
+			r.Post("/send", digestHandler.Send)
+		})
+
 		// Snippet CRUD (read for GET, write for modifications)
 		r.Route("/api/v1/snippets", func(r chi.Router) {
-			r.With(middleware.RequireRead, apiRateLimiter.RateLimitRead).Get("/", snippetHandler.List)
-			r.With(middleware.RequireWrite, apiRateLimiter.RateLimitWrite).Post("/", snippetHandler.Create)
-			r.With(middleware.RequireRead, apiRateLimiter.RateLimitRead).Get("/search", snippetHandler.Search)
+			r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/", snippetHandler.List)
+			r.With(snippetsWrite, apiRateLimiter.RateLimitWrite).Post("/", snippetHandler.Create)
+			r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/search", snippetHandler.Search)
+			r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/search-index", snippetHandler.SearchIndex)
+			r.With(snippetsRead, apiRateLimiter.RateLimitRead).Post("/check-duplicates", snippetHandler.CheckDuplicates)
+			r.With(snippetsWrite, apiRateLimiter.RateLimitWrite).Post("/fork", snippetHandler.Fork)
+			r.With(snippetsWrite, apiRateLimiter.RateLimitWrite).Post("/bulk", snippetHandler.Bulk)
+			r.With(snippetsWrite, apiRateLimiter.RateLimitWrite).Put("/external/{external_id}", snippetHandler.Upsert)
 
 			r.Route("/{id}", func(r chi.Router) {
-				r.With(middleware.RequireRead, apiRateLimiter.RateLimitRead).Get("/", snippetHandler.Get)
-				r.With(middleware.RequireWrite, apiRateLimiter.RateLimitWrite).Put("/", snippetHandler.Update)
-				r.With(middleware.RequireWrite, apiRateLimiter.RateLimitWrite).Delete("/", snippetHandler.Delete)
-				r.With(middleware.RequireWrite, apiRateLimiter.RateLimitWrite).Post("/favorite", snippetHandler.ToggleFavorite)
-				r.With(middleware.RequireWrite, apiRateLimiter.RateLimitWrite).Post("/archive", snippetHandler.ToggleArchive)
-				r.With(middleware.RequireWrite, apiRateLimiter.RateLimitWrite).Post("/duplicate", snippetHandler.Duplicate)
-				r.With(middleware.RequireWrite, apiRateLimiter.RateLimitWrite).Post("/restore", snippetHandler.Restore)
+				r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/", snippetHandler.Get)
+				r.With(snippetsWrite, apiRateLimiter.RateLimitWrite).Put("/", snippetHandler.Update)
+				r.With(snippetsWrite, apiRateLimiter.RateLimitWrite).Delete("/", snippetHandler.Delete)
+				r.With(snippetsWrite, apiRateLimiter.RateLimitWrite).Post("/favorite", snippetHandler.ToggleFavorite)
+				r.With(snippetsWrite, apiRateLimiter.RateLimitWrite).Post("/archive", snippetHandler.ToggleArchive)
+				r.With(snippetsRead, apiRateLimiter.RateLimitRead).Post("/used", snippetHandler.MarkUsed)
+				r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/views", snippetHandler.GetViewStats)
+				r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/neighbors", snippetHandler.Neighbors)
+				r.With(snippetsWrite, apiRateLimiter.RateLimitWrite).Post("/duplicate", snippetHandler.Duplicate)
+				r.With(snippetsWrite, apiRateLimiter.RateLimitWrite).Post("/restore", snippetHandler.Restore)
 
 				// History routes
-				r.With(middleware.RequireRead, apiRateLimiter.RateLimitRead).Get("/history", snippetHandler.GetHistory)
-				r.With(middleware.RequireWrite, apiRateLimiter.RateLimitWrite).Post("/history/{history_id}/restore", snippetHandler.RestoreFromHistory)
+				r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/history", snippetHandler.GetHistory)
+				r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/history/{from}/diff/{to}", snippetHandler.DiffHistory)
+				r.With(snippetsWrite, apiRateLimiter.RateLimitWrite).Post("/history/{history_id}/restore", snippetHandler.RestoreFromHistory)
+				r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/activity", snippetHandler.SnippetActivity)
 			})
 		})
 
+		// Activity feed: recent changes to the snippets the caller can see
+		r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/api/v1/activity", snippetHandler.Activity)
+
+		// Quick capture for shell aliases (plain text in, snippet URL out)
+		r.With(snippetsWrite, apiRateLimiter.RateLimitWrite).Post("/api/v1/quick", quickCaptureHandler.Capture)
+
+		// Snapshot raw files from the web (public addresses only)
+		r.With(snippetsWrite, apiRateLimiter.RateLimitWrite).Post("/api/v1/import/urls", urlImportHandler.Import)
+
+		// Saved reports of past backup, S3 and URL imports, for the user who ran them
+		r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/api/v1/imports", importReportHandler.List)
+		r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/api/v1/imports/{id}/report", importReportHandler.Report)
+
+		// Markdown rendering with the public page pipeline
+		r.With(snippetsRead, apiRateLimiter.RateLimitRead).Post("/api/v1/render/markdown", renderHandler.Markdown)
+
+		// Polling triggers for Zapier, n8n and similar tools (flat JSON, no envelope)
+		r.Route("/api/v1/triggers", func(r chi.Router) {
+			r.Use(snippetsRead, apiRateLimiter.RateLimitRead)
+			r.Get("/snippets", triggerHandler.NewSnippets)
+			r.Get("/snippets/sample", triggerHandler.Sample)
+		})
+
 		// Tag CRUD (read for GET, write for modifications)
 		r.Route("/api/v1/tags", func(r chi.Router) {
-			r.With(middleware.RequireRead, apiRateLimiter.RateLimitRead).Get("/", tagHandler.List)
-			r.With(middleware.RequireWrite, apiRateLimiter.RateLimitWrite).Post("/", tagHandler.Create)
+			r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/", tagHandler.List)
+			r.With(tagsWrite, apiRateLimiter.RateLimitWrite).Post("/", tagHandler.Create)
 
 			r.Route("/{id}", func(r chi.Router) {
-				r.With(middleware.RequireRead, apiRateLimiter.RateLimitRead).Get("/", tagHandler.Get)
-				r.With(middleware.RequireWrite, apiRateLimiter.RateLimitWrite).Put("/", tagHandler.Update)
-				r.With(middleware.RequireWrite, apiRateLimiter.RateLimitWrite).Delete("/", tagHandler.Delete)
+				r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/", tagHandler.Get)
+				r.With(tagsWrite, apiRateLimiter.RateLimitWrite).Put("/", tagHandler.Update)
+				r.With(tagsWrite, apiRateLimiter.RateLimitWrite).Delete("/", tagHandler.Delete)
+				r.With(tagsWrite, apiRateLimiter.RateLimitWrite).Post("/restore", tagHandler.Restore)
 			})
 		})
 
 		// Folder CRUD (read for GET, write for modifications)
 		r.Route("/api/v1/folders", func(r chi.Router) {
-			r.With(middleware.RequireRead, apiRateLimiter.RateLimitRead).Get("/", folderHandler.List)
-			r.With(middleware.RequireWrite, apiRateLimiter.RateLimitWrite).Post("/", folderHandler.Create)
+			r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/", folderHandler.List)
+			r.With(foldersWrite, apiRateLimiter.RateLimitWrite).Post("/", folderHandler.Create)
 
 			r.Route("/{id}", func(r chi.Router) {
-				r.With(middleware.RequireRead, apiRateLimiter.RateLimitRead).Get("/", folderHandler.Get)
-				r.With(middleware.RequireWrite, apiRateLimiter.RateLimitWrite).Put("/", folderHandler.Update)
-				r.With(middleware.RequireWrite, apiRateLimiter.RateLimitWrite).Delete("/", folderHandler.Delete)
-				r.With(middleware.RequireWrite, apiRateLimiter.RateLimitWrite).Put("/move", folderHandler.Move)
+				r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/", folderHandler.Get)
+				r.With(foldersWrite, apiRateLimiter.RateLimitWrite).Put("/", folderHandler.Update)
+				r.With(foldersWrite, apiRateLimiter.RateLimitWrite).Delete("/", folderHandler.Delete)
+				r.With(foldersWrite, apiRateLimiter.RateLimitWrite).Post("/restore", folderHandler.Restore)
+				r.With(foldersWrite, apiRateLimiter.RateLimitWrite).Put("/move", folderHandler.Move)
+				r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/access", folderHandler.GetAccess)
+				r.With(foldersWrite, apiRateLimiter.RateLimitWrite).Put("/access", folderHandler.SetAccess)
 			})
 		})
 
-		// API Token management (admin only)
-		if cfg.Config == nil || cfg.Config.Features.APITokens {
+		// Server version and capabilities
+		r.With(apiRateLimiter.RateLimitRead).Get("/api/v1/system/version", healthHandler.Version)
+
+		// Aggregate statistics (read)
+		r.With(snippetsRead, apiRateLimiter.RateLimitRead).Get("/api/v1/stats/languages", statsHandler.Languages)
+
+		// API Token management (admin or workspace admin)
+		if a.Config.Features.APITokens {
 			r.Route("/api/v1/tokens", func(r chi.Router) {
-				r.Use(middleware.RequireAdminWithPassword(cfg.AuthService))
+				r.Use(middleware.RequireWorkspaceAdminWithPassword(a.Auth))
 				r.Use(apiRateLimiter.RateLimitAdmin)
 				r.Get("/", tokenHandler.List)
 				r.Post("/", tokenHandler.Create)
@@ -248,34 +330,44 @@ func NewRouter(cfg RouterConfig) http.Handler {
 				r.Route("/{id}", func(r chi.Router) {
 					r.Get("/", tokenHandler.Get)
 					r.Delete("/", tokenHandler.Delete)
+					r.Get("/stats", tokenHandler.Stats)
+					r.Post("/rotate", tokenHandler.Rotate)
 				})
 			})
 		}
 
-		// Backup & Restore (admin only)
-		if cfg.Config == nil || cfg.Config.Features.BackupRestore {
+		// Backup & Restore (admin, workspace admin or backup:run)
+		if a.Config.Features.BackupRestore {
 			r.Route("/api/v1/backup", func(r chi.Router) {
-				r.Use(middleware.RequireAdminWithPassword(cfg.AuthService))
 				r.Use(apiRateLimiter.RateLimitAdmin)
-				r.Get("/export", backupHandler.Export)
-				r.Post("/export", backupHandler.Export)
-				r.Post("/import", backupHandler.Import)
-
-				// S3 operations
-				r.Get("/s3/status", backupHandler.S3Status)
-				r.Post("/s3/sync", backupHandler.S3Sync)
-				r.Get("/s3/list", backupHandler.S3List)
-				r.Post("/s3/restore", backupHandler.S3Restore)
-				r.Delete("/s3/delete", backupHandler.S3Delete)
+				r.Group(func(r chi.Router) {
+					r.Use(middleware.RequireWorkspaceScopeWithPassword(a.Auth, models.ScopeBackupRun))
+					r.Get("/export", backupHandler.Export)
+					r.Post("/export", backupHandler.Export)
+					r.Post("/import", backupHandler.Import)
+				})
+
+				// S3 operations, on the instance's bucket (admin or backup:run)
+				r.Group(func(r chi.Router) {
+					r.Use(middleware.RequireScopeWithPassword(a.Auth, models.ScopeBackupRun))
+					r.Get("/s3/status", backupHandler.S3Status)
+					r.Post("/s3/sync", backupHandler.S3Sync)
+					r.Get("/s3/list", backupHandler.S3List)
+					r.Get("/s3/presign", backupHandler.S3Presign)
+					r.Post("/s3/restore", backupHandler.S3Restore)
+					r.Delete("/s3/delete", backupHandler.S3Delete)
+				})
 			})
 		}
 
-		// GitHub Gist Sync (admin only for config, write for sync operations)
+		// GitHub Gist Sync (admin only for config, write or sync:manage for sync operations)
 		if gistSyncHandler != nil {
 			r.Route("/api/v1/gist", func(r chi.Router) {
+				r.Use(gistSync)
+
 				// Config endpoints (admin only)
 				r.Group(func(r chi.Router) {
-					r.Use(middleware.RequireAdminWithPassword(cfg.AuthService))
+					r.Use(middleware.RequireAdminWithPassword(a.Auth))
 					r.Use(apiRateLimiter.RateLimitAdmin)
 					r.Get("/config", gistSyncHandler.GetConfig)
 					r.Post("/config", gistSyncHandler.UpdateConfig)
@@ -283,9 +375,9 @@ func NewRouter(cfg RouterConfig) http.Handler {
 					r.Post("/config/test", gistSyncHandler.TestConnection)
 				})
 
-				// Sync operations (write permission)
+				// Sync operations (write permission or sync:manage)
 				r.Group(func(r chi.Router) {
-					r.Use(middleware.RequireWrite)
+					r.Use(syncManage)
 					r.Use(apiRateLimiter.RateLimitWrite)
 					r.Post("/sync/snippet/{id}", gistSyncHandler.SyncSnippet)
 					r.Post("/sync/all", gistSyncHandler.SyncAll)
@@ -295,49 +387,156 @@ func NewRouter(cfg RouterConfig) http.Handler {
 					r.Post("/sync/verify", gistSyncHandler.VerifyMappings)
 				})
 
-				// Mappings and conflicts (read permission)
+				// Mappings and conflicts (read permission or snippets:read)
 				r.Group(func(r chi.Router) {
-					r.Use(middleware.RequireRead)
+					r.Use(snippetsRead)
 					r.Use(apiRateLimiter.RateLimitRead)
 					r.Get("/mappings", gistSyncHandler.ListMappings)
 					r.Get("/conflicts", gistSyncHandler.ListConflicts)
 					r.Get("/logs", gistSyncHandler.GetLogs)
 				})
 
-				// Mapping deletion and conflict resolution (write permission)
+				// Mapping deletion and conflict resolution (write permission or sync:manage)
 				r.Group(func(r chi.Router) {
-					r.Use(middleware.RequireWrite)
+					r.Use(syncManage)
 					r.Use(apiRateLimiter.RateLimitWrite)
 					r.Delete("/mappings/{id}", gistSyncHandler.DeleteMapping)
 					r.Post("/conflicts/{id}/resolve", gistSyncHandler.ResolveConflict)
 				})
 			})
 		}
+
+		// Admin overview: where sessions and failed logins come from
+		r.Route("/api/v1/admin", func(r chi.Router) {
+			r.Use(middleware.RequireAdminWithPassword(a.Auth))
+			r.Use(apiRateLimiter.RateLimitAdmin)
+			r.Get("/overview", adminHandler.Overview)
+			r.Get("/usage", adminHandler.Usage)
+			r.Get("/search-index", adminHandler.SearchIndexStatus)
+			r.Post("/search-index/rebuild", adminHandler.RebuildSearchIndex)
+			r.Post("/database/maintain", adminHandler.MaintainDatabase)
+		})
+
+		// Abuse report moderation queue (admin only)
+		if a.Config.Features.PublicSnippets {
+			r.Route("/api/v1/reports", func(r chi.Router) {
+				r.Use(middleware.RequireAdminWithPassword(a.Auth))
+				r.Use(apiRateLimiter.RateLimitAdmin)
+				r.Get("/", reportHandler.List)
+				r.Post("/{id}/resolve", reportHandler.Resolve)
+			})
+		}
+
+		// Remote sources: mirror public snippets from other instances (admin only)
+		r.Route("/api/v1/remote-sources", func(r chi.Router) {
+			r.Use(middleware.RequireAdminWithPassword(a.Auth))
+			r.Use(apiRateLimiter.RateLimitAdmin)
+			r.Get("/", remoteSourceHandler.List)
+			r.Post("/", remoteSourceHandler.Create)
+			r.Delete("/{id}", remoteSourceHandler.Delete)
+			r.Post("/{id}/sync", remoteSourceHandler.Sync)
+		})
+
+		// Inbound webhooks: turn POSTed payloads into snippets (admin only)
+		r.Route("/api/v1/webhooks", func(r chi.Router) {
+			r.Use(middleware.RequireAdminWithPassword(a.Auth))
+			r.Use(apiRateLimiter.RateLimitAdmin)
+			r.Get("/", webhookHandler.List)
+			r.Post("/", webhookHandler.Create)
+			r.Delete("/{id}", webhookHandler.Delete)
+			r.Post("/{id}/rotate-secret", webhookHandler.RotateSecret)
+		})
+
+		// User accounts in multi-user mode (admin only)
+		if a.Auth.MultiUserEnabled() {
+			userHandler := handlers.NewUserHandler(a.UserRepo)
+			r.Route("/api/v1/users", func(r chi.Router) {
+				r.Use(middleware.RequireAdminWithPassword(a.Auth))
+				r.Use(apiRateLimiter.RateLimitAdmin)
+				r.Get("/", userHandler.List)
+				r.Post("/", userHandler.Create)
+				r.Delete("/{id}", userHandler.Delete)
+			})
+
+			// Workspaces: members list their own, admins manage them
+			workspaceHandler := handlers.NewWorkspaceHandler(a.WorkspaceRepo, a.Auth)
+			r.Route("/api/v1/workspaces", func(r chi.Router) {
+				r.With(apiRateLimiter.RateLimitRead).Get("/", workspaceHandler.List)
+				r.Group(func(r chi.Router) {
+					r.Use(middleware.RequireAdminWithPassword(a.Auth))
+					r.Use(apiRateLimiter.RateLimitAdmin)
+					r.Post("/", workspaceHandler.Create)
+					r.Put("/{id}", workspaceHandler.Update)
+					r.Delete("/{id}", workspaceHandler.Delete)
+					r.Get("/{id}/members", workspaceHandler.ListMembers)
+					r.Put("/{id}/members/{userId}", workspaceHandler.AddMember)
+					r.Delete("/{id}/members/{userId}", workspaceHandler.RemoveMember)
+				})
+			})
+		}
+
+		// Chat bot that saves code blocks and answers /find (admin only, token is encrypted)
+		if a.Bot != nil {
+			botHandler := handlers.NewBotHandler(a.Bot)
+			r.Route("/api/v1/bot", func(r chi.Router) {
+				r.Use(middleware.RequireAdminWithPassword(a.Auth))
+				r.Use(apiRateLimiter.RateLimitAdmin)
+				r.Get("/", botHandler.GetConfig)
+				r.Put("/", botHandler.UpdateConfig)
+				r.Delete("/", botHandler.DeleteConfig)
+			})
+		}
+
+		// One-shot export to a GitHub repository (admin only, uses the gist sync token)
+		if githubExportHandler != nil {
+			r.With(
+				middleware.RequireAdminWithPassword(a.Auth),
+				apiRateLimiter.RateLimitAdmin,
+			).Post("/api/v1/export/github", githubExportHandler.Export)
+		}
 	})
 
 	// Web UI routes
-	webHandler, err := web.NewHandler(cfg.AuthService, settingsRepo, cfg.Version)
+	webHandler, err := web.NewHandler(a.Auth, a.SettingsRepo, cfg.Version)
 	if err != nil {
-		cfg.Logger.Error("failed to create web handler", "error", err)
+		logger.Error("failed to create web handler", "error", err)
 	} else {
 		// Set demo mode and base path if enabled
-		webHandler = webHandler.WithDemoMode(cfg.Config.Demo.Enabled).WithBasePath(cfg.BasePath)
+		webHandler = webHandler.WithDemoMode(a.Config.Demo.Enabled).WithBasePath(basePath).WithPublicViews(a.Snippets).WithPublicSnippets(a.Snippets).
+			WithTheme(a.Config.Server.ThemeDir, logger)
 
 		// Static files
-		r.Handle("/static/*", web.StaticHandler(cfg.BasePath))
+		r.Handle("/static/*", web.StaticHandler(basePath, a.Config.Server.ThemeDir))
 
 		// Web pages
 		r.Get("/", webHandler.Index)
 		r.Get("/login", webHandler.Login)
-		if cfg.Config == nil || cfg.Config.Features.PublicSnippets {
-			r.Get("/s/{id}", webHandler.PublicSnippet) // Public snippet share page
+		if a.Config.Features.PublicSnippets {
+			r.With(publicSharing).Get("/s/{id}", webHandler.PublicSnippet) // Public snippet share page
+			r.With(publicSharing).Get("/embed/{id}", webHandler.Embed)     // Share page for iframes on other sites
 		}
 	}
 
+	// Unknown routes: JSON errors for the API, error pages for everything else
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/api/") || webHandler == nil {
+			handlers.NotFound(w, r, "")
+			return
+		}
+		webHandler.NotFound(w, r)
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/api/") || webHandler == nil {
+			handlers.Error(w, r, http.StatusMethodNotAllowed, apierror.MethodNotAllowed, "Method not allowed")
+			return
+		}
+		webHandler.MethodNotAllowed(w, r)
+	})
+
 	// If base path is configured, mount everything under it
-	if cfg.BasePath != "" {
+	if basePath != "" {
 		baseRouter := chi.NewRouter()
-		baseRouter.Mount(cfg.BasePath, r)
+		baseRouter.Mount(basePath, r)
 		return baseRouter
 	}
 
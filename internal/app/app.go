@@ -0,0 +1,495 @@
+// Package app wires repositories and services together from configuration.
+// The HTTP server, background workers and CLI subcommands all build on the
+// same App so they cannot drift apart in how services are constructed.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/auth"
+	"github.com/MohamedElashri/snipo/internal/config"
+	"github.com/MohamedElashri/snipo/internal/database"
+	"github.com/MohamedElashri/snipo/internal/demo"
+	"github.com/MohamedElashri/snipo/internal/geoip"
+	"github.com/MohamedElashri/snipo/internal/mail"
+	"github.com/MohamedElashri/snipo/internal/metrics"
+	"github.com/MohamedElashri/snipo/internal/outbound"
+	"github.com/MohamedElashri/snipo/internal/replica"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/storage"
+	"github.com/MohamedElashri/snipo/internal/telemetry"
+)
+
+// App holds the database and every repository and service built on it
+type App struct {
+	Config *config.Config
+	Logger *slog.Logger
+	DB     *database.DB
+	Auth   *auth.Service
+
+	// Repositories
+	SnippetRepo      *repository.SnippetRepository
+	TagRepo          *repository.TagRepository
+	FolderRepo       *repository.FolderRepository
+	FileRepo         *repository.SnippetFileRepository
+	TokenRepo        *repository.TokenRepository
+	SettingsRepo     *repository.SettingsRepository
+	HistoryRepo      *repository.HistoryRepository
+	ActivityRepo     *repository.ActivityRepository
+	GistSyncRepo     *repository.GistSyncRepository
+	RemoteSourceRepo *repository.RemoteSourceRepository
+	StatsRepo        *repository.StatsRepository
+	ReportRepo       *repository.ReportRepository
+	WebhookRepo      *repository.InboundWebhookRepository
+	BotRepo          *repository.BotRepository
+	UserRepo         *repository.UserRepository
+	WorkspaceRepo    *repository.WorkspaceRepository
+	JobRunRepo       *repository.JobRunRepository
+	ImportReportRepo *repository.ImportReportRepository
+
+	// Services
+	Snippets      *services.SnippetService
+	Backup        *services.BackupService
+	S3Sync        *services.S3SyncService // nil unless S3 or SFTP is enabled and reachable
+	RemoteSources *services.RemoteSourceService
+	Reports       *services.ReportService
+	Webhooks      *services.InboundWebhookService
+	QuickCapture  *services.QuickCaptureService
+	URLImport     *services.URLImportService
+	SearchIndex   *services.SearchIndexService
+	Maintenance   *services.MaintenanceService
+	Encryption    *services.EncryptionService // nil if the key could not be derived
+	Bot           *services.BotService        // nil if the encryption service is unavailable
+	Digest        *services.DigestService     // nil unless SMTP is configured
+
+	Metrics   *metrics.Registry   // nil unless SNIPO_METRICS_ENABLED is set
+	Telemetry *telemetry.Recorder // nil unless SNIPO_TELEMETRY_ENABLED is set
+
+	gistSyncWorker *services.GistSyncWorker
+	replicator     *replica.Replicator
+	reloadHooks    []func()
+}
+
+// OpenDatabase connects to the configured database and applies migrations
+func OpenDatabase(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*database.DB, error) {
+	db, err := database.New(database.Config{
+		Path:            cfg.Database.Path,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		BusyTimeout:     cfg.Database.BusyTimeout,
+		JournalMode:     cfg.Database.JournalMode,
+		SynchronousMode: cfg.Database.SynchronousMode,
+		MMapSize:        cfg.Database.MMapSize,
+		CacheSize:       cfg.Database.CacheSize,
+
+		ManualCheckpoints: cfg.Replica.Enabled,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	if err := db.Migrate(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return db, nil
+}
+
+// Build opens the database and constructs all repositories and services.
+// Background workers are not started until Start is called.
+func Build(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*App, error) {
+	if err := outbound.SetProxy(cfg.Outbound.Proxy); err != nil {
+		return nil, fmt.Errorf("invalid SNIPO_OUTBOUND_PROXY: %w", err)
+	}
+	if proxy := outbound.ProxyURL(); proxy != "" {
+		logger.Info("outbound requests go through a proxy", "proxy", proxy)
+	}
+
+	db, err := OpenDatabase(ctx, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &App{
+		Config: cfg,
+		Logger: logger,
+		DB:     db,
+
+		SnippetRepo:      repository.NewSnippetRepository(db.DB),
+		TagRepo:          repository.NewTagRepository(db.DB),
+		FolderRepo:       repository.NewFolderRepository(db.DB),
+		FileRepo:         repository.NewSnippetFileRepository(db.DB),
+		TokenRepo:        repository.NewTokenRepository(db.DB),
+		SettingsRepo:     repository.NewSettingsRepository(db.DB),
+		HistoryRepo:      repository.NewHistoryRepository(db.DB),
+		ActivityRepo:     repository.NewActivityRepository(db.DB),
+		GistSyncRepo:     repository.NewGistSyncRepository(db.DB),
+		RemoteSourceRepo: repository.NewRemoteSourceRepository(db.DB),
+		StatsRepo:        repository.NewStatsRepository(db.DB),
+		ReportRepo:       repository.NewReportRepository(db.DB),
+		WebhookRepo:      repository.NewInboundWebhookRepository(db.DB),
+		BotRepo:          repository.NewBotRepository(db.DB),
+		UserRepo:         repository.NewUserRepository(db.DB),
+		WorkspaceRepo:    repository.NewWorkspaceRepository(db.DB),
+		JobRunRepo:       repository.NewJobRunRepository(db.DB),
+		ImportReportRepo: repository.NewImportReportRepository(db.DB),
+	}
+
+	// Use pre-hashed password if available, otherwise use plain password
+	masterPassword := cfg.Auth.MasterPasswordHash
+	if masterPassword == "" {
+		masterPassword = cfg.Auth.MasterPassword
+	}
+	a.Auth = auth.NewService(db.DB, masterPassword, cfg.Auth.SessionSecret, cfg.Auth.SessionDuration, logger, cfg.Auth.Disabled).
+		WithLoginChallenge(cfg.Auth.ChallengeAfter, cfg.Auth.ChallengeDifficulty).
+		WithRememberMe(cfg.Auth.RememberMeDuration, cfg.Auth.SessionMaxLifetime).
+		WithMultiUser(cfg.Auth.MultiUser)
+
+	// A generated secret changes on every restart, so signing cookies with it
+	// would log everyone out each time
+	if !cfg.Auth.SessionSecretGenerated {
+		a.Auth.WithSignedCookies(cfg.Auth.PreviousSessionSecret, cfg.Auth.SessionSecretGrace)
+	}
+
+	if cfg.Auth.GeoIPDatabase != "" {
+		if reader, err := geoip.Open(cfg.Auth.GeoIPDatabase); err != nil {
+			logger.Warn("failed to load GeoIP database, access insights won't include countries", "error", err)
+		} else {
+			a.Auth.WithGeoIP(reader)
+			logger.Info("GeoIP database loaded", "path", cfg.Auth.GeoIPDatabase)
+		}
+	}
+
+	a.Snippets = services.NewSnippetService(a.SnippetRepo, logger).
+		WithTagRepo(a.TagRepo).
+		WithFolderRepo(a.FolderRepo).
+		WithFileRepo(a.FileRepo).
+		WithHistoryRepo(a.HistoryRepo).
+		WithActivityRepo(a.ActivityRepo).
+		WithSettingsRepo(a.SettingsRepo).
+		WithRemoteSourceRepo(a.RemoteSourceRepo).
+		WithMaxFiles(cfg.Server.MaxFilesPerSnippet).
+		WithPrivateNetworks(cfg.Outbound.AllowPrivate).
+		WithPublicCache(cfg.API.PublicCacheTTL).
+		WithShareLinksByID(cfg.API.ShareLinksByID)
+
+	a.Backup = services.NewBackupService(db.DB, a.Snippets, a.TagRepo, a.FolderRepo, a.FileRepo, logger, cfg.Auth.EncryptionSalt).
+		WithImportReports(a.ImportReportRepo)
+
+	a.RemoteSources = services.NewRemoteSourceService(a.RemoteSourceRepo, a.SnippetRepo, a.FileRepo, a.FolderRepo, logger)
+	a.Reports = services.NewReportService(a.ReportRepo, a.SnippetRepo, a.SettingsRepo, logger).WithShareLinksByID(cfg.API.ShareLinksByID)
+	a.Webhooks = services.NewInboundWebhookService(a.WebhookRepo, a.Snippets, a.FolderRepo, logger)
+	a.QuickCapture = services.NewQuickCaptureService(a.Snippets, logger)
+	a.URLImport = services.NewURLImportService(a.Snippets, logger).
+		WithPrivateNetworks(cfg.Outbound.AllowPrivate).
+		WithImportReports(a.ImportReportRepo)
+	a.SearchIndex = services.NewSearchIndexService(a.SnippetRepo, a.SettingsRepo, logger)
+	a.Maintenance = services.NewMaintenanceService(db.DB, cfg.Database.Path, logger).
+		WithReplication(cfg.Replica.Enabled)
+
+	if encryptionSvc, err := NewEncryption(cfg); err != nil {
+		logger.Warn("failed to initialize encryption service", "error", err)
+	} else {
+		a.Encryption = encryptionSvc
+		a.Bot = services.NewBotService(a.BotRepo, a.Snippets, a.Encryption, logger)
+	}
+
+	a.S3Sync = a.newS3Sync(cfg)
+
+	if cfg.Mail.Enabled() {
+		sender := mail.NewSMTPSender(cfg.Mail.SMTPHost, cfg.Mail.SMTPPort, cfg.Mail.SMTPUsername, cfg.Mail.SMTPPassword, cfg.Mail.From)
+		a.Digest = services.NewDigestService(a.ActivityRepo, a.GistSyncRepo, a.SettingsRepo, a.JobRunRepo, sender, cfg.Mail.DigestInterval, logger)
+		if a.S3Sync != nil {
+			a.Digest.WithBackups(a.S3Sync)
+		}
+	}
+
+	if cfg.Metrics.Enabled {
+		a.Metrics = newMetrics(a)
+	}
+	if cfg.Telemetry.Enabled {
+		a.Telemetry = telemetry.NewRecorder(repository.NewUsageRepository(db.DB), logger)
+	}
+
+	return a, nil
+}
+
+// NewEncryption creates the encryption service protecting GitHub tokens and
+// encrypted backups. Its key is derived from the encryption salt and the
+// session secret, so it survives restarts as long as both are persistent.
+func NewEncryption(cfg *config.Config) (*services.EncryptionService, error) {
+	legacyEncryptionKey := services.DeriveEncryptionKey(cfg.Auth.EncryptionSalt)
+	encryptionKey := services.DeriveEncryptionKeyWithSecret(cfg.Auth.EncryptionSalt, cfg.Auth.SessionSecret)
+	if cfg.Auth.SessionSecretGenerated {
+		encryptionKey = legacyEncryptionKey
+	}
+	fallbackKeys := [][]byte{legacyEncryptionKey}
+	if cfg.Auth.PreviousSessionSecret != "" {
+		// Data encrypted before the session secret was rotated
+		fallbackKeys = append(fallbackKeys, services.DeriveEncryptionKeyWithSecret(cfg.Auth.EncryptionSalt, cfg.Auth.PreviousSessionSecret))
+	}
+	return services.NewEncryptionServiceWithFallback(encryptionKey, fallbackKeys...)
+}
+
+// NewBackupStorage connects to the storage backups go to: S3 or, if S3 is
+// disabled, SFTP. encrypt reports whether backups are to be encrypted
+// client-side. The storage is nil if neither is enabled.
+func NewBackupStorage(cfg *config.Config) (backupStorage services.BackupStorage, encrypt bool, err error) {
+	switch {
+	case cfg.S3.Enabled:
+		s3Storage, err := storage.NewS3Storage(storage.S3Config{
+			Endpoint:        cfg.S3.Endpoint,
+			AccessKeyID:     cfg.S3.AccessKeyID,
+			SecretAccessKey: cfg.S3.SecretAccessKey,
+			Bucket:          cfg.S3.Bucket,
+			Region:          cfg.S3.Region,
+			UseSSL:          cfg.S3.UseSSL,
+			Proxy:           outbound.Proxy,
+			StorageClass:    cfg.S3.StorageClass,
+			Tags:            cfg.S3.Tags,
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to initialize S3 storage: %w", err)
+		}
+		return s3Storage, cfg.S3.Encrypt, nil
+	case cfg.SFTP.Enabled:
+		sftpStorage, err := storage.NewSFTPStorage(storage.SFTPConfig{
+			Host:                 cfg.SFTP.Host,
+			User:                 cfg.SFTP.User,
+			Password:             cfg.SFTP.Password,
+			PrivateKey:           cfg.SFTP.PrivateKey,
+			PrivateKeyPassphrase: cfg.SFTP.PrivateKeyPassphrase,
+			HostKey:              cfg.SFTP.HostKey,
+			Path:                 cfg.SFTP.Path,
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to initialize SFTP storage: %w", err)
+		}
+		return sftpStorage, cfg.SFTP.Encrypt, nil
+	}
+	return nil, false, nil
+}
+
+// newS3Sync creates the backup sync service for the storage cfg configures,
+// or returns nil if there is none or it can't be used
+func (a *App) newS3Sync(cfg *config.Config) *services.S3SyncService {
+	if cfg.S3.Enabled && cfg.SFTP.Enabled {
+		a.Logger.Warn("both S3 and SFTP storage are enabled, using S3")
+	}
+	backupStorage, encrypt, err := NewBackupStorage(cfg)
+	if err != nil {
+		a.Logger.Warn("failed to initialize backup storage", "error", err)
+		return nil
+	}
+	if backupStorage == nil {
+		return nil
+	}
+	if cfg.S3.Enabled {
+		a.Logger.Info("S3 storage initialized", "bucket", cfg.S3.Bucket, "storage_class", cfg.S3.StorageClass)
+	} else {
+		a.Logger.Info("SFTP storage initialized", "host", cfg.SFTP.Host, "path", cfg.SFTP.Path)
+	}
+
+	s3Sync := services.NewS3SyncService(backupStorage, a.Backup, a.Logger)
+	if encrypt {
+		if a.Encryption == nil {
+			// Never upload in the clear when encryption was asked for
+			a.Logger.Error("client-side backup encryption requested but the encryption service is unavailable, disabling backups")
+			return nil
+		}
+		s3Sync.WithEnvelopeEncryption(a.Encryption)
+	}
+	return s3Sync
+}
+
+// OnReload registers fn to be called by Reload once the new settings are in
+// a.Config, so parts built from them, like the router's rate limiters, can
+// pick them up
+func (a *App) OnReload(fn func()) {
+	a.reloadHooks = append(a.reloadHooks, fn)
+}
+
+// Reload applies the settings of cfg that can change without a restart:
+// rate limits, CORS origins and S3 or SFTP storage. The rest of cfg is ignored.
+func (a *App) Reload(cfg *config.Config) {
+	a.Config.Auth.RateLimit = cfg.Auth.RateLimit
+	a.Config.API.AllowedOrigins = cfg.API.AllowedOrigins
+	a.Config.API.RateLimitRead = cfg.API.RateLimitRead
+	a.Config.API.RateLimitWrite = cfg.API.RateLimitWrite
+	a.Config.API.RateLimitAdmin = cfg.API.RateLimitAdmin
+
+	if !reflect.DeepEqual(cfg.S3, a.Config.S3) || !reflect.DeepEqual(cfg.SFTP, a.Config.SFTP) {
+		a.Config.S3 = cfg.S3
+		a.Config.SFTP = cfg.SFTP
+		a.Config.Features.S3Sync = cfg.Features.S3Sync
+		a.S3Sync = a.newS3Sync(cfg)
+		if !cfg.S3.Enabled && !cfg.SFTP.Enabled {
+			a.Logger.Info("backup storage disabled")
+		}
+		if a.Digest != nil {
+			if a.S3Sync != nil {
+				a.Digest.WithBackups(a.S3Sync)
+			} else {
+				a.Digest.WithBackups(nil)
+			}
+		}
+	}
+
+	for _, fn := range a.reloadHooks {
+		fn()
+	}
+}
+
+// newMetrics creates the metrics registry with the gauges read at scrape
+// time; request and gist sync counters are added where they are counted
+func newMetrics(a *App) *metrics.Registry {
+	reg := metrics.NewRegistry()
+
+	reg.GaugeFunc("snipo_sessions_active", "Login sessions that haven't expired.", func() (float64, error) {
+		count, err := a.Auth.ActiveSessions()
+		return float64(count), err
+	})
+
+	stat := func(read func(s sql.DBStats) float64) func() (float64, error) {
+		return func() (float64, error) { return read(a.DB.DB.Stats()), nil }
+	}
+	reg.GaugeFunc("snipo_db_open_connections", "Open database connections, in use or idle.",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	reg.GaugeFunc("snipo_db_in_use_connections", "Database connections currently in use.",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	reg.GaugeFunc("snipo_db_idle_connections", "Idle database connections.",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	reg.GaugeFunc("snipo_db_max_open_connections", "Maximum number of open database connections.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	reg.CounterFunc("snipo_db_wait_count_total", "Times a query waited for a free database connection.",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	reg.CounterFunc("snipo_db_wait_duration_seconds_total", "Time spent waiting for a free database connection.",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+
+	reg.CounterFunc("snipo_public_cache_hits_total", "Public snippet reads answered from memory.", func() (float64, error) {
+		hits, _ := a.Snippets.PublicCacheStats()
+		return float64(hits), nil
+	})
+	reg.CounterFunc("snipo_public_cache_loads_total", "Public snippet reads that went to the database, shared by concurrent requests.", func() (float64, error) {
+		_, loads := a.Snippets.PublicCacheStats()
+		return float64(loads), nil
+	})
+
+	return reg
+}
+
+// Start launches the background workers: session cleanup, trash and token
+// cleanup, gist sync, the chat bot, scheduled publishing, remote source sync,
+// the digest e-mail and, in demo mode, periodic resets
+func (a *App) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(1 * time.Hour)
+		for range ticker.C {
+			if err := a.Auth.CleanupExpiredSessions(); err != nil {
+				a.Logger.Warn("failed to cleanup sessions", "error", err)
+			}
+		}
+	}()
+
+	a.SearchIndex.WarnIfInconsistent(ctx)
+
+	if a.Telemetry != nil {
+		a.Telemetry.Start(ctx)
+	}
+
+	services.NewCleanupService(a.SnippetRepo, a.Logger).WithTokenRepo(a.TokenRepo).WithActivityRepo(a.ActivityRepo).WithTagRepo(a.TagRepo).WithFolderRepo(a.FolderRepo).
+		WithImportReportRepo(a.ImportReportRepo).Start(ctx)
+
+	if a.Encryption != nil {
+		a.gistSyncWorker = services.NewGistSyncWorker(a.GistSyncRepo, a.SnippetRepo, a.FileRepo, a.Encryption, a.Logger).
+			WithGitHubAPIURL(a.Config.GitHub.APIURL).
+			WithFeatureFlags(a.SettingsRepo)
+		if a.Metrics != nil {
+			a.gistSyncWorker.WithMetrics(a.Metrics)
+		}
+		if err := a.gistSyncWorker.Start(ctx); err != nil {
+			a.Logger.Warn("failed to start gist sync worker", "error", err)
+		}
+	}
+
+	if a.Bot != nil {
+		a.Bot.Start(ctx)
+	}
+
+	services.NewPublishScheduler(a.SnippetRepo, a.Config.Publish.CheckInterval, a.Config.Publish.WebhookURLs, a.Logger).Start(ctx)
+
+	a.RemoteSources.Start(ctx, a.Config.Remote.SyncInterval)
+
+	if a.Digest != nil {
+		a.Digest.Start(ctx)
+	}
+
+	if a.Config.Demo.Enabled {
+		demo.NewService(a.DB.DB, a.Snippets, a.Logger, a.Config.Demo.ResetInterval, a.Config.Demo.Enabled).
+			StartPeriodicReset(ctx)
+	}
+
+	if a.Config.Replica.Enabled {
+		a.startReplication(ctx)
+	}
+}
+
+// startReplication ships the database's WAL to the backup storage
+func (a *App) startReplication(ctx context.Context) {
+	backupStorage, encrypt, err := NewBackupStorage(a.Config)
+	if err != nil {
+		a.Logger.Warn("failed to initialize backup storage, replication disabled", "error", err)
+		return
+	}
+	if backupStorage == nil {
+		a.Logger.Warn("replication needs S3 or SFTP storage, replication disabled")
+		return
+	}
+	replicator := replica.New(a.Config.Database.Path, backupStorage, a.Logger).
+		WithIntervals(a.Config.Replica.Interval, a.Config.Replica.SnapshotInterval, a.Config.Replica.Retention)
+	if encrypt {
+		if a.Encryption == nil {
+			// Never upload in the clear when encryption was asked for
+			a.Logger.Error("client-side backup encryption requested but the encryption service is unavailable, replication disabled")
+			return
+		}
+		replicator.WithEncryption(a.Encryption)
+	}
+	if err := replicator.Start(ctx); err != nil {
+		a.Logger.Warn("failed to start replication", "error", err)
+		return
+	}
+	a.replicator = replicator
+	a.Logger.Info("database replication started", "interval", a.Config.Replica.Interval)
+}
+
+// Stop stops background workers that need an orderly shutdown
+func (a *App) Stop() {
+	if a.gistSyncWorker != nil {
+		if err := a.gistSyncWorker.Stop(); err != nil {
+			a.Logger.Warn("failed to stop gist sync worker", "error", err)
+		}
+	}
+	if a.Bot != nil {
+		a.Bot.Stop()
+	}
+	if a.replicator != nil {
+		a.replicator.Stop()
+	}
+}
+
+// Close releases the database connection
+func (a *App) Close() error {
+	if a.Telemetry != nil {
+		if err := a.Telemetry.Flush(context.Background()); err != nil {
+			a.Logger.Warn("failed to save usage counts", "error", err)
+		}
+	}
+	return a.DB.Close()
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/config"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestBuild(t *testing.T) {
+	t.Setenv("SNIPO_DB_PATH", filepath.Join(t.TempDir(), "snipo.db"))
+	t.Setenv("SNIPO_MASTER_PASSWORD", "test123")
+	t.Setenv("SNIPO_SESSION_SECRET", "app-test-session-secret-0123456789")
+	t.Setenv("SNIPO_ENCRYPTION_SALT", "app-test-salt")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load failed: %v", err)
+	}
+
+	ctx := testutil.TestContext()
+	a, err := Build(ctx, cfg, testutil.TestLogger())
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	t.Cleanup(func() { _ = a.Close() })
+
+	if a.Auth == nil || a.Snippets == nil || a.Backup == nil || a.RemoteSources == nil {
+		t.Fatal("expected core services to be built")
+	}
+	if a.Encryption == nil {
+		t.Error("expected encryption service when a salt is configured")
+	}
+	if a.S3Sync != nil {
+		t.Error("expected no S3 sync service when S3 is disabled")
+	}
+
+	// The snippet service must be wired with the file and tag repositories
+	created, err := a.Snippets.Create(ctx, &models.SnippetInput{
+		Title: "Wired",
+		Tags:  []string{"wiring"},
+		Files: []models.SnippetFileInput{{Filename: "main.go", Content: "package main", Language: "go"}},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	fetched, err := a.Snippets.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if len(fetched.Files) != 1 || len(fetched.Tags) != 1 {
+		t.Errorf("expected files and tags to be stored, got %d files and %d tags", len(fetched.Files), len(fetched.Tags))
+	}
+}
+
+func TestReload(t *testing.T) {
+	t.Setenv("SNIPO_DB_PATH", filepath.Join(t.TempDir(), "snipo.db"))
+	t.Setenv("SNIPO_MASTER_PASSWORD", "test123")
+	t.Setenv("SNIPO_SESSION_SECRET", "app-test-session-secret-0123456789")
+	t.Setenv("SNIPO_ENCRYPTION_SALT", "app-test-salt")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load failed: %v", err)
+	}
+	a, err := Build(testutil.TestContext(), cfg, testutil.TestLogger())
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	t.Cleanup(func() { _ = a.Close() })
+
+	reloads := 0
+	a.OnReload(func() { reloads++ })
+
+	t.Setenv("SNIPO_RATE_LIMIT_READ", "42")
+	t.Setenv("SNIPO_ALLOWED_ORIGINS", "https://app.example.com")
+	t.Setenv("SNIPO_S3_ENABLED", "true")
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(bucket.Close)
+	t.Setenv("SNIPO_S3_ENDPOINT", strings.TrimPrefix(bucket.URL, "http://"))
+	t.Setenv("SNIPO_S3_SSL", "false")
+	t.Setenv("SNIPO_S3_BUCKET", "backups")
+	t.Setenv("SNIPO_S3_ACCESS_KEY", "key")
+	t.Setenv("SNIPO_S3_SECRET_KEY", "secret")
+	t.Setenv("SNIPO_PORT", "9999")
+	reloaded, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load failed: %v", err)
+	}
+	a.Reload(reloaded)
+
+	if reloads != 1 {
+		t.Errorf("expected the reload hook to run once, ran %d times", reloads)
+	}
+	if a.Config.API.RateLimitRead != 42 || len(a.Config.API.AllowedOrigins) != 1 {
+		t.Errorf("expected the rate limit and origins to be reloaded, got %+v", a.Config.API)
+	}
+	if a.Config.Server.Port == 9999 {
+		t.Error("expected settings needing a restart to be left alone")
+	}
+	if a.S3Sync == nil {
+		t.Fatal("expected S3 to be set up by the reload")
+	}
+
+	t.Setenv("SNIPO_S3_ENABLED", "false")
+	if reloaded, err = config.Load(); err != nil {
+		t.Fatalf("config.Load failed: %v", err)
+	}
+	a.Reload(reloaded)
+	if a.S3Sync != nil {
+		t.Error("expected S3 to be turned off by the reload")
+	}
+}
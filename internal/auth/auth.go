@@ -17,6 +17,8 @@ import (
 	"time"
 
 	"golang.org/x/crypto/argon2"
+
+	"github.com/MohamedElashri/snipo/internal/models"
 )
 
 // Common errors
@@ -47,9 +49,15 @@ type Service struct {
 	masterPasswordHash string
 	sessionSecret      string
 	sessionDuration    time.Duration
+	rememberDuration   time.Duration // Idle timeout of remember-me sessions, 0 disables them
+	maxLifetime        time.Duration // Remember-me sessions never outlive this from login
 	logger             *slog.Logger
 	failedAttempts     *FailedLoginTracker
-	authDisabled       bool // If true, authentication is completely bypassed
+	challenge          *loginChallenge // nil unless login challenges are enabled
+	cookies            *cookieSigner   // nil unless session cookies are signed
+	geoip              CountryLookup   // nil unless a GeoIP database is configured
+	authDisabled       bool            // If true, authentication is completely bypassed
+	multiUser          bool            // If true, accounts in the users table can log in
 }
 
 // FailedLoginTracker tracks failed login attempts per IP for progressive delays
@@ -176,6 +184,20 @@ func NewService(db *sql.DB, masterPassword, sessionSecret string, sessionDuratio
 	}
 }
 
+// WithRememberMe lets logins ask for a long-lived session. A remembered
+// session expires after idle without use, is extended on every use and never
+// outlives maxLifetime from login. Zero idle disables remember-me.
+func (s *Service) WithRememberMe(idle, maxLifetime time.Duration) *Service {
+	s.rememberDuration = idle
+	s.maxLifetime = maxLifetime
+	return s
+}
+
+// RememberMeEnabled reports whether logins may ask for a remembered session
+func (s *Service) RememberMeEnabled() bool {
+	return s.rememberDuration > 0
+}
+
 // IsAuthDisabled returns whether authentication is disabled
 func (s *Service) IsAuthDisabled() bool {
 	return s.authDisabled
@@ -204,9 +226,18 @@ func (s *Service) VerifyPasswordWithDelay(password, clientIP string) (bool, time
 		return true, 0
 	}
 
+	s.loginFailed(clientIP)
+	return false, 0
+}
+
+// loginFailed records a failed login from clientIP
+func (s *Service) loginFailed(clientIP string) {
 	s.failedAttempts.RecordFailure(clientIP)
+	if s.challenge != nil {
+		s.challenge.recordFailure()
+	}
+	s.recordFailedLogin(clientIP)
 	s.logger.Warn("failed login attempt", "ip", clientIP)
-	return false, 0
 }
 
 // UpdatePassword updates the master password (in-memory only, resets on restart)
@@ -221,8 +252,17 @@ func (s *Service) UpdatePassword(newPassword string) error {
 	return nil
 }
 
-// CreateSession creates a new session and returns the session token
-func (s *Service) CreateSession() (string, error) {
+// CreateSession creates a new session for the owner and returns the session
+// token, signed for use as a cookie value when cookie signing is enabled.
+// With remember set (and remember-me enabled) the session uses sliding
+// expiration instead of the fixed session duration. clientIP is only used to
+// record the session's country.
+func (s *Service) CreateSession(remember bool, clientIP string) (string, error) {
+	return s.CreateUserSession(models.OwnerUserID, remember, clientIP)
+}
+
+// CreateUserSession is CreateSession for the given user
+func (s *Service) CreateUserSession(userID int64, remember bool, clientIP string) (string, error) {
 	// Generate random token
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
@@ -241,53 +281,96 @@ func (s *Service) CreateSession() (string, error) {
 	sessionID := hex.EncodeToString(idBytes)
 
 	// Calculate expiry
-	expiresAt := time.Now().Add(s.sessionDuration)
+	now := time.Now()
+	expiresAt := now.Add(s.sessionDuration)
+	var maxExpiresAt sql.NullTime
+	remember = remember && s.RememberMeEnabled()
+	if remember {
+		maxExpiresAt = sql.NullTime{Time: now.Add(s.maxLifetime), Valid: true}
+		expiresAt = s.slidingExpiry(now, maxExpiresAt)
+	}
 
 	// Store session
 	_, err := s.db.Exec(
-		"INSERT INTO sessions (id, token_hash, expires_at) VALUES (?, ?, ?)",
-		sessionID, tokenHash, expiresAt,
+		"INSERT INTO sessions (id, token_hash, expires_at, remember, max_expires_at, country, user_id) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		sessionID, tokenHash, expiresAt, remember, maxExpiresAt, s.country(clientIP), userID,
 	)
 	if err != nil {
 		return "", fmt.Errorf("failed to create session: %w", err)
 	}
 
-	s.logger.Info("session created", "session_id", sessionID, "expires_at", expiresAt)
-	return token, nil
+	s.logger.Info("session created", "session_id", sessionID, "user_id", userID, "expires_at", expiresAt, "remember", remember)
+	return s.sealSessionToken(token), nil
+}
+
+// slidingExpiry returns when a remembered session used at now expires,
+// capped by its absolute maximum lifetime
+func (s *Service) slidingExpiry(now time.Time, maxExpiresAt sql.NullTime) time.Time {
+	expiresAt := now.Add(s.rememberDuration)
+	if maxExpiresAt.Valid && expiresAt.After(maxExpiresAt.Time) {
+		expiresAt = maxExpiresAt.Time
+	}
+	return expiresAt
 }
 
-// ValidateSession checks if a session token is valid
+// ValidateSession checks if a session token is valid. The token is the
+// cookie value, so its signature is verified first when cookies are signed.
 // MIGRATION STRATEGY: Supports both HMAC-SHA256 (new) and SHA256 (legacy) for backward compatibility
 // - Tries HMAC-SHA256 first (all new sessions)
 // - Falls back to SHA256 only for old sessions
 // - Automatically upgrades old sessions to HMAC-SHA256 on first use
-func (s *Service) ValidateSession(token string) bool {
-	if token == "" {
-		return false
+func (s *Service) ValidateSession(value string) bool {
+	_, ok := s.SessionUser(value)
+	return ok
+}
+
+// SessionUser validates a session token like ValidateSession and returns the
+// user the session belongs to
+func (s *Service) SessionUser(value string) (int64, bool) {
+	if value == "" {
+		return 0, false
+	}
+	token, _, ok := s.openSessionToken(value)
+	if !ok {
+		return 0, false
 	}
 
 	// Try new HMAC-SHA256 hash first
 	tokenHash := hashToken(token)
 	var expiresAt time.Time
 	var sessionID string
+	var remember bool
+	var maxExpiresAt sql.NullTime
+	var userID int64
 	err := s.db.QueryRow(
-		"SELECT id, expires_at FROM sessions WHERE token_hash = ?",
+		"SELECT id, expires_at, remember, max_expires_at, user_id FROM sessions WHERE token_hash = ?",
 		tokenHash,
-	).Scan(&sessionID, &expiresAt)
+	).Scan(&sessionID, &expiresAt, &remember, &maxExpiresAt, &userID)
 
 	if err == nil {
-		if time.Now().After(expiresAt) {
+		now := time.Now()
+		if now.After(expiresAt) || (maxExpiresAt.Valid && now.After(maxExpiresAt.Time)) {
 			_, _ = s.db.Exec("DELETE FROM sessions WHERE token_hash = ?", tokenHash)
-			return false
+			return 0, false
 		}
-		return true
+
+		// Remembered sessions slide forward on activity. Skip the write
+		// unless the expiry moves noticeably, so busy pages don't hit the
+		// database on every request.
+		if remember && s.RememberMeEnabled() {
+			if next := s.slidingExpiry(now, maxExpiresAt); next.Sub(expiresAt) > time.Minute {
+				_, _ = s.db.Exec("UPDATE sessions SET expires_at = ? WHERE id = ?", next, sessionID)
+			}
+		}
+		return userID, true
 	}
 
-	return false
+	return 0, false
 }
 
 // InvalidateSession removes a session
-func (s *Service) InvalidateSession(token string) error {
+func (s *Service) InvalidateSession(value string) error {
+	token, _, _ := cutLast(value, ".")
 	tokenHash := hashToken(token)
 	_, err := s.db.Exec("DELETE FROM sessions WHERE token_hash = ?", tokenHash)
 	return err
@@ -304,26 +387,38 @@ func (s *Service) CleanupExpiredSessions() error {
 	if rows > 0 {
 		s.logger.Info("cleaned up expired sessions", "count", rows)
 	}
-	return nil
+
+	_, err = s.db.Exec("DELETE FROM login_failures WHERE created_at < ?", time.Now().Add(-insightsWindow))
+	return err
+}
+
+// SetSessionCookie sets the session cookie on the response. A remembered
+// session's cookie is kept until the maximum lifetime; the server enforces
+// the idle timeout.
+func (s *Service) SetSessionCookie(w http.ResponseWriter, token string, remember bool) {
+	maxAge := s.sessionDuration
+	if remember && s.RememberMeEnabled() {
+		maxAge = s.maxLifetime
+	}
+	s.writeSessionCookie(w, token, maxAge)
 }
 
-// SetSessionCookie sets the session cookie on the response
-func (s *Service) SetSessionCookie(w http.ResponseWriter, token string) {
+func (s *Service) writeSessionCookie(w http.ResponseWriter, value string, maxAge time.Duration) {
 	http.SetCookie(w, &http.Cookie{
-		Name:     "snipo_session",
-		Value:    token,
+		Name:     sessionCookieName,
+		Value:    value,
 		Path:     "/",
 		HttpOnly: true,
 		Secure:   true,
 		SameSite: http.SameSiteStrictMode,
-		MaxAge:   int(s.sessionDuration.Seconds()),
+		MaxAge:   int(maxAge.Seconds()),
 	})
 }
 
 // ClearSessionCookie clears the session cookie
 func (s *Service) ClearSessionCookie(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
-		Name:     "snipo_session",
+		Name:     sessionCookieName,
 		Value:    "",
 		Path:     "/",
 		HttpOnly: true,
@@ -336,7 +431,7 @@ func (s *Service) ClearSessionCookie(w http.ResponseWriter) {
 // GetSessionFromRequest extracts the session token from the request
 func GetSessionFromRequest(r *http.Request) string {
 	// Check cookie first
-	cookie, err := r.Cookie("snipo_session")
+	cookie, err := r.Cookie(sessionCookieName)
 	if err == nil && cookie.Value != "" {
 		return cookie.Value
 	}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestRememberedSession(t *testing.T) {
+	db := testutil.TestDB(t)
+	s := NewService(db, "correct-password", "test-secret", time.Hour, testutil.TestLogger(), false).
+		WithRememberMe(24*time.Hour, 72*time.Hour)
+
+	expiry := func(token string) time.Time {
+		t.Helper()
+		var expiresAt time.Time
+		if err := db.QueryRow("SELECT expires_at FROM sessions WHERE token_hash = ?", hashToken(token)).Scan(&expiresAt); err != nil {
+			t.Fatalf("failed to read session: %v", err)
+		}
+		return expiresAt
+	}
+
+	regular, err := s.CreateSession(false, "")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	remembered, err := s.CreateSession(true, "")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	if got := time.Until(expiry(regular)); got > time.Hour {
+		t.Errorf("regular session expires in %v, want at most 1h", got)
+	}
+	if got := time.Until(expiry(remembered)); got < 23*time.Hour {
+		t.Errorf("remembered session expires in %v, want about 24h", got)
+	}
+
+	// Activity slides a remembered session forward but not a regular one
+	soon := time.Now().Add(10 * time.Minute)
+	if _, err := db.Exec("UPDATE sessions SET expires_at = ?", soon); err != nil {
+		t.Fatal(err)
+	}
+	if !s.ValidateSession(regular) || !s.ValidateSession(remembered) {
+		t.Fatal("expected both sessions to be valid")
+	}
+	if got := time.Until(expiry(remembered)); got < 23*time.Hour {
+		t.Errorf("remembered session was not extended, expires in %v", got)
+	}
+	if got := time.Until(expiry(regular)); got > 11*time.Minute {
+		t.Errorf("regular session was extended, expires in %v", got)
+	}
+
+	// Sliding never passes the absolute maximum lifetime
+	limit := time.Now().Add(30 * time.Minute)
+	if _, err := db.Exec("UPDATE sessions SET expires_at = ?, max_expires_at = ? WHERE token_hash = ?", soon, limit, hashToken(remembered)); err != nil {
+		t.Fatal(err)
+	}
+	if !s.ValidateSession(remembered) {
+		t.Fatal("expected remembered session to be valid")
+	}
+	if got := expiry(remembered); got.Sub(limit).Abs() > time.Second {
+		t.Errorf("expires_at = %v, want capped at %v", got, limit)
+	}
+
+	if _, err := db.Exec("UPDATE sessions SET max_expires_at = ? WHERE token_hash = ?", time.Now().Add(-time.Minute), hashToken(remembered)); err != nil {
+		t.Fatal(err)
+	}
+	if s.ValidateSession(remembered) {
+		t.Error("expected session past its maximum lifetime to be rejected")
+	}
+}
+
+func TestRememberMeDisabled(t *testing.T) {
+	db := testutil.TestDB(t)
+	s := NewService(db, "correct-password", "test-secret", time.Hour, testutil.TestLogger(), false)
+
+	token, err := s.CreateSession(true, "")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	var remember bool
+	if err := db.QueryRow("SELECT remember FROM sessions WHERE token_hash = ?", hashToken(token)).Scan(&remember); err != nil {
+		t.Fatal(err)
+	}
+	if remember {
+		t.Error("expected remember-me to be ignored when disabled")
+	}
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Login challenge headers. When a challenge is required the login response
+// carries ChallengeHeader and ChallengeDifficultyHeader; the client retries
+// with ChallengeResponseHeader set to "<challenge>:<nonce>" where
+// SHA-256("<challenge>:<nonce>") starts with at least difficulty zero bits.
+const (
+	ChallengeHeader           = "X-Snipo-Challenge"
+	ChallengeDifficultyHeader = "X-Snipo-Challenge-Difficulty"
+	ChallengeResponseHeader   = "X-Snipo-Challenge-Response"
+)
+
+const (
+	challengeTTL    = 5 * time.Minute  // How long an issued challenge can be solved
+	challengeWindow = 15 * time.Minute // Failures older than this no longer count
+)
+
+// loginChallenge decides when logins need proof of work and verifies
+// solutions. Failures are counted across all clients, so guessing spread over
+// many addresses still triggers it, while legitimate users sharing an address
+// only pay a short computation instead of being locked out.
+type loginChallenge struct {
+	after      int
+	difficulty int
+	secret     []byte
+
+	mu       sync.Mutex
+	failures []time.Time          // The most recent failures, at most after of them
+	used     map[string]time.Time // Solved challenges, kept until they expire
+}
+
+func newLoginChallenge(after, difficulty int, secret string) *loginChallenge {
+	return &loginChallenge{
+		after:      after,
+		difficulty: difficulty,
+		secret:     []byte("snipo-login-challenge-v1:" + secret),
+		used:       make(map[string]time.Time),
+	}
+}
+
+func (c *loginChallenge) recordFailure() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures = append(c.failures, time.Now())
+	if len(c.failures) > c.after {
+		c.failures = c.failures[len(c.failures)-c.after:]
+	}
+}
+
+// required reports whether after failures happened within the window
+func (c *loginChallenge) required() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.failures) >= c.after && time.Since(c.failures[0]) < challengeWindow
+}
+
+// issue returns a signed challenge of the form "<unix time>.<random>.<mac>"
+func (c *loginChallenge) issue() (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	payload := strconv.FormatInt(time.Now().Unix(), 10) + "." + hex.EncodeToString(random)
+	return payload + "." + c.sign(payload), nil
+}
+
+func (c *loginChallenge) sign(payload string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// verify checks a "<challenge>:<nonce>" response. Each challenge is accepted once.
+func (c *loginChallenge) verify(response string) bool {
+	challenge, nonce, ok := strings.Cut(response, ":")
+	if !ok || nonce == "" || len(nonce) > 64 {
+		return false
+	}
+
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 3 || !hmac.Equal([]byte(parts[2]), []byte(c.sign(parts[0]+"."+parts[1]))) {
+		return false
+	}
+	issuedUnix, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return false
+	}
+	issued := time.Unix(issuedUnix, 0)
+	if time.Since(issued) > challengeTTL || time.Until(issued) > time.Minute {
+		return false
+	}
+
+	if leadingZeroBits(sha256.Sum256([]byte(response))) < c.difficulty {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for used, expires := range c.used {
+		if now.After(expires) {
+			delete(c.used, used)
+		}
+	}
+	if _, seen := c.used[challenge]; seen {
+		return false
+	}
+	c.used[challenge] = issued.Add(challengeTTL)
+	return true
+}
+
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+// WithLoginChallenge requires a proof-of-work challenge on every login once
+// after failed attempts have happened within 15 minutes, from any client.
+// Zero after disables the challenge.
+func (s *Service) WithLoginChallenge(after, difficulty int) *Service {
+	if after > 0 {
+		s.challenge = newLoginChallenge(after, difficulty, s.sessionSecret)
+	}
+	return s
+}
+
+// ChallengeRequired reports whether logins currently need a solved challenge
+func (s *Service) ChallengeRequired() bool {
+	return s.challenge != nil && s.challenge.required()
+}
+
+// IssueChallenge returns a new challenge and the number of leading zero bits
+// its solution must have
+func (s *Service) IssueChallenge() (string, int, error) {
+	if s.challenge == nil {
+		return "", 0, fmt.Errorf("login challenge is not enabled")
+	}
+	challenge, err := s.challenge.issue()
+	return challenge, s.challenge.difficulty, err
+}
+
+// VerifyChallenge checks a solved challenge from ChallengeResponseHeader
+func (s *Service) VerifyChallenge(response string) bool {
+	return s.challenge != nil && s.challenge.verify(response)
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// solve brute-forces a nonce the way the web UI does
+func solve(t *testing.T, challenge string, difficulty int) string {
+	t.Helper()
+	for nonce := 0; nonce < 1<<24; nonce++ {
+		response := challenge + ":" + strconv.Itoa(nonce)
+		if leadingZeroBits(sha256.Sum256([]byte(response))) >= difficulty {
+			return response
+		}
+	}
+	t.Fatal("no solution found")
+	return ""
+}
+
+func TestLoginChallenge(t *testing.T) {
+	s := NewService(nil, "correct-password", "test-secret", time.Hour, slog.Default(), false).
+		WithLoginChallenge(3, 8)
+
+	// Failures from different addresses all count towards the threshold
+	for i, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		if s.ChallengeRequired() {
+			t.Fatalf("challenge required after %d failures", i)
+		}
+		s.VerifyPasswordWithDelay("wrong", ip)
+	}
+	if s.ChallengeRequired() {
+		t.Fatal("challenge required before the threshold")
+	}
+	s.VerifyPasswordWithDelay("wrong", "10.0.0.3")
+	if !s.ChallengeRequired() {
+		t.Fatal("expected challenge after 3 failures")
+	}
+
+	challenge, difficulty, err := s.IssueChallenge()
+	if err != nil {
+		t.Fatalf("IssueChallenge failed: %v", err)
+	}
+	if difficulty != 8 {
+		t.Errorf("expected difficulty 8, got %d", difficulty)
+	}
+
+	t.Run("rejects unsolved and forged responses", func(t *testing.T) {
+		for _, response := range []string{"", challenge, challenge + ":", "1.2.3:4"} {
+			if s.VerifyChallenge(response) {
+				t.Errorf("expected %q to be rejected", response)
+			}
+		}
+
+		// A solution to a challenge with a tampered signature is worthless
+		parts := strings.Split(challenge, ".")
+		forged := parts[0] + "." + strings.Repeat("0", len(parts[1])) + "." + parts[2]
+		if s.VerifyChallenge(solve(t, forged, difficulty)) {
+			t.Error("expected forged challenge to be rejected")
+		}
+	})
+
+	t.Run("accepts a solution once", func(t *testing.T) {
+		response := solve(t, challenge, difficulty)
+		if !s.VerifyChallenge(response) {
+			t.Fatal("expected solved challenge to be accepted")
+		}
+		if s.VerifyChallenge(response) {
+			t.Error("expected a replayed solution to be rejected")
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		plain := NewService(nil, "correct-password", "test-secret", time.Hour, slog.Default(), false)
+		for i := 0; i < 10; i++ {
+			plain.VerifyPasswordWithDelay("wrong", "10.0.0."+strconv.Itoa(i))
+		}
+		if plain.ChallengeRequired() {
+			t.Error("expected no challenge when disabled")
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sessionCookieName is the cookie holding the signed session token
+const sessionCookieName = "snipo_session"
+
+// cookieSigner signs session tokens in cookies with the session secret. After
+// the secret changes, cookies signed with the previous secret (and unsigned
+// cookies from before signing existed) keep working until the grace window
+// ends, and are re-signed with the new secret as they are used.
+type cookieSigner struct {
+	current       []byte
+	previous      []byte // nil unless a previous secret was configured
+	previousUntil time.Time
+}
+
+func cookieKey(secret string) []byte {
+	return []byte("snipo-session-cookie-v1:" + secret)
+}
+
+func (c *cookieSigner) mac(key []byte, token string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// seal returns the cookie value for a session token: "<token>.<mac>"
+func (c *cookieSigner) seal(token string) string {
+	return token + "." + c.mac(c.current, token)
+}
+
+// open verifies a cookie value and returns the session token inside it.
+// resign is true when the value was accepted only during the grace window.
+func (c *cookieSigner) open(value string) (token string, resign bool, ok bool) {
+	token, mac, signed := cutLast(value, ".")
+	inGrace := time.Now().Before(c.previousUntil)
+	if !signed {
+		return value, true, inGrace
+	}
+	if hmac.Equal([]byte(mac), []byte(c.mac(c.current, token))) {
+		return token, false, true
+	}
+	if c.previous != nil && inGrace && hmac.Equal([]byte(mac), []byte(c.mac(c.previous, token))) {
+		return token, true, true
+	}
+	return "", false, false
+}
+
+// cutLast slices s around the last instance of sep. Session tokens are
+// base64url encoded and never contain a dot, the signature follows it.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// WithSignedCookies signs session cookies with the session secret. Cookies
+// signed with previousSecret, and unsigned cookies issued before signing was
+// enabled, are still accepted for grace after startup so rotating
+// SNIPO_SESSION_SECRET doesn't log every device out at once.
+func (s *Service) WithSignedCookies(previousSecret string, grace time.Duration) *Service {
+	s.cookies = &cookieSigner{
+		current:       cookieKey(s.sessionSecret),
+		previousUntil: time.Now().Add(grace),
+	}
+	if previousSecret != "" && previousSecret != s.sessionSecret {
+		s.cookies.previous = cookieKey(previousSecret)
+		s.logger.Info("accepting sessions signed with the previous session secret", "until", s.cookies.previousUntil)
+	}
+	return s
+}
+
+// sealSessionToken turns a session token into its cookie value
+func (s *Service) sealSessionToken(token string) string {
+	if s.cookies == nil {
+		return token
+	}
+	return s.cookies.seal(token)
+}
+
+// openSessionToken extracts the session token from a cookie value. Without
+// signing enabled any signature is ignored, as the token alone identifies the
+// session.
+func (s *Service) openSessionToken(value string) (token string, resign bool, ok bool) {
+	if s.cookies == nil {
+		token, _, _ = cutLast(value, ".")
+		return token, false, true
+	}
+	return s.cookies.open(value)
+}
+
+// ResignSessionCookie re-signs the request's session cookie with the current
+// secret if it was only accepted because of the rotation grace window. Call it
+// after the session has been validated.
+func (s *Service) ResignSessionCookie(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil || s.cookies == nil {
+		return
+	}
+	token, resign, ok := s.openSessionToken(cookie.Value)
+	if !ok || !resign {
+		return
+	}
+
+	// Keep the cookie lifetime the session already had
+	var expiresAt time.Time
+	var remember bool
+	var maxExpiresAt sql.NullTime
+	err = s.db.QueryRow(
+		"SELECT expires_at, remember, max_expires_at FROM sessions WHERE token_hash = ?",
+		hashToken(token),
+	).Scan(&expiresAt, &remember, &maxExpiresAt)
+	if err != nil {
+		return
+	}
+	if remember && maxExpiresAt.Valid {
+		expiresAt = maxExpiresAt.Time
+	}
+
+	s.writeSessionCookie(w, s.sealSessionToken(token), time.Until(expiresAt))
+}
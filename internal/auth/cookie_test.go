@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestSignedSessionCookies(t *testing.T) {
+	db := testutil.TestDB(t)
+	oldService := NewService(db, "correct-password", "old-secret", time.Hour, testutil.TestLogger(), false).
+		WithSignedCookies("", time.Hour)
+
+	oldValue, err := oldService.CreateSession(false, "")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if !strings.Contains(oldValue, ".") {
+		t.Fatalf("expected a signed cookie value, got %q", oldValue)
+	}
+	token, _, _ := cutLast(oldValue, ".")
+
+	// A forged signature is rejected
+	if oldService.ValidateSession(token + ".forged") {
+		t.Error("expected forged signature to be rejected")
+	}
+
+	// After rotation, the old signature works during the grace window and
+	// the cookie is re-signed with the new secret
+	rotated := NewService(db, "correct-password", "new-secret", time.Hour, testutil.TestLogger(), false).
+		WithSignedCookies("old-secret", time.Hour)
+	if !rotated.ValidateSession(oldValue) {
+		t.Fatal("expected cookie signed with the previous secret to be accepted")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: oldValue})
+	rec := httptest.NewRecorder()
+	rotated.ResignSessionCookie(rec, req)
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected the cookie to be re-signed, got %d cookies", len(cookies))
+	}
+	newValue := cookies[0].Value
+	if newValue == oldValue || !strings.HasPrefix(newValue, token+".") {
+		t.Fatalf("unexpected re-signed value %q", newValue)
+	}
+	if cookies[0].MaxAge <= 0 || cookies[0].MaxAge > int(time.Hour.Seconds()) {
+		t.Errorf("MaxAge = %d, want the session's remaining lifetime", cookies[0].MaxAge)
+	}
+
+	// A current signature needs no re-signing
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: newValue})
+	rec = httptest.NewRecorder()
+	rotated.ResignSessionCookie(rec, req)
+	if len(rec.Result().Cookies()) != 0 {
+		t.Error("expected a current cookie to be left alone")
+	}
+
+	// Once the grace window is over only the new secret is accepted
+	expired := NewService(db, "correct-password", "new-secret", time.Hour, testutil.TestLogger(), false).
+		WithSignedCookies("old-secret", 0)
+	if expired.ValidateSession(oldValue) {
+		t.Error("expected previous signature to be rejected after the grace window")
+	}
+	if expired.ValidateSession(token) {
+		t.Error("expected unsigned cookie to be rejected after the grace window")
+	}
+	if !expired.ValidateSession(newValue) {
+		t.Error("expected cookie signed with the current secret to be accepted")
+	}
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// insightsWindow is how long failed logins are kept for AccessInsights
+const insightsWindow = 30 * 24 * time.Hour
+
+// unknownCountry groups clients the GeoIP database can't place, and all
+// clients when no database is configured
+const unknownCountry = "unknown"
+
+// CountryLookup resolves a client IP to an ISO country code.
+// geoip.Reader implements it.
+type CountryLookup interface {
+	Country(ip string) (string, error)
+}
+
+// AccessInsights summarizes where sessions and failed logins come from
+type AccessInsights struct {
+	GeoIPEnabled          bool           `json:"geoip_enabled"`
+	ActiveSessions        int            `json:"active_sessions"`
+	SessionsByCountry     map[string]int `json:"sessions_by_country"`
+	FailedLogins          int            `json:"failed_logins"`
+	FailedLoginsByCountry map[string]int `json:"failed_logins_by_country"`
+	Since                 time.Time      `json:"since"` // Failed logins are counted from here
+}
+
+// WithGeoIP records the country of new sessions and failed logins. Only the
+// country is stored, never the address.
+func (s *Service) WithGeoIP(lookup CountryLookup) *Service {
+	s.geoip = lookup
+	return s
+}
+
+// country returns the country of clientIP, or NULL if it can't be resolved
+func (s *Service) country(clientIP string) sql.NullString {
+	if s.geoip == nil || clientIP == "" {
+		return sql.NullString{}
+	}
+	code, err := s.geoip.Country(clientIP)
+	if err != nil {
+		s.logger.Debug("GeoIP lookup failed", "error", err)
+		return sql.NullString{}
+	}
+	return sql.NullString{String: code, Valid: code != ""}
+}
+
+func (s *Service) recordFailedLogin(clientIP string) {
+	if s.db == nil {
+		return
+	}
+	if _, err := s.db.Exec("INSERT INTO login_failures (country) VALUES (?)", s.country(clientIP)); err != nil {
+		s.logger.Warn("failed to record failed login", "error", err)
+	}
+}
+
+// AccessInsights counts active sessions and the failed logins of the last 30
+// days by country
+func (s *Service) AccessInsights() (*AccessInsights, error) {
+	now := time.Now()
+	insights := &AccessInsights{
+		GeoIPEnabled: s.geoip != nil,
+		Since:        now.Add(-insightsWindow).UTC(),
+	}
+
+	var err error
+	insights.SessionsByCountry, insights.ActiveSessions, err = s.countByCountry(
+		"SELECT country, COUNT(*) FROM sessions WHERE expires_at > ? GROUP BY country", now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count sessions: %w", err)
+	}
+	insights.FailedLoginsByCountry, insights.FailedLogins, err = s.countByCountry(
+		"SELECT country, COUNT(*) FROM login_failures WHERE created_at > ? GROUP BY country", insights.Since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count failed logins: %w", err)
+	}
+	return insights, nil
+}
+
+// ActiveSessions counts sessions that haven't expired
+func (s *Service) ActiveSessions() (int, error) {
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM sessions WHERE expires_at > ?", time.Now()).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count sessions: %w", err)
+	}
+	return count, nil
+}
+
+func (s *Service) countByCountry(query string, since time.Time) (map[string]int, int, error) {
+	rows, err := s.db.Query(query, since)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	counts := make(map[string]int)
+	total := 0
+	for rows.Next() {
+		var country sql.NullString
+		var n int
+		if err := rows.Scan(&country, &n); err != nil {
+			return nil, 0, err
+		}
+		key := country.String
+		if !country.Valid || key == "" {
+			key = unknownCountry
+		}
+		counts[key] += n
+		total += n
+	}
+	return counts, total, rows.Err()
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+// staticCountries resolves IPs from a fixed table
+type staticCountries map[string]string
+
+func (c staticCountries) Country(ip string) (string, error) {
+	return c[ip], nil
+}
+
+func TestAccessInsights(t *testing.T) {
+	db := testutil.TestDB(t)
+	s := NewService(db, "correct-password", "test-secret", time.Hour, testutil.TestLogger(), false).
+		WithGeoIP(staticCountries{"81.2.69.142": "GB", "89.160.20.112": "SE"})
+
+	for _, ip := range []string{"81.2.69.142", "81.2.69.142", "10.0.0.1"} {
+		if _, err := s.CreateSession(false, ip); err != nil {
+			t.Fatalf("CreateSession failed: %v", err)
+		}
+	}
+	s.VerifyPasswordWithDelay("wrong", "89.160.20.112")
+	s.VerifyPasswordWithDelay("correct-password", "81.2.69.142")
+
+	insights, err := s.AccessInsights()
+	if err != nil {
+		t.Fatalf("AccessInsights failed: %v", err)
+	}
+	if !insights.GeoIPEnabled || insights.ActiveSessions != 3 || insights.FailedLogins != 1 {
+		t.Errorf("unexpected totals: %+v", insights)
+	}
+	if insights.SessionsByCountry["GB"] != 2 || insights.SessionsByCountry[unknownCountry] != 1 {
+		t.Errorf("unexpected sessions by country: %v", insights.SessionsByCountry)
+	}
+	if insights.FailedLoginsByCountry["SE"] != 1 {
+		t.Errorf("unexpected failed logins by country: %v", insights.FailedLoginsByCountry)
+	}
+
+	var stored int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sessions WHERE country = 'GB'").Scan(&stored); err != nil || stored != 2 {
+		t.Errorf("expected 2 sessions stored with a country, got %d (%v)", stored, err)
+	}
+}
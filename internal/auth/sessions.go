@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSessionNotFound is returned when revoking a session that doesn't exist
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session describes an active login session. The token itself is never
+// exposed, only the session's ID.
+type Session struct {
+	ID           string     `json:"id"`
+	UserID       int64      `json:"user_id"`
+	Remember     bool       `json:"remember"`
+	Country      string     `json:"country,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	MaxExpiresAt *time.Time `json:"max_expires_at,omitempty"` // Remembered sessions only
+	Current      bool       `json:"current"`
+}
+
+// ListSessions returns userID's sessions that haven't expired, newest first.
+// current is the caller's session cookie value, used to mark their own
+// session; it may be empty.
+func (s *Service) ListSessions(userID int64, current string) ([]Session, error) {
+	currentHash := s.sessionHash(current)
+	now := time.Now()
+
+	rows, err := s.db.Query(`
+		SELECT id, token_hash, user_id, remember, country, created_at, expires_at, max_expires_at
+		FROM sessions
+		WHERE user_id = ? AND expires_at > ? AND (max_expires_at IS NULL OR max_expires_at > ?)
+		ORDER BY created_at DESC, id ASC
+	`, userID, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	sessions := make([]Session, 0)
+	for rows.Next() {
+		var session Session
+		var tokenHash string
+		var country sql.NullString
+		var maxExpiresAt sql.NullTime
+		if err := rows.Scan(&session.ID, &tokenHash, &session.UserID, &session.Remember, &country,
+			&session.CreatedAt, &session.ExpiresAt, &maxExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		session.Country = country.String
+		if maxExpiresAt.Valid {
+			session.MaxExpiresAt = &maxExpiresAt.Time
+		}
+		session.Current = currentHash != "" && tokenHash == currentHash
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// RevokeSession deletes userID's session with the given ID
+func (s *Service) RevokeSession(userID int64, id string) error {
+	result, err := s.db.Exec("DELETE FROM sessions WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrSessionNotFound
+	}
+
+	s.logger.Info("session revoked", "session_id", id)
+	return nil
+}
+
+// RevokeOtherSessions deletes every session of userID except the one whose
+// cookie value is current, and returns how many were deleted. With no
+// current session, as for API token requests, all of userID's sessions are
+// revoked.
+func (s *Service) RevokeOtherSessions(userID int64, current string) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM sessions WHERE user_id = ? AND token_hash != ?", userID, s.sessionHash(current))
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	s.logger.Info("other sessions revoked", "user_id", userID, "count", rows)
+	return rows, nil
+}
+
+// sessionHash returns the stored hash of the session a cookie value
+// carries, or "" if there is none
+func (s *Service) sessionHash(value string) string {
+	if value == "" {
+		return ""
+	}
+	token, _, ok := s.openSessionToken(value)
+	if !ok {
+		return ""
+	}
+	return hashToken(token)
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestSessionManagement(t *testing.T) {
+	db := testutil.TestDB(t)
+	s := NewService(db, "correct-password", "test-secret", time.Hour, testutil.TestLogger(), false).
+		WithSignedCookies("", 0)
+
+	current, err := s.CreateSession(false, "")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	var others []string
+	for range 2 {
+		token, err := s.CreateSession(false, "")
+		if err != nil {
+			t.Fatalf("CreateSession failed: %v", err)
+		}
+		others = append(others, token)
+	}
+	expired, err := s.CreateSession(false, "")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if _, err := db.Exec("UPDATE sessions SET expires_at = ? WHERE token_hash = ?", time.Now().Add(-time.Minute), s.sessionHash(expired)); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := s.ListSessions(models.OwnerUserID, current)
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(sessions) != 3 {
+		t.Fatalf("expected 3 active sessions, got %d", len(sessions))
+	}
+	var currentID, otherID string
+	for _, session := range sessions {
+		if session.ExpiresAt.Before(session.CreatedAt) {
+			t.Errorf("session %s expires before it was created", session.ID)
+		}
+		if session.Current {
+			currentID = session.ID
+		} else {
+			otherID = session.ID
+		}
+	}
+	if currentID == "" {
+		t.Fatal("expected the caller's session to be marked current")
+	}
+
+	// Revoking one session logs it out
+	if err := s.RevokeSession(models.OwnerUserID, otherID); err != nil {
+		t.Fatalf("RevokeSession failed: %v", err)
+	}
+	if err := s.RevokeSession(models.OwnerUserID, otherID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound revoking twice, got %v", err)
+	}
+
+	// Revoking the others keeps only the caller's session
+	revoked, err := s.RevokeOtherSessions(models.OwnerUserID, current)
+	if err != nil {
+		t.Fatalf("RevokeOtherSessions failed: %v", err)
+	}
+	if revoked != 2 {
+		t.Errorf("expected the other and the expired session to be revoked, got %d", revoked)
+	}
+	if !s.ValidateSession(current) {
+		t.Error("expected the current session to survive")
+	}
+	for _, token := range others {
+		if s.ValidateSession(token) {
+			t.Error("expected other sessions to be revoked")
+		}
+	}
+
+	// Without a current session everything goes
+	if revoked, err := s.RevokeOtherSessions(models.OwnerUserID, ""); err != nil || revoked != 1 {
+		t.Errorf("expected the last session to be revoked, got %d, %v", revoked, err)
+	}
+}
+
+func TestSessionManagementPerUser(t *testing.T) {
+	db := testutil.TestDB(t)
+	s := NewService(db, "correct-password", "test-secret", time.Hour, testutil.TestLogger(), false).
+		WithSignedCookies("", 0)
+
+	result, err := db.Exec("INSERT INTO users (username, password_hash) VALUES ('bob', 'hash')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, _ := result.LastInsertId()
+
+	owner, err := s.CreateSession(false, "")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	bobSessions := make([]string, 2)
+	for i := range bobSessions {
+		if bobSessions[i], err = s.CreateUserSession(bob, false, ""); err != nil {
+			t.Fatalf("CreateUserSession failed: %v", err)
+		}
+	}
+
+	// Users only see their own sessions
+	sessions, err := s.ListSessions(models.OwnerUserID, owner)
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(sessions) != 1 || !sessions[0].Current {
+		t.Fatalf("expected only the owner's session, got %+v", sessions)
+	}
+	bobList, err := s.ListSessions(bob, bobSessions[0])
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(bobList) != 2 {
+		t.Fatalf("expected bob's 2 sessions, got %d", len(bobList))
+	}
+
+	// Nor revoke them
+	if err := s.RevokeSession(models.OwnerUserID, bobList[0].ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound revoking another user's session, got %v", err)
+	}
+	if revoked, err := s.RevokeOtherSessions(models.OwnerUserID, owner); err != nil || revoked != 0 {
+		t.Errorf("expected no sessions revoked, got %d, %v", revoked, err)
+	}
+	for _, token := range bobSessions {
+		if !s.ValidateSession(token) {
+			t.Error("expected bob's sessions to survive the owner revoking theirs")
+		}
+	}
+
+	if revoked, err := s.RevokeOtherSessions(bob, bobSessions[0]); err != nil || revoked != 1 {
+		t.Errorf("expected bob's other session to be revoked, got %d, %v", revoked, err)
+	}
+	if !s.ValidateSession(owner) {
+		t.Error("expected the owner's session to survive bob revoking theirs")
+	}
+}
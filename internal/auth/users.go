@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// WithMultiUser lets accounts from the users table log in alongside the
+// owner, who keeps using the master password
+func (s *Service) WithMultiUser(enabled bool) *Service {
+	s.multiUser = enabled
+	return s
+}
+
+// MultiUserEnabled reports whether accounts other than the owner can log in
+func (s *Service) MultiUserEnabled() bool {
+	return s.multiUser
+}
+
+// dummyPasswordHash is checked for usernames that don't exist, so they take
+// as long to refuse as a wrong password and can't be told apart by timing.
+// No password hashes to all zeros.
+const dummyPasswordHash = "$argon2id$AAAAAAAAAAAAAAAAAAAAAA$AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+
+// VerifyUserWithDelay checks a username and password with the same delays
+// as VerifyPasswordWithDelay and returns the user they belong to. An empty
+// username is the owner logging in with the master password.
+func (s *Service) VerifyUserWithDelay(username, password, clientIP string) (int64, bool, time.Duration) {
+	if username == "" || !s.multiUser {
+		valid, delay := s.VerifyPasswordWithDelay(password, clientIP)
+		return models.OwnerUserID, valid, delay
+	}
+
+	if delay := s.failedAttempts.GetDelay(clientIP); delay > 0 {
+		return 0, false, delay
+	}
+
+	var id int64
+	var passwordHash string
+	err := s.db.QueryRow("SELECT id, password_hash FROM users WHERE username = ?", username).Scan(&id, &passwordHash)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			s.logger.Error("failed to look up user", "error", err)
+		}
+		passwordHash = dummyPasswordHash
+	}
+	if VerifyPasswordHash(password, passwordHash) && err == nil {
+		s.failedAttempts.RecordSuccess(clientIP)
+		return id, true, 0
+	}
+
+	s.loginFailed(clientIP)
+	return 0, false, 0
+}
+
+// IsAdmin reports whether a user may use admin routes. The owner always may.
+func (s *Service) IsAdmin(userID int64) bool {
+	if userID == models.OwnerUserID {
+		return true
+	}
+	var isAdmin bool
+	if err := s.db.QueryRow("SELECT is_admin FROM users WHERE id = ?", userID).Scan(&isAdmin); err != nil {
+		return false
+	}
+	return isAdmin
+}
+
+// VerifyUserPassword checks a user's password. The owner's is the master
+// password.
+func (s *Service) VerifyUserPassword(userID int64, password string) bool {
+	if userID == models.OwnerUserID {
+		return s.VerifyPassword(password)
+	}
+	var passwordHash string
+	if err := s.db.QueryRow("SELECT password_hash FROM users WHERE id = ?", userID).Scan(&passwordHash); err != nil {
+		return false
+	}
+	return VerifyPasswordHash(password, passwordHash)
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestUserLogin(t *testing.T) {
+	db := testutil.TestDB(t)
+	s := NewService(db, "master-password", "test-secret", time.Hour, testutil.TestLogger(), false).
+		WithMultiUser(true)
+
+	hash, err := HashPassword("alice-password")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	var aliceID int64
+	if err := db.QueryRow("INSERT INTO users (username, password_hash) VALUES ('alice', ?) RETURNING id", hash).Scan(&aliceID); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	if id, ok, _ := s.VerifyUserWithDelay("", "master-password", "10.0.0.1"); !ok || id != models.OwnerUserID {
+		t.Errorf("expected the master password to log in as the owner, got %d, %v", id, ok)
+	}
+	if id, ok, _ := s.VerifyUserWithDelay("Alice", "alice-password", "10.0.0.1"); !ok || id != aliceID {
+		t.Errorf("expected alice to log in, got %d, %v", id, ok)
+	}
+	if _, ok, _ := s.VerifyUserWithDelay("alice", "master-password", "10.0.0.2"); ok {
+		t.Error("expected the master password not to work for alice")
+	}
+	if _, ok, _ := s.VerifyUserWithDelay("bob", "alice-password", "10.0.0.3"); ok {
+		t.Error("expected an unknown user to be rejected")
+	}
+
+	token, err := s.CreateUserSession(aliceID, false, "")
+	if err != nil {
+		t.Fatalf("CreateUserSession failed: %v", err)
+	}
+	if id, ok := s.SessionUser(token); !ok || id != aliceID {
+		t.Errorf("expected the session to belong to alice, got %d, %v", id, ok)
+	}
+
+	if !s.IsAdmin(models.OwnerUserID) {
+		t.Error("expected the owner to be an admin")
+	}
+	if s.IsAdmin(aliceID) {
+		t.Error("expected alice not to be an admin")
+	}
+}
+
+func TestUserLoginTiming(t *testing.T) {
+	db := testutil.TestDB(t)
+	s := NewService(db, "master-password", "test-secret", time.Hour, testutil.TestLogger(), false).
+		WithMultiUser(true)
+
+	hash, err := HashPassword("alice-password")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO users (username, password_hash) VALUES ('alice', ?)", hash); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	// Unknown usernames pay for a password hash like wrong passwords do, so
+	// response times don't reveal which accounts exist
+	attempt := 0
+	fastest := func(username string) time.Duration {
+		best := time.Duration(1<<63 - 1)
+		for range 3 {
+			attempt++
+			start := time.Now()
+			if _, ok, _ := s.VerifyUserWithDelay(username, "wrong-password", fmt.Sprintf("10.0.1.%d", attempt)); ok {
+				t.Fatalf("expected %q to be rejected", username)
+			}
+			best = min(best, time.Since(start))
+		}
+		return best
+	}
+	known, unknown := fastest("alice"), fastest("nobody")
+	if unknown < known/2 {
+		t.Errorf("expected an unknown user to take about as long as a wrong password, got %v and %v", unknown, known)
+	}
+}
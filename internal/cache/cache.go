@@ -0,0 +1,99 @@
+// Package cache provides a small in-memory read-through cache. Concurrent
+// misses for the same key share one load, so a burst of identical requests
+// costs a single database read.
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache holds values for a fixed time after they are loaded. It is safe for
+// concurrent use. Values are shared between callers and must not be modified.
+type Cache[V any] struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu         sync.Mutex
+	entries    map[string]entry[V]
+	generation uint64 // Bumped by Clear, so loads started before it aren't stored
+	group      *singleflight.Group
+
+	hits  atomic.Int64
+	loads atomic.Int64
+}
+
+type entry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// New creates a cache keeping values for ttl and at most maxEntries of them
+func New[V any](ttl time.Duration, maxEntries int) *Cache[V] {
+	return &Cache[V]{ttl: ttl, maxEntries: maxEntries, entries: make(map[string]entry[V]), group: &singleflight.Group{}}
+}
+
+// Get returns the cached value for key, or calls load to get it. Only one
+// load runs per key at a time; other callers wait for its result. Errors
+// are returned to every waiting caller but not cached.
+func (c *Cache[V]) Get(key string, load func() (V, error)) (V, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && time.Now().Before(e.expires) {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return e.value, nil
+	}
+	generation, group := c.generation, c.group
+	c.mu.Unlock()
+
+	value, err, _ := group.Do(key, func() (any, error) {
+		c.loads.Add(1)
+		value, err := load()
+		if err != nil {
+			return value, err
+		}
+		c.store(key, value, generation)
+		return value, nil
+	})
+	v, _ := value.(V)
+	return v, err
+}
+
+func (c *Cache[V]) store(key string, value V, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	now := time.Now()
+	if len(c.entries) >= c.maxEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= c.maxEntries {
+			clear(c.entries)
+		}
+	}
+	c.entries[key] = entry[V]{value: value, expires: now.Add(c.ttl)}
+}
+
+// Clear drops every value, and keeps loads already running from storing
+// what may be a stale value
+func (c *Cache[V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	clear(c.entries)
+	c.group = &singleflight.Group{} // New callers don't wait for loads that started before
+}
+
+// Stats returns how many lookups were answered from the cache and how many
+// loads ran; callers that waited for another's load count as neither
+func (c *Cache[V]) Stats() (hits, loads int64) {
+	return c.hits.Load(), c.loads.Load()
+}
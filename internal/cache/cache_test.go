@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_CoalescesLoads(t *testing.T) {
+	c := New[string](time.Minute, 10)
+	var loads atomic.Int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := c.Get("a", func() (string, error) {
+				loads.Add(1)
+				<-release
+				return "value", nil
+			})
+			if err != nil || value != "value" {
+				t.Errorf("expected value, got %q, %v", value, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("expected 1 load, got %d", n)
+	}
+	if value, _ := c.Get("a", func() (string, error) { return "reloaded", nil }); value != "value" {
+		t.Errorf("expected the cached value, got %q", value)
+	}
+}
+
+func TestCache_ExpiryAndClear(t *testing.T) {
+	c := New[int](20*time.Millisecond, 10)
+	n := 0
+	load := func() (int, error) { n++; return n, nil }
+
+	if v, _ := c.Get("a", load); v != 1 {
+		t.Fatalf("expected 1, got %d", v)
+	}
+	if v, _ := c.Get("a", load); v != 1 {
+		t.Errorf("expected the cached 1, got %d", v)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if v, _ := c.Get("a", load); v != 2 {
+		t.Errorf("expected a reload after expiry, got %d", v)
+	}
+	c.Clear()
+	if v, _ := c.Get("a", load); v != 3 {
+		t.Errorf("expected a reload after Clear, got %d", v)
+	}
+
+	hits, loads := c.Stats()
+	if hits != 1 || loads != 3 {
+		t.Errorf("expected 1 hit and 3 loads, got %d and %d", hits, loads)
+	}
+}
+
+func TestCache_ErrorsAreNotCached(t *testing.T) {
+	c := New[string](time.Minute, 10)
+	if _, err := c.Get("a", func() (string, error) { return "", errors.New("boom") }); err == nil {
+		t.Fatal("expected the load error")
+	}
+	if value, err := c.Get("a", func() (string, error) { return "ok", nil }); err != nil || value != "ok" {
+		t.Errorf("expected a fresh load after an error, got %q, %v", value, err)
+	}
+}
+
+func TestCache_ClearDuringLoad(t *testing.T) {
+	c := New[string](time.Minute, 10)
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = c.Get("a", func() (string, error) {
+			close(started)
+			<-release
+			return "stale", nil
+		})
+	}()
+	<-started
+	c.Clear()
+	close(release)
+	<-done
+
+	if value, _ := c.Get("a", func() (string, error) { return "fresh", nil }); value != "fresh" {
+		t.Errorf("expected a load started before Clear not to be kept, got %q", value)
+	}
+}
+
+func TestCache_MaxEntries(t *testing.T) {
+	c := New[int](time.Minute, 2)
+	for i, key := range []string{"a", "b", "c"} {
+		_, _ = c.Get(key, func() (int, error) { return i, nil })
+	}
+	if len(c.entries) > 2 {
+		t.Errorf("expected at most 2 entries, got %d", len(c.entries))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -13,119 +14,253 @@ import (
 
 // Config holds all application configuration
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Auth     AuthConfig
-	S3       S3Config
-	Logging  LoggingConfig
-	API      APIConfig
-	Features FeatureFlags
-	Demo     DemoConfig
+	Server    ServerConfig       `json:"server"`
+	Database  DatabaseConfig     `json:"database"`
+	Auth      AuthConfig         `json:"auth"`
+	S3        S3Config           `json:"s3"`
+	SFTP      SFTPConfig         `json:"sftp"`
+	Replica   ReplicaConfig      `json:"replica"`
+	Logging   LoggingConfig      `json:"logging"`
+	API       APIConfig          `json:"api"`
+	Features  FeatureFlags       `json:"features"`
+	Demo      DemoConfig         `json:"demo"`
+	Publish   PublishConfig      `json:"publish"`
+	Remote    RemoteConfig       `json:"remote"`
+	GitHub    GitHubConfig       `json:"github"`
+	Metrics   MetricsConfig      `json:"metrics"`
+	Telemetry TelemetryConfig    `json:"telemetry"`
+	Slash     SlashCommandConfig `json:"slash"`
+	Outbound  OutboundConfig     `json:"outbound"`
+	Mail      MailConfig         `json:"mail"`
 }
 
 // ServerConfig holds HTTP server settings
 type ServerConfig struct {
-	Host               string
-	Port               int
-	ReadTimeout        time.Duration
-	WriteTimeout       time.Duration
-	TrustProxy         bool
-	MaxFilesPerSnippet int
-	BasePath           string // Base path for reverse proxy (e.g., "/snipo")
+	Host               string        `json:"host"`
+	Port               int           `json:"port"`
+	ReadTimeout        time.Duration `json:"read_timeout"`
+	WriteTimeout       time.Duration `json:"write_timeout"`
+	TrustProxy         bool          `json:"trust_proxy"`
+	MaxFilesPerSnippet int           `json:"max_files_per_snippet"`
+	BasePath           string        `json:"base_path"` // Base path for reverse proxy (e.g., "/snipo")
+	ThemeDir           string        `json:"theme_dir"` // Directory whose templates/ and static/ override the built-in ones
 }
 
 // DatabaseConfig holds SQLite settings
 type DatabaseConfig struct {
-	Path            string
-	MaxOpenConns    int
-	BusyTimeout     int
-	JournalMode     string
-	SynchronousMode string
-	MMapSize        int64 // Memory-mapped I/O size in bytes
-	CacheSize       int   // Cache size in pages (negative = KB)
+	Path            string `json:"path"`
+	MaxOpenConns    int    `json:"max_conns"`
+	BusyTimeout     int    `json:"busy_timeout"`
+	JournalMode     string `json:"journal"`
+	SynchronousMode string `json:"sync"`
+	MMapSize        int64  `json:"mmap_size"`  // Memory-mapped I/O size in bytes
+	CacheSize       int    `json:"cache_size"` // Cache size in pages (negative = KB)
 }
 
 // AuthConfig holds authentication settings
 type AuthConfig struct {
-	MasterPassword          string
-	MasterPasswordHash      string // Pre-hashed password (Argon2id format)
-	Disabled                bool   // Disable authentication entirely (use with external auth like Authelia)
-	SessionSecret           string
-	SessionSecretGenerated  bool // True if session secret was auto-generated (not recommended for production)
-	SessionDuration         time.Duration
-	RateLimit               int
-	RateLimitWindow         time.Duration
-	EncryptionSalt          string // Salt for backup encryption (PBKDF2)
-	EncryptionSaltGenerated bool   // True if salt was auto-generated
+	MasterPassword          string        `json:"master_password"`
+	MasterPasswordHash      string        `json:"master_password_hash"` // Pre-hashed password (Argon2id format)
+	Disabled                bool          `json:"disabled"`             // Disable authentication entirely (use with external auth like Authelia)
+	SessionSecret           string        `json:"session_secret"`
+	SessionSecretGenerated  bool          `json:"session_secret_generated"` // True if session secret was auto-generated (not recommended for production)
+	SessionDuration         time.Duration `json:"session_duration"`
+	PreviousSessionSecret   string        `json:"session_secret_previous"` // Secret session cookies were signed with before rotation
+	SessionSecretGrace      time.Duration `json:"session_secret_grace"`    // How long after startup cookies signed with the previous secret are accepted
+	RememberMeDuration      time.Duration `json:"remember_me_duration"`    // Idle timeout of remember-me sessions, 0 to disable remember-me
+	SessionMaxLifetime      time.Duration `json:"session_max_lifetime"`    // Absolute lifetime of remember-me sessions
+	RateLimit               int           `json:"rate_limit"`
+	RateLimitWindow         time.Duration `json:"rate_window"`
+	ChallengeAfter          int           `json:"login_challenge_after"`      // Failed logins (from any client) before a proof-of-work challenge is required, 0 to disable
+	ChallengeDifficulty     int           `json:"login_challenge_difficulty"` // Leading zero bits a challenge solution needs
+	EncryptionSalt          string        `json:"encryption_salt"`            // Salt for backup encryption (PBKDF2)
+	EncryptionSaltGenerated bool          `json:"encryption_salt_generated"`  // True if salt was auto-generated
+	GeoIPDatabase           string        `json:"geoip_db"`                   // Path to a MaxMind DB country database, empty to disable GeoIP
+	MultiUser               bool          `json:"multi_user"`                 // Accounts besides the owner, each seeing only their own snippets
+	WorkspaceDomain         string        `json:"workspace_domain"`           // Parent domain whose subdomains select a workspace, empty to disable
 }
 
 // S3Config holds S3 storage settings
 type S3Config struct {
-	Enabled         bool
-	Endpoint        string
-	AccessKeyID     string
-	SecretAccessKey string
-	Bucket          string
-	Region          string
-	UseSSL          bool
+	Enabled         bool              `json:"enabled"`
+	Endpoint        string            `json:"endpoint"`
+	AccessKeyID     string            `json:"access_key"`
+	SecretAccessKey string            `json:"secret_key"`
+	Bucket          string            `json:"bucket"`
+	Region          string            `json:"region"`
+	UseSSL          bool              `json:"ssl"`
+	Encrypt         bool              `json:"encrypt"`       // Encrypt backups client-side before uploading
+	StorageClass    string            `json:"storage_class"` // Storage class of uploaded backups, empty for the bucket default
+	Tags            map[string]string `json:"tags"`          // Object tags of uploaded backups, for bucket lifecycle rules
+}
+
+// SFTPConfig holds SFTP storage settings, the backup target used instead
+// of S3 when S3 is disabled
+type SFTPConfig struct {
+	Enabled              bool   `json:"enabled"`
+	Host                 string `json:"host"` // host or host:port, port 22 by default
+	User                 string `json:"user"`
+	Password             string `json:"password"`
+	PrivateKey           string `json:"private_key"` // PEM or OpenSSH private key
+	PrivateKeyPassphrase string `json:"private_key_passphrase"`
+	HostKey              string `json:"host_key"` // Pinned host key, as ssh-keyscan prints it or a SHA256: fingerprint
+	Path                 string `json:"path"`     // Directory backups go in, relative to the login directory unless absolute
+	Encrypt              bool   `json:"encrypt"`  // Encrypt backups client-side before uploading
+}
+
+// ReplicaConfig holds continuous replication settings. The WAL is shipped to
+// the S3 or SFTP backup storage.
+type ReplicaConfig struct {
+	Enabled          bool          `json:"enabled"`
+	Interval         time.Duration `json:"interval"`          // How often committed transactions are shipped
+	SnapshotInterval time.Duration `json:"snapshot_interval"` // How often a new snapshot of the database is taken
+	Retention        time.Duration `json:"retention"`         // How far back the database can be restored
 }
 
 // LoggingConfig holds logging settings
 type LoggingConfig struct {
-	Level  string
-	Format string
+	Level  string `json:"level"`
+	Format string `json:"format"`
 }
 
 // APIConfig holds API-specific settings
 type APIConfig struct {
-	AllowedOrigins []string // CORS allowed origins
-	RateLimitRead  int      // requests per hour for read operations
-	RateLimitWrite int      // requests per hour for write operations
-	RateLimitAdmin int      // requests per hour for admin operations
+	AllowedOrigins []string      `json:"allowed_origins"`   // CORS allowed origins
+	RateLimitRead  int           `json:"rate_limit_read"`   // requests per hour for read operations
+	RateLimitWrite int           `json:"rate_limit_write"`  // requests per hour for write operations
+	RateLimitAdmin int           `json:"rate_limit_admin"`  // requests per hour for admin operations
+	PublicCacheTTL time.Duration `json:"public_cache_ttl"`  // How long public snippets are cached in memory, 0 to disable
+	ShareLinksByID bool          `json:"share_links_by_id"` // Also accept snippet IDs in share links, as used before public tokens
 }
 
 // FeatureFlags holds feature toggle settings
 type FeatureFlags struct {
-	PublicSnippets bool
-	S3Sync         bool
-	APITokens      bool
-	BackupRestore  bool
+	PublicSnippets bool `json:"public_snippets"`
+	S3Sync         bool `json:"s3_sync"`
+	APITokens      bool `json:"api_tokens"`
+	BackupRestore  bool `json:"backup_restore"`
+}
+
+// Map returns the flags keyed by the names clients see in /health
+func (f FeatureFlags) Map() map[string]bool {
+	return map[string]bool{
+		"public_snippets": f.PublicSnippets,
+		"s3_sync":         f.S3Sync,
+		"api_tokens":      f.APITokens,
+		"backup_restore":  f.BackupRestore,
+	}
+}
+
+// PublishConfig holds scheduled publishing settings
+type PublishConfig struct {
+	CheckInterval time.Duration `json:"check_interval"` // How often due snippets are published
+	WebhookURLs   []string      `json:"webhooks"`       // Endpoints notified when a scheduled snippet goes public
+}
+
+// GitHubConfig holds GitHub integration settings
+type GitHubConfig struct {
+	APIURL        string `json:"api_url"`        // REST API root used for gist sync and repository export
+	WebhookSecret string `json:"webhook_secret"` // Verifies the X-Hub-Signature-256 of gist webhook deliveries; the endpoint is only served when set
+}
+
+// OutboundConfig holds settings for requests to other servers
+type OutboundConfig struct {
+	AllowPrivate bool   `json:"allow_private"` // Let user-supplied URLs (imports, forks) reach loopback and private addresses
+	Proxy        string `json:"proxy"`         // Proxy for all outbound requests, overriding HTTP_PROXY and HTTPS_PROXY
+}
+
+// MailConfig holds outgoing e-mail settings. E-mail is only sent when an
+// SMTP host and sender address are set.
+type MailConfig struct {
+	SMTPHost       string        `json:"smtp_host"`
+	SMTPPort       int           `json:"smtp_port"` // 465 uses implicit TLS, other ports STARTTLS when the server offers it
+	SMTPUsername   string        `json:"smtp_username"`
+	SMTPPassword   string        `json:"smtp_password"`
+	From           string        `json:"from"`
+	DigestInterval time.Duration `json:"digest_interval"` // How often the digest e-mail is sent
+}
+
+// Enabled reports whether e-mail can be sent
+func (c MailConfig) Enabled() bool {
+	return c.SMTPHost != "" && c.From != ""
+}
+
+// MetricsConfig holds Prometheus metrics settings
+type MetricsConfig struct {
+	Enabled bool   `json:"enabled"` // Serve /metrics
+	Token   string `json:"token"`   // Bearer token scrapers can use instead of admin credentials
+}
+
+// TelemetryConfig holds the opt-in usage telemetry settings
+type TelemetryConfig struct {
+	Enabled bool `json:"enabled"` // Count API usage per route for the local usage report
+}
+
+// SlashCommandConfig holds Slack and Mattermost slash command settings. The
+// command endpoint is only served when one of them is set.
+type SlashCommandConfig struct {
+	SlackSigningSecret string `json:"slack_signing_secret"` // Verifies the X-Slack-Signature of Slack requests
+	MattermostToken    string `json:"mattermost_token"`     // Compared with the token Mattermost sends with each request
+}
+
+// Enabled reports whether the slash command endpoint should be served
+func (c SlashCommandConfig) Enabled() bool {
+	return c.SlackSigningSecret != "" || c.MattermostToken != ""
+}
+
+// RemoteConfig holds remote source (federation) settings
+type RemoteConfig struct {
+	SyncInterval time.Duration `json:"sync_interval"` // How often remote instances' public feeds are pulled
 }
 
 // DemoConfig holds demo mode settings
 type DemoConfig struct {
-	Enabled       bool
-	ResetInterval time.Duration
+	Enabled       bool          `json:"enabled"`
+	ResetInterval time.Duration `json:"reset_interval"`
 }
 
-// Load reads configuration from environment variables
+// Load reads configuration from environment variables and the
+// configuration file named by SNIPO_CONFIG, or else DefaultFile
 func Load() (*Config, error) {
+	return LoadFile(os.Getenv("SNIPO_CONFIG"))
+}
+
+// LoadFile reads configuration from environment variables and the
+// configuration file at path, or DefaultFile if path is empty. Environment
+// variables take precedence over the file.
+func LoadFile(path string) (*Config, error) {
+	file, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	l := &loader{file: file}
 	cfg := &Config{}
 
 	// Server
-	cfg.Server.Host = getEnv("SNIPO_HOST", "0.0.0.0")
-	cfg.Server.Port = getEnvInt("SNIPO_PORT", 8080)
-	cfg.Server.ReadTimeout = getEnvDuration("SNIPO_READ_TIMEOUT", 30*time.Second)
-	cfg.Server.WriteTimeout = getEnvDuration("SNIPO_WRITE_TIMEOUT", 30*time.Second)
-	cfg.Server.TrustProxy = getEnvBool("SNIPO_TRUST_PROXY", false)
-	cfg.Server.MaxFilesPerSnippet = getEnvInt("SNIPO_MAX_FILES_PER_SNIPPET", 10)
-	cfg.Server.BasePath = normalizeBasePath(getEnv("SNIPO_BASE_PATH", ""))
+	cfg.Server.Host = l.getEnv("SNIPO_HOST", "0.0.0.0")
+	cfg.Server.Port = l.getEnvInt("SNIPO_PORT", 8080)
+	cfg.Server.ReadTimeout = l.getEnvDuration("SNIPO_READ_TIMEOUT", 30*time.Second)
+	cfg.Server.WriteTimeout = l.getEnvDuration("SNIPO_WRITE_TIMEOUT", 30*time.Second)
+	cfg.Server.TrustProxy = l.getEnvBool("SNIPO_TRUST_PROXY", false)
+	cfg.Server.MaxFilesPerSnippet = l.getEnvInt("SNIPO_MAX_FILES_PER_SNIPPET", 10)
+	cfg.Server.BasePath = normalizeBasePath(l.getEnv("SNIPO_BASE_PATH", ""))
+	cfg.Server.ThemeDir = l.getEnv("SNIPO_THEME_DIR", "")
 
 	// Database
-	cfg.Database.Path = getEnv("SNIPO_DB_PATH", "/data/snipo.db")
-	cfg.Database.MaxOpenConns = getEnvInt("SNIPO_DB_MAX_CONNS", 1)
-	cfg.Database.BusyTimeout = getEnvInt("SNIPO_DB_BUSY_TIMEOUT", 5000)
-	cfg.Database.JournalMode = getEnv("SNIPO_DB_JOURNAL", "WAL")
-	cfg.Database.SynchronousMode = getEnv("SNIPO_DB_SYNC", "NORMAL")
-	cfg.Database.MMapSize = getEnvInt64("SNIPO_DB_MMAP_SIZE", 268435456) // 256MB default
-	cfg.Database.CacheSize = getEnvInt("SNIPO_DB_CACHE_SIZE", -2000)     // 2MB default (negative = KB)
+	cfg.Database.Path = l.getEnv("SNIPO_DB_PATH", "/data/snipo.db")
+	cfg.Database.MaxOpenConns = l.getEnvInt("SNIPO_DB_MAX_CONNS", 1)
+	cfg.Database.BusyTimeout = l.getEnvInt("SNIPO_DB_BUSY_TIMEOUT", 5000)
+	cfg.Database.JournalMode = l.getEnv("SNIPO_DB_JOURNAL", "WAL")
+	cfg.Database.SynchronousMode = l.getEnv("SNIPO_DB_SYNC", "NORMAL")
+	cfg.Database.MMapSize = l.getEnvInt64("SNIPO_DB_MMAP_SIZE", 268435456) // 256MB default
+	cfg.Database.CacheSize = l.getEnvInt("SNIPO_DB_CACHE_SIZE", -2000)     // 2MB default (negative = KB)
 
 	// Demo Mode (check early to override auth requirements)
-	cfg.Demo.Enabled = getEnvBool("SNIPO_DEMO_MODE", false)
-	cfg.Demo.ResetInterval = getEnvDuration("SNIPO_DEMO_RESET_INTERVAL", 15*time.Minute)
+	cfg.Demo.Enabled = l.getEnvBool("SNIPO_DEMO_MODE", false)
+	cfg.Demo.ResetInterval = l.getEnvDuration("SNIPO_DEMO_RESET_INTERVAL", 15*time.Minute)
 
 	// Auth - Check if authentication is disabled
-	cfg.Auth.Disabled = getEnvBool("SNIPO_DISABLE_AUTH", false)
+	cfg.Auth.Disabled = l.getEnvBool("SNIPO_DISABLE_AUTH", false)
 
 	// If demo mode is enabled, override auth settings
 	if cfg.Demo.Enabled {
@@ -138,8 +273,12 @@ func Load() (*Config, error) {
 		cfg.Auth.MasterPasswordHash = ""
 	} else {
 		// Auth enabled - Support both plain text password and pre-hashed password
-		cfg.Auth.MasterPassword = os.Getenv("SNIPO_MASTER_PASSWORD")
-		cfg.Auth.MasterPasswordHash = os.Getenv("SNIPO_MASTER_PASSWORD_HASH")
+		if cfg.Auth.MasterPassword, err = l.getSecret("SNIPO_MASTER_PASSWORD"); err != nil {
+			return nil, err
+		}
+		if cfg.Auth.MasterPasswordHash, err = l.getSecret("SNIPO_MASTER_PASSWORD_HASH"); err != nil {
+			return nil, err
+		}
 
 		// At least one password method must be provided when auth is enabled
 		if cfg.Auth.MasterPassword == "" && cfg.Auth.MasterPasswordHash == "" {
@@ -156,7 +295,10 @@ func Load() (*Config, error) {
 		}
 	}
 
-	sessionSecret := os.Getenv("SNIPO_SESSION_SECRET")
+	sessionSecret, err := l.getSecret("SNIPO_SESSION_SECRET")
+	if err != nil {
+		return nil, err
+	}
 	if sessionSecret == "" {
 		secret, err := generateSecret()
 		if err != nil {
@@ -166,13 +308,33 @@ func Load() (*Config, error) {
 		cfg.Auth.SessionSecretGenerated = true
 	}
 	cfg.Auth.SessionSecret = sessionSecret
-	cfg.Auth.SessionDuration = getEnvDuration("SNIPO_SESSION_DURATION", 168*time.Hour)
-	cfg.Auth.RateLimit = getEnvInt("SNIPO_RATE_LIMIT", 100)
-	cfg.Auth.RateLimitWindow = getEnvDuration("SNIPO_RATE_WINDOW", 1*time.Minute)
+	cfg.Auth.SessionDuration = l.getEnvDuration("SNIPO_SESSION_DURATION", 168*time.Hour)
+	if cfg.Auth.PreviousSessionSecret, err = l.getSecret("SNIPO_SESSION_SECRET_PREVIOUS"); err != nil {
+		return nil, err
+	}
+	cfg.Auth.SessionSecretGrace = l.getEnvDuration("SNIPO_SESSION_SECRET_GRACE", 168*time.Hour)
+	cfg.Auth.RememberMeDuration = l.getEnvDuration("SNIPO_REMEMBER_ME_DURATION", 720*time.Hour)
+	cfg.Auth.SessionMaxLifetime = l.getEnvDuration("SNIPO_SESSION_MAX_LIFETIME", 2160*time.Hour)
+	if cfg.Auth.RememberMeDuration > 0 && cfg.Auth.SessionMaxLifetime < cfg.Auth.RememberMeDuration {
+		return nil, errors.New("SNIPO_SESSION_MAX_LIFETIME must not be shorter than SNIPO_REMEMBER_ME_DURATION")
+	}
+	cfg.Auth.RateLimit = l.getEnvInt("SNIPO_RATE_LIMIT", 100)
+	cfg.Auth.RateLimitWindow = l.getEnvDuration("SNIPO_RATE_WINDOW", 1*time.Minute)
+	cfg.Auth.GeoIPDatabase = l.lookup("SNIPO_GEOIP_DB")
+	cfg.Auth.MultiUser = l.getEnvBool("SNIPO_MULTI_USER", false)
+	cfg.Auth.WorkspaceDomain = l.lookup("SNIPO_WORKSPACE_DOMAIN")
+	cfg.Auth.ChallengeAfter = l.getEnvInt("SNIPO_LOGIN_CHALLENGE_AFTER", 0)
+	cfg.Auth.ChallengeDifficulty = l.getEnvInt("SNIPO_LOGIN_CHALLENGE_DIFFICULTY", 16)
+	if cfg.Auth.ChallengeDifficulty < 1 || cfg.Auth.ChallengeDifficulty > 32 {
+		return nil, errors.New("SNIPO_LOGIN_CHALLENGE_DIFFICULTY must be between 1 and 32")
+	}
 
 	// Encryption salt for backups and token encryption
 	// Priority: env var > persisted file > generate new (and persist)
-	encryptionSalt := os.Getenv("SNIPO_ENCRYPTION_SALT")
+	encryptionSalt, err := l.getSecret("SNIPO_ENCRYPTION_SALT")
+	if err != nil {
+		return nil, err
+	}
 	if encryptionSalt == "" {
 		saltFilePath := filepath.Join(filepath.Dir(cfg.Database.Path), ".encryption_salt")
 		if data, err := os.ReadFile(saltFilePath); err == nil && len(strings.TrimSpace(string(data))) > 0 {
@@ -193,20 +355,63 @@ func Load() (*Config, error) {
 	cfg.Auth.EncryptionSalt = encryptionSalt
 
 	// S3
-	cfg.S3.Enabled = getEnvBool("SNIPO_S3_ENABLED", false)
-	cfg.S3.Endpoint = os.Getenv("SNIPO_S3_ENDPOINT")
-	cfg.S3.AccessKeyID = os.Getenv("SNIPO_S3_ACCESS_KEY")
-	cfg.S3.SecretAccessKey = os.Getenv("SNIPO_S3_SECRET_KEY")
-	cfg.S3.Bucket = os.Getenv("SNIPO_S3_BUCKET")
-	cfg.S3.Region = getEnv("SNIPO_S3_REGION", "us-east-1")
-	cfg.S3.UseSSL = getEnvBool("SNIPO_S3_SSL", true)
+	cfg.S3.Enabled = l.getEnvBool("SNIPO_S3_ENABLED", false)
+	cfg.S3.Endpoint = l.lookup("SNIPO_S3_ENDPOINT")
+	if cfg.S3.AccessKeyID, err = l.getSecret("SNIPO_S3_ACCESS_KEY"); err != nil {
+		return nil, err
+	}
+	if cfg.S3.SecretAccessKey, err = l.getSecret("SNIPO_S3_SECRET_KEY"); err != nil {
+		return nil, err
+	}
+	cfg.S3.Bucket = l.lookup("SNIPO_S3_BUCKET")
+	cfg.S3.Region = l.getEnv("SNIPO_S3_REGION", "us-east-1")
+	cfg.S3.UseSSL = l.getEnvBool("SNIPO_S3_SSL", true)
+	cfg.S3.Encrypt = l.getEnvBool("SNIPO_S3_ENCRYPT", false)
+	cfg.S3.StorageClass = strings.ToUpper(l.lookup("SNIPO_S3_STORAGE_CLASS"))
+	cfg.S3.Tags = map[string]string{}
+	for _, tag := range strings.Split(l.lookup("SNIPO_S3_TAGS"), ",") {
+		if tag = strings.TrimSpace(tag); tag == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(tag, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("SNIPO_S3_TAGS must be key=value pairs separated by commas, got %q", tag)
+		}
+		cfg.S3.Tags[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	// SFTP
+	cfg.SFTP.Enabled = l.getEnvBool("SNIPO_SFTP_ENABLED", false)
+	cfg.SFTP.Host = l.lookup("SNIPO_SFTP_HOST")
+	cfg.SFTP.User = l.lookup("SNIPO_SFTP_USER")
+	if cfg.SFTP.Password, err = l.getSecret("SNIPO_SFTP_PASSWORD"); err != nil {
+		return nil, err
+	}
+	if cfg.SFTP.PrivateKey, err = l.getSecret("SNIPO_SFTP_PRIVATE_KEY"); err != nil {
+		return nil, err
+	}
+	if cfg.SFTP.PrivateKeyPassphrase, err = l.getSecret("SNIPO_SFTP_PRIVATE_KEY_PASSPHRASE"); err != nil {
+		return nil, err
+	}
+	cfg.SFTP.HostKey = l.lookup("SNIPO_SFTP_HOST_KEY")
+	cfg.SFTP.Path = l.getEnv("SNIPO_SFTP_PATH", "snipo")
+	cfg.SFTP.Encrypt = l.getEnvBool("SNIPO_SFTP_ENCRYPT", false)
+
+	// Replication
+	cfg.Replica.Enabled = l.getEnvBool("SNIPO_REPLICA_ENABLED", false)
+	cfg.Replica.Interval = l.getEnvDuration("SNIPO_REPLICA_INTERVAL", time.Second)
+	cfg.Replica.SnapshotInterval = l.getEnvDuration("SNIPO_REPLICA_SNAPSHOT_INTERVAL", 24*time.Hour)
+	cfg.Replica.Retention = l.getEnvDuration("SNIPO_REPLICA_RETENTION", 72*time.Hour)
+	if cfg.Replica.Interval <= 0 || cfg.Replica.SnapshotInterval <= 0 {
+		return nil, errors.New("SNIPO_REPLICA_INTERVAL and SNIPO_REPLICA_SNAPSHOT_INTERVAL must be positive")
+	}
 
 	// Logging
-	cfg.Logging.Level = getEnv("SNIPO_LOG_LEVEL", "info")
-	cfg.Logging.Format = getEnv("SNIPO_LOG_FORMAT", "json")
+	cfg.Logging.Level = l.getEnv("SNIPO_LOG_LEVEL", "info")
+	cfg.Logging.Format = l.getEnv("SNIPO_LOG_FORMAT", "json")
 
 	// API
-	originsStr := getEnv("SNIPO_ALLOWED_ORIGINS", "")
+	originsStr := l.getEnv("SNIPO_ALLOWED_ORIGINS", "")
 	originsStr = strings.TrimSpace(originsStr)
 	switch originsStr {
 	case "*":
@@ -220,15 +425,66 @@ func Load() (*Config, error) {
 			cfg.API.AllowedOrigins[i] = strings.TrimSpace(origin)
 		}
 	}
-	cfg.API.RateLimitRead = getEnvInt("SNIPO_RATE_LIMIT_READ", 1000)
-	cfg.API.RateLimitWrite = getEnvInt("SNIPO_RATE_LIMIT_WRITE", 500)
-	cfg.API.RateLimitAdmin = getEnvInt("SNIPO_RATE_LIMIT_ADMIN", 100)
+	cfg.API.RateLimitRead = l.getEnvInt("SNIPO_RATE_LIMIT_READ", 1000)
+	cfg.API.RateLimitWrite = l.getEnvInt("SNIPO_RATE_LIMIT_WRITE", 500)
+	cfg.API.RateLimitAdmin = l.getEnvInt("SNIPO_RATE_LIMIT_ADMIN", 100)
+	cfg.API.PublicCacheTTL = l.getEnvDuration("SNIPO_PUBLIC_CACHE_TTL", 10*time.Second)
+	cfg.API.ShareLinksByID = l.getEnvBool("SNIPO_SHARE_LINKS_BY_ID", true)
 
 	// Feature Flags
-	cfg.Features.PublicSnippets = getEnvBool("SNIPO_ENABLE_PUBLIC_SNIPPETS", true)
-	cfg.Features.S3Sync = cfg.S3.Enabled // S3Sync follows S3.Enabled
-	cfg.Features.APITokens = getEnvBool("SNIPO_ENABLE_API_TOKENS", true)
-	cfg.Features.BackupRestore = getEnvBool("SNIPO_ENABLE_BACKUP_RESTORE", true)
+	cfg.Features.PublicSnippets = l.getEnvBool("SNIPO_ENABLE_PUBLIC_SNIPPETS", true)
+	cfg.Features.S3Sync = cfg.S3.Enabled || cfg.SFTP.Enabled // S3Sync follows whether a backup target is enabled
+	cfg.Features.APITokens = l.getEnvBool("SNIPO_ENABLE_API_TOKENS", true)
+	cfg.Features.BackupRestore = l.getEnvBool("SNIPO_ENABLE_BACKUP_RESTORE", true)
+
+	// GitHub integration
+	cfg.GitHub.APIURL = strings.TrimRight(l.getEnv("SNIPO_GITHUB_API_URL", "https://api.github.com"), "/")
+	if cfg.GitHub.WebhookSecret, err = l.getSecret("SNIPO_GITHUB_WEBHOOK_SECRET"); err != nil {
+		return nil, err
+	}
+
+	// Outbound requests
+	cfg.Outbound.AllowPrivate = l.getEnvBool("SNIPO_OUTBOUND_ALLOW_PRIVATE", false)
+	cfg.Outbound.Proxy = strings.TrimSpace(l.lookup("SNIPO_OUTBOUND_PROXY"))
+
+	// Prometheus metrics
+	cfg.Metrics.Enabled = l.getEnvBool("SNIPO_METRICS_ENABLED", false)
+	if cfg.Metrics.Token, err = l.getSecret("SNIPO_METRICS_TOKEN"); err != nil {
+		return nil, err
+	}
+
+	// Usage telemetry, kept local
+	cfg.Telemetry.Enabled = l.getEnvBool("SNIPO_TELEMETRY_ENABLED", false)
+
+	// Slash commands
+	if cfg.Slash.SlackSigningSecret, err = l.getSecret("SNIPO_SLACK_SIGNING_SECRET"); err != nil {
+		return nil, err
+	}
+	if cfg.Slash.MattermostToken, err = l.getSecret("SNIPO_MATTERMOST_TOKEN"); err != nil {
+		return nil, err
+	}
+
+	// E-mail
+	cfg.Mail.SMTPHost = l.lookup("SNIPO_SMTP_HOST")
+	cfg.Mail.SMTPPort = l.getEnvInt("SNIPO_SMTP_PORT", 587)
+	cfg.Mail.SMTPUsername = l.lookup("SNIPO_SMTP_USERNAME")
+	if cfg.Mail.SMTPPassword, err = l.getSecret("SNIPO_SMTP_PASSWORD"); err != nil {
+		return nil, err
+	}
+	cfg.Mail.From = l.lookup("SNIPO_SMTP_FROM")
+	cfg.Mail.DigestInterval = l.getEnvDuration("SNIPO_DIGEST_INTERVAL", 168*time.Hour)
+
+	// Remote sources
+	cfg.Remote.SyncInterval = l.getEnvDuration("SNIPO_REMOTE_SYNC_INTERVAL", time.Hour)
+
+	// Scheduled publishing
+	cfg.Publish.CheckInterval = l.getEnvDuration("SNIPO_PUBLISH_CHECK_INTERVAL", time.Minute)
+	cfg.Publish.WebhookURLs = []string{}
+	for _, url := range strings.Split(l.getEnv("SNIPO_PUBLISH_WEBHOOKS", ""), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			cfg.Publish.WebhookURLs = append(cfg.Publish.WebhookURLs, url)
+		}
+	}
 
 	return cfg, nil
 }
@@ -240,15 +496,46 @@ func (c *ServerConfig) Addr() string {
 
 // Helper functions
 
-func getEnv(key, defaultVal string) string {
+// loader looks settings up in the environment, then in the configuration file
+type loader struct {
+	file map[string]string // Settings from the configuration file, keyed by environment variable
+}
+
+func (l *loader) lookup(key string) string {
 	if val := os.Getenv(key); val != "" {
 		return val
 	}
+	return l.file[key]
+}
+
+func (l *loader) getEnv(key, defaultVal string) string {
+	if val := l.lookup(key); val != "" {
+		return val
+	}
 	return defaultVal
 }
 
-func getEnvInt64(key string, defaultVal int64) int64 {
-	if val := os.Getenv(key); val != "" {
+// getSecret returns the value of key, or else the contents of the file named
+// by key_FILE, so secrets can be mounted as Docker or Kubernetes secrets
+func (l *loader) getSecret(key string) (string, error) {
+	val := os.Getenv(key)
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return l.lookup(key), nil
+	}
+	if val != "" {
+		return "", fmt.Errorf("only one of %s and %s_FILE may be set", key, key)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %w", key, err)
+	}
+	// Files written by editors and echo end with a newline that isn't part of the secret
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func (l *loader) getEnvInt64(key string, defaultVal int64) int64 {
+	if val := l.lookup(key); val != "" {
 		if i, err := strconv.ParseInt(val, 10, 64); err == nil {
 			return i
 		}
@@ -256,8 +543,8 @@ func getEnvInt64(key string, defaultVal int64) int64 {
 	return defaultVal
 }
 
-func getEnvInt(key string, defaultVal int) int {
-	if val := os.Getenv(key); val != "" {
+func (l *loader) getEnvInt(key string, defaultVal int) int {
+	if val := l.lookup(key); val != "" {
 		if i, err := strconv.Atoi(val); err == nil {
 			return i
 		}
@@ -265,15 +552,15 @@ func getEnvInt(key string, defaultVal int) int {
 	return defaultVal
 }
 
-func getEnvBool(key string, defaultVal bool) bool {
-	if val := os.Getenv(key); val != "" {
+func (l *loader) getEnvBool(key string, defaultVal bool) bool {
+	if val := l.lookup(key); val != "" {
 		return val == "true" || val == "1" || val == "yes"
 	}
 	return defaultVal
 }
 
-func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
-	if val := os.Getenv(key); val != "" {
+func (l *loader) getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	if val := l.lookup(key); val != "" {
 		if d, err := time.ParseDuration(val); err == nil {
 			return d
 		}
@@ -0,0 +1,288 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultFile is the configuration file read when no other is given and it exists
+const DefaultFile = "/etc/snipo/config.yaml"
+
+// fileKeys maps each setting of the configuration file, as section.key, to
+// the environment variable it stands in for
+var fileKeys = map[string]string{
+	"server.host":                     "SNIPO_HOST",
+	"server.port":                     "SNIPO_PORT",
+	"server.read_timeout":             "SNIPO_READ_TIMEOUT",
+	"server.write_timeout":            "SNIPO_WRITE_TIMEOUT",
+	"server.trust_proxy":              "SNIPO_TRUST_PROXY",
+	"server.max_files_per_snippet":    "SNIPO_MAX_FILES_PER_SNIPPET",
+	"server.base_path":                "SNIPO_BASE_PATH",
+	"server.theme_dir":                "SNIPO_THEME_DIR",
+	"database.path":                   "SNIPO_DB_PATH",
+	"database.max_conns":              "SNIPO_DB_MAX_CONNS",
+	"database.busy_timeout":           "SNIPO_DB_BUSY_TIMEOUT",
+	"database.journal":                "SNIPO_DB_JOURNAL",
+	"database.sync":                   "SNIPO_DB_SYNC",
+	"database.mmap_size":              "SNIPO_DB_MMAP_SIZE",
+	"database.cache_size":             "SNIPO_DB_CACHE_SIZE",
+	"auth.disabled":                   "SNIPO_DISABLE_AUTH",
+	"auth.master_password":            "SNIPO_MASTER_PASSWORD",
+	"auth.master_password_hash":       "SNIPO_MASTER_PASSWORD_HASH",
+	"auth.session_secret":             "SNIPO_SESSION_SECRET",
+	"auth.session_secret_previous":    "SNIPO_SESSION_SECRET_PREVIOUS",
+	"auth.session_secret_grace":       "SNIPO_SESSION_SECRET_GRACE",
+	"auth.session_duration":           "SNIPO_SESSION_DURATION",
+	"auth.remember_me_duration":       "SNIPO_REMEMBER_ME_DURATION",
+	"auth.session_max_lifetime":       "SNIPO_SESSION_MAX_LIFETIME",
+	"auth.rate_limit":                 "SNIPO_RATE_LIMIT",
+	"auth.rate_window":                "SNIPO_RATE_WINDOW",
+	"auth.geoip_db":                   "SNIPO_GEOIP_DB",
+	"auth.multi_user":                 "SNIPO_MULTI_USER",
+	"auth.workspace_domain":           "SNIPO_WORKSPACE_DOMAIN",
+	"auth.login_challenge_after":      "SNIPO_LOGIN_CHALLENGE_AFTER",
+	"auth.login_challenge_difficulty": "SNIPO_LOGIN_CHALLENGE_DIFFICULTY",
+	"auth.encryption_salt":            "SNIPO_ENCRYPTION_SALT",
+	"s3.enabled":                      "SNIPO_S3_ENABLED",
+	"s3.endpoint":                     "SNIPO_S3_ENDPOINT",
+	"s3.access_key":                   "SNIPO_S3_ACCESS_KEY",
+	"s3.secret_key":                   "SNIPO_S3_SECRET_KEY",
+	"s3.bucket":                       "SNIPO_S3_BUCKET",
+	"s3.region":                       "SNIPO_S3_REGION",
+	"s3.ssl":                          "SNIPO_S3_SSL",
+	"s3.encrypt":                      "SNIPO_S3_ENCRYPT",
+	"s3.storage_class":                "SNIPO_S3_STORAGE_CLASS",
+	"s3.tags":                         "SNIPO_S3_TAGS",
+	"sftp.enabled":                    "SNIPO_SFTP_ENABLED",
+	"sftp.host":                       "SNIPO_SFTP_HOST",
+	"sftp.user":                       "SNIPO_SFTP_USER",
+	"sftp.password":                   "SNIPO_SFTP_PASSWORD",
+	"sftp.private_key":                "SNIPO_SFTP_PRIVATE_KEY",
+	"sftp.private_key_passphrase":     "SNIPO_SFTP_PRIVATE_KEY_PASSPHRASE",
+	"sftp.host_key":                   "SNIPO_SFTP_HOST_KEY",
+	"sftp.path":                       "SNIPO_SFTP_PATH",
+	"sftp.encrypt":                    "SNIPO_SFTP_ENCRYPT",
+	"replica.enabled":                 "SNIPO_REPLICA_ENABLED",
+	"replica.interval":                "SNIPO_REPLICA_INTERVAL",
+	"replica.snapshot_interval":       "SNIPO_REPLICA_SNAPSHOT_INTERVAL",
+	"replica.retention":               "SNIPO_REPLICA_RETENTION",
+	"logging.level":                   "SNIPO_LOG_LEVEL",
+	"logging.format":                  "SNIPO_LOG_FORMAT",
+	"api.allowed_origins":             "SNIPO_ALLOWED_ORIGINS",
+	"api.rate_limit_read":             "SNIPO_RATE_LIMIT_READ",
+	"api.rate_limit_write":            "SNIPO_RATE_LIMIT_WRITE",
+	"api.rate_limit_admin":            "SNIPO_RATE_LIMIT_ADMIN",
+	"api.public_cache_ttl":            "SNIPO_PUBLIC_CACHE_TTL",
+	"api.share_links_by_id":           "SNIPO_SHARE_LINKS_BY_ID",
+	"features.public_snippets":        "SNIPO_ENABLE_PUBLIC_SNIPPETS",
+	"features.api_tokens":             "SNIPO_ENABLE_API_TOKENS",
+	"features.backup_restore":         "SNIPO_ENABLE_BACKUP_RESTORE",
+	"demo.enabled":                    "SNIPO_DEMO_MODE",
+	"demo.reset_interval":             "SNIPO_DEMO_RESET_INTERVAL",
+	"github.api_url":                  "SNIPO_GITHUB_API_URL",
+	"github.webhook_secret":           "SNIPO_GITHUB_WEBHOOK_SECRET",
+	"outbound.allow_private":          "SNIPO_OUTBOUND_ALLOW_PRIVATE",
+	"outbound.proxy":                  "SNIPO_OUTBOUND_PROXY",
+	"metrics.enabled":                 "SNIPO_METRICS_ENABLED",
+	"metrics.token":                   "SNIPO_METRICS_TOKEN",
+	"telemetry.enabled":               "SNIPO_TELEMETRY_ENABLED",
+	"slash.slack_signing_secret":      "SNIPO_SLACK_SIGNING_SECRET",
+	"slash.mattermost_token":          "SNIPO_MATTERMOST_TOKEN",
+	"smtp.host":                       "SNIPO_SMTP_HOST",
+	"smtp.port":                       "SNIPO_SMTP_PORT",
+	"smtp.username":                   "SNIPO_SMTP_USERNAME",
+	"smtp.password":                   "SNIPO_SMTP_PASSWORD",
+	"smtp.from":                       "SNIPO_SMTP_FROM",
+	"digest.interval":                 "SNIPO_DIGEST_INTERVAL",
+	"remote.sync_interval":            "SNIPO_REMOTE_SYNC_INTERVAL",
+	"publish.check_interval":          "SNIPO_PUBLISH_CHECK_INTERVAL",
+	"publish.webhooks":                "SNIPO_PUBLISH_WEBHOOKS",
+}
+
+// readFile reads the configuration file at path, or DefaultFile if path is
+// empty, and returns its settings keyed by environment variable. A missing
+// DefaultFile is not an error.
+func readFile(path string) (map[string]string, error) {
+	explicit := path != ""
+	if !explicit {
+		path = DefaultFile
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	settings, err := parseYAML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	values := make(map[string]string, len(settings))
+	for key, value := range settings {
+		env, ok := fileKeys[key]
+		if !ok {
+			return nil, fmt.Errorf("invalid config file %s: unknown setting %q", path, key)
+		}
+		values[env] = value
+	}
+	return values, nil
+}
+
+// parseYAML parses the subset of YAML the configuration file needs: nested
+// mappings of scalars and lists, with comments. Keys are flattened to
+// dotted paths, and lists are joined with commas like their environment
+// variables.
+func parseYAML(data string) (map[string]string, error) {
+	type level struct {
+		indent int
+		prefix string
+	}
+	values := map[string]string{}
+	var stack []level
+	var listKey string // Key whose block list items follow
+	var err error
+
+	for n, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(stripComment(line), " \t\r")
+		content := strings.TrimLeft(line, " ")
+		if content == "" || content == "---" {
+			continue
+		}
+		if strings.HasPrefix(content, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", n+1)
+		}
+		indent := len(line) - len(content)
+
+		if item, ok := strings.CutPrefix(content, "- "); ok || content == "-" {
+			if listKey == "" {
+				return nil, fmt.Errorf("line %d: list item outside a list", n+1)
+			}
+			value, err := parseScalar(item)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			if values[listKey] != "" {
+				value = values[listKey] + "," + value
+			}
+			values[listKey] = value
+			continue
+		}
+		listKey = ""
+
+		key, value, ok := strings.Cut(content, ": ")
+		if !ok {
+			key, ok = strings.CutSuffix(content, ":")
+		}
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected key: value", n+1)
+		}
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		path := strings.TrimSpace(key)
+		if len(stack) > 0 {
+			path = stack[len(stack)-1].prefix + "." + path
+		}
+
+		value = strings.TrimSpace(value)
+		if value == "" {
+			// A nested mapping or a block list follows
+			stack = append(stack, level{indent: indent, prefix: path})
+			listKey = path
+			values[path] = ""
+			continue
+		}
+		if _, exists := values[path]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", n+1, path)
+		}
+		if list, ok := strings.CutPrefix(value, "["); ok {
+			list, ok = strings.CutSuffix(list, "]")
+			if !ok {
+				return nil, fmt.Errorf("line %d: unterminated list", n+1)
+			}
+			var items []string
+			for _, item := range strings.Split(list, ",") {
+				if item = strings.TrimSpace(item); item == "" {
+					continue
+				}
+				item, err := parseScalar(item)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", n+1, err)
+				}
+				items = append(items, item)
+			}
+			values[path] = strings.Join(items, ",")
+			continue
+		}
+		if values[path], err = parseScalar(value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+	}
+
+	// Keys that only introduced a mapping aren't settings themselves
+	for path, value := range values {
+		if value != "" {
+			continue
+		}
+		for other := range values {
+			if strings.HasPrefix(other, path+".") {
+				delete(values, path)
+				break
+			}
+		}
+	}
+	return values, nil
+}
+
+// parseScalar returns the value of a plain, single-quoted or double-quoted scalar
+func parseScalar(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, `"`):
+		value, err := strconv.Unquote(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", s)
+		}
+		return value, nil
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("invalid string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	return s, nil
+}
+
+// stripComment removes a # comment from line, leaving # inside quotes and
+// within words (such as URL fragments) alone. Like in YAML, quotes only
+// count at the start of a value, so apostrophes in plain text don't hide
+// the comment after them.
+func stripComment(line string) string {
+	var quote rune
+	for i, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && startsValue(line[:i]):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// startsValue reports whether a value begins after before: at the start of
+// the line, after a key, a list dash, or an opening bracket or comma of a
+// flow list
+func startsValue(before string) bool {
+	before = strings.TrimRight(before, " \t")
+	return before == "" || strings.ContainsAny(before[len(before)-1:], ":-[,")
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseYAML(t *testing.T) {
+	values, err := parseYAML(`---
+# Snipo configuration
+server:
+  port: 9090 # Behind the proxy
+  base_path: "/snipo"
+auth:
+  master_password_hash: '$argon2id$v=19$it''s'
+api:
+  allowed_origins: [https://a.example, "https://b.example"]
+publish:
+  webhooks:
+    - https://hooks.example/one
+    - https://hooks.example/two#fragment
+github:
+  api_url:
+smtp:
+  from: it's snipo # Plain text with an apostrophe
+  host: "smtp.example # not a comment" # A comment
+`)
+	if err != nil {
+		t.Fatalf("parseYAML failed: %v", err)
+	}
+	want := map[string]string{
+		"server.port":               "9090",
+		"server.base_path":          "/snipo",
+		"auth.master_password_hash": "$argon2id$v=19$it's",
+		"api.allowed_origins":       "https://a.example,https://b.example",
+		"publish.webhooks":          "https://hooks.example/one,https://hooks.example/two#fragment",
+		"github.api_url":            "",
+		"smtp.from":                 "it's snipo",
+		"smtp.host":                 "smtp.example # not a comment",
+	}
+	if len(values) != len(want) {
+		t.Errorf("expected %d settings, got %v", len(want), values)
+	}
+	for key, value := range want {
+		if values[key] != value {
+			t.Errorf("%s: expected %q, got %q", key, value, values[key])
+		}
+	}
+
+	for _, invalid := range []string{
+		"server:\n  port 9090\n",
+		"- item\n",
+		"server:\n\tport: 9090\n",
+		"server:\n  port: 1\n  port: 2\n",
+		"s3:\n  bucket: \"unterminated\n",
+	} {
+		if _, err := parseYAML(invalid); err == nil {
+			t.Errorf("expected an error parsing %q", invalid)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+server:
+  port: 9090
+database:
+  path: /srv/snipo.db
+auth:
+  master_password: from-file
+  session_secret: file-session-secret
+  encryption_salt: salt
+s3:
+  enabled: true
+  bucket: backups
+  storage_class: standard_ia
+  tags:
+    - team=platform
+    - retention=90d
+demo:
+  reset_interval: 5m
+github:
+  webhook_secret: gist-hook-secret
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SNIPO_MASTER_PASSWORD", "")
+	t.Setenv("SNIPO_SESSION_SECRET", "")
+	t.Setenv("SNIPO_PORT", "7070")
+	t.Setenv("SNIPO_GITHUB_WEBHOOK_SECRET", "")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.Server.Port != 7070 {
+		t.Errorf("expected the environment to override the file, got port %d", cfg.Server.Port)
+	}
+	if cfg.Database.Path != "/srv/snipo.db" || cfg.Auth.MasterPassword != "from-file" || cfg.Auth.SessionSecret != "file-session-secret" {
+		t.Errorf("expected settings from the file, got %+v %+v", cfg.Database, cfg.Auth)
+	}
+	if !cfg.S3.Enabled || !cfg.Features.S3Sync || cfg.S3.Bucket != "backups" || cfg.Demo.ResetInterval != 5*time.Minute {
+		t.Errorf("expected S3 and demo settings from the file, got %+v %+v", cfg.S3, cfg.Demo)
+	}
+	if cfg.S3.StorageClass != "STANDARD_IA" || len(cfg.S3.Tags) != 2 || cfg.S3.Tags["retention"] != "90d" {
+		t.Errorf("expected the storage class and tags from the file, got %q %v", cfg.S3.StorageClass, cfg.S3.Tags)
+	}
+	if cfg.GitHub.WebhookSecret != "gist-hook-secret" {
+		t.Errorf("expected the gist webhook secret from the file, got %q", cfg.GitHub.WebhookSecret)
+	}
+
+	// Unknown settings are likely typos
+	if err := os.WriteFile(path, []byte("server:\n  prot: 9090\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Error("expected an error for an unknown setting")
+	}
+
+	// A file given explicitly must exist
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing config file")
+	}
+}
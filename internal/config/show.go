@@ -0,0 +1,74 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// redacted is shown in place of secrets that are set
+const redacted = "[redacted]"
+
+// Effective returns the configuration in effect, for `snipo config show`:
+// sections keyed like the JSON tags, durations as strings like "1h0m0s" and
+// secrets that are set replaced with "[redacted]"
+func (c *Config) Effective() map[string]any {
+	shown := *c
+	for _, secret := range []*string{
+		&shown.Auth.MasterPassword,
+		&shown.Auth.MasterPasswordHash,
+		&shown.Auth.SessionSecret,
+		&shown.Auth.PreviousSessionSecret,
+		&shown.Auth.EncryptionSalt,
+		&shown.S3.AccessKeyID,
+		&shown.S3.SecretAccessKey,
+		&shown.SFTP.Password,
+		&shown.SFTP.PrivateKey,
+		&shown.SFTP.PrivateKeyPassphrase,
+		&shown.Metrics.Token,
+		&shown.GitHub.WebhookSecret,
+		&shown.Slash.SlackSigningSecret,
+		&shown.Slash.MattermostToken,
+		&shown.Mail.SMTPPassword,
+	} {
+		if *secret != "" {
+			*secret = redacted
+		}
+	}
+	return effectiveValue(reflect.ValueOf(shown)).(map[string]any)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func effectiveValue(v reflect.Value) any {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	if v.Kind() != reflect.Struct {
+		return v.Interface()
+	}
+	fields := make(map[string]any, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		fields[name] = effectiveValue(v.Field(i))
+	}
+	return fields
+}
+
+// Warnings lists the settings left to insecure defaults, which the server
+// also warns about when it starts
+func (c *Config) Warnings() []string {
+	warnings := []string{}
+	if c.Auth.Disabled {
+		warnings = append(warnings, "Authentication is disabled (SNIPO_DISABLE_AUTH=true): all requests are accepted without verification")
+	} else if c.Auth.SessionSecretGenerated {
+		warnings = append(warnings, "SNIPO_SESSION_SECRET is not set: a new session secret is generated on every start, signing everyone out")
+	}
+	if c.Auth.EncryptionSaltGenerated {
+		warnings = append(warnings, "SNIPO_ENCRYPTION_SALT is not set: a new salt was generated and saved to .encryption_salt in the data directory")
+	}
+	if c.Replica.Enabled && !c.S3.Enabled && !c.SFTP.Enabled {
+		warnings = append(warnings, "SNIPO_REPLICA_ENABLED is set, but neither S3 nor SFTP storage is enabled to replicate to")
+	}
+	return warnings
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestEffective(t *testing.T) {
+	t.Setenv("SNIPO_DB_PATH", filepath.Join(t.TempDir(), "snipo.db"))
+	t.Setenv("SNIPO_MASTER_PASSWORD", "hunter2")
+	t.Setenv("SNIPO_SESSION_SECRET", "")
+	t.Setenv("SNIPO_ENCRYPTION_SALT", "salt")
+	t.Setenv("SNIPO_S3_SECRET_KEY", "")
+	t.Setenv("SNIPO_PUBLIC_CACHE_TTL", "30s")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	shown := cfg.Effective()
+
+	auth := shown["auth"].(map[string]any)
+	if auth["master_password"] != redacted || auth["session_secret"] != redacted {
+		t.Errorf("expected secrets to be redacted, got %v", auth)
+	}
+	if cfg.Auth.MasterPassword != "hunter2" {
+		t.Error("expected Effective to leave the configuration alone")
+	}
+	if got := shown["s3"].(map[string]any)["secret_key"]; got != "" {
+		t.Errorf("expected unset secrets to stay empty, got %q", got)
+	}
+	if got := shown["api"].(map[string]any)["public_cache_ttl"]; got != "30s" {
+		t.Errorf("expected durations as strings, got %v", got)
+	}
+	if got := shown["server"].(map[string]any)["port"]; got != 8080 {
+		t.Errorf("expected the default port, got %v", got)
+	}
+
+	warnings := cfg.Warnings()
+	if len(warnings) != 1 {
+		t.Errorf("expected a warning about the generated session secret, got %v", warnings)
+	}
+}
@@ -26,6 +26,9 @@ type Config struct {
 	SynchronousMode string
 	MMapSize        int64 // Memory-mapped I/O size in bytes
 	CacheSize       int   // Cache size in pages (negative = KB)
+	// ManualCheckpoints puts the database in WAL mode and turns automatic
+	// checkpoints off, leaving them to the replicator
+	ManualCheckpoints bool
 }
 
 // New creates a new database connection
@@ -45,6 +48,10 @@ func New(cfg Config, logger *slog.Logger) (*DB, error) {
 		cfg.JournalMode,
 		cfg.SynchronousMode,
 	)
+	if cfg.ManualCheckpoints {
+		// The driver runs _pragma parameters on every connection it opens
+		dsn += fmt.Sprintf("&_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_pragma=wal_autocheckpoint(0)", cfg.BusyTimeout)
+	}
 
 	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
@@ -93,10 +100,9 @@ func (db *DB) Migrate(ctx context.Context) error {
 	}
 
 	// Get current version
-	var currentVersion int
-	row := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations")
-	if err := row.Scan(&currentVersion); err != nil {
-		return fmt.Errorf("failed to get current migration version: %w", err)
+	currentVersion, err := db.SchemaVersion(ctx)
+	if err != nil {
+		return err
 	}
 
 	db.logger.Info("current schema version", "version", currentVersion)
@@ -158,33 +164,80 @@ func (db *DB) Migrate(ctx context.Context) error {
 			}
 		}
 
-		// Execute migration in a transaction
-		tx, err := db.BeginTx(ctx, nil)
-		if err != nil {
-			return fmt.Errorf("failed to begin transaction: %w", err)
+		if err := db.apply(ctx, m); err != nil {
+			return err
 		}
 
-		if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
-			_ = tx.Rollback()
-			return fmt.Errorf("failed to apply migration %d (%s): %w", m.Version, m.Name, err)
+		db.logger.Info("migration applied", "version", m.Version, "name", m.Name)
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the version of the last migration applied
+func (db *DB) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	row := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations")
+	if err := row.Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get current migration version: %w", err)
+	}
+	return version, nil
+}
+
+// apply runs one migration in a transaction and records it. Table rebuilds
+// run on a dedicated connection with foreign keys off, as SQLite requires,
+// and are checked for dangling references before committing.
+func (db *DB) apply(ctx context.Context, m Migration) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if m.RebuildsTables {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return fmt.Errorf("failed to disable foreign keys: %w", err)
 		}
+		defer func() { _, _ = conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON") }()
+	}
 
-		// Record migration
-		if _, err := tx.ExecContext(ctx,
-			"INSERT INTO schema_migrations (version, name) VALUES (?, ?)",
-			m.Version, m.Name,
-		); err != nil {
+	// Execute migration in a transaction
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to apply migration %d (%s): %w", m.Version, m.Name, err)
+	}
+
+	if m.RebuildsTables {
+		rows, err := tx.QueryContext(ctx, "PRAGMA foreign_key_check")
+		if err != nil {
 			_ = tx.Rollback()
-			return fmt.Errorf("failed to record migration: %w", err)
+			return fmt.Errorf("failed to check foreign keys: %w", err)
 		}
-
-		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("failed to commit migration: %w", err)
+		dangling := rows.Next()
+		_ = rows.Close()
+		if dangling {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %d (%s) left dangling foreign keys", m.Version, m.Name)
 		}
+	}
 
-		db.logger.Info("migration applied", "version", m.Version, "name", m.Name)
+	// Record migration
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations (version, name) VALUES (?, ?)",
+		m.Version, m.Name,
+	); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to record migration: %w", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration: %w", err)
+	}
 	return nil
 }
 
@@ -5,6 +5,10 @@ type Migration struct {
 	Version int
 	Name    string
 	SQL     string
+	// RebuildsTables runs the migration with foreign keys off, so tables
+	// can be recreated without cascading deletes into the rows that
+	// reference them
+	RebuildsTables bool
 }
 
 // Initial schema SQL
@@ -359,6 +363,517 @@ ALTER TABLE settings ADD COLUMN default_expiration_days INTEGER DEFAULT 0;
 CREATE INDEX IF NOT EXISTS idx_snippets_expires_at ON snippets(expires_at);
 `
 
+// Migration 12: Add scheduled publishing
+const addPublishAtSQL = `
+-- Add publish_at column to snippets (nullable, cleared once published)
+ALTER TABLE snippets ADD COLUMN publish_at DATETIME DEFAULT NULL;
+
+-- Index for publish_at to speed up the publish scheduler
+CREATE INDEX IF NOT EXISTS idx_snippets_publish_at ON snippets(publish_at);
+`
+
+// Migration 13: Add fork provenance
+const addProvenanceSQL = `
+-- Add provenance column to snippets (JSON with source URL/ID of forked snippets)
+ALTER TABLE snippets ADD COLUMN provenance TEXT DEFAULT NULL;
+`
+
+// Migration 14: Add remote sources (federation)
+const addRemoteSourcesSQL = `
+-- Other snipo instances whose public snippets are mirrored locally
+CREATE TABLE IF NOT EXISTS remote_sources (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    folder_id INTEGER DEFAULT NULL,
+    enabled INTEGER DEFAULT 1,
+    etag TEXT DEFAULT '',
+    last_synced_at DATETIME DEFAULT NULL,
+    last_error TEXT DEFAULT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE SET NULL
+);
+
+-- Mapping between remote snippets and their local read-only mirrors
+CREATE TABLE IF NOT EXISTS remote_snippets (
+    source_id INTEGER NOT NULL,
+    remote_id TEXT NOT NULL,
+    snippet_id TEXT NOT NULL,
+    remote_updated_at DATETIME,
+    PRIMARY KEY (source_id, remote_id),
+    FOREIGN KEY (source_id) REFERENCES remote_sources(id) ON DELETE CASCADE,
+    FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_remote_snippets_snippet ON remote_snippets(snippet_id);
+`
+
+// Migration 15: Add usage tracking
+const addUsageTrackingSQL = `
+-- Count of copies/uses and when the snippet was last used (distinct from views)
+ALTER TABLE snippets ADD COLUMN use_count INTEGER DEFAULT 0;
+ALTER TABLE snippets ADD COLUMN last_used_at DATETIME DEFAULT NULL;
+
+-- Index for sorting by recent use
+CREATE INDEX IF NOT EXISTS idx_snippets_last_used_at ON snippets(last_used_at);
+`
+
+// Migration 16: Add public view tracking
+const addPublicViewsSQL = `
+-- Share page visits by signed-out visitors, separate from view_count
+ALTER TABLE snippets ADD COLUMN public_view_count INTEGER DEFAULT 0;
+
+-- Referring domains of share page visits
+CREATE TABLE IF NOT EXISTS snippet_referrers (
+    snippet_id TEXT NOT NULL,
+    domain TEXT NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0,
+    last_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (snippet_id, domain),
+    FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+`
+
+// Migration 17: Add abuse reports
+const addAbuseReportsSQL = `
+-- Abuse reports against public snippets, reviewed by the admin
+CREATE TABLE IF NOT EXISTS snippet_reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    snippet_id TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    details TEXT DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    resolved_at DATETIME DEFAULT NULL,
+    FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_snippet_reports_status ON snippet_reports(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_snippet_reports_snippet ON snippet_reports(snippet_id);
+
+-- Open reports needed to unpublish a snippet automatically (0 = never)
+ALTER TABLE settings ADD COLUMN report_unpublish_threshold INTEGER DEFAULT 0 NOT NULL;
+`
+
+// Migration to control raw HTML in markdown rendered for public pages
+const addMarkdownHTMLPolicySQL = `
+-- How raw HTML in public markdown is handled: sanitize, escape or allow
+ALTER TABLE settings ADD COLUMN markdown_html_policy TEXT DEFAULT 'sanitize' NOT NULL;
+`
+
+// Migration to restrict API tokens to individual scopes
+const addTokenScopesSQL = `
+-- Comma-separated scopes; empty means the permission level alone applies
+ALTER TABLE api_tokens ADD COLUMN scopes TEXT DEFAULT '' NOT NULL;
+`
+
+// Migration for remember-me sessions with sliding expiration
+const addSessionRememberSQL = `
+-- Remembered sessions extend expires_at on use, up to max_expires_at
+ALTER TABLE sessions ADD COLUMN remember INTEGER DEFAULT 0 NOT NULL;
+ALTER TABLE sessions ADD COLUMN max_expires_at DATETIME DEFAULT NULL;
+`
+
+// Migration to store settings as key-value pairs
+const addKeyValueSettingsSQL = `
+-- Settings as key-value pairs, so new settings don't need a migration.
+-- The settings row is kept for its created_at and updated_at.
+CREATE TABLE IF NOT EXISTS app_settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Carry over the values saved so far
+INSERT OR IGNORE INTO app_settings (key, value)
+SELECT 'app_name', CAST(app_name AS TEXT) FROM settings WHERE id = 1 AND app_name IS NOT NULL
+UNION ALL SELECT 'custom_css', CAST(custom_css AS TEXT) FROM settings WHERE id = 1 AND custom_css IS NOT NULL
+UNION ALL SELECT 'theme', CAST(theme AS TEXT) FROM settings WHERE id = 1 AND theme IS NOT NULL
+UNION ALL SELECT 'default_language', CAST(default_language AS TEXT) FROM settings WHERE id = 1 AND default_language IS NOT NULL
+UNION ALL SELECT 's3_enabled', CAST(s3_enabled AS TEXT) FROM settings WHERE id = 1 AND s3_enabled IS NOT NULL
+UNION ALL SELECT 's3_endpoint', CAST(s3_endpoint AS TEXT) FROM settings WHERE id = 1 AND s3_endpoint IS NOT NULL
+UNION ALL SELECT 's3_bucket', CAST(s3_bucket AS TEXT) FROM settings WHERE id = 1 AND s3_bucket IS NOT NULL
+UNION ALL SELECT 's3_region', CAST(s3_region AS TEXT) FROM settings WHERE id = 1 AND s3_region IS NOT NULL
+UNION ALL SELECT 'backup_encryption_enabled', CAST(backup_encryption_enabled AS TEXT) FROM settings WHERE id = 1 AND backup_encryption_enabled IS NOT NULL
+UNION ALL SELECT 'archive_enabled', CAST(archive_enabled AS TEXT) FROM settings WHERE id = 1 AND archive_enabled IS NOT NULL
+UNION ALL SELECT 'trash_enabled', CAST(trash_enabled AS TEXT) FROM settings WHERE id = 1 AND trash_enabled IS NOT NULL
+UNION ALL SELECT 'history_enabled', CAST(history_enabled AS TEXT) FROM settings WHERE id = 1 AND history_enabled IS NOT NULL
+UNION ALL SELECT 'auto_archive_enabled', CAST(auto_archive_enabled AS TEXT) FROM settings WHERE id = 1 AND auto_archive_enabled IS NOT NULL
+UNION ALL SELECT 'default_expiration_days', CAST(default_expiration_days AS TEXT) FROM settings WHERE id = 1 AND default_expiration_days IS NOT NULL
+UNION ALL SELECT 'disable_login', CAST(disable_login AS TEXT) FROM settings WHERE id = 1 AND disable_login IS NOT NULL
+UNION ALL SELECT 'editor_font_size', CAST(editor_font_size AS TEXT) FROM settings WHERE id = 1 AND editor_font_size IS NOT NULL
+UNION ALL SELECT 'editor_tab_size', CAST(editor_tab_size AS TEXT) FROM settings WHERE id = 1 AND editor_tab_size IS NOT NULL
+UNION ALL SELECT 'editor_theme', CAST(editor_theme AS TEXT) FROM settings WHERE id = 1 AND editor_theme IS NOT NULL
+UNION ALL SELECT 'editor_word_wrap', CAST(editor_word_wrap AS TEXT) FROM settings WHERE id = 1 AND editor_word_wrap IS NOT NULL
+UNION ALL SELECT 'editor_show_print_margin', CAST(editor_show_print_margin AS TEXT) FROM settings WHERE id = 1 AND editor_show_print_margin IS NOT NULL
+UNION ALL SELECT 'editor_show_gutter', CAST(editor_show_gutter AS TEXT) FROM settings WHERE id = 1 AND editor_show_gutter IS NOT NULL
+UNION ALL SELECT 'editor_show_indent_guides', CAST(editor_show_indent_guides AS TEXT) FROM settings WHERE id = 1 AND editor_show_indent_guides IS NOT NULL
+UNION ALL SELECT 'editor_highlight_active_line', CAST(editor_highlight_active_line AS TEXT) FROM settings WHERE id = 1 AND editor_highlight_active_line IS NOT NULL
+UNION ALL SELECT 'editor_use_soft_tabs', CAST(editor_use_soft_tabs AS TEXT) FROM settings WHERE id = 1 AND editor_use_soft_tabs IS NOT NULL
+UNION ALL SELECT 'editor_enable_snippets', CAST(editor_enable_snippets AS TEXT) FROM settings WHERE id = 1 AND editor_enable_snippets IS NOT NULL
+UNION ALL SELECT 'editor_enable_live_autocompletion', CAST(editor_enable_live_autocompletion AS TEXT) FROM settings WHERE id = 1 AND editor_enable_live_autocompletion IS NOT NULL
+UNION ALL SELECT 'markdown_font_size', CAST(markdown_font_size AS TEXT) FROM settings WHERE id = 1 AND markdown_font_size IS NOT NULL
+UNION ALL SELECT 'exclude_first_line_on_copy', CAST(exclude_first_line_on_copy AS TEXT) FROM settings WHERE id = 1 AND exclude_first_line_on_copy IS NOT NULL
+UNION ALL SELECT 'report_unpublish_threshold', CAST(report_unpublish_threshold AS TEXT) FROM settings WHERE id = 1 AND report_unpublish_threshold IS NOT NULL
+UNION ALL SELECT 'markdown_html_policy', CAST(markdown_html_policy AS TEXT) FROM settings WHERE id = 1 AND markdown_html_policy IS NOT NULL;
+`
+
+// Migration to record where sessions and failed logins come from
+const addLoginInsightsSQL = `
+-- Country of the client, from the optional GeoIP database
+ALTER TABLE sessions ADD COLUMN country TEXT DEFAULT NULL;
+
+-- Failed logins, kept for 30 days for the admin overview
+CREATE TABLE IF NOT EXISTS login_failures (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    country TEXT DEFAULT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_failures_created ON login_failures(created_at);
+`
+
+// Migration to add an optional note to snippet files
+const addSnippetFileNotesSQL = `
+-- Kept with file history so restores bring notes back
+ALTER TABLE snippet_files ADD COLUMN note TEXT NOT NULL DEFAULT '';
+ALTER TABLE snippet_files_history ADD COLUMN note TEXT NOT NULL DEFAULT '';
+`
+
+// Migration to find snippets with identical content
+const addContentHashSQL = `
+-- Filled in by the application; NULL until first computed
+ALTER TABLE snippets ADD COLUMN content_hash TEXT DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_snippets_content_hash ON snippets(content_hash);
+`
+
+// Migration to let FTS5 read snippet text back for ranked, highlighted
+// search. The external content table named its first column snippet_id,
+// which the snippets table doesn't have, so snippet() and highlight() failed.
+const rebuildSnippetsFTSSQL = `
+DROP TRIGGER IF EXISTS snippets_ai;
+DROP TRIGGER IF EXISTS snippets_ad;
+DROP TRIGGER IF EXISTS snippets_au;
+DROP TABLE IF EXISTS snippets_fts;
+
+CREATE VIRTUAL TABLE snippets_fts USING fts5(
+    id UNINDEXED,
+    title,
+    description,
+    content,
+    content='snippets',
+    content_rowid='rowid'
+);
+
+CREATE TRIGGER snippets_ai AFTER INSERT ON snippets BEGIN
+    INSERT INTO snippets_fts(rowid, id, title, description, content)
+    VALUES (NEW.rowid, NEW.id, NEW.title, NEW.description, NEW.content);
+END;
+
+CREATE TRIGGER snippets_ad AFTER DELETE ON snippets BEGIN
+    INSERT INTO snippets_fts(snippets_fts, rowid, id, title, description, content)
+    VALUES('delete', OLD.rowid, OLD.id, OLD.title, OLD.description, OLD.content);
+END;
+
+CREATE TRIGGER snippets_au AFTER UPDATE ON snippets BEGIN
+    INSERT INTO snippets_fts(snippets_fts, rowid, id, title, description, content)
+    VALUES('delete', OLD.rowid, OLD.id, OLD.title, OLD.description, OLD.content);
+    INSERT INTO snippets_fts(rowid, id, title, description, content)
+    VALUES (NEW.rowid, NEW.id, NEW.title, NEW.description, NEW.content);
+END;
+
+INSERT INTO snippets_fts(snippets_fts) VALUES('rebuild');
+`
+
+// Migration 26: Add inbound webhooks
+const addInboundWebhooksSQL = `
+-- Endpoints that turn POSTed payloads into snippets. Only a hash of the
+-- secret is kept, like API tokens.
+CREATE TABLE IF NOT EXISTS inbound_webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL UNIQUE,
+    secret_hash TEXT NOT NULL,
+    folder_id INTEGER DEFAULT NULL,
+    tags TEXT NOT NULL DEFAULT '',
+    language TEXT NOT NULL DEFAULT 'plaintext',
+    enabled INTEGER NOT NULL DEFAULT 1,
+    received_count INTEGER NOT NULL DEFAULT 0,
+    last_received_at DATETIME DEFAULT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE SET NULL
+);
+`
+
+// Migration 27: Add the chat bot bridge
+const addBotConfigSQL = `
+-- Telegram or Matrix bot that turns code blocks into snippets. The token is
+-- encrypted like the gist sync token.
+CREATE TABLE IF NOT EXISTS bot_config (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    enabled INTEGER NOT NULL DEFAULT 0,
+    platform TEXT NOT NULL DEFAULT 'telegram',
+    token_encrypted TEXT NOT NULL DEFAULT '',
+    homeserver_url TEXT NOT NULL DEFAULT '',
+    allowed_chats TEXT NOT NULL DEFAULT '',
+    tags TEXT NOT NULL DEFAULT '',
+    cursor TEXT NOT NULL DEFAULT '',
+    last_error TEXT DEFAULT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+// Migration 28: Add user accounts
+const addUsersSQL = `
+-- Accounts for multi-user mode. User 0 is the owner who logs in with the
+-- master password; it is not stored here and owns all existing data.
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    password_hash TEXT NOT NULL,
+    is_admin INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE snippets ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE folders ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sessions ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE api_tokens ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;
+
+-- Tag names are unique per user, which needs the table rebuilt
+CREATE TABLE tags_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    name TEXT NOT NULL,
+    color TEXT DEFAULT '#6366f1',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, name)
+);
+INSERT INTO tags_new (id, name, color, created_at) SELECT id, name, color, created_at FROM tags;
+DROP TABLE tags;
+ALTER TABLE tags_new RENAME TO tags;
+CREATE INDEX IF NOT EXISTS idx_tags_name ON tags(name);
+
+CREATE INDEX IF NOT EXISTS idx_snippets_user ON snippets(user_id);
+CREATE INDEX IF NOT EXISTS idx_folders_user ON folders(user_id);
+`
+
+// Migration 29: Add external IDs for upserts
+const addSnippetExternalIDSQL = `
+-- Caller-chosen keys so infrastructure-as-code tools can update the snippet
+-- they created last time instead of adding another
+ALTER TABLE snippets ADD COLUMN external_id TEXT DEFAULT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_snippets_external_id ON snippets(user_id, external_id) WHERE external_id IS NOT NULL;
+`
+
+// Migration 30: Add workspaces
+const addWorkspacesSQL = `
+-- Workspaces let several teams share one deployment, each seeing only its
+-- own snippets, tags and folders. Workspace 0 is the default, where every
+-- user keeps their own data as before.
+CREATE TABLE IF NOT EXISTS workspaces (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    name TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+-- Settings a workspace overrides; anything missing falls back to app_settings
+CREATE TABLE IF NOT EXISTS workspace_settings (
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace_id, key)
+);
+
+ALTER TABLE snippets ADD COLUMN workspace_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE folders ADD COLUMN workspace_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE api_tokens ADD COLUMN workspace_id INTEGER NOT NULL DEFAULT 0;
+
+-- Tag names are unique per user in the default workspace and per workspace
+-- elsewhere, which needs the table rebuilt
+CREATE TABLE tags_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    workspace_id INTEGER NOT NULL DEFAULT 0,
+    name TEXT NOT NULL,
+    color TEXT DEFAULT '#6366f1',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO tags_new (id, user_id, name, color, created_at) SELECT id, user_id, name, color, created_at FROM tags;
+DROP TABLE tags;
+ALTER TABLE tags_new RENAME TO tags;
+CREATE INDEX IF NOT EXISTS idx_tags_name ON tags(name);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags(user_id, name) WHERE workspace_id = 0;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_workspace_name ON tags(workspace_id, name) WHERE workspace_id != 0;
+
+DROP INDEX IF EXISTS idx_snippets_external_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_snippets_external_id ON snippets(user_id, external_id) WHERE external_id IS NOT NULL AND workspace_id = 0;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_snippets_workspace_external_id ON snippets(workspace_id, external_id) WHERE external_id IS NOT NULL AND workspace_id != 0;
+
+CREATE INDEX IF NOT EXISTS idx_snippets_workspace ON snippets(workspace_id);
+CREATE INDEX IF NOT EXISTS idx_folders_workspace ON folders(workspace_id);
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user ON workspace_members(user_id);
+`
+
+// Migration 31: Add API token usage tracking
+const addTokenUsageSQL = `
+-- Usage counters so stale or leaked tokens stand out
+ALTER TABLE api_tokens ADD COLUMN request_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE api_tokens ADD COLUMN last_used_ip TEXT DEFAULT NULL;
+
+-- Requests per token, day and client address, kept for 30 days
+CREATE TABLE IF NOT EXISTS api_token_usage (
+    token_id INTEGER NOT NULL REFERENCES api_tokens(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    ip TEXT NOT NULL DEFAULT '',
+    requests INTEGER NOT NULL DEFAULT 0,
+    last_seen_at DATETIME NOT NULL,
+    PRIMARY KEY (token_id, day, ip)
+);
+CREATE INDEX IF NOT EXISTS idx_api_token_usage_day ON api_token_usage(day);
+`
+
+// Migration 32: Add workspace roles
+const addWorkspaceRolesSQL = `
+-- viewer, editor or admin; existing members keep being able to edit
+ALTER TABLE workspace_members ADD COLUMN role TEXT NOT NULL DEFAULT 'editor';
+`
+
+// Migration 33: Add folder access lists
+const addFolderAccessSQL = `
+-- Who may see a folder besides its creator: a user (role '') or members
+-- with at least a workspace role (user_id 0). No rows means everyone.
+CREATE TABLE IF NOT EXISTS folder_access (
+    folder_id INTEGER NOT NULL REFERENCES folders(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    role TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (folder_id, user_id, role)
+);
+`
+
+// Migration 34: Add the snippet activity log
+const addActivitySQL = `
+-- One row per change to a snippet, kept after the snippet is deleted so the
+-- feed can still say so. user_id is who made the change.
+CREATE TABLE IF NOT EXISTS activity (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    snippet_id TEXT NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    workspace_id INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_activity_workspace ON activity(workspace_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_activity_snippet ON activity(snippet_id);
+CREATE INDEX IF NOT EXISTS idx_activity_created ON activity(created_at);
+`
+
+// Migration 35: Record when background jobs last ran
+const addJobRunsSQL = `
+-- When each background job last did its work, so schedules longer than a
+-- restart survive one
+CREATE TABLE IF NOT EXISTS job_runs (
+    name TEXT PRIMARY KEY,
+    last_run_at DATETIME NOT NULL
+);
+`
+
+// Migration 36: Address public snippets by a random token instead of their ID
+const addPublicTokensSQL = `
+-- Share links use public_token, so they don't reveal snippet IDs and a
+-- snippet gets a new link each time it is shared again. Statements that
+-- make a snippet public set it themselves; these triggers cover the rest.
+ALTER TABLE snippets ADD COLUMN public_token TEXT DEFAULT NULL;
+
+UPDATE snippets SET public_token = lower(hex(randomblob(12))) WHERE is_public = 1;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_snippets_public_token ON snippets(public_token);
+
+CREATE TRIGGER IF NOT EXISTS snippets_public_token_ai AFTER INSERT ON snippets
+WHEN NEW.is_public = 1 AND NEW.public_token IS NULL BEGIN
+    UPDATE snippets SET public_token = lower(hex(randomblob(12))) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS snippets_public_token_au AFTER UPDATE OF is_public ON snippets
+WHEN NEW.is_public = 1 AND OLD.is_public = 0 AND NEW.public_token IS OLD.public_token BEGIN
+    UPDATE snippets SET public_token = lower(hex(randomblob(12))) WHERE rowid = NEW.rowid;
+END;
+`
+
+// Migration 37: Count feature usage for the opt-in telemetry report
+const addUsageCountsSQL = `
+-- Requests per day and route pattern, recorded only with
+-- SNIPO_TELEMETRY_ENABLED. Patterns carry no IDs, and nothing about who made
+-- the request is kept.
+CREATE TABLE IF NOT EXISTS usage_counts (
+    day TEXT NOT NULL,
+    route TEXT NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, route)
+);
+`
+
+// Migration 38: Move deleted tags and folders to the trash
+const addTagFolderTrashSQL = `
+-- Like snippets, tags and folders deleted while the trash is enabled keep
+-- their row and their snippet associations until restored or purged
+ALTER TABLE tags ADD COLUMN deleted_at DATETIME DEFAULT NULL;
+ALTER TABLE folders ADD COLUMN deleted_at DATETIME DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tags_deleted_at ON tags(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_folders_deleted_at ON folders(deleted_at);
+`
+
+// Migration 39: Keep import reports after the response is gone
+const addImportReportsSQL = `
+-- The per-item outcome of each backup, S3 or URL import, as JSON, so it can
+-- be read again after the browser tab that started the import is closed
+CREATE TABLE IF NOT EXISTS import_reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    source TEXT NOT NULL,
+    created INTEGER NOT NULL DEFAULT 0,
+    updated INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    report TEXT NOT NULL DEFAULT '{}',
+    user_id INTEGER NOT NULL DEFAULT 0,
+    workspace_id INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_reports_workspace ON import_reports(workspace_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_import_reports_created ON import_reports(created_at);
+`
+
+const addGitHubRateLimitSQL = `
+-- The GitHub API quota left after the last gist sync, as JSON, so the
+-- settings page can show it between syncs
+ALTER TABLE gist_sync_config ADD COLUMN rate_limit TEXT DEFAULT NULL;
+`
+
+const addGistBaseVersionSQL = `
+-- The snippet as last synced with its gist, as JSON, so edits made on both
+-- sides since can be merged against it
+ALTER TABLE snippet_gist_mappings ADD COLUMN base_version TEXT DEFAULT NULL;
+`
+
 // getMigrations returns all available migrations in order
 func getMigrations() []Migration {
 	return []Migration{
@@ -373,5 +888,35 @@ func getMigrations() []Migration {
 		{Version: 9, Name: "add_gist_sync", SQL: addGistSyncSQL},
 		{Version: 10, Name: "add_soft_delete", SQL: addSoftDeleteSQL},
 		{Version: 11, Name: "add_snippet_expiration", SQL: addExpirationSQL},
+		{Version: 12, Name: "add_publish_at", SQL: addPublishAtSQL},
+		{Version: 13, Name: "add_provenance", SQL: addProvenanceSQL},
+		{Version: 14, Name: "add_remote_sources", SQL: addRemoteSourcesSQL},
+		{Version: 15, Name: "add_usage_tracking", SQL: addUsageTrackingSQL},
+		{Version: 16, Name: "add_public_views", SQL: addPublicViewsSQL},
+		{Version: 17, Name: "add_abuse_reports", SQL: addAbuseReportsSQL},
+		{Version: 18, Name: "add_markdown_html_policy", SQL: addMarkdownHTMLPolicySQL},
+		{Version: 19, Name: "add_token_scopes", SQL: addTokenScopesSQL},
+		{Version: 20, Name: "add_session_remember", SQL: addSessionRememberSQL},
+		{Version: 21, Name: "add_key_value_settings", SQL: addKeyValueSettingsSQL},
+		{Version: 22, Name: "add_login_insights", SQL: addLoginInsightsSQL},
+		{Version: 23, Name: "add_snippet_file_notes", SQL: addSnippetFileNotesSQL},
+		{Version: 24, Name: "add_content_hash", SQL: addContentHashSQL},
+		{Version: 25, Name: "rebuild_snippets_fts", SQL: rebuildSnippetsFTSSQL},
+		{Version: 26, Name: "add_inbound_webhooks", SQL: addInboundWebhooksSQL},
+		{Version: 27, Name: "add_bot_config", SQL: addBotConfigSQL},
+		{Version: 28, Name: "add_users", SQL: addUsersSQL, RebuildsTables: true},
+		{Version: 29, Name: "add_snippet_external_id", SQL: addSnippetExternalIDSQL},
+		{Version: 30, Name: "add_workspaces", SQL: addWorkspacesSQL, RebuildsTables: true},
+		{Version: 31, Name: "add_token_usage", SQL: addTokenUsageSQL},
+		{Version: 32, Name: "add_workspace_roles", SQL: addWorkspaceRolesSQL},
+		{Version: 33, Name: "add_folder_access", SQL: addFolderAccessSQL},
+		{Version: 34, Name: "add_activity", SQL: addActivitySQL},
+		{Version: 35, Name: "add_job_runs", SQL: addJobRunsSQL},
+		{Version: 36, Name: "add_public_tokens", SQL: addPublicTokensSQL},
+		{Version: 37, Name: "add_usage_counts", SQL: addUsageCountsSQL},
+		{Version: 38, Name: "add_tag_folder_trash", SQL: addTagFolderTrashSQL},
+		{Version: 39, Name: "add_import_reports", SQL: addImportReportsSQL},
+		{Version: 40, Name: "add_github_rate_limit", SQL: addGitHubRateLimitSQL},
+		{Version: 41, Name: "add_gist_base_version", SQL: addGistBaseVersionSQL},
 	}
 }
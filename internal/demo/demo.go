@@ -13,14 +13,14 @@ import (
 // Service handles demo mode functionality
 type Service struct {
 	db             *sql.DB
-	snippetService *services.SnippetService
+	snippetService services.SnippetManager
 	logger         *slog.Logger
 	resetInterval  time.Duration
 	enabled        bool
 }
 
 // NewService creates a new demo service
-func NewService(db *sql.DB, snippetService *services.SnippetService, logger *slog.Logger, resetInterval time.Duration, enabled bool) *Service {
+func NewService(db *sql.DB, snippetService services.SnippetManager, logger *slog.Logger, resetInterval time.Duration, enabled bool) *Service {
 	return &Service{
 		db:             db,
 		snippetService: snippetService,
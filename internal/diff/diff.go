@@ -0,0 +1,241 @@
+// Package diff computes line diffs between two texts and formats them as
+// unified diffs, as shown by `diff -u` and git.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change
+const DefaultContext = 3
+
+// maxEdits bounds the work spent on very different texts; past it the
+// changed region is reported as a whole replacement
+const maxEdits = 2000
+
+type op int
+
+const (
+	opEqual op = iota
+	opDelete
+	opInsert
+)
+
+// edit is one line of the diff. a and b are the line's positions in the old
+// and new text; for inserts a is the position in the old text the line goes
+// before, and likewise b for deletes.
+type edit struct {
+	op   op
+	a, b int
+}
+
+// Unified returns a unified diff turning from into to, or "" when they have
+// the same lines. fromName and toName label the --- and +++ headers.
+func Unified(fromName, toName, from, to string, context int) string {
+	a, b := splitLines(from), splitLines(to)
+	edits := lineEdits(a, b)
+
+	hunks := groupHunks(edits, context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range hunks {
+		writeHunk(&sb, h, a, b)
+	}
+	return sb.String()
+}
+
+// splitLines splits text into lines, ignoring a final newline
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// lineEdits returns the edits turning a into b. The common prefix and suffix
+// are matched directly so typical small changes to long texts stay cheap.
+func lineEdits(a, b []string) []edit {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	edits := make([]edit, 0, len(a)+len(b))
+	for i := 0; i < pre; i++ {
+		edits = append(edits, edit{opEqual, i, i})
+	}
+	edits = append(edits, myers(a[pre:len(a)-suf], b[pre:len(b)-suf], pre, pre)...)
+	for i := suf; i > 0; i-- {
+		edits = append(edits, edit{opEqual, len(a) - i, len(b) - i})
+	}
+	return edits
+}
+
+// myers finds a shortest edit script with Myers' O(ND) algorithm. offA and
+// offB are added to the positions it returns.
+func myers(a, b []string, offA, offB int) []edit {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replaceAll(n, m, offA, offB)
+	}
+
+	maxD := n + m
+	off := maxD + 1
+	v := make([]int, 2*maxD+3)
+	// trace[d] holds v[-d-1..d+1] as it was before step d
+	var trace [][]int
+
+	for d := 0; d <= maxD; d++ {
+		if d > maxEdits {
+			return replaceAll(n, m, offA, offB)
+		}
+		trace = append(trace, append([]int(nil), v[off-d-1:off+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m, offA, offB)
+			}
+		}
+	}
+	return replaceAll(n, m, offA, offB)
+}
+
+// backtrack walks the saved states back from (n, m) to recover the edits
+func backtrack(trace [][]int, n, m, offA, offB int) []edit {
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		if d == 0 {
+			prevX, prevY = 0, 0
+		}
+
+		for x > prevX && y > prevY {
+			edits = append(edits, edit{opEqual, x - 1 + offA, y - 1 + offB})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, edit{opInsert, x + offA, y - 1 + offB})
+			} else {
+				edits = append(edits, edit{opDelete, x - 1 + offA, y + offB})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// replaceAll deletes every line of a and inserts every line of b
+func replaceAll(n, m, offA, offB int) []edit {
+	edits := make([]edit, 0, n+m)
+	for i := 0; i < n; i++ {
+		edits = append(edits, edit{opDelete, offA + i, offB})
+	}
+	for j := 0; j < m; j++ {
+		edits = append(edits, edit{opInsert, offA + n, offB + j})
+	}
+	return edits
+}
+
+// groupHunks splits edits into hunks of changes with up to context unchanged
+// lines around them, merging changes that are close together
+func groupHunks(edits []edit, context int) [][]edit {
+	if context < 0 {
+		context = 0
+	}
+
+	var hunks [][]edit
+	start, end := -1, -1 // Current hunk is edits[start:end]
+	for i, e := range edits {
+		if e.op == opEqual {
+			continue
+		}
+		lo := max(i-context, 0)
+		if start >= 0 && lo <= end {
+			end = min(i+context+1, len(edits))
+			continue
+		}
+		if start >= 0 {
+			hunks = append(hunks, edits[start:end])
+		}
+		start, end = lo, min(i+context+1, len(edits))
+	}
+	if start >= 0 {
+		hunks = append(hunks, edits[start:end])
+	}
+	return hunks
+}
+
+func writeHunk(sb *strings.Builder, hunk []edit, a, b []string) {
+	var aLen, bLen int
+	for _, e := range hunk {
+		if e.op != opInsert {
+			aLen++
+		}
+		if e.op != opDelete {
+			bLen++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(hunk[0].a, aLen), hunkRange(hunk[0].b, bLen))
+
+	for _, e := range hunk {
+		switch e.op {
+		case opEqual:
+			sb.WriteString(" " + a[e.a] + "\n")
+		case opDelete:
+			sb.WriteString("-" + a[e.a] + "\n")
+		case opInsert:
+			sb.WriteString("+" + b[e.b] + "\n")
+		}
+	}
+}
+
+// hunkRange formats a hunk header range; an empty range names the line
+// before it, as diff -u does
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
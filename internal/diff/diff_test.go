@@ -0,0 +1,89 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+	}{
+		{
+			name: "identical",
+			from: "a\nb\n",
+			to:   "a\nb\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			from: "one\ntwo\nthree\n",
+			to:   "one\n2\nthree\n",
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		},
+		{
+			name: "added file",
+			from: "",
+			to:   "x\ny\n",
+			want: "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			name: "removed line",
+			from: "x\ny\n",
+			to:   "x\n",
+			want: "--- a\n+++ b\n@@ -1,2 +1 @@\n x\n-y\n",
+		},
+		{
+			name: "interleaved",
+			from: "a\nb\nc\nd\n",
+			to:   "a\nc\nd\ne\n",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n a\n-b\n c\n d\n+e\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("a", "b", tt.from, tt.to, DefaultContext); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnified_SeparateHunks(t *testing.T) {
+	var from, to []string
+	for i := 0; i < 20; i++ {
+		line := string(rune('a' + i))
+		from = append(from, line)
+		to = append(to, line)
+	}
+	to[1] = "B"
+	to[18] = "S"
+
+	got := Unified("a", "b", strings.Join(from, "\n"), strings.Join(to, "\n"), 1)
+	want := "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n@@ -18,3 +18,3 @@\n r\n-s\n+S\n t\n"
+	if got != want {
+		t.Errorf("Unified() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnified_AppliesCleanly(t *testing.T) {
+	from := "func main() {\n\tfmt.Println(1)\n\tfmt.Println(2)\n}\n"
+	to := "package main\n\nfunc main() {\n\tfmt.Println(2)\n\tfmt.Println(3)\n}\n"
+
+	got := Unified("a", "b", from, to, 0)
+	var rebuilt, original []string
+	for _, line := range strings.Split(got, "\n") {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "@@"):
+		case strings.HasPrefix(line, "-"):
+			original = append(original, line[1:])
+		case strings.HasPrefix(line, "+"):
+			rebuilt = append(rebuilt, line[1:])
+		}
+	}
+	if strings.Join(original, "|") != "\tfmt.Println(1)" || strings.Join(rebuilt, "|") != "package main||\tfmt.Println(3)" {
+		t.Errorf("unexpected minimal diff:\n%s", got)
+	}
+}
@@ -0,0 +1,145 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+)
+
+// change replaces base lines [start, end) with lines. An insertion has
+// start == end.
+type change struct {
+	start, end int
+	lines      []string
+}
+
+// Merge3 merges the edits turning base into ours and base into theirs, line
+// by line as diff3 does. ok is false when the edits overlap: both sides
+// changed the same lines differently, or inserted different lines at the
+// same place or right next to a change of the other side. Identical edits
+// on both sides are taken once.
+func Merge3(base, ours, theirs string) (merged string, ok bool) {
+	switch {
+	case ours == theirs || theirs == base:
+		return ours, true
+	case ours == base:
+		return theirs, true
+	}
+
+	b := splitLines(base)
+	oursChanges := changes(b, splitLines(ours))
+	theirsChanges := changes(b, splitLines(theirs))
+
+	var out []string
+	pos := 0 // Next base line to copy
+	i, j := 0, 0
+	for i < len(oursChanges) || j < len(theirsChanges) {
+		// Start a run with the side whose next change comes first, then take
+		// in every change touching the run, which may grow it
+		var fromOurs, fromTheirs []change
+		var start, end int
+		if j == len(theirsChanges) || (i < len(oursChanges) && oursChanges[i].start <= theirsChanges[j].start) {
+			fromOurs = append(fromOurs, oursChanges[i])
+			start, end = oursChanges[i].start, oursChanges[i].end
+			i++
+		} else {
+			fromTheirs = append(fromTheirs, theirsChanges[j])
+			start, end = theirsChanges[j].start, theirsChanges[j].end
+			j++
+		}
+		for {
+			if i < len(oursChanges) && touches(oursChanges[i], start, end) {
+				fromOurs = append(fromOurs, oursChanges[i])
+				start, end = min(start, oursChanges[i].start), max(end, oursChanges[i].end)
+				i++
+			} else if j < len(theirsChanges) && touches(theirsChanges[j], start, end) {
+				fromTheirs = append(fromTheirs, theirsChanges[j])
+				start, end = min(start, theirsChanges[j].start), max(end, theirsChanges[j].end)
+				j++
+			} else {
+				break
+			}
+		}
+
+		out = append(out, b[pos:start]...)
+		oursLines := apply(b, start, end, fromOurs)
+		theirsLines := apply(b, start, end, fromTheirs)
+		switch {
+		case len(fromTheirs) == 0:
+			out = append(out, oursLines...)
+		case len(fromOurs) == 0:
+			out = append(out, theirsLines...)
+		case slices.Equal(oursLines, theirsLines):
+			out = append(out, oursLines...)
+		default:
+			return "", false
+		}
+		pos = end
+	}
+	out = append(out, b[pos:]...)
+
+	// The final newline is kept unless a side changed it
+	newline := strings.HasSuffix(theirs, "\n")
+	if strings.HasSuffix(ours, "\n") != strings.HasSuffix(base, "\n") {
+		newline = strings.HasSuffix(ours, "\n")
+	}
+	if len(out) == 0 {
+		return "", true
+	}
+	merged = strings.Join(out, "\n")
+	if newline {
+		merged += "\n"
+	}
+	return merged, true
+}
+
+// touches reports whether c overlaps or borders the base lines [start, end).
+// Changes that only border each other still conflict when one of them is an
+// insertion, as the order of the lines would be a guess.
+func touches(c change, start, end int) bool {
+	if c.start < end && start < c.end {
+		return true
+	}
+	insertion := c.start == c.end || start == end
+	return insertion && c.start <= end && start <= c.end
+}
+
+// apply returns base lines [start, end) with changes, which all fall within
+// them, applied
+func apply(base []string, start, end int, changes []change) []string {
+	var out []string
+	pos := start
+	for _, c := range changes {
+		out = append(out, base[pos:c.start]...)
+		out = append(out, c.lines...)
+		pos = c.end
+	}
+	return append(out, base[pos:end]...)
+}
+
+// changes groups the line edits turning base into other into changes, in
+// base order
+func changes(base, other []string) []change {
+	var out []change
+	var cur *change
+	for _, e := range lineEdits(base, other) {
+		if e.op == opEqual {
+			if cur != nil {
+				out = append(out, *cur)
+				cur = nil
+			}
+			continue
+		}
+		if cur == nil {
+			cur = &change{start: e.a, end: e.a}
+		}
+		if e.op == opDelete {
+			cur.end = e.a + 1
+		} else {
+			cur.lines = append(cur.lines, other[e.b])
+		}
+	}
+	if cur != nil {
+		out = append(out, *cur)
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package diff
+
+import "testing"
+
+func TestMerge3(t *testing.T) {
+	const base = "one\ntwo\nthree\nfour\nfive\nsix\nseven\n"
+	tests := []struct {
+		name         string
+		ours, theirs string
+		want         string
+		conflict     bool
+	}{
+		{
+			name:   "one side changed",
+			ours:   base,
+			theirs: "one\n2\nthree\nfour\nfive\nsix\nseven\n",
+			want:   "one\n2\nthree\nfour\nfive\nsix\nseven\n",
+		},
+		{
+			name:   "separate lines",
+			ours:   "one\n2\nthree\nfour\nfive\nsix\nseven\n",
+			theirs: "one\ntwo\nthree\nfour\nfive\n6\nseven\n",
+			want:   "one\n2\nthree\nfour\nfive\n6\nseven\n",
+		},
+		{
+			name:   "insertions and deletions",
+			ours:   "zero\none\ntwo\nthree\nfour\nfive\nsix\nseven\n",
+			theirs: "one\ntwo\nthree\nfour\nsix\nseven\neight\n",
+			want:   "zero\none\ntwo\nthree\nfour\nsix\nseven\neight\n",
+		},
+		{
+			name:   "adjacent lines",
+			ours:   "one\n2\nthree\nfour\nfive\nsix\nseven\n",
+			theirs: "one\ntwo\n3\nfour\nfive\nsix\nseven\n",
+			want:   "one\n2\n3\nfour\nfive\nsix\nseven\n",
+		},
+		{
+			name:   "same edit on both sides",
+			ours:   "one\n2\nthree\nfour\nfive\nsix\nseven\n",
+			theirs: "one\n2\nthree\nfour\nfive\n6\nseven\n",
+			want:   "one\n2\nthree\nfour\nfive\n6\nseven\n",
+		},
+		{
+			name:     "same line changed differently",
+			ours:     "one\n2\nthree\nfour\nfive\nsix\nseven\n",
+			theirs:   "one\nTWO\nthree\nfour\nfive\nsix\nseven\n",
+			conflict: true,
+		},
+		{
+			name:     "overlapping ranges",
+			ours:     "one\n2\n3\nfour\nfive\nsix\nseven\n",
+			theirs:   "one\ntwo\nTHREE\nFOUR\nfive\nsix\nseven\n",
+			conflict: true,
+		},
+		{
+			name:     "insertions at the same place",
+			ours:     "one\ntwo\nthree\nours\nfour\nfive\nsix\nseven\n",
+			theirs:   "one\ntwo\nthree\ntheirs\nfour\nfive\nsix\nseven\n",
+			conflict: true,
+		},
+		{
+			name:     "insertion next to a change",
+			ours:     "one\ntwo\nthree\nadded\nfour\nfive\nsix\nseven\n",
+			theirs:   "one\ntwo\nthree\n4\nfive\nsix\nseven\n",
+			conflict: true,
+		},
+		{
+			name:     "deleted on one side, changed on the other",
+			ours:     "one\ntwo\nfour\nfive\nsix\nseven\n",
+			theirs:   "one\ntwo\n3\nfour\nfive\nsix\nseven\n",
+			conflict: true,
+		},
+		{
+			name:   "final newline removed",
+			ours:   "one\n2\nthree\nfour\nfive\nsix\nseven\n",
+			theirs: "one\ntwo\nthree\nfour\nfive\nsix\nseven",
+			want:   "one\n2\nthree\nfour\nfive\nsix\nseven",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Merge3(base, tt.ours, tt.theirs)
+			if ok == tt.conflict {
+				t.Fatalf("Merge3() ok = %v, want %v (got %q)", ok, !tt.conflict, got)
+			}
+			if ok && got != tt.want {
+				t.Errorf("Merge3() =\n%q\nwant\n%q", got, tt.want)
+			}
+			if !tt.conflict {
+				// Merging is symmetric
+				if swapped, _ := Merge3(base, tt.theirs, tt.ours); swapped != got {
+					t.Errorf("Merge3() with sides swapped =\n%q\nwant\n%q", swapped, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMerge3_EmptyBase(t *testing.T) {
+	if got, ok := Merge3("", "a\n", "a\n"); !ok || got != "a\n" {
+		t.Errorf("expected the same new file to merge, got %q %v", got, ok)
+	}
+	if _, ok := Merge3("", "a\n", "b\n"); ok {
+		t.Error("expected different new files to conflict")
+	}
+}
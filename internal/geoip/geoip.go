@@ -0,0 +1,338 @@
+// Package geoip resolves IP addresses to countries using a local MaxMind DB
+// (MMDB) file such as GeoLite2-Country or DB-IP's free country database.
+// Only the parts of the format needed for country lookups are implemented.
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+)
+
+// metadataMarker precedes the metadata section at the end of the file
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// ErrInvalidDatabase is returned for files that aren't MMDB databases
+var ErrInvalidDatabase = errors.New("invalid MaxMind database")
+
+// Reader looks up countries in an MMDB file loaded into memory
+type Reader struct {
+	tree       []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint // Node where IPv4 addresses start in an IPv6 tree
+}
+
+// Open loads an MMDB file
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GeoIP database: %w", err)
+	}
+	return New(buf)
+}
+
+// New parses an MMDB database from buf
+func New(buf []byte) (*Reader, error) {
+	i := bytes.LastIndex(buf, metadataMarker)
+	if i < 0 {
+		return nil, ErrInvalidDatabase
+	}
+	metaStart := i + len(metadataMarker)
+	meta, _, err := (&decoder{buf: buf[metaStart:]}).decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDatabase, err)
+	}
+	m, ok := meta.(map[string]any)
+	if !ok {
+		return nil, ErrInvalidDatabase
+	}
+
+	r := &Reader{
+		nodeCount:  toUint(m["node_count"]),
+		recordSize: toUint(m["record_size"]),
+		ipVersion:  toUint(m["ip_version"]),
+	}
+	switch r.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalidDatabase, r.recordSize)
+	}
+
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+16 > uint(i) {
+		return nil, ErrInvalidDatabase
+	}
+	r.tree = buf[:treeSize]
+	r.data = buf[treeSize+16 : i]
+
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for n := 0; n < 96 && node < r.nodeCount; n++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country an address is
+// located in, or "" if the database doesn't know it
+func (r *Reader) Country(ip string) (string, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", fmt.Errorf("invalid IP address %q: %w", ip, err)
+	}
+	record, err := r.lookup(addr.Unmap())
+	if err != nil || record == nil {
+		return "", err
+	}
+
+	m, _ := record.(map[string]any)
+	for _, field := range []string{"country", "registered_country"} {
+		if country, ok := m[field].(map[string]any); ok {
+			if code, ok := country["iso_code"].(string); ok {
+				return code, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// lookup walks the search tree and decodes the record for addr
+func (r *Reader) lookup(addr netip.Addr) (any, error) {
+	var bits []byte
+	node := uint(0)
+	if addr.Is4() {
+		b := addr.As4()
+		bits = b[:]
+		if r.ipVersion == 6 {
+			node = r.ipv4Start
+		}
+	} else {
+		if r.ipVersion == 4 {
+			return nil, nil
+		}
+		b := addr.As16()
+		bits = b[:]
+	}
+
+	for i := 0; i < len(bits)*8 && node < r.nodeCount; i++ {
+		bit := uint(bits[i/8]>>(7-i%8)) & 1
+		node = r.record(node, bit)
+	}
+	if node <= r.nodeCount {
+		return nil, nil // Not found
+	}
+
+	offset := node - r.nodeCount - 16
+	value, _, err := (&decoder{buf: r.data}).decode(offset)
+	return value, err
+}
+
+// record returns the left (bit 0) or right (bit 1) record of a tree node
+func (r *Reader) record(node, bit uint) uint {
+	b := r.tree[node*r.recordSize/4:]
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// decoder decodes values from an MMDB data section
+type decoder struct {
+	buf []byte
+}
+
+// Data section types
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+var errTruncated = errors.New("truncated data")
+
+func (d *decoder) read(offset, n uint) ([]byte, error) {
+	if offset+n > uint(len(d.buf)) {
+		return nil, errTruncated
+	}
+	return d.buf[offset : offset+n], nil
+}
+
+// decode returns the value at offset and the offset following it
+func (d *decoder) decode(offset uint) (any, uint, error) {
+	ctrl, err := d.read(offset, 1)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset++
+	typ := uint(ctrl[0] >> 5)
+
+	if typ == typePointer {
+		target, next, err := d.pointer(ctrl[0], offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(target)
+		return value, next, err
+	}
+
+	if typ == typeExtended {
+		ext, err := d.read(offset, 1)
+		if err != nil {
+			return nil, 0, err
+		}
+		typ = 7 + uint(ext[0])
+		offset++
+	}
+
+	size := uint(ctrl[0] & 0x1F)
+	if size >= 29 {
+		extra := size - 28
+		b, err := d.read(offset, extra)
+		if err != nil {
+			return nil, 0, err
+		}
+		offset += extra
+		n := uint(0)
+		for _, c := range b {
+			n = n<<8 | uint(c)
+		}
+		switch size {
+		case 29:
+			size = 29 + n
+		case 30:
+			size = 285 + n
+		default:
+			size = 65821 + n
+		}
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+			value, next, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[k] = value
+			offset = next
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	case typeContainer, typeEndMarker:
+		return nil, offset, nil
+	}
+
+	b, err := d.read(offset, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset += size
+	switch typ {
+	case typeString:
+		return string(b), offset, nil
+	case typeBytes, typeUint128:
+		return append([]byte(nil), b...), offset, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errors.New("invalid double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errors.New("invalid float size")
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(b)), offset, nil
+	case typeUint16, typeUint32, typeUint64:
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, offset, nil
+	case typeInt32:
+		var n uint32
+		for _, c := range b {
+			n = n<<8 | uint32(c)
+		}
+		return int32(n), offset, nil
+	}
+	return nil, 0, fmt.Errorf("unknown data type %d", typ)
+}
+
+// pointer decodes a pointer whose control byte is ctrl
+func (d *decoder) pointer(ctrl byte, offset uint) (target, next uint, err error) {
+	size := uint(ctrl>>3)&0x3 + 1
+	b, err := d.read(offset, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	n := uint(0)
+	if size < 4 {
+		n = uint(ctrl & 0x7)
+	}
+	for _, c := range b {
+		n = n<<8 | uint(c)
+	}
+	switch size {
+	case 2:
+		n += 2048
+	case 3:
+		n += 526336
+	}
+	return n, offset + size, nil
+}
+
+func toUint(v any) uint {
+	n, _ := v.(uint64)
+	return uint(n)
+}
@@ -0,0 +1,143 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// encodeString encodes a short MMDB string
+func encodeString(s string) []byte {
+	return append([]byte{byte(typeString<<5 | len(s))}, s...)
+}
+
+// encodeUint encodes n as an MMDB uint16/uint32
+func encodeUint(typ int, n uint32) []byte {
+	b := binary.BigEndian.AppendUint32(nil, n)
+	b = bytes.TrimLeft(b, "\x00")
+	return append([]byte{byte(typ<<5 | len(b))}, b...)
+}
+
+// encodeMap encodes a map with sorted keys and pre-encoded values
+func encodeMap(m map[string][]byte) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := []byte{byte(typeMap<<5 | len(m))}
+	for _, k := range keys {
+		out = append(out, encodeString(k)...)
+		out = append(out, m[k]...)
+	}
+	return out
+}
+
+// buildDatabase writes an IPv4 MMDB with 24-bit records mapping networks to
+// country codes
+func buildDatabase(t *testing.T, networks map[string]string) []byte {
+	t.Helper()
+
+	type node struct{ children [2]int } // -1 empty, >=0 node, <= -2 data index
+	nodes := []node{{children: [2]int{-1, -1}}}
+	var data []byte
+	dataOffsets := map[string]int{}
+
+	for prefix, country := range networks {
+		p := netip.MustParsePrefix(prefix)
+		if _, ok := dataOffsets[country]; !ok {
+			dataOffsets[country] = len(data)
+			data = append(data, encodeMap(map[string][]byte{
+				"country": encodeMap(map[string][]byte{"iso_code": encodeString(country)}),
+			})...)
+		}
+		ip := p.Addr().As4()
+		current := 0
+		for i := 0; i < p.Bits(); i++ {
+			bit := int(ip[i/8]>>(7-i%8)) & 1
+			if i == p.Bits()-1 {
+				nodes[current].children[bit] = -2 - dataOffsets[country]
+				break
+			}
+			if nodes[current].children[bit] < 0 {
+				nodes = append(nodes, node{children: [2]int{-1, -1}})
+				nodes[current].children[bit] = len(nodes) - 1
+			}
+			current = nodes[current].children[bit]
+		}
+	}
+
+	nodeCount := len(nodes)
+	var tree []byte
+	for _, n := range nodes {
+		for _, child := range n.children {
+			value := nodeCount // Not found
+			switch {
+			case child >= 0:
+				value = child
+			case child <= -2:
+				value = nodeCount + 16 + (-2 - child)
+			}
+			tree = append(tree, byte(value>>16), byte(value>>8), byte(value))
+		}
+	}
+
+	out := append(tree, make([]byte, 16)...)
+	out = append(out, data...)
+	out = append(out, metadataMarker...)
+	out = append(out, encodeMap(map[string][]byte{
+		"node_count":    encodeUint(typeUint32, uint32(nodeCount)),
+		"record_size":   encodeUint(typeUint16, 24),
+		"ip_version":    encodeUint(typeUint16, 4),
+		"database_type": encodeString("Test-Country"),
+	})...)
+	return out
+}
+
+func TestReader_Country(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	db := buildDatabase(t, map[string]string{
+		"81.2.69.0/24":  "GB",
+		"89.160.0.0/16": "SE",
+	})
+	if err := os.WriteFile(path, db, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"81.2.69.142", "GB"},
+		{"89.160.20.112", "SE"},
+		{"::ffff:81.2.69.1", "GB"},
+		{"81.2.70.1", ""},
+		{"10.0.0.1", ""},
+		{"2001:db8::1", ""},
+	}
+	for _, tt := range tests {
+		got, err := r.Country(tt.ip)
+		if err != nil {
+			t.Errorf("Country(%s) failed: %v", tt.ip, err)
+		}
+		if got != tt.want {
+			t.Errorf("Country(%s) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+
+	if _, err := r.Country("not-an-ip"); err == nil {
+		t.Error("expected an error for an invalid address")
+	}
+	if _, err := New([]byte("not a database")); err == nil {
+		t.Error("expected an error for an invalid database")
+	}
+}
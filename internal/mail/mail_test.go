@@ -0,0 +1,61 @@
+package mail
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"strings"
+	"testing"
+)
+
+func TestMessageBytes(t *testing.T) {
+	msg := &Message{
+		To:      []string{"a@example.com", "b@example.com"},
+		Subject: "Snipo digest: café",
+		Text:    "Plain " + strings.Repeat("long ", 30),
+		HTML:    "<p>Plain</p>",
+	}
+	data, err := msg.Bytes("Snipo <snipo@example.com>")
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+
+	parsed, err := netmail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("ReadMessage failed: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("expected subject %q, got %q (%v)", msg.Subject, subject, err)
+	}
+	if to, err := parsed.Header.AddressList("To"); err != nil || len(to) != 2 {
+		t.Errorf("expected 2 recipients, got %v (%v)", to, err)
+	}
+	if id := parsed.Header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("expected a Message-ID in the sender's domain, got %q", id)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("expected multipart/alternative, got %q (%v)", mediaType, err)
+	}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		part, err := reader.NextRawPart()
+		if err != nil {
+			t.Fatalf("NextRawPart failed: %v", err)
+		}
+		if part.Header.Get("Content-Type") != want.contentType {
+			t.Errorf("expected %s, got %s", want.contentType, part.Header.Get("Content-Type"))
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil || string(body) != want.body {
+			t.Errorf("expected body %q, got %q (%v)", want.body, body, err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Package markdown renders markdown snippets to HTML for public pages.
+// Raw HTML embedded in the markdown is handled according to a policy so a
+// shared snippet cannot run scripts in a visitor's browser.
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// Options adjusts the HTML Render produces
+type Options struct {
+	LineNumbers bool // Prefix every line of a code block with its number
+	HeadingIDs  bool // Give headings id attributes so they can be linked to
+}
+
+// rendererKey picks one of the prebuilt renderers
+type rendererKey struct {
+	unsafe bool // Pass raw HTML through, to be sanitized or trusted afterwards
+	Options
+}
+
+var (
+	// renderers holds a renderer for every combination of options, since
+	// goldmark instances are safe to share but costly to build per request
+	renderers = newRenderers()
+
+	sanitizer = newSanitizer()
+)
+
+func newRenderers() map[rendererKey]goldmark.Markdown {
+	renderers := make(map[rendererKey]goldmark.Markdown)
+	for _, unsafe := range []bool{false, true} {
+		for _, lineNumbers := range []bool{false, true} {
+			for _, headingIDs := range []bool{false, true} {
+				key := rendererKey{unsafe: unsafe, Options: Options{LineNumbers: lineNumbers, HeadingIDs: headingIDs}}
+				renderers[key] = newRenderer(key)
+			}
+		}
+	}
+	return renderers
+}
+
+func newRenderer(key rendererKey) goldmark.Markdown {
+	opts := []goldmark.Option{goldmark.WithExtensions(extension.GFM)}
+	if key.unsafe {
+		opts = append(opts, goldmark.WithRendererOptions(html.WithUnsafe()))
+	}
+	if key.HeadingIDs {
+		opts = append(opts, goldmark.WithParserOptions(parser.WithAutoHeadingID()))
+	}
+	if key.LineNumbers {
+		opts = append(opts, goldmark.WithRendererOptions(renderer.WithNodeRenderers(
+			util.Prioritized(&lineNumberRenderer{}, 100),
+		)))
+	}
+	return goldmark.New(opts...)
+}
+
+// newSanitizer builds a policy for user-generated content that also keeps
+// the markup GitHub-flavored markdown produces for code blocks and task lists
+func newSanitizer() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^line-number$`)).OnElements("span")
+	return p
+}
+
+// Render converts markdown to HTML. Raw HTML is sanitized, dropped or kept
+// depending on policy; an empty or unknown policy is treated as sanitize.
+func Render(source, policy string) (string, error) {
+	return RenderWithOptions(source, policy, Options{})
+}
+
+// RenderWithOptions is Render with control over line numbers and heading IDs
+func RenderWithOptions(source, policy string, opts Options) (string, error) {
+	md := renderers[rendererKey{unsafe: policy != models.MarkdownHTMLEscape, Options: opts}]
+
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(source), &buf); err != nil {
+		return "", fmt.Errorf("failed to render markdown: %w", err)
+	}
+
+	switch policy {
+	case models.MarkdownHTMLEscape, models.MarkdownHTMLAllow:
+		return buf.String(), nil
+	default:
+		return sanitizer.Sanitize(buf.String()), nil
+	}
+}
+
+// lineNumberRenderer renders code blocks like goldmark does, with every
+// line prefixed by a <span class="line-number">. The numbers are plain text
+// so they show even where stylesheets are stripped, as in e-mail.
+type lineNumberRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r *lineNumberRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindCodeBlock, r.renderCodeBlock)
+	reg.Register(ast.KindFencedCodeBlock, r.renderCodeBlock)
+}
+
+func (r *lineNumberRenderer) renderCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("</code></pre>\n")
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = w.WriteString("<pre><code")
+	if fenced, ok := node.(*ast.FencedCodeBlock); ok {
+		if language := fenced.Language(source); language != nil {
+			_, _ = w.WriteString(` class="language-`)
+			html.DefaultWriter.Write(w, language)
+			_ = w.WriteByte('"')
+		}
+	}
+	_ = w.WriteByte('>')
+
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		_, _ = fmt.Fprintf(w, `<span class="line-number">%d</span>`, i+1)
+		html.DefaultWriter.RawWrite(w, line.Value(source))
+	}
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,90 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+func TestRender(t *testing.T) {
+	source := "# Title\n\n<script>alert(1)</script>\n\n<b onclick=\"steal()\">bold</b> [link](javascript:alert(1))\n\n```go\nfmt.Println(1)\n```\n\n- [x] done\n"
+
+	tests := []struct {
+		name     string
+		policy   string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "sanitize",
+			policy:   models.MarkdownHTMLSanitize,
+			contains: []string{"<h1", "Title", "<b>bold</b>", `class="language-go"`, `type="checkbox"`},
+			excludes: []string{"<script", "onclick", "javascript:"},
+		},
+		{
+			name:     "empty policy sanitizes",
+			policy:   "",
+			contains: []string{"<b>bold</b>"},
+			excludes: []string{"<script", "onclick"},
+		},
+		{
+			name:     "escape",
+			policy:   models.MarkdownHTMLEscape,
+			contains: []string{"<h1", "raw HTML omitted"},
+			excludes: []string{"<script", "onclick", "<b", "javascript:"},
+		},
+		{
+			name:     "allow",
+			policy:   models.MarkdownHTMLAllow,
+			contains: []string{"<script>alert(1)</script>", `onclick="steal()"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Render(source, tt.policy)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(out, want) {
+					t.Errorf("expected output to contain %q, got:\n%s", want, out)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(out, unwanted) {
+					t.Errorf("expected output not to contain %q, got:\n%s", unwanted, out)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderWithOptions(t *testing.T) {
+	source := "## Getting Started\n\n```go\nx := 1\nfmt.Println(x < 2)\n```\n"
+
+	for _, policy := range []string{models.MarkdownHTMLSanitize, models.MarkdownHTMLEscape} {
+		plain, err := RenderWithOptions(source, policy, Options{})
+		if err != nil {
+			t.Fatalf("RenderWithOptions failed: %v", err)
+		}
+		if strings.Contains(plain, "id=") || strings.Contains(plain, "line-number") {
+			t.Errorf("%s: expected no IDs or line numbers by default, got:\n%s", policy, plain)
+		}
+
+		out, err := RenderWithOptions(source, policy, Options{LineNumbers: true, HeadingIDs: true})
+		if err != nil {
+			t.Fatalf("RenderWithOptions failed: %v", err)
+		}
+		for _, want := range []string{
+			`<h2 id="getting-started">`,
+			`<code class="language-go"><span class="line-number">1</span>x := 1`,
+			`<span class="line-number">2</span>fmt.Println(x &lt; 2)`,
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: expected output to contain %q, got:\n%s", policy, want, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,296 @@
+// Package metrics collects the counters, histograms and gauges served on
+// /metrics and writes them in the Prometheus text exposition format. It
+// implements only what snipo exports rather than depending on the Prometheus
+// client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram upper bounds in seconds, matching the
+// Prometheus client defaults
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds every metric in registration order. It is safe for
+// concurrent use.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	byName  map[string]metric
+}
+
+type metric interface {
+	id() string
+	write(w io.Writer) error
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{byName: make(map[string]metric)}
+}
+
+// register adds m unless a metric with its name exists, returning the one kept
+func (r *Registry) register(m metric) metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.byName[m.id()]; ok {
+		return existing
+	}
+	r.byName[m.id()] = m
+	r.metrics = append(r.metrics, m)
+	return m
+}
+
+// Counter returns the counter named name, registering it on first use
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{name: name, help: help, labels: labels}, values: make(map[string]*counterValue)}
+	return r.register(c).(*CounterVec)
+}
+
+// Histogram returns the histogram named name, registering it on first use
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{desc: desc{name: name, help: help, labels: labels}, buckets: buckets, values: make(map[string]*histogramValue)}
+	return r.register(h).(*HistogramVec)
+}
+
+// GaugeFunc registers a gauge read from collect at scrape time. Gauges whose
+// collect fails are left out of the scrape.
+func (r *Registry) GaugeFunc(name, help string, collect func() (float64, error)) {
+	r.register(&funcMetric{desc: desc{name: name, help: help}, kind: "gauge", collect: collect})
+}
+
+// CounterFunc registers a counter read from collect at scrape time, for
+// totals something else already keeps
+func (r *Registry) CounterFunc(name, help string, collect func() (float64, error)) {
+	r.register(&funcMetric{desc: desc{name: name, help: help}, kind: "counter", collect: collect})
+}
+
+// Write writes every metric in the text exposition format
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		if err := m.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP serves the scrape
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.Write(w)
+}
+
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (d desc) header(w io.Writer, kind string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, kind)
+	return err
+}
+
+// key joins label values into a map key; label values can't hold NUL
+func (d desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\x00")
+}
+
+// labelPairs renders the label set for a key, with extra pairs appended
+func (d desc) labelPairs(key string, extra ...string) string {
+	var pairs []string
+	if len(d.labels) > 0 {
+		for i, value := range strings.Split(key, "\x00") {
+			pairs = append(pairs, d.labels[i]+`="`+escapeLabel(value)+`"`)
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escapeLabel(extra[i+1])+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (d desc) id() string { return d.name }
+
+// CounterVec is a counter partitioned by label values
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	value float64
+}
+
+// Inc adds one to the counter with the given label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds delta, which must not be negative, to the counter with the given
+// label values
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("metrics: counters can't decrease")
+	}
+	key := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.values[key]
+	if !ok {
+		v = &counterValue{}
+		c.values[key] = v
+	}
+	v.value += delta
+}
+
+// Value returns the counter with the given label values
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	key := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, ok := c.values[key]; ok {
+		return v.value
+	}
+	return 0
+}
+
+func (c *CounterVec) write(w io.Writer) error {
+	if err := c.header(w, "counter"); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(key), formatFloat(c.values[key].value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HistogramVec is a histogram partitioned by label values
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	counts []uint64 // Per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// Observe records a value for the given label values
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = v
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			v.counts[i]++
+			break
+		}
+	}
+	v.count++
+	v.sum += value
+}
+
+func (h *HistogramVec) write(w io.Writer) error {
+	if err := h.header(w, "histogram"); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range sortedKeys(h.values) {
+		v := h.values[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += v.counts[i]
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(key, "le", formatFloat(bound)), cumulative); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			h.name, h.labelPairs(key, "le", "+Inf"), v.count,
+			h.name, h.labelPairs(key), formatFloat(v.sum),
+			h.name, h.labelPairs(key), v.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type funcMetric struct {
+	desc
+	kind    string
+	collect func() (float64, error)
+}
+
+func (f *funcMetric) write(w io.Writer) error {
+	value, err := f.collect()
+	if err != nil {
+		return nil
+	}
+	if err := f.header(w, f.kind); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s %s\n", f.name, formatFloat(value))
+	return err
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
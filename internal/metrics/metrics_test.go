@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	reg := NewRegistry()
+
+	requests := reg.Counter("test_requests_total", "Requests.", "method", "path")
+	requests.Inc("GET", "/a")
+	requests.Inc("GET", "/a")
+	requests.Add(0.5, "POST", `/b"\`)
+	if reg.Counter("test_requests_total", "Again.", "method", "path") != requests {
+		t.Error("expected the registered counter to be returned again")
+	}
+
+	latency := reg.Histogram("test_latency_seconds", "Latency.", []float64{0.1, 1})
+	latency.Observe(0.05)
+	latency.Observe(0.5)
+	latency.Observe(3)
+
+	reg.GaugeFunc("test_up", "Up.", func() (float64, error) { return 1, nil })
+	reg.GaugeFunc("test_broken", "Broken.", func() (float64, error) { return 0, errors.New("boom") })
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+
+	want := `# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",path="/a"} 2
+test_requests_total{method="POST",path="/b\"\\"} 0.5
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{le="0.1"} 1
+test_latency_seconds_bucket{le="1"} 2
+test_latency_seconds_bucket{le="+Inf"} 3
+test_latency_seconds_sum 3.55
+test_latency_seconds_count 3
+# HELP test_up Up.
+# TYPE test_up gauge
+test_up 1
+`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCounterLabelCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a missing label value")
+		}
+	}()
+	NewRegistry().Counter("test_total", "Test.", "a", "b").Inc("only-one")
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Activity actions recorded for snippets
+const (
+	ActivityCreated    = "created"
+	ActivityUpdated    = "updated"
+	ActivityTrashed    = "trashed"
+	ActivityDeleted    = "deleted" // Permanently
+	ActivityRestored   = "restored"
+	ActivityArchived   = "archived"
+	ActivityUnarchived = "unarchived"
+	ActivityReverted   = "reverted" // Restored from version history
+)
+
+// ActivityActor is the user who made a change. Username is empty for the
+// owner and for users that have since been deleted.
+type ActivityActor struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username,omitempty"`
+}
+
+// ActivityItem is an entry of the activity feed. Changes of the same kind
+// one user makes to a snippet in quick succession are folded into one item,
+// counted in Count between FirstAt and LastAt.
+type ActivityItem struct {
+	SnippetID string        `json:"snippet_id"`
+	Title     string        `json:"title"` // The snippet's title at the time of the latest change
+	Action    string        `json:"action"`
+	Actor     ActivityActor `json:"actor"`
+	Count     int           `json:"count"`
+	FirstAt   time.Time     `json:"first_at"`
+	LastAt    time.Time     `json:"last_at"`
+	Summary   string        `json:"summary"`
+}
+
+// Summarize sets the item's human-readable summary, e.g.
+// `Updated "deploy.sh" 3 times`
+func (i *ActivityItem) Summarize() {
+	verb := i.Action
+	if verb == ActivityDeleted {
+		verb = "permanently deleted"
+	}
+	i.Summary = fmt.Sprintf("%s %q", strings.ToUpper(verb[:1])+verb[1:], i.Title)
+	if i.Count > 1 {
+		i.Summary += fmt.Sprintf(" %d times", i.Count)
+	}
+}
+
+// ActivityFilter selects the activity to show
+type ActivityFilter struct {
+	SnippetID string     // Only this snippet
+	UserID    *int64     // Only changes made by this user
+	Since     *time.Time // Only changes after this time
+	Limit     int        // Maximum number of items
+}
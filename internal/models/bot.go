@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// Chat platforms the bot bridge can connect to
+const (
+	BotPlatformTelegram = "telegram"
+	BotPlatformMatrix   = "matrix"
+)
+
+// BotConfig configures the chat bot that saves code blocks sent to it as
+// snippets and answers /find queries. The token is stored encrypted.
+type BotConfig struct {
+	Enabled        bool      `json:"enabled"`
+	Platform       string    `json:"platform"`
+	TokenEncrypted string    `json:"-"`
+	HasToken       bool      `json:"has_token"`
+	HomeserverURL  string    `json:"homeserver_url,omitempty"` // Matrix only
+	AllowedChats   []string  `json:"allowed_chats"`            // Telegram chat IDs or Matrix room IDs the bot answers
+	Tags           []string  `json:"tags"`                     // Added to every snippet the bot creates
+	Cursor         string    `json:"-"`                        // Telegram update offset or Matrix sync token
+	LastError      *string   `json:"last_error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// BotConfigInput represents input for configuring the bot. An empty token
+// keeps the stored one.
+type BotConfigInput struct {
+	Enabled       bool     `json:"enabled"`
+	Platform      string   `json:"platform"`
+	Token         string   `json:"token,omitempty"`
+	HomeserverURL string   `json:"homeserver_url,omitempty"`
+	AllowedChats  []string `json:"allowed_chats"`
+	Tags          []string `json:"tags"`
+}
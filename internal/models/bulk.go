@@ -0,0 +1,41 @@
+package models
+
+// Bulk snippet actions
+const (
+	BulkCreate    = "create"
+	BulkUpdate    = "update"
+	BulkDelete    = "delete"
+	BulkRestore   = "restore"
+	BulkArchive   = "archive"
+	BulkUnarchive = "unarchive"
+	BulkTag       = "tag"
+	BulkMove      = "move"
+)
+
+// BulkSnippetInput applies one action to many snippets at once. Create takes
+// Snippets; every other action takes IDs plus the fields named below.
+type BulkSnippetInput struct {
+	Action     string            `json:"action"`
+	IDs        []string          `json:"ids,omitempty"`
+	Snippets   []SnippetInput    `json:"snippets,omitempty"`    // create
+	Set        *BulkSnippetPatch `json:"set,omitempty"`         // update
+	Permanent  bool              `json:"permanent,omitempty"`   // delete, skipping the trash
+	AddTags    []string          `json:"add_tags,omitempty"`    // tag
+	RemoveTags []string          `json:"remove_tags,omitempty"` // tag
+	FolderID   *int64            `json:"folder_id,omitempty"`   // move, null to take snippets out of their folder
+}
+
+// BulkSnippetPatch lists the fields a bulk update can set; nil fields are
+// left alone
+type BulkSnippetPatch struct {
+	IsPublic   *bool `json:"is_public,omitempty"`
+	IsFavorite *bool `json:"is_favorite,omitempty"`
+}
+
+// BulkSnippetResult reports the snippets a bulk action changed
+type BulkSnippetResult struct {
+	Action   string    `json:"action"`
+	Affected int       `json:"affected"`
+	IDs      []string  `json:"ids"`
+	Snippets []Snippet `json:"snippets,omitempty"` // Created snippets
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Digest summarizes what happened in a period, for the digest e-mail
+type Digest struct {
+	Since     time.Time      `json:"since"`
+	Until     time.Time      `json:"until"`
+	Activity  []ActivityItem `json:"activity"`  // Snippet changes, newest first
+	Conflicts int            `json:"conflicts"` // Unresolved gist sync conflicts
+	Backup    *DigestBackup  `json:"backup,omitempty"`
+}
+
+// DigestBackup is the state of S3 backups, included when S3 is set up
+type DigestBackup struct {
+	Count        int        `json:"count"`
+	LastBackupAt *time.Time `json:"last_backup_at,omitempty"`
+	Error        string     `json:"error,omitempty"` // Why the backups couldn't be listed
+}
+
+// DigestSendResult reports a digest sent on demand
+type DigestSendResult struct {
+	Recipients []string `json:"recipients"`
+	Digest     *Digest  `json:"digest"`
+}
@@ -6,16 +6,17 @@ import (
 
 // GistSyncConfig represents the global gist sync configuration
 type GistSyncConfig struct {
-	ID                         int        `json:"id"`
-	Enabled                    bool       `json:"enabled"`
-	GithubTokenEncrypted       string     `json:"-"`
-	GithubUsername             string     `json:"github_username"`
-	AutoSyncEnabled            bool       `json:"auto_sync_enabled"`
-	SyncIntervalMinutes        int        `json:"sync_interval_minutes"`
-	ConflictResolutionStrategy string     `json:"conflict_resolution_strategy"`
-	LastFullSyncAt             *time.Time `json:"last_full_sync_at,omitempty"`
-	CreatedAt                  time.Time  `json:"created_at"`
-	UpdatedAt                  time.Time  `json:"updated_at"`
+	ID                         int              `json:"id"`
+	Enabled                    bool             `json:"enabled"`
+	GithubTokenEncrypted       string           `json:"-"`
+	GithubUsername             string           `json:"github_username"`
+	AutoSyncEnabled            bool             `json:"auto_sync_enabled"`
+	SyncIntervalMinutes        int              `json:"sync_interval_minutes"`
+	ConflictResolutionStrategy string           `json:"conflict_resolution_strategy"`
+	LastFullSyncAt             *time.Time       `json:"last_full_sync_at,omitempty"`
+	RateLimit                  *GitHubRateLimit `json:"rate_limit,omitempty"` // As of the last sync
+	CreatedAt                  time.Time        `json:"created_at"`
+	UpdatedAt                  time.Time        `json:"updated_at"`
 }
 
 // SnippetGistMapping represents the mapping between a snippet and a gist
@@ -34,6 +35,13 @@ type SnippetGistMapping struct {
 	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
+// GistBaseVersion is a snippet as last synced with its gist: the common
+// ancestor edits made on both sides since are merged against
+type GistBaseVersion struct {
+	Title string            `json:"title"`
+	Files map[string]string `json:"files"` // Content by gist filename
+}
+
 // GistSyncConflict represents a sync conflict that needs resolution
 type GistSyncConflict struct {
 	ID               int64      `json:"id"`
@@ -60,12 +68,14 @@ type GistSyncLog struct {
 
 // SyncResult represents the result of a sync operation
 type SyncResult struct {
-	TotalProcessed int      `json:"total_processed"`
-	Synced         int      `json:"synced"`
-	Conflicts      int      `json:"conflicts"`
-	Errors         int      `json:"errors"`
-	ErrorMessages  []string `json:"error_messages,omitempty"`
-	Duration       string   `json:"duration"`
+	TotalProcessed int              `json:"total_processed"`
+	Synced         int              `json:"synced"`
+	Conflicts      int              `json:"conflicts"`
+	Errors         int              `json:"errors"`
+	Skipped        int              `json:"skipped"` // Left for the next sync after the rate limit ran out
+	ErrorMessages  []string         `json:"error_messages,omitempty"`
+	Duration       string           `json:"duration"`
+	RateLimit      *GitHubRateLimit `json:"rate_limit,omitempty"` // Quota left after the sync
 }
 
 // GistRequest represents a request to create or update a gist
@@ -100,6 +110,35 @@ type GistOwner struct {
 	ID    int64  `json:"id"`
 }
 
+// GitHubRepo represents a repository returned by the GitHub API
+type GitHubRepo struct {
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	HTMLURL       string `json:"html_url"`
+	Private       bool   `json:"private"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// GitHubExportRequest configures a one-shot publish of snippets to a GitHub repository
+type GitHubExportRequest struct {
+	Repo        string `json:"repo"`                  // "name" or "owner/name"
+	Description string `json:"description,omitempty"` // Used when the repository is created
+	Public      bool   `json:"public"`                // Visibility of a newly created repository
+	PublicOnly  bool   `json:"public_only"`           // Only export snippets marked public
+	Message     string `json:"message,omitempty"`     // Commit message
+}
+
+// GitHubExportResult contains the result of a GitHub repository export
+type GitHubExportResult struct {
+	Repo         string `json:"repo"`
+	RepoURL      string `json:"repo_url"`
+	Branch       string `json:"branch"`
+	CommitSHA    string `json:"commit_sha"`
+	RepoCreated  bool   `json:"repo_created"`
+	FilesWritten int    `json:"files_written"`
+	Snippets     int    `json:"snippets"`
+}
+
 // SnipoMetadata represents Snipo-specific metadata stored in gists
 type SnipoMetadata struct {
 	Version      string   `json:"version"`
@@ -108,6 +147,23 @@ type SnipoMetadata struct {
 	TagsOverflow []string `json:"tags_overflow,omitempty"`
 	IsFavorite   bool     `json:"is_favorite"`
 	IsArchived   bool     `json:"is_archived"`
+
+	// Files holds per-file settings gists can't store, keyed by filename
+	Files map[string]SnipoFileMetadata `json:"files,omitempty"`
+}
+
+// SnipoFileMetadata records a file's language override and note
+type SnipoFileMetadata struct {
+	Language string `json:"language,omitempty"`
+	Note     string `json:"note,omitempty"`
+}
+
+// GitHubRateLimit is the API quota GitHub reported on its last response
+type GitHubRateLimit struct {
+	Limit     int       `json:"limit"`     // Requests allowed per hour, 0 if not reported
+	Remaining int       `json:"remaining"` // Requests left until Reset
+	Reset     time.Time `json:"reset"`
+	CheckedAt time.Time `json:"checked_at"`
 }
 
 // SyncDirection represents the direction of sync
@@ -119,6 +175,7 @@ const (
 	GistToSnipo
 	Conflict
 	GistDeleted
+	Merged // Both sides changed and the edits were merged
 )
 
 // Sync status constants
@@ -144,6 +201,7 @@ const (
 	SyncOpDelete   = "delete"
 	SyncOpSync     = "sync"
 	SyncOpConflict = "conflict"
+	SyncOpMerge    = "merge"
 )
 
 // Sync operation statuses
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Import report sources
+const (
+	ImportSourceBackup = "backup" // Uploaded backup file
+	ImportSourceS3     = "s3"     // Backup restored from S3
+	ImportSourceURLs   = "urls"   // Raw file URLs
+)
+
+// ImportReport is the saved outcome of an import, kept so it can be read
+// again after the response that carried it is gone
+type ImportReport struct {
+	ID        int64              `json:"id"`
+	Source    string             `json:"source"`
+	Created   int                `json:"created"`
+	Updated   int                `json:"updated"`
+	Skipped   int                `json:"skipped"`
+	Failed    int                `json:"failed"`
+	Items     []ImportItemResult `json:"items,omitempty"`  // Left out of listings
+	Errors    []string           `json:"errors,omitempty"` // Left out of listings
+	CreatedAt time.Time          `json:"created_at"`
+}
@@ -0,0 +1,66 @@
+package models
+
+// Database maintenance tasks, in the order they run
+const (
+	MaintenanceIntegrityCheck = "integrity_check"
+	MaintenanceFTSOptimize    = "fts_optimize"
+	MaintenanceAnalyze        = "analyze"
+	MaintenanceVacuum         = "vacuum"
+	MaintenanceWALCheckpoint  = "wal_checkpoint"
+)
+
+// MaintenanceTasks lists every maintenance task, in the order they run
+var MaintenanceTasks = []string{
+	MaintenanceIntegrityCheck,
+	MaintenanceFTSOptimize,
+	MaintenanceAnalyze,
+	MaintenanceVacuum,
+	MaintenanceWALCheckpoint,
+}
+
+// Outcomes of a maintenance task
+const (
+	MaintenanceOK      = "ok"
+	MaintenanceSkipped = "skipped"
+	MaintenanceFailed  = "failed"
+)
+
+// MaintenanceRequest selects the tasks to run, all of them if empty
+type MaintenanceRequest struct {
+	Tasks []string `json:"tasks"`
+}
+
+// MaintenanceReport is the outcome of a maintenance run
+type MaintenanceReport struct {
+	Tasks    []MaintenanceTaskResult `json:"tasks"`
+	Before   DatabaseSize            `json:"before"`
+	After    DatabaseSize            `json:"after"`
+	Duration string                  `json:"duration"`
+}
+
+// Failed reports whether any task failed, including an integrity check
+// finding problems
+func (r *MaintenanceReport) Failed() bool {
+	for _, task := range r.Tasks {
+		if task.Status == MaintenanceFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// MaintenanceTaskResult is the outcome of one task
+type MaintenanceTaskResult struct {
+	Task     string   `json:"task"`
+	Status   string   `json:"status"`
+	Detail   string   `json:"detail,omitempty"`
+	Problems []string `json:"problems,omitempty"` // What integrity_check found, if not ok
+	Duration string   `json:"duration"`
+}
+
+// DatabaseSize is the space the database takes on disk
+type DatabaseSize struct {
+	Bytes     int64 `json:"bytes"`      // Size of the database file
+	FreeBytes int64 `json:"free_bytes"` // Unused pages VACUUM would reclaim
+	WALBytes  int64 `json:"wal_bytes"`  // Size of the write-ahead log, 0 outside WAL mode
+}
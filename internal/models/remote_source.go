@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// RemoteSource is another snipo instance whose public snippets are mirrored locally
+type RemoteSource struct {
+	ID           int64      `json:"id"`
+	Name         string     `json:"name"`
+	URL          string     `json:"url"`
+	FolderID     *int64     `json:"folder_id,omitempty"` // Local read-only folder holding mirrored snippets
+	Enabled      bool       `json:"enabled"`
+	ETag         string     `json:"-"`
+	LastSyncedAt *time.Time `json:"last_synced_at,omitempty"`
+	LastError    *string    `json:"last_error,omitempty"`
+	SnippetCount int        `json:"snippet_count"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// RemoteSourceInput represents input for adding a remote source
+type RemoteSourceInput struct {
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	Enabled *bool  `json:"enabled,omitempty"`
+}
+
+// RemoteSnippet maps a snippet on a remote instance to its local mirror
+type RemoteSnippet struct {
+	SourceID        int64     `json:"source_id"`
+	RemoteID        string    `json:"remote_id"`
+	SnippetID       string    `json:"snippet_id"`
+	RemoteUpdatedAt time.Time `json:"remote_updated_at"`
+}
+
+// RemoteSyncResult summarizes a pull from a remote source
+type RemoteSyncResult struct {
+	SourceID    int64 `json:"source_id"`
+	NotModified bool  `json:"not_modified"`
+	Created     int   `json:"created"`
+	Updated     int   `json:"updated"`
+	Removed     int   `json:"removed"`
+	Unchanged   int   `json:"unchanged"`
+}
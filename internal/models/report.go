@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Report statuses
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusDismissed = "dismissed"
+	ReportStatusActioned  = "actioned" // The snippet was unpublished
+)
+
+// Report is an abuse report filed against a public snippet
+type Report struct {
+	ID           int64      `json:"id"`
+	SnippetID    string     `json:"snippet_id"`
+	SnippetTitle string     `json:"snippet_title"`
+	Reason       string     `json:"reason"`
+	Details      string     `json:"details,omitempty"`
+	Status       string     `json:"status"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+}
+
+// ReportInput is submitted by visitors of a public snippet
+type ReportInput struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+// ReportResolution is the admin's decision on a report. "dismiss" closes the
+// report; "unpublish" makes the snippet private and closes every open report
+// against it.
+type ReportResolution struct {
+	Action string `json:"action"`
+}
@@ -2,72 +2,124 @@ package models
 
 import "time"
 
+// Policies for raw HTML embedded in markdown rendered on public pages
+const (
+	MarkdownHTMLSanitize = "sanitize" // Keep safe formatting tags, drop scripts, handlers and unsafe URLs
+	MarkdownHTMLEscape   = "escape"   // Drop all raw HTML
+	MarkdownHTMLAllow    = "allow"    // Render raw HTML unchanged; only for trusted content
+)
+
+// Tokenizers the search index can be built with. Changing the setting takes
+// effect when the index is rebuilt with snipo reindex-fts.
+const (
+	SearchTokenizerDefault = "unicode61" // Words split at spaces and punctuation, so snake_case and dotted.names too
+	SearchTokenizerCode    = "code"      // Also a trigram index finding parts of words, like Case in CamelCase
+)
+
+// MarkdownRenderInput is markdown to render the way public pages do
+type MarkdownRenderInput struct {
+	Content        string `json:"content"`
+	LineNumbers    bool   `json:"line_numbers"`    // Number the lines of code blocks
+	HeadingAnchors bool   `json:"heading_anchors"` // Give headings ids to link to
+}
+
+// MarkdownRenderResult is rendered markdown and the raw HTML policy applied
+type MarkdownRenderResult struct {
+	HTML   string `json:"html"`
+	Policy string `json:"policy"`
+}
+
+// Features that can be switched off at runtime through the settings API
+const (
+	FeaturePublicSnippets = "public_snippets" // Public share pages, the public API and abuse reports
+	FeatureGistSync       = "gist_sync"       // GitHub Gist sync endpoints and the background sync worker
+)
+
+// RuntimeFeatures lists every feature that can be toggled at runtime
+var RuntimeFeatures = []string{FeaturePublicSnippets, FeatureGistSync}
+
 // Settings represents application settings
 type Settings struct {
-	ID                             int64     `json:"id"`
-	AppName                        string    `json:"app_name"`
-	CustomCSS                      string    `json:"custom_css"`
-	Theme                          string    `json:"theme"`
-	DefaultLanguage                string    `json:"default_language"`
-	S3Enabled                      bool      `json:"s3_enabled"`
-	S3Endpoint                     string    `json:"s3_endpoint"`
-	S3Bucket                       string    `json:"s3_bucket"`
-	S3Region                       string    `json:"s3_region"`
-	BackupEncryptionEnabled        bool      `json:"backup_encryption_enabled"`
-	ArchiveEnabled                 bool      `json:"archive_enabled"`
-	TrashEnabled                   bool      `json:"trash_enabled"`
-	HistoryEnabled                 bool      `json:"history_enabled"`
-	AutoArchiveEnabled             bool      `json:"auto_archive_enabled"`
-	DefaultExpirationDays          int       `json:"default_expiration_days"`
-	DisableLogin                   bool      `json:"disable_login"`
-	EditorFontSize                 int       `json:"editor_font_size"`
-	EditorTabSize                  int       `json:"editor_tab_size"`
-	EditorTheme                    string    `json:"editor_theme"`
-	EditorWordWrap                 bool      `json:"editor_word_wrap"`
-	EditorShowPrintMargin          bool      `json:"editor_show_print_margin"`
-	EditorShowGutter               bool      `json:"editor_show_gutter"`
-	EditorShowIndentGuides         bool      `json:"editor_show_indent_guides"`
-	EditorHighlightActiveLine      bool      `json:"editor_highlight_active_line"`
-	EditorUseSoftTabs              bool      `json:"editor_use_soft_tabs"`
-	EditorEnableSnippets           bool      `json:"editor_enable_snippets"`
-	EditorEnableLiveAutocompletion bool      `json:"editor_enable_live_autocompletion"`
-	MarkdownFontSize               int       `json:"markdown_font_size"`
-	ExcludeFirstLineOnCopy         bool      `json:"exclude_first_line_on_copy"`
-	CreatedAt                      time.Time `json:"created_at"`
-	UpdatedAt                      time.Time `json:"updated_at"`
+	ID                             int64           `json:"id"`
+	AppName                        string          `json:"app_name"`
+	CustomCSS                      string          `json:"custom_css"`
+	Theme                          string          `json:"theme"`
+	DefaultLanguage                string          `json:"default_language"`
+	S3Enabled                      bool            `json:"s3_enabled"`
+	S3Endpoint                     string          `json:"s3_endpoint"`
+	S3Bucket                       string          `json:"s3_bucket"`
+	S3Region                       string          `json:"s3_region"`
+	BackupEncryptionEnabled        bool            `json:"backup_encryption_enabled"`
+	ArchiveEnabled                 bool            `json:"archive_enabled"`
+	TrashEnabled                   bool            `json:"trash_enabled"`
+	HistoryEnabled                 bool            `json:"history_enabled"`
+	AutoArchiveEnabled             bool            `json:"auto_archive_enabled"`
+	DefaultExpirationDays          int             `json:"default_expiration_days"`
+	DisableLogin                   bool            `json:"disable_login"`
+	EditorFontSize                 int             `json:"editor_font_size"`
+	EditorTabSize                  int             `json:"editor_tab_size"`
+	EditorTheme                    string          `json:"editor_theme"`
+	EditorWordWrap                 bool            `json:"editor_word_wrap"`
+	EditorShowPrintMargin          bool            `json:"editor_show_print_margin"`
+	EditorShowGutter               bool            `json:"editor_show_gutter"`
+	EditorShowIndentGuides         bool            `json:"editor_show_indent_guides"`
+	EditorHighlightActiveLine      bool            `json:"editor_highlight_active_line"`
+	EditorUseSoftTabs              bool            `json:"editor_use_soft_tabs"`
+	EditorEnableSnippets           bool            `json:"editor_enable_snippets"`
+	EditorEnableLiveAutocompletion bool            `json:"editor_enable_live_autocompletion"`
+	MarkdownFontSize               int             `json:"markdown_font_size"`
+	ExcludeFirstLineOnCopy         bool            `json:"exclude_first_line_on_copy"`
+	RejectDuplicateContent         bool            `json:"reject_duplicate_content"`   // Refuse to create snippets whose content already exists
+	ReportUnpublishThreshold       int             `json:"report_unpublish_threshold"` // Open reports that unpublish a snippet, 0 to disable
+	MarkdownHTMLPolicy             string          `json:"markdown_html_policy"`       // Raw HTML handling in public markdown
+	TagPalette                     string          `json:"tag_palette"`                // Comma-separated colors assigned to new tags
+	DigestEnabled                  bool            `json:"digest_enabled"`             // E-mail a periodic digest to DigestRecipients
+	DigestRecipients               string          `json:"digest_recipients"`          // Comma-separated e-mail addresses
+	SearchTokenizer                string          `json:"search_tokenizer"`           // Tokenizer of the search index, applied by snipo reindex-fts
+	Features                       map[string]bool `json:"features"`                   // Runtime feature flags by name
+	CreatedAt                      time.Time       `json:"created_at"`
+	UpdatedAt                      time.Time       `json:"updated_at"`
 }
 
 // SettingsInput represents input for updating settings
 type SettingsInput struct {
-	AppName                        string `json:"app_name"`
-	CustomCSS                      string `json:"custom_css"`
-	Theme                          string `json:"theme"`
-	DefaultLanguage                string `json:"default_language"`
-	S3Enabled                      bool   `json:"s3_enabled"`
-	S3Endpoint                     string `json:"s3_endpoint"`
-	S3Bucket                       string `json:"s3_bucket"`
-	S3Region                       string `json:"s3_region"`
-	S3AccessKeyID                  string `json:"s3_access_key_id,omitempty"`     // Optional, only for updates
-	S3SecretAccessKey              string `json:"s3_secret_access_key,omitempty"` // Optional, only for updates
-	BackupEncryptionEnabled        bool   `json:"backup_encryption_enabled"`
-	ArchiveEnabled                 bool   `json:"archive_enabled"`
-	TrashEnabled                   bool   `json:"trash_enabled"`
-	HistoryEnabled                 bool   `json:"history_enabled"`
-	AutoArchiveEnabled             bool   `json:"auto_archive_enabled"`
-	DefaultExpirationDays          int    `json:"default_expiration_days"`
-	DisableLogin                   bool   `json:"disable_login"`
-	EditorFontSize                 int    `json:"editor_font_size"`
-	EditorTabSize                  int    `json:"editor_tab_size"`
-	EditorTheme                    string `json:"editor_theme"`
-	EditorWordWrap                 bool   `json:"editor_word_wrap"`
-	EditorShowPrintMargin          bool   `json:"editor_show_print_margin"`
-	EditorShowGutter               bool   `json:"editor_show_gutter"`
-	EditorShowIndentGuides         bool   `json:"editor_show_indent_guides"`
-	EditorHighlightActiveLine      bool   `json:"editor_highlight_active_line"`
-	EditorUseSoftTabs              bool   `json:"editor_use_soft_tabs"`
-	EditorEnableSnippets           bool   `json:"editor_enable_snippets"`
-	EditorEnableLiveAutocompletion bool   `json:"editor_enable_live_autocompletion"`
-	MarkdownFontSize               int    `json:"markdown_font_size"`
-	ExcludeFirstLineOnCopy         bool   `json:"exclude_first_line_on_copy"`
-	Password                       string `json:"password,omitempty"`
+	AppName                        string          `json:"app_name"`
+	CustomCSS                      string          `json:"custom_css"`
+	Theme                          string          `json:"theme"`
+	DefaultLanguage                string          `json:"default_language"`
+	S3Enabled                      bool            `json:"s3_enabled"`
+	S3Endpoint                     string          `json:"s3_endpoint"`
+	S3Bucket                       string          `json:"s3_bucket"`
+	S3Region                       string          `json:"s3_region"`
+	S3AccessKeyID                  string          `json:"s3_access_key_id,omitempty"`     // Optional, only for updates
+	S3SecretAccessKey              string          `json:"s3_secret_access_key,omitempty"` // Optional, only for updates
+	BackupEncryptionEnabled        bool            `json:"backup_encryption_enabled"`
+	ArchiveEnabled                 bool            `json:"archive_enabled"`
+	TrashEnabled                   bool            `json:"trash_enabled"`
+	HistoryEnabled                 bool            `json:"history_enabled"`
+	AutoArchiveEnabled             bool            `json:"auto_archive_enabled"`
+	DefaultExpirationDays          int             `json:"default_expiration_days"`
+	DisableLogin                   bool            `json:"disable_login"`
+	EditorFontSize                 int             `json:"editor_font_size"`
+	EditorTabSize                  int             `json:"editor_tab_size"`
+	EditorTheme                    string          `json:"editor_theme"`
+	EditorWordWrap                 bool            `json:"editor_word_wrap"`
+	EditorShowPrintMargin          bool            `json:"editor_show_print_margin"`
+	EditorShowGutter               bool            `json:"editor_show_gutter"`
+	EditorShowIndentGuides         bool            `json:"editor_show_indent_guides"`
+	EditorHighlightActiveLine      bool            `json:"editor_highlight_active_line"`
+	EditorUseSoftTabs              bool            `json:"editor_use_soft_tabs"`
+	EditorEnableSnippets           bool            `json:"editor_enable_snippets"`
+	EditorEnableLiveAutocompletion bool            `json:"editor_enable_live_autocompletion"`
+	MarkdownFontSize               int             `json:"markdown_font_size"`
+	ExcludeFirstLineOnCopy         bool            `json:"exclude_first_line_on_copy"`
+	RejectDuplicateContent         bool            `json:"reject_duplicate_content"`
+	ReportUnpublishThreshold       int             `json:"report_unpublish_threshold"`
+	MarkdownHTMLPolicy             string          `json:"markdown_html_policy"`
+	TagPalette                     string          `json:"tag_palette"`
+	DigestEnabled                  bool            `json:"digest_enabled"`
+	DigestRecipients               string          `json:"digest_recipients"`
+	SearchTokenizer                string          `json:"search_tokenizer"`
+	Features                       map[string]bool `json:"features,omitempty"` // Only the listed features change
+	Password                       string          `json:"password,omitempty"`
 }
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -11,33 +15,47 @@ type SnippetFile struct {
 	Filename  string    `json:"filename"`
 	Content   string    `json:"content"`
 	Language  string    `json:"language"`
+	Note      string    `json:"note,omitempty"`
 	SortOrder int       `json:"sort_order"`
 	CreatedAt time.Time `json:"created_at,omitempty"`
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
+
+	// RenderedHTML is the markdown rendered for public pages, only set there
+	RenderedHTML string `json:"rendered_html,omitempty"`
 }
 
 // Snippet represents a code snippet
 type Snippet struct {
-	ID          string     `json:"id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Content     string     `json:"content"`  // Primary/legacy content (first file)
-	Language    string     `json:"language"` // Primary/legacy language
-	IsFavorite  bool       `json:"is_favorite"`
-	IsPublic    bool       `json:"is_public"`
-	IsArchived  bool       `json:"is_archived"`
-	ViewCount   int        `json:"view_count"`
-	S3Key       *string    `json:"s3_key,omitempty"`
-	Checksum    *string    `json:"checksum,omitempty"`
-	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	ID              string      `json:"id"`
+	Title           string      `json:"title"`
+	Description     string      `json:"description"`
+	Content         string      `json:"content"`  // Primary/legacy content (first file)
+	Language        string      `json:"language"` // Primary/legacy language
+	IsFavorite      bool        `json:"is_favorite"`
+	IsPublic        bool        `json:"is_public"`
+	IsArchived      bool        `json:"is_archived"`
+	ViewCount       int         `json:"view_count"`
+	PublicViewCount int         `json:"public_view_count"`      // Share page visits by signed-out visitors
+	UseCount        int         `json:"use_count"`              // Times copied or otherwise used, distinct from views
+	LastUsedAt      *time.Time  `json:"last_used_at,omitempty"` // When the snippet was last used
+	S3Key           *string     `json:"s3_key,omitempty"`
+	Checksum        *string     `json:"checksum,omitempty"`
+	ExpiresAt       *time.Time  `json:"expires_at,omitempty"`
+	PublishAt       *time.Time  `json:"publish_at,omitempty"`   // Scheduled time to make the snippet public
+	Provenance      *Provenance `json:"provenance,omitempty"`   // Origin of forked snippets
+	ExternalID      *string     `json:"external_id,omitempty"`  // Caller-chosen key for upserts by infrastructure-as-code tools
+	PublicToken     *string     `json:"public_token,omitempty"` // Key of the share link, renewed each time the snippet is made public
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+	DeletedAt       *time.Time  `json:"deleted_at,omitempty"`
 
 	// Relationships (populated when needed)
 	Tags    []Tag         `json:"tags,omitempty"`
 	Folders []Folder      `json:"folders,omitempty"`
 	Files   []SnippetFile `json:"files,omitempty"` // Multi-file support
+
+	// RenderedHTML is the legacy markdown content rendered for public pages
+	RenderedHTML string `json:"rendered_html,omitempty"`
 }
 
 // IsExpired returns true if the snippet has expired
@@ -48,12 +66,46 @@ func (s *Snippet) IsExpired() bool {
 	return time.Now().After(*s.ExpiresAt)
 }
 
+// Provenance records where a forked snippet came from
+type Provenance struct {
+	SourceURL string    `json:"source_url"`
+	SourceID  string    `json:"source_id"`
+	ForkedAt  time.Time `json:"forked_at"`
+}
+
+// Value stores provenance as JSON text
+func (p Provenance) Value() (driver.Value, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan reads provenance from its JSON text column
+func (p *Provenance) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	case []byte:
+		return json.Unmarshal(v, p)
+	default:
+		return fmt.Errorf("unsupported provenance type %T", src)
+	}
+}
+
+// ForkInput represents input for forking a public snippet
+type ForkInput struct {
+	URL string `json:"url"`
+}
+
 // SnippetFileInput represents input for a file within a snippet
 type SnippetFileInput struct {
 	ID       int64  `json:"id,omitempty"` // 0 for new files
 	Filename string `json:"filename"`
 	Content  string `json:"content"`
 	Language string `json:"language"`
+	Note     string `json:"note,omitempty"`
 }
 
 // SnippetInput represents input for creating/updating a snippet
@@ -67,25 +119,28 @@ type SnippetInput struct {
 	IsPublic    bool               `json:"is_public"`
 	IsArchived  bool               `json:"is_archived,omitempty"`
 	ExpiresAt   *time.Time         `json:"expires_at,omitempty"`
+	PublishAt   *time.Time         `json:"publish_at,omitempty"`
 	Files       []SnippetFileInput `json:"files,omitempty"` // Multi-file support
 }
 
 // SnippetFilter represents filter options for listing snippets
 type SnippetFilter struct {
-	Query      string
-	Language   string
-	TagID      int64   // Single tag filter (deprecated, use TagIDs)
-	FolderID   int64   // Single folder filter (deprecated, use FolderIDs)
-	TagIDs     []int64 // Multiple tags filter
-	FolderIDs  []int64 // Multiple folders filter
-	IsFavorite *bool
-	IsPublic   *bool
-	IsArchived *bool
-	IsDeleted  *bool
-	Page       int
-	Limit      int
-	SortBy     string
-	SortOrder  string
+	Query        string
+	Language     string
+	TagID        int64   // Single tag filter (deprecated, use TagIDs)
+	FolderID     int64   // Single folder filter (deprecated, use FolderIDs)
+	TagIDs       []int64 // Multiple tags filter
+	FolderIDs    []int64 // Multiple folders filter
+	IsFavorite   *bool
+	IsPublic     *bool
+	IsArchived   *bool
+	IsDeleted    *bool
+	CreatedSince *time.Time // Only snippets created at or after this time
+	Page         int
+	Limit        int
+	Cursor       string // Continues after the snippet a previous page's next_cursor marks; Page is ignored
+	SortBy       string
+	SortOrder    string
 }
 
 // DefaultSnippetFilter returns default filter values
@@ -100,11 +155,12 @@ func DefaultSnippetFilter() SnippetFilter {
 
 // Tag represents a tag for organizing snippets
 type Tag struct {
-	ID           int64     `json:"id"`
-	Name         string    `json:"name"`
-	Color        string    `json:"color"`
-	CreatedAt    time.Time `json:"created_at"`
-	SnippetCount int       `json:"snippet_count,omitempty"`
+	ID           int64      `json:"id"`
+	Name         string     `json:"name"`
+	Color        string     `json:"color"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"` // set while the tag is in the trash
+	SnippetCount int        `json:"snippet_count,omitempty"`
 }
 
 // TagInput represents input for creating/updating a tag
@@ -115,14 +171,24 @@ type TagInput struct {
 
 // Folder represents a folder for organizing snippets
 type Folder struct {
-	ID           int64     `json:"id"`
-	Name         string    `json:"name"`
-	ParentID     *int64    `json:"parent_id,omitempty"`
-	Icon         string    `json:"icon"`
-	SortOrder    int       `json:"sort_order"`
-	CreatedAt    time.Time `json:"created_at"`
-	SnippetCount int       `json:"snippet_count,omitempty"`
-	Children     []Folder  `json:"children,omitempty"`
+	ID           int64      `json:"id"`
+	Name         string     `json:"name"`
+	ParentID     *int64     `json:"parent_id,omitempty"`
+	Icon         string     `json:"icon"`
+	SortOrder    int        `json:"sort_order"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"` // set while the folder is in the trash
+	SnippetCount int        `json:"snippet_count,omitempty"`
+	Children     []Folder   `json:"children,omitempty"`
+}
+
+// FolderAccess lists who may see a folder in a workspace besides its
+// creator: the given users and members with at least one of the roles. A
+// folder without entries is visible to every member; its subfolders and
+// snippets are hidden along with it.
+type FolderAccess struct {
+	UserIDs []int64  `json:"user_ids"`
+	Roles   []string `json:"roles"`
 }
 
 // FolderInput represents input for creating/updating a folder
@@ -133,32 +199,202 @@ type FolderInput struct {
 	SortOrder int    `json:"sort_order,omitempty"`
 }
 
+// Folder delete modes decide what happens to a deleted folder's subfolders
+// and snippets
+const (
+	FolderDeleteMoveToParent = "move_to_parent" // Move subfolders and snippets up to the folder's parent
+	FolderDeleteOrphan       = "orphan"         // Make subfolders root folders and leave snippets unfiled
+	FolderDeleteTrash        = "trash"          // Delete subfolders too and move their snippets to the trash
+)
+
+// Token scopes restrict an API token to specific route groups
+const (
+	ScopeSnippetsRead  = "snippets:read"  // Read snippets, tags, folders and sync status
+	ScopeSnippetsWrite = "snippets:write" // Create, update and delete snippets
+	ScopeTagsWrite     = "tags:write"     // Create, update and delete tags
+	ScopeFoldersWrite  = "folders:write"  // Create, update, move and delete folders
+	ScopeBackupRun     = "backup:run"     // Export, import and S3 backup operations
+	ScopeSyncManage    = "sync:manage"    // Run gist sync and manage mappings and conflicts
+)
+
+// AllScopes lists every token scope in display order
+var AllScopes = []string{
+	ScopeSnippetsRead,
+	ScopeSnippetsWrite,
+	ScopeTagsWrite,
+	ScopeFoldersWrite,
+	ScopeBackupRun,
+	ScopeSyncManage,
+}
+
+// TokenScopes is the set of scopes granted to a token, stored as
+// comma-separated text. An empty set means the token is governed by its
+// permission level alone.
+type TokenScopes []string
+
+// Has reports whether the set contains scope
+func (s TokenScopes) Has(scope string) bool {
+	for _, granted := range s {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Value stores scopes as comma-separated text
+func (s TokenScopes) Value() (driver.Value, error) {
+	return strings.Join(s, ","), nil
+}
+
+// Scan reads scopes from their comma-separated text column
+func (s *TokenScopes) Scan(src interface{}) error {
+	var text string
+	switch v := src.(type) {
+	case nil:
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("unsupported scopes type %T", src)
+	}
+
+	*s = nil
+	for _, scope := range strings.Split(text, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			*s = append(*s, scope)
+		}
+	}
+	return nil
+}
+
 // APIToken represents an API token for external access
 type APIToken struct {
-	ID          int64      `json:"id"`
-	Name        string     `json:"name"`
-	Token       string     `json:"token,omitempty"` // Only returned on creation
-	TokenHash   string     `json:"-"`
-	Permissions string     `json:"permissions"`
-	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
-	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
+	ID           int64       `json:"id"`
+	Name         string      `json:"name"`
+	Token        string      `json:"token,omitempty"` // Only returned on creation and rotation
+	TokenHash    string      `json:"-"`
+	Permissions  string      `json:"permissions"`
+	Scopes       TokenScopes `json:"scopes,omitempty"` // When set, the token may only use these route groups
+	LastUsedAt   *time.Time  `json:"last_used_at,omitempty"`
+	LastUsedIP   *string     `json:"last_used_ip,omitempty"` // Client address of the latest request
+	RequestCount int64       `json:"request_count"`          // Requests since the token was issued or last rotated
+	ExpiresAt    *time.Time  `json:"expires_at,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UserID       int64       `json:"-"`                      // The user requests made with the token act as
+	WorkspaceID  int64       `json:"workspace_id,omitempty"` // The workspace the token is bound to, if any
 }
 
 // APITokenInput struct here represents input for creating an API token
 type APITokenInput struct {
-	Name          string `json:"name"`
-	Permissions   string `json:"permissions"` // "read", "write", "admin"
-	ExpiresInDays *int   `json:"expires_in_days,omitempty"`
-	Password      string `json:"password,omitempty"` // Required when disable_login is enabled
+	Name          string     `json:"name"`
+	Permissions   string     `json:"permissions"`      // "read", "write", "admin"
+	Scopes        []string   `json:"scopes,omitempty"` // Optional, narrows the token to these scopes
+	ExpiresInDays *int       `json:"expires_in_days,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"` // Alternative to expires_in_days
+	Password      string     `json:"password,omitempty"`   // Required when disable_login is enabled
+}
+
+// APITokenRotateInput represents input for rotating an API token. Without
+// an expiry the new token lives as long as the old one did.
+type APITokenRotateInput struct {
+	ExpiresInDays *int       `json:"expires_in_days,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	Password      string     `json:"password,omitempty"`
+}
+
+// APITokenStats shows how an API token has been used recently, so tokens
+// that sit unused or are suddenly used from new addresses stand out
+type APITokenStats struct {
+	TokenID      int64                 `json:"token_id"`
+	RequestCount int64                 `json:"request_count"`
+	LastUsedAt   *time.Time            `json:"last_used_at,omitempty"`
+	LastUsedIP   *string               `json:"last_used_ip,omitempty"`
+	Days         []APITokenDayUsage    `json:"days"` // Days with requests, oldest first
+	IPs          []APITokenClientUsage `json:"ips"`  // Client addresses, most recently seen first
+}
+
+// APITokenDayUsage counts a token's requests on one day (UTC)
+type APITokenDayUsage struct {
+	Date     string `json:"date"`
+	Requests int64  `json:"requests"`
+}
+
+// APITokenClientUsage counts a token's requests from one client address
+type APITokenClientUsage struct {
+	IP         string    `json:"ip"`
+	Requests   int64     `json:"requests"`
+	LastSeenAt time.Time `json:"last_seen_at"`
 }
 
 // Pagination holds pagination info for list responses (ايه ده ؟)
+// SearchResult is a snippet found by full-text search
+type SearchResult struct {
+	Snippet
+	Rank       float64          `json:"rank"` // bm25 score; lower is a better match
+	Highlights SearchHighlights `json:"highlights"`
+}
+
+// SearchHighlights shows why a search result matched. Fragments are HTML
+// escaped with each matched term wrapped in <mark>; fields without a match
+// are left empty.
+type SearchHighlights struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Content     string `json:"content,omitempty"`
+}
+
+// SearchIndexStatus compares the search index with the snippets table it is
+// built from. The counts differ when the index missed writes, for example
+// after a database file was restored or edited outside snipo.
+type SearchIndexStatus struct {
+	Tokenizer      string `json:"tokenizer"`                 // tokenizer the index was built with
+	Snippets       int    `json:"snippets"`                  // rows in the snippets table
+	Indexed        int    `json:"indexed"`                   // rows in the word index
+	TrigramIndexed *int   `json:"trigram_indexed,omitempty"` // rows in the trigram index, if built
+	Consistent     bool   `json:"consistent"`
+}
+
+// SearchIndexVersion is the layout version of SearchIndexExport
+const SearchIndexVersion = 1
+
+// SearchIndexFields are the document fields clients index for local
+// filtering
+var SearchIndexFields = []string{"title", "tags", "language", "folders"}
+
+// SearchIndexExport is a compact index of the titles and tags the caller can
+// see, for clients to filter large libraries locally. The documents load as
+// they are into MiniSearch, which takes "id" as the document ID. An export
+// made with since holds only the snippets changed after it, and the IDs of
+// the ones removed.
+type SearchIndexExport struct {
+	Version     int                   `json:"version"`
+	Fields      []string              `json:"fields"`
+	GeneratedAt time.Time             `json:"generated_at"` // Pass as since to fetch the next changes
+	Since       *time.Time            `json:"since,omitempty"`
+	Documents   []SearchIndexDocument `json:"documents"`
+	Removed     []string              `json:"removed"` // Trashed or deleted since; empty for full exports
+}
+
+// SearchIndexDocument is one snippet in a SearchIndexExport
+type SearchIndexDocument struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Tags       []string  `json:"tags"`
+	Language   string    `json:"language"`
+	Folders    []string  `json:"folders"`
+	IsFavorite bool      `json:"is_favorite"`
+	IsArchived bool      `json:"is_archived"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
 type Pagination struct {
-	Page       int `json:"page"`
-	Limit      int `json:"limit"`
-	Total      int `json:"total"`
-	TotalPages int `json:"totalPages"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+	Total      int    `json:"total"`
+	TotalPages int    `json:"totalPages"`
+	NextCursor string `json:"next_cursor,omitempty"` // Set when more snippets follow and the sort supports cursors
 }
 
 // SnippetListResponse represents a paginated list of snippets
@@ -167,6 +403,31 @@ type SnippetListResponse struct {
 	Pagination Pagination `json:"pagination"`
 }
 
+// SnippetNeighbors locates a snippet within a filtered, sorted list so detail
+// views can step to the previous or next snippet
+type SnippetNeighbors struct {
+	PrevID   *string `json:"prev_id"`
+	NextID   *string `json:"next_id"`
+	Position int     `json:"position"` // 1-based
+	Total    int     `json:"total"`
+}
+
+// DuplicateCheckInput describes a snippet about to be created so existing
+// snippets with the same title or content can be found first
+type DuplicateCheckInput struct {
+	Title   string             `json:"title"`
+	Content string             `json:"content"`
+	Files   []SnippetFileInput `json:"files,omitempty"`
+}
+
+// DuplicateMatch is an existing snippet that matches a new one
+type DuplicateMatch struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	MatchedBy string    `json:"matched_by"` // "content" or "title"
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 // BackupData represents a complete backup of all data
 type BackupData struct {
 	Version   string    `json:"version"`
@@ -184,39 +445,128 @@ type ExportOptions struct {
 
 // ImportOptions configures backup import behavior
 type ImportOptions struct {
-	Strategy string `json:"strategy"` // "replace", "merge", "skip"
-	Password string `json:"password"` // Decryption password if encrypted
+	Strategy         string `json:"strategy"`          // "replace", "merge", "skip"
+	ConflictStrategy string `json:"conflict_strategy"` // "skip", "overwrite", "duplicate"
+	Password         string `json:"password"`          // Decryption password if encrypted
+	DryRun           bool   `json:"dry_run"`           // Report what would change without writing anything
+	Source           string `json:"-"`                 // Import report source, ImportSourceBackup when empty
 }
 
+// Import conflict strategies
+const (
+	ConflictSkip      = "skip"      // Leave the existing snippet untouched
+	ConflictOverwrite = "overwrite" // Replace the existing snippet with the imported one
+	ConflictDuplicate = "duplicate" // Import as a new snippet alongside the existing one
+)
+
 // ImportResult contains the results of an import operation
 type ImportResult struct {
-	SnippetsImported int      `json:"snippets_imported"`
-	TagsImported     int      `json:"tags_imported"`
-	FoldersImported  int      `json:"folders_imported"`
-	Errors           []string `json:"errors,omitempty"`
+	SnippetsImported int                `json:"snippets_imported"`
+	SnippetsUpdated  int                `json:"snippets_updated"`
+	SnippetsSkipped  int                `json:"snippets_skipped"`
+	TagsImported     int                `json:"tags_imported"`
+	FoldersImported  int                `json:"folders_imported"`
+	DryRun           bool               `json:"dry_run,omitempty"`
+	Plan             *ImportPlan        `json:"plan,omitempty"` // Only set for dry runs
+	Items            []ImportItemResult `json:"items,omitempty"`
+	Errors           []string           `json:"errors,omitempty"`
+	ReportID         int64              `json:"report_id,omitempty"` // Saved report at /api/v1/imports/{id}/report
+}
+
+// ImportItemResult reports what happened to a single snippet from the backup
+type ImportItemResult struct {
+	SourceID  string `json:"source_id"`
+	Title     string `json:"title"`
+	Action    string `json:"action"`               // "created", "updated", "skipped" or "failed"
+	SnippetID string `json:"snippet_id,omitempty"` // Resulting (or untouched existing) snippet ID
+	MatchedBy string `json:"matched_by,omitempty"` // Set when the snippet conflicted with an existing one
+	Error     string `json:"error,omitempty"`
+}
+
+// ImportPlan summarizes the changes an import would make
+type ImportPlan struct {
+	SnippetsToCreate int              `json:"snippets_to_create"`
+	SnippetsToUpdate int              `json:"snippets_to_update"`
+	SnippetsToSkip   int              `json:"snippets_to_skip"`
+	TagsToCreate     int              `json:"tags_to_create"`
+	FoldersToCreate  int              `json:"folders_to_create"`
+	Conflicts        []ImportConflict `json:"conflicts,omitempty"`
+}
+
+// ImportConflict describes a backup snippet that matches an existing snippet
+type ImportConflict struct {
+	SourceID   string `json:"source_id"`   // Snippet ID as recorded in the backup
+	Title      string `json:"title"`       // Snippet title as recorded in the backup
+	ExistingID string `json:"existing_id"` // ID of the matching snippet in this instance
+	MatchedBy  string `json:"matched_by"`  // "id", "checksum" or "title"
 }
 
 // S3BackupInfo represents info about a backup stored in S3
 type S3BackupInfo struct {
-	Key          string    `json:"key"`
-	Size         int64     `json:"size"`
-	LastModified time.Time `json:"last_modified"`
+	Key          string          `json:"key"`
+	Size         int64           `json:"size"`
+	LastModified time.Time       `json:"last_modified"`
+	StorageClass string          `json:"storage_class,omitempty"` // As reported by the bucket, e.g. STANDARD_IA
+	Metadata     *BackupMetadata `json:"metadata,omitempty"`      // nil for backups uploaded before metadata existed
+}
+
+// S3PresignedURL is a time-limited link for downloading a backup straight
+// from the bucket
+type S3PresignedURL struct {
+	Key       string          `json:"key"`
+	URL       string          `json:"url"`
+	Size      int64           `json:"size"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	Metadata  *BackupMetadata `json:"metadata,omitempty"` // Says whether the download is encrypted
+}
+
+// BackupMetadata describes a backup without having to download it. S3
+// backups store it next to the backup as "<key>.meta.json".
+type BackupMetadata struct {
+	CreatedAt     time.Time `json:"created_at"`
+	SnippetCount  int       `json:"snippet_count"`
+	TagCount      int       `json:"tag_count"`
+	FolderCount   int       `json:"folder_count"`
+	AppVersion    string    `json:"app_version"`    // Snipo version that wrote the backup
+	BackupVersion string    `json:"backup_version"` // Backup format version
+	Format        string    `json:"format"`         // "json" or "zip"
+	Size          int64     `json:"size"`
+	Encrypted     bool      `json:"encrypted"` // Password protected
+	// Set when the backup was encrypted client-side with its own data key,
+	// which is stored wrapped by the server's master key
+	KeyID      string `json:"key_id,omitempty"`
+	WrappedKey string `json:"wrapped_key,omitempty"`
 }
 
 // S3SyncResult contains the results of an S3 sync operation
 type S3SyncResult struct {
+	Key        string    `json:"key,omitempty"`
 	Uploaded   int       `json:"uploaded"`
 	Errors     []string  `json:"errors,omitempty"`
 	StartedAt  time.Time `json:"started_at"`
 	FinishedAt time.Time `json:"finished_at"`
 }
 
+// S3UploadProgress reports the state of the latest S3 backup upload
+type S3UploadProgress struct {
+	Key        string     `json:"key"`
+	SentBytes  int64      `json:"sent_bytes"`
+	TotalBytes int64      `json:"total_bytes"`
+	Running    bool       `json:"running"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
 // S3RestoreResult contains the results of an S3 restore operation
 type S3RestoreResult struct {
-	Restored   int       `json:"restored"`
-	Errors     []string  `json:"errors,omitempty"`
-	StartedAt  time.Time `json:"started_at"`
-	FinishedAt time.Time `json:"finished_at"`
+	Restored   int         `json:"restored"`
+	DryRun     bool        `json:"dry_run,omitempty"`
+	Plan       *ImportPlan `json:"plan,omitempty"` // Only set for dry runs
+	Errors     []string    `json:"errors,omitempty"`
+	ReportID   int64       `json:"report_id,omitempty"`
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt time.Time   `json:"finished_at"`
 }
 
 // SnippetHistory represents a historical version of a snippet
@@ -235,6 +585,37 @@ type SnippetHistory struct {
 	Files       []SnippetFileHistory `json:"files,omitempty"`
 }
 
+// HistoryDiff describes the changes between two history entries of a snippet
+type HistoryDiff struct {
+	SnippetID string        `json:"snippet_id"`
+	FromID    int64         `json:"from_id"`
+	ToID      int64         `json:"to_id"`
+	Fields    []FieldChange `json:"fields"`
+	Files     []FileDiff    `json:"files"`
+}
+
+// FieldChange is a snippet attribute whose value differs between two entries
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// File diff statuses
+const (
+	FileDiffAdded     = "added"
+	FileDiffRemoved   = "removed"
+	FileDiffModified  = "modified"
+	FileDiffUnchanged = "unchanged"
+)
+
+// FileDiff is the unified diff of one file between two history entries
+type FileDiff struct {
+	Filename string `json:"filename"`
+	Status   string `json:"status"`
+	Diff     string `json:"diff,omitempty"`
+}
+
 // SnippetFileHistory represents a historical version of a snippet file
 type SnippetFileHistory struct {
 	ID        int64     `json:"id"`
@@ -243,6 +624,7 @@ type SnippetFileHistory struct {
 	Filename  string    `json:"filename"`
 	Content   string    `json:"content"`
 	Language  string    `json:"language"`
+	Note      string    `json:"note,omitempty"`
 	SortOrder int       `json:"sort_order"`
 	CreatedAt time.Time `json:"created_at"`
 }
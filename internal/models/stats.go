@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// LanguageStat is the number of snippets written in a language
+type LanguageStat struct {
+	Language string `json:"language"`
+	Count    int    `json:"count"`
+}
+
+// TagStat is the number of snippets carrying a tag
+type TagStat struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+	Count int    `json:"count"`
+}
+
+// ReferrerStat is the number of share page visits referred by a domain
+type ReferrerStat struct {
+	Domain     string    `json:"domain"`
+	Count      int       `json:"count"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// SnippetViewStats breaks down how often a snippet has been read. ViewCount
+// counts every read through the public API, including those made by the
+// share page; PublicViewCount counts only share page visits by people who
+// are not signed in.
+type SnippetViewStats struct {
+	ViewCount       int            `json:"view_count"`
+	PublicViewCount int            `json:"public_view_count"`
+	Referrers       []ReferrerStat `json:"referrers"`
+}
+
+// SnippetStats summarizes how active snippets are distributed across
+// languages and tags. Archived and trashed snippets are not counted.
+type SnippetStats struct {
+	Total     int            `json:"total"`
+	Languages []LanguageStat `json:"languages"`
+	Tags      []TagStat      `json:"tags"`
+}
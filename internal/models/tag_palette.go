@@ -0,0 +1,47 @@
+package models
+
+import (
+	"hash/fnv"
+	"strings"
+)
+
+// DefaultTagPalette is used for tags created without a color until the
+// tag_palette setting is changed
+var DefaultTagPalette = []string{
+	"#6366f1", // Indigo
+	"#ef4444", // Red
+	"#f97316", // Orange
+	"#eab308", // Yellow
+	"#22c55e", // Green
+	"#14b8a6", // Teal
+	"#0ea5e9", // Sky
+	"#8b5cf6", // Violet
+	"#ec4899", // Pink
+	"#64748b", // Slate
+}
+
+// ParseTagPalette splits a comma-separated tag_palette setting, falling back
+// to DefaultTagPalette when it is empty
+func ParseTagPalette(value string) []string {
+	var palette []string
+	for _, color := range strings.Split(value, ",") {
+		if color = strings.TrimSpace(color); color != "" {
+			palette = append(palette, color)
+		}
+	}
+	if len(palette) == 0 {
+		return DefaultTagPalette
+	}
+	return palette
+}
+
+// TagColor picks a color from palette by hashing the tag name, ignoring case,
+// so a tag gets the same color wherever and whenever it is created
+func TagColor(name string, palette []string) string {
+	if len(palette) == 0 {
+		palette = DefaultTagPalette
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(strings.ToLower(strings.TrimSpace(name))))
+	return palette[h.Sum32()%uint32(len(palette))]
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// TriggerSnippet is the flat snippet record served to polling triggers of
+// low-code tools such as Zapier and n8n, which map fields more easily
+// without nested objects
+type TriggerSnippet struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Content     string    `json:"content"`
+	Language    string    `json:"language"`
+	Tags        string    `json:"tags"`    // Comma-separated tag names
+	Folders     string    `json:"folders"` // Comma-separated folder names
+	IsPublic    bool      `json:"is_public"`
+	IsFavorite  bool      `json:"is_favorite"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// NewTriggerSnippet flattens a snippet with its tags and folders loaded
+func NewTriggerSnippet(s *Snippet) TriggerSnippet {
+	tags := make([]string, len(s.Tags))
+	for i, tag := range s.Tags {
+		tags[i] = tag.Name
+	}
+	folders := make([]string, len(s.Folders))
+	for i, folder := range s.Folders {
+		folders[i] = folder.Name
+	}
+
+	return TriggerSnippet{
+		ID:          s.ID,
+		Title:       s.Title,
+		Description: s.Description,
+		Content:     s.Content,
+		Language:    s.Language,
+		Tags:        strings.Join(tags, ","),
+		Folders:     strings.Join(folders, ","),
+		IsPublic:    s.IsPublic,
+		IsFavorite:  s.IsFavorite,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+// URLImportInput lists raw file URLs to snapshot as snippets. Tags and
+// FolderID apply to every snippet created.
+type URLImportInput struct {
+	URLs     []string `json:"urls"`
+	Tags     []string `json:"tags,omitempty"`
+	FolderID *int64   `json:"folder_id,omitempty"`
+}
+
+// URLImportItem reports what happened to one URL; exactly one of Snippet
+// and Error is set
+type URLImportItem struct {
+	URL     string   `json:"url"`
+	Snippet *Snippet `json:"snippet,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// URLImportResult reports a URL import in the order the URLs were given
+type URLImportResult struct {
+	Created  int             `json:"created"`
+	Failed   int             `json:"failed"`
+	Items    []URLImportItem `json:"items"`
+	ReportID int64           `json:"report_id,omitempty"` // Saved report at /api/v1/imports/{id}/report
+}
@@ -0,0 +1,17 @@
+package models
+
+// UsageReport is the local telemetry report: how often each part of the API
+// was used, without anything about who used it
+type UsageReport struct {
+	Enabled  bool         `json:"enabled"`
+	Days     int          `json:"days"`
+	Since    string       `json:"since"`    // first day counted, YYYY-MM-DD in UTC
+	Features []UsageCount `json:"features"` // requests per API area, most used first
+	Routes   []UsageCount `json:"routes"`   // requests per method and route pattern, most used first
+}
+
+// UsageCount is the number of successful requests to a feature or route
+type UsageCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
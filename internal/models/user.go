@@ -0,0 +1,42 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// OwnerUserID is the account that logs in with the master password. It owns
+// everything created in single-user mode and is always an admin.
+const OwnerUserID int64 = 0
+
+// User is an account in multi-user mode. The owner is not stored here.
+type User struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	IsAdmin   bool      `json:"is_admin"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserInput represents input for creating a user
+type UserInput struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	IsAdmin  bool   `json:"is_admin"`
+}
+
+type userIDKey struct{}
+
+// WithUserID returns a context whose requests act as the given user.
+// Repositories only read and change that user's snippets, tags and folders.
+func WithUserID(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, userIDKey{}, id)
+}
+
+// UserIDFromContext returns the user a request acts as. ok is false for
+// requests without a user, such as public pages and background workers,
+// which are not restricted to one user's data.
+func UserIDFromContext(ctx context.Context) (id int64, ok bool) {
+	id, ok = ctx.Value(userIDKey{}).(int64)
+	return id, ok
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// InboundWebhook is an endpoint that turns POSTed payloads into snippets,
+// filed with preset tags and folder
+type InboundWebhook struct {
+	ID             int64      `json:"id"`
+	Name           string     `json:"name"`
+	Slug           string     `json:"slug"` // Random part of the delivery URL
+	Path           string     `json:"path"` // Delivery path, relative to the instance root
+	SecretHash     string     `json:"-"`
+	FolderID       *int64     `json:"folder_id,omitempty"`
+	Tags           []string   `json:"tags"`
+	Language       string     `json:"language"` // Used when the payload doesn't name one
+	Enabled        bool       `json:"enabled"`
+	ReceivedCount  int        `json:"received_count"`
+	LastReceivedAt *time.Time `json:"last_received_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// InboundWebhookInput represents input for creating an inbound webhook
+type InboundWebhookInput struct {
+	Name     string   `json:"name"`
+	FolderID *int64   `json:"folder_id,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Language string   `json:"language,omitempty"`
+	Enabled  *bool    `json:"enabled,omitempty"`
+}
+
+// InboundWebhookWithSecret is returned when a webhook is created or its
+// secret rotated; the secret is never shown again
+type InboundWebhookWithSecret struct {
+	InboundWebhook
+	Secret string `json:"secret"`
+}
+
+// InboundDelivery is a payload POSTed to an inbound webhook. Title and
+// Language come from the query string and override the payload.
+type InboundDelivery struct {
+	ContentType string
+	Body        []byte
+	Title       string
+	Language    string
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultWorkspaceID is the workspace requests use when none is selected.
+// In it every user only sees their own snippets, tags and folders.
+const DefaultWorkspaceID int64 = 0
+
+// Workspace roles. Viewers can read the workspace's snippets, editors can
+// also change them, and admins can manage its tokens, settings and backups.
+const (
+	WorkspaceRoleViewer = "viewer"
+	WorkspaceRoleEditor = "editor"
+	WorkspaceRoleAdmin  = "admin"
+)
+
+// WorkspaceRoles lists the valid workspace roles
+var WorkspaceRoles = []string{WorkspaceRoleViewer, WorkspaceRoleEditor, WorkspaceRoleAdmin}
+
+// Workspace is a team space whose snippets, tags, folders and settings are
+// shared by its members and kept apart from everyone else's
+type Workspace struct {
+	ID        int64     `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role,omitempty"` // The requesting user's role, when listed for them
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WorkspaceInput represents input for creating or updating a workspace
+type WorkspaceInput struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// WorkspaceMember is a user who belongs to a workspace
+type WorkspaceMember struct {
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WorkspaceMemberInput sets a member's role; it defaults to editor
+type WorkspaceMemberInput struct {
+	Role string `json:"role"`
+}
+
+type workspaceIDKey struct{}
+
+type workspaceRoleKey struct{}
+
+// WithWorkspaceID returns a context whose requests act inside the given
+// workspace. Repositories then read and change the workspace's data instead
+// of the user's own.
+func WithWorkspaceID(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, workspaceIDKey{}, id)
+}
+
+// WorkspaceIDFromContext returns the workspace a request acts in, or
+// DefaultWorkspaceID when none was selected
+func WorkspaceIDFromContext(ctx context.Context) int64 {
+	id, _ := ctx.Value(workspaceIDKey{}).(int64)
+	return id
+}
+
+// WithWorkspaceRole returns a context carrying the user's role in the
+// workspace a request acts in
+func WithWorkspaceRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, workspaceRoleKey{}, role)
+}
+
+// WorkspaceRoleFromContext returns the user's role in the workspace a
+// request acts in, or "" outside of workspaces
+func WorkspaceRoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(workspaceRoleKey{}).(string)
+	return role
+}
@@ -0,0 +1,183 @@
+// Package outbound builds the HTTP clients used for requests to other
+// servers. Clients for URLs that users supply refuse to connect to loopback,
+// private and other internal addresses, so they can't be pointed at services
+// that are only reachable from inside the network.
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned when a request would reach a non-public address
+var ErrBlockedAddress = errors.New("address is not publicly routable")
+
+// maxRedirects stops redirect loops
+const maxRedirects = 5
+
+// Options configures a client
+type Options struct {
+	// Timeout bounds each request, redirects and reading the body included
+	Timeout time.Duration
+	// AllowPrivate permits non-public addresses. Set it for destinations an
+	// operator configured, such as the GitHub API URL or webhooks, and leave
+	// it off for URLs that come from users.
+	AllowPrivate bool
+	// Proxy picks the proxy for a request. When nil, the proxy given to
+	// SetProxy is used, or HTTP_PROXY, HTTPS_PROXY and NO_PROXY from the
+	// environment.
+	Proxy func(*http.Request) (*url.URL, error)
+}
+
+// NewClient returns an HTTP client with timeouts, a redirect limit and proxy
+// support. Unless AllowPrivate is set, the address is checked after DNS
+// resolution at connect time, so a hostname can't be rebound to an internal
+// address between lookup and connect, and redirects are checked too.
+func NewClient(opts Options) *http.Client {
+	proxy := opts.Proxy
+	if proxy == nil {
+		proxy = Proxy
+	}
+
+	transport := &http.Transport{
+		Proxy:               proxy,
+		TLSHandshakeTimeout: opts.Timeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     30 * time.Second,
+	}
+
+	if opts.AllowPrivate {
+		transport.DialContext = (&net.Dialer{Timeout: opts.Timeout}).DialContext
+	} else {
+		g := &guard{next: proxy, proxies: make(map[string]bool)}
+		transport.Proxy = g.proxy
+		transport.DialContext = g.dial(opts.Timeout)
+	}
+
+	return &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// guard blocks connections to non-public addresses. Proxies are exempt,
+// since they are configured by the operator and usually internal; when a
+// request goes through one the destination is checked before it is handed
+// over, as the proxy does the final lookup.
+type guard struct {
+	next func(*http.Request) (*url.URL, error)
+
+	mu      sync.RWMutex
+	proxies map[string]bool
+}
+
+// proxy picks the proxy for req and, when there is one, checks the
+// destination host and remembers the proxy's address so it may be dialed
+func (g *guard) proxy(req *http.Request) (*url.URL, error) {
+	proxyURL, err := g.next(req)
+	if err != nil || proxyURL == nil {
+		return proxyURL, err
+	}
+
+	if err := checkHost(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	g.proxies[proxyAddr(proxyURL)] = true
+	g.mu.Unlock()
+	return proxyURL, nil
+}
+
+// dial connects to proxies directly and to anything else only after the
+// resolved address passed the public address check
+func (g *guard) dial(timeout time.Duration) func(ctx context.Context, network, address string) (net.Conn, error) {
+	direct := &net.Dialer{Timeout: timeout}
+	guarded := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+			}
+			return nil
+		},
+	}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		g.mu.RLock()
+		isProxy := g.proxies[address]
+		g.mu.RUnlock()
+		if isProxy {
+			return direct.DialContext(ctx, network, address)
+		}
+		return guarded.DialContext(ctx, network, address)
+	}
+}
+
+// checkHost resolves host and fails if any of its addresses isn't public
+func checkHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublicIP(ip) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+		}
+	}
+	return nil
+}
+
+// proxyAddr is the host:port the transport dials for a proxy URL
+func proxyAddr(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// cgnatRange is shared address space (RFC 6598), which is not public either
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPublicIP reports whether ip is a globally routable unicast address
+func IsPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || cgnatRange.Contains(ip) || ip.Equal(net.IPv4bcast) ||
+		(ip.To4() != nil && ip.To4()[0] == 0))
+}
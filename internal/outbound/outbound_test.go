@@ -0,0 +1,114 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "192.168.0.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1"} {
+		if IsPublicIP(net.ParseIP(ip)) {
+			t.Errorf("expected %s to be blocked", ip)
+		}
+	}
+	for _, ip := range []string{"1.1.1.1", "140.82.112.3", "2606:4700::1111"} {
+		if !IsPublicIP(net.ParseIP(ip)) {
+			t.Errorf("expected %s to be allowed", ip)
+		}
+	}
+}
+
+func TestNewClient_PrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	strict := NewClient(Options{Timeout: 5 * time.Second})
+	if _, err := strict.Get(server.URL); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("expected loopback to be blocked, got %v", err)
+	}
+
+	allowed := NewClient(Options{Timeout: 5 * time.Second, AllowPrivate: true})
+	resp, err := allowed.Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected loopback to be allowed, got %v", err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestNewClient_Proxy(t *testing.T) {
+	// The proxy is on loopback, which is fine: only destinations are checked
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		_, _ = io.WriteString(w, "via proxy")
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	client := NewClient(Options{Timeout: 5 * time.Second, Proxy: http.ProxyURL(proxyURL)})
+
+	resp, err := client.Get("http://93.184.215.14/file.txt")
+	if err != nil {
+		t.Fatalf("expected a public destination to go through the proxy, got %v", err)
+	}
+	_ = resp.Body.Close()
+	if len(proxied) != 1 || proxied[0] != "http://93.184.215.14/file.txt" {
+		t.Errorf("expected the proxy to receive the request, got %v", proxied)
+	}
+
+	if _, err := client.Get("http://10.0.0.1/admin"); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("expected a private destination to be blocked before reaching the proxy, got %v", err)
+	}
+	if len(proxied) != 1 {
+		t.Errorf("expected the blocked request not to reach the proxy, got %v", proxied)
+	}
+}
+
+func TestSetProxy(t *testing.T) {
+	defer func() { _ = SetProxy("") }()
+
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxy.Close()
+
+	if err := SetProxy("ftp://proxy.example"); err == nil {
+		t.Error("expected an unsupported proxy scheme to be rejected")
+	}
+	if err := SetProxy(proxy.URL); err != nil {
+		t.Fatalf("SetProxy failed: %v", err)
+	}
+
+	// Clients created before and after SetProxy both pick it up
+	client := NewClient(Options{Timeout: 5 * time.Second, AllowPrivate: true})
+	resp, err := client.Get("http://api.github.example/zen")
+	if err != nil {
+		t.Fatalf("expected the request to go through the proxy, got %v", err)
+	}
+	_ = resp.Body.Close()
+	if len(proxied) != 1 || proxied[0] != "http://api.github.example/zen" {
+		t.Errorf("expected the proxy to receive the request, got %v", proxied)
+	}
+
+	if configured, err := CheckProxy(context.Background()); !configured || err != nil {
+		t.Errorf("expected a reachable proxy, got %v %v", configured, err)
+	}
+	if got := ProxyURL(); got != proxy.URL {
+		t.Errorf("expected ProxyURL %q, got %q", proxy.URL, got)
+	}
+
+	proxy.Close()
+	if configured, err := CheckProxy(context.Background()); !configured || err == nil {
+		t.Errorf("expected an unreachable proxy to be reported, got %v %v", configured, err)
+	}
+}
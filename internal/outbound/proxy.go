@@ -0,0 +1,131 @@
+package outbound
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// proxyCheckTimeout bounds the connection attempt made by CheckProxy
+const proxyCheckTimeout = 3 * time.Second
+
+var (
+	proxyMu sync.RWMutex
+	// proxyURL and proxyFunc are set by SetProxy; nil means the environment
+	// decides
+	proxyURL  *url.URL
+	proxyFunc func(*url.URL) (*url.URL, error)
+)
+
+// SetProxy sends outbound requests through rawURL instead of the proxies
+// named by HTTP_PROXY and HTTPS_PROXY. Hosts matched by NO_PROXY still
+// connect directly. An empty URL goes back to the environment.
+func SetProxy(rawURL string) error {
+	if rawURL == "" {
+		proxyMu.Lock()
+		proxyURL, proxyFunc = nil, nil
+		proxyMu.Unlock()
+		return nil
+	}
+
+	u, err := parseProxyURL(rawURL)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+
+	cfg := httpproxy.FromEnvironment()
+	cfg.HTTPProxy = u.String()
+	cfg.HTTPSProxy = u.String()
+
+	proxyMu.Lock()
+	proxyURL, proxyFunc = u, cfg.ProxyFunc()
+	proxyMu.Unlock()
+	return nil
+}
+
+// Proxy picks the proxy for req: the one given to SetProxy, or otherwise
+// the one from the environment. Clients from NewClient use it by default,
+// and other transports, such as the S3 client's, can use it too.
+func Proxy(req *http.Request) (*url.URL, error) {
+	proxyMu.RLock()
+	fn := proxyFunc
+	proxyMu.RUnlock()
+	if fn == nil {
+		return http.ProxyFromEnvironment(req)
+	}
+	return fn(req.URL)
+}
+
+// ProxyURL returns the proxy outbound requests use, from SetProxy or the
+// environment, with any password masked. It is empty when there is none.
+func ProxyURL() string {
+	u, err := configuredProxy()
+	if err != nil || u == nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+// CheckProxy connects to the configured proxy to see whether it is up. It
+// reports false when no proxy is configured.
+func CheckProxy(ctx context.Context) (bool, error) {
+	u, err := configuredProxy()
+	if err != nil {
+		return true, err
+	}
+	if u == nil {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, proxyCheckTimeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", proxyAddr(u))
+	if err != nil {
+		return true, fmt.Errorf("proxy %s unreachable: %w", u.Host, err)
+	}
+	_ = conn.Close()
+	return true, nil
+}
+
+// configuredProxy is the proxy given to SetProxy, or else the HTTPS or HTTP
+// proxy from the environment
+func configuredProxy() (*url.URL, error) {
+	proxyMu.RLock()
+	u := proxyURL
+	proxyMu.RUnlock()
+	if u != nil {
+		return u, nil
+	}
+
+	env := httpproxy.FromEnvironment()
+	for _, raw := range []string{env.HTTPSProxy, env.HTTPProxy} {
+		if raw != "" {
+			return parseProxyURL(raw)
+		}
+	}
+	return nil, nil
+}
+
+// parseProxyURL parses a proxy URL, defaulting to http:// like HTTP_PROXY
+func parseProxyURL(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", raw)
+	}
+	return u, nil
+}
@@ -0,0 +1,452 @@
+// Package replica continuously copies the SQLite database to the backup
+// storage, as Litestream does: a snapshot of the database file starts a
+// generation, and the frames committed to the write-ahead log after it are
+// shipped as segments every sync interval. A database can be restored to
+// any time a generation covers, to within one sync interval.
+//
+// Frames can only be shipped if the WAL isn't reset before they are read,
+// so the replicator does the checkpointing: every other connection to the
+// database must run with wal_autocheckpoint=0. A checkpoint made outside
+// the replicator is detected and starts a new generation.
+package replica
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/storage"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// prefix holds the generations, one directory each
+	prefix = "replica/"
+	// timeFormat names generations and segments, sorting like the times
+	timeFormat = "20060102T150405.000000Z"
+	// encryptedSuffix marks objects encrypted with the instance's key
+	encryptedSuffix = ".enc"
+
+	// The WAL is checkpointed once it grows past checkpointSize, or
+	// checkpointInterval after the last checkpoint
+	checkpointSize     = 4 << 20
+	checkpointInterval = time.Minute
+)
+
+// errDiscontinuity means frames left the WAL without being shipped
+var errDiscontinuity = errors.New("the WAL was checkpointed outside the replicator")
+
+// Storage is where replicas are kept, S3 or SFTP
+type Storage interface {
+	Upload(ctx context.Context, key string, content []byte, contentType string) error
+	Download(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]storage.ObjectInfo, error)
+}
+
+// Encrypter encrypts replicas client-side, as backups are
+type Encrypter interface {
+	EncryptBytes(plaintext []byte) ([]byte, error)
+	DecryptBytes(data []byte) ([]byte, error)
+}
+
+// Replicator ships the WAL of one database. Its state is only touched by
+// the goroutine Start launches.
+type Replicator struct {
+	path      string
+	store     Storage
+	encrypter Encrypter
+	logger    *slog.Logger
+
+	interval         time.Duration
+	snapshotInterval time.Duration
+	retention        time.Duration
+
+	db             *sql.DB
+	lockConn       *sql.Conn // Holds the write lock while the WAL is checkpointed
+	checkpointConn *sql.Conn
+
+	generation      string // Empty until a snapshot is stored
+	generationStart time.Time
+	index           int // Of the next segment
+	pos             walPos
+	checkpointed    bool // The WAL was wholly checkpointed at pos, so the next write may restart it
+	lastCheckpoint  time.Time
+	failing         bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a replicator for the database at path, syncing every second,
+// starting a generation every day and keeping three days of them
+func New(path string, store Storage, logger *slog.Logger) *Replicator {
+	return &Replicator{
+		path:             path,
+		store:            store,
+		logger:           logger,
+		interval:         time.Second,
+		snapshotInterval: 24 * time.Hour,
+		retention:        72 * time.Hour,
+	}
+}
+
+// WithEncryption encrypts snapshots and segments before they are uploaded
+func (r *Replicator) WithEncryption(encrypter Encrypter) *Replicator {
+	r.encrypter = encrypter
+	return r
+}
+
+// WithIntervals sets how often the WAL is shipped, how often a generation
+// is started and how long generations are kept after the next one starts
+func (r *Replicator) WithIntervals(sync, snapshot, retention time.Duration) *Replicator {
+	r.interval, r.snapshotInterval, r.retention = sync, snapshot, retention
+	return r
+}
+
+// Start opens the database and replicates it until Stop is called. The
+// first snapshot is taken right away.
+func (r *Replicator) Start(ctx context.Context) error {
+	if err := r.open(ctx); err != nil {
+		return err
+	}
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+	go r.run(ctx)
+	return nil
+}
+
+// Stop ships what was committed since the last sync and closes the
+// database
+func (r *Replicator) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+}
+
+func (r *Replicator) open(ctx context.Context) error {
+	db, err := sql.Open("sqlite", r.path+"?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_pragma=wal_autocheckpoint(0)")
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	if r.lockConn, err = db.Conn(ctx); err == nil {
+		r.checkpointConn, err = db.Conn(ctx)
+	}
+	if err != nil {
+		_ = db.Close()
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	r.db = db
+	return nil
+}
+
+func (r *Replicator) close() {
+	_ = r.lockConn.Close()
+	_ = r.checkpointConn.Close()
+	_ = r.db.Close()
+}
+
+func (r *Replicator) run(ctx context.Context) {
+	defer close(r.done)
+	defer r.close()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.step(ctx)
+		select {
+		case <-ctx.Done():
+			if r.generation != "" {
+				finalCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+				if err := r.sync(finalCtx); err != nil {
+					r.logger.Warn("failed to ship the last WAL frames", "error", err)
+				}
+				cancel()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// step replicates once, logging when replication starts and stops failing
+// rather than on every attempt
+func (r *Replicator) step(ctx context.Context) {
+	err := r.replicate(ctx)
+	switch {
+	case err != nil && !r.failing:
+		r.logger.Warn("database replication failed, retrying", "error", err)
+	case err == nil && r.failing:
+		r.logger.Info("database replication resumed")
+	}
+	r.failing = err != nil
+}
+
+func (r *Replicator) replicate(ctx context.Context) error {
+	if r.generation == "" || time.Since(r.generationStart) >= r.snapshotInterval {
+		return r.snapshot(ctx)
+	}
+	if err := r.sync(ctx); err != nil {
+		if !errors.Is(err, errDiscontinuity) {
+			return err
+		}
+		r.logger.Warn("WAL frames were checkpointed before they were shipped, starting a new generation")
+		r.generation = ""
+		return r.snapshot(ctx)
+	}
+	if !r.checkpointed && (r.pos.offset >= checkpointSize || time.Since(r.lastCheckpoint) >= checkpointInterval) {
+		return r.checkpoint(ctx)
+	}
+	return nil
+}
+
+// sync ships the transactions committed to the WAL since the last sync
+func (r *Replicator) sync(ctx context.Context) error {
+	f, err := os.Open(r.path + "-wal")
+	var hdr walHeader
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		err = errNoWAL
+	case err == nil:
+		defer func() { _ = f.Close() }()
+		hdr, err = readWALHeader(f)
+	}
+	if errors.Is(err, errNoWAL) {
+		if r.checkpointed || r.pos.offset == 0 {
+			return nil
+		}
+		return errDiscontinuity
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read WAL: %w", err)
+	}
+
+	pos := r.pos
+	if hdr.salt1 != pos.header.salt1 || hdr.salt2 != pos.header.salt2 {
+		// The WAL restarted, which only loses frames if they weren't all
+		// shipped before it was checkpointed
+		if !r.checkpointed && pos.offset != 0 {
+			return errDiscontinuity
+		}
+		pos = hdr.start()
+	}
+	frames, next, err := readFrames(f, pos)
+	if err != nil {
+		return fmt.Errorf("failed to read WAL: %w", err)
+	}
+	if len(frames) == 0 {
+		return nil
+	}
+
+	segment := append(binary.BigEndian.AppendUint32(nil, pos.header.pageSize), frames...)
+	key := fmt.Sprintf("%s%s/wal/%08d-%s.gz", prefix, r.generation, r.index, time.Now().UTC().Format(timeFormat))
+	if err := r.upload(ctx, key, segment); err != nil {
+		return err
+	}
+	r.pos, r.index, r.checkpointed = next, r.index+1, false
+	return nil
+}
+
+// checkpoint ships the WAL and copies it into the database. The write lock
+// is held from the last sync on so no transaction commits in between, so
+// every frame checkpointed has been shipped. Most frames are shipped
+// before taking it, so writers don't wait on the upload.
+func (r *Replicator) checkpoint(ctx context.Context) error {
+	if err := r.sync(ctx); err != nil {
+		return err
+	}
+	if _, err := r.lockConn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("failed to lock database: %w", err)
+	}
+	defer func() { _, _ = r.lockConn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK") }()
+
+	if err := r.sync(ctx); err != nil {
+		return err
+	}
+	frames, complete, err := r.walCheckpoint(ctx)
+	if err != nil {
+		return err
+	}
+	r.lastCheckpoint = time.Now()
+	r.checkpointed = complete && frames == r.pos.frames()
+	return nil
+}
+
+// walCheckpoint copies the WAL into the database without waiting for
+// readers, returning how many frames the WAL holds and whether all of them
+// were copied
+func (r *Replicator) walCheckpoint(ctx context.Context) (int64, bool, error) {
+	var busy, frames, copied int64
+	if err := r.checkpointConn.QueryRowContext(ctx, "PRAGMA wal_checkpoint(PASSIVE)").Scan(&busy, &frames, &copied); err != nil {
+		return 0, false, fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	return frames, busy == 0 && frames == copied, nil
+}
+
+// snapshot starts a generation with a copy of the database file, taken
+// just after the whole WAL was checkpointed into it
+func (r *Replicator) snapshot(ctx context.Context) error {
+	if _, err := r.lockConn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("failed to lock database: %w", err)
+	}
+	locked := true
+	unlock := func() {
+		if locked {
+			_, _ = r.lockConn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+			locked = false
+		}
+	}
+	defer unlock()
+
+	start := time.Now().UTC()
+	frames, complete, err := r.walCheckpoint(ctx)
+	if err != nil {
+		return err
+	}
+	if !complete {
+		return errors.New("readers kept the WAL from being checkpointed")
+	}
+	pos, err := r.walEnd()
+	if err != nil {
+		return err
+	}
+	if pos.frames() != frames {
+		return fmt.Errorf("WAL holds %d frames, expected %d", pos.frames(), frames)
+	}
+	// Only checkpoints write to the database file, and the next one is the
+	// replicator's own, so it can be copied without the lock
+	unlock()
+
+	content, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read database: %w", err)
+	}
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	generation := start.Format(timeFormat) + "-" + hex.EncodeToString(suffix)
+	if err := r.upload(ctx, prefix+generation+"/snapshot.db.gz", content); err != nil {
+		return err
+	}
+
+	r.generation, r.generationStart, r.index = generation, start, 0
+	r.pos, r.checkpointed, r.lastCheckpoint = pos, true, time.Now()
+	r.logger.Info("database snapshot stored", "generation", generation, "size", len(content))
+
+	if err := r.prune(ctx); err != nil {
+		r.logger.Warn("failed to delete old replica generations", "error", err)
+	}
+	return nil
+}
+
+// walEnd returns the position after the last transaction in the WAL
+func (r *Replicator) walEnd() (walPos, error) {
+	f, err := os.Open(r.path + "-wal")
+	if errors.Is(err, fs.ErrNotExist) {
+		return walPos{}, nil
+	}
+	if err != nil {
+		return walPos{}, fmt.Errorf("failed to read WAL: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	hdr, err := readWALHeader(f)
+	if errors.Is(err, errNoWAL) {
+		return walPos{}, nil
+	}
+	if err != nil {
+		return walPos{}, fmt.Errorf("failed to read WAL: %w", err)
+	}
+	_, end, err := readFrames(f, hdr.start())
+	if err != nil {
+		return walPos{}, fmt.Errorf("failed to read WAL: %w", err)
+	}
+	return end, nil
+}
+
+// prune deletes the generations that are no longer needed to restore to
+// any time within the retention period: those followed by a generation
+// that started before it
+func (r *Replicator) prune(ctx context.Context) error {
+	generations, err := listGenerations(ctx, r.store)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-r.retention)
+	for i := 0; i+1 < len(generations); i++ {
+		if generations[i].name == r.generation || !generations[i+1].start.Before(cutoff) {
+			continue
+		}
+		for _, key := range generations[i].keys {
+			if err := r.store.Delete(ctx, key); err != nil {
+				return err
+			}
+		}
+		r.logger.Info("old replica generation deleted", "generation", generations[i].name)
+	}
+	return nil
+}
+
+// upload compresses content and, if replicas are encrypted, encrypts it
+func (r *Replicator) upload(ctx context.Context, key string, content []byte) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(content); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+
+	if r.encrypter != nil {
+		var err error
+		if data, err = r.encrypter.EncryptBytes(data); err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", key, err)
+		}
+		key += encryptedSuffix
+	}
+	if err := r.store.Upload(ctx, key, data, "application/gzip"); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	return nil
+}
+
+// download reverses upload
+func download(ctx context.Context, store Storage, encrypter Encrypter, key string) ([]byte, error) {
+	data, err := store.Download(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	if strings.HasSuffix(key, encryptedSuffix) {
+		if encrypter == nil {
+			return nil, fmt.Errorf("%s is encrypted, but the encryption key is unavailable", key)
+		}
+		if data, err = encrypter.DecryptBytes(data); err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s: %w", key, err)
+		}
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", key, err)
+	}
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", key, err)
+	}
+	return content, nil
+}
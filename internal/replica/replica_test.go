@@ -0,0 +1,305 @@
+package replica
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/storage"
+)
+
+// memStorage keeps objects in memory
+type memStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{objects: make(map[string][]byte)}
+}
+
+func (m *memStorage) Upload(_ context.Context, key string, content []byte, _ string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = bytes.Clone(content)
+	return nil
+}
+
+func (m *memStorage) Download(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	content, ok := m.objects[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return content, nil
+}
+
+func (m *memStorage) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memStorage) List(_ context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var objects []storage.ObjectInfo
+	for key, content := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, storage.ObjectInfo{Key: key, Size: int64(len(content))})
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (m *memStorage) keys() []string {
+	objects, _ := m.List(context.Background(), "")
+	keys := make([]string, len(objects))
+	for i, object := range objects {
+		keys[i] = object.Key
+	}
+	return keys
+}
+
+// xorEncrypter stands in for the encryption service
+type xorEncrypter struct{}
+
+func (xorEncrypter) EncryptBytes(plaintext []byte) ([]byte, error) {
+	out := bytes.Clone(plaintext)
+	for i := range out {
+		out[i] ^= 0x5a
+	}
+	return out, nil
+}
+
+func (e xorEncrypter) DecryptBytes(data []byte) ([]byte, error) {
+	return e.EncryptBytes(data)
+}
+
+// openAppDB opens the database the way the app does with replication on
+func openAppDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_pragma=wal_autocheckpoint(0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	mustExec(t, db, "CREATE TABLE IF NOT EXISTS notes (id INTEGER PRIMARY KEY, body TEXT)")
+	return db
+}
+
+func mustExec(t *testing.T, db *sql.DB, query string, args ...any) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+func newTestReplicator(t *testing.T, path string, store Storage) *Replicator {
+	t.Helper()
+	r := New(path, store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := r.open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(r.close)
+	return r
+}
+
+func mustReplicate(t *testing.T, r *Replicator) {
+	t.Helper()
+	if err := r.replicate(context.Background()); err != nil {
+		t.Fatalf("replicate failed: %v", err)
+	}
+}
+
+// restoredNotes restores the replica as of at and returns its notes
+func restoredNotes(t *testing.T, store Storage, encrypter Encrypter, at time.Time) []string {
+	t.Helper()
+	output := filepath.Join(t.TempDir(), "restored.db")
+	if _, err := Restore(context.Background(), store, encrypter, output, at); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	db, err := sql.Open("sqlite", output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	rows, err := db.Query("SELECT body FROM notes ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rows.Close() }()
+	var notes []string
+	for rows.Next() {
+		var body string
+		if err := rows.Scan(&body); err != nil {
+			t.Fatal(err)
+		}
+		notes = append(notes, body)
+	}
+	return notes
+}
+
+func TestReplicator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snipo.db")
+	db := openAppDB(t, path)
+	store := newMemStorage()
+	r := newTestReplicator(t, path, store)
+
+	mustExec(t, db, "INSERT INTO notes (body) VALUES ('one')")
+	mustReplicate(t, r) // Snapshot
+	mustExec(t, db, "INSERT INTO notes (body) VALUES ('two')")
+	mustReplicate(t, r)
+	afterTwo := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	// Large enough to span many pages, then dropped so the file shrinks
+	mustExec(t, db, "CREATE TABLE big (data BLOB)")
+	mustExec(t, db, "INSERT INTO big VALUES (?)", bytes.Repeat([]byte("x"), 200<<10))
+	mustExec(t, db, "INSERT INTO notes (body) VALUES ('three')")
+	if err := r.checkpoint(context.Background()); err != nil {
+		t.Fatalf("checkpoint failed: %v", err)
+	}
+	if !r.checkpointed {
+		t.Fatal("expected the WAL to be checkpointed")
+	}
+	// The WAL restarts with this write
+	mustExec(t, db, "DROP TABLE big")
+	mustExec(t, db, "VACUUM")
+	mustExec(t, db, "INSERT INTO notes (body) VALUES ('four')")
+	mustReplicate(t, r)
+
+	var segments int
+	for _, key := range store.keys() {
+		if strings.Contains(key, "/wal/") {
+			segments++
+		}
+	}
+	if segments != 3 {
+		t.Errorf("expected 3 segments, got %v", store.keys())
+	}
+
+	if got := restoredNotes(t, store, nil, time.Now()); strings.Join(got, ",") != "one,two,three,four" {
+		t.Errorf("expected every note restored, got %v", got)
+	}
+	if got := restoredNotes(t, store, nil, afterTwo); strings.Join(got, ",") != "one,two" {
+		t.Errorf("expected the notes written by the timestamp, got %v", got)
+	}
+	if _, err := Restore(context.Background(), store, nil, filepath.Join(t.TempDir(), "x.db"), r.generationStart.Add(-time.Second)); err == nil {
+		t.Error("expected an error restoring to before the first snapshot")
+	}
+	if _, err := Restore(context.Background(), store, nil, path, time.Now()); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected restoring over the database to be refused, got %v", err)
+	}
+}
+
+func TestReplicator_ExternalCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snipo.db")
+	db := openAppDB(t, path)
+	store := newMemStorage()
+	r := newTestReplicator(t, path, store)
+
+	mustExec(t, db, "INSERT INTO notes (body) VALUES ('one')")
+	mustReplicate(t, r)
+	first := r.generation
+	mustExec(t, db, "INSERT INTO notes (body) VALUES ('two')")
+	mustReplicate(t, r)
+
+	// Checkpointed and reset before the replicator saw it
+	mustExec(t, db, "INSERT INTO notes (body) VALUES ('three')")
+	mustExec(t, db, "PRAGMA wal_checkpoint(TRUNCATE)")
+	mustExec(t, db, "INSERT INTO notes (body) VALUES ('four')")
+	mustReplicate(t, r)
+
+	if r.generation == first {
+		t.Fatal("expected a new generation")
+	}
+	if got := restoredNotes(t, store, nil, time.Now()); strings.Join(got, ",") != "one,two,three,four" {
+		t.Errorf("expected every note restored, got %v", got)
+	}
+}
+
+func TestReplicator_Encryption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snipo.db")
+	db := openAppDB(t, path)
+	store := newMemStorage()
+	r := newTestReplicator(t, path, store).WithEncryption(xorEncrypter{})
+
+	mustExec(t, db, "INSERT INTO notes (body) VALUES ('secret')")
+	mustReplicate(t, r)
+	mustExec(t, db, "INSERT INTO notes (body) VALUES ('also secret')")
+	mustReplicate(t, r)
+
+	for _, key := range store.keys() {
+		if !strings.HasSuffix(key, encryptedSuffix) {
+			t.Errorf("expected %s to be encrypted", key)
+		}
+	}
+	if _, err := Restore(context.Background(), store, nil, filepath.Join(t.TempDir(), "x.db"), time.Now()); err == nil {
+		t.Error("expected restoring without the key to fail")
+	}
+	if got := restoredNotes(t, store, xorEncrypter{}, time.Now()); strings.Join(got, ",") != "secret,also secret" {
+		t.Errorf("expected the notes restored, got %v", got)
+	}
+}
+
+func TestReplicator_Prune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snipo.db")
+	db := openAppDB(t, path)
+	store := newMemStorage()
+	r := newTestReplicator(t, path, store).WithIntervals(time.Second, 0, time.Hour)
+
+	var generations []string
+	for range 3 {
+		mustExec(t, db, "INSERT INTO notes (body) VALUES ('note')")
+		mustReplicate(t, r) // Always a snapshot, with no snapshot interval
+		generations = append(generations, r.generation)
+		time.Sleep(time.Millisecond)
+	}
+	for _, g := range generations {
+		if !strings.Contains(strings.Join(store.keys(), " "), g) {
+			t.Errorf("expected generation %s to be kept within the retention period", g)
+		}
+	}
+
+	r.retention = 0
+	if err := r.prune(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	keys := strings.Join(store.keys(), " ")
+	if strings.Contains(keys, generations[0]) || strings.Contains(keys, generations[1]) || !strings.Contains(keys, generations[2]) {
+		t.Errorf("expected only the current generation to be left, got %v", store.keys())
+	}
+}
+
+func TestReplicator_StartStop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snipo.db")
+	db := openAppDB(t, path)
+	store := newMemStorage()
+	r := New(path, store, slog.New(slog.NewTextHandler(io.Discard, nil))).WithIntervals(10*time.Millisecond, time.Hour, time.Hour)
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	for _, note := range []string{"one", "two", "three"} {
+		mustExec(t, db, "INSERT INTO notes (body) VALUES (?)", note)
+		time.Sleep(15 * time.Millisecond)
+	}
+	// Shipped by Stop if not before
+	mustExec(t, db, "INSERT INTO notes (body) VALUES ('four')")
+	r.Stop()
+
+	if got := restoredNotes(t, store, nil, time.Now()); strings.Join(got, ",") != "one,two,three,four" {
+		t.Errorf("expected every note restored, got %v", got)
+	}
+}
@@ -0,0 +1,203 @@
+package replica
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// generation is a snapshot and the segments shipped after it
+type generation struct {
+	name     string
+	start    time.Time
+	snapshot string // Key, empty if the snapshot is missing
+	segments []segment
+	keys     []string // Every object of the generation
+}
+
+// segment is frames shipped from the WAL at time
+type segment struct {
+	key   string
+	index int
+	time  time.Time
+}
+
+// listGenerations returns the generations in storage, oldest first
+func listGenerations(ctx context.Context, store Storage) ([]*generation, error) {
+	objects, err := store.List(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replicas: %w", err)
+	}
+
+	byName := make(map[string]*generation)
+	var generations []*generation
+	for _, object := range objects {
+		name, rest, ok := strings.Cut(strings.TrimPrefix(object.Key, prefix), "/")
+		if !ok {
+			continue
+		}
+		stamp, _, _ := strings.Cut(name, "-")
+		start, err := time.Parse(timeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		g := byName[name]
+		if g == nil {
+			g = &generation{name: name, start: start}
+			byName[name] = g
+			generations = append(generations, g)
+		}
+		g.keys = append(g.keys, object.Key)
+
+		base := strings.TrimSuffix(rest, encryptedSuffix)
+		if base == "snapshot.db.gz" {
+			g.snapshot = object.Key
+			continue
+		}
+		// wal/<index>-<time>.gz
+		file, ok := strings.CutPrefix(strings.TrimSuffix(base, ".gz"), "wal/")
+		if !ok {
+			continue
+		}
+		indexText, stamp, _ := strings.Cut(file, "-")
+		index, err := strconv.Atoi(indexText)
+		if err != nil {
+			continue
+		}
+		shipped, err := time.Parse(timeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		g.segments = append(g.segments, segment{key: object.Key, index: index, time: shipped})
+	}
+
+	sort.Slice(generations, func(i, j int) bool { return generations[i].name < generations[j].name })
+	for _, g := range generations {
+		sort.Slice(g.segments, func(i, j int) bool { return g.segments[i].index < g.segments[j].index })
+	}
+	return generations, nil
+}
+
+// RestoreResult describes a restored database
+type RestoreResult struct {
+	Generation string
+	Snapshot   time.Time // When the snapshot restored was taken
+	Segments   int       // WAL segments applied on top of it
+	RestoredTo time.Time // When the last of them was shipped, or the snapshot taken
+}
+
+// Restore writes the database as it was at the time at to output, which
+// must not exist: the latest snapshot taken by then, with the WAL segments
+// shipped after it up to at applied. encrypter may be nil if the replicas
+// aren't encrypted.
+func Restore(ctx context.Context, store Storage, encrypter Encrypter, output string, at time.Time) (*RestoreResult, error) {
+	// A WAL left next to the output would be applied to the restored
+	// database when it's opened
+	for _, path := range []string{output, output + "-wal"} {
+		if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s already exists", path)
+		}
+	}
+
+	generations, err := listGenerations(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	var g *generation
+	for i := len(generations) - 1; i >= 0; i-- {
+		if generations[i].snapshot != "" && !generations[i].start.After(at) {
+			g = generations[i]
+			break
+		}
+	}
+	if g == nil {
+		return nil, fmt.Errorf("no replica was taken by %s", at.UTC().Format(time.RFC3339))
+	}
+
+	snapshot, err := download(ctx, store, encrypter, g.snapshot)
+	if err != nil {
+		return nil, err
+	}
+	tmp := output + ".restoring"
+	if err := os.WriteFile(tmp, snapshot, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write database: %w", err)
+	}
+	defer func() {
+		for _, path := range []string{tmp, tmp + "-wal", tmp + "-shm"} {
+			_ = os.Remove(path)
+		}
+	}()
+
+	result := &RestoreResult{Generation: g.name, Snapshot: g.start, RestoredTo: g.start}
+	if err := applySegments(ctx, store, encrypter, tmp, g, at, result); err != nil {
+		return nil, err
+	}
+	if err := checkDatabase(ctx, tmp); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, output); err != nil {
+		return nil, fmt.Errorf("failed to move restored database into place: %w", err)
+	}
+	return result, nil
+}
+
+// applySegments applies the segments of g shipped by at to the database
+// file at path
+func applySegments(ctx context.Context, store Storage, encrypter Encrypter, path string, g *generation, at time.Time, result *RestoreResult) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	for i, seg := range g.segments {
+		if seg.time.After(at) {
+			break
+		}
+		if seg.index != i {
+			return fmt.Errorf("WAL segment %d of generation %s is missing", i, g.name)
+		}
+		content, err := download(ctx, store, encrypter, seg.key)
+		if err != nil {
+			return err
+		}
+		if len(content) < 4 {
+			return fmt.Errorf("WAL segment %s is truncated", seg.key)
+		}
+		if err := applyFrames(f, int(binary.BigEndian.Uint32(content)), content[4:]); err != nil {
+			return fmt.Errorf("failed to apply WAL segment %s: %w", seg.key, err)
+		}
+		result.Segments++
+		result.RestoredTo = seg.time
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to write database: %w", err)
+	}
+	return f.Close()
+}
+
+// checkDatabase runs SQLite's quick check on the restored database
+func checkDatabase(ctx context.Context, path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("failed to open restored database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var result string
+	if err := db.QueryRowContext(ctx, "PRAGMA quick_check").Scan(&result); err != nil {
+		return fmt.Errorf("failed to check restored database: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("restored database is corrupt: %s", result)
+	}
+	return nil
+}
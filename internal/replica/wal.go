@@ -0,0 +1,158 @@
+package replica
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// The SQLite write-ahead log format, https://sqlite.org/fileformat.html#the_write_ahead_log
+const (
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+	walMagicLE         = 0x377f0682
+	walMagicBE         = 0x377f0683
+)
+
+// walHeader is the part of a WAL header the replicator needs
+type walHeader struct {
+	pageSize     uint32
+	salt1, salt2 uint32
+	bigEndian    bool // Byte order of the checksums
+	s0, s1       uint32
+}
+
+// walPos is a point in a WAL just after a committed transaction, with the
+// running checksum needed to verify the frames that follow it
+type walPos struct {
+	header walHeader
+	offset int64
+	s0, s1 uint32
+}
+
+// start returns the position of the first frame of the WAL hdr heads
+func (hdr walHeader) start() walPos {
+	return walPos{header: hdr, offset: walHeaderSize, s0: hdr.s0, s1: hdr.s1}
+}
+
+func (p walPos) frameSize() int64 {
+	return walFrameHeaderSize + int64(p.header.pageSize)
+}
+
+// frames returns how many frames come before p
+func (p walPos) frames() int64 {
+	if p.offset < walHeaderSize {
+		return 0
+	}
+	return (p.offset - walHeaderSize) / p.frameSize()
+}
+
+// errNoWAL is returned while the WAL is missing or has no valid header,
+// as after a truncating checkpoint and before the next write
+var errNoWAL = errors.New("no write-ahead log")
+
+// readWALHeader reads and verifies the header of the WAL in f
+func readWALHeader(f io.ReaderAt) (walHeader, error) {
+	var b [walHeaderSize]byte
+	if _, err := f.ReadAt(b[:], 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return walHeader{}, errNoWAL
+		}
+		return walHeader{}, err
+	}
+
+	var hdr walHeader
+	switch binary.BigEndian.Uint32(b[0:]) {
+	case walMagicLE:
+	case walMagicBE:
+		hdr.bigEndian = true
+	default:
+		return walHeader{}, errNoWAL
+	}
+	hdr.pageSize = binary.BigEndian.Uint32(b[8:])
+	hdr.salt1 = binary.BigEndian.Uint32(b[16:])
+	hdr.salt2 = binary.BigEndian.Uint32(b[20:])
+	hdr.s0, hdr.s1 = walChecksum(hdr.bigEndian, 0, 0, b[:24])
+	if hdr.s0 != binary.BigEndian.Uint32(b[24:]) || hdr.s1 != binary.BigEndian.Uint32(b[28:]) {
+		// Being rewritten as the WAL restarts
+		return walHeader{}, errNoWAL
+	}
+	if hdr.pageSize < 512 || hdr.pageSize > 65536 || hdr.pageSize&(hdr.pageSize-1) != 0 {
+		return walHeader{}, fmt.Errorf("invalid WAL page size %d", hdr.pageSize)
+	}
+	return hdr, nil
+}
+
+// readFrames returns the frames of committed transactions in f after pos,
+// and the position after the last of them. It stops at the first frame
+// that fails verification: one being written, or left from before the
+// WAL restarted.
+func readFrames(f io.ReaderAt, pos walPos) ([]byte, walPos, error) {
+	end := pos
+	var out []byte
+	frame := make([]byte, pos.frameSize())
+	s0, s1 := pos.s0, pos.s1
+	for offset := pos.offset; ; offset += pos.frameSize() {
+		if _, err := f.ReadAt(frame, offset); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, pos, err
+		}
+		if binary.BigEndian.Uint32(frame[8:]) != pos.header.salt1 || binary.BigEndian.Uint32(frame[12:]) != pos.header.salt2 {
+			break
+		}
+		s0, s1 = walChecksum(pos.header.bigEndian, s0, s1, frame[:8])
+		s0, s1 = walChecksum(pos.header.bigEndian, s0, s1, frame[walFrameHeaderSize:])
+		if s0 != binary.BigEndian.Uint32(frame[16:]) || s1 != binary.BigEndian.Uint32(frame[20:]) {
+			break
+		}
+		out = append(out, frame...)
+		if binary.BigEndian.Uint32(frame[4:]) != 0 {
+			// A commit frame ends a transaction
+			end = walPos{header: pos.header, offset: offset + pos.frameSize(), s0: s0, s1: s1}
+		}
+	}
+	return out[:end.offset-pos.offset], end, nil
+}
+
+// walChecksum continues the checksum s0, s1 over b, as SQLite computes it
+func walChecksum(bigEndian bool, s0, s1 uint32, b []byte) (uint32, uint32) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	for i := 0; i+8 <= len(b); i += 8 {
+		s0 += order.Uint32(b[i:]) + s1
+		s1 += order.Uint32(b[i+4:]) + s0
+	}
+	return s0, s1
+}
+
+// applyFrames writes the pages of frames, whole transactions of pageSize
+// pages, to the database file f
+func applyFrames(f *os.File, pageSize int, frames []byte) error {
+	frameSize := walFrameHeaderSize + pageSize
+	if len(frames)%frameSize != 0 {
+		return fmt.Errorf("truncated frame")
+	}
+	for i := 0; i < len(frames); i += frameSize {
+		frame := frames[i : i+frameSize]
+		pgno := binary.BigEndian.Uint32(frame[0:])
+		if pgno == 0 {
+			return fmt.Errorf("invalid page number 0")
+		}
+		if _, err := f.WriteAt(frame[walFrameHeaderSize:], int64(pgno-1)*int64(pageSize)); err != nil {
+			return err
+		}
+		if commit := binary.BigEndian.Uint32(frame[4:]); commit != 0 {
+			// The database is commit pages long after the transaction
+			if err := f.Truncate(int64(commit) * int64(pageSize)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// ActivityGroupWindow is how close together changes of the same kind to a
+// snippet by one user must be to be folded into one feed item
+const ActivityGroupWindow = time.Hour
+
+// ActivityRetentionDays is how long activity is kept
+const ActivityRetentionDays = 90
+
+// ActivityRepository handles the snippet activity log
+type ActivityRepository struct {
+	db *sql.DB
+}
+
+// NewActivityRepository creates a new activity repository
+func NewActivityRepository(db *sql.DB) *ActivityRepository {
+	return &ActivityRepository{db: db}
+}
+
+// Record logs a change to a snippet, made by the user in ctx
+func (r *ActivityRepository) Record(ctx context.Context, snippetID, title, action string) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO activity (snippet_id, title, action, user_id, workspace_id) VALUES (?, ?, ?, ?, ?)`,
+		snippetID, title, action, ownerID(ctx), workspaceID(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record activity: %w", err)
+	}
+	return nil
+}
+
+// Feed returns the activity visible in ctx, newest first, with runs of the
+// same change folded into single items
+func (r *ActivityRepository) Feed(ctx context.Context, filter models.ActivityFilter) ([]models.ActivityItem, error) {
+	owner, args := ownerFilter(ctx, "a.user_id")
+	hidden, hiddenArgs := hiddenSnippets(ctx, "a.snippet_id")
+	query := `
+		SELECT a.snippet_id, a.title, a.action, a.user_id, COALESCE(u.username, ''), a.created_at
+		FROM activity a
+		LEFT JOIN users u ON u.id = a.user_id
+		WHERE 1=1` + owner + hidden
+	args = append(args, hiddenArgs...)
+
+	if filter.SnippetID != "" {
+		query += " AND a.snippet_id = ?"
+		args = append(args, filter.SnippetID)
+	}
+	if filter.UserID != nil {
+		query += " AND a.user_id = ?"
+		args = append(args, *filter.UserID)
+	}
+	if filter.Since != nil {
+		query += " AND a.created_at > ?"
+		args = append(args, filter.Since.UTC().Format(time.DateTime))
+	}
+	query += " ORDER BY a.id DESC"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activity: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	// Rows come newest first, so a change joins the item before it when it
+	// continues the same run
+	items := make([]models.ActivityItem, 0)
+	for rows.Next() {
+		var entry models.ActivityItem
+		if err := rows.Scan(&entry.SnippetID, &entry.Title, &entry.Action,
+			&entry.Actor.ID, &entry.Actor.Username, &entry.LastAt); err != nil {
+			return nil, fmt.Errorf("failed to scan activity: %w", err)
+		}
+
+		if n := len(items); n > 0 {
+			last := &items[n-1]
+			if last.SnippetID == entry.SnippetID && last.Action == entry.Action && last.Actor.ID == entry.Actor.ID &&
+				last.FirstAt.Sub(entry.LastAt) <= ActivityGroupWindow {
+				last.Count++
+				last.FirstAt = entry.LastAt
+				continue
+			}
+		}
+		if filter.Limit > 0 && len(items) == filter.Limit {
+			break
+		}
+		entry.Count = 1
+		entry.FirstAt = entry.LastAt
+		items = append(items, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		items[i].Summarize()
+	}
+	return items, nil
+}
+
+// Prune deletes activity older than ActivityRetentionDays
+func (r *ActivityRepository) Prune(ctx context.Context) (int64, error) {
+	before := time.Now().UTC().AddDate(0, 0, -ActivityRetentionDays).Format(time.DateTime)
+	result, err := r.db.ExecContext(ctx, `DELETE FROM activity WHERE created_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune activity: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestActivityRepository_Feed(t *testing.T) {
+	db := testutil.TestDB(t)
+	users := NewUserRepository(db)
+	workspaces := NewWorkspaceRepository(db)
+	folders := NewFolderRepository(db)
+	activity := NewActivityRepository(db)
+
+	alice, err := users.Create(testutil.TestContext(), "alice", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	bob, err := users.Create(testutil.TestContext(), "bob", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	team, err := workspaces.Create(testutil.TestContext(), &models.WorkspaceInput{Slug: "team", Name: "Team"})
+	if err != nil {
+		t.Fatalf("Create workspace failed: %v", err)
+	}
+
+	member := func(userID int64) context.Context {
+		return models.WithWorkspaceRole(models.WithWorkspaceID(models.WithUserID(testutil.TestContext(), userID), team.ID), models.WorkspaceRoleEditor)
+	}
+	aliceTeam, bobTeam := member(alice.ID), member(bob.ID)
+	alicePersonal := models.WithUserID(testutil.TestContext(), alice.ID)
+
+	record := func(ctx context.Context, snippetID, action string, ago time.Duration) {
+		t.Helper()
+		if err := activity.Record(ctx, snippetID, snippetID+" title", action); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+		if _, err := db.Exec(`UPDATE activity SET created_at = ? WHERE id = (SELECT MAX(id) FROM activity)`,
+			time.Now().UTC().Add(-ago).Format(time.DateTime)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	record(aliceTeam, "a", models.ActivityCreated, 5*time.Hour)
+	record(aliceTeam, "a", models.ActivityUpdated, 4*time.Hour)
+	record(aliceTeam, "a", models.ActivityUpdated, 3*time.Hour+30*time.Minute)
+	record(aliceTeam, "a", models.ActivityUpdated, time.Hour) // Too long after the others to join them
+	record(bobTeam, "a", models.ActivityUpdated, 30*time.Minute)
+	record(alicePersonal, "p", models.ActivityCreated, time.Minute)
+
+	items, err := activity.Feed(bobTeam, models.ActivityFilter{})
+	if err != nil {
+		t.Fatalf("Feed failed: %v", err)
+	}
+	want := []struct {
+		actor  int64
+		action string
+		count  int
+	}{
+		{bob.ID, models.ActivityUpdated, 1},
+		{alice.ID, models.ActivityUpdated, 1},
+		{alice.ID, models.ActivityUpdated, 2},
+		{alice.ID, models.ActivityCreated, 1},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("expected %d workspace items, got %+v", len(want), items)
+	}
+	for i, w := range want {
+		if items[i].Actor.ID != w.actor || items[i].Action != w.action || items[i].Count != w.count {
+			t.Errorf("item %d: expected %d %s x%d, got %+v", i, w.actor, w.action, w.count, items[i])
+		}
+	}
+	if items[0].Actor.Username != "bob" {
+		t.Errorf("expected the actor's username, got %q", items[0].Actor.Username)
+	}
+	if items[2].LastAt.Sub(items[2].FirstAt) != 30*time.Minute {
+		t.Errorf("expected the folded item to span 30m, got %v to %v", items[2].FirstAt, items[2].LastAt)
+	}
+
+	// Filters
+	since := time.Now().Add(-2 * time.Hour)
+	if recent, _ := activity.Feed(bobTeam, models.ActivityFilter{Since: &since}); len(recent) != 2 {
+		t.Errorf("expected 2 items since 2h ago, got %+v", recent)
+	}
+	if mine, _ := activity.Feed(bobTeam, models.ActivityFilter{UserID: &alice.ID, Limit: 2}); len(mine) != 2 || mine[1].Count != 2 {
+		t.Errorf("expected alice's 2 latest items, got %+v", mine)
+	}
+
+	// Personal activity stays personal
+	if personal, _ := activity.Feed(alicePersonal, models.ActivityFilter{}); len(personal) != 1 || personal[0].SnippetID != "p" {
+		t.Errorf("expected only alice's personal activity, got %+v", personal)
+	}
+
+	// Snippets in folders hidden from the user leave the feed
+	folder, err := folders.Create(aliceTeam, &models.FolderInput{Name: "private"})
+	if err != nil {
+		t.Fatalf("Create folder failed: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO snippets (id, title, content, workspace_id, user_id) VALUES ('a', 'a', 'x', ?, ?)`, team.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := folders.SetSnippetFolder(aliceTeam, "a", &folder.ID); err != nil {
+		t.Fatalf("SetSnippetFolder failed: %v", err)
+	}
+	if err := folders.SetAccess(aliceTeam, folder.ID, &models.FolderAccess{UserIDs: []int64{alice.ID}}); err != nil {
+		t.Fatalf("SetAccess failed: %v", err)
+	}
+	if hidden, _ := activity.Feed(bobTeam, models.ActivityFilter{}); len(hidden) != 0 {
+		t.Errorf("expected the hidden snippet's activity to be left out, got %+v", hidden)
+	}
+	if visible, _ := activity.Feed(aliceTeam, models.ActivityFilter{}); len(visible) != 4 {
+		t.Errorf("expected alice to still see the activity, got %+v", visible)
+	}
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// BotRepository handles chat bot configuration database operations
+type BotRepository struct {
+	db *sql.DB
+}
+
+// NewBotRepository creates a new bot repository
+func NewBotRepository(db *sql.DB) *BotRepository {
+	return &BotRepository{db: db}
+}
+
+// GetConfig retrieves the bot configuration, or nil if it was never saved
+func (r *BotRepository) GetConfig(ctx context.Context) (*models.BotConfig, error) {
+	query := `
+		SELECT enabled, platform, token_encrypted, homeserver_url, allowed_chats,
+		       tags, cursor, last_error, created_at, updated_at
+		FROM bot_config
+		WHERE id = 1
+	`
+
+	config := &models.BotConfig{}
+	var allowedChats, tags string
+	var lastError sql.NullString
+
+	err := r.db.QueryRowContext(ctx, query).Scan(
+		&config.Enabled,
+		&config.Platform,
+		&config.TokenEncrypted,
+		&config.HomeserverURL,
+		&allowedChats,
+		&tags,
+		&config.Cursor,
+		&lastError,
+		&config.CreatedAt,
+		&config.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bot config: %w", err)
+	}
+
+	config.HasToken = config.TokenEncrypted != ""
+	config.AllowedChats = splitList(allowedChats)
+	config.Tags = splitList(tags)
+	if lastError.Valid {
+		config.LastError = &lastError.String
+	}
+
+	return config, nil
+}
+
+// SaveConfig creates or updates the bot configuration. The cursor is reset
+// when the platform or token changes, since it belongs to the old bot.
+func (r *BotRepository) SaveConfig(ctx context.Context, config *models.BotConfig) error {
+	query := `
+		INSERT INTO bot_config (
+			id, enabled, platform, token_encrypted, homeserver_url, allowed_chats, tags, updated_at
+		) VALUES (1, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(id) DO UPDATE SET
+			enabled = excluded.enabled,
+			platform = excluded.platform,
+			token_encrypted = excluded.token_encrypted,
+			homeserver_url = excluded.homeserver_url,
+			allowed_chats = excluded.allowed_chats,
+			tags = excluded.tags,
+			cursor = CASE
+				WHEN bot_config.platform = excluded.platform
+				 AND bot_config.token_encrypted = excluded.token_encrypted
+				 AND bot_config.homeserver_url = excluded.homeserver_url
+				THEN bot_config.cursor ELSE '' END,
+			last_error = NULL,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		config.Enabled,
+		config.Platform,
+		config.TokenEncrypted,
+		config.HomeserverURL,
+		strings.Join(config.AllowedChats, ","),
+		strings.Join(config.Tags, ","),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save bot config: %w", err)
+	}
+
+	return nil
+}
+
+// SetCursor stores how far the bot has read its updates
+func (r *BotRepository) SetCursor(ctx context.Context, cursor string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE bot_config SET cursor = ? WHERE id = 1`, cursor)
+	if err != nil {
+		return fmt.Errorf("failed to save bot cursor: %w", err)
+	}
+	return nil
+}
+
+// SetLastError records why the last poll failed, or clears it when nil
+func (r *BotRepository) SetLastError(ctx context.Context, message *string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE bot_config SET last_error = ? WHERE id = 1`, message)
+	if err != nil {
+		return fmt.Errorf("failed to save bot error: %w", err)
+	}
+	return nil
+}
+
+// DeleteConfig deletes the bot configuration
+func (r *BotRepository) DeleteConfig(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM bot_config WHERE id = 1`)
+	if err != nil {
+		return fmt.Errorf("failed to delete bot config: %w", err)
+	}
+	return nil
+}
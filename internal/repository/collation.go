@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"cmp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"modernc.org/sqlite"
+)
+
+// naturalCollation orders text case-insensitively with embedded numbers
+// compared by value, so "file2" sorts before "file10"
+const naturalCollation = "NATURAL_NOCASE"
+
+func init() {
+	// Collations apply to connections opened after registration, which is
+	// every connection since the repositories are imported before any DB is opened
+	sqlite.MustRegisterCollationUtf8(naturalCollation, naturalCompare)
+}
+
+// naturalCompare compares two strings in natural order. Strings that are
+// equal under that ordering fall back to a byte comparison to keep the
+// order total.
+func naturalCompare(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isASCIIDigit(a[i]) && isASCIIDigit(b[j]) {
+			si, sj := i, j
+			for i < len(a) && isASCIIDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isASCIIDigit(b[j]) {
+				j++
+			}
+			na := strings.TrimLeft(a[si:i], "0")
+			nb := strings.TrimLeft(b[sj:j], "0")
+			if len(na) != len(nb) {
+				return cmp.Compare(len(na), len(nb))
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			continue
+		}
+
+		ra, sizeA := utf8.DecodeRuneInString(a[i:])
+		rb, sizeB := utf8.DecodeRuneInString(b[j:])
+		if la, lb := unicode.ToLower(ra), unicode.ToLower(rb); la != lb {
+			return cmp.Compare(la, lb)
+		}
+		i += sizeA
+		j += sizeB
+	}
+
+	if c := cmp.Compare(len(a)-i, len(b)-j); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func isASCIIDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
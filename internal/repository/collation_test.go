@@ -0,0 +1,26 @@
+package repository
+
+import "testing"
+
+func TestNaturalCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"file2", "file10", -1},
+		{"file10", "file2", 1},
+		{"apple", "Banana", -1},
+		{"Apple", "apple", -1}, // equal ignoring case, byte order breaks the tie
+		{"v1.9", "v1.10", -1},
+		{"item 007", "item 8", -1},
+		{"abc", "abcd", -1},
+		{"same", "same", 0},
+		{"émile", "Zoe", 1},
+	}
+
+	for _, tt := range tests {
+		if got := naturalCompare(tt.a, tt.b); got != tt.want {
+			t.Errorf("naturalCompare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
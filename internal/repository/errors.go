@@ -6,4 +6,6 @@ import "errors"
 var (
 	ErrNotFound      = errors.New("not found")
 	ErrAlreadyExists = errors.New("already exists")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrForbidden     = errors.New("forbidden")
 )
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/MohamedElashri/snipo/internal/models"
 )
@@ -27,13 +28,13 @@ func (r *FolderRepository) Create(ctx context.Context, input *models.FolderInput
 	}
 
 	query := `
-		INSERT INTO folders (name, parent_id, icon, sort_order)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO folders (name, parent_id, icon, sort_order, user_id, workspace_id)
+		VALUES (?, ?, ?, ?, ?, ?)
 		RETURNING id, name, parent_id, icon, sort_order, created_at
 	`
 
 	folder := &models.Folder{}
-	err := r.db.QueryRowContext(ctx, query, input.Name, input.ParentID, icon, input.SortOrder).Scan(
+	err := r.db.QueryRowContext(ctx, query, input.Name, input.ParentID, icon, input.SortOrder, ownerID(ctx), workspaceID(ctx)).Scan(
 		&folder.ID,
 		&folder.Name,
 		&folder.ParentID,
@@ -50,10 +51,11 @@ func (r *FolderRepository) Create(ctx context.Context, input *models.FolderInput
 
 // GetByID retrieves a folder by ID
 func (r *FolderRepository) GetByID(ctx context.Context, id int64) (*models.Folder, error) {
-	query := `SELECT id, name, parent_id, icon, sort_order, created_at FROM folders WHERE id = ?`
+	owner, ownerArgs := folderFilter(ctx, "user_id")
+	query := `SELECT id, name, parent_id, icon, sort_order, created_at FROM folders WHERE id = ? AND deleted_at IS NULL` + owner
 
 	folder := &models.Folder{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, append([]interface{}{id}, ownerArgs...)...).Scan(
 		&folder.ID,
 		&folder.Name,
 		&folder.ParentID,
@@ -73,16 +75,18 @@ func (r *FolderRepository) GetByID(ctx context.Context, id int64) (*models.Folde
 
 // List retrieves all folders (flat list) with snippet counts
 func (r *FolderRepository) List(ctx context.Context) ([]models.Folder, error) {
+	owner, ownerArgs := folderFilter(ctx, "f.user_id")
 	query := `
 		SELECT f.id, f.name, f.parent_id, f.icon, f.sort_order, f.created_at,
 		       (SELECT COUNT(*) FROM snippet_folders sf 
 		        INNER JOIN snippets s ON s.id = sf.snippet_id 
 		        WHERE sf.folder_id = f.id AND s.is_archived = 0) as snippet_count
 		FROM folders f
+		WHERE f.deleted_at IS NULL` + owner + `
 		ORDER BY f.sort_order ASC, f.name ASC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, ownerArgs...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list folders: %w", err)
 	}
@@ -169,15 +173,17 @@ func (r *FolderRepository) Update(ctx context.Context, id int64, input *models.F
 		icon = "folder"
 	}
 
+	owner, ownerArgs := folderFilter(ctx, "user_id")
 	query := `
 		UPDATE folders
 		SET name = ?, parent_id = ?, icon = ?, sort_order = ?
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL` + owner + `
 		RETURNING id, name, parent_id, icon, sort_order, created_at
 	`
 
+	args := append([]interface{}{input.Name, input.ParentID, icon, input.SortOrder, id}, ownerArgs...)
 	folder := &models.Folder{}
-	err := r.db.QueryRowContext(ctx, query, input.Name, input.ParentID, icon, input.SortOrder, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(
 		&folder.ID,
 		&folder.Name,
 		&folder.ParentID,
@@ -195,25 +201,367 @@ func (r *FolderRepository) Update(ctx context.Context, id int64, input *models.F
 	return folder, nil
 }
 
-// Delete deletes a folder
-func (r *FolderRepository) Delete(ctx context.Context, id int64) error {
-	result, err := r.db.ExecContext(ctx, `DELETE FROM folders WHERE id = ?`, id)
+// folderSubtreeCTE selects a folder and all of its descendants
+const folderSubtreeCTE = `
+	WITH RECURSIVE subtree(id) AS (
+		SELECT ?
+		UNION
+		SELECT f.id FROM folders f JOIN subtree s ON f.parent_id = s.id
+	)`
+
+// Delete deletes a folder in a single transaction. mode is one of the
+// FolderDelete constants: move_to_parent and orphan keep the folder's
+// subfolders and snippets, trash deletes the whole subtree and its snippets.
+// When the trash is enabled, deleted folders and snippets go to the trash
+// and keep their snippet associations, so Restore can put them back.
+func (r *FolderRepository) Delete(ctx context.Context, id int64, mode string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	owner, ownerArgs := folderFilter(ctx, "user_id")
+	var parentID *int64
+	err = tx.QueryRowContext(ctx, `SELECT parent_id FROM folders WHERE id = ? AND deleted_at IS NULL`+owner, append([]interface{}{id}, ownerArgs...)...).Scan(&parentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to get folder: %w", err)
+	}
+
+	trashEnabled, err := settingBool(ctx, tx, "trash_enabled")
+	if err != nil {
+		return fmt.Errorf("failed to check trash settings: %w", err)
+	}
+
+	switch mode {
+	case models.FolderDeleteMoveToParent, models.FolderDeleteOrphan:
+		newParentID := parentID
+		if mode == models.FolderDeleteOrphan {
+			newParentID = nil
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE folders SET parent_id = ? WHERE parent_id = ?`, newParentID, id); err != nil {
+			return fmt.Errorf("failed to move subfolders: %w", err)
+		}
+		if newParentID != nil {
+			_, err := tx.ExecContext(ctx, `
+				INSERT OR IGNORE INTO snippet_folders (snippet_id, folder_id)
+				SELECT snippet_id, ? FROM snippet_folders WHERE folder_id = ?
+			`, *newParentID, id)
+			if err != nil {
+				return fmt.Errorf("failed to move snippets: %w", err)
+			}
+		}
+		if trashEnabled {
+			if _, err := tx.ExecContext(ctx, `UPDATE folders SET deleted_at = ? WHERE id = ?`, trashTimestamp(), id); err != nil {
+				return fmt.Errorf("failed to trash folder: %w", err)
+			}
+			break
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM snippet_folders WHERE folder_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to unfile snippets: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE remote_sources SET folder_id = NULL WHERE folder_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to detach remote sources: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM folder_access WHERE folder_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete folder access: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM folders WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete folder: %w", err)
+		}
+
+	case models.FolderDeleteTrash:
+		if trashEnabled {
+			if err := trashFolderSubtree(ctx, tx, id); err != nil {
+				return err
+			}
+			break
+		}
+		if err := deleteFolderSubtree(ctx, tx, id); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unknown folder delete mode: %s", mode)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// trashTimestamp returns the current time as CURRENT_TIMESTAMP formats it.
+// Folders and snippets trashed together share one value, which is how
+// Restore finds what to bring back.
+func trashTimestamp() string {
+	return time.Now().UTC().Format(time.DateTime)
+}
+
+// trashFolderSubtree moves a folder, its descendants and every snippet filed
+// in them to the trash
+func trashFolderSubtree(ctx context.Context, tx *sql.Tx, id int64) error {
+	deletedAt := trashTimestamp()
+	_, err := tx.ExecContext(ctx, folderSubtreeCTE+`
+		UPDATE snippets
+		SET deleted_at = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE deleted_at IS NULL
+		  AND id IN (SELECT snippet_id FROM snippet_folders WHERE folder_id IN (SELECT id FROM subtree))
+	`, id, deletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to trash folder snippets: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, folderSubtreeCTE+`
+		UPDATE folders SET deleted_at = ?
+		WHERE deleted_at IS NULL AND id IN (SELECT id FROM subtree)
+	`, id, deletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to trash folders: %w", err)
+	}
+	return nil
+}
+
+// deleteFolderSubtree deletes a folder, its descendants and every snippet
+// filed in them. Related rows are removed explicitly in case CASCADE doesn't
+// work, as in SnippetRepository.Delete.
+func deleteFolderSubtree(ctx context.Context, tx *sql.Tx, id int64) error {
+	rows, err := tx.QueryContext(ctx, folderSubtreeCTE+`
+		SELECT snippet_id FROM snippet_folders WHERE folder_id IN (SELECT id FROM subtree)`, id)
+	if err != nil {
+		return fmt.Errorf("failed to list folder snippets: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var snippetID string
+		if err := rows.Scan(&snippetID); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan snippet id: %w", err)
+		}
+		ids = append(ids, snippetID)
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("failed to list folder snippets: %w", err)
+	}
+	for _, snippetID := range ids {
+		for _, query := range []string{
+			"DELETE FROM snippet_tags WHERE snippet_id = ?",
+			"DELETE FROM snippet_folders WHERE snippet_id = ?",
+			"DELETE FROM snippet_files WHERE snippet_id = ?",
+			"DELETE FROM snippets WHERE id = ?",
+		} {
+			if _, err := tx.ExecContext(ctx, query, snippetID); err != nil {
+				return fmt.Errorf("failed to delete folder snippet: %w", err)
+			}
+		}
+	}
+
+	for _, query := range []string{
+		"UPDATE remote_sources SET folder_id = NULL WHERE folder_id IN (SELECT id FROM subtree)",
+		"DELETE FROM snippet_folders WHERE folder_id IN (SELECT id FROM subtree)",
+		"DELETE FROM folder_access WHERE folder_id IN (SELECT id FROM subtree)",
+		"DELETE FROM folders WHERE id IN (SELECT id FROM subtree)",
+	} {
+		if _, err := tx.ExecContext(ctx, folderSubtreeCTE+" "+query, id); err != nil {
+			return fmt.Errorf("failed to delete folders: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListDeleted retrieves the folders in the trash, most recently deleted
+// first. Subfolders trashed along with their parent are left out; restoring
+// the parent brings them back.
+func (r *FolderRepository) ListDeleted(ctx context.Context) ([]models.Folder, error) {
+	owner, ownerArgs := folderFilter(ctx, "f.user_id")
+	query := `
+		SELECT f.id, f.name, f.parent_id, f.icon, f.sort_order, f.created_at, f.deleted_at,
+		       (SELECT COUNT(*) FROM snippet_folders sf WHERE sf.folder_id = f.id) as snippet_count
+		FROM folders f
+		WHERE f.deleted_at IS NOT NULL
+		  AND (f.parent_id IS NULL OR f.parent_id NOT IN (SELECT id FROM folders WHERE deleted_at IS NOT NULL))` + owner + `
+		ORDER BY f.deleted_at DESC, f.name ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, ownerArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted folders: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	folders := []models.Folder{}
+	for rows.Next() {
+		var folder models.Folder
+		if err := rows.Scan(
+			&folder.ID,
+			&folder.Name,
+			&folder.ParentID,
+			&folder.Icon,
+			&folder.SortOrder,
+			&folder.CreatedAt,
+			&folder.DeletedAt,
+			&folder.SnippetCount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan folder: %w", err)
+		}
+		folders = append(folders, folder)
+	}
+
+	return folders, rows.Err()
+}
+
+// Restore takes a folder out of the trash together with the subfolders and
+// snippets deleted with it. Snippets still filed in the restored folders
+// leave the folder they were moved to, and a folder whose parent is gone or
+// in the trash becomes a root folder.
+func (r *FolderRepository) Restore(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	owner, ownerArgs := folderFilter(ctx, "user_id")
+	// Read the raw text: the driver would parse it into a time.Time
+	var deletedAt string
+	err = tx.QueryRowContext(ctx, `SELECT CAST(deleted_at AS TEXT) FROM folders WHERE id = ? AND deleted_at IS NOT NULL`+owner, append([]interface{}{id}, ownerArgs...)...).Scan(&deletedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to get folder: %w", err)
+	}
+
+	restoreSteps := []struct {
+		query string
+		args  []interface{}
+	}{
+		{folderSubtreeCTE + `
+			UPDATE snippets SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+			WHERE deleted_at = ? AND id IN (
+				SELECT sf.snippet_id FROM snippet_folders sf JOIN folders f ON f.id = sf.folder_id
+				WHERE f.id IN (SELECT id FROM subtree) AND f.deleted_at = ?
+			)`, []interface{}{id, deletedAt, deletedAt}},
+		{folderSubtreeCTE + `
+			UPDATE folders SET deleted_at = NULL
+			WHERE id IN (SELECT id FROM subtree) AND deleted_at = ?`, []interface{}{id, deletedAt}},
+		{`UPDATE folders SET parent_id = NULL
+			WHERE id = ? AND parent_id NOT IN (SELECT id FROM folders WHERE deleted_at IS NULL)`, []interface{}{id}},
+		{folderSubtreeCTE + `, restored(id) AS (
+				SELECT id FROM folders WHERE id IN (SELECT id FROM subtree) AND deleted_at IS NULL
+			)
+			DELETE FROM snippet_folders
+			WHERE folder_id NOT IN (SELECT id FROM restored)
+			  AND snippet_id IN (SELECT snippet_id FROM snippet_folders WHERE folder_id IN (SELECT id FROM restored))`, []interface{}{id}},
+	}
+	for _, step := range restoreSteps {
+		if _, err := tx.ExecContext(ctx, step.query, step.args...); err != nil {
+			return fmt.Errorf("failed to restore folder: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Purge permanently deletes a folder in the trash and the subfolders in the
+// trash below it. Their snippets are not deleted; those in the trash are
+// purged with the rest of the snippet trash.
+func (r *FolderRepository) Purge(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to delete folder: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	rows, err := result.RowsAffected()
+	owner, ownerArgs := folderFilter(ctx, "user_id")
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT 1 FROM folders WHERE id = ? AND deleted_at IS NOT NULL`+owner, append([]interface{}{id}, ownerArgs...)...).Scan(&exists)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to get folder: %w", err)
 	}
 
-	if rows == 0 {
-		return ErrNotFound
+	if err := purgeFolder(ctx, tx, id); err != nil {
+		return err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
+// trashedSubtreeCTE selects a folder and its descendants in the trash
+const trashedSubtreeCTE = `
+	WITH RECURSIVE purged(id) AS (
+		SELECT ?
+		UNION
+		SELECT f.id FROM folders f JOIN purged p ON f.parent_id = p.id WHERE f.deleted_at IS NOT NULL
+	)`
+
+// purgeFolder deletes a folder and its descendants in the trash. Folders
+// outside the trash below them become root folders rather than being
+// deleted by CASCADE.
+func purgeFolder(ctx context.Context, tx *sql.Tx, id int64) error {
+	for _, query := range []string{
+		"UPDATE folders SET parent_id = NULL WHERE parent_id IN (SELECT id FROM purged) AND id NOT IN (SELECT id FROM purged)",
+		"UPDATE remote_sources SET folder_id = NULL WHERE folder_id IN (SELECT id FROM purged)",
+		"DELETE FROM snippet_folders WHERE folder_id IN (SELECT id FROM purged)",
+		"DELETE FROM folder_access WHERE folder_id IN (SELECT id FROM purged)",
+		"DELETE FROM folders WHERE id IN (SELECT id FROM purged)",
+	} {
+		if _, err := tx.ExecContext(ctx, trashedSubtreeCTE+" "+query, id); err != nil {
+			return fmt.Errorf("failed to purge folders: %w", err)
+		}
+	}
+	return nil
+}
+
+// CleanupDeleted permanently deletes folders that have been in the trash for
+// more than days days
+func (r *FolderRepository) CleanupDeleted(ctx context.Context, days int) (int64, error) {
+	cutoff := time.Now().UTC().AddDate(0, 0, -days)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM folders WHERE deleted_at < ?`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query old folders: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	_ = rows.Close()
+
+	for _, id := range ids {
+		if err := purgeFolder(ctx, tx, id); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return int64(len(ids)), nil
+}
+
 // Move moves a folder to a new parent
 func (r *FolderRepository) Move(ctx context.Context, id int64, newParentID *int64) (*models.Folder, error) {
 	// Check for circular reference
@@ -223,15 +571,16 @@ func (r *FolderRepository) Move(ctx context.Context, id int64, newParentID *int6
 		}
 	}
 
+	owner, ownerArgs := folderFilter(ctx, "user_id")
 	query := `
 		UPDATE folders
 		SET parent_id = ?
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL` + owner + `
 		RETURNING id, name, parent_id, icon, sort_order, created_at
 	`
 
 	folder := &models.Folder{}
-	err := r.db.QueryRowContext(ctx, query, newParentID, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, append([]interface{}{newParentID, id}, ownerArgs...)...).Scan(
 		&folder.ID,
 		&folder.Name,
 		&folder.ParentID,
@@ -275,6 +624,91 @@ func (r *FolderRepository) checkCircularReference(ctx context.Context, folderID,
 	}
 }
 
+// GetAccess returns who a folder is restricted to
+func (r *FolderRepository) GetAccess(ctx context.Context, id int64) (*models.FolderAccess, error) {
+	if err := r.checkAccessManager(ctx, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT user_id, role FROM folder_access WHERE folder_id = ? ORDER BY user_id, role`, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get folder access: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	access := &models.FolderAccess{UserIDs: make([]int64, 0), Roles: make([]string, 0)}
+	for rows.Next() {
+		var userID int64
+		var role string
+		if err := rows.Scan(&userID, &role); err != nil {
+			return nil, fmt.Errorf("failed to scan folder access: %w", err)
+		}
+		if role != "" {
+			access.Roles = append(access.Roles, role)
+		} else {
+			access.UserIDs = append(access.UserIDs, userID)
+		}
+	}
+
+	return access, rows.Err()
+}
+
+// SetAccess replaces who a folder is restricted to; empty lists make it
+// visible to everyone again
+func (r *FolderRepository) SetAccess(ctx context.Context, id int64, access *models.FolderAccess) error {
+	if err := r.checkAccessManager(ctx, id); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM folder_access WHERE folder_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to clear folder access: %w", err)
+	}
+	for _, userID := range access.UserIDs {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO folder_access (folder_id, user_id) VALUES (?, ?)`, id, userID); err != nil {
+			return fmt.Errorf("failed to set folder access: %w", err)
+		}
+	}
+	for _, role := range access.Roles {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO folder_access (folder_id, role) VALUES (?, ?)`, id, role); err != nil {
+			return fmt.Errorf("failed to set folder access: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// checkAccessManager makes sure the user in ctx may manage who a folder is
+// restricted to: its creator, or a workspace admin, who can also reach
+// folders hidden from them. Other members get ErrForbidden.
+func (r *FolderRepository) checkAccessManager(ctx context.Context, id int64) error {
+	owner, ownerArgs := ownerFilter(ctx, "user_id")
+	var creator int64
+	err := r.db.QueryRowContext(ctx, `SELECT user_id FROM folders WHERE id = ?`+owner, append([]interface{}{id}, ownerArgs...)...).Scan(&creator)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to get folder: %w", err)
+	}
+
+	role := models.WorkspaceRoleFromContext(ctx)
+	if creator != ownerID(ctx) && role != "" && role != models.WorkspaceRoleAdmin {
+		return ErrForbidden
+	}
+	return nil
+}
+
 // GetFolderSnippetCount returns the number of snippets in a folder
 func (r *FolderRepository) GetFolderSnippetCount(ctx context.Context, folderID int64) (int, error) {
 	var count int
@@ -296,7 +730,7 @@ func (r *FolderRepository) GetSnippetFolders(ctx context.Context, snippetID stri
 		SELECT f.id, f.name, f.parent_id, f.icon, f.sort_order, f.created_at
 		FROM folders f
 		JOIN snippet_folders sf ON f.id = sf.folder_id
-		WHERE sf.snippet_id = ?
+		WHERE sf.snippet_id = ? AND f.deleted_at IS NULL
 		ORDER BY f.name ASC
 	`
 
@@ -345,6 +779,16 @@ func (r *FolderRepository) SetSnippetFolder(ctx context.Context, snippetID strin
 
 	// Add new folder association if provided
 	if folderID != nil {
+		owner, ownerArgs := folderFilter(ctx, "user_id")
+		var exists int
+		err = tx.QueryRowContext(ctx, `SELECT 1 FROM folders WHERE id = ? AND deleted_at IS NULL`+owner, append([]interface{}{*folderID}, ownerArgs...)...).Scan(&exists)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get folder: %w", err)
+		}
+
 		_, err = tx.ExecContext(ctx,
 			`INSERT INTO snippet_folders (snippet_id, folder_id) VALUES (?, ?)`,
 			snippetID, *folderID,
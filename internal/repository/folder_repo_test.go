@@ -1,6 +1,10 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
 	"testing"
 
 	"github.com/MohamedElashri/snipo/internal/models"
@@ -248,7 +252,7 @@ func TestFolderRepository_Delete(t *testing.T) {
 	}
 
 	// Delete it
-	err = repo.Delete(ctx, created.ID)
+	err = repo.Delete(ctx, created.ID, models.FolderDeleteMoveToParent)
 	if err != nil {
 		t.Fatalf("Delete failed: %v", err)
 	}
@@ -265,12 +269,298 @@ func TestFolderRepository_Delete_NotFound(t *testing.T) {
 	repo := NewFolderRepository(db)
 	ctx := testutil.TestContext()
 
-	err := repo.Delete(ctx, 99999)
+	err := repo.Delete(ctx, 99999, models.FolderDeleteMoveToParent)
 	if err != ErrNotFound {
 		t.Errorf("expected ErrNotFound, got %v", err)
 	}
 }
 
+// folderDeleteFixture creates root > parent > child with a snippet filed in
+// parent and another in child
+func folderDeleteFixture(t *testing.T, db *sql.DB) (root, parent, child *models.Folder, inParent, inChild *models.Snippet) {
+	t.Helper()
+	ctx := testutil.TestContext()
+	folders := NewFolderRepository(db)
+	snippets := NewSnippetRepository(db)
+
+	var err error
+	if root, err = folders.Create(ctx, &models.FolderInput{Name: "Root"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if parent, err = folders.Create(ctx, &models.FolderInput{Name: "Parent", ParentID: &root.ID}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if child, err = folders.Create(ctx, &models.FolderInput{Name: "Child", ParentID: &parent.ID}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if inParent, err = snippets.Create(ctx, &models.SnippetInput{Title: "In parent", Content: "a", Language: "go"}); err != nil {
+		t.Fatalf("Create snippet failed: %v", err)
+	}
+	if inChild, err = snippets.Create(ctx, &models.SnippetInput{Title: "In child", Content: "b", Language: "go"}); err != nil {
+		t.Fatalf("Create snippet failed: %v", err)
+	}
+	if err := folders.SetSnippetFolder(ctx, inParent.ID, &parent.ID); err != nil {
+		t.Fatalf("SetSnippetFolder failed: %v", err)
+	}
+	if err := folders.SetSnippetFolder(ctx, inChild.ID, &child.ID); err != nil {
+		t.Fatalf("SetSnippetFolder failed: %v", err)
+	}
+	return root, parent, child, inParent, inChild
+}
+
+func snippetFolderIDs(t *testing.T, repo *FolderRepository, snippetID string) []int64 {
+	t.Helper()
+	folders, err := repo.GetSnippetFolders(testutil.TestContext(), snippetID)
+	if err != nil {
+		t.Fatalf("GetSnippetFolders failed: %v", err)
+	}
+	ids := make([]int64, len(folders))
+	for i, f := range folders {
+		ids[i] = f.ID
+	}
+	return ids
+}
+
+func TestFolderRepository_Delete_MoveToParent(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewFolderRepository(db)
+	ctx := testutil.TestContext()
+	root, parent, child, inParent, inChild := folderDeleteFixture(t, db)
+
+	if err := repo.Delete(ctx, parent.ID, models.FolderDeleteMoveToParent); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	moved, err := repo.GetByID(ctx, child.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if moved.ParentID == nil || *moved.ParentID != root.ID {
+		t.Errorf("expected child to move under root, got parent %v", moved.ParentID)
+	}
+	if ids := snippetFolderIDs(t, repo, inParent.ID); len(ids) != 1 || ids[0] != root.ID {
+		t.Errorf("expected snippet to move to root folder, got %v", ids)
+	}
+	if ids := snippetFolderIDs(t, repo, inChild.ID); len(ids) != 1 || ids[0] != child.ID {
+		t.Errorf("expected nested snippet to stay in child, got %v", ids)
+	}
+}
+
+func TestFolderRepository_Delete_Orphan(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewFolderRepository(db)
+	ctx := testutil.TestContext()
+	_, parent, child, inParent, _ := folderDeleteFixture(t, db)
+
+	if err := repo.Delete(ctx, parent.ID, models.FolderDeleteOrphan); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	orphaned, err := repo.GetByID(ctx, child.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if orphaned.ParentID != nil {
+		t.Errorf("expected child to become a root folder, got parent %d", *orphaned.ParentID)
+	}
+	if ids := snippetFolderIDs(t, repo, inParent.ID); len(ids) != 0 {
+		t.Errorf("expected snippet to be unfiled, got %v", ids)
+	}
+	snippet, err := NewSnippetRepository(db).GetByID(ctx, inParent.ID)
+	if err != nil || snippet == nil {
+		t.Errorf("expected snippet to survive, got %v (err %v)", snippet, err)
+	}
+}
+
+func TestFolderRepository_Delete_Trash(t *testing.T) {
+	for _, trashEnabled := range []bool{true, false} {
+		db := testutil.TestDB(t)
+		repo := NewFolderRepository(db)
+		ctx := testutil.TestContext()
+		if err := NewSettingsRepository(db).Set(ctx, "trash_enabled", strconv.FormatBool(trashEnabled)); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		root, parent, child, inParent, inChild := folderDeleteFixture(t, db)
+
+		if err := repo.Delete(ctx, parent.ID, models.FolderDeleteTrash); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+
+		for _, id := range []int64{parent.ID, child.ID} {
+			if _, err := repo.GetByID(ctx, id); err != ErrNotFound {
+				t.Errorf("expected folder %d to be deleted, got %v", id, err)
+			}
+		}
+		if _, err := repo.GetByID(ctx, root.ID); err != nil {
+			t.Errorf("expected root folder to survive, got %v", err)
+		}
+
+		for _, s := range []*models.Snippet{inParent, inChild} {
+			var deletedAt sql.NullTime
+			err := db.QueryRowContext(ctx, "SELECT deleted_at FROM snippets WHERE id = ?", s.ID).Scan(&deletedAt)
+			switch {
+			case trashEnabled && (err != nil || !deletedAt.Valid):
+				t.Errorf("expected %s to be in the trash, got %v (err %v)", s.Title, deletedAt, err)
+			case !trashEnabled && err != sql.ErrNoRows:
+				t.Errorf("expected %s to be deleted, got err %v", s.Title, err)
+			}
+			if ids := snippetFolderIDs(t, repo, s.ID); len(ids) != 0 {
+				t.Errorf("expected %s to be unfiled, got %v", s.Title, ids)
+			}
+		}
+	}
+}
+
+func TestFolderRepository_Restore(t *testing.T) {
+	modes := []string{models.FolderDeleteMoveToParent, models.FolderDeleteOrphan, models.FolderDeleteTrash}
+	for _, mode := range modes {
+		t.Run(mode, func(t *testing.T) {
+			db := testutil.TestDB(t)
+			repo := NewFolderRepository(db)
+			snippets := NewSnippetRepository(db)
+			ctx := testutil.TestContext()
+			root, parent, child, inParent, inChild := folderDeleteFixture(t, db)
+
+			if err := repo.Delete(ctx, parent.ID, mode); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			deleted, err := repo.ListDeleted(ctx)
+			if err != nil {
+				t.Fatalf("ListDeleted failed: %v", err)
+			}
+			if len(deleted) != 1 || deleted[0].ID != parent.ID || deleted[0].DeletedAt == nil {
+				t.Fatalf("expected only the deleted folder in the trash, got %+v", deleted)
+			}
+
+			if err := repo.Restore(ctx, parent.ID); err != nil {
+				t.Fatalf("Restore failed: %v", err)
+			}
+
+			restored, err := repo.GetByID(ctx, parent.ID)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if restored.ParentID == nil || *restored.ParentID != root.ID {
+				t.Errorf("expected restored folder under root, got parent %v", restored.ParentID)
+			}
+			if ids := snippetFolderIDs(t, repo, inParent.ID); len(ids) != 1 || ids[0] != parent.ID {
+				t.Errorf("expected snippet back in the restored folder only, got %v", ids)
+			}
+			if _, err := repo.GetByID(ctx, child.ID); err != nil {
+				t.Errorf("expected child folder to exist, got %v", err)
+			}
+			if ids := snippetFolderIDs(t, repo, inChild.ID); len(ids) != 1 || ids[0] != child.ID {
+				t.Errorf("expected nested snippet in child, got %v", ids)
+			}
+			for _, s := range []*models.Snippet{inParent, inChild} {
+				if got, err := snippets.GetByID(ctx, s.ID); err != nil || got == nil || got.DeletedAt != nil {
+					t.Errorf("expected %s to be live, got %v (err %v)", s.Title, got, err)
+				}
+			}
+			if err := repo.Restore(ctx, parent.ID); err != ErrNotFound {
+				t.Errorf("expected ErrNotFound restoring a live folder, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFolderRepository_Restore_ParentGone(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewFolderRepository(db)
+	ctx := testutil.TestContext()
+	root, parent, _, _, _ := folderDeleteFixture(t, db)
+
+	if err := repo.Delete(ctx, parent.ID, models.FolderDeleteOrphan); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := repo.Delete(ctx, root.ID, models.FolderDeleteOrphan); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := repo.Restore(ctx, parent.ID); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	restored, err := repo.GetByID(ctx, parent.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if restored.ParentID != nil {
+		t.Errorf("expected a root folder when the parent is in the trash, got parent %d", *restored.ParentID)
+	}
+}
+
+func TestFolderRepository_Purge(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewFolderRepository(db)
+	ctx := testutil.TestContext()
+	_, parent, child, inParent, inChild := folderDeleteFixture(t, db)
+
+	if err := repo.Purge(ctx, parent.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound purging a live folder, got %v", err)
+	}
+	if err := repo.Delete(ctx, parent.ID, models.FolderDeleteTrash); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := repo.Purge(ctx, parent.ID); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM folders WHERE id IN (?, ?)`, parent.ID, child.ID).Scan(&count); err != nil || count != 0 {
+		t.Errorf("expected the subtree to be purged, got %d rows (err %v)", count, err)
+	}
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM snippet_folders WHERE snippet_id IN (?, ?)`, inParent.ID, inChild.ID).Scan(&count); err != nil || count != 0 {
+		t.Errorf("expected the snippets to be unfiled, got %d rows (err %v)", count, err)
+	}
+	if err := repo.Restore(ctx, parent.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound restoring a purged folder, got %v", err)
+	}
+}
+
+func TestFolderRepository_CleanupDeleted(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewFolderRepository(db)
+	ctx := testutil.TestContext()
+	root, parent, _, _, _ := folderDeleteFixture(t, db)
+
+	if err := repo.Delete(ctx, parent.ID, models.FolderDeleteTrash); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `UPDATE folders SET deleted_at = datetime('now', '-40 days') WHERE deleted_at IS NOT NULL`); err != nil {
+		t.Fatalf("failed to age folders: %v", err)
+	}
+
+	count, err := repo.CleanupDeleted(ctx, 30)
+	if err != nil {
+		t.Fatalf("CleanupDeleted failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 folders purged, got %d", count)
+	}
+	if deleted, _ := repo.ListDeleted(ctx); len(deleted) != 0 {
+		t.Errorf("expected an empty trash, got %v", deleted)
+	}
+	if _, err := repo.GetByID(ctx, root.ID); err != nil {
+		t.Errorf("expected root folder to survive, got %v", err)
+	}
+}
+
+func TestFolderRepository_Delete_UnknownMode(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewFolderRepository(db)
+	ctx := testutil.TestContext()
+
+	created, err := repo.Create(ctx, &models.FolderInput{Name: "Kept"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := repo.Delete(ctx, created.ID, "shred"); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+	if _, err := repo.GetByID(ctx, created.ID); err != nil {
+		t.Errorf("expected folder to survive, got %v", err)
+	}
+}
+
 func TestFolderRepository_Move(t *testing.T) {
 	db := testutil.TestDB(t)
 	repo := NewFolderRepository(db)
@@ -539,3 +829,95 @@ func TestFolderRepository_GetFolderSnippetCount_Archived(t *testing.T) {
 		t.Errorf("expected count 1 after unarchiving, got %d", count)
 	}
 }
+
+func TestFolderRepository_Access(t *testing.T) {
+	db := testutil.TestDB(t)
+	users := NewUserRepository(db)
+	workspaces := NewWorkspaceRepository(db)
+	folders := NewFolderRepository(db)
+	snippets := NewSnippetRepository(db)
+
+	alice, err := users.Create(testutil.TestContext(), "alice", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	bob, err := users.Create(testutil.TestContext(), "bob", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	team, err := workspaces.Create(testutil.TestContext(), &models.WorkspaceInput{Slug: "team", Name: "Team"})
+	if err != nil {
+		t.Fatalf("Create workspace failed: %v", err)
+	}
+
+	member := func(userID int64, role string) context.Context {
+		return models.WithWorkspaceRole(models.WithWorkspaceID(models.WithUserID(testutil.TestContext(), userID), team.ID), role)
+	}
+	aliceCtx := member(alice.ID, models.WorkspaceRoleEditor)
+	bobCtx := member(bob.ID, models.WorkspaceRoleEditor)
+	adminCtx := member(models.OwnerUserID, models.WorkspaceRoleAdmin)
+
+	personal, err := folders.Create(aliceCtx, &models.FolderInput{Name: "personal"})
+	if err != nil {
+		t.Fatalf("Create folder failed: %v", err)
+	}
+	nested, err := folders.Create(aliceCtx, &models.FolderInput{Name: "nested", ParentID: &personal.ID})
+	if err != nil {
+		t.Fatalf("Create folder failed: %v", err)
+	}
+	private, err := snippets.Create(aliceCtx, &models.SnippetInput{Title: "private", Content: "x", Language: "go"})
+	if err != nil {
+		t.Fatalf("Create snippet failed: %v", err)
+	}
+	if err := folders.SetSnippetFolder(aliceCtx, private.ID, &nested.ID); err != nil {
+		t.Fatalf("SetSnippetFolder failed: %v", err)
+	}
+	shared, err := snippets.Create(aliceCtx, &models.SnippetInput{Title: "shared", Content: "y", Language: "go"})
+	if err != nil {
+		t.Fatalf("Create snippet failed: %v", err)
+	}
+
+	if err := folders.SetAccess(bobCtx, personal.ID, &models.FolderAccess{UserIDs: []int64{bob.ID}}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expected only the creator or an admin to restrict a folder, got %v", err)
+	}
+	if err := folders.SetAccess(aliceCtx, personal.ID, &models.FolderAccess{Roles: []string{models.WorkspaceRoleAdmin}}); err != nil {
+		t.Fatalf("SetAccess failed: %v", err)
+	}
+
+	// Bob no longer sees the folder, its subfolder or the snippet filed there
+	if _, err := folders.GetByID(bobCtx, nested.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the subfolder to be hidden, got %v", err)
+	}
+	if list, _ := folders.List(bobCtx); len(list) != 0 {
+		t.Errorf("expected no visible folders, got %+v", list)
+	}
+	if s, _ := snippets.GetByID(bobCtx, private.ID); s != nil {
+		t.Error("expected the snippet in a hidden folder to be missing")
+	}
+	list, err := snippets.List(bobCtx, models.SnippetFilter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if list.Pagination.Total != 1 || list.Data[0].ID != shared.ID {
+		t.Errorf("expected only the shared snippet, got %+v", list.Data)
+	}
+
+	// The creator and members with a granted role still see everything
+	for name, ctx := range map[string]context.Context{"creator": aliceCtx, "admin": adminCtx} {
+		if s, _ := snippets.GetByID(ctx, private.ID); s == nil {
+			t.Errorf("expected the %s to see the snippet", name)
+		}
+	}
+
+	// Granting bob by name brings it back
+	if err := folders.SetAccess(adminCtx, personal.ID, &models.FolderAccess{UserIDs: []int64{bob.ID}}); err != nil {
+		t.Fatalf("SetAccess failed: %v", err)
+	}
+	access, err := folders.GetAccess(adminCtx, personal.ID)
+	if err != nil || len(access.UserIDs) != 1 || len(access.Roles) != 0 {
+		t.Errorf("expected the access list to be replaced, got %+v, %v", access, err)
+	}
+	if s, _ := snippets.GetByID(bobCtx, private.ID); s == nil {
+		t.Error("expected bob to see the snippet once granted")
+	}
+}
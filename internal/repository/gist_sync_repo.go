@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 
 	"github.com/MohamedElashri/snipo/internal/models"
@@ -23,13 +24,14 @@ func (r *GistSyncRepository) GetConfig(ctx context.Context) (*models.GistSyncCon
 	query := `
 		SELECT id, enabled, github_token_encrypted, github_username,
 		       auto_sync_enabled, sync_interval_minutes, conflict_strategy,
-		       last_full_sync_at, created_at, updated_at
+		       last_full_sync_at, rate_limit, created_at, updated_at
 		FROM gist_sync_config
 		WHERE id = 1
 	`
 
 	config := &models.GistSyncConfig{}
 	var lastFullSyncAt sql.NullTime
+	var rateLimit sql.NullString
 
 	err := r.db.QueryRowContext(ctx, query).Scan(
 		&config.ID,
@@ -40,6 +42,7 @@ func (r *GistSyncRepository) GetConfig(ctx context.Context) (*models.GistSyncCon
 		&config.SyncIntervalMinutes,
 		&config.ConflictResolutionStrategy,
 		&lastFullSyncAt,
+		&rateLimit,
 		&config.CreatedAt,
 		&config.UpdatedAt,
 	)
@@ -54,6 +57,12 @@ func (r *GistSyncRepository) GetConfig(ctx context.Context) (*models.GistSyncCon
 	if lastFullSyncAt.Valid {
 		config.LastFullSyncAt = &lastFullSyncAt.Time
 	}
+	if rateLimit.Valid {
+		config.RateLimit = &models.GitHubRateLimit{}
+		if err := json.Unmarshal([]byte(rateLimit.String), config.RateLimit); err != nil {
+			config.RateLimit = nil
+		}
+	}
 
 	return config, nil
 }
@@ -223,17 +232,20 @@ func (r *GistSyncRepository) GetMappingByGistID(ctx context.Context, gistID stri
 	return mapping, nil
 }
 
-// ListMappings retrieves all mappings
+// ListMappings retrieves the mappings of the snippets visible in ctx
 func (r *GistSyncRepository) ListMappings(ctx context.Context) ([]*models.SnippetGistMapping, error) {
+	owner, args := snippetFilter(ctx, "s.user_id")
 	query := `
-		SELECT id, snippet_id, gist_id, gist_url, sync_enabled,
-		       last_synced_at, snipo_checksum, gist_checksum,
-		       sync_status, error_message, created_at, updated_at
-		FROM snippet_gist_mappings
-		ORDER BY created_at DESC
+		SELECT m.id, m.snippet_id, m.gist_id, m.gist_url, m.sync_enabled,
+		       m.last_synced_at, m.snipo_checksum, m.gist_checksum,
+		       m.sync_status, m.error_message, m.created_at, m.updated_at
+		FROM snippet_gist_mappings m
+		INNER JOIN snippets s ON s.id = m.snippet_id
+		WHERE 1 = 1` + owner + `
+		ORDER BY m.created_at DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list mappings: %w", err)
 	}
@@ -305,13 +317,54 @@ func (r *GistSyncRepository) UpdateMapping(ctx context.Context, mapping *models.
 	return nil
 }
 
-// DeleteMapping deletes a mapping
+// GetBaseVersion returns the content a mapping was last synced with, or nil
+// if it was last synced before base versions were kept
+func (r *GistSyncRepository) GetBaseVersion(ctx context.Context, mappingID int64) (*models.GistBaseVersion, error) {
+	var data sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT base_version FROM snippet_gist_mappings WHERE id = ?`, mappingID).Scan(&data)
+	if err == sql.ErrNoRows || (err == nil && !data.Valid) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base version: %w", err)
+	}
+
+	var base models.GistBaseVersion
+	if err := json.Unmarshal([]byte(data.String), &base); err != nil {
+		return nil, fmt.Errorf("failed to decode base version: %w", err)
+	}
+	return &base, nil
+}
+
+// SaveBaseVersion records the content a mapping was just synced with
+func (r *GistSyncRepository) SaveBaseVersion(ctx context.Context, mappingID int64, base *models.GistBaseVersion) error {
+	data, err := json.Marshal(base)
+	if err != nil {
+		return fmt.Errorf("failed to encode base version: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, `UPDATE snippet_gist_mappings SET base_version = ? WHERE id = ?`, string(data), mappingID); err != nil {
+		return fmt.Errorf("failed to save base version: %w", err)
+	}
+	return nil
+}
+
+// DeleteMapping deletes a mapping of a snippet visible in ctx. Returns
+// ErrNotFound when there is no such mapping.
 func (r *GistSyncRepository) DeleteMapping(ctx context.Context, id int64) error {
-	query := `DELETE FROM snippet_gist_mappings WHERE id = ?`
-	_, err := r.db.ExecContext(ctx, query, id)
+	owner, ownerArgs := snippetFilter(ctx, "s.user_id")
+	query := `DELETE FROM snippet_gist_mappings WHERE id = ?
+		AND EXISTS (SELECT 1 FROM snippets s WHERE s.id = snippet_gist_mappings.snippet_id` + owner + `)`
+	result, err := r.db.ExecContext(ctx, query, append([]interface{}{id}, ownerArgs...)...)
 	if err != nil {
 		return fmt.Errorf("failed to delete mapping: %w", err)
 	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
 	return nil
 }
 
@@ -338,20 +391,23 @@ func (r *GistSyncRepository) CreateConflict(ctx context.Context, conflict *model
 	return nil
 }
 
-// GetConflict retrieves a conflict by ID
+// GetConflict retrieves a conflict by ID, or nil if it doesn't exist or
+// its snippet isn't visible in ctx
 func (r *GistSyncRepository) GetConflict(ctx context.Context, id int64) (*models.GistSyncConflict, error) {
+	owner, ownerArgs := snippetFilter(ctx, "s.user_id")
 	query := `
-		SELECT id, snippet_id, gist_id, snipo_version, gist_version,
-		       resolved, resolution_choice, created_at, resolved_at
-		FROM gist_sync_conflicts
-		WHERE id = ?
+		SELECT c.id, c.snippet_id, c.gist_id, c.snipo_version, c.gist_version,
+		       c.resolved, c.resolution_choice, c.created_at, c.resolved_at
+		FROM gist_sync_conflicts c
+		INNER JOIN snippets s ON s.id = c.snippet_id
+		WHERE c.id = ?` + owner + `
 	`
 
 	conflict := &models.GistSyncConflict{}
 	var resolutionChoice sql.NullString
 	var resolvedAt sql.NullTime
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, append([]interface{}{id}, ownerArgs...)...).Scan(
 		&conflict.ID,
 		&conflict.SnippetID,
 		&conflict.GistID,
@@ -380,17 +436,20 @@ func (r *GistSyncRepository) GetConflict(ctx context.Context, id int64) (*models
 	return conflict, nil
 }
 
-// ListConflicts retrieves all unresolved conflicts
+// ListConflicts retrieves the resolved or unresolved conflicts of the
+// snippets visible in ctx
 func (r *GistSyncRepository) ListConflicts(ctx context.Context, resolvedOnly bool) ([]*models.GistSyncConflict, error) {
+	owner, ownerArgs := snippetFilter(ctx, "s.user_id")
 	query := `
-		SELECT id, snippet_id, gist_id, snipo_version, gist_version,
-		       resolved, resolution_choice, created_at, resolved_at
-		FROM gist_sync_conflicts
-		WHERE resolved = ?
-		ORDER BY created_at DESC
+		SELECT c.id, c.snippet_id, c.gist_id, c.snipo_version, c.gist_version,
+		       c.resolved, c.resolution_choice, c.created_at, c.resolved_at
+		FROM gist_sync_conflicts c
+		INNER JOIN snippets s ON s.id = c.snippet_id
+		WHERE c.resolved = ?` + owner + `
+		ORDER BY c.created_at DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, resolvedOnly)
+	rows, err := r.db.QueryContext(ctx, query, append([]interface{}{resolvedOnly}, ownerArgs...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list conflicts: %w", err)
 	}
@@ -432,18 +491,28 @@ func (r *GistSyncRepository) ListConflicts(ctx context.Context, resolvedOnly boo
 	return conflicts, nil
 }
 
-// ResolveConflict marks a conflict as resolved
+// ResolveConflict marks a conflict of a snippet visible in ctx as resolved.
+// Returns ErrNotFound when there is no such conflict.
 func (r *GistSyncRepository) ResolveConflict(ctx context.Context, id int64, resolution string) error {
+	owner, ownerArgs := snippetFilter(ctx, "s.user_id")
 	query := `
 		UPDATE gist_sync_conflicts
 		SET resolved = 1, resolution_choice = ?, resolved_at = CURRENT_TIMESTAMP
 		WHERE id = ?
+		  AND EXISTS (SELECT 1 FROM snippets s WHERE s.id = gist_sync_conflicts.snippet_id` + owner + `)
 	`
 
-	_, err := r.db.ExecContext(ctx, query, resolution, id)
+	result, err := r.db.ExecContext(ctx, query, append([]interface{}{resolution, id}, ownerArgs...)...)
 	if err != nil {
 		return fmt.Errorf("failed to resolve conflict: %w", err)
 	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
 
 	return nil
 }
@@ -541,6 +610,19 @@ func (r *GistSyncRepository) UpdateLastFullSyncTime(ctx context.Context) error {
 	return nil
 }
 
+// UpdateRateLimit saves the GitHub API quota reported during a sync
+func (r *GistSyncRepository) UpdateRateLimit(ctx context.Context, limit *models.GitHubRateLimit) error {
+	data, err := json.Marshal(limit)
+	if err != nil {
+		return fmt.Errorf("failed to encode rate limit: %w", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx, `UPDATE gist_sync_config SET rate_limit = ? WHERE id = 1`, string(data)); err != nil {
+		return fmt.Errorf("failed to update rate limit: %w", err)
+	}
+	return nil
+}
+
 // GetEnabledMappings retrieves all mappings with sync enabled
 func (r *GistSyncRepository) GetEnabledMappings(ctx context.Context) ([]*models.SnippetGistMapping, error) {
 	query := `
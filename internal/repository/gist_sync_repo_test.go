@@ -3,10 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
 	_ "modernc.org/sqlite"
 )
 
@@ -17,6 +19,13 @@ func setupTestDB(t *testing.T) *sql.DB {
 	}
 
 	schema := `
+	CREATE TABLE snippets (
+		id TEXT PRIMARY KEY,
+		user_id INTEGER DEFAULT 0,
+		workspace_id INTEGER DEFAULT 0
+	);
+	INSERT INTO snippets (id) VALUES ('snippet-123');
+
 	CREATE TABLE gist_sync_config (
 		id INTEGER PRIMARY KEY CHECK (id = 1),
 		enabled INTEGER DEFAULT 0,
@@ -26,6 +35,7 @@ func setupTestDB(t *testing.T) *sql.DB {
 		sync_interval_minutes INTEGER DEFAULT 15,
 		conflict_strategy TEXT DEFAULT 'manual',
 		last_full_sync_at DATETIME,
+		rate_limit TEXT DEFAULT NULL,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
@@ -41,6 +51,7 @@ func setupTestDB(t *testing.T) *sql.DB {
 		gist_checksum TEXT,
 		sync_status TEXT DEFAULT 'synced',
 		error_message TEXT,
+		base_version TEXT DEFAULT NULL,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
@@ -116,6 +127,25 @@ func TestGistSyncRepository_Config(t *testing.T) {
 			t.Errorf("expected username 'testuser', got '%s'", retrieved.GithubUsername)
 		}
 	})
+
+	t.Run("update rate limit", func(t *testing.T) {
+		reset := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		err := repo.UpdateRateLimit(ctx, &models.GitHubRateLimit{Limit: 5000, Remaining: 42, Reset: reset})
+		if err != nil {
+			t.Fatalf("failed to update rate limit: %v", err)
+		}
+
+		retrieved, err := repo.GetConfig(ctx)
+		if err != nil {
+			t.Fatalf("failed to get config: %v", err)
+		}
+		if retrieved.RateLimit == nil || retrieved.RateLimit.Remaining != 42 || !retrieved.RateLimit.Reset.Equal(reset) {
+			t.Errorf("expected 42 requests left until %v, got %+v", reset, retrieved.RateLimit)
+		}
+		if retrieved.GithubUsername != "testuser" {
+			t.Errorf("expected the rest of the config kept, got username '%s'", retrieved.GithubUsername)
+		}
+	})
 }
 
 func TestGistSyncRepository_Mapping(t *testing.T) {
@@ -229,6 +259,79 @@ func TestGistSyncRepository_Conflict(t *testing.T) {
 	})
 }
 
+func TestGistSyncRepository_Owners(t *testing.T) {
+	db := testutil.TestDB(t)
+	users := NewUserRepository(db)
+	repo := NewGistSyncRepository(db)
+
+	alice, err := users.Create(testutil.TestContext(), "alice", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	bob, err := users.Create(testutil.TestContext(), "bob", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	aliceCtx := models.WithUserID(testutil.TestContext(), alice.ID)
+	bobCtx := models.WithUserID(testutil.TestContext(), bob.ID)
+
+	if _, err := db.Exec(`INSERT INTO snippets (id, title, content, user_id) VALUES ('a', 'a', 'x', ?), ('b', 'b', 'x', ?)`, alice.ID, bob.ID); err != nil {
+		t.Fatalf("insert snippets: %v", err)
+	}
+	mappings := map[string]*models.SnippetGistMapping{}
+	conflicts := map[string]*models.GistSyncConflict{}
+	for _, id := range []string{"a", "b"} {
+		mappings[id] = &models.SnippetGistMapping{SnippetID: id, GistID: "gist-" + id, GistURL: "https://gist.github.com/" + id, SyncStatus: models.SyncStatusSynced}
+		if err := repo.CreateMapping(testutil.TestContext(), mappings[id]); err != nil {
+			t.Fatalf("CreateMapping failed: %v", err)
+		}
+		conflicts[id] = &models.GistSyncConflict{SnippetID: id, GistID: "gist-" + id, SnipoVersion: "{}", GistVersion: "{}"}
+		if err := repo.CreateConflict(testutil.TestContext(), conflicts[id]); err != nil {
+			t.Fatalf("CreateConflict failed: %v", err)
+		}
+	}
+
+	list, err := repo.ListMappings(aliceCtx)
+	if err != nil {
+		t.Fatalf("ListMappings failed: %v", err)
+	}
+	if len(list) != 1 || list[0].SnippetID != "a" {
+		t.Errorf("expected only alice's mapping, got %+v", list)
+	}
+	open, err := repo.ListConflicts(aliceCtx, false)
+	if err != nil {
+		t.Fatalf("ListConflicts failed: %v", err)
+	}
+	if len(open) != 1 || open[0].SnippetID != "a" {
+		t.Errorf("expected only alice's conflict, got %+v", open)
+	}
+
+	// Other users' mappings and conflicts can't be reached by ID
+	if conflict, err := repo.GetConflict(aliceCtx, conflicts["b"].ID); err != nil || conflict != nil {
+		t.Errorf("expected bob's conflict to be hidden from alice, got %+v, %v", conflict, err)
+	}
+	if err := repo.ResolveConflict(aliceCtx, conflicts["b"].ID, models.ConflictStrategySnipoWins); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound resolving bob's conflict, got %v", err)
+	}
+	if err := repo.DeleteMapping(aliceCtx, mappings["b"].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting bob's mapping, got %v", err)
+	}
+	if mapping, err := repo.GetMapping(bobCtx, "b"); err != nil || mapping == nil {
+		t.Errorf("expected bob's mapping to survive, got %+v, %v", mapping, err)
+	}
+	if conflict, err := repo.GetConflict(bobCtx, conflicts["b"].ID); err != nil || conflict == nil || conflict.Resolved {
+		t.Errorf("expected bob's conflict to stay open, got %+v, %v", conflict, err)
+	}
+
+	// The owner still can
+	if err := repo.ResolveConflict(bobCtx, conflicts["b"].ID, models.ConflictStrategySnipoWins); err != nil {
+		t.Errorf("ResolveConflict failed: %v", err)
+	}
+	if err := repo.DeleteMapping(bobCtx, mappings["b"].ID); err != nil {
+		t.Errorf("DeleteMapping failed: %v", err)
+	}
+}
+
 func TestGistSyncRepository_Log(t *testing.T) {
 	db := setupTestDB(t)
 	defer func() { _ = db.Close() }()
@@ -59,8 +59,8 @@ func (r *HistoryRepository) CreateFileHistory(ctx context.Context, historyID int
 
 	query := `
 		INSERT INTO snippet_files_history 
-		(history_id, snippet_id, filename, content, language, sort_order)
-		VALUES (?, ?, ?, ?, ?, ?)
+		(history_id, snippet_id, filename, content, language, note, sort_order)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 
 	stmt, err := r.db.PrepareContext(ctx, query)
@@ -80,6 +80,7 @@ func (r *HistoryRepository) CreateFileHistory(ctx context.Context, historyID int
 			file.Filename,
 			file.Content,
 			file.Language,
+			file.Note,
 			file.SortOrder,
 		)
 		if err != nil {
@@ -197,7 +198,7 @@ func (r *HistoryRepository) GetHistoryByID(ctx context.Context, historyID int64)
 // GetHistoryFiles retrieves files for a specific history entry
 func (r *HistoryRepository) GetHistoryFiles(ctx context.Context, historyID int64) ([]models.SnippetFileHistory, error) {
 	query := `
-		SELECT id, history_id, snippet_id, filename, content, language, sort_order, created_at
+		SELECT id, history_id, snippet_id, filename, content, language, note, sort_order, created_at
 		FROM snippet_files_history
 		WHERE history_id = ?
 		ORDER BY sort_order ASC
@@ -223,6 +224,7 @@ func (r *HistoryRepository) GetHistoryFiles(ctx context.Context, historyID int64
 			&f.Filename,
 			&f.Content,
 			&f.Language,
+			&f.Note,
 			&f.SortOrder,
 			&f.CreatedAt,
 		)
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// ImportReportRetentionDays is how long import reports are kept
+const ImportReportRetentionDays = 90
+
+// importReportDetails is what the report column holds
+type importReportDetails struct {
+	Items  []models.ImportItemResult `json:"items"`
+	Errors []string                  `json:"errors,omitempty"`
+}
+
+// ImportReportRepository handles saved import reports
+type ImportReportRepository struct {
+	db *sql.DB
+}
+
+// NewImportReportRepository creates a new import report repository
+func NewImportReportRepository(db *sql.DB) *ImportReportRepository {
+	return &ImportReportRepository{db: db}
+}
+
+// reportFilter limits a query to the reports of the user in ctx. Reports
+// name every snippet an import touched, so unlike other workspace data they
+// stay with whoever ran the import.
+func reportFilter(ctx context.Context) (string, []interface{}) {
+	owner, args := ownerFilter(ctx, "user_id")
+	if models.WorkspaceIDFromContext(ctx) != models.DefaultWorkspaceID {
+		if id, ok := models.UserIDFromContext(ctx); ok {
+			owner += " AND user_id = ?"
+			args = append(args, id)
+		}
+	}
+	return owner, args
+}
+
+// Create saves a report for the user in ctx and sets its ID and creation time
+func (r *ImportReportRepository) Create(ctx context.Context, report *models.ImportReport) error {
+	details, err := json.Marshal(importReportDetails{Items: report.Items, Errors: report.Errors})
+	if err != nil {
+		return fmt.Errorf("failed to encode import report: %w", err)
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO import_reports (source, created, updated, skipped, failed, report, user_id, workspace_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id, created_at
+	`, report.Source, report.Created, report.Updated, report.Skipped, report.Failed, string(details),
+		ownerID(ctx), workspaceID(ctx),
+	).Scan(&report.ID, &report.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save import report: %w", err)
+	}
+	return nil
+}
+
+// List returns up to limit reports, newest first, with their counts only
+func (r *ImportReportRepository) List(ctx context.Context, limit int) ([]models.ImportReport, error) {
+	owner, args := reportFilter(ctx)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, source, created, updated, skipped, failed, created_at
+		FROM import_reports
+		WHERE 1=1`+owner+`
+		ORDER BY id DESC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import reports: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	reports := []models.ImportReport{}
+	for rows.Next() {
+		var report models.ImportReport
+		if err := rows.Scan(&report.ID, &report.Source, &report.Created, &report.Updated,
+			&report.Skipped, &report.Failed, &report.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan import report: %w", err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// GetByID returns a report with its items
+func (r *ImportReportRepository) GetByID(ctx context.Context, id int64) (*models.ImportReport, error) {
+	owner, args := reportFilter(ctx)
+	var report models.ImportReport
+	var details string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, source, created, updated, skipped, failed, report, created_at
+		FROM import_reports
+		WHERE id = ?`+owner,
+		append([]interface{}{id}, args...)...,
+	).Scan(&report.ID, &report.Source, &report.Created, &report.Updated,
+		&report.Skipped, &report.Failed, &details, &report.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import report: %w", err)
+	}
+
+	var decoded importReportDetails
+	if err := json.Unmarshal([]byte(details), &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode import report: %w", err)
+	}
+	report.Items = decoded.Items
+	report.Errors = decoded.Errors
+	return &report, nil
+}
+
+// Prune deletes reports older than ImportReportRetentionDays
+func (r *ImportReportRepository) Prune(ctx context.Context) (int64, error) {
+	before := time.Now().UTC().AddDate(0, 0, -ImportReportRetentionDays).Format(time.DateTime)
+	result, err := r.db.ExecContext(ctx, `DELETE FROM import_reports WHERE created_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune import reports: %w", err)
+	}
+	return result.RowsAffected()
+}
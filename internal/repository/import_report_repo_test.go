@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestImportReportRepository(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewImportReportRepository(db)
+	users := NewUserRepository(db)
+
+	alice, err := users.Create(testutil.TestContext(), "alice", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	bob, err := users.Create(testutil.TestContext(), "bob", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	aliceCtx := models.WithUserID(testutil.TestContext(), alice.ID)
+	bobCtx := models.WithUserID(testutil.TestContext(), bob.ID)
+
+	report := &models.ImportReport{
+		Source:  models.ImportSourceURLs,
+		Created: 1,
+		Failed:  1,
+		Items: []models.ImportItemResult{
+			{SourceID: "https://example.com/a.sh", Title: "a.sh", Action: "created", SnippetID: "abc"},
+			{SourceID: "https://example.com/b.bin", Action: "failed", Error: "file is not UTF-8 text"},
+		},
+	}
+	if err := repo.Create(aliceCtx, report); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if report.ID == 0 || report.CreatedAt.IsZero() {
+		t.Fatalf("expected ID and creation time to be set, got %+v", report)
+	}
+
+	got, err := repo.GetByID(aliceCtx, report.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.Source != models.ImportSourceURLs || got.Created != 1 || got.Failed != 1 || len(got.Items) != 2 || got.Items[1].Error == "" {
+		t.Errorf("unexpected report: %+v", got)
+	}
+
+	list, err := repo.List(aliceCtx, 10)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != report.ID || list[0].Items != nil {
+		t.Errorf("expected one report without items, got %+v", list)
+	}
+
+	if _, err := repo.GetByID(bobCtx, report.ID); err != ErrNotFound {
+		t.Errorf("expected another user's report to be hidden, got %v", err)
+	}
+	if list, _ := repo.List(bobCtx, 10); len(list) != 0 {
+		t.Errorf("expected no reports for another user, got %+v", list)
+	}
+
+	if _, err := db.Exec(`UPDATE import_reports SET created_at = datetime('now', '-100 days')`); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := repo.Prune(aliceCtx); err != nil || n != 1 {
+		t.Errorf("expected 1 report pruned, got %d (err %v)", n, err)
+	}
+}
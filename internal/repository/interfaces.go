@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// The interfaces below describe the repository methods services and handlers
+// depend on, so alternate implementations (caches, fakes in tests) can be
+// swapped in without touching the SQL-backed repositories.
+
+// SnippetStore persists snippets
+type SnippetStore interface {
+	Create(ctx context.Context, input *models.SnippetInput) (*models.Snippet, error)
+	GetByID(ctx context.Context, id string) (*models.Snippet, error)
+	PublicID(ctx context.Context, key string, byID bool) (string, error)
+	Update(ctx context.Context, id string, input *models.SnippetInput) (*models.Snippet, error)
+	Delete(ctx context.Context, id string, permanent bool) error
+	Restore(ctx context.Context, id string) error
+	List(ctx context.Context, filter models.SnippetFilter) (*models.SnippetListResponse, error)
+	Neighbors(ctx context.Context, id string, filter models.SnippetFilter) (*models.SnippetNeighbors, error)
+	ToggleFavorite(ctx context.Context, id string) (*models.Snippet, error)
+	ToggleArchive(ctx context.Context, id string) (*models.Snippet, error)
+	IncrementViewCount(ctx context.Context, id string) error
+	RecordUse(ctx context.Context, id string) (*models.Snippet, error)
+	RecordPublicView(ctx context.Context, id, referrerDomain string) error
+	GetReferrers(ctx context.Context, id string) ([]models.ReferrerStat, error)
+	Search(ctx context.Context, query string, limit int) ([]models.SearchResult, error)
+	ExportSearchIndex(ctx context.Context, since *time.Time) (*models.SearchIndexExport, error)
+	SetProvenance(ctx context.Context, id string, provenance *models.Provenance) error
+	GetByExternalID(ctx context.Context, externalID string) (*models.Snippet, error)
+	SetExternalID(ctx context.Context, id, externalID string) error
+	UpdateChecksum(ctx context.Context, id, checksum string) error
+	UpdateContentHash(ctx context.Context, id, hash string) error
+	ListMissingContentHashes(ctx context.Context) ([]string, error)
+	FindDuplicates(ctx context.Context, title, contentHash string, limit int) ([]models.DuplicateMatch, error)
+	Bulk(ctx context.Context, input *models.BulkSnippetInput) (*models.BulkSnippetResult, error)
+}
+
+// TagStore persists tags and snippet-tag associations
+type TagStore interface {
+	Create(ctx context.Context, input *models.TagInput) (*models.Tag, error)
+	GetByID(ctx context.Context, id int64) (*models.Tag, error)
+	GetByName(ctx context.Context, name string) (*models.Tag, error)
+	List(ctx context.Context) ([]models.Tag, error)
+	Update(ctx context.Context, id int64, input *models.TagInput) (*models.Tag, error)
+	Delete(ctx context.Context, id int64) error
+	ListDeleted(ctx context.Context) ([]models.Tag, error)
+	Restore(ctx context.Context, id int64) error
+	Purge(ctx context.Context, id int64) error
+	GetSnippetTags(ctx context.Context, snippetID string) ([]models.Tag, error)
+	SetSnippetTags(ctx context.Context, snippetID string, tagNames []string) error
+	GetTagSnippetCount(ctx context.Context, tagID int64) (int, error)
+}
+
+// FolderStore persists folders and snippet-folder associations
+type FolderStore interface {
+	Create(ctx context.Context, input *models.FolderInput) (*models.Folder, error)
+	GetByID(ctx context.Context, id int64) (*models.Folder, error)
+	List(ctx context.Context) ([]models.Folder, error)
+	ListTree(ctx context.Context) ([]models.Folder, error)
+	Update(ctx context.Context, id int64, input *models.FolderInput) (*models.Folder, error)
+	Delete(ctx context.Context, id int64, mode string) error
+	ListDeleted(ctx context.Context) ([]models.Folder, error)
+	Restore(ctx context.Context, id int64) error
+	Purge(ctx context.Context, id int64) error
+	Move(ctx context.Context, id int64, newParentID *int64) (*models.Folder, error)
+	GetFolderSnippetCount(ctx context.Context, folderID int64) (int, error)
+	GetSnippetFolders(ctx context.Context, snippetID string) ([]models.Folder, error)
+	SetSnippetFolder(ctx context.Context, snippetID string, folderID *int64) error
+	GetAccess(ctx context.Context, id int64) (*models.FolderAccess, error)
+	SetAccess(ctx context.Context, id int64, access *models.FolderAccess) error
+}
+
+// SnippetFileStore persists the files of multi-file snippets
+type SnippetFileStore interface {
+	GetBySnippetID(ctx context.Context, snippetID string) ([]models.SnippetFile, error)
+	SyncFiles(ctx context.Context, snippetID string, files []models.SnippetFileInput) ([]models.SnippetFile, error)
+}
+
+// HistoryStore persists snippet history entries
+type HistoryStore interface {
+	CreateHistory(ctx context.Context, snippet *models.Snippet, changeType string) (int64, error)
+	CreateFileHistory(ctx context.Context, historyID int64, files []models.SnippetFile) error
+	GetSnippetHistory(ctx context.Context, snippetID string, limit int) ([]models.SnippetHistory, error)
+	GetHistoryByID(ctx context.Context, historyID int64) (*models.SnippetHistory, error)
+}
+
+// ActivityStore records changes to snippets and reads them back as a feed
+type ActivityStore interface {
+	Record(ctx context.Context, snippetID, title, action string) error
+	Feed(ctx context.Context, filter models.ActivityFilter) ([]models.ActivityItem, error)
+}
+
+// SettingsStore persists application settings
+type SettingsStore interface {
+	Get(ctx context.Context) (*models.Settings, error)
+	Update(ctx context.Context, input *models.SettingsInput) (*models.Settings, error)
+}
+
+// FeatureFlags reports whether runtime-toggleable features are switched on
+type FeatureFlags interface {
+	FeatureEnabled(ctx context.Context, name string) (bool, error)
+}
+
+// RemoteSnippetChecker reports whether a snippet is a read-only remote mirror
+type RemoteSnippetChecker interface {
+	IsRemoteSnippet(ctx context.Context, snippetID string) (bool, error)
+}
+
+// StatsReader computes aggregate snippet statistics
+type StatsReader interface {
+	SnippetStats(ctx context.Context) (*models.SnippetStats, error)
+}
+
+// WorkspaceResolver looks up the workspace a request selected and the
+// user's role in it
+type WorkspaceResolver interface {
+	GetBySlug(ctx context.Context, slug string) (*models.Workspace, error)
+	GetByID(ctx context.Context, id int64) (*models.Workspace, error)
+	MemberRole(ctx context.Context, workspaceID, userID int64) (string, error)
+}
+
+var (
+	_ SnippetStore         = (*SnippetRepository)(nil)
+	_ TagStore             = (*TagRepository)(nil)
+	_ FolderStore          = (*FolderRepository)(nil)
+	_ SnippetFileStore     = (*SnippetFileRepository)(nil)
+	_ HistoryStore         = (*HistoryRepository)(nil)
+	_ ActivityStore        = (*ActivityRepository)(nil)
+	_ SettingsStore        = (*SettingsRepository)(nil)
+	_ RemoteSnippetChecker = (*RemoteSourceRepository)(nil)
+	_ StatsReader          = (*StatsRepository)(nil)
+	_ WorkspaceResolver    = (*WorkspaceRepository)(nil)
+)
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// JobRunRepository records when background jobs last ran
+type JobRunRepository struct {
+	db *sql.DB
+}
+
+// NewJobRunRepository creates a new job run repository
+func NewJobRunRepository(db *sql.DB) *JobRunRepository {
+	return &JobRunRepository{db: db}
+}
+
+// LastRun returns when the job last ran, or nil if it never has
+func (r *JobRunRepository) LastRun(ctx context.Context, name string) (*time.Time, error) {
+	var last time.Time
+	err := r.db.QueryRowContext(ctx, `SELECT last_run_at FROM job_runs WHERE name = ?`, name).Scan(&last)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last run of %s: %w", name, err)
+	}
+	return &last, nil
+}
+
+// MarkRun records that the job ran at the given time
+func (r *JobRunRepository) MarkRun(ctx context.Context, name string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO job_runs (name, last_run_at) VALUES (?, ?)
+		ON CONFLICT(name) DO UPDATE SET last_run_at = excluded.last_run_at
+	`, name, at.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record run of %s: %w", name, err)
+	}
+	return nil
+}
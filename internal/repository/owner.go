@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// ownerFilter returns a condition limiting a query to the data visible in
+// ctx: everything in the selected workspace, or the user's own rows in the
+// default workspace. It returns nothing when ctx carries neither. column
+// names the user_id column; the workspace_id column next to it is used too.
+func ownerFilter(ctx context.Context, column string) (string, []interface{}) {
+	workspaceColumn := strings.TrimSuffix(column, "user_id") + "workspace_id"
+	if ws := models.WorkspaceIDFromContext(ctx); ws != models.DefaultWorkspaceID {
+		return " AND " + workspaceColumn + " = ?", []interface{}{ws}
+	}
+
+	id, ok := models.UserIDFromContext(ctx)
+	if !ok {
+		return "", nil
+	}
+	return " AND " + column + " = ? AND " + workspaceColumn + " = 0", []interface{}{id}
+}
+
+// snippetFilter is ownerFilter for snippets, which also leaves out the
+// snippets filed in folders hidden from the user. column names the snippets
+// table's user_id column.
+func snippetFilter(ctx context.Context, column string) (string, []interface{}) {
+	owner, args := ownerFilter(ctx, column)
+	hidden, hiddenArgs := hiddenSnippets(ctx, strings.TrimSuffix(column, "user_id")+"id")
+	return owner + hidden, append(args, hiddenArgs...)
+}
+
+// hiddenSnippets returns a condition leaving out the snippets filed in
+// folders hidden from the user, or nothing when no folders are hidden.
+// column names a snippet ID column.
+func hiddenSnippets(ctx context.Context, column string) (string, []interface{}) {
+	hidden, args := hiddenFolders(ctx)
+	if hidden == "" {
+		return "", nil
+	}
+	return " AND " + column + " NOT IN (SELECT snippet_id FROM snippet_folders WHERE folder_id IN (" + hidden + "))", args
+}
+
+// folderFilter is ownerFilter for folders, which also leaves out the folders
+// hidden from the user. column names the folders table's user_id column.
+func folderFilter(ctx context.Context, column string) (string, []interface{}) {
+	owner, args := ownerFilter(ctx, column)
+	hidden, hiddenArgs := hiddenFolders(ctx)
+	if hidden == "" {
+		return owner, args
+	}
+	idColumn := strings.TrimSuffix(column, "user_id") + "id"
+	return owner + " AND " + idColumn + " NOT IN (" + hidden + ")", append(args, hiddenArgs...)
+}
+
+// hiddenFolders returns a query selecting the folders of ctx's workspace
+// that are restricted to other users or roles, together with their
+// subfolders. Folders stay visible to whoever created them. Outside of
+// workspaces nobody else sees the user's folders, so nothing is hidden.
+func hiddenFolders(ctx context.Context) (string, []interface{}) {
+	ws := models.WorkspaceIDFromContext(ctx)
+	userID, ok := models.UserIDFromContext(ctx)
+	if ws == models.DefaultWorkspaceID || !ok {
+		return "", nil
+	}
+
+	// A role entry admits members with that role or a higher one
+	var granted string
+	var roleArgs []interface{}
+	if i := slices.Index(models.WorkspaceRoles, models.WorkspaceRoleFromContext(ctx)); i >= 0 {
+		roles := models.WorkspaceRoles[:i+1]
+		granted = " OR a.role IN (?" + strings.Repeat(", ?", len(roles)-1) + ")"
+		for _, role := range roles {
+			roleArgs = append(roleArgs, role)
+		}
+	}
+
+	query := `WITH RECURSIVE hidden_folders(id) AS (
+			SELECT f.id FROM folders f
+			WHERE f.workspace_id = ? AND f.user_id != ?
+			  AND EXISTS (SELECT 1 FROM folder_access a WHERE a.folder_id = f.id)
+			  AND NOT EXISTS (SELECT 1 FROM folder_access a WHERE a.folder_id = f.id AND (a.user_id = ?` + granted + `))
+			UNION
+			SELECT f.id FROM folders f INNER JOIN hidden_folders h ON f.parent_id = h.id
+		) SELECT id FROM hidden_folders`
+	return query, append([]interface{}{ws, userID, userID}, roleArgs...)
+}
+
+// personalFilter is like ownerFilter but always limits the query to the
+// user in ctx, for rows such as API tokens that workspace members don't share
+func personalFilter(ctx context.Context, column string) (string, []interface{}) {
+	id, ok := models.UserIDFromContext(ctx)
+	if !ok {
+		return "", nil
+	}
+	workspaceColumn := strings.TrimSuffix(column, "user_id") + "workspace_id"
+	return " AND " + column + " = ? AND " + workspaceColumn + " = ?", []interface{}{id, models.WorkspaceIDFromContext(ctx)}
+}
+
+// ownerID returns the user new rows created in ctx belong to
+func ownerID(ctx context.Context) int64 {
+	id, _ := models.UserIDFromContext(ctx)
+	return id
+}
+
+// workspaceID returns the workspace new rows created in ctx belong to
+func workspaceID(ctx context.Context) int64 {
+	return models.WorkspaceIDFromContext(ctx)
+}
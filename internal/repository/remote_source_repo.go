@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// RemoteSourceRepository handles remote source database operations
+type RemoteSourceRepository struct {
+	db *sql.DB
+}
+
+// NewRemoteSourceRepository creates a new remote source repository
+func NewRemoteSourceRepository(db *sql.DB) *RemoteSourceRepository {
+	return &RemoteSourceRepository{db: db}
+}
+
+const remoteSourceColumns = `
+	rs.id, rs.name, rs.url, rs.folder_id, rs.enabled, rs.etag,
+	rs.last_synced_at, rs.last_error, rs.created_at, rs.updated_at,
+	(SELECT COUNT(*) FROM remote_snippets WHERE source_id = rs.id)
+`
+
+func scanRemoteSource(row interface{ Scan(...any) error }) (*models.RemoteSource, error) {
+	source := &models.RemoteSource{}
+	var folderID sql.NullInt64
+	var lastSyncedAt sql.NullTime
+	var lastError sql.NullString
+
+	err := row.Scan(
+		&source.ID,
+		&source.Name,
+		&source.URL,
+		&folderID,
+		&source.Enabled,
+		&source.ETag,
+		&lastSyncedAt,
+		&lastError,
+		&source.CreatedAt,
+		&source.UpdatedAt,
+		&source.SnippetCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if folderID.Valid {
+		source.FolderID = &folderID.Int64
+	}
+	if lastSyncedAt.Valid {
+		source.LastSyncedAt = &lastSyncedAt.Time
+	}
+	if lastError.Valid {
+		source.LastError = &lastError.String
+	}
+
+	return source, nil
+}
+
+// Create adds a new remote source
+func (r *RemoteSourceRepository) Create(ctx context.Context, input *models.RemoteSourceInput) (*models.RemoteSource, error) {
+	enabled := true
+	if input.Enabled != nil {
+		enabled = *input.Enabled
+	}
+
+	var id int64
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO remote_sources (name, url, enabled) VALUES (?, ?, ?) RETURNING id`,
+		input.Name, input.URL, enabled,
+	).Scan(&id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrAlreadyExists
+		}
+		return nil, fmt.Errorf("failed to create remote source: %w", err)
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// GetByID retrieves a remote source by ID
+func (r *RemoteSourceRepository) GetByID(ctx context.Context, id int64) (*models.RemoteSource, error) {
+	query := `SELECT ` + remoteSourceColumns + ` FROM remote_sources rs WHERE rs.id = ?`
+
+	source, err := scanRemoteSource(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remote source: %w", err)
+	}
+
+	return source, nil
+}
+
+// List retrieves all remote sources
+func (r *RemoteSourceRepository) List(ctx context.Context) ([]models.RemoteSource, error) {
+	query := `SELECT ` + remoteSourceColumns + ` FROM remote_sources rs ORDER BY rs.name`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote sources: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	sources := []models.RemoteSource{}
+	for rows.Next() {
+		source, err := scanRemoteSource(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan remote source: %w", err)
+		}
+		sources = append(sources, *source)
+	}
+
+	return sources, rows.Err()
+}
+
+// UpdateSyncState records the outcome of a sync. A nil syncErr clears the last error.
+func (r *RemoteSourceRepository) UpdateSyncState(ctx context.Context, id int64, etag string, folderID *int64, syncErr error) error {
+	var lastError *string
+	if syncErr != nil {
+		msg := syncErr.Error()
+		lastError = &msg
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE remote_sources
+		SET etag = ?, folder_id = ?, last_error = ?, last_synced_at = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, etag, folderID, lastError, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update remote source sync state: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a remote source and its snippet mappings
+func (r *RemoteSourceRepository) Delete(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM remote_snippets WHERE source_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete remote snippet mappings: %w", err)
+	}
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM remote_sources WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete remote source: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ListSnippets retrieves the snippet mappings for a remote source
+func (r *RemoteSourceRepository) ListSnippets(ctx context.Context, sourceID int64) ([]models.RemoteSnippet, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT source_id, remote_id, snippet_id, remote_updated_at
+		FROM remote_snippets
+		WHERE source_id = ?
+	`, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote snippets: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var mappings []models.RemoteSnippet
+	for rows.Next() {
+		var m models.RemoteSnippet
+		if err := rows.Scan(&m.SourceID, &m.RemoteID, &m.SnippetID, &m.RemoteUpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan remote snippet: %w", err)
+		}
+		mappings = append(mappings, m)
+	}
+
+	return mappings, rows.Err()
+}
+
+// UpsertSnippet creates or updates a remote snippet mapping
+func (r *RemoteSourceRepository) UpsertSnippet(ctx context.Context, m *models.RemoteSnippet) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO remote_snippets (source_id, remote_id, snippet_id, remote_updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(source_id, remote_id) DO UPDATE SET
+			snippet_id = excluded.snippet_id,
+			remote_updated_at = excluded.remote_updated_at
+	`, m.SourceID, m.RemoteID, m.SnippetID, m.RemoteUpdatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to upsert remote snippet: %w", err)
+	}
+	return nil
+}
+
+// DeleteSnippet removes a remote snippet mapping
+func (r *RemoteSourceRepository) DeleteSnippet(ctx context.Context, sourceID int64, remoteID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM remote_snippets WHERE source_id = ? AND remote_id = ?`, sourceID, remoteID)
+	if err != nil {
+		return fmt.Errorf("failed to delete remote snippet: %w", err)
+	}
+	return nil
+}
+
+// IsRemoteSnippet reports whether a snippet is a read-only mirror of a remote snippet
+func (r *RemoteSourceRepository) IsRemoteSnippet(ctx context.Context, snippetID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM remote_snippets WHERE snippet_id = ?)`, snippetID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check remote snippet: %w", err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// ReportRepository handles abuse report database operations
+type ReportRepository struct {
+	db *sql.DB
+}
+
+// NewReportRepository creates a new report repository
+func NewReportRepository(db *sql.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+const reportColumns = `
+	r.id, r.snippet_id, COALESCE(s.title, ''), r.reason, r.details, r.status, r.created_at, r.resolved_at
+`
+
+func scanReport(row interface{ Scan(...any) error }) (*models.Report, error) {
+	report := &models.Report{}
+	var resolvedAt sql.NullTime
+
+	err := row.Scan(
+		&report.ID,
+		&report.SnippetID,
+		&report.SnippetTitle,
+		&report.Reason,
+		&report.Details,
+		&report.Status,
+		&report.CreatedAt,
+		&resolvedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolvedAt.Valid {
+		report.ResolvedAt = &resolvedAt.Time
+	}
+
+	return report, nil
+}
+
+// Create files a new open report against a snippet
+func (r *ReportRepository) Create(ctx context.Context, snippetID string, input *models.ReportInput) (*models.Report, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO snippet_reports (snippet_id, reason, details) VALUES (?, ?, ?) RETURNING id`,
+		snippetID, input.Reason, input.Details,
+	).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report: %w", err)
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// GetByID retrieves a report, or nil if it does not exist
+func (r *ReportRepository) GetByID(ctx context.Context, id int64) (*models.Report, error) {
+	query := `SELECT ` + reportColumns + `
+		FROM snippet_reports r
+		LEFT JOIN snippets s ON s.id = r.snippet_id
+		WHERE r.id = ?`
+
+	report, err := scanReport(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report: %w", err)
+	}
+
+	return report, nil
+}
+
+// List returns reports with the given status, oldest first so the queue is
+// worked in order. An empty status returns every report, newest first.
+func (r *ReportRepository) List(ctx context.Context, status string) ([]models.Report, error) {
+	query := `SELECT ` + reportColumns + `
+		FROM snippet_reports r
+		LEFT JOIN snippets s ON s.id = r.snippet_id`
+	var args []interface{}
+	if status != "" {
+		query += ` WHERE r.status = ? ORDER BY r.created_at ASC, r.id ASC`
+		args = append(args, status)
+	} else {
+		query += ` ORDER BY r.created_at DESC, r.id DESC`
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	reports := []models.Report{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report: %w", err)
+		}
+		reports = append(reports, *report)
+	}
+
+	return reports, rows.Err()
+}
+
+// CountOpen returns the number of open reports against a snippet
+func (r *ReportRepository) CountOpen(ctx context.Context, snippetID string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM snippet_reports WHERE snippet_id = ? AND status = ?`,
+		snippetID, models.ReportStatusOpen,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count reports: %w", err)
+	}
+	return count, nil
+}
+
+// Resolve closes an open report with the given status
+func (r *ReportRepository) Resolve(ctx context.Context, id int64, status string) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE snippet_reports SET status = ?, resolved_at = CURRENT_TIMESTAMP WHERE id = ? AND status = ?`,
+		status, id, models.ReportStatusOpen,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to resolve report: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ResolveAllForSnippet closes every open report against a snippet
+func (r *ReportRepository) ResolveAllForSnippet(ctx context.Context, snippetID, status string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE snippet_reports SET status = ?, resolved_at = CURRENT_TIMESTAMP WHERE snippet_id = ? AND status = ?`,
+		status, snippetID, models.ReportStatusOpen,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to resolve reports: %w", err)
+	}
+	return nil
+}
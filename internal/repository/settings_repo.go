@@ -4,11 +4,102 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/MohamedElashri/snipo/internal/models"
 )
 
-// SettingsRepository handles settings database operations
+// settingDefaults lists every setting kept in the app_settings key-value
+// table together with the value used until it is first saved. Keys match the
+// JSON names of the models.Settings and models.SettingsInput fields, so a new
+// setting only needs those fields and an entry here, no migration.
+var settingDefaults = map[string]string{
+	"app_name":                          "snipo",
+	"custom_css":                        "",
+	"theme":                             "auto",
+	"default_language":                  "plaintext",
+	"s3_enabled":                        "false",
+	"s3_endpoint":                       "",
+	"s3_bucket":                         "",
+	"s3_region":                         "us-east-1",
+	"backup_encryption_enabled":         "false",
+	"archive_enabled":                   "false",
+	"trash_enabled":                     "true",
+	"history_enabled":                   "true",
+	"auto_archive_enabled":              "false",
+	"default_expiration_days":           "0",
+	"disable_login":                     "false",
+	"editor_font_size":                  "14",
+	"editor_tab_size":                   "2",
+	"editor_theme":                      "auto",
+	"editor_word_wrap":                  "true",
+	"editor_show_print_margin":          "false",
+	"editor_show_gutter":                "true",
+	"editor_show_indent_guides":         "true",
+	"editor_highlight_active_line":      "true",
+	"editor_use_soft_tabs":              "true",
+	"editor_enable_snippets":            "true",
+	"editor_enable_live_autocompletion": "true",
+	"markdown_font_size":                "14",
+	"exclude_first_line_on_copy":        "false",
+	"reject_duplicate_content":          "false",
+	"report_unpublish_threshold":        "0",
+	"markdown_html_policy":              models.MarkdownHTMLSanitize,
+	"tag_palette":                       strings.Join(models.DefaultTagPalette, ","),
+	"digest_enabled":                    "false",
+	"digest_recipients":                 "",
+	"search_tokenizer":                  models.SearchTokenizerDefault,
+}
+
+// workspaceSettingKeys lists the settings a workspace can override in
+// workspace_settings. The rest, such as storage, login and background jobs,
+// apply to the whole instance.
+var workspaceSettingKeys = map[string]bool{
+	"app_name":                          true,
+	"custom_css":                        true,
+	"theme":                             true,
+	"default_language":                  true,
+	"archive_enabled":                   true,
+	"trash_enabled":                     true,
+	"history_enabled":                   true,
+	"default_expiration_days":           true,
+	"editor_font_size":                  true,
+	"editor_tab_size":                   true,
+	"editor_theme":                      true,
+	"editor_word_wrap":                  true,
+	"editor_show_print_margin":          true,
+	"editor_show_gutter":                true,
+	"editor_show_indent_guides":         true,
+	"editor_highlight_active_line":      true,
+	"editor_use_soft_tabs":              true,
+	"editor_enable_snippets":            true,
+	"editor_enable_live_autocompletion": true,
+	"markdown_font_size":                true,
+	"exclude_first_line_on_copy":        true,
+	"reject_duplicate_content":          true,
+	"tag_palette":                       true,
+}
+
+// featureKeyPrefix namespaces runtime feature flags in app_settings.
+// Every feature is enabled until switched off.
+const featureKeyPrefix = "feature_"
+
+func init() {
+	for _, name := range models.RuntimeFeatures {
+		settingDefaults[featureKey(name)] = "true"
+	}
+}
+
+func featureKey(name string) string {
+	return featureKeyPrefix + name
+}
+
+// SettingsRepository handles settings database operations. Values live in
+// the app_settings key-value table; the single settings row only tracks
+// when settings were created and last changed. Inside a workspace, values in
+// workspace_settings take precedence.
 type SettingsRepository struct {
 	db *sql.DB
 }
@@ -20,157 +111,267 @@ func NewSettingsRepository(db *sql.DB) *SettingsRepository {
 
 // Get retrieves application settings
 func (r *SettingsRepository) Get(ctx context.Context) (*models.Settings, error) {
-	query := `
-		SELECT id, app_name, custom_css, theme, default_language,
-		       s3_enabled, s3_endpoint, s3_bucket, s3_region,
-		       backup_encryption_enabled, archive_enabled, trash_enabled, history_enabled,
-		       auto_archive_enabled, default_expiration_days,
-		       disable_login,
-		       editor_font_size, editor_tab_size, editor_theme, editor_word_wrap,
-		       editor_show_print_margin, editor_show_gutter, editor_show_indent_guides,
-		       editor_highlight_active_line, editor_use_soft_tabs, editor_enable_snippets,
-		       editor_enable_live_autocompletion, markdown_font_size, exclude_first_line_on_copy,
-		       created_at, updated_at
-		FROM settings
-		WHERE id = 1
-	`
-
 	settings := &models.Settings{}
-	err := r.db.QueryRowContext(ctx, query).Scan(
-		&settings.ID,
-		&settings.AppName,
-		&settings.CustomCSS,
-		&settings.Theme,
-		&settings.DefaultLanguage,
-		&settings.S3Enabled,
-		&settings.S3Endpoint,
-		&settings.S3Bucket,
-		&settings.S3Region,
-		&settings.BackupEncryptionEnabled,
-		&settings.ArchiveEnabled,
-		&settings.TrashEnabled,
-		&settings.HistoryEnabled,
-		&settings.AutoArchiveEnabled,
-		&settings.DefaultExpirationDays,
-		&settings.DisableLogin,
-		&settings.EditorFontSize,
-		&settings.EditorTabSize,
-		&settings.EditorTheme,
-		&settings.EditorWordWrap,
-		&settings.EditorShowPrintMargin,
-		&settings.EditorShowGutter,
-		&settings.EditorShowIndentGuides,
-		&settings.EditorHighlightActiveLine,
-		&settings.EditorUseSoftTabs,
-		&settings.EditorEnableSnippets,
-		&settings.EditorEnableLiveAutocompletion,
-		&settings.MarkdownFontSize,
-		&settings.ExcludeFirstLineOnCopy,
-		&settings.CreatedAt,
-		&settings.UpdatedAt,
-	)
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id, created_at, updated_at FROM settings WHERE id = 1",
+	).Scan(&settings.ID, &settings.CreatedAt, &settings.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
 
+	values, err := loadSettings(ctx, r.db)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get settings: %w", err)
 	}
+	if err := decodeSettings(values, settings); err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	settings.Features = make(map[string]bool, len(models.RuntimeFeatures))
+	for _, name := range models.RuntimeFeatures {
+		settings.Features[name], _ = strconv.ParseBool(values[featureKey(name)])
+	}
 
 	return settings, nil
 }
 
-// Update updates application settings
+// Update updates application settings. Inside a workspace only the settings
+// a workspace can override are saved, for that workspace alone.
 func (r *SettingsRepository) Update(ctx context.Context, input *models.SettingsInput) (*models.Settings, error) {
-	query := `
-		UPDATE settings
-		SET app_name = ?, custom_css = ?, theme = ?, default_language = ?,
-		    s3_enabled = ?, s3_endpoint = ?, s3_bucket = ?, s3_region = ?,
-		    backup_encryption_enabled = ?, archive_enabled = ?, trash_enabled = ?, history_enabled = ?,
-		    auto_archive_enabled = ?, default_expiration_days = ?,
-		    disable_login = ?,
-		    editor_font_size = ?, editor_tab_size = ?, editor_theme = ?, editor_word_wrap = ?,
-		    editor_show_print_margin = ?, editor_show_gutter = ?, editor_show_indent_guides = ?,
-		    editor_highlight_active_line = ?, editor_use_soft_tabs = ?, editor_enable_snippets = ?,
-		    editor_enable_live_autocompletion = ?, markdown_font_size = ?, exclude_first_line_on_copy = ?,
-		    updated_at = CURRENT_TIMESTAMP
-		WHERE id = 1
-		RETURNING id, app_name, custom_css, theme, default_language,
-		          s3_enabled, s3_endpoint, s3_bucket, s3_region,
-		          backup_encryption_enabled, archive_enabled, trash_enabled, history_enabled,
-		          auto_archive_enabled, default_expiration_days,
-		          disable_login,
-		          editor_font_size, editor_tab_size, editor_theme, editor_word_wrap,
-		          editor_show_print_margin, editor_show_gutter, editor_show_indent_guides,
-		          editor_highlight_active_line, editor_use_soft_tabs, editor_enable_snippets,
-		          editor_enable_live_autocompletion, markdown_font_size, exclude_first_line_on_copy,
-		          created_at, updated_at
-	`
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
 
-	settings := &models.Settings{}
-	err := r.db.QueryRowContext(ctx, query,
-		input.AppName,
-		input.CustomCSS,
-		input.Theme,
-		input.DefaultLanguage,
-		input.S3Enabled,
-		input.S3Endpoint,
-		input.S3Bucket,
-		input.S3Region,
-		input.BackupEncryptionEnabled,
-		input.ArchiveEnabled,
-		input.TrashEnabled,
-		input.HistoryEnabled,
-		input.AutoArchiveEnabled,
-		input.DefaultExpirationDays,
-		input.DisableLogin,
-		input.EditorFontSize,
-		input.EditorTabSize,
-		input.EditorTheme,
-		input.EditorWordWrap,
-		input.EditorShowPrintMargin,
-		input.EditorShowGutter,
-		input.EditorShowIndentGuides,
-		input.EditorHighlightActiveLine,
-		input.EditorUseSoftTabs,
-		input.EditorEnableSnippets,
-		input.EditorEnableLiveAutocompletion,
-		input.MarkdownFontSize,
-		input.ExcludeFirstLineOnCopy,
-	).Scan(
-		&settings.ID,
-		&settings.AppName,
-		&settings.CustomCSS,
-		&settings.Theme,
-		&settings.DefaultLanguage,
-		&settings.S3Enabled,
-		&settings.S3Endpoint,
-		&settings.S3Bucket,
-		&settings.S3Region,
-		&settings.BackupEncryptionEnabled,
-		&settings.ArchiveEnabled,
-		&settings.TrashEnabled,
-		&settings.HistoryEnabled,
-		&settings.AutoArchiveEnabled,
-		&settings.DefaultExpirationDays,
-		&settings.DisableLogin,
-		&settings.EditorFontSize,
-		&settings.EditorTabSize,
-		&settings.EditorTheme,
-		&settings.EditorWordWrap,
-		&settings.EditorShowPrintMargin,
-		&settings.EditorShowGutter,
-		&settings.EditorShowIndentGuides,
-		&settings.EditorHighlightActiveLine,
-		&settings.EditorUseSoftTabs,
-		&settings.EditorEnableSnippets,
-		&settings.EditorEnableLiveAutocompletion,
-		&settings.MarkdownFontSize,
-		&settings.ExcludeFirstLineOnCopy,
-		&settings.CreatedAt,
-		&settings.UpdatedAt,
-	)
+	values := encodeSettings(input)
+	features := input.Features
+	if models.WorkspaceIDFromContext(ctx) != models.DefaultWorkspaceID {
+		for key := range values {
+			if !workspaceSettingKeys[key] {
+				delete(values, key)
+			}
+		}
+		features = nil
+	}
+	for name, enabled := range features {
+		key := featureKey(name)
+		if _, ok := settingDefaults[key]; !ok {
+			return nil, fmt.Errorf("unknown feature: %s", name)
+		}
+		values[key] = strconv.FormatBool(enabled)
+	}
+	for key, value := range values {
+		if err := setSetting(ctx, tx, key, value); err != nil {
+			return nil, fmt.Errorf("failed to update settings: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE settings SET updated_at = CURRENT_TIMESTAMP WHERE id = 1"); err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
 
-	if err != nil {
+	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to update settings: %w", err)
 	}
 
-	return settings, nil
+	return r.Get(ctx)
+}
+
+// GetString returns a single setting, or its default if it was never saved
+func (r *SettingsRepository) GetString(ctx context.Context, key string) (string, error) {
+	return getSetting(ctx, r.db, key)
+}
+
+// GetBool returns a boolean setting
+func (r *SettingsRepository) GetBool(ctx context.Context, key string) (bool, error) {
+	return settingBool(ctx, r.db, key)
+}
+
+// GetInt returns an integer setting
+func (r *SettingsRepository) GetInt(ctx context.Context, key string) (int, error) {
+	value, err := getSetting(ctx, r.db, key)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("setting %s is not an integer: %w", key, err)
+	}
+	return n, nil
+}
+
+// FeatureEnabled reports whether a runtime feature is switched on
+func (r *SettingsRepository) FeatureEnabled(ctx context.Context, name string) (bool, error) {
+	return settingBool(ctx, r.db, featureKey(name))
+}
+
+// Set saves a single setting. The value is stored in its string form.
+func (r *SettingsRepository) Set(ctx context.Context, key string, value any) error {
+	if _, ok := settingDefaults[key]; !ok {
+		return fmt.Errorf("unknown setting: %s", key)
+	}
+	if err := setSetting(ctx, r.db, key, fmt.Sprint(value)); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, "UPDATE settings SET updated_at = CURRENT_TIMESTAMP WHERE id = 1")
+	return err
+}
+
+// settingQuerier is satisfied by both *sql.DB and *sql.Tx
+type settingQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// loadSettings returns every known setting, falling back to defaults, with
+// the overrides of the workspace in ctx applied
+func loadSettings(ctx context.Context, q settingQuerier) (map[string]string, error) {
+	values := make(map[string]string, len(settingDefaults))
+	for key, value := range settingDefaults {
+		values[key] = value
+	}
+
+	if err := scanSettings(ctx, q, values, "SELECT key, value FROM app_settings"); err != nil {
+		return nil, err
+	}
+	if ws := models.WorkspaceIDFromContext(ctx); ws != models.DefaultWorkspaceID {
+		overrides := make(map[string]string)
+		if err := scanSettings(ctx, q, overrides, "SELECT key, value FROM workspace_settings WHERE workspace_id = ?", ws); err != nil {
+			return nil, err
+		}
+		for key, value := range overrides {
+			if workspaceSettingKeys[key] {
+				values[key] = value
+			}
+		}
+	}
+	return values, nil
+}
+
+// scanSettings adds the key and value rows returned by query to values
+func scanSettings(ctx context.Context, q settingQuerier, values map[string]string, query string, args ...any) error {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return err
+		}
+		values[key] = value
+	}
+	return rows.Err()
+}
+
+func getSetting(ctx context.Context, q settingQuerier, key string) (string, error) {
+	value, ok := settingDefaults[key]
+	if !ok {
+		return "", fmt.Errorf("unknown setting: %s", key)
+	}
+	if ws := models.WorkspaceIDFromContext(ctx); ws != models.DefaultWorkspaceID && workspaceSettingKeys[key] {
+		err := q.QueryRowContext(ctx, "SELECT value FROM workspace_settings WHERE workspace_id = ? AND key = ?", ws, key).Scan(&value)
+		if err == nil {
+			return value, nil
+		}
+		if err != sql.ErrNoRows {
+			return "", fmt.Errorf("failed to get setting %s: %w", key, err)
+		}
+	}
+	err := q.QueryRowContext(ctx, "SELECT value FROM app_settings WHERE key = ?", key).Scan(&value)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to get setting %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// settingBool reads a boolean setting; other repositories use it to honour
+// settings such as trash_enabled without depending on SettingsRepository
+func settingBool(ctx context.Context, q settingQuerier, key string) (bool, error) {
+	value, err := getSetting(ctx, q, key)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("setting %s is not a boolean: %w", key, err)
+	}
+	return b, nil
+}
+
+// setSetting saves a setting for the workspace in ctx when it can override
+// it, and for the whole instance otherwise
+func setSetting(ctx context.Context, q settingQuerier, key, value string) error {
+	var err error
+	if ws := models.WorkspaceIDFromContext(ctx); ws != models.DefaultWorkspaceID && workspaceSettingKeys[key] {
+		_, err = q.ExecContext(ctx, `
+			INSERT INTO workspace_settings (workspace_id, key, value, updated_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+			ON CONFLICT(workspace_id, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+		`, ws, key, value)
+	} else {
+		_, err = q.ExecContext(ctx, `
+			INSERT INTO app_settings (key, value, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+			ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+		`, key, value)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save setting %s: %w", key, err)
+	}
+	return nil
+}
+
+// settingKey returns the setting key of a struct field, or "" if the field
+// isn't a stored setting
+func settingKey(field reflect.StructField) string {
+	key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if _, ok := settingDefaults[key]; !ok {
+		return ""
+	}
+	return key
+}
+
+// decodeSettings fills the setting fields of settings from values
+func decodeSettings(values map[string]string, settings *models.Settings) error {
+	v := reflect.ValueOf(settings).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key := settingKey(v.Type().Field(i))
+		if key == "" {
+			continue
+		}
+		raw := values[key]
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(raw)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("setting %s is not a boolean: %w", key, err)
+			}
+			field.SetBool(b)
+		case reflect.Int, reflect.Int64:
+			n, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return fmt.Errorf("setting %s is not an integer: %w", key, err)
+			}
+			field.SetInt(n)
+		default:
+			return fmt.Errorf("setting %s has unsupported type %s", key, field.Kind())
+		}
+	}
+	return nil
+}
+
+// encodeSettings returns the stored settings in input as strings
+func encodeSettings(input *models.SettingsInput) map[string]string {
+	values := make(map[string]string)
+	v := reflect.ValueOf(input).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if key := settingKey(v.Type().Field(i)); key != "" {
+			values[key] = fmt.Sprint(v.Field(i).Interface())
+		}
+	}
+	return values
 }
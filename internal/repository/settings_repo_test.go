@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestSettingsRepository_KeyValue(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	repo := NewSettingsRepository(db)
+
+	// Nothing saved yet: every setting comes from its default
+	settings, err := repo.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if settings.ID != 1 || !settings.TrashEnabled || settings.EditorFontSize != 14 || settings.MarkdownHTMLPolicy != models.MarkdownHTMLSanitize {
+		t.Errorf("unexpected defaults: %+v", settings)
+	}
+
+	input := &models.SettingsInput{
+		AppName:            "My Snippets",
+		Theme:              "dark",
+		TrashEnabled:       false,
+		EditorFontSize:     18,
+		MarkdownHTMLPolicy: models.MarkdownHTMLEscape,
+	}
+	updated, err := repo.Update(ctx, input)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated.AppName != "My Snippets" || updated.Theme != "dark" || updated.TrashEnabled || updated.EditorFontSize != 18 {
+		t.Errorf("unexpected settings after update: %+v", updated)
+	}
+
+	if trash, err := repo.GetBool(ctx, "trash_enabled"); err != nil || trash {
+		t.Errorf("GetBool(trash_enabled) = %v, %v", trash, err)
+	}
+	if size, err := repo.GetInt(ctx, "editor_font_size"); err != nil || size != 18 {
+		t.Errorf("GetInt(editor_font_size) = %v, %v", size, err)
+	}
+
+	if err := repo.Set(ctx, "markdown_html_policy", models.MarkdownHTMLAllow); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if policy, err := repo.GetString(ctx, "markdown_html_policy"); err != nil || policy != models.MarkdownHTMLAllow {
+		t.Errorf("GetString(markdown_html_policy) = %q, %v", policy, err)
+	}
+
+	// Feature flags default to on and only change when listed
+	if !updated.Features[models.FeatureGistSync] || !updated.Features[models.FeaturePublicSnippets] {
+		t.Errorf("expected features to default to enabled, got %v", updated.Features)
+	}
+	updated, err = repo.Update(ctx, &models.SettingsInput{Features: map[string]bool{models.FeatureGistSync: false}})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated.Features[models.FeatureGistSync] || !updated.Features[models.FeaturePublicSnippets] {
+		t.Errorf("unexpected features after update: %v", updated.Features)
+	}
+	if enabled, err := repo.FeatureEnabled(ctx, models.FeatureGistSync); err != nil || enabled {
+		t.Errorf("FeatureEnabled(gist_sync) = %v, %v", enabled, err)
+	}
+	if _, err := repo.Update(ctx, &models.SettingsInput{Features: map[string]bool{"no_such_feature": true}}); err == nil {
+		t.Error("expected unknown feature to be rejected")
+	}
+
+	if err := repo.Set(ctx, "no_such_setting", true); err == nil {
+		t.Error("expected unknown setting to be rejected")
+	}
+	if _, err := repo.GetString(ctx, "no_such_setting"); err == nil {
+		t.Error("expected unknown setting to be rejected")
+	}
+}
+
+// Every stored setting must be readable and writable through the API models
+func TestSettingDefaults_MatchModels(t *testing.T) {
+	for _, model := range []any{models.Settings{}, models.SettingsInput{}} {
+		found := map[string]bool{}
+		typ := reflect.TypeOf(model)
+		for i := 0; i < typ.NumField(); i++ {
+			if key := settingKey(typ.Field(i)); key != "" {
+				found[key] = true
+			}
+		}
+		for key := range settingDefaults {
+			if !found[key] && !strings.HasPrefix(key, featureKeyPrefix) {
+				t.Errorf("%s has no field for setting %q", typ.Name(), key)
+			}
+		}
+	}
+
+	// Defaults must decode into their fields
+	if err := decodeSettings(settingDefaults, &models.Settings{}); err != nil {
+		t.Errorf("defaults don't decode: %v", err)
+	}
+}
@@ -21,7 +21,7 @@ func NewSnippetFileRepository(db *sql.DB) *SnippetFileRepository {
 // GetBySnippetID retrieves all files for a snippet
 func (r *SnippetFileRepository) GetBySnippetID(ctx context.Context, snippetID string) ([]models.SnippetFile, error) {
 	query := `
-		SELECT id, snippet_id, filename, content, language, sort_order, created_at, updated_at
+		SELECT id, snippet_id, filename, content, language, note, sort_order, created_at, updated_at
 		FROM snippet_files
 		WHERE snippet_id = ?
 		ORDER BY sort_order, id
@@ -44,6 +44,7 @@ func (r *SnippetFileRepository) GetBySnippetID(ctx context.Context, snippetID st
 			&f.Filename,
 			&f.Content,
 			&f.Language,
+			&f.Note,
 			&f.SortOrder,
 			&f.CreatedAt,
 			&f.UpdatedAt,
@@ -58,18 +59,24 @@ func (r *SnippetFileRepository) GetBySnippetID(ctx context.Context, snippetID st
 
 // Create creates a new snippet file
 func (r *SnippetFileRepository) Create(ctx context.Context, snippetID string, file *models.SnippetFileInput, sortOrder int) (*models.SnippetFile, error) {
+	return insertSnippetFile(ctx, r.db, snippetID, file, sortOrder)
+}
+
+// insertSnippetFile adds a file to a snippet; q may be a transaction
+func insertSnippetFile(ctx context.Context, q settingQuerier, snippetID string, file *models.SnippetFileInput, sortOrder int) (*models.SnippetFile, error) {
 	query := `
-		INSERT INTO snippet_files (snippet_id, filename, content, language, sort_order)
-		VALUES (?, ?, ?, ?, ?)
-		RETURNING id, snippet_id, filename, content, language, sort_order, created_at, updated_at
+		INSERT INTO snippet_files (snippet_id, filename, content, language, note, sort_order)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id, snippet_id, filename, content, language, note, sort_order, created_at, updated_at
 	`
 
 	var f models.SnippetFile
-	err := r.db.QueryRowContext(ctx, query,
+	err := q.QueryRowContext(ctx, query,
 		snippetID,
 		file.Filename,
 		file.Content,
 		file.Language,
+		file.Note,
 		sortOrder,
 	).Scan(
 		&f.ID,
@@ -77,6 +84,7 @@ func (r *SnippetFileRepository) Create(ctx context.Context, snippetID string, fi
 		&f.Filename,
 		&f.Content,
 		&f.Language,
+		&f.Note,
 		&f.SortOrder,
 		&f.CreatedAt,
 		&f.UpdatedAt,
@@ -92,9 +100,9 @@ func (r *SnippetFileRepository) Create(ctx context.Context, snippetID string, fi
 func (r *SnippetFileRepository) Update(ctx context.Context, file *models.SnippetFileInput, sortOrder int) (*models.SnippetFile, error) {
 	query := `
 		UPDATE snippet_files
-		SET filename = ?, content = ?, language = ?, sort_order = ?, updated_at = CURRENT_TIMESTAMP
+		SET filename = ?, content = ?, language = ?, note = ?, sort_order = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
-		RETURNING id, snippet_id, filename, content, language, sort_order, created_at, updated_at
+		RETURNING id, snippet_id, filename, content, language, note, sort_order, created_at, updated_at
 	`
 
 	var f models.SnippetFile
@@ -102,6 +110,7 @@ func (r *SnippetFileRepository) Update(ctx context.Context, file *models.Snippet
 		file.Filename,
 		file.Content,
 		file.Language,
+		file.Note,
 		sortOrder,
 		file.ID,
 	).Scan(
@@ -110,6 +119,7 @@ func (r *SnippetFileRepository) Update(ctx context.Context, file *models.Snippet
 		&f.Filename,
 		&f.Content,
 		&f.Language,
+		&f.Note,
 		&f.SortOrder,
 		&f.CreatedAt,
 		&f.UpdatedAt,
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"html"
 	"log/slog"
 	"strings"
 	"time"
@@ -23,15 +24,20 @@ func NewSnippetRepository(db *sql.DB) *SnippetRepository {
 
 // Create inserts a new snippet
 func (r *SnippetRepository) Create(ctx context.Context, input *models.SnippetInput) (*models.Snippet, error) {
+	return createSnippet(ctx, r.db, input)
+}
+
+// createSnippet inserts a snippet row; q may be a transaction
+func createSnippet(ctx context.Context, q settingQuerier, input *models.SnippetInput) (*models.Snippet, error) {
 	query := `
-		INSERT INTO snippets (title, description, content, language, is_public, is_archived, expires_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO snippets (title, description, content, language, is_public, is_archived, expires_at, publish_at, user_id, workspace_id, public_token)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CASE WHEN ? THEN lower(hex(randomblob(12))) END)
 		RETURNING id, title, description, content, language, is_favorite, is_public,
-		          view_count, s3_key, checksum, is_archived, expires_at, created_at, updated_at, deleted_at
+		          view_count, use_count, public_view_count, last_used_at, s3_key, checksum, is_archived, expires_at, publish_at, provenance, external_id, public_token, created_at, updated_at, deleted_at
 	`
 
 	snippet := &models.Snippet{}
-	err := r.db.QueryRowContext(ctx, query,
+	err := q.QueryRowContext(ctx, query,
 		input.Title,
 		input.Description,
 		input.Content,
@@ -39,6 +45,10 @@ func (r *SnippetRepository) Create(ctx context.Context, input *models.SnippetInp
 		input.IsPublic,
 		input.IsArchived,
 		input.ExpiresAt,
+		input.PublishAt,
+		ownerID(ctx),
+		workspaceID(ctx),
+		input.IsPublic,
 	).Scan(
 		&snippet.ID,
 		&snippet.Title,
@@ -48,10 +58,17 @@ func (r *SnippetRepository) Create(ctx context.Context, input *models.SnippetInp
 		&snippet.IsFavorite,
 		&snippet.IsPublic,
 		&snippet.ViewCount,
+		&snippet.UseCount,
+		&snippet.PublicViewCount,
+		&snippet.LastUsedAt,
 		&snippet.S3Key,
 		&snippet.Checksum,
 		&snippet.IsArchived,
 		&snippet.ExpiresAt,
+		&snippet.PublishAt,
+		&snippet.Provenance,
+		&snippet.ExternalID,
+		&snippet.PublicToken,
 		&snippet.CreatedAt,
 		&snippet.UpdatedAt,
 		&snippet.DeletedAt,
@@ -66,15 +83,15 @@ func (r *SnippetRepository) Create(ctx context.Context, input *models.SnippetInp
 
 // GetByID retrieves a snippet by ID
 func (r *SnippetRepository) GetByID(ctx context.Context, id string) (*models.Snippet, error) {
+	owner, ownerArgs := snippetFilter(ctx, "user_id")
 	query := `
 		SELECT id, title, description, content, language, is_favorite, is_public,
-		       view_count, s3_key, checksum, is_archived, expires_at, created_at, updated_at, deleted_at
+		       view_count, use_count, public_view_count, last_used_at, s3_key, checksum, is_archived, expires_at, publish_at, provenance, external_id, public_token, created_at, updated_at, deleted_at
 		FROM snippets
-		WHERE id = ?
-	`
+		WHERE id = ?` + owner
 
 	snippet := &models.Snippet{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, append([]interface{}{id}, ownerArgs...)...).Scan(
 		&snippet.ID,
 		&snippet.Title,
 		&snippet.Description,
@@ -83,10 +100,17 @@ func (r *SnippetRepository) GetByID(ctx context.Context, id string) (*models.Sni
 		&snippet.IsFavorite,
 		&snippet.IsPublic,
 		&snippet.ViewCount,
+		&snippet.UseCount,
+		&snippet.PublicViewCount,
+		&snippet.LastUsedAt,
 		&snippet.S3Key,
 		&snippet.Checksum,
 		&snippet.IsArchived,
 		&snippet.ExpiresAt,
+		&snippet.PublishAt,
+		&snippet.Provenance,
+		&snippet.ExternalID,
+		&snippet.PublicToken,
 		&snippet.CreatedAt,
 		&snippet.UpdatedAt,
 		&snippet.DeletedAt,
@@ -102,18 +126,42 @@ func (r *SnippetRepository) GetByID(ctx context.Context, id string) (*models.Sni
 	return snippet, nil
 }
 
+// PublicID returns the ID of the public snippet whose share link uses key,
+// or "" if there is none. With byID, a snippet's own ID also works as a
+// key, for links shared before public tokens existed.
+func (r *SnippetRepository) PublicID(ctx context.Context, key string, byID bool) (string, error) {
+	query := `SELECT id FROM snippets WHERE public_token = ? AND is_public = 1 AND deleted_at IS NULL`
+	args := []interface{}{key}
+	if byID {
+		query = `SELECT id FROM snippets WHERE (public_token = ? OR id = ?) AND is_public = 1 AND deleted_at IS NULL
+			ORDER BY public_token = ? DESC LIMIT 1`
+		args = []interface{}{key, key, key}
+	}
+
+	var id string
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve public snippet: %w", err)
+	}
+	return id, nil
+}
+
 // Update updates an existing snippet
 func (r *SnippetRepository) Update(ctx context.Context, id string, input *models.SnippetInput) (*models.Snippet, error) {
+	owner, ownerArgs := snippetFilter(ctx, "user_id")
 	query := `
 		UPDATE snippets
-		SET title = ?, description = ?, content = ?, language = ?, is_public = ?, is_archived = ?, expires_at = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?
+		SET title = ?, description = ?, content = ?, language = ?, is_public = ?, is_archived = ?, expires_at = ?, publish_at = ?, checksum = NULL, content_hash = NULL, updated_at = CURRENT_TIMESTAMP,
+		    public_token = CASE WHEN ? AND NOT is_public THEN lower(hex(randomblob(12))) ELSE public_token END
+		WHERE id = ?` + owner + `
 		RETURNING id, title, description, content, language, is_favorite, is_public,
-		          view_count, s3_key, checksum, is_archived, expires_at, created_at, updated_at, deleted_at
+		          view_count, use_count, public_view_count, last_used_at, s3_key, checksum, is_archived, expires_at, publish_at, provenance, external_id, public_token, created_at, updated_at, deleted_at
 	`
 
-	snippet := &models.Snippet{}
-	err := r.db.QueryRowContext(ctx, query,
+	args := []interface{}{
 		input.Title,
 		input.Description,
 		input.Content,
@@ -121,8 +169,13 @@ func (r *SnippetRepository) Update(ctx context.Context, id string, input *models
 		input.IsPublic,
 		input.IsArchived,
 		input.ExpiresAt,
+		input.PublishAt,
+		input.IsPublic,
 		id,
-	).Scan(
+	}
+
+	snippet := &models.Snippet{}
+	err := r.db.QueryRowContext(ctx, query, append(args, ownerArgs...)...).Scan(
 		&snippet.ID,
 		&snippet.Title,
 		&snippet.Description,
@@ -131,10 +184,17 @@ func (r *SnippetRepository) Update(ctx context.Context, id string, input *models
 		&snippet.IsFavorite,
 		&snippet.IsPublic,
 		&snippet.ViewCount,
+		&snippet.UseCount,
+		&snippet.PublicViewCount,
+		&snippet.LastUsedAt,
 		&snippet.S3Key,
 		&snippet.Checksum,
 		&snippet.IsArchived,
 		&snippet.ExpiresAt,
+		&snippet.PublishAt,
+		&snippet.Provenance,
+		&snippet.ExternalID,
+		&snippet.PublicToken,
 		&snippet.CreatedAt,
 		&snippet.UpdatedAt,
 		&snippet.DeletedAt,
@@ -154,20 +214,20 @@ func (r *SnippetRepository) Update(ctx context.Context, id string, input *models
 // If permanent is true, it forces a hard delete regardless of settings
 func (r *SnippetRepository) Delete(ctx context.Context, id string, permanent bool) error {
 	// Check if trash is enabled
-	var trashEnabled bool
-	err := r.db.QueryRowContext(ctx, "SELECT trash_enabled FROM settings WHERE id = 1").Scan(&trashEnabled)
+	trashEnabled, err := settingBool(ctx, r.db, "trash_enabled")
 	if err != nil {
 		return fmt.Errorf("failed to check trash settings: %w", err)
 	}
 
+	owner, ownerArgs := snippetFilter(ctx, "user_id")
+
 	// Soft delete if enabled and not forced permanent
 	if trashEnabled && !permanent {
 		query := `
             UPDATE snippets 
             SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP 
-            WHERE id = ? AND deleted_at IS NULL
-        `
-		result, err := r.db.ExecContext(ctx, query, id)
+            WHERE id = ? AND deleted_at IS NULL` + owner
+		result, err := r.db.ExecContext(ctx, query, append([]interface{}{id}, ownerArgs...)...)
 		if err != nil {
 			return fmt.Errorf("failed to soft delete snippet: %w", err)
 		}
@@ -189,6 +249,12 @@ func (r *SnippetRepository) Delete(ctx context.Context, id string, permanent boo
 	}
 	defer func() { _ = tx.Rollback() }()
 
+	var exists int
+	err = tx.QueryRowContext(ctx, "SELECT 1 FROM snippets WHERE id = ?"+owner, append([]interface{}{id}, ownerArgs...)...).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
 	// Delete related data first (in case CASCADE doesn't work)
 	_, _ = tx.ExecContext(ctx, "DELETE FROM snippet_tags WHERE snippet_id = ?", id)
 	_, _ = tx.ExecContext(ctx, "DELETE FROM snippet_folders WHERE snippet_id = ?", id)
@@ -218,12 +284,12 @@ func (r *SnippetRepository) Delete(ctx context.Context, id string, permanent boo
 
 // Restore restores a soft-deleted snippet
 func (r *SnippetRepository) Restore(ctx context.Context, id string) error {
+	owner, ownerArgs := snippetFilter(ctx, "user_id")
 	query := `
         UPDATE snippets 
         SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP 
-        WHERE id = ? AND deleted_at IS NOT NULL
-    `
-	result, err := r.db.ExecContext(ctx, query, id)
+        WHERE id = ? AND deleted_at IS NOT NULL` + owner
+	result, err := r.db.ExecContext(ctx, query, append([]interface{}{id}, ownerArgs...)...)
 	if err != nil {
 		return fmt.Errorf("failed to restore snippet: %w", err)
 	}
@@ -299,45 +365,46 @@ func (r *SnippetRepository) CleanupDeleted(ctx context.Context, days int) (int64
 // Allowed sort columns - maps user input to safe SQL column identifiers
 // This prevents SQL injection by only allowing predefined column names
 var allowedSortColumns = map[string]string{
-	"id":          "id",
-	"title":       "title",
-	"description": "description",
-	"content":     "content",
-	"language":    "language",
-	"is_favorite": "is_favorite",
-	"is_public":   "is_public",
-	"view_count":  "view_count",
-	"created_at":  "created_at",
-	"updated_at":  "updated_at",
-	"deleted_at":  "deleted_at",
+	"id":                "id",
+	"title":             "title",
+	"description":       "description",
+	"content":           "content",
+	"language":          "language",
+	"is_favorite":       "is_favorite",
+	"is_public":         "is_public",
+	"view_count":        "view_count",
+	"public_view_count": "public_view_count",
+	"use_count":         "use_count",
+	"last_used":         "last_used_at",
+	"created_at":        "created_at",
+	"updated_at":        "updated_at",
+	"deleted_at":        "deleted_at",
 }
 
-// List retrieves snippets with filtering and pagination
-func (r *SnippetRepository) List(ctx context.Context, filter models.SnippetFilter) (*models.SnippetListResponse, error) {
-	if filter.Limit <= 0 {
-		filter.Limit = 20
-	}
-
-	// Map user-provided sort column to safe SQL column name
-	// This prevents SQL injection by using a constant value from allowedSortColumns
-	sortColumn, ok := allowedSortColumns[filter.SortBy]
-	if !ok {
-		sortColumn = "updated_at"
-	}
+// listOrder returns the ORDER BY expression for a snippet filter. Ties are
+// broken by rowid so the order is stable across queries.
+func listOrder(filter models.SnippetFilter) string {
+	sortColumn, sortOrder := listSort(filter)
 
-	// Validate sort order using constant values
-	sortOrder := "DESC"
-	if filter.SortOrder == "asc" {
-		sortOrder = "ASC"
-	}
-	if filter.Page <= 0 {
-		filter.Page = 1
+	// Titles sort naturally so "Step 2" comes before "Step 10"
+	orderBy := "s." + sortColumn
+	if sortColumn == "title" {
+		orderBy += " COLLATE " + naturalCollation
 	}
+	return orderBy + " " + sortOrder + ", s.rowid " + sortOrder
+}
 
-	// Build query
+// listConditions returns the WHERE clause and its arguments for a snippet
+// filter, limited to the data visible in ctx
+func listConditions(ctx context.Context, filter models.SnippetFilter) (string, []interface{}) {
 	var conditions []string
 	var args []interface{}
 
+	if owner, ownerArgs := snippetFilter(ctx, "s.user_id"); owner != "" {
+		conditions = append(conditions, strings.TrimPrefix(owner, " AND "))
+		args = append(args, ownerArgs...)
+	}
+
 	// Filter by deletion status
 	if filter.IsDeleted != nil && *filter.IsDeleted {
 		conditions = append(conditions, "s.deleted_at IS NOT NULL")
@@ -363,6 +430,11 @@ func (r *SnippetRepository) List(ctx context.Context, filter models.SnippetFilte
 		}
 	}
 
+	if filter.CreatedSince != nil {
+		conditions = append(conditions, "s.created_at >= datetime(?)")
+		args = append(args, filter.CreatedSince.UTC().Format(time.RFC3339))
+	}
+
 	if filter.Language != "" {
 		conditions = append(conditions, "s.language = ?")
 		args = append(args, filter.Language)
@@ -430,6 +502,19 @@ func (r *SnippetRepository) List(ctx context.Context, filter models.SnippetFilte
 	if len(conditions) > 0 {
 		whereClause = "WHERE " + strings.Join(conditions, " AND ")
 	}
+	return whereClause, args
+}
+
+// List retrieves snippets with filtering and pagination
+func (r *SnippetRepository) List(ctx context.Context, filter models.SnippetFilter) (*models.SnippetListResponse, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	orderBy := listOrder(filter)
+	whereClause, args := listConditions(ctx, filter)
 
 	// Count total
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM snippets s %s", whereClause)
@@ -438,22 +523,37 @@ func (r *SnippetRepository) List(ctx context.Context, filter models.SnippetFilte
 		return nil, fmt.Errorf("failed to count snippets: %w", err)
 	}
 
-	// Calculate offset
+	// A cursor continues after the row it marks, skipping the OFFSET scan;
+	// otherwise page selects the rows as before
+	pageWhere := whereClause
+	pageArgs := append([]interface{}(nil), args...)
 	offset := (filter.Page - 1) * filter.Limit
+	if filter.Cursor != "" {
+		cond, cursorArgs, err := keysetCondition(filter)
+		if err != nil {
+			return nil, err
+		}
+		pageWhere += " AND " + cond
+		pageArgs = append(pageArgs, cursorArgs...)
+		offset = 0
+	}
+	sortColumn, _ := listSort(filter)
 
-	// Build main query using safe column names from allowedSortColumns map
+	// Build main query using safe column names from allowedSortColumns map.
+	// One extra row is fetched to tell whether a next page exists.
 	query := fmt.Sprintf(`
 		SELECT s.id, s.title, s.description, s.content, s.language, s.is_favorite, s.is_public,
-		       s.view_count, s.s3_key, s.checksum, s.is_archived, s.expires_at, s.created_at, s.updated_at, s.deleted_at
+		       s.view_count, s.use_count, s.public_view_count, s.last_used_at, s.s3_key, s.checksum, s.is_archived, s.expires_at, s.publish_at, s.provenance, s.external_id, s.public_token, s.created_at, s.updated_at, s.deleted_at,
+		       CAST(s.%s AS TEXT), s.rowid
 		FROM snippets s
 		%s
-		ORDER BY s.%s %s
+		ORDER BY %s
 		LIMIT ? OFFSET ?
-	`, whereClause, sortColumn, sortOrder)
+	`, sortColumn, pageWhere, orderBy)
 
-	args = append(args, filter.Limit, offset)
+	pageArgs = append(pageArgs, filter.Limit+1, offset)
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := r.db.QueryContext(ctx, query, pageArgs...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list snippets: %w", err)
 	}
@@ -464,8 +564,13 @@ func (r *SnippetRepository) List(ctx context.Context, filter models.SnippetFilte
 	}()
 
 	var snippets []models.Snippet
+	var lastKey sql.NullString
+	var lastRowID int64
+	more := false
 	for rows.Next() {
 		var s models.Snippet
+		var key sql.NullString
+		var rowID int64
 		if err := rows.Scan(
 			&s.ID,
 			&s.Title,
@@ -475,23 +580,42 @@ func (r *SnippetRepository) List(ctx context.Context, filter models.SnippetFilte
 			&s.IsFavorite,
 			&s.IsPublic,
 			&s.ViewCount,
+			&s.UseCount,
+			&s.PublicViewCount,
+			&s.LastUsedAt,
 			&s.S3Key,
 			&s.Checksum,
 			&s.IsArchived,
 			&s.ExpiresAt,
+			&s.PublishAt,
+			&s.Provenance,
+			&s.ExternalID,
+			&s.PublicToken,
 			&s.CreatedAt,
 			&s.UpdatedAt,
 			&s.DeletedAt,
+			&key,
+			&rowID,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan snippet: %w", err)
 		}
+		if len(snippets) == filter.Limit {
+			more = true
+			break
+		}
 		snippets = append(snippets, s)
+		lastKey, lastRowID = key, rowID
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating snippets: %w", err)
 	}
 
+	var nextCursor string
+	if more && lastKey.Valid && keysetColumns[sortColumn] {
+		nextCursor = encodeListCursor(filter, lastKey.String, lastRowID)
+	}
+
 	// Calculate total pages
 	totalPages := total / filter.Limit
 	if total%filter.Limit > 0 {
@@ -505,22 +629,56 @@ func (r *SnippetRepository) List(ctx context.Context, filter models.SnippetFilte
 			Limit:      filter.Limit,
 			Total:      total,
 			TotalPages: totalPages,
+			NextCursor: nextCursor,
 		},
 	}, nil
 }
 
+// Neighbors returns the snippets before and after id in the list selected by
+// filter, or nil if id isn't part of that list
+func (r *SnippetRepository) Neighbors(ctx context.Context, id string, filter models.SnippetFilter) (*models.SnippetNeighbors, error) {
+	orderBy := listOrder(filter)
+	whereClause, args := listConditions(ctx, filter)
+
+	query := fmt.Sprintf(`
+		WITH ordered AS (
+			SELECT s.id,
+			       LAG(s.id) OVER (ORDER BY %[2]s) AS prev_id,
+			       LEAD(s.id) OVER (ORDER BY %[2]s) AS next_id,
+			       ROW_NUMBER() OVER (ORDER BY %[2]s) AS position,
+			       COUNT(*) OVER () AS total
+			FROM snippets s
+			%[1]s
+		)
+		SELECT prev_id, next_id, position, total FROM ordered WHERE id = ?
+	`, whereClause, orderBy)
+	args = append(args, id)
+
+	neighbors := &models.SnippetNeighbors{}
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(
+		&neighbors.PrevID, &neighbors.NextID, &neighbors.Position, &neighbors.Total)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snippet neighbors: %w", err)
+	}
+	return neighbors, nil
+}
+
 // ToggleFavorite toggles the favorite status of a snippet
 func (r *SnippetRepository) ToggleFavorite(ctx context.Context, id string) (*models.Snippet, error) {
+	owner, ownerArgs := snippetFilter(ctx, "user_id")
 	query := `
 		UPDATE snippets
 		SET is_favorite = NOT is_favorite
-		WHERE id = ?
+		WHERE id = ?` + owner + `
 		RETURNING id, title, description, content, language, is_favorite, is_public,
-		          view_count, s3_key, checksum, is_archived, created_at, updated_at, deleted_at
+		          view_count, use_count, public_view_count, last_used_at, s3_key, checksum, is_archived, created_at, updated_at, deleted_at
 	`
 
 	snippet := &models.Snippet{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, append([]interface{}{id}, ownerArgs...)...).Scan(
 		&snippet.ID,
 		&snippet.Title,
 		&snippet.Description,
@@ -529,6 +687,9 @@ func (r *SnippetRepository) ToggleFavorite(ctx context.Context, id string) (*mod
 		&snippet.IsFavorite,
 		&snippet.IsPublic,
 		&snippet.ViewCount,
+		&snippet.UseCount,
+		&snippet.PublicViewCount,
+		&snippet.LastUsedAt,
 		&snippet.S3Key,
 		&snippet.Checksum,
 		&snippet.IsArchived,
@@ -549,18 +710,20 @@ func (r *SnippetRepository) ToggleFavorite(ctx context.Context, id string) (*mod
 
 // ToggleArchive toggles the archive status of a snippet
 func (r *SnippetRepository) ToggleArchive(ctx context.Context, id string) (*models.Snippet, error) {
+	owner, ownerArgs := snippetFilter(ctx, "user_id")
 	query := `
 		UPDATE snippets
 		SET is_archived = NOT is_archived,
 		    is_public = CASE WHEN (NOT is_archived) = 1 THEN 0 ELSE is_public END,
+		    checksum = NULL,
 		    updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?
+		WHERE id = ?` + owner + `
 		RETURNING id, title, description, content, language, is_favorite, is_public,
-		          view_count, s3_key, checksum, is_archived, expires_at, created_at, updated_at, deleted_at
+		          view_count, use_count, public_view_count, last_used_at, s3_key, checksum, is_archived, expires_at, publish_at, provenance, external_id, public_token, created_at, updated_at, deleted_at
 	`
 
 	snippet := &models.Snippet{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, append([]interface{}{id}, ownerArgs...)...).Scan(
 		&snippet.ID,
 		&snippet.Title,
 		&snippet.Description,
@@ -569,10 +732,17 @@ func (r *SnippetRepository) ToggleArchive(ctx context.Context, id string) (*mode
 		&snippet.IsFavorite,
 		&snippet.IsPublic,
 		&snippet.ViewCount,
+		&snippet.UseCount,
+		&snippet.PublicViewCount,
+		&snippet.LastUsedAt,
 		&snippet.S3Key,
 		&snippet.Checksum,
 		&snippet.IsArchived,
 		&snippet.ExpiresAt,
+		&snippet.PublishAt,
+		&snippet.Provenance,
+		&snippet.ExternalID,
+		&snippet.PublicToken,
 		&snippet.CreatedAt,
 		&snippet.UpdatedAt,
 		&snippet.DeletedAt,
@@ -597,24 +767,192 @@ func (r *SnippetRepository) IncrementViewCount(ctx context.Context, id string) e
 	return nil
 }
 
-// Search performs full-text search on snippets
-func (r *SnippetRepository) Search(ctx context.Context, query string, limit int) ([]models.Snippet, error) {
+// RecordPublicView counts a visit to a public snippet's share page and, when
+// the visitor came from another site, tallies the referring domain. Visits to
+// snippets that are not public are ignored.
+func (r *SnippetRepository) RecordPublicView(ctx context.Context, id, referrerDomain string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE snippets SET public_view_count = public_view_count + 1
+		WHERE id = ? AND is_public = 1 AND deleted_at IS NULL
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to record public view: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil
+	}
+
+	if referrerDomain != "" {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO snippet_referrers (snippet_id, domain, count, last_seen_at)
+			VALUES (?, ?, 1, CURRENT_TIMESTAMP)
+			ON CONFLICT(snippet_id, domain) DO UPDATE SET
+				count = count + 1,
+				last_seen_at = CURRENT_TIMESTAMP
+		`, id, referrerDomain)
+		if err != nil {
+			return fmt.Errorf("failed to record referrer: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetReferrers returns the domains that sent visitors to a snippet's share
+// page, most frequent first
+func (r *SnippetRepository) GetReferrers(ctx context.Context, id string) ([]models.ReferrerStat, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT domain, count, last_seen_at
+		FROM snippet_referrers
+		WHERE snippet_id = ?
+		ORDER BY count DESC, domain ASC
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get referrers: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	referrers := []models.ReferrerStat{}
+	for rows.Next() {
+		var ref models.ReferrerStat
+		if err := rows.Scan(&ref.Domain, &ref.Count, &ref.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("failed to scan referrer: %w", err)
+		}
+		referrers = append(referrers, ref)
+	}
+
+	return referrers, rows.Err()
+}
+
+// RecordUse increments the use count of a snippet and stamps last_used_at.
+// Returns nil if the snippet does not exist or is in the trash.
+func (r *SnippetRepository) RecordUse(ctx context.Context, id string) (*models.Snippet, error) {
+	owner, ownerArgs := snippetFilter(ctx, "user_id")
+	query := `
+		UPDATE snippets
+		SET use_count = use_count + 1,
+		    last_used_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND deleted_at IS NULL` + owner + `
+		RETURNING id, title, description, content, language, is_favorite, is_public,
+		          view_count, use_count, public_view_count, last_used_at, s3_key, checksum, is_archived, expires_at, publish_at, provenance, external_id, public_token, created_at, updated_at, deleted_at
+	`
+
+	snippet := &models.Snippet{}
+	err := r.db.QueryRowContext(ctx, query, append([]interface{}{id}, ownerArgs...)...).Scan(
+		&snippet.ID,
+		&snippet.Title,
+		&snippet.Description,
+		&snippet.Content,
+		&snippet.Language,
+		&snippet.IsFavorite,
+		&snippet.IsPublic,
+		&snippet.ViewCount,
+		&snippet.UseCount,
+		&snippet.PublicViewCount,
+		&snippet.LastUsedAt,
+		&snippet.S3Key,
+		&snippet.Checksum,
+		&snippet.IsArchived,
+		&snippet.ExpiresAt,
+		&snippet.PublishAt,
+		&snippet.Provenance,
+		&snippet.ExternalID,
+		&snippet.PublicToken,
+		&snippet.CreatedAt,
+		&snippet.UpdatedAt,
+		&snippet.DeletedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record snippet use: %w", err)
+	}
+
+	return snippet, nil
+}
+
+// Search performs full-text search on snippets, best matches first. Title
+// matches weigh most, then the description, then the content. When the
+// search index was built with the code tokenizer and finds fewer than limit
+// snippets, snippets containing the query inside words are added after them.
+func (r *SnippetRepository) Search(ctx context.Context, query string, limit int) ([]models.SearchResult, error) {
 	if limit <= 0 {
 		limit = 10
 	}
 
-	sqlQuery := `
+	results, err := r.searchIndex(ctx, wordIndex, query, limit)
+	if err != nil || len(results) >= limit {
+		return results, err
+	}
+
+	var trigram int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'snippets_trigram'`).Scan(&trigram); err != nil {
+		return nil, fmt.Errorf("failed to check search index: %w", err)
+	}
+	if trigram == 0 {
+		return results, nil
+	}
+	more, err := r.searchIndex(ctx, trigramIndex, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool, len(results))
+	for _, res := range results {
+		found[res.Snippet.ID] = true
+	}
+	for _, res := range more {
+		if len(results) == limit {
+			break
+		}
+		if !found[res.Snippet.ID] {
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+// ftsIndex describes an FTS5 table over the title, description and content
+// of snippets
+type ftsIndex struct {
+	table      string
+	titleIndex int // Column number of title; description and content follow
+	bm25       string
+}
+
+var (
+	wordIndex    = ftsIndex{table: "snippets_fts", titleIndex: 1, bm25: "bm25(snippets_fts, 0, 10.0, 5.0, 1.0)"}
+	trigramIndex = ftsIndex{table: "snippets_trigram", titleIndex: 0, bm25: "bm25(snippets_trigram, 10.0, 5.0, 1.0)"}
+)
+
+// searchIndex runs query against one FTS index
+func (r *SnippetRepository) searchIndex(ctx context.Context, index ftsIndex, query string, limit int) ([]models.SearchResult, error) {
+	owner, ownerArgs := snippetFilter(ctx, "s.user_id")
+
+	sqlQuery := fmt.Sprintf(`
 		SELECT s.id, s.title, s.description, s.content, s.language, s.is_favorite, s.is_public,
-		       s.view_count, s.s3_key, s.checksum, s.is_archived, s.expires_at, s.created_at, s.updated_at, s.deleted_at
-		FROM snippets s
-		WHERE s.rowid IN (
-			SELECT rowid FROM snippets_fts WHERE snippets_fts MATCH ?
-		)
-        AND s.deleted_at IS NULL
+		       s.view_count, s.use_count, s.public_view_count, s.last_used_at, s.s3_key, s.checksum, s.is_archived, s.expires_at, s.publish_at, s.provenance, s.external_id, s.public_token, s.created_at, s.updated_at, s.deleted_at,
+		       %[2]s AS rank,
+		       highlight(%[1]s, %[3]d, ?, ?),
+		       snippet(%[1]s, %[4]d, ?, ?, '…', 16),
+		       snippet(%[1]s, %[5]d, ?, ?, '…', 24)
+		FROM %[1]s
+		JOIN snippets s ON s.rowid = %[1]s.rowid
+		WHERE %[1]s MATCH ?
+		  AND s.deleted_at IS NULL`+owner+`
+		ORDER BY rank
 		LIMIT ?
-	`
+	`, index.table, index.bm25, index.titleIndex, index.titleIndex+1, index.titleIndex+2)
 
-	rows, err := r.db.QueryContext(ctx, sqlQuery, query, limit)
+	args := append([]interface{}{markStart, markEnd, markStart, markEnd, markStart, markEnd, query}, ownerArgs...)
+	rows, err := r.db.QueryContext(ctx, sqlQuery, append(args, limit)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search snippets: %w", err)
 	}
@@ -624,9 +962,11 @@ func (r *SnippetRepository) Search(ctx context.Context, query string, limit int)
 		}
 	}()
 
-	var snippets []models.Snippet
+	results := []models.SearchResult{}
 	for rows.Next() {
-		var s models.Snippet
+		var res models.SearchResult
+		s := &res.Snippet
+		var title, description, content sql.NullString
 		if err := rows.Scan(
 			&s.ID,
 			&s.Title,
@@ -636,20 +976,296 @@ func (r *SnippetRepository) Search(ctx context.Context, query string, limit int)
 			&s.IsFavorite,
 			&s.IsPublic,
 			&s.ViewCount,
+			&s.UseCount,
+			&s.PublicViewCount,
+			&s.LastUsedAt,
 			&s.S3Key,
 			&s.Checksum,
 			&s.IsArchived,
 			&s.ExpiresAt,
+			&s.PublishAt,
+			&s.Provenance,
+			&s.ExternalID,
+			&s.PublicToken,
 			&s.CreatedAt,
 			&s.UpdatedAt,
 			&s.DeletedAt,
+			&res.Rank,
+			&title,
+			&description,
+			&content,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan snippet: %w", err)
 		}
-		snippets = append(snippets, s)
+		res.Highlights = models.SearchHighlights{
+			Title:       markedFragment(title.String),
+			Description: markedFragment(description.String),
+			Content:     markedFragment(content.String),
+		}
+		results = append(results, res)
 	}
 
-	return snippets, rows.Err()
+	return results, rows.Err()
+}
+
+// searchIndexSQL recreates the word index, as created by migration 25, and
+// drops the trigram index
+const searchIndexSQL = `
+DROP TRIGGER IF EXISTS snippets_ai;
+DROP TRIGGER IF EXISTS snippets_ad;
+DROP TRIGGER IF EXISTS snippets_au;
+DROP TRIGGER IF EXISTS snippets_trigram_ai;
+DROP TRIGGER IF EXISTS snippets_trigram_ad;
+DROP TRIGGER IF EXISTS snippets_trigram_au;
+DROP TABLE IF EXISTS snippets_fts;
+DROP TABLE IF EXISTS snippets_trigram;
+
+CREATE VIRTUAL TABLE snippets_fts USING fts5(
+    id UNINDEXED,
+    title,
+    description,
+    content,
+    content='snippets',
+    content_rowid='rowid'
+);
+
+CREATE TRIGGER snippets_ai AFTER INSERT ON snippets BEGIN
+    INSERT INTO snippets_fts(rowid, id, title, description, content)
+    VALUES (NEW.rowid, NEW.id, NEW.title, NEW.description, NEW.content);
+END;
+
+CREATE TRIGGER snippets_ad AFTER DELETE ON snippets BEGIN
+    INSERT INTO snippets_fts(snippets_fts, rowid, id, title, description, content)
+    VALUES('delete', OLD.rowid, OLD.id, OLD.title, OLD.description, OLD.content);
+END;
+
+CREATE TRIGGER snippets_au AFTER UPDATE ON snippets BEGIN
+    INSERT INTO snippets_fts(snippets_fts, rowid, id, title, description, content)
+    VALUES('delete', OLD.rowid, OLD.id, OLD.title, OLD.description, OLD.content);
+    INSERT INTO snippets_fts(rowid, id, title, description, content)
+    VALUES (NEW.rowid, NEW.id, NEW.title, NEW.description, NEW.content);
+END;
+
+INSERT INTO snippets_fts(snippets_fts) VALUES('rebuild');
+`
+
+// trigramIndexSQL adds the trigram index of the code tokenizer, which
+// matches any three or more characters inside words
+const trigramIndexSQL = `
+CREATE VIRTUAL TABLE snippets_trigram USING fts5(
+    title,
+    description,
+    content,
+    content='snippets',
+    content_rowid='rowid',
+    tokenize='trigram'
+);
+
+CREATE TRIGGER snippets_trigram_ai AFTER INSERT ON snippets BEGIN
+    INSERT INTO snippets_trigram(rowid, title, description, content)
+    VALUES (NEW.rowid, NEW.title, NEW.description, NEW.content);
+END;
+
+CREATE TRIGGER snippets_trigram_ad AFTER DELETE ON snippets BEGIN
+    INSERT INTO snippets_trigram(snippets_trigram, rowid, title, description, content)
+    VALUES('delete', OLD.rowid, OLD.title, OLD.description, OLD.content);
+END;
+
+CREATE TRIGGER snippets_trigram_au AFTER UPDATE OF title, description, content ON snippets BEGIN
+    INSERT INTO snippets_trigram(snippets_trigram, rowid, title, description, content)
+    VALUES('delete', OLD.rowid, OLD.title, OLD.description, OLD.content);
+    INSERT INTO snippets_trigram(rowid, title, description, content)
+    VALUES (NEW.rowid, NEW.title, NEW.description, NEW.content);
+END;
+
+INSERT INTO snippets_trigram(snippets_trigram) VALUES('rebuild');
+`
+
+// RebuildSearchIndex recreates the search index for tokenizer, one of the
+// models.SearchTokenizer values, and fills it from the snippets table
+func (r *SnippetRepository) RebuildSearchIndex(ctx context.Context, tokenizer string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, searchIndexSQL); err != nil {
+		return fmt.Errorf("failed to rebuild search index: %w", err)
+	}
+	if tokenizer == models.SearchTokenizerCode {
+		if _, err := tx.ExecContext(ctx, trigramIndexSQL); err != nil {
+			return fmt.Errorf("failed to build trigram index: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CheckSearchIndex counts the rows of the search index against the snippets
+// table. FTS5 keeps one docsize row per indexed snippet.
+func (r *SnippetRepository) CheckSearchIndex(ctx context.Context) (*models.SearchIndexStatus, error) {
+	status := &models.SearchIndexStatus{Tokenizer: models.SearchTokenizerDefault}
+
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM snippets`).Scan(&status.Snippets); err != nil {
+		return nil, fmt.Errorf("failed to count snippets: %w", err)
+	}
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM snippets_fts_docsize`).Scan(&status.Indexed); err != nil {
+		return nil, fmt.Errorf("failed to count search index rows: %w", err)
+	}
+
+	var trigram int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'snippets_trigram'`).Scan(&trigram); err != nil {
+		return nil, fmt.Errorf("failed to check search index: %w", err)
+	}
+	if trigram > 0 {
+		var indexed int
+		if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM snippets_trigram_docsize`).Scan(&indexed); err != nil {
+			return nil, fmt.Errorf("failed to count trigram index rows: %w", err)
+		}
+		status.Tokenizer = models.SearchTokenizerCode
+		status.TrigramIndexed = &indexed
+	}
+
+	status.Consistent = status.Indexed == status.Snippets &&
+		(status.TrigramIndexed == nil || *status.TrigramIndexed == status.Snippets)
+	return status, nil
+}
+
+// ExportSearchIndex returns the search index documents of the snippets
+// visible in ctx that aren't in the trash. With since, only the snippets
+// changed or restored from then on are returned, together with the IDs of
+// those trashed or deleted since.
+func (r *SnippetRepository) ExportSearchIndex(ctx context.Context, since *time.Time) (*models.SearchIndexExport, error) {
+	// Taken before reading so changes made meanwhile are picked up by the
+	// next export
+	export := &models.SearchIndexExport{
+		Version:     models.SearchIndexVersion,
+		Fields:      models.SearchIndexFields,
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		Since:       since,
+		Documents:   []models.SearchIndexDocument{},
+		Removed:     []string{},
+	}
+
+	owner, args := snippetFilter(ctx, "s.user_id")
+	where := " WHERE s.deleted_at IS NULL" + owner
+	if since != nil {
+		from := since.UTC().Format(time.DateTime)
+		where += " AND (s.updated_at >= ? OR s.id IN (SELECT snippet_id FROM activity WHERE created_at >= ?))"
+		args = append(args, from, from)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT s.id, s.title, s.language, s.is_favorite, s.is_archived, s.updated_at
+		FROM snippets s`+where+`
+		ORDER BY s.updated_at DESC, s.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export search index: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	positions := make(map[string]int)
+	for rows.Next() {
+		doc := models.SearchIndexDocument{Tags: []string{}, Folders: []string{}}
+		if err := rows.Scan(&doc.ID, &doc.Title, &doc.Language, &doc.IsFavorite, &doc.IsArchived, &doc.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan search index document: %w", err)
+		}
+		positions[doc.ID] = len(export.Documents)
+		export.Documents = append(export.Documents, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Tag and folder names are read in one query each rather than per snippet
+	labels := []struct {
+		query string
+		add   func(doc *models.SearchIndexDocument, name string)
+	}{
+		{`SELECT st.snippet_id, t.name FROM snippet_tags st
+			JOIN tags t ON t.id = st.tag_id AND t.deleted_at IS NULL
+			JOIN snippets s ON s.id = st.snippet_id` + where + ` ORDER BY t.name`,
+			func(doc *models.SearchIndexDocument, name string) { doc.Tags = append(doc.Tags, name) }},
+		{`SELECT sf.snippet_id, f.name FROM snippet_folders sf
+			JOIN folders f ON f.id = sf.folder_id AND f.deleted_at IS NULL
+			JOIN snippets s ON s.id = sf.snippet_id` + where + ` ORDER BY f.name`,
+			func(doc *models.SearchIndexDocument, name string) { doc.Folders = append(doc.Folders, name) }},
+	}
+	for _, label := range labels {
+		if err := r.exportLabels(ctx, label.query, args, func(id, name string) {
+			if i, ok := positions[id]; ok {
+				label.add(&export.Documents[i], name)
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if since == nil {
+		return export, nil
+	}
+
+	// Trashed snippets keep their row; deleted ones are only left in the
+	// activity log
+	from := since.UTC().Format(time.DateTime)
+	trashedOwner, trashedArgs := snippetFilter(ctx, "user_id")
+	activityOwner, activityArgs := ownerFilter(ctx, "user_id")
+	removedArgs := append([]interface{}{from}, trashedArgs...)
+	removedArgs = append(append(removedArgs, from), activityArgs...)
+	removed, err := r.db.QueryContext(ctx, `
+		SELECT id FROM snippets WHERE deleted_at >= ?`+trashedOwner+`
+		UNION
+		SELECT snippet_id FROM activity WHERE created_at >= ?`+activityOwner+`
+			AND snippet_id NOT IN (SELECT id FROM snippets WHERE deleted_at IS NULL)
+		ORDER BY 1`, removedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list removed snippets: %w", err)
+	}
+	defer func() { _ = removed.Close() }()
+	for removed.Next() {
+		var id string
+		if err := removed.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan removed snippet: %w", err)
+		}
+		export.Removed = append(export.Removed, id)
+	}
+	return export, removed.Err()
+}
+
+// exportLabels runs a query selecting snippet IDs and names, passing each
+// row to add
+func (r *SnippetRepository) exportLabels(ctx context.Context, query string, args []interface{}, add func(id, name string)) error {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to export search index labels: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return fmt.Errorf("failed to scan search index label: %w", err)
+		}
+		add(id, name)
+	}
+	return rows.Err()
+}
+
+// markStart and markEnd delimit matches in FTS fragments. They are control
+// characters so they survive HTML escaping and can't appear in the query.
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+// markedFragment escapes an FTS fragment for HTML and turns its match
+// delimiters into <mark> tags, or returns "" when nothing in it matched
+func markedFragment(fragment string) string {
+	if !strings.Contains(fragment, markStart) {
+		return ""
+	}
+	escaped := html.EscapeString(fragment)
+	return strings.NewReplacer(markStart, "<mark>", markEnd, "</mark>").Replace(escaped)
 }
 
 // AutoArchiveExpired archives snippets that have passed their expiration date
@@ -663,7 +1279,7 @@ func (r *SnippetRepository) AutoArchiveExpired(ctx context.Context) (int64, erro
 	// Find expired snippets (expires_at is in the past, not already archived, not deleted)
 	query := `
 		UPDATE snippets
-		SET is_archived = 1, is_public = 0, updated_at = CURRENT_TIMESTAMP
+		SET is_archived = 1, is_public = 0, checksum = NULL, updated_at = CURRENT_TIMESTAMP
 		WHERE expires_at IS NOT NULL
 		  AND expires_at < CURRENT_TIMESTAMP
 		  AND is_archived = 0
@@ -686,3 +1302,360 @@ func (r *SnippetRepository) AutoArchiveExpired(ctx context.Context) (int64, erro
 
 	return count, nil
 }
+
+// SetProvenance records where a snippet was forked from
+func (r *SnippetRepository) SetProvenance(ctx context.Context, id string, provenance *models.Provenance) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE snippets SET provenance = ? WHERE id = ?", provenance, id)
+	if err != nil {
+		return fmt.Errorf("failed to set snippet provenance: %w", err)
+	}
+	return nil
+}
+
+// GetByExternalID retrieves the snippet a caller tagged with externalID, or
+// nil if there is none
+func (r *SnippetRepository) GetByExternalID(ctx context.Context, externalID string) (*models.Snippet, error) {
+	owner, ownerArgs := snippetFilter(ctx, "user_id")
+	var id string
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM snippets WHERE external_id = ?"+owner,
+		append([]interface{}{externalID}, ownerArgs...)...).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snippet by external ID: %w", err)
+	}
+	return r.GetByID(ctx, id)
+}
+
+// SetExternalID tags a snippet with a caller-chosen key. The key is unique
+// per user or workspace, so ErrAlreadyExists is returned if another snippet has it.
+func (r *SnippetRepository) SetExternalID(ctx context.Context, id, externalID string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE snippets SET external_id = ? WHERE id = ?", externalID, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrAlreadyExists
+		}
+		return fmt.Errorf("failed to set snippet external ID: %w", err)
+	}
+	return nil
+}
+
+// UpdateChecksum stores the content checksum used by gist sync to detect local changes.
+// Writes that change checksummed fields reset it to NULL so a stale value is never read.
+func (r *SnippetRepository) UpdateChecksum(ctx context.Context, id, checksum string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE snippets SET checksum = ? WHERE id = ?", checksum, id)
+	if err != nil {
+		return fmt.Errorf("failed to update snippet checksum: %w", err)
+	}
+	return nil
+}
+
+// UpdateContentHash stores the hash of a snippet's code used to find
+// duplicates. Update resets it to NULL, like the checksum.
+func (r *SnippetRepository) UpdateContentHash(ctx context.Context, id, hash string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE snippets SET content_hash = ? WHERE id = ?", hash, id)
+	if err != nil {
+		return fmt.Errorf("failed to update snippet content hash: %w", err)
+	}
+	return nil
+}
+
+// ListMissingContentHashes returns the IDs of snippets whose content hash
+// hasn't been computed yet
+func (r *SnippetRepository) ListMissingContentHashes(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id FROM snippets WHERE content_hash IS NULL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snippets without content hash: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan snippet id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// FindDuplicates returns snippets outside the trash whose content hash equals
+// contentHash or whose title equals title, ignoring case. Empty arguments
+// are not matched. Content matches come first.
+func (r *SnippetRepository) FindDuplicates(ctx context.Context, title, contentHash string, limit int) ([]models.DuplicateMatch, error) {
+	if title == "" && contentHash == "" {
+		return nil, nil
+	}
+	owner, ownerArgs := snippetFilter(ctx, "user_id")
+	args := append([]interface{}{contentHash, contentHash, contentHash, title, title}, ownerArgs...)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, title, updated_at,
+		       CASE WHEN content_hash = ? THEN 'content' ELSE 'title' END AS matched_by
+		FROM snippets
+		WHERE deleted_at IS NULL
+		  AND ((? != '' AND content_hash = ?) OR (? != '' AND title = ? COLLATE NOCASE))`+owner+`
+		ORDER BY matched_by = 'content' DESC, updated_at DESC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate snippets: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	matches := make([]models.DuplicateMatch, 0)
+	for rows.Next() {
+		var m models.DuplicateMatch
+		if err := rows.Scan(&m.ID, &m.Title, &m.UpdatedAt, &m.MatchedBy); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate snippet: %w", err)
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// Unpublish makes a snippet private and cancels any scheduled publishing.
+// The checksum is cleared so sync picks up the visibility change.
+func (r *SnippetRepository) Unpublish(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE snippets
+		SET is_public = 0, publish_at = NULL, checksum = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to unpublish snippet: %w", err)
+	}
+	return nil
+}
+
+// PublishScheduled makes public every snippet whose publish_at has passed and
+// returns the snippets that were published. publish_at is cleared so a later
+// unpublish is not undone on the next run.
+func (r *SnippetRepository) PublishScheduled(ctx context.Context) ([]models.Snippet, error) {
+	query := `
+		UPDATE snippets
+		SET is_public = 1, publish_at = NULL, checksum = NULL, updated_at = CURRENT_TIMESTAMP,
+		    public_token = CASE WHEN is_public THEN public_token ELSE lower(hex(randomblob(12))) END
+		WHERE publish_at IS NOT NULL
+		  AND publish_at <= ?
+		  AND is_archived = 0
+		  AND deleted_at IS NULL
+		RETURNING id, title, public_token
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish scheduled snippets: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var published []models.Snippet
+	for rows.Next() {
+		var s models.Snippet
+		if err := rows.Scan(&s.ID, &s.Title, &s.PublicToken); err != nil {
+			return nil, fmt.Errorf("failed to scan published snippet: %w", err)
+		}
+		s.IsPublic = true
+		published = append(published, s)
+	}
+
+	return published, rows.Err()
+}
+
+// Bulk applies one action to many snippets in a single transaction, so either
+// every snippet changes or none does. A missing snippet, or for delete and
+// restore one that is already in or out of the trash, rolls the whole batch
+// back with sql.ErrNoRows.
+func (r *SnippetRepository) Bulk(ctx context.Context, input *models.BulkSnippetInput) (*models.BulkSnippetResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result := &models.BulkSnippetResult{Action: input.Action, IDs: []string{}}
+
+	if input.Action == models.BulkCreate {
+		for i := range input.Snippets {
+			snippet, err := bulkCreateSnippet(ctx, tx, &input.Snippets[i])
+			if err != nil {
+				return nil, err
+			}
+			result.IDs = append(result.IDs, snippet.ID)
+			result.Snippets = append(result.Snippets, *snippet)
+		}
+	} else {
+		trashEnabled, err := settingBool(ctx, tx, "trash_enabled")
+		if err != nil {
+			return nil, fmt.Errorf("failed to check trash settings: %w", err)
+		}
+		for _, id := range input.IDs {
+			if err := bulkApply(ctx, tx, id, input, trashEnabled); err != nil {
+				return nil, err
+			}
+		}
+		result.IDs = append(result.IDs, input.IDs...)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.Affected = len(result.IDs)
+	return result, nil
+}
+
+// bulkCreateSnippet inserts a snippet with its files, tags and folder
+func bulkCreateSnippet(ctx context.Context, tx *sql.Tx, input *models.SnippetInput) (*models.Snippet, error) {
+	snippet, err := createSnippet(ctx, tx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range input.Files {
+		file, err := insertSnippetFile(ctx, tx, snippet.ID, &input.Files[i], i)
+		if err != nil {
+			return nil, err
+		}
+		snippet.Files = append(snippet.Files, *file)
+	}
+
+	if len(input.Tags) > 0 {
+		if err := linkSnippetTags(ctx, tx, snippet.ID, input.Tags); err != nil {
+			return nil, err
+		}
+	}
+
+	if input.FolderID != nil {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO snippet_folders (snippet_id, folder_id) VALUES (?, ?)`,
+			snippet.ID, *input.FolderID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to set snippet folder: %w", err)
+		}
+	}
+
+	return snippet, nil
+}
+
+// bulkApply runs a bulk action other than create on one snippet
+func bulkApply(ctx context.Context, tx *sql.Tx, id string, input *models.BulkSnippetInput, trashEnabled bool) error {
+	var (
+		res sql.Result
+		err error
+	)
+
+	// Snippets of other users are treated as missing
+	if owner, ownerArgs := snippetFilter(ctx, "user_id"); owner != "" {
+		var exists int
+		if err := tx.QueryRowContext(ctx, "SELECT 1 FROM snippets WHERE id = ?"+owner, append([]interface{}{id}, ownerArgs...)...).Scan(&exists); err != nil {
+			return err
+		}
+	}
+
+	switch input.Action {
+	case models.BulkUpdate:
+		sets := []string{"updated_at = CURRENT_TIMESTAMP"}
+		var args []interface{}
+		if input.Set.IsPublic != nil {
+			// An explicit visibility replaces any publish schedule
+			sets = append(sets, "is_public = ?", "publish_at = NULL", "checksum = NULL")
+			args = append(args, *input.Set.IsPublic)
+		}
+		if input.Set.IsFavorite != nil {
+			sets = append(sets, "is_favorite = ?")
+			args = append(args, *input.Set.IsFavorite)
+		}
+		res, err = tx.ExecContext(ctx, "UPDATE snippets SET "+strings.Join(sets, ", ")+" WHERE id = ?", append(args, id)...)
+
+	case models.BulkDelete:
+		if trashEnabled && !input.Permanent {
+			res, err = tx.ExecContext(ctx, `
+				UPDATE snippets SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+				WHERE id = ? AND deleted_at IS NULL
+			`, id)
+			break
+		}
+		// Delete related data first (in case CASCADE doesn't work)
+		_, _ = tx.ExecContext(ctx, "DELETE FROM snippet_tags WHERE snippet_id = ?", id)
+		_, _ = tx.ExecContext(ctx, "DELETE FROM snippet_folders WHERE snippet_id = ?", id)
+		_, _ = tx.ExecContext(ctx, "DELETE FROM snippet_files WHERE snippet_id = ?", id)
+		res, err = tx.ExecContext(ctx, "DELETE FROM snippets WHERE id = ?", id)
+
+	case models.BulkRestore:
+		res, err = tx.ExecContext(ctx, `
+			UPDATE snippets SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND deleted_at IS NOT NULL
+		`, id)
+
+	case models.BulkArchive:
+		// Archived snippets are never public, matching ToggleArchive
+		res, err = tx.ExecContext(ctx, `
+			UPDATE snippets SET is_archived = 1, is_public = 0, checksum = NULL, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, id)
+
+	case models.BulkUnarchive:
+		res, err = tx.ExecContext(ctx, `
+			UPDATE snippets SET is_archived = 0, checksum = NULL, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, id)
+
+	case models.BulkTag, models.BulkMove:
+		// Touch the snippet so the change shows in its updated_at and a
+		// missing snippet is caught
+		res, err = tx.ExecContext(ctx, "UPDATE snippets SET updated_at = CURRENT_TIMESTAMP WHERE id = ?", id)
+		if err != nil {
+			break
+		}
+		if input.Action == models.BulkTag {
+			err = bulkTag(ctx, tx, id, input.AddTags, input.RemoveTags)
+		} else {
+			err = bulkMove(ctx, tx, id, input.FolderID)
+		}
+
+	default:
+		return fmt.Errorf("unknown bulk action: %s", input.Action)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to %s snippet %s: %w", input.Action, id, err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// bulkTag adds and removes tags on a snippet, leaving its other tags alone
+func bulkTag(ctx context.Context, tx *sql.Tx, id string, add, remove []string) error {
+	for _, name := range remove {
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM snippet_tags
+			WHERE snippet_id = ? AND tag_id IN (
+				SELECT t.id FROM tags t JOIN snippets s ON s.id = ?
+				WHERE t.name = ? AND t.workspace_id = s.workspace_id AND (t.workspace_id != 0 OR t.user_id = s.user_id)
+			)
+		`, id, id, name); err != nil {
+			return err
+		}
+	}
+	return linkSnippetTags(ctx, tx, id, add)
+}
+
+// bulkMove puts a snippet in a folder, or in none when folderID is nil
+func bulkMove(ctx context.Context, tx *sql.Tx, id string, folderID *int64) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM snippet_folders WHERE snippet_id = ?`, id); err != nil {
+		return err
+	}
+	if folderID == nil {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO snippet_folders (snippet_id, folder_id) VALUES (?, ?)`, id, *folderID)
+	return err
+}
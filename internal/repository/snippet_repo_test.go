@@ -1,7 +1,13 @@
 package repository
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/MohamedElashri/snipo/internal/models"
 	"github.com/MohamedElashri/snipo/internal/testutil"
@@ -292,6 +298,89 @@ func TestSnippetRepository_List_Pagination(t *testing.T) {
 	}
 }
 
+func TestSnippetRepository_List_Cursor(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewSnippetRepository(db)
+	ctx := testutil.TestContext()
+
+	// Snippets created in the same second tie on updated_at, so the walk
+	// relies on the rowid tie-break
+	for i := 0; i < 10; i++ {
+		if _, err := repo.Create(ctx, &models.SnippetInput{
+			Title:    fmt.Sprintf("Step %d", i+1),
+			Content:  "content",
+			Language: "plaintext",
+		}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	for _, sort := range []struct{ by, order string }{{"updated_at", "desc"}, {"title", "asc"}, {"view_count", "desc"}} {
+		filter := models.SnippetFilter{Page: 1, Limit: 10, SortBy: sort.by, SortOrder: sort.order}
+		all, err := repo.List(ctx, filter)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if all.Pagination.NextCursor != "" {
+			t.Errorf("%s: expected no next cursor on the last page", sort.by)
+		}
+
+		var walked []string
+		filter.Limit = 3
+		for pages := 0; ; pages++ {
+			if pages > 4 {
+				t.Fatalf("%s: cursor walk did not end", sort.by)
+			}
+			result, err := repo.List(ctx, filter)
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if result.Pagination.Total != 10 {
+				t.Errorf("%s: expected total 10 with a cursor, got %d", sort.by, result.Pagination.Total)
+			}
+			for _, s := range result.Data {
+				walked = append(walked, s.ID)
+			}
+			if result.Pagination.NextCursor == "" {
+				break
+			}
+			filter.Cursor = result.Pagination.NextCursor
+		}
+
+		if len(walked) != len(all.Data) {
+			t.Fatalf("%s: expected %d snippets from the cursor walk, got %d", sort.by, len(all.Data), len(walked))
+		}
+		for i, s := range all.Data {
+			if walked[i] != s.ID {
+				t.Errorf("%s: position %d: expected %s, got %s", sort.by, i, s.ID, walked[i])
+			}
+		}
+	}
+
+	first, err := repo.List(ctx, models.SnippetFilter{Page: 1, Limit: 3, SortBy: "updated_at", SortOrder: "desc"})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	invalid := []models.SnippetFilter{
+		{Limit: 3, Cursor: "not a cursor"},
+		{Limit: 3, SortBy: "title", SortOrder: "asc", Cursor: first.Pagination.NextCursor},
+		{Limit: 3, SortBy: "last_used", SortOrder: "desc", Cursor: first.Pagination.NextCursor},
+	}
+	for _, filter := range invalid {
+		if _, err := repo.List(ctx, filter); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q with sort %q: expected ErrInvalidCursor, got %v", filter.Cursor, filter.SortBy, err)
+		}
+	}
+
+	lastUsed, err := repo.List(ctx, models.SnippetFilter{Page: 1, Limit: 3, SortBy: "last_used", SortOrder: "desc"})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if lastUsed.Pagination.NextCursor != "" {
+		t.Error("expected no next cursor for a sort without cursor support")
+	}
+}
+
 func TestSnippetRepository_List_FilterByLanguage(t *testing.T) {
 	db := testutil.TestDB(t)
 	repo := NewSnippetRepository(db)
@@ -431,6 +520,190 @@ func TestSnippetRepository_Search(t *testing.T) {
 	}
 }
 
+func TestSnippetRepository_Search_RankAndHighlight(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewSnippetRepository(db)
+	ctx := testutil.TestContext()
+
+	inContent, err := repo.Create(ctx, &models.SnippetInput{
+		Title:   "Cleanup script",
+		Content: "#!/bin/sh\n# remove stopped containers\ndocker container prune -f <all>\n",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	inTitle, err := repo.Create(ctx, &models.SnippetInput{Title: "Docker cheatsheet", Content: "ps, images, logs"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	results, err := repo.Search(ctx, "docker", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+
+	// Title matches outrank content matches
+	if results[0].ID != inTitle.ID || results[1].ID != inContent.ID {
+		t.Errorf("expected the title match first, got %s then %s", results[0].Title, results[1].Title)
+	}
+	if results[0].Rank > results[1].Rank {
+		t.Errorf("expected ranks in ascending order, got %f then %f", results[0].Rank, results[1].Rank)
+	}
+
+	if got := results[0].Highlights.Title; got != "<mark>Docker</mark> cheatsheet" {
+		t.Errorf("unexpected title highlight %q", got)
+	}
+	if results[0].Highlights.Content != "" {
+		t.Errorf("expected no content highlight without a content match, got %q", results[0].Highlights.Content)
+	}
+
+	content := results[1].Highlights.Content
+	if !strings.Contains(content, "<mark>docker</mark> container prune") || !strings.Contains(content, "&lt;all&gt;") {
+		t.Errorf("expected an escaped content fragment with the match marked, got %q", content)
+	}
+	if results[1].Highlights.Title != "" {
+		t.Errorf("expected no title highlight, got %q", results[1].Highlights.Title)
+	}
+}
+
+func TestSnippetRepository_RebuildSearchIndex(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewSnippetRepository(db)
+	ctx := testutil.TestContext()
+
+	snippet, err := repo.Create(ctx, &models.SnippetInput{Title: "Parser", Content: "func parseHTTPRequest(r io.Reader) {}"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	search := func(query string) []models.SearchResult {
+		t.Helper()
+		results, err := repo.Search(ctx, query, 10)
+		if err != nil {
+			t.Fatalf("Search(%q) failed: %v", query, err)
+		}
+		return results
+	}
+
+	// Words inside an identifier only match with the code tokenizer
+	if results := search("Request"); len(results) != 0 {
+		t.Fatalf("expected no results before reindexing, got %d", len(results))
+	}
+
+	if err := repo.RebuildSearchIndex(ctx, models.SearchTokenizerCode); err != nil {
+		t.Fatalf("RebuildSearchIndex failed: %v", err)
+	}
+	if results := search("Request"); len(results) != 1 || results[0].ID != snippet.ID {
+		t.Fatalf("expected the snippet to match inside its identifier, got %v", results)
+	}
+	if results := search("parser"); len(results) != 1 {
+		t.Errorf("expected whole words to still match once, got %d results", len(results))
+	}
+
+	// The trigram index follows later edits
+	if _, err := repo.Update(ctx, snippet.ID, &models.SnippetInput{Title: "Parser", Content: "func readConfigFile() {}"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if results := search("Request"); len(results) != 0 {
+		t.Errorf("expected the old content to be gone, got %d results", len(results))
+	}
+	if results := search("Config"); len(results) != 1 {
+		t.Errorf("expected the new content to match, got %d results", len(results))
+	}
+
+	if err := repo.RebuildSearchIndex(ctx, models.SearchTokenizerDefault); err != nil {
+		t.Fatalf("RebuildSearchIndex failed: %v", err)
+	}
+	if results := search("Config"); len(results) != 0 {
+		t.Errorf("expected no substring matches after switching back, got %d", len(results))
+	}
+	if results := search("parser"); len(results) != 1 {
+		t.Errorf("expected the rebuilt word index to match, got %d results", len(results))
+	}
+}
+
+func TestSnippetRepository_ExportSearchIndex(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewSnippetRepository(db)
+	tagRepo := NewTagRepository(db)
+	folderRepo := NewFolderRepository(db)
+	activityRepo := NewActivityRepository(db)
+	ctx := testutil.TestContext()
+
+	ids := make(map[string]string)
+	for _, title := range []string{"Deploy", "Backup", "Cleanup"} {
+		s, err := repo.Create(ctx, &models.SnippetInput{Title: title, Content: "c", Language: "bash"})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		ids[title] = s.ID
+	}
+	if err := tagRepo.SetSnippetTags(ctx, ids["Deploy"], []string{"ops", "ci"}); err != nil {
+		t.Fatalf("SetSnippetTags failed: %v", err)
+	}
+	folder, err := folderRepo.Create(ctx, &models.FolderInput{Name: "Scripts"})
+	if err != nil {
+		t.Fatalf("Create folder failed: %v", err)
+	}
+	if err := folderRepo.SetSnippetFolder(ctx, ids["Deploy"], &folder.ID); err != nil {
+		t.Fatalf("SetSnippetFolder failed: %v", err)
+	}
+
+	full, err := repo.ExportSearchIndex(ctx, nil)
+	if err != nil {
+		t.Fatalf("ExportSearchIndex failed: %v", err)
+	}
+	if len(full.Documents) != 3 || len(full.Removed) != 0 {
+		t.Fatalf("expected 3 documents and no removals, got %d and %v", len(full.Documents), full.Removed)
+	}
+	for _, doc := range full.Documents {
+		if doc.ID != ids["Deploy"] {
+			continue
+		}
+		if !slices.Equal(doc.Tags, []string{"ci", "ops"}) || !slices.Equal(doc.Folders, []string{"Scripts"}) {
+			t.Errorf("expected tags [ci ops] in Scripts, got %v in %v", doc.Tags, doc.Folders)
+		}
+	}
+
+	// Everything so far happened an hour ago; then one snippet is edited,
+	// one trashed and one deleted
+	if _, err := db.Exec(`UPDATE snippets SET updated_at = datetime('now', '-1 hour')`); err != nil {
+		t.Fatalf("failed to age snippets: %v", err)
+	}
+	since := time.Now().Add(-time.Minute)
+	if _, err := repo.Update(ctx, ids["Backup"], &models.SnippetInput{Title: "Backup all", Content: "c", Language: "bash"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := repo.Delete(ctx, ids["Cleanup"], false); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := repo.Delete(ctx, ids["Deploy"], true); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := activityRepo.Record(ctx, ids["Deploy"], "Deploy", models.ActivityDeleted); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	changes, err := repo.ExportSearchIndex(ctx, &since)
+	if err != nil {
+		t.Fatalf("ExportSearchIndex failed: %v", err)
+	}
+	if len(changes.Documents) != 1 || changes.Documents[0].Title != "Backup all" {
+		t.Errorf("expected only the edited snippet, got %v", changes.Documents)
+	}
+	removed := []string{ids["Cleanup"], ids["Deploy"]}
+	slices.Sort(removed)
+	if !slices.Equal(changes.Removed, removed) {
+		t.Errorf("expected %v removed, got %v", removed, changes.Removed)
+	}
+	if changes.GeneratedAt.Before(since) {
+		t.Errorf("expected generated_at after since, got %v", changes.GeneratedAt)
+	}
+}
+
 func TestSnippetRepository_IncrementViewCount(t *testing.T) {
 	db := testutil.TestDB(t)
 	repo := NewSnippetRepository(db)
@@ -467,6 +740,178 @@ func TestSnippetRepository_IncrementViewCount(t *testing.T) {
 	}
 }
 
+func TestSnippetRepository_RecordUse(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewSnippetRepository(db)
+	ctx := testutil.TestContext()
+
+	older, err := repo.Create(ctx, &models.SnippetInput{Title: "Older", Content: "a", Language: "plaintext"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	newer, err := repo.Create(ctx, &models.SnippetInput{Title: "Newer", Content: "b", Language: "plaintext"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	used, err := repo.RecordUse(ctx, older.ID)
+	if err != nil {
+		t.Fatalf("RecordUse failed: %v", err)
+	}
+	if used.UseCount != 1 || used.LastUsedAt == nil {
+		t.Errorf("expected use_count 1 and last_used_at set, got %d and %v", used.UseCount, used.LastUsedAt)
+	}
+	if used.ViewCount != 0 {
+		t.Errorf("expected view_count to be untouched, got %d", used.ViewCount)
+	}
+
+	// Used snippets sort ahead of never-used ones, whatever their update time
+	result, err := repo.List(ctx, models.SnippetFilter{SortBy: "last_used", SortOrder: "desc"})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(result.Data) != 2 || result.Data[0].ID != older.ID || result.Data[1].ID != newer.ID {
+		t.Errorf("expected used snippet first, got %+v", result.Data)
+	}
+
+	// Trashed snippets cannot be used
+	if err := repo.Delete(ctx, older.ID, false); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if used, err := repo.RecordUse(ctx, older.ID); err != nil || used != nil {
+		t.Errorf("expected nil for trashed snippet, got %v (err %v)", used, err)
+	}
+}
+
+func TestSnippetRepository_RecordPublicView(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewSnippetRepository(db)
+	ctx := testutil.TestContext()
+
+	public, err := repo.Create(ctx, &models.SnippetInput{Title: "Shared", Content: "x", Language: "go", IsPublic: true})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	private, err := repo.Create(ctx, &models.SnippetInput{Title: "Private", Content: "x", Language: "go"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	for _, domain := range []string{"news.ycombinator.com", "", "reddit.com", "news.ycombinator.com"} {
+		if err := repo.RecordPublicView(ctx, public.ID, domain); err != nil {
+			t.Fatalf("RecordPublicView failed: %v", err)
+		}
+	}
+	if err := repo.RecordPublicView(ctx, private.ID, "reddit.com"); err != nil {
+		t.Fatalf("RecordPublicView on private snippet failed: %v", err)
+	}
+
+	got, err := repo.GetByID(ctx, public.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.PublicViewCount != 4 || got.ViewCount != 0 {
+		t.Errorf("expected 4 public views and no API views, got %d and %d", got.PublicViewCount, got.ViewCount)
+	}
+
+	referrers, err := repo.GetReferrers(ctx, public.ID)
+	if err != nil {
+		t.Fatalf("GetReferrers failed: %v", err)
+	}
+	if len(referrers) != 2 || referrers[0].Domain != "news.ycombinator.com" || referrers[0].Count != 2 {
+		t.Errorf("expected news.ycombinator.com first with 2 visits, got %+v", referrers)
+	}
+
+	// Private snippets are not counted
+	got, err = repo.GetByID(ctx, private.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.PublicViewCount != 0 {
+		t.Errorf("expected private snippet to have no public views, got %d", got.PublicViewCount)
+	}
+	if referrers, _ := repo.GetReferrers(ctx, private.ID); len(referrers) != 0 {
+		t.Errorf("expected no referrers for private snippet, got %+v", referrers)
+	}
+}
+
+func TestSnippetRepository_ListNaturalTitleSort(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewSnippetRepository(db)
+	ctx := testutil.TestContext()
+
+	for _, title := range []string{"step 10", "Step 2", "step 1", "Apple"} {
+		if _, err := repo.Create(ctx, &models.SnippetInput{Title: title, Content: "x", Language: "plaintext"}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	result, err := repo.List(ctx, models.SnippetFilter{SortBy: "title", SortOrder: "asc"})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	var got []string
+	for _, s := range result.Data {
+		got = append(got, s.Title)
+	}
+	want := []string{"Apple", "step 1", "Step 2", "step 10"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSnippetRepository_Neighbors(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewSnippetRepository(db)
+	ctx := testutil.TestContext()
+
+	ids := map[string]string{}
+	for _, title := range []string{"C", "A", "D", "B"} {
+		language := "go"
+		if title == "D" {
+			language = "python"
+		}
+		s, err := repo.Create(ctx, &models.SnippetInput{Title: title, Content: "x", Language: language})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		ids[title] = s.ID
+	}
+
+	filter := models.SnippetFilter{SortBy: "title", SortOrder: "asc", Language: "go"}
+	tests := []struct {
+		title          string
+		prev, next     string
+		position, want int
+	}{
+		{"A", "", "B", 1, 3},
+		{"B", "A", "C", 2, 3},
+		{"C", "B", "", 3, 3},
+	}
+	for _, tt := range tests {
+		n, err := repo.Neighbors(ctx, ids[tt.title], filter)
+		if err != nil || n == nil {
+			t.Fatalf("Neighbors(%s) failed: %v", tt.title, err)
+		}
+		if stringValue(n.PrevID) != ids[tt.prev] || stringValue(n.NextID) != ids[tt.next] || n.Position != tt.position || n.Total != tt.want {
+			t.Errorf("Neighbors(%s) = prev %q next %q %d/%d", tt.title, stringValue(n.PrevID), stringValue(n.NextID), n.Position, n.Total)
+		}
+	}
+
+	n, err := repo.Neighbors(ctx, ids["D"], filter)
+	if err != nil || n != nil {
+		t.Errorf("expected no neighbors for a snippet outside the filter, got %+v, %v", n, err)
+	}
+}
+
+func stringValue(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
 func TestSnippetRepository_ToggleArchive(t *testing.T) {
 	db := testutil.TestDB(t)
 	repo := NewSnippetRepository(db)
@@ -995,3 +1440,223 @@ func TestSnippetRepository_List_SQLInjectionPrevention(t *testing.T) {
 		})
 	}
 }
+
+func TestSnippetRepository_Bulk_Create(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewSnippetRepository(db)
+	ctx := testutil.TestContext()
+
+	folder, err := NewFolderRepository(db).Create(ctx, &models.FolderInput{Name: "Ops"})
+	if err != nil {
+		t.Fatalf("Create folder failed: %v", err)
+	}
+
+	result, err := repo.Bulk(ctx, &models.BulkSnippetInput{
+		Action: models.BulkCreate,
+		Snippets: []models.SnippetInput{
+			{Title: "One", Content: "1", Language: "plaintext", Tags: []string{"ops"}, FolderID: &folder.ID},
+			{Title: "Two", Language: "go", Files: []models.SnippetFileInput{{Filename: "main.go", Content: "package main", Language: "go"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Bulk failed: %v", err)
+	}
+	if result.Affected != 2 || len(result.Snippets) != 2 {
+		t.Fatalf("expected 2 created snippets, got %+v", result)
+	}
+	if len(result.Snippets[1].Files) != 1 || result.Snippets[1].Files[0].Filename != "main.go" {
+		t.Errorf("expected main.go to be created, got %+v", result.Snippets[1].Files)
+	}
+
+	tags, _ := NewTagRepository(db).GetSnippetTags(ctx, result.IDs[0])
+	if len(tags) != 1 || tags[0].Name != "ops" {
+		t.Errorf("expected tag ops, got %+v", tags)
+	}
+	folders, _ := NewFolderRepository(db).GetSnippetFolders(ctx, result.IDs[0])
+	if len(folders) != 1 || folders[0].ID != folder.ID {
+		t.Errorf("expected folder %d, got %+v", folder.ID, folders)
+	}
+}
+
+func TestSnippetRepository_Bulk_TagMoveArchive(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewSnippetRepository(db)
+	tagRepo := NewTagRepository(db)
+	folderRepo := NewFolderRepository(db)
+	ctx := testutil.TestContext()
+
+	var ids []string
+	for _, title := range []string{"A", "B"} {
+		s, err := repo.Create(ctx, &models.SnippetInput{Title: title, Content: "c", Language: "plaintext", IsPublic: true})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if err := tagRepo.SetSnippetTags(ctx, s.ID, []string{"keep", "draft"}); err != nil {
+			t.Fatalf("SetSnippetTags failed: %v", err)
+		}
+		ids = append(ids, s.ID)
+	}
+	folder, _ := folderRepo.Create(ctx, &models.FolderInput{Name: "Done"})
+
+	steps := []*models.BulkSnippetInput{
+		{Action: models.BulkTag, IDs: ids, AddTags: []string{"new"}, RemoveTags: []string{"draft"}},
+		{Action: models.BulkMove, IDs: ids, FolderID: &folder.ID},
+		{Action: models.BulkArchive, IDs: ids},
+	}
+	for _, step := range steps {
+		if _, err := repo.Bulk(ctx, step); err != nil {
+			t.Fatalf("Bulk %s failed: %v", step.Action, err)
+		}
+	}
+
+	for _, id := range ids {
+		tags, _ := tagRepo.GetSnippetTags(ctx, id)
+		var names []string
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		if strings.Join(names, ",") != "keep,new" {
+			t.Errorf("expected tags keep,new, got %v", names)
+		}
+		folders, _ := folderRepo.GetSnippetFolders(ctx, id)
+		if len(folders) != 1 || folders[0].ID != folder.ID {
+			t.Errorf("expected folder %d, got %+v", folder.ID, folders)
+		}
+		s, _ := repo.GetByID(ctx, id)
+		if !s.IsArchived || s.IsPublic {
+			t.Errorf("expected archived private snippet, got archived=%v public=%v", s.IsArchived, s.IsPublic)
+		}
+	}
+}
+
+func TestSnippetRepository_Bulk_MissingSnippetRollsBack(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewSnippetRepository(db)
+	ctx := testutil.TestContext()
+
+	s, _ := repo.Create(ctx, &models.SnippetInput{Title: "A", Content: "c", Language: "plaintext"})
+	favorite := true
+
+	_, err := repo.Bulk(ctx, &models.BulkSnippetInput{
+		Action: models.BulkUpdate,
+		IDs:    []string{s.ID, "missing"},
+		Set:    &models.BulkSnippetPatch{IsFavorite: &favorite},
+	})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	got, _ := repo.GetByID(ctx, s.ID)
+	if got.IsFavorite {
+		t.Error("expected the update to be rolled back")
+	}
+}
+
+func TestSnippetRepository_Bulk_DeleteRestore(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewSnippetRepository(db)
+	ctx := testutil.TestContext()
+
+	if err := NewSettingsRepository(db).Set(ctx, "trash_enabled", "true"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	a, _ := repo.Create(ctx, &models.SnippetInput{Title: "A", Content: "c", Language: "plaintext"})
+	b, _ := repo.Create(ctx, &models.SnippetInput{Title: "B", Content: "c", Language: "plaintext"})
+	ids := []string{a.ID, b.ID}
+
+	if _, err := repo.Bulk(ctx, &models.BulkSnippetInput{Action: models.BulkDelete, IDs: ids}); err != nil {
+		t.Fatalf("Bulk delete failed: %v", err)
+	}
+	if got, _ := repo.GetByID(ctx, a.ID); got == nil || got.DeletedAt == nil {
+		t.Fatal("expected snippet to be in the trash")
+	}
+
+	if _, err := repo.Bulk(ctx, &models.BulkSnippetInput{Action: models.BulkRestore, IDs: ids}); err != nil {
+		t.Fatalf("Bulk restore failed: %v", err)
+	}
+	if got, _ := repo.GetByID(ctx, a.ID); got.DeletedAt != nil {
+		t.Error("expected snippet to be restored")
+	}
+
+	if _, err := repo.Bulk(ctx, &models.BulkSnippetInput{Action: models.BulkDelete, IDs: ids, Permanent: true}); err != nil {
+		t.Fatalf("Bulk permanent delete failed: %v", err)
+	}
+	if got, _ := repo.GetByID(ctx, b.ID); got != nil {
+		t.Error("expected snippet to be deleted permanently")
+	}
+}
+
+func TestSnippetRepository_PublicToken(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewSnippetRepository(db)
+	ctx := testutil.TestContext()
+
+	input := &models.SnippetInput{Title: "Shared", Content: "x", Language: "go", IsPublic: true}
+	snippet, err := repo.Create(ctx, input)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if snippet.PublicToken == nil || len(*snippet.PublicToken) != 24 {
+		t.Fatalf("expected a 24 character public token, got %v", snippet.PublicToken)
+	}
+	token := *snippet.PublicToken
+
+	private, err := repo.Create(ctx, &models.SnippetInput{Title: "Private", Content: "x", Language: "go"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if private.PublicToken != nil {
+		t.Errorf("expected no token for a private snippet, got %q", *private.PublicToken)
+	}
+
+	resolve := func(key string, byID bool) string {
+		t.Helper()
+		id, err := repo.PublicID(ctx, key, byID)
+		if err != nil {
+			t.Fatalf("PublicID failed: %v", err)
+		}
+		return id
+	}
+	if resolve(token, false) != snippet.ID {
+		t.Error("expected the token to resolve to the snippet")
+	}
+	if resolve(snippet.ID, false) != "" || resolve(snippet.ID, true) != snippet.ID {
+		t.Error("expected the ID to resolve only when allowed")
+	}
+	if resolve(private.ID, true) != "" {
+		t.Error("expected a private snippet not to resolve")
+	}
+
+	// Edits keep the link, sharing again replaces it
+	updated, err := repo.Update(ctx, snippet.ID, input)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated.PublicToken == nil || *updated.PublicToken != token {
+		t.Errorf("expected an edit to keep the token %s, got %v", token, updated.PublicToken)
+	}
+	if err := repo.Unpublish(ctx, snippet.ID); err != nil {
+		t.Fatalf("Unpublish failed: %v", err)
+	}
+	if resolve(token, false) != "" {
+		t.Error("expected the token to stop resolving once private")
+	}
+	shared, err := repo.Update(ctx, snippet.ID, input)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if shared.PublicToken == nil || *shared.PublicToken == token {
+		t.Fatalf("expected a new token after sharing again, got %v", shared.PublicToken)
+	}
+	if resolve(token, false) != "" || resolve(*shared.PublicToken, false) != snippet.ID {
+		t.Error("expected only the new token to resolve")
+	}
+
+	// Statements that don't set a token themselves get one from the trigger
+	if _, err := db.Exec(`UPDATE snippets SET is_public = 1 WHERE id = ?`, private.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repo.GetByID(ctx, private.ID); got.PublicToken == nil {
+		t.Error("expected a token for a snippet made public directly")
+	}
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// StatsRepository computes aggregate snippet statistics
+type StatsRepository struct {
+	db *sql.DB
+}
+
+// NewStatsRepository creates a new stats repository
+func NewStatsRepository(db *sql.DB) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// SnippetStats counts the active snippets visible in ctx in total, per
+// language and per tag
+func (r *StatsRepository) SnippetStats(ctx context.Context) (*models.SnippetStats, error) {
+	stats := &models.SnippetStats{
+		Languages: make([]models.LanguageStat, 0),
+		Tags:      make([]models.TagStat, 0),
+	}
+	owner, ownerArgs := snippetFilter(ctx, "s.user_id")
+
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM snippets s WHERE s.deleted_at IS NULL AND s.is_archived = 0`+owner,
+		ownerArgs...,
+	).Scan(&stats.Total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count snippets: %w", err)
+	}
+
+	languageRows, err := r.db.QueryContext(ctx, `
+		SELECT COALESCE(NULLIF(s.language, ''), 'plaintext') AS lang, COUNT(*) AS n
+		FROM snippets s
+		WHERE s.deleted_at IS NULL AND s.is_archived = 0`+owner+`
+		GROUP BY lang
+		ORDER BY n DESC, lang ASC
+	`, ownerArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count languages: %w", err)
+	}
+	defer func() {
+		if err := languageRows.Close(); err != nil {
+			slog.Error("failed to close rows", "error", err)
+		}
+	}()
+
+	for languageRows.Next() {
+		var stat models.LanguageStat
+		if err := languageRows.Scan(&stat.Language, &stat.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan language count: %w", err)
+		}
+		stats.Languages = append(stats.Languages, stat)
+	}
+	if err := languageRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating language counts: %w", err)
+	}
+
+	tagOwner, tagOwnerArgs := ownerFilter(ctx, "t.user_id")
+	tagRows, err := r.db.QueryContext(ctx, `
+		SELECT t.id, t.name, t.color,
+		       (SELECT COUNT(*) FROM snippet_tags st
+		        INNER JOIN snippets s ON s.id = st.snippet_id
+		        WHERE st.tag_id = t.id AND s.deleted_at IS NULL AND s.is_archived = 0`+owner+`) AS n
+		FROM tags t
+		WHERE t.deleted_at IS NULL`+tagOwner+`
+		ORDER BY n DESC, t.name ASC
+	`, append(append([]interface{}{}, ownerArgs...), tagOwnerArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", err)
+	}
+	defer func() {
+		if err := tagRows.Close(); err != nil {
+			slog.Error("failed to close rows", "error", err)
+		}
+	}()
+
+	for tagRows.Next() {
+		var stat models.TagStat
+		if err := tagRows.Scan(&stat.ID, &stat.Name, &stat.Color, &stat.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag count: %w", err)
+		}
+		stats.Tags = append(stats.Tags, stat)
+	}
+	if err := tagRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag counts: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestStatsRepository_SnippetStats(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	snippets := NewSnippetRepository(db)
+	tags := NewTagRepository(db)
+	stats := NewStatsRepository(db)
+
+	create := func(title, language string, tagNames ...string) *models.Snippet {
+		t.Helper()
+		snippet, err := snippets.Create(ctx, &models.SnippetInput{Title: title, Content: "x", Language: language})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if err := tags.SetSnippetTags(ctx, snippet.ID, tagNames); err != nil {
+			t.Fatalf("SetSnippetTags failed: %v", err)
+		}
+		return snippet
+	}
+
+	create("a", "go", "backend")
+	create("b", "go", "backend", "cli")
+	create("c", "python", "cli")
+	archived := create("d", "rust", "backend")
+	trashed := create("e", "python", "cli")
+
+	if _, err := snippets.ToggleArchive(ctx, archived.ID); err != nil {
+		t.Fatalf("ToggleArchive failed: %v", err)
+	}
+	if err := snippets.Delete(ctx, trashed.ID, false); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	got, err := stats.SnippetStats(ctx)
+	if err != nil {
+		t.Fatalf("SnippetStats failed: %v", err)
+	}
+
+	if got.Total != 3 {
+		t.Errorf("expected 3 active snippets, got %d", got.Total)
+	}
+
+	wantLanguages := []models.LanguageStat{{Language: "go", Count: 2}, {Language: "python", Count: 1}}
+	if len(got.Languages) != len(wantLanguages) {
+		t.Fatalf("expected %v, got %v", wantLanguages, got.Languages)
+	}
+	for i, want := range wantLanguages {
+		if got.Languages[i] != want {
+			t.Errorf("language %d: expected %v, got %v", i, want, got.Languages[i])
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, tag := range got.Tags {
+		counts[tag.Name] = tag.Count
+	}
+	if counts["backend"] != 2 || counts["cli"] != 2 {
+		t.Errorf("unexpected tag counts: %v", counts)
+	}
+}
+
+func TestStatsRepository_SnippetStatsPerUser(t *testing.T) {
+	db := testutil.TestDB(t)
+	users := NewUserRepository(db)
+	snippets := NewSnippetRepository(db)
+	tags := NewTagRepository(db)
+	stats := NewStatsRepository(db)
+
+	alice, err := users.Create(testutil.TestContext(), "alice", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	bob, err := users.Create(testutil.TestContext(), "bob", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	aliceCtx := models.WithUserID(testutil.TestContext(), alice.ID)
+	bobCtx := models.WithUserID(testutil.TestContext(), bob.ID)
+
+	create := func(ctx context.Context, language string, tagNames ...string) {
+		t.Helper()
+		snippet, err := snippets.Create(ctx, &models.SnippetInput{Title: language, Content: "x", Language: language})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if err := tags.SetSnippetTags(ctx, snippet.ID, tagNames); err != nil {
+			t.Fatalf("SetSnippetTags failed: %v", err)
+		}
+	}
+	create(aliceCtx, "go", "backend")
+	create(bobCtx, "rust", "secret-project")
+	create(bobCtx, "rust", "secret-project")
+
+	got, err := stats.SnippetStats(aliceCtx)
+	if err != nil {
+		t.Fatalf("SnippetStats failed: %v", err)
+	}
+	if got.Total != 1 {
+		t.Errorf("expected alice's 1 snippet, got %d", got.Total)
+	}
+	if len(got.Languages) != 1 || got.Languages[0] != (models.LanguageStat{Language: "go", Count: 1}) {
+		t.Errorf("expected only alice's languages, got %v", got.Languages)
+	}
+	if len(got.Tags) != 1 || got.Tags[0].Name != "backend" || got.Tags[0].Count != 1 {
+		t.Errorf("expected only alice's tags, got %v", got.Tags)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/MohamedElashri/snipo/internal/models"
 )
@@ -19,16 +20,35 @@ func NewTagRepository(db *sql.DB) *TagRepository {
 	return &TagRepository{db: db}
 }
 
-// Create creates a new tag
+// Create creates a new tag, assigning it a color from the tag palette when
+// input has none. A tag of the same name in the trash is restored instead,
+// with its snippets.
 func (r *TagRepository) Create(ctx context.Context, input *models.TagInput) (*models.Tag, error) {
+	color, err := tagColor(ctx, r.db, input)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := &models.Tag{}
+	err = r.db.QueryRowContext(ctx, `
+		UPDATE tags SET color = ?, deleted_at = NULL
+		WHERE name = ? AND workspace_id = ? AND (workspace_id != 0 OR user_id = ?) AND deleted_at IS NOT NULL
+		RETURNING id, name, color, created_at
+	`, color, input.Name, workspaceID(ctx), ownerID(ctx)).Scan(&tag.ID, &tag.Name, &tag.Color, &tag.CreatedAt)
+	if err == nil {
+		return tag, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to restore tag: %w", err)
+	}
+
 	query := `
-		INSERT INTO tags (name, color)
-		VALUES (?, ?)
+		INSERT INTO tags (name, color, user_id, workspace_id)
+		VALUES (?, ?, ?, ?)
 		RETURNING id, name, color, created_at
 	`
 
-	tag := &models.Tag{}
-	err := r.db.QueryRowContext(ctx, query, input.Name, input.Color).Scan(
+	err = r.db.QueryRowContext(ctx, query, input.Name, color, ownerID(ctx), workspaceID(ctx)).Scan(
 		&tag.ID,
 		&tag.Name,
 		&tag.Color,
@@ -43,10 +63,11 @@ func (r *TagRepository) Create(ctx context.Context, input *models.TagInput) (*mo
 
 // GetByID retrieves a tag by ID
 func (r *TagRepository) GetByID(ctx context.Context, id int64) (*models.Tag, error) {
-	query := `SELECT id, name, color, created_at FROM tags WHERE id = ?`
+	owner, ownerArgs := ownerFilter(ctx, "user_id")
+	query := `SELECT id, name, color, created_at FROM tags WHERE id = ? AND deleted_at IS NULL` + owner
 
 	tag := &models.Tag{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, append([]interface{}{id}, ownerArgs...)...).Scan(
 		&tag.ID,
 		&tag.Name,
 		&tag.Color,
@@ -64,10 +85,13 @@ func (r *TagRepository) GetByID(ctx context.Context, id int64) (*models.Tag, err
 
 // GetByName retrieves a tag by name
 func (r *TagRepository) GetByName(ctx context.Context, name string) (*models.Tag, error) {
-	query := `SELECT id, name, color, created_at FROM tags WHERE name = ?`
+	query := `
+		SELECT id, name, color, created_at FROM tags
+		WHERE name = ? AND workspace_id = ? AND (workspace_id != 0 OR user_id = ?) AND deleted_at IS NULL
+	`
 
 	tag := &models.Tag{}
-	err := r.db.QueryRowContext(ctx, query, name).Scan(
+	err := r.db.QueryRowContext(ctx, query, name, workspaceID(ctx), ownerID(ctx)).Scan(
 		&tag.ID,
 		&tag.Name,
 		&tag.Color,
@@ -85,16 +109,18 @@ func (r *TagRepository) GetByName(ctx context.Context, name string) (*models.Tag
 
 // List retrieves all tags with snippet counts
 func (r *TagRepository) List(ctx context.Context) ([]models.Tag, error) {
+	owner, ownerArgs := ownerFilter(ctx, "t.user_id")
 	query := `
 		SELECT t.id, t.name, t.color, t.created_at,
 		       (SELECT COUNT(*) FROM snippet_tags st 
 		        INNER JOIN snippets s ON s.id = st.snippet_id 
 		        WHERE st.tag_id = t.id AND s.is_archived = 0) as snippet_count
 		FROM tags t
+		WHERE t.deleted_at IS NULL` + owner + `
 		ORDER BY snippet_count DESC, t.name ASC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, ownerArgs...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tags: %w", err)
 	}
@@ -120,17 +146,37 @@ func (r *TagRepository) List(ctx context.Context) ([]models.Tag, error) {
 	return tags, nil
 }
 
-// Update updates an existing tag
+// Update updates an existing tag; an empty color is assigned from the tag
+// palette as in Create. Renaming a tag to the name of one in the trash
+// fails with ErrAlreadyExists.
 func (r *TagRepository) Update(ctx context.Context, id int64, input *models.TagInput) (*models.Tag, error) {
+	color, err := tagColor(ctx, r.db, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var trashed int
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM tags
+		WHERE name = ? AND id != ? AND workspace_id = ? AND (workspace_id != 0 OR user_id = ?) AND deleted_at IS NOT NULL
+	`, input.Name, id, workspaceID(ctx), ownerID(ctx)).Scan(&trashed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check tag name: %w", err)
+	}
+	if trashed > 0 {
+		return nil, ErrAlreadyExists
+	}
+
+	owner, ownerArgs := ownerFilter(ctx, "user_id")
 	query := `
 		UPDATE tags
 		SET name = ?, color = ?
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL` + owner + `
 		RETURNING id, name, color, created_at
 	`
 
 	tag := &models.Tag{}
-	err := r.db.QueryRowContext(ctx, query, input.Name, input.Color, id).Scan(
+	err = r.db.QueryRowContext(ctx, query, append([]interface{}{input.Name, color, id}, ownerArgs...)...).Scan(
 		&tag.ID,
 		&tag.Name,
 		&tag.Color,
@@ -146,9 +192,20 @@ func (r *TagRepository) Update(ctx context.Context, id int64, input *models.TagI
 	return tag, nil
 }
 
-// Delete deletes a tag
+// Delete deletes a tag, moving it to the trash with its snippet
+// associations when the trash is enabled
 func (r *TagRepository) Delete(ctx context.Context, id int64) error {
-	result, err := r.db.ExecContext(ctx, `DELETE FROM tags WHERE id = ?`, id)
+	trashEnabled, err := settingBool(ctx, r.db, "trash_enabled")
+	if err != nil {
+		return fmt.Errorf("failed to check trash settings: %w", err)
+	}
+
+	owner, ownerArgs := ownerFilter(ctx, "user_id")
+	query := `DELETE FROM tags WHERE id = ? AND deleted_at IS NULL` + owner
+	if trashEnabled {
+		query = `UPDATE tags SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL` + owner
+	}
+	result, err := r.db.ExecContext(ctx, query, append([]interface{}{id}, ownerArgs...)...)
 	if err != nil {
 		return fmt.Errorf("failed to delete tag: %w", err)
 	}
@@ -165,13 +222,123 @@ func (r *TagRepository) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
+// ListDeleted retrieves the tags in the trash, most recently deleted first
+func (r *TagRepository) ListDeleted(ctx context.Context) ([]models.Tag, error) {
+	owner, ownerArgs := ownerFilter(ctx, "t.user_id")
+	query := `
+		SELECT t.id, t.name, t.color, t.created_at, t.deleted_at,
+		       (SELECT COUNT(*) FROM snippet_tags st WHERE st.tag_id = t.id) as snippet_count
+		FROM tags t
+		WHERE t.deleted_at IS NOT NULL` + owner + `
+		ORDER BY t.deleted_at DESC, t.name ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, ownerArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted tags: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.Color, &tag.CreatedAt, &tag.DeletedAt, &tag.SnippetCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// Restore takes a tag out of the trash, back on the snippets it was on
+func (r *TagRepository) Restore(ctx context.Context, id int64) error {
+	owner, ownerArgs := ownerFilter(ctx, "user_id")
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE tags SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`+owner,
+		append([]interface{}{id}, ownerArgs...)...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to restore tag: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Purge permanently deletes a tag in the trash
+func (r *TagRepository) Purge(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	owner, ownerArgs := ownerFilter(ctx, "user_id")
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT 1 FROM tags WHERE id = ? AND deleted_at IS NOT NULL`+owner, append([]interface{}{id}, ownerArgs...)...).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get tag: %w", err)
+	}
+
+	// Delete associations first in case CASCADE doesn't work
+	if _, err := tx.ExecContext(ctx, `DELETE FROM snippet_tags WHERE tag_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to untag snippets: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to purge tag: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// CleanupDeleted permanently deletes tags that have been in the trash for
+// more than days days
+func (r *TagRepository) CleanupDeleted(ctx context.Context, days int) (int64, error) {
+	cutoff := time.Now().UTC().AddDate(0, 0, -days)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM snippet_tags WHERE tag_id IN (SELECT id FROM tags WHERE deleted_at < ?)`, cutoff); err != nil {
+		return 0, fmt.Errorf("failed to untag snippets: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE deleted_at < ?`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge tags: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return count, nil
+}
+
 // GetSnippetTags retrieves all tags for a snippet
 func (r *TagRepository) GetSnippetTags(ctx context.Context, snippetID string) ([]models.Tag, error) {
 	query := `
 		SELECT t.id, t.name, t.color, t.created_at
 		FROM tags t
 		JOIN snippet_tags st ON t.id = st.tag_id
-		WHERE st.snippet_id = ?
+		WHERE st.snippet_id = ? AND t.deleted_at IS NULL
 		ORDER BY t.name ASC
 	`
 
@@ -212,15 +379,47 @@ func (r *TagRepository) SetSnippetTags(ctx context.Context, snippetID string, ta
 	}
 
 	// Add new tags
+	if err := linkSnippetTags(ctx, tx, snippetID, tagNames); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// linkSnippetTags adds tags to a snippet, creating the ones its owner or
+// workspace doesn't have yet and restoring the ones in the trash
+func linkSnippetTags(ctx context.Context, q settingQuerier, snippetID string, tagNames []string) error {
+	var userID, workspace int64
+	if err := q.QueryRowContext(ctx, `SELECT user_id, workspace_id FROM snippets WHERE id = ?`, snippetID).Scan(&userID, &workspace); err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get snippet owner: %w", err)
+	}
+
 	for _, name := range tagNames {
 		// Get or create tag
 		var tagID int64
-		err := tx.QueryRowContext(ctx, `SELECT id FROM tags WHERE name = ?`, name).Scan(&tagID)
+		var trashed bool
+		err := q.QueryRowContext(ctx,
+			`SELECT id, deleted_at IS NOT NULL FROM tags WHERE name = ? AND workspace_id = ? AND (workspace_id != 0 OR user_id = ?)`,
+			name, workspace, userID,
+		).Scan(&tagID, &trashed)
+		if err == nil && trashed {
+			if _, err := q.ExecContext(ctx, `UPDATE tags SET deleted_at = NULL WHERE id = ?`, tagID); err != nil {
+				return fmt.Errorf("failed to restore tag %s: %w", name, err)
+			}
+		}
 		if err == sql.ErrNoRows {
-			// Create new tag with default color
-			err = tx.QueryRowContext(ctx,
-				`INSERT INTO tags (name, color) VALUES (?, '#6366f1') RETURNING id`,
-				name,
+			// Create new tag with a palette color
+			color, err := tagColor(ctx, q, &models.TagInput{Name: name})
+			if err != nil {
+				return err
+			}
+			err = q.QueryRowContext(ctx,
+				`INSERT INTO tags (name, color, user_id, workspace_id) VALUES (?, ?, ?, ?) RETURNING id`,
+				name, color, userID, workspace,
 			).Scan(&tagID)
 			if err != nil {
 				return fmt.Errorf("failed to create tag %s: %w", name, err)
@@ -230,7 +429,7 @@ func (r *TagRepository) SetSnippetTags(ctx context.Context, snippetID string, ta
 		}
 
 		// Link tag to snippet
-		_, err = tx.ExecContext(ctx,
+		_, err = q.ExecContext(ctx,
 			`INSERT OR IGNORE INTO snippet_tags (snippet_id, tag_id) VALUES (?, ?)`,
 			snippetID, tagID,
 		)
@@ -238,11 +437,6 @@ func (r *TagRepository) SetSnippetTags(ctx context.Context, snippetID string, ta
 			return fmt.Errorf("failed to link tag %s to snippet: %w", name, err)
 		}
 	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	return nil
 }
 
@@ -260,3 +454,15 @@ func (r *TagRepository) GetTagSnippetCount(ctx context.Context, tagID int64) (in
 	}
 	return count, nil
 }
+
+// tagColor returns input's color, or the tag_palette color for its name
+func tagColor(ctx context.Context, q settingQuerier, input *models.TagInput) (string, error) {
+	if input.Color != "" {
+		return input.Color, nil
+	}
+	palette, err := getSetting(ctx, q, "tag_palette")
+	if err != nil {
+		return "", err
+	}
+	return models.TagColor(input.Name, models.ParseTagPalette(palette)), nil
+}
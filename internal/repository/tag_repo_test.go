@@ -33,6 +33,40 @@ func TestTagRepository_Create(t *testing.T) {
 	}
 }
 
+func TestTagRepository_Create_PaletteColor(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewTagRepository(db)
+	snippetRepo := NewSnippetRepository(db)
+	ctx := testutil.TestContext()
+
+	tag, err := repo.Create(ctx, &models.TagInput{Name: "Golang"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if want := models.TagColor("golang", models.DefaultTagPalette); tag.Color != want {
+		t.Errorf("expected palette color %q, got %q", want, tag.Color)
+	}
+
+	// Tags created implicitly follow the configured palette
+	if err := NewSettingsRepository(db).Set(ctx, "tag_palette", "#111111,#222222"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	snippet, err := snippetRepo.Create(ctx, &models.SnippetInput{Title: "Test", Content: "x", Language: "plaintext"})
+	if err != nil {
+		t.Fatalf("Create snippet failed: %v", err)
+	}
+	if err := repo.SetSnippetTags(ctx, snippet.ID, []string{"rust"}); err != nil {
+		t.Fatalf("SetSnippetTags failed: %v", err)
+	}
+	rust, err := repo.GetByName(ctx, "rust")
+	if err != nil {
+		t.Fatalf("GetByName failed: %v", err)
+	}
+	if want := models.TagColor("rust", []string{"#111111", "#222222"}); rust.Color != want {
+		t.Errorf("expected palette color %q, got %q", want, rust.Color)
+	}
+}
+
 func TestTagRepository_Create_Duplicate(t *testing.T) {
 	db := testutil.TestDB(t)
 	repo := NewTagRepository(db)
@@ -364,6 +398,105 @@ func TestTagRepository_Delete_NotFound(t *testing.T) {
 	}
 }
 
+func TestTagRepository_Trash(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewTagRepository(db)
+	ctx := testutil.TestContext()
+
+	snippet, err := NewSnippetRepository(db).Create(ctx, &models.SnippetInput{Title: "Tagged", Content: "x", Language: "go"})
+	if err != nil {
+		t.Fatalf("Create snippet failed: %v", err)
+	}
+	if err := repo.SetSnippetTags(ctx, snippet.ID, []string{"keep", "trashed"}); err != nil {
+		t.Fatalf("SetSnippetTags failed: %v", err)
+	}
+	tag, err := repo.GetByName(ctx, "trashed")
+	if err != nil {
+		t.Fatalf("GetByName failed: %v", err)
+	}
+
+	if err := repo.Delete(ctx, tag.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if tags, _ := repo.GetSnippetTags(ctx, snippet.ID); len(tags) != 1 || tags[0].Name != "keep" {
+		t.Errorf("expected only the live tag on the snippet, got %v", tags)
+	}
+	deleted, err := repo.ListDeleted(ctx)
+	if err != nil {
+		t.Fatalf("ListDeleted failed: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0].ID != tag.ID || deleted[0].DeletedAt == nil || deleted[0].SnippetCount != 1 {
+		t.Fatalf("expected the trashed tag with its snippet, got %+v", deleted)
+	}
+
+	if err := repo.Restore(ctx, tag.ID); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if tags, _ := repo.GetSnippetTags(ctx, snippet.ID); len(tags) != 2 {
+		t.Errorf("expected the association back after restore, got %v", tags)
+	}
+	if err := repo.Restore(ctx, tag.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound restoring a live tag, got %v", err)
+	}
+
+	// Creating a tag with a trashed tag's name restores it
+	if err := repo.Delete(ctx, tag.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	created, err := repo.Create(ctx, &models.TagInput{Name: "trashed", Color: "#112233"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.ID != tag.ID || created.Color != "#112233" {
+		t.Errorf("expected tag %d to be restored with the new color, got %+v", tag.ID, created)
+	}
+
+	// Purge only deletes tags in the trash
+	if err := repo.Purge(ctx, tag.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound purging a live tag, got %v", err)
+	}
+	if err := repo.Delete(ctx, tag.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := repo.Purge(ctx, tag.ID); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if deleted, _ := repo.ListDeleted(ctx); len(deleted) != 0 {
+		t.Errorf("expected an empty trash after purge, got %v", deleted)
+	}
+	if count, _ := repo.GetTagSnippetCount(ctx, tag.ID); count != 0 {
+		t.Errorf("expected no associations after purge, got %d", count)
+	}
+}
+
+func TestTagRepository_CleanupDeleted(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := NewTagRepository(db)
+	ctx := testutil.TestContext()
+
+	old, _ := repo.Create(ctx, &models.TagInput{Name: "old"})
+	recent, _ := repo.Create(ctx, &models.TagInput{Name: "recent"})
+	for _, tag := range []*models.Tag{old, recent} {
+		if err := repo.Delete(ctx, tag.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, `UPDATE tags SET deleted_at = datetime('now', '-40 days') WHERE id = ?`, old.ID); err != nil {
+		t.Fatalf("failed to age tag: %v", err)
+	}
+
+	count, err := repo.CleanupDeleted(ctx, 30)
+	if err != nil {
+		t.Fatalf("CleanupDeleted failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 tag purged, got %d", count)
+	}
+	if deleted, _ := repo.ListDeleted(ctx); len(deleted) != 1 || deleted[0].ID != recent.ID {
+		t.Errorf("expected only the recent tag left in the trash, got %v", deleted)
+	}
+}
+
 func TestTagRepository_SetSnippetTags(t *testing.T) {
 	db := testutil.TestDB(t)
 	tagRepo := NewTagRepository(db)
@@ -9,11 +9,18 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"sort"
 	"time"
 
 	"github.com/MohamedElashri/snipo/internal/models"
 )
 
+// TokenUsageDays is how many days of per-day token usage are kept
+const TokenUsageDays = 30
+
+// tokenColumns are the api_tokens columns scanToken reads
+const tokenColumns = `id, name, permissions, scopes, last_used_at, last_used_ip, request_count, expires_at, created_at, user_id, workspace_id`
+
 // TokenRepository handles API token database operations
 type TokenRepository struct {
 	db *sql.DB
@@ -24,6 +31,28 @@ func NewTokenRepository(db *sql.DB) *TokenRepository {
 	return &TokenRepository{db: db}
 }
 
+// scanToken reads a row selected with tokenColumns
+func scanToken(row rowScanner) (*models.APIToken, error) {
+	token := &models.APIToken{}
+	err := row.Scan(
+		&token.ID,
+		&token.Name,
+		&token.Permissions,
+		&token.Scopes,
+		&token.LastUsedAt,
+		&token.LastUsedIP,
+		&token.RequestCount,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&token.UserID,
+		&token.WorkspaceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
 // generateToken generates a secure random token
 func generateToken() (string, error) {
 	bytes := make([]byte, 32)
@@ -61,28 +90,15 @@ func (r *TokenRepository) Create(ctx context.Context, input *models.APITokenInpu
 		return nil, fmt.Errorf("invalid permissions: must be 'read', 'write', or 'admin'")
 	}
 
-	// Calculate expiration date from expires_in_days
-	var expiresAt *time.Time
-	if input.ExpiresInDays != nil && *input.ExpiresInDays > 0 {
-		expiration := time.Now().AddDate(0, 0, *input.ExpiresInDays)
-		expiresAt = &expiration
-	}
+	expiresAt := tokenExpiry(input.ExpiresInDays, input.ExpiresAt)
 
 	query := `
-		INSERT INTO api_tokens (name, token_hash, permissions, expires_at)
-		VALUES (?, ?, ?, ?)
-		RETURNING id, name, permissions, last_used_at, expires_at, created_at
+		INSERT INTO api_tokens (name, token_hash, permissions, scopes, expires_at, user_id, workspace_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING ` + tokenColumns + `
 	`
 
-	apiToken := &models.APIToken{}
-	err = r.db.QueryRowContext(ctx, query, input.Name, tokenHash, input.Permissions, expiresAt).Scan(
-		&apiToken.ID,
-		&apiToken.Name,
-		&apiToken.Permissions,
-		&apiToken.LastUsedAt,
-		&apiToken.ExpiresAt,
-		&apiToken.CreatedAt,
-	)
+	apiToken, err := scanToken(r.db.QueryRowContext(ctx, query, input.Name, tokenHash, input.Permissions, models.TokenScopes(input.Scopes), expiresAt, ownerID(ctx), workspaceID(ctx)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create token: %w", err)
 	}
@@ -93,19 +109,73 @@ func (r *TokenRepository) Create(ctx context.Context, input *models.APITokenInpu
 	return apiToken, nil
 }
 
+// tokenExpiry resolves an expiry given either in days from now or as a time
+func tokenExpiry(days *int, at *time.Time) *time.Time {
+	if at != nil {
+		expiration := at.UTC()
+		return &expiration
+	}
+	if days != nil && *days > 0 {
+		expiration := time.Now().UTC().AddDate(0, 0, *days)
+		return &expiration
+	}
+	return nil
+}
+
+// Rotate replaces a token's secret in place, keeping its name, permissions
+// and scopes, so the old value stops working the moment the new one is
+// issued. The new token expires at expiresAt, or never if it is nil.
+func (r *TokenRepository) Rotate(ctx context.Context, id int64, expiresAt *time.Time) (*models.APIToken, error) {
+	token, err := generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	owner, ownerArgs := personalFilter(ctx, "user_id")
+	query := `
+		UPDATE api_tokens
+		SET token_hash = ?, expires_at = ?, last_used_at = NULL, last_used_ip = NULL, request_count = 0, created_at = CURRENT_TIMESTAMP
+		WHERE id = ?` + owner + `
+		RETURNING ` + tokenColumns + `
+	`
+
+	args := append([]interface{}{hashToken(token), expiresAt, id}, ownerArgs...)
+	apiToken, err := scanToken(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to rotate token: %w", err)
+	}
+
+	// Usage of the old secret doesn't count towards the new one
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM api_token_usage WHERE token_id = ?`, apiToken.ID); err != nil {
+		return nil, fmt.Errorf("failed to reset token usage: %w", err)
+	}
+
+	apiToken.Token = token
+	return apiToken, nil
+}
+
+// DeleteExpired deletes tokens that expired more than grace ago. Recently
+// expired tokens are kept so they can still be seen and rotated.
+func (r *TokenRepository) DeleteExpired(ctx context.Context, grace time.Duration) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM api_tokens WHERE expires_at IS NOT NULL AND expires_at < ?`,
+		time.Now().UTC().Add(-grace),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired tokens: %w", err)
+	}
+	return result.RowsAffected()
+}
+
 // GetByID retrieves a token by ID
 func (r *TokenRepository) GetByID(ctx context.Context, id int64) (*models.APIToken, error) {
-	query := `SELECT id, name, permissions, last_used_at, expires_at, created_at FROM api_tokens WHERE id = ?`
+	owner, ownerArgs := personalFilter(ctx, "user_id")
+	query := `SELECT ` + tokenColumns + ` FROM api_tokens WHERE id = ?` + owner
 
-	token := &models.APIToken{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&token.ID,
-		&token.Name,
-		&token.Permissions,
-		&token.LastUsedAt,
-		&token.ExpiresAt,
-		&token.CreatedAt,
-	)
+	token, err := scanToken(r.db.QueryRowContext(ctx, query, append([]interface{}{id}, ownerArgs...)...))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
@@ -122,18 +192,10 @@ func (r *TokenRepository) GetByID(ctx context.Context, id int64) (*models.APITok
 // - Falls back to SHA256 only for old tokens
 // GetByToken retrieves a token by its raw string value
 func (r *TokenRepository) GetByToken(ctx context.Context, token string) (*models.APIToken, error) {
-	query := `SELECT id, name, permissions, last_used_at, expires_at, created_at FROM api_tokens WHERE token_hash = ?`
+	query := `SELECT ` + tokenColumns + ` FROM api_tokens WHERE token_hash = ?`
 
 	tokenHash := hashToken(token)
-	apiToken := &models.APIToken{}
-	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
-		&apiToken.ID,
-		&apiToken.Name,
-		&apiToken.Permissions,
-		&apiToken.LastUsedAt,
-		&apiToken.ExpiresAt,
-		&apiToken.CreatedAt,
-	)
+	apiToken, err := scanToken(r.db.QueryRowContext(ctx, query, tokenHash))
 	if err == nil {
 		return apiToken, nil
 	}
@@ -146,9 +208,10 @@ func (r *TokenRepository) GetByToken(ctx context.Context, token string) (*models
 
 // List retrieves all API tokens
 func (r *TokenRepository) List(ctx context.Context) ([]models.APIToken, error) {
-	query := `SELECT id, name, permissions, last_used_at, expires_at, created_at FROM api_tokens ORDER BY created_at DESC`
+	owner, ownerArgs := personalFilter(ctx, "user_id")
+	query := `SELECT ` + tokenColumns + ` FROM api_tokens WHERE 1 = 1` + owner + ` ORDER BY created_at DESC`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, ownerArgs...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tokens: %w", err)
 	}
@@ -160,18 +223,11 @@ func (r *TokenRepository) List(ctx context.Context) ([]models.APIToken, error) {
 
 	tokens := make([]models.APIToken, 0)
 	for rows.Next() {
-		var token models.APIToken
-		if err := rows.Scan(
-			&token.ID,
-			&token.Name,
-			&token.Permissions,
-			&token.LastUsedAt,
-			&token.ExpiresAt,
-			&token.CreatedAt,
-		); err != nil {
+		token, err := scanToken(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan token: %w", err)
 		}
-		tokens = append(tokens, token)
+		tokens = append(tokens, *token)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -183,7 +239,8 @@ func (r *TokenRepository) List(ctx context.Context) ([]models.APIToken, error) {
 
 // Delete deletes a token
 func (r *TokenRepository) Delete(ctx context.Context, id int64) error {
-	result, err := r.db.ExecContext(ctx, `DELETE FROM api_tokens WHERE id = ?`, id)
+	owner, ownerArgs := personalFilter(ctx, "user_id")
+	result, err := r.db.ExecContext(ctx, `DELETE FROM api_tokens WHERE id = ?`+owner, append([]interface{}{id}, ownerArgs...)...)
 	if err != nil {
 		return fmt.Errorf("failed to delete token: %w", err)
 	}
@@ -197,23 +254,120 @@ func (r *TokenRepository) Delete(ctx context.Context, id int64) error {
 		return ErrNotFound
 	}
 
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM api_token_usage WHERE token_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete token usage: %w", err)
+	}
+
 	return nil
 }
 
-// UpdateLastUsed updates the last_used_at timestamp for a token
-func (r *TokenRepository) UpdateLastUsed(ctx context.Context, id int64) error {
+// RecordUse counts a request made with a token and notes when and from
+// which client address it came
+func (r *TokenRepository) RecordUse(ctx context.Context, id int64, clientIP string) error {
+	now := time.Now().UTC()
+	var lastIP interface{}
+	if clientIP != "" {
+		lastIP = clientIP
+	}
+
 	_, err := r.db.ExecContext(ctx,
-		`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`,
-		time.Now().UTC(), id,
+		`UPDATE api_tokens SET last_used_at = ?, last_used_ip = COALESCE(?, last_used_ip), request_count = request_count + 1 WHERE id = ?`,
+		now, lastIP, id,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update last used: %w", err)
 	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO api_token_usage (token_id, day, ip, requests, last_seen_at) VALUES (?, ?, ?, 1, ?)
+		ON CONFLICT(token_id, day, ip) DO UPDATE SET requests = requests + 1, last_seen_at = excluded.last_seen_at
+	`, id, now.Format(time.DateOnly), clientIP, now)
+	if err != nil {
+		return fmt.Errorf("failed to record token usage: %w", err)
+	}
 	return nil
 }
 
-// ValidateToken validates a token and returns it if valid
-func (r *TokenRepository) ValidateToken(ctx context.Context, token string) (*models.APIToken, error) {
+// Stats reports how a token was used over the last TokenUsageDays days
+func (r *TokenRepository) Stats(ctx context.Context, id int64) (*models.APITokenStats, error) {
+	token, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -TokenUsageDays).Format(time.DateOnly)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT day, ip, requests, last_seen_at FROM api_token_usage
+		WHERE token_id = ? AND day > ?
+		ORDER BY day ASC
+	`, id, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token usage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	stats := &models.APITokenStats{
+		TokenID:      token.ID,
+		RequestCount: token.RequestCount,
+		LastUsedAt:   token.LastUsedAt,
+		LastUsedIP:   token.LastUsedIP,
+		Days:         make([]models.APITokenDayUsage, 0),
+		IPs:          make([]models.APITokenClientUsage, 0),
+	}
+	clients := make(map[string]int)
+	for rows.Next() {
+		var (
+			day, ip    string
+			requests   int64
+			lastSeenAt time.Time
+		)
+		if err := rows.Scan(&day, &ip, &requests, &lastSeenAt); err != nil {
+			return nil, fmt.Errorf("failed to scan token usage: %w", err)
+		}
+
+		if n := len(stats.Days); n > 0 && stats.Days[n-1].Date == day {
+			stats.Days[n-1].Requests += requests
+		} else {
+			stats.Days = append(stats.Days, models.APITokenDayUsage{Date: day, Requests: requests})
+		}
+
+		if i, ok := clients[ip]; ok {
+			stats.IPs[i].Requests += requests
+			if lastSeenAt.After(stats.IPs[i].LastSeenAt) {
+				stats.IPs[i].LastSeenAt = lastSeenAt
+			}
+		} else {
+			clients[ip] = len(stats.IPs)
+			stats.IPs = append(stats.IPs, models.APITokenClientUsage{IP: ip, Requests: requests, LastSeenAt: lastSeenAt})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating token usage: %w", err)
+	}
+
+	sort.Slice(stats.IPs, func(i, j int) bool {
+		return stats.IPs[i].LastSeenAt.After(stats.IPs[j].LastSeenAt)
+	})
+	return stats, nil
+}
+
+// PruneUsage deletes per-day usage older than TokenUsageDays and usage of
+// tokens that no longer exist
+func (r *TokenRepository) PruneUsage(ctx context.Context) (int64, error) {
+	since := time.Now().UTC().AddDate(0, 0, -TokenUsageDays).Format(time.DateOnly)
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM api_token_usage WHERE day <= ? OR token_id NOT IN (SELECT id FROM api_tokens)`,
+		since,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune token usage: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ValidateToken validates a token and returns it if valid. The request is
+// recorded against the token together with clientIP.
+func (r *TokenRepository) ValidateToken(ctx context.Context, token, clientIP string) (*models.APIToken, error) {
 	apiToken, err := r.GetByToken(ctx, token)
 	if err != nil {
 		return nil, err
@@ -224,8 +378,8 @@ func (r *TokenRepository) ValidateToken(ctx context.Context, token string) (*mod
 		return nil, fmt.Errorf("token expired")
 	}
 
-	// Update last used timestamp
-	_ = r.UpdateLastUsed(ctx, apiToken.ID)
+	// Update usage statistics
+	_ = r.RecordUse(ctx, apiToken.ID, clientIP)
 
 	return apiToken, nil
 }
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestTokenRepository_Scopes(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	repo := NewTokenRepository(db)
+
+	scoped, err := repo.Create(ctx, &models.APITokenInput{
+		Name:   "automation",
+		Scopes: []string{models.ScopeSnippetsWrite, models.ScopeTagsWrite},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(scoped.Scopes) != 2 || !scoped.Scopes.Has(models.ScopeTagsWrite) {
+		t.Errorf("expected scopes to be returned on creation, got %v", scoped.Scopes)
+	}
+
+	validated, err := repo.ValidateToken(ctx, scoped.Token, "")
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	if !validated.Scopes.Has(models.ScopeSnippetsWrite) || validated.Scopes.Has(models.ScopeBackupRun) {
+		t.Errorf("unexpected scopes after lookup: %v", validated.Scopes)
+	}
+
+	unscoped, err := repo.Create(ctx, &models.APITokenInput{Name: "cli", Permissions: "write"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	fetched, err := repo.GetByID(ctx, unscoped.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if len(fetched.Scopes) != 0 {
+		t.Errorf("expected no scopes, got %v", fetched.Scopes)
+	}
+
+	tokens, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("expected 2 tokens, got %d", len(tokens))
+	}
+	for _, token := range tokens {
+		if token.Name == "automation" && len(token.Scopes) != 2 {
+			t.Errorf("expected listed token to keep its scopes, got %v", token.Scopes)
+		}
+	}
+}
+
+func TestTokenRepository_Rotate(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	repo := NewTokenRepository(db)
+
+	original, err := repo.Create(ctx, &models.APITokenInput{
+		Name:        "ci",
+		Permissions: "write",
+		Scopes:      []string{models.ScopeSnippetsWrite},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	expiresAt := time.Now().UTC().Add(48 * time.Hour)
+	rotated, err := repo.Rotate(ctx, original.ID, &expiresAt)
+	if err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if rotated.ID != original.ID || rotated.Token == "" || rotated.Token == original.Token {
+		t.Fatalf("expected a new secret for the same token, got %+v", rotated)
+	}
+	if rotated.Permissions != "write" || !rotated.Scopes.Has(models.ScopeSnippetsWrite) {
+		t.Errorf("expected permissions and scopes to be kept, got %q %v", rotated.Permissions, rotated.Scopes)
+	}
+	if rotated.ExpiresAt == nil || !rotated.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("expected expiry %v, got %v", expiresAt, rotated.ExpiresAt)
+	}
+
+	if _, err := repo.ValidateToken(ctx, original.Token, ""); err == nil {
+		t.Error("expected the old token to stop working after rotation")
+	}
+	if _, err := repo.ValidateToken(ctx, rotated.Token, ""); err != nil {
+		t.Errorf("expected the rotated token to validate, got %v", err)
+	}
+
+	if _, err := repo.Rotate(ctx, 9999, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing token, got %v", err)
+	}
+}
+
+func TestTokenRepository_DeleteExpired(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	repo := NewTokenRepository(db)
+
+	now := time.Now().UTC()
+	longExpired := now.Add(-10 * 24 * time.Hour)
+	justExpired := now.Add(-time.Hour)
+	for name, expiresAt := range map[string]*time.Time{"old": &longExpired, "recent": &justExpired, "forever": nil} {
+		if _, err := repo.Create(ctx, &models.APITokenInput{Name: name, ExpiresAt: expiresAt}); err != nil {
+			t.Fatalf("Create %s failed: %v", name, err)
+		}
+	}
+
+	deleted, err := repo.DeleteExpired(ctx, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("DeleteExpired failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 token deleted, got %d", deleted)
+	}
+
+	tokens, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Errorf("expected the recently expired and non-expiring tokens to remain, got %d", len(tokens))
+	}
+}
+
+func TestTokenRepository_Usage(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	repo := NewTokenRepository(db)
+
+	token, err := repo.Create(ctx, &models.APITokenInput{Name: "ci"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	for _, ip := range []string{"10.0.0.1", "10.0.0.1", "10.0.0.2"} {
+		if _, err := repo.ValidateToken(ctx, token.Token, ip); err != nil {
+			t.Fatalf("ValidateToken failed: %v", err)
+		}
+	}
+
+	tokens, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	listed := tokens[0]
+	if listed.RequestCount != 3 || listed.LastUsedAt == nil || listed.LastUsedIP == nil || *listed.LastUsedIP != "10.0.0.2" {
+		t.Errorf("expected 3 requests last from 10.0.0.2, got %d from %v at %v", listed.RequestCount, listed.LastUsedIP, listed.LastUsedAt)
+	}
+
+	stats, err := repo.Stats(ctx, token.ID)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.RequestCount != 3 || len(stats.Days) != 1 || stats.Days[0].Requests != 3 {
+		t.Errorf("expected 3 requests today, got %d, %+v", stats.RequestCount, stats.Days)
+	}
+	if len(stats.IPs) != 2 || stats.IPs[0].IP != "10.0.0.2" || stats.IPs[1].Requests != 2 {
+		t.Errorf("expected 2 clients, most recent first, got %+v", stats.IPs)
+	}
+	if _, err := repo.Stats(ctx, 9999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing token, got %v", err)
+	}
+
+	// Old usage is pruned, and rotating starts the counts over
+	old := time.Now().UTC().AddDate(0, 0, -TokenUsageDays-1)
+	if _, err := db.Exec(`INSERT INTO api_token_usage (token_id, day, ip, requests, last_seen_at) VALUES (?, ?, '', 5, ?)`,
+		token.ID, old.Format(time.DateOnly), old); err != nil {
+		t.Fatalf("insert usage failed: %v", err)
+	}
+	if pruned, err := repo.PruneUsage(ctx); err != nil || pruned != 1 {
+		t.Errorf("expected 1 pruned row, got %d, %v", pruned, err)
+	}
+
+	rotated, err := repo.Rotate(ctx, token.ID, nil)
+	if err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if rotated.RequestCount != 0 || rotated.LastUsedIP != nil {
+		t.Errorf("expected rotation to reset usage, got %d from %v", rotated.RequestCount, rotated.LastUsedIP)
+	}
+	if stats, _ := repo.Stats(ctx, token.ID); stats == nil || len(stats.Days) != 0 {
+		t.Errorf("expected no usage after rotation, got %+v", stats)
+	}
+}
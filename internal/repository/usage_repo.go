@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// UsageRepository stores the daily request counts of the telemetry report
+type UsageRepository struct {
+	db *sql.DB
+}
+
+// NewUsageRepository creates a new usage repository
+func NewUsageRepository(db *sql.DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// AddUsage adds counts, keyed by route, to the totals of day (YYYY-MM-DD)
+func (r *UsageRepository) AddUsage(ctx context.Context, day string, counts map[string]int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for route, count := range counts {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO usage_counts (day, route, count) VALUES (?, ?, ?)
+			ON CONFLICT(day, route) DO UPDATE SET count = count + excluded.count
+		`, day, route, count)
+		if err != nil {
+			return fmt.Errorf("failed to add usage of %s: %w", route, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UsageSince returns the requests per route from day (YYYY-MM-DD) onwards,
+// most used first
+func (r *UsageRepository) UsageSince(ctx context.Context, day string) ([]models.UsageCount, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT route, SUM(count) AS total FROM usage_counts
+		WHERE day >= ?
+		GROUP BY route
+		ORDER BY total DESC, route
+	`, day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	counts := []models.UsageCount{}
+	for rows.Next() {
+		var count models.UsageCount
+		if err := rows.Scan(&count.Name, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// PruneUsage deletes the counts of days before day (YYYY-MM-DD)
+func (r *UsageRepository) PruneUsage(ctx context.Context, day string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM usage_counts WHERE day < ?`, day); err != nil {
+		return fmt.Errorf("failed to prune usage: %w", err)
+	}
+	return nil
+}
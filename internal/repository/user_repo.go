@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// UserRepository handles user account database operations
+type UserRepository struct {
+	db *sql.DB
+}
+
+// NewUserRepository creates a new user repository
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// Create creates a user with an already hashed password
+func (r *UserRepository) Create(ctx context.Context, username, passwordHash string, isAdmin bool) (*models.User, error) {
+	query := `
+		INSERT INTO users (username, password_hash, is_admin)
+		VALUES (?, ?, ?)
+		RETURNING id, username, is_admin, created_at, updated_at
+	`
+
+	user := &models.User{}
+	err := r.db.QueryRowContext(ctx, query, username, passwordHash, isAdmin).Scan(
+		&user.ID,
+		&user.Username,
+		&user.IsAdmin,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrAlreadyExists
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// List retrieves all users ordered by username
+func (r *UserRepository) List(ctx context.Context) ([]models.User, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, username, is_admin, created_at, updated_at
+		FROM users
+		ORDER BY username COLLATE NOCASE ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.IsAdmin, &user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// Delete deletes a user together with their snippets, tags, folders, API
+// tokens, sessions and workspace memberships in a single transaction. What
+// they created inside workspaces stays with the workspace.
+func (r *UserRepository) Delete(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	// Related rows are removed explicitly in case CASCADE doesn't work, as in
+	// SnippetRepository.Delete
+	for _, query := range []string{
+		"DELETE FROM snippet_tags WHERE snippet_id IN (SELECT id FROM snippets WHERE user_id = ? AND workspace_id = 0)",
+		"DELETE FROM snippet_folders WHERE snippet_id IN (SELECT id FROM snippets WHERE user_id = ? AND workspace_id = 0)",
+		"DELETE FROM snippet_files WHERE snippet_id IN (SELECT id FROM snippets WHERE user_id = ? AND workspace_id = 0)",
+		"DELETE FROM snippets WHERE user_id = ? AND workspace_id = 0",
+		"DELETE FROM snippet_tags WHERE tag_id IN (SELECT id FROM tags WHERE user_id = ? AND workspace_id = 0)",
+		"DELETE FROM tags WHERE user_id = ? AND workspace_id = 0",
+		"UPDATE remote_sources SET folder_id = NULL WHERE folder_id IN (SELECT id FROM folders WHERE user_id = ? AND workspace_id = 0)",
+		"DELETE FROM snippet_folders WHERE folder_id IN (SELECT id FROM folders WHERE user_id = ? AND workspace_id = 0)",
+		"DELETE FROM folder_access WHERE folder_id IN (SELECT id FROM folders WHERE user_id = ? AND workspace_id = 0)",
+		"DELETE FROM folders WHERE user_id = ? AND workspace_id = 0",
+		"DELETE FROM activity WHERE user_id = ? AND workspace_id = 0",
+		"DELETE FROM api_tokens WHERE user_id = ?",
+		"DELETE FROM sessions WHERE user_id = ?",
+		"DELETE FROM workspace_members WHERE user_id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("failed to delete user data: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
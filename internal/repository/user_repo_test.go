@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestUserScoping(t *testing.T) {
+	db := testutil.TestDB(t)
+	users := NewUserRepository(db)
+	snippets := NewSnippetRepository(db)
+	tags := NewTagRepository(db)
+	folders := NewFolderRepository(db)
+
+	alice, err := users.Create(testutil.TestContext(), "alice", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	if _, err := users.Create(testutil.TestContext(), "ALICE", "hash", false); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("expected usernames to be unique ignoring case, got %v", err)
+	}
+
+	owner := models.WithUserID(testutil.TestContext(), models.OwnerUserID)
+	aliceCtx := models.WithUserID(testutil.TestContext(), alice.ID)
+
+	mine, err := snippets.Create(aliceCtx, &models.SnippetInput{Title: "alice's", Content: "x", Language: "go"})
+	if err != nil {
+		t.Fatalf("Create snippet failed: %v", err)
+	}
+	if err := tags.SetSnippetTags(aliceCtx, mine.ID, []string{"go"}); err != nil {
+		t.Fatalf("SetSnippetTags failed: %v", err)
+	}
+	if _, err := folders.Create(aliceCtx, &models.FolderInput{Name: "Work"}); err != nil {
+		t.Fatalf("Create folder failed: %v", err)
+	}
+
+	// The owner sees none of it and can't touch it
+	if s, err := snippets.GetByID(owner, mine.ID); err != nil || s != nil {
+		t.Errorf("expected another user's snippet to be missing, got %+v, %v", s, err)
+	}
+	if s, _ := snippets.Update(owner, mine.ID, &models.SnippetInput{Title: "stolen", Content: "x", Language: "go"}); s != nil {
+		t.Error("expected updating another user's snippet to fail")
+	}
+	if err := snippets.Delete(owner, mine.ID, true); err == nil {
+		t.Error("expected deleting another user's snippet to fail")
+	}
+	list, err := snippets.List(owner, models.SnippetFilter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if list.Pagination.Total != 0 {
+		t.Errorf("expected the owner to list no snippets, got %d", list.Pagination.Total)
+	}
+	if ownerTags, _ := tags.List(owner); len(ownerTags) != 0 {
+		t.Errorf("expected the owner to have no tags, got %+v", ownerTags)
+	}
+	if ownerFolders, _ := folders.List(owner); len(ownerFolders) != 0 {
+		t.Errorf("expected the owner to have no folders, got %+v", ownerFolders)
+	}
+
+	// Tag names are per user
+	if _, err := tags.Create(owner, &models.TagInput{Name: "go"}); err != nil {
+		t.Errorf("expected the owner to get their own go tag: %v", err)
+	}
+
+	// Contexts without a user, such as background jobs, see everything
+	all, err := snippets.List(testutil.TestContext(), models.SnippetFilter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if all.Pagination.Total != 1 {
+		t.Errorf("expected unscoped listing to include alice's snippet, got %d", all.Pagination.Total)
+	}
+
+	// Deleting the user removes what they own
+	if err := users.Delete(testutil.TestContext(), alice.ID); err != nil {
+		t.Fatalf("Delete user failed: %v", err)
+	}
+	if s, _ := snippets.GetByID(testutil.TestContext(), mine.ID); s != nil {
+		t.Error("expected the user's snippets to be deleted with them")
+	}
+	if aliceTags, _ := tags.List(aliceCtx); len(aliceTags) != 0 {
+		t.Errorf("expected the user's tags to be deleted with them, got %+v", aliceTags)
+	}
+	if err := users.Delete(testutil.TestContext(), alice.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting a missing user, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"crypto/hmac"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// InboundWebhookPathPrefix is where inbound webhooks receive deliveries
+const InboundWebhookPathPrefix = "/api/v1/hooks/"
+
+// InboundWebhookRepository handles inbound webhook database operations
+type InboundWebhookRepository struct {
+	db *sql.DB
+}
+
+// NewInboundWebhookRepository creates a new inbound webhook repository
+func NewInboundWebhookRepository(db *sql.DB) *InboundWebhookRepository {
+	return &InboundWebhookRepository{db: db}
+}
+
+const inboundWebhookColumns = `
+	id, name, slug, secret_hash, folder_id, tags, language, enabled,
+	received_count, last_received_at, created_at, updated_at
+`
+
+func scanInboundWebhook(row interface{ Scan(...any) error }) (*models.InboundWebhook, error) {
+	hook := &models.InboundWebhook{}
+	var folderID sql.NullInt64
+	var tags string
+	var lastReceivedAt sql.NullTime
+
+	err := row.Scan(
+		&hook.ID,
+		&hook.Name,
+		&hook.Slug,
+		&hook.SecretHash,
+		&folderID,
+		&tags,
+		&hook.Language,
+		&hook.Enabled,
+		&hook.ReceivedCount,
+		&lastReceivedAt,
+		&hook.CreatedAt,
+		&hook.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	hook.Path = InboundWebhookPathPrefix + hook.Slug
+	if folderID.Valid {
+		hook.FolderID = &folderID.Int64
+	}
+	if lastReceivedAt.Valid {
+		hook.LastReceivedAt = &lastReceivedAt.Time
+	}
+	hook.Tags = splitList(tags)
+
+	return hook, nil
+}
+
+// splitList splits a comma-separated column. Tag names and chat IDs can't
+// contain commas, so lists of them are stored this way.
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Create adds an inbound webhook with a random URL slug and secret, and
+// returns the secret, which is only stored hashed
+func (r *InboundWebhookRepository) Create(ctx context.Context, input *models.InboundWebhookInput) (*models.InboundWebhook, string, error) {
+	slug, err := generateToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate webhook slug: %w", err)
+	}
+	secret, err := generateToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	enabled := true
+	if input.Enabled != nil {
+		enabled = *input.Enabled
+	}
+
+	var id int64
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO inbound_webhooks (name, slug, secret_hash, folder_id, tags, language, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, input.Name, slug[:32], hashToken(secret), input.FolderID, strings.Join(input.Tags, ","), input.Language, enabled).Scan(&id)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create inbound webhook: %w", err)
+	}
+
+	hook, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	return hook, secret, nil
+}
+
+// GetByID retrieves an inbound webhook by ID
+func (r *InboundWebhookRepository) GetByID(ctx context.Context, id int64) (*models.InboundWebhook, error) {
+	query := `SELECT ` + inboundWebhookColumns + ` FROM inbound_webhooks WHERE id = ?`
+
+	hook, err := scanInboundWebhook(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inbound webhook: %w", err)
+	}
+
+	return hook, nil
+}
+
+// GetBySlug retrieves an inbound webhook by the slug in its delivery URL
+func (r *InboundWebhookRepository) GetBySlug(ctx context.Context, slug string) (*models.InboundWebhook, error) {
+	query := `SELECT ` + inboundWebhookColumns + ` FROM inbound_webhooks WHERE slug = ?`
+
+	hook, err := scanInboundWebhook(r.db.QueryRowContext(ctx, query, slug))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inbound webhook: %w", err)
+	}
+
+	return hook, nil
+}
+
+// List retrieves all inbound webhooks
+func (r *InboundWebhookRepository) List(ctx context.Context) ([]models.InboundWebhook, error) {
+	query := `SELECT ` + inboundWebhookColumns + ` FROM inbound_webhooks ORDER BY name, id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inbound webhooks: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	hooks := []models.InboundWebhook{}
+	for rows.Next() {
+		hook, err := scanInboundWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan inbound webhook: %w", err)
+		}
+		hooks = append(hooks, *hook)
+	}
+
+	return hooks, rows.Err()
+}
+
+// VerifySecret reports whether secret is the webhook's secret
+func (r *InboundWebhookRepository) VerifySecret(hook *models.InboundWebhook, secret string) bool {
+	return hmac.Equal([]byte(hashToken(secret)), []byte(hook.SecretHash))
+}
+
+// RotateSecret replaces a webhook's secret and returns the new one
+func (r *InboundWebhookRepository) RotateSecret(ctx context.Context, id int64) (string, error) {
+	secret, err := generateToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE inbound_webhooks SET secret_hash = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, hashToken(secret), id)
+	if err != nil {
+		return "", fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return "", ErrNotFound
+	}
+
+	return secret, nil
+}
+
+// RecordDelivery counts a delivery that created a snippet
+func (r *InboundWebhookRepository) RecordDelivery(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE inbound_webhooks
+		SET received_count = received_count + 1, last_received_at = ?
+		WHERE id = ?
+	`, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// Delete removes an inbound webhook. Snippets it created are kept.
+func (r *InboundWebhookRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM inbound_webhooks WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete inbound webhook: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// WorkspaceRepository handles workspace and membership database operations
+type WorkspaceRepository struct {
+	db *sql.DB
+}
+
+// NewWorkspaceRepository creates a new workspace repository
+func NewWorkspaceRepository(db *sql.DB) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db}
+}
+
+// Create creates a workspace
+func (r *WorkspaceRepository) Create(ctx context.Context, input *models.WorkspaceInput) (*models.Workspace, error) {
+	query := `
+		INSERT INTO workspaces (slug, name)
+		VALUES (?, ?)
+		RETURNING id, slug, name, created_at, updated_at
+	`
+
+	workspace, err := scanWorkspace(r.db.QueryRowContext(ctx, query, input.Slug, input.Name))
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrAlreadyExists
+		}
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	return workspace, nil
+}
+
+// GetByID retrieves a workspace by ID
+func (r *WorkspaceRepository) GetByID(ctx context.Context, id int64) (*models.Workspace, error) {
+	workspace, err := scanWorkspace(r.db.QueryRowContext(ctx,
+		`SELECT id, slug, name, created_at, updated_at FROM workspaces WHERE id = ?`, id,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+	return workspace, nil
+}
+
+// GetBySlug retrieves a workspace by its slug, ignoring case
+func (r *WorkspaceRepository) GetBySlug(ctx context.Context, slug string) (*models.Workspace, error) {
+	workspace, err := scanWorkspace(r.db.QueryRowContext(ctx,
+		`SELECT id, slug, name, created_at, updated_at FROM workspaces WHERE slug = ?`, slug,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+	return workspace, nil
+}
+
+// List retrieves all workspaces ordered by name
+func (r *WorkspaceRepository) List(ctx context.Context) ([]models.Workspace, error) {
+	return r.list(ctx, false, `
+		SELECT id, slug, name, created_at, updated_at
+		FROM workspaces
+		ORDER BY name COLLATE NOCASE ASC
+	`)
+}
+
+// ListForUser retrieves the workspaces a user is a member of, with the
+// user's role in each
+func (r *WorkspaceRepository) ListForUser(ctx context.Context, userID int64) ([]models.Workspace, error) {
+	return r.list(ctx, true, `
+		SELECT w.id, w.slug, w.name, w.created_at, w.updated_at, m.role
+		FROM workspaces w
+		INNER JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE m.user_id = ?
+		ORDER BY w.name COLLATE NOCASE ASC
+	`, userID)
+}
+
+// list runs a workspace query, which selects the member's role last when
+// withRole is set
+func (r *WorkspaceRepository) list(ctx context.Context, withRole bool, query string, args ...interface{}) ([]models.Workspace, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	workspaces := make([]models.Workspace, 0)
+	for rows.Next() {
+		var role string
+		var extra []any
+		if withRole {
+			extra = append(extra, &role)
+		}
+		workspace, err := scanWorkspace(rows, extra...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan workspace: %w", err)
+		}
+		workspace.Role = role
+		workspaces = append(workspaces, *workspace)
+	}
+
+	return workspaces, rows.Err()
+}
+
+// Update renames a workspace or changes its slug
+func (r *WorkspaceRepository) Update(ctx context.Context, id int64, input *models.WorkspaceInput) (*models.Workspace, error) {
+	query := `
+		UPDATE workspaces SET slug = ?, name = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+		RETURNING id, slug, name, created_at, updated_at
+	`
+
+	workspace, err := scanWorkspace(r.db.QueryRowContext(ctx, query, input.Slug, input.Name, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrAlreadyExists
+		}
+		return nil, fmt.Errorf("failed to update workspace: %w", err)
+	}
+
+	return workspace, nil
+}
+
+// Delete deletes a workspace together with its snippets, tags, folders,
+// activity, settings, memberships and the API tokens bound to it in a single
+// transaction
+func (r *WorkspaceRepository) Delete(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM workspaces WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	// Related rows are removed explicitly in case CASCADE doesn't work, as in
+	// UserRepository.Delete
+	for _, query := range []string{
+		"DELETE FROM snippet_tags WHERE snippet_id IN (SELECT id FROM snippets WHERE workspace_id = ?)",
+		"DELETE FROM snippet_folders WHERE snippet_id IN (SELECT id FROM snippets WHERE workspace_id = ?)",
+		"DELETE FROM snippet_files WHERE snippet_id IN (SELECT id FROM snippets WHERE workspace_id = ?)",
+		"DELETE FROM snippets WHERE workspace_id = ?",
+		"DELETE FROM snippet_tags WHERE tag_id IN (SELECT id FROM tags WHERE workspace_id = ?)",
+		"DELETE FROM tags WHERE workspace_id = ?",
+		"UPDATE remote_sources SET folder_id = NULL WHERE folder_id IN (SELECT id FROM folders WHERE workspace_id = ?)",
+		"DELETE FROM snippet_folders WHERE folder_id IN (SELECT id FROM folders WHERE workspace_id = ?)",
+		"DELETE FROM folder_access WHERE folder_id IN (SELECT id FROM folders WHERE workspace_id = ?)",
+		"DELETE FROM folders WHERE workspace_id = ?",
+		"DELETE FROM activity WHERE workspace_id = ?",
+		"DELETE FROM api_tokens WHERE workspace_id = ?",
+		"DELETE FROM workspace_settings WHERE workspace_id = ?",
+		"DELETE FROM workspace_members WHERE workspace_id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("failed to delete workspace data: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// AddMember adds a user to a workspace with a role, or changes the role of
+// an existing member. ErrNotFound is returned when the user doesn't exist.
+func (r *WorkspaceRepository) AddMember(ctx context.Context, workspaceID, userID int64, role string) error {
+	var exists int
+	err := r.db.QueryRowContext(ctx, `SELECT 1 FROM users WHERE id = ?`, userID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role) VALUES (?, ?, ?)
+		ON CONFLICT(workspace_id, user_id) DO UPDATE SET role = excluded.role
+	`, workspaceID, userID, role)
+	if err != nil {
+		return fmt.Errorf("failed to add workspace member: %w", err)
+	}
+	return nil
+}
+
+// RemoveMember removes a user from a workspace. What they created there
+// stays with the workspace.
+func (r *WorkspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID int64) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM workspace_members WHERE workspace_id = ? AND user_id = ?`,
+		workspaceID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to remove workspace member: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	// Tokens bound to the workspace stop being useful, so revoke them
+	if _, err := r.db.ExecContext(ctx,
+		`DELETE FROM api_tokens WHERE workspace_id = ? AND user_id = ?`,
+		workspaceID, userID,
+	); err != nil {
+		return fmt.Errorf("failed to revoke workspace tokens: %w", err)
+	}
+	return nil
+}
+
+// ListMembers retrieves the members of a workspace ordered by username
+func (r *WorkspaceRepository) ListMembers(ctx context.Context, workspaceID int64) ([]models.WorkspaceMember, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT u.id, u.username, m.role, m.created_at
+		FROM workspace_members m
+		INNER JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = ?
+		ORDER BY u.username COLLATE NOCASE ASC
+	`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace members: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	members := make([]models.WorkspaceMember, 0)
+	for rows.Next() {
+		var member models.WorkspaceMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Role, &member.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace member: %w", err)
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// MemberRole returns a user's role in a workspace, or ErrNotFound when
+// they aren't a member
+func (r *WorkspaceRepository) MemberRole(ctx context.Context, workspaceID, userID int64) (string, error) {
+	var role string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT role FROM workspace_members WHERE workspace_id = ? AND user_id = ?`,
+		workspaceID, userID,
+	).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check workspace membership: %w", err)
+	}
+	return role, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanWorkspace reads id, slug, name, created_at and updated_at, followed by
+// any extra columns into extra
+func scanWorkspace(row rowScanner, extra ...any) (*models.Workspace, error) {
+	workspace := &models.Workspace{}
+	dest := append([]any{
+		&workspace.ID,
+		&workspace.Slug,
+		&workspace.Name,
+		&workspace.CreatedAt,
+		&workspace.UpdatedAt,
+	}, extra...)
+	err := row.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestWorkspaceScoping(t *testing.T) {
+	db := testutil.TestDB(t)
+	users := NewUserRepository(db)
+	workspaces := NewWorkspaceRepository(db)
+	snippets := NewSnippetRepository(db)
+	tags := NewTagRepository(db)
+	settings := NewSettingsRepository(db)
+
+	alice, err := users.Create(testutil.TestContext(), "alice", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	bob, err := users.Create(testutil.TestContext(), "bob", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+
+	team, err := workspaces.Create(testutil.TestContext(), &models.WorkspaceInput{Slug: "team", Name: "Team"})
+	if err != nil {
+		t.Fatalf("Create workspace failed: %v", err)
+	}
+	if _, err := workspaces.Create(testutil.TestContext(), &models.WorkspaceInput{Slug: "TEAM", Name: "Other"}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("expected slugs to be unique ignoring case, got %v", err)
+	}
+	for _, id := range []int64{alice.ID, bob.ID} {
+		if err := workspaces.AddMember(testutil.TestContext(), team.ID, id, models.WorkspaceRoleViewer); err != nil {
+			t.Fatalf("AddMember failed: %v", err)
+		}
+	}
+	if err := workspaces.AddMember(testutil.TestContext(), team.ID, 999, models.WorkspaceRoleEditor); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound adding a missing user, got %v", err)
+	}
+
+	// Adding a member again changes their role
+	if err := workspaces.AddMember(testutil.TestContext(), team.ID, bob.ID, models.WorkspaceRoleEditor); err != nil {
+		t.Fatalf("AddMember failed: %v", err)
+	}
+	if role, err := workspaces.MemberRole(testutil.TestContext(), team.ID, bob.ID); err != nil || role != models.WorkspaceRoleEditor {
+		t.Errorf("expected bob to be an editor, got %q, %v", role, err)
+	}
+	if mine, _ := workspaces.ListForUser(testutil.TestContext(), alice.ID); len(mine) != 1 || mine[0].Role != models.WorkspaceRoleViewer {
+		t.Errorf("expected alice's workspace to be listed with her role, got %+v", mine)
+	}
+
+	aliceTeam := models.WithWorkspaceID(models.WithUserID(testutil.TestContext(), alice.ID), team.ID)
+	bobTeam := models.WithWorkspaceID(models.WithUserID(testutil.TestContext(), bob.ID), team.ID)
+	alicePersonal := models.WithUserID(testutil.TestContext(), alice.ID)
+
+	shared, err := snippets.Create(aliceTeam, &models.SnippetInput{Title: "shared", Content: "x", Language: "go"})
+	if err != nil {
+		t.Fatalf("Create snippet failed: %v", err)
+	}
+	if err := tags.SetSnippetTags(aliceTeam, shared.ID, []string{"go"}); err != nil {
+		t.Fatalf("SetSnippetTags failed: %v", err)
+	}
+	personal, err := snippets.Create(alicePersonal, &models.SnippetInput{Title: "personal", Content: "y", Language: "go"})
+	if err != nil {
+		t.Fatalf("Create snippet failed: %v", err)
+	}
+
+	// Members share the workspace's snippets and tags
+	if s, err := snippets.GetByID(bobTeam, shared.ID); err != nil || s == nil {
+		t.Errorf("expected bob to see the workspace snippet, got %+v, %v", s, err)
+	}
+	if s, err := snippets.Update(bobTeam, shared.ID, &models.SnippetInput{Title: "edited", Content: "x", Language: "go"}); err != nil || s == nil {
+		t.Errorf("expected bob to edit the workspace snippet, got %+v, %v", s, err)
+	}
+	if teamTags, _ := tags.List(bobTeam); len(teamTags) != 1 || teamTags[0].Name != "go" {
+		t.Errorf("expected bob to see the workspace's go tag, got %+v", teamTags)
+	}
+	if _, err := tags.GetByName(bobTeam, "go"); err != nil {
+		t.Errorf("expected GetByName to find the workspace tag: %v", err)
+	}
+
+	// The workspace and personal spaces don't see each other
+	list, err := snippets.List(aliceTeam, models.SnippetFilter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if list.Pagination.Total != 1 || list.Data[0].ID != shared.ID {
+		t.Errorf("expected the workspace to list only its snippet, got %+v", list.Data)
+	}
+	if s, _ := snippets.GetByID(aliceTeam, personal.ID); s != nil {
+		t.Error("expected a personal snippet to be missing inside the workspace")
+	}
+	if s, _ := snippets.GetByID(alicePersonal, shared.ID); s != nil {
+		t.Error("expected a workspace snippet to be missing outside the workspace")
+	}
+	if personalTags, _ := tags.List(alicePersonal); len(personalTags) != 0 {
+		t.Errorf("expected alice to have no personal tags, got %+v", personalTags)
+	}
+	if _, err := tags.Create(alicePersonal, &models.TagInput{Name: "go"}); err != nil {
+		t.Errorf("expected alice to get a personal go tag: %v", err)
+	}
+
+	// Workspace settings override the instance's without changing them
+	if _, err := settings.Update(aliceTeam, &models.SettingsInput{AppName: "Team Snippets", Theme: "dark", DefaultLanguage: "go", S3Enabled: true}); err != nil {
+		t.Fatalf("Update settings failed: %v", err)
+	}
+	teamSettings, err := settings.Get(aliceTeam)
+	if err != nil {
+		t.Fatalf("Get settings failed: %v", err)
+	}
+	if teamSettings.AppName != "Team Snippets" || teamSettings.Theme != "dark" {
+		t.Errorf("expected workspace overrides, got %q, %q", teamSettings.AppName, teamSettings.Theme)
+	}
+	global, err := settings.Get(testutil.TestContext())
+	if err != nil {
+		t.Fatalf("Get settings failed: %v", err)
+	}
+	if global.AppName != "snipo" || global.S3Enabled {
+		t.Errorf("expected instance settings to be unchanged, got %q, s3 %v", global.AppName, global.S3Enabled)
+	}
+
+	// Deleting the workspace removes what's in it, but not personal data
+	if err := workspaces.Delete(testutil.TestContext(), team.ID); err != nil {
+		t.Fatalf("Delete workspace failed: %v", err)
+	}
+	if s, _ := snippets.GetByID(testutil.TestContext(), shared.ID); s != nil {
+		t.Error("expected the workspace's snippets to be deleted with it")
+	}
+	if s, _ := snippets.GetByID(alicePersonal, personal.ID); s == nil {
+		t.Error("expected personal snippets to survive deleting a workspace")
+	}
+	if _, err := workspaces.MemberRole(testutil.TestContext(), team.ID, alice.ID); !errors.Is(err, ErrNotFound) {
+		t.Error("expected memberships to be deleted with the workspace")
+	}
+	if err := workspaces.Delete(testutil.TestContext(), team.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting a missing workspace, got %v", err)
+	}
+}
+
+func TestWorkspaceTokens(t *testing.T) {
+	db := testutil.TestDB(t)
+	users := NewUserRepository(db)
+	workspaces := NewWorkspaceRepository(db)
+	tokens := NewTokenRepository(db)
+
+	alice, err := users.Create(testutil.TestContext(), "alice", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	team, err := workspaces.Create(testutil.TestContext(), &models.WorkspaceInput{Slug: "team", Name: "Team"})
+	if err != nil {
+		t.Fatalf("Create workspace failed: %v", err)
+	}
+	if err := workspaces.AddMember(testutil.TestContext(), team.ID, alice.ID, models.WorkspaceRoleAdmin); err != nil {
+		t.Fatalf("AddMember failed: %v", err)
+	}
+
+	aliceTeam := models.WithWorkspaceID(models.WithUserID(testutil.TestContext(), alice.ID), team.ID)
+	alicePersonal := models.WithUserID(testutil.TestContext(), alice.ID)
+
+	bound, err := tokens.Create(aliceTeam, &models.APITokenInput{Name: "ci"})
+	if err != nil {
+		t.Fatalf("Create token failed: %v", err)
+	}
+	if bound.WorkspaceID != team.ID {
+		t.Errorf("expected the token to be bound to the workspace, got %d", bound.WorkspaceID)
+	}
+	validated, err := tokens.ValidateToken(testutil.TestContext(), bound.Token, "")
+	if err != nil || validated.WorkspaceID != team.ID || validated.UserID != alice.ID {
+		t.Errorf("expected the validated token to carry its user and workspace, got %+v, %v", validated, err)
+	}
+	if personal, _ := tokens.List(alicePersonal); len(personal) != 0 {
+		t.Errorf("expected workspace tokens to be left out of the personal list, got %+v", personal)
+	}
+
+	// Leaving the workspace revokes its tokens
+	if err := workspaces.RemoveMember(testutil.TestContext(), team.ID, alice.ID); err != nil {
+		t.Fatalf("RemoveMember failed: %v", err)
+	}
+	if _, err := tokens.ValidateToken(testutil.TestContext(), bound.Token, ""); err == nil {
+		t.Error("expected the bound token to be revoked when its user left the workspace")
+	}
+	if err := workspaces.RemoveMember(testutil.TestContext(), team.ID, alice.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound removing a non-member, got %v", err)
+	}
+}
@@ -4,8 +4,6 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
 	"database/sql"
@@ -29,15 +27,18 @@ const (
 var (
 	ErrInvalidBackupFormat = errors.New("invalid backup format")
 	ErrDecryptionFailed    = errors.New("decryption failed - wrong password?")
+	ErrPasswordRequired    = errors.New("backup is encrypted, a password is required")
+	ErrIncompatibleBackup  = errors.New("backup format is not supported by this version")
 )
 
 // BackupService handles backup and restore operations
 type BackupService struct {
 	db             *sql.DB
-	snippetSvc     *SnippetService
+	snippetSvc     SnippetManager
 	tagRepo        *repository.TagRepository
 	folderRepo     *repository.FolderRepository
 	fileRepo       *repository.SnippetFileRepository
+	importReports  *repository.ImportReportRepository
 	logger         *slog.Logger
 	encryptionSalt string
 }
@@ -45,7 +46,7 @@ type BackupService struct {
 // NewBackupService creates a new backup service
 func NewBackupService(
 	db *sql.DB,
-	snippetSvc *SnippetService,
+	snippetSvc SnippetManager,
 	tagRepo *repository.TagRepository,
 	folderRepo *repository.FolderRepository,
 	fileRepo *repository.SnippetFileRepository,
@@ -63,8 +64,22 @@ func NewBackupService(
 	}
 }
 
+// WithImportReports saves a report of every import, readable at
+// /api/v1/imports/{id}/report
+func (b *BackupService) WithImportReports(repo *repository.ImportReportRepository) *BackupService {
+	b.importReports = repo
+	return b
+}
+
 // Export creates a complete backup of all data
 func (b *BackupService) Export(ctx context.Context, opts models.ExportOptions) ([]byte, string, error) {
+	content, filename, _, err := b.ExportWithMetadata(ctx, opts)
+	return content, filename, err
+}
+
+// ExportWithMetadata creates a complete backup and describes what it holds.
+// AppVersion is left for the caller to fill in.
+func (b *BackupService) ExportWithMetadata(ctx context.Context, opts models.ExportOptions) ([]byte, string, *models.BackupMetadata, error) {
 	data := models.BackupData{
 		Version:   BackupVersion,
 		CreatedAt: time.Now().UTC(),
@@ -76,7 +91,7 @@ func (b *BackupService) Export(ctx context.Context, opts models.ExportOptions) (
 		Limit: 10000, // Get all snippets
 	})
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get snippets: %w", err)
+		return nil, "", nil, fmt.Errorf("failed to get snippets: %w", err)
 	}
 
 	// Fetch full details for each snippet (including files, tags, folders)
@@ -115,14 +130,14 @@ func (b *BackupService) Export(ctx context.Context, opts models.ExportOptions) (
 	if opts.Format == "zip" {
 		content, err = b.createZipBackup(data)
 		if err != nil {
-			return nil, "", fmt.Errorf("failed to create zip backup: %w", err)
+			return nil, "", nil, fmt.Errorf("failed to create zip backup: %w", err)
 		}
 		filename = fmt.Sprintf("snipo-backup-%s.zip", time.Now().Format("2006-01-02-150405"))
 	} else {
 		// Default to JSON
 		content, err = json.MarshalIndent(data, "", "  ")
 		if err != nil {
-			return nil, "", fmt.Errorf("failed to marshal backup: %w", err)
+			return nil, "", nil, fmt.Errorf("failed to marshal backup: %w", err)
 		}
 		filename = fmt.Sprintf("snipo-backup-%s.json", time.Now().Format("2006-01-02-150405"))
 	}
@@ -131,7 +146,7 @@ func (b *BackupService) Export(ctx context.Context, opts models.ExportOptions) (
 	if opts.Password != "" {
 		content, err = b.encrypt(content, opts.Password)
 		if err != nil {
-			return nil, "", fmt.Errorf("failed to encrypt backup: %w", err)
+			return nil, "", nil, fmt.Errorf("failed to encrypt backup: %w", err)
 		}
 		filename = filename + ".enc"
 	}
@@ -144,48 +159,33 @@ func (b *BackupService) Export(ctx context.Context, opts models.ExportOptions) (
 		"encrypted", opts.Password != "",
 	)
 
-	return content, filename, nil
+	format := "json"
+	if opts.Format == "zip" {
+		format = "zip"
+	}
+	metadata := &models.BackupMetadata{
+		CreatedAt:     data.CreatedAt,
+		SnippetCount:  len(data.Snippets),
+		TagCount:      len(data.Tags),
+		FolderCount:   len(data.Folders),
+		BackupVersion: data.Version,
+		Format:        format,
+		Size:          int64(len(content)),
+		Encrypted:     opts.Password != "",
+	}
+
+	return content, filename, metadata, nil
 }
 
 // Import restores data from a backup
 func (b *BackupService) Import(ctx context.Context, content []byte, opts models.ImportOptions) (*models.ImportResult, error) {
-	// Decrypt if password provided
-	var err error
-	if opts.Password != "" {
-		content, err = b.decrypt(content, opts.Password)
-		if err != nil {
-			return nil, ErrDecryptionFailed
-		}
+	data, err := b.decodeBackup(content, opts.Password)
+	if err != nil {
+		return nil, err
 	}
 
-	var data models.BackupData
-
-	// Try JSON first
-	if err := json.Unmarshal(content, &data); err != nil {
-		// Try ZIP
-		zr, zipErr := zip.NewReader(bytes.NewReader(content), int64(len(content)))
-		if zipErr != nil {
-			return nil, ErrInvalidBackupFormat
-		}
-
-		for _, f := range zr.File {
-			if f.Name == "metadata.json" {
-				rc, err := f.Open()
-				if err != nil {
-					return nil, fmt.Errorf("failed to open metadata: %w", err)
-				}
-				if err := json.NewDecoder(rc).Decode(&data); err != nil {
-					_ = rc.Close()
-					return nil, fmt.Errorf("failed to decode metadata: %w", err)
-				}
-				_ = rc.Close()
-				break
-			}
-		}
-
-		if data.Version == "" {
-			return nil, ErrInvalidBackupFormat
-		}
+	if opts.DryRun {
+		return b.planImport(ctx, data, opts)
 	}
 
 	result := &models.ImportResult{}
@@ -210,13 +210,11 @@ func (b *BackupService) Import(ctx context.Context, content []byte, opts models.
 		existingFoldersByName[existingFolders[i].Name] = &existingFolders[i]
 	}
 
-	existingSnippets, _ := b.snippetSvc.List(ctx, models.SnippetFilter{Limit: 10000})
-	existingSnippetsByTitle := make(map[string]*models.Snippet)
-	if existingSnippets != nil {
-		for i := range existingSnippets.Data {
-			existingSnippetsByTitle[existingSnippets.Data[i].Title] = &existingSnippets.Data[i]
-		}
+	existingSnippets, err := b.listAllSnippets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list existing snippets: %w", err)
 	}
+	index := newSnippetImportIndex(existingSnippets)
 
 	// Import tags first (needed for relationships)
 	tagMap := make(map[int64]int64) // old ID -> new ID
@@ -283,66 +281,354 @@ func (b *BackupService) Import(ctx context.Context, content []byte, opts models.
 	}
 
 	// Import snippets
-	for _, snippet := range data.Snippets {
-		// Check if snippet with same title already exists
-		if _, exists := existingSnippetsByTitle[snippet.Title]; exists {
-			// Skip if strategy is "skip" or "merge" (merge doesn't overwrite existing)
-			if opts.Strategy == "skip" || opts.Strategy == "merge" {
-				continue
-			}
-		}
-
-		// Prepare input
-		input := &models.SnippetInput{
-			Title:       snippet.Title,
-			Description: snippet.Description,
-			Content:     snippet.Content,
-			Language:    snippet.Language,
-			IsPublic:    snippet.IsPublic,
-			IsArchived:  snippet.IsArchived,
+	conflictStrategy := resolveConflictStrategy(opts)
+	for i := range data.Snippets {
+		snippet := &data.Snippets[i]
+		item := models.ImportItemResult{SourceID: snippet.ID, Title: snippet.Title}
+
+		existing, matchedBy := index.match(snippet)
+		item.MatchedBy = matchedBy
+		if existing != nil && conflictStrategy == models.ConflictSkip {
+			item.Action = "skipped"
+			item.SnippetID = existing.ID
+			result.SnippetsSkipped++
+			result.Items = append(result.Items, item)
+			continue
 		}
 
-		// Map tags
-		for _, tag := range snippet.Tags {
-			input.Tags = append(input.Tags, tag.Name)
-		}
+		input := b.snippetInputFromBackup(snippet, folderMap)
 
-		// Map folder (use first folder if any)
-		if len(snippet.Folders) > 0 {
-			if newFolderID, ok := folderMap[snippet.Folders[0].ID]; ok {
-				input.FolderID = &newFolderID
+		var saved *models.Snippet
+		var err error
+		if existing != nil && conflictStrategy == models.ConflictOverwrite {
+			saved, err = b.snippetSvc.Update(ctx, existing.ID, input)
+			if err == nil {
+				item.Action = "updated"
+				result.SnippetsUpdated++
+			}
+		} else {
+			saved, err = b.snippetSvc.Create(ctx, input)
+			if err == nil {
+				item.Action = "created"
+				result.SnippetsImported++
 			}
 		}
 
-		// Map files
-		for _, file := range snippet.Files {
-			input.Files = append(input.Files, models.SnippetFileInput{
-				Filename: file.Filename,
-				Content:  file.Content,
-				Language: file.Language,
-			})
-		}
-
-		_, err := b.snippetSvc.Create(ctx, input)
-		if err == nil {
-			result.SnippetsImported++
-			// Add to map to prevent duplicates within same import
-			existingSnippetsByTitle[snippet.Title] = &snippet
-		} else {
+		if err != nil {
+			item.Action = "failed"
+			item.Error = err.Error()
 			result.Errors = append(result.Errors, fmt.Sprintf("snippet %s: %v", snippet.Title, err))
+		} else {
+			item.SnippetID = saved.ID
+			// Add to index to prevent duplicates within same import
+			index.add(saved)
 		}
+		result.Items = append(result.Items, item)
 	}
 
 	b.logger.Info("backup imported",
 		"snippets", result.SnippetsImported,
+		"updated", result.SnippetsUpdated,
+		"skipped", result.SnippetsSkipped,
 		"tags", result.TagsImported,
 		"folders", result.FoldersImported,
 		"errors", len(result.Errors),
 	)
 
+	b.saveReport(ctx, opts.Source, result)
 	return result, nil
 }
 
+// saveReport keeps the outcome of an import and sets its report ID. An
+// import that went through isn't failed because its report couldn't be saved.
+func (b *BackupService) saveReport(ctx context.Context, source string, result *models.ImportResult) {
+	if b.importReports == nil {
+		return
+	}
+	if source == "" {
+		source = models.ImportSourceBackup
+	}
+
+	report := &models.ImportReport{
+		Source:  source,
+		Created: result.SnippetsImported,
+		Updated: result.SnippetsUpdated,
+		Skipped: result.SnippetsSkipped,
+		Items:   result.Items,
+		Errors:  result.Errors,
+	}
+	for _, item := range result.Items {
+		if item.Action == "failed" {
+			report.Failed++
+		}
+	}
+	if err := b.importReports.Create(ctx, report); err != nil {
+		b.logger.Warn("failed to save import report", "error", err)
+		return
+	}
+	result.ReportID = report.ID
+}
+
+// planImport reports what Import would do with the given backup without writing anything
+func (b *BackupService) planImport(ctx context.Context, data *models.BackupData, opts models.ImportOptions) (*models.ImportResult, error) {
+	plan := &models.ImportPlan{}
+	tagNames := make(map[string]bool)
+	folderNames := make(map[string]bool)
+	index := newSnippetImportIndex(nil)
+
+	// A replace import starts from an empty database, so existing data never conflicts
+	if opts.Strategy != "replace" {
+		existingTags, _ := b.tagRepo.List(ctx)
+		for _, tag := range existingTags {
+			tagNames[tag.Name] = true
+		}
+
+		existingFolders, _ := b.folderRepo.List(ctx)
+		for _, folder := range existingFolders {
+			folderNames[folder.Name] = true
+		}
+
+		existingSnippets, err := b.listAllSnippets(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list existing snippets: %w", err)
+		}
+		index = newSnippetImportIndex(existingSnippets)
+	}
+
+	for _, tag := range data.Tags {
+		if !tagNames[tag.Name] {
+			tagNames[tag.Name] = true
+			plan.TagsToCreate++
+		}
+	}
+
+	for _, folder := range data.Folders {
+		if !folderNames[folder.Name] {
+			folderNames[folder.Name] = true
+			plan.FoldersToCreate++
+		}
+	}
+
+	conflictStrategy := resolveConflictStrategy(opts)
+	for i := range data.Snippets {
+		snippet := &data.Snippets[i]
+
+		existing, matchedBy := index.match(snippet)
+		if existing == nil {
+			plan.SnippetsToCreate++
+			index.add(snippet)
+			continue
+		}
+
+		plan.Conflicts = append(plan.Conflicts, models.ImportConflict{
+			SourceID:   snippet.ID,
+			Title:      snippet.Title,
+			ExistingID: existing.ID,
+			MatchedBy:  matchedBy,
+		})
+
+		switch conflictStrategy {
+		case models.ConflictSkip:
+			plan.SnippetsToSkip++
+		case models.ConflictOverwrite:
+			plan.SnippetsToUpdate++
+		default:
+			plan.SnippetsToCreate++
+			index.add(snippet)
+		}
+	}
+
+	return &models.ImportResult{DryRun: true, Plan: plan}, nil
+}
+
+// decodeBackup decrypts (if needed) and parses a JSON or ZIP backup
+func (b *BackupService) decodeBackup(content []byte, password string) (*models.BackupData, error) {
+	// Decrypt if password provided
+	var err error
+	if password == "" && IsEncryptedBackup(content) {
+		return nil, ErrPasswordRequired
+	}
+	if password != "" {
+		content, err = b.decrypt(content, password)
+		if err != nil {
+			return nil, ErrDecryptionFailed
+		}
+	}
+
+	var data models.BackupData
+
+	// Try JSON first
+	if err := json.Unmarshal(content, &data); err != nil {
+		// Try ZIP
+		zr, zipErr := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		if zipErr != nil {
+			return nil, ErrInvalidBackupFormat
+		}
+
+		for _, f := range zr.File {
+			if f.Name == "metadata.json" {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, fmt.Errorf("failed to open metadata: %w", err)
+				}
+				if err := json.NewDecoder(rc).Decode(&data); err != nil {
+					_ = rc.Close()
+					return nil, fmt.Errorf("failed to decode metadata: %w", err)
+				}
+				_ = rc.Close()
+				break
+			}
+		}
+
+		if data.Version == "" {
+			return nil, ErrInvalidBackupFormat
+		}
+	}
+
+	if data.Version != "" {
+		if err := CheckBackupCompatibility(data.Version); err != nil {
+			return nil, err
+		}
+	}
+
+	return &data, nil
+}
+
+// listAllSnippets pages through every non-deleted snippet, archived ones included
+func (b *BackupService) listAllSnippets(ctx context.Context) ([]models.Snippet, error) {
+	return listAllSnippets(ctx, b.snippetSvc)
+}
+
+// listAllSnippets pages through every non-deleted snippet of a service, archived ones included
+func listAllSnippets(ctx context.Context, snippetSvc SnippetManager) ([]models.Snippet, error) {
+	var snippets []models.Snippet
+	for _, archived := range []bool{false, true} {
+		isArchived := archived
+		for page := 1; ; page++ {
+			resp, err := snippetSvc.List(ctx, models.SnippetFilter{
+				Page:       page,
+				Limit:      100,
+				IsArchived: &isArchived,
+			})
+			if err != nil {
+				return nil, err
+			}
+			snippets = append(snippets, resp.Data...)
+			if page >= resp.Pagination.TotalPages {
+				break
+			}
+		}
+	}
+	return snippets, nil
+}
+
+// snippetInputFromBackup converts a backup snippet into service input, remapping folders
+func (b *BackupService) snippetInputFromBackup(snippet *models.Snippet, folderMap map[int64]int64) *models.SnippetInput {
+	input := &models.SnippetInput{
+		Title:       snippet.Title,
+		Description: snippet.Description,
+		Content:     snippet.Content,
+		Language:    snippet.Language,
+		IsPublic:    snippet.IsPublic,
+		IsArchived:  snippet.IsArchived,
+		Tags:        []string{},
+	}
+
+	// Map tags
+	for _, tag := range snippet.Tags {
+		input.Tags = append(input.Tags, tag.Name)
+	}
+
+	// Map folder (use first folder if any)
+	if len(snippet.Folders) > 0 {
+		if newFolderID, ok := folderMap[snippet.Folders[0].ID]; ok {
+			input.FolderID = &newFolderID
+		}
+	}
+
+	// Map files
+	for _, file := range snippet.Files {
+		input.Files = append(input.Files, models.SnippetFileInput{
+			Filename: file.Filename,
+			Content:  file.Content,
+			Language: file.Language,
+			Note:     file.Note,
+		})
+	}
+
+	return input
+}
+
+// resolveConflictStrategy picks the conflict strategy, falling back to the legacy
+// behavior of the import strategy: merge and skip leave matches alone, replace
+// imports everything
+func resolveConflictStrategy(opts models.ImportOptions) string {
+	switch opts.ConflictStrategy {
+	case models.ConflictSkip, models.ConflictOverwrite, models.ConflictDuplicate:
+		return opts.ConflictStrategy
+	}
+	if opts.Strategy == "replace" {
+		return models.ConflictDuplicate
+	}
+	return models.ConflictSkip
+}
+
+// snippetImportIndex matches backup snippets against snippets that already exist
+type snippetImportIndex struct {
+	byID       map[string]*models.Snippet
+	byChecksum map[string]*models.Snippet
+	byTitle    map[string]*models.Snippet
+}
+
+// newSnippetImportIndex builds an index over the given snippets
+func newSnippetImportIndex(snippets []models.Snippet) *snippetImportIndex {
+	idx := &snippetImportIndex{
+		byID:       make(map[string]*models.Snippet),
+		byChecksum: make(map[string]*models.Snippet),
+		byTitle:    make(map[string]*models.Snippet),
+	}
+	for i := range snippets {
+		idx.add(&snippets[i])
+	}
+	return idx
+}
+
+// add registers a snippet with the index
+func (idx *snippetImportIndex) add(snippet *models.Snippet) {
+	if snippet.ID != "" {
+		idx.byID[snippet.ID] = snippet
+	}
+	if checksum, ok := importChecksum(snippet); ok {
+		idx.byChecksum[checksum] = snippet
+	}
+	idx.byTitle[snippet.Title] = snippet
+}
+
+// match returns the indexed snippet that conflicts with the given one and how it matched
+func (idx *snippetImportIndex) match(snippet *models.Snippet) (*models.Snippet, string) {
+	if existing, ok := idx.byID[snippet.ID]; ok && snippet.ID != "" {
+		return existing, "id"
+	}
+	if checksum, ok := importChecksum(snippet); ok {
+		if existing, ok := idx.byChecksum[checksum]; ok {
+			return existing, "checksum"
+		}
+	}
+	if existing, ok := idx.byTitle[snippet.Title]; ok {
+		return existing, "title"
+	}
+	return nil, ""
+}
+
+// importChecksum returns the snippet checksum, which only covers content stored in files
+func importChecksum(snippet *models.Snippet) (string, bool) {
+	if len(snippet.Files) == 0 {
+		return "", false
+	}
+	checksum, err := CalculateSnippetChecksum(snippet)
+	return checksum, err == nil
+}
+
 // createZipBackup creates a ZIP archive with snippets as individual files
 func (b *BackupService) createZipBackup(data models.BackupData) ([]byte, error) {
 	buf := new(bytes.Buffer)
@@ -392,19 +678,31 @@ func (b *BackupService) createZipBackup(data models.BackupData) ([]byte, error)
 	return buf.Bytes(), nil
 }
 
-// clearAllData removes all snippets, tags, and folders
+// clearAllData removes the snippets, tags and folders of the user in ctx,
+// or the workspace's when ctx acts in one. Other users' data is left alone.
 func (b *BackupService) clearAllData(ctx context.Context) error {
+	owner := "user_id = ? AND workspace_id = 0"
+	userID, _ := models.UserIDFromContext(ctx)
+	args := []interface{}{userID}
+	if ws := models.WorkspaceIDFromContext(ctx); ws != models.DefaultWorkspaceID {
+		owner = "workspace_id = ?"
+		args = []interface{}{ws}
+	}
 	queries := []string{
-		"DELETE FROM snippet_tags",
-		"DELETE FROM snippet_folders",
-		"DELETE FROM snippet_files",
-		"DELETE FROM snippets",
-		"DELETE FROM tags",
-		"DELETE FROM folders",
+		"DELETE FROM snippet_tags WHERE snippet_id IN (SELECT id FROM snippets WHERE " + owner + ")",
+		"DELETE FROM snippet_folders WHERE snippet_id IN (SELECT id FROM snippets WHERE " + owner + ")",
+		"DELETE FROM snippet_files WHERE snippet_id IN (SELECT id FROM snippets WHERE " + owner + ")",
+		"DELETE FROM snippets WHERE " + owner,
+		"DELETE FROM snippet_tags WHERE tag_id IN (SELECT id FROM tags WHERE " + owner + ")",
+		"DELETE FROM tags WHERE " + owner,
+		"UPDATE remote_sources SET folder_id = NULL WHERE folder_id IN (SELECT id FROM folders WHERE " + owner + ")",
+		"DELETE FROM snippet_folders WHERE folder_id IN (SELECT id FROM folders WHERE " + owner + ")",
+		"DELETE FROM folder_access WHERE folder_id IN (SELECT id FROM folders WHERE " + owner + ")",
+		"DELETE FROM folders WHERE " + owner,
 	}
 
 	for _, q := range queries {
-		if _, err := b.db.ExecContext(ctx, q); err != nil {
+		if _, err := b.db.ExecContext(ctx, q, args...); err != nil {
 			b.logger.Warn("failed to execute clear query", "query", q, "error", err)
 		}
 	}
@@ -468,57 +766,68 @@ func getExtension(lang string) string {
 	return "txt"
 }
 
-// deriveKey derives a 32-byte key from password using PBKDF2
-func (b *BackupService) deriveKey(password string) []byte {
-	return pbkdf2.Key([]byte(password), []byte(b.encryptionSalt), 100000, 32, sha256.New)
+// Backups encrypted with a password start with encryptedBackupMagic and the
+// random salt their key is derived with, so they can be restored on any
+// instance. Older ones have neither: their key is salted with the encryption
+// salt of the instance that wrote them.
+const (
+	encryptedBackupMagic = "SNIPOENC1"
+	backupSaltSize       = 16
+	backupKDFIterations  = 600000
+	legacyKDFIterations  = 100000
+)
+
+// IsEncryptedBackup reports whether content is a backup encrypted with a
+// password
+func IsEncryptedBackup(content []byte) bool {
+	return bytes.HasPrefix(content, []byte(encryptedBackupMagic))
 }
 
-// encrypt encrypts data using AES-256-GCM
+// encrypt seals data with AES-256-GCM under a key derived from password with
+// PBKDF2 and a fresh salt
 func (b *BackupService) encrypt(data []byte, password string) ([]byte, error) {
-	key := b.deriveKey(password)
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
+	salt := make([]byte, backupSaltSize)
+	if _, err := rand.Read(salt); err != nil {
 		return nil, err
 	}
+	key := pbkdf2.Key([]byte(password), salt, backupKDFIterations, 32, sha256.New)
 
-	gcm, err := cipher.NewGCM(block)
+	sealed, err := sealGCM(key, data)
 	if err != nil {
 		return nil, err
 	}
-
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := rand.Read(nonce); err != nil {
-		return nil, err
-	}
-
-	return gcm.Seal(nonce, nonce, data, nil), nil
+	out := make([]byte, 0, len(encryptedBackupMagic)+len(salt)+len(sealed))
+	out = append(out, encryptedBackupMagic...)
+	out = append(out, salt...)
+	return append(out, sealed...), nil
 }
 
-// decrypt decrypts data using AES-256-GCM
+// decrypt opens data sealed by encrypt, or by earlier versions with the
+// instance's encryption salt
 func (b *BackupService) decrypt(data []byte, password string) ([]byte, error) {
-	return decryptWithKey(data, b.deriveKey(password))
-}
-
-// decryptWithKey performs the actual AES-256-GCM decryption with a given key
-func decryptWithKey(data []byte, key []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
+	if !IsEncryptedBackup(data) {
+		key := pbkdf2.Key([]byte(password), []byte(b.encryptionSalt), legacyKDFIterations, 32, sha256.New)
+		return openGCM(key, data)
 	}
 
-	nonceSize := gcm.NonceSize()
-	if len(data) < nonceSize {
+	data = data[len(encryptedBackupMagic):]
+	if len(data) < backupSaltSize {
 		return nil, errors.New("ciphertext too short")
 	}
+	salt, sealed := data[:backupSaltSize], data[backupSaltSize:]
+	return openGCM(pbkdf2.Key([]byte(password), salt, backupKDFIterations, 32, sha256.New), sealed)
+}
 
-	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	return gcm.Open(nil, nonce, ciphertext, nil)
+// CheckBackupCompatibility reports ErrIncompatibleBackup for backups written
+// in a format version this version cannot restore. Versions sharing the
+// major number are compatible.
+func CheckBackupCompatibility(version string) error {
+	major, _, _ := strings.Cut(version, ".")
+	current, _, _ := strings.Cut(BackupVersion, ".")
+	if major != current {
+		return fmt.Errorf("%w: backup format %q, supported %s.x", ErrIncompatibleBackup, version, current)
+	}
+	return nil
 }
 
 // GetFilename generates a backup filename
@@ -550,7 +859,12 @@ func ValidateBackupFile(content []byte) (string, error) {
 		return "zip", nil
 	}
 
-	// Check for encrypted (starts with random bytes, so just check it's not empty)
+	if IsEncryptedBackup(content) {
+		return "encrypted", nil
+	}
+
+	// Backups encrypted by older versions start with random bytes, so just
+	// check it's not empty
 	if len(content) > 32 {
 		return "encrypted", nil
 	}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+func setupBackupService(t *testing.T) (*BackupService, *SnippetService, *sql.DB) {
+	t.Helper()
+	db := testutil.TestDB(t)
+	logger := testutil.TestLogger()
+	tagRepo := repository.NewTagRepository(db)
+	folderRepo := repository.NewFolderRepository(db)
+	fileRepo := repository.NewSnippetFileRepository(db)
+
+	snippetSvc := NewSnippetService(repository.NewSnippetRepository(db), logger).
+		WithTagRepo(tagRepo).
+		WithFolderRepo(folderRepo).
+		WithFileRepo(fileRepo)
+
+	return NewBackupService(db, snippetSvc, tagRepo, folderRepo, fileRepo, logger, "test-salt"), snippetSvc, db
+}
+
+func marshalBackup(t *testing.T, snippets ...models.Snippet) []byte {
+	t.Helper()
+	content, err := json.Marshal(models.BackupData{
+		Version:   BackupVersion,
+		CreatedAt: time.Now().UTC(),
+		Snippets:  snippets,
+		Tags:      []models.Tag{{ID: 1, Name: "imported"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal backup: %v", err)
+	}
+	return content
+}
+
+func TestBackupService_Import_DryRun(t *testing.T) {
+	backupSvc, snippetSvc, db := setupBackupService(t)
+	ctx := testutil.TestContext()
+
+	existing, err := snippetSvc.Create(ctx, &models.SnippetInput{
+		Title:    "Existing",
+		Content:  "echo hi",
+		Language: "bash",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	content := marshalBackup(t,
+		models.Snippet{ID: existing.ID, Title: "Renamed elsewhere", Content: "echo hi", Language: "bash"},
+		models.Snippet{ID: "other", Title: "Existing", Content: "different", Language: "bash"},
+		models.Snippet{ID: "new", Title: "Brand new", Content: "ls", Language: "bash"},
+	)
+
+	result, err := backupSvc.Import(ctx, content, models.ImportOptions{Strategy: "merge", DryRun: true})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if !result.DryRun || result.Plan == nil {
+		t.Fatalf("expected dry run result with plan, got %+v", result)
+	}
+	if result.Plan.SnippetsToCreate != 1 || result.Plan.SnippetsToSkip != 2 {
+		t.Errorf("expected 1 create and 2 skips, got %+v", result.Plan)
+	}
+	if result.Plan.TagsToCreate != 1 {
+		t.Errorf("expected 1 tag to create, got %d", result.Plan.TagsToCreate)
+	}
+	if len(result.Plan.Conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %d", len(result.Plan.Conflicts))
+	}
+	if result.Plan.Conflicts[0].MatchedBy != "id" || result.Plan.Conflicts[1].MatchedBy != "title" {
+		t.Errorf("unexpected conflict matches: %+v", result.Plan.Conflicts)
+	}
+
+	// Nothing should have been written
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM snippets").Scan(&count); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected dry run to leave 1 snippet, found %d", count)
+	}
+}
+
+func TestBackupService_Import_DryRunReplace(t *testing.T) {
+	backupSvc, snippetSvc, _ := setupBackupService(t)
+	ctx := testutil.TestContext()
+
+	if _, err := snippetSvc.Create(ctx, &models.SnippetInput{Title: "Existing", Content: "x"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	content := marshalBackup(t, models.Snippet{ID: "a", Title: "Existing", Content: "x"})
+
+	result, err := backupSvc.Import(ctx, content, models.ImportOptions{Strategy: "replace", DryRun: true})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Plan.SnippetsToCreate != 1 || len(result.Plan.Conflicts) != 0 {
+		t.Errorf("expected replace to create without conflicts, got %+v", result.Plan)
+	}
+}
+
+func TestBackupService_Import_ConflictStrategies(t *testing.T) {
+	tests := []struct {
+		strategy     string
+		wantAction   string
+		wantSnippets int
+		wantContent  string
+	}{
+		{models.ConflictSkip, "skipped", 1, "v1"},
+		{models.ConflictOverwrite, "updated", 1, "v2"},
+		{models.ConflictDuplicate, "created", 2, "v1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			backupSvc, snippetSvc, db := setupBackupService(t)
+			ctx := testutil.TestContext()
+
+			existing, err := snippetSvc.Create(ctx, &models.SnippetInput{Title: "Shared", Content: "v1"})
+			if err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+
+			content := marshalBackup(t, models.Snippet{ID: existing.ID, Title: "Shared", Content: "v2"})
+			result, err := backupSvc.Import(ctx, content, models.ImportOptions{
+				Strategy:         "merge",
+				ConflictStrategy: tt.strategy,
+			})
+			if err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+
+			if len(result.Items) != 1 {
+				t.Fatalf("expected 1 item result, got %d", len(result.Items))
+			}
+			item := result.Items[0]
+			if item.Action != tt.wantAction || item.MatchedBy != "id" {
+				t.Errorf("expected action %q matched by id, got %+v", tt.wantAction, item)
+			}
+
+			var count int
+			if err := db.QueryRow("SELECT COUNT(*) FROM snippets").Scan(&count); err != nil {
+				t.Fatalf("count failed: %v", err)
+			}
+			if count != tt.wantSnippets {
+				t.Errorf("expected %d snippets, got %d", tt.wantSnippets, count)
+			}
+
+			got, err := snippetSvc.GetByID(ctx, existing.ID)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			if got.Content != tt.wantContent {
+				t.Errorf("expected existing content %q, got %q", tt.wantContent, got.Content)
+			}
+		})
+	}
+}
+
+func TestBackupService_Import_SavesReport(t *testing.T) {
+	backupSvc, snippetSvc, db := setupBackupService(t)
+	reports := repository.NewImportReportRepository(db)
+	backupSvc.WithImportReports(reports)
+	ctx := testutil.TestContext()
+
+	existing, err := snippetSvc.Create(ctx, &models.SnippetInput{Title: "Shared", Content: "v1"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	content := marshalBackup(t,
+		models.Snippet{ID: existing.ID, Title: "Shared", Content: "v2"},
+		models.Snippet{ID: "new", Title: "Brand new", Content: "ls"},
+	)
+
+	result, err := backupSvc.Import(ctx, content, models.ImportOptions{Strategy: "merge", ConflictStrategy: models.ConflictSkip})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.ReportID == 0 {
+		t.Fatal("expected the import to return a report ID")
+	}
+
+	report, err := reports.GetByID(ctx, result.ReportID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if report.Source != models.ImportSourceBackup || report.Created != 1 || report.Skipped != 1 || report.Failed != 0 {
+		t.Errorf("unexpected report counts: %+v", report)
+	}
+	if len(report.Items) != 2 || report.Items[0].Action != "skipped" || report.Items[1].Action != "created" {
+		t.Errorf("expected the item results to be saved, got %+v", report.Items)
+	}
+
+	// Dry runs change nothing, so they leave no report
+	dryRun, err := backupSvc.Import(ctx, content, models.ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if dryRun.ReportID != 0 {
+		t.Errorf("expected no report for a dry run, got %d", dryRun.ReportID)
+	}
+}
+
+func TestBackupService_PasswordEncryption(t *testing.T) {
+	backupSvc, snippetSvc, _ := setupBackupService(t)
+	ctx := testutil.TestContext()
+
+	if _, err := snippetSvc.Create(ctx, &models.SnippetInput{Title: "Deploy keys", Content: "secret-value", Language: "bash"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	content, filename, err := backupSvc.Export(ctx, models.ExportOptions{Format: "json", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !strings.HasSuffix(filename, ".json.enc") {
+		t.Errorf("expected an .enc filename, got %q", filename)
+	}
+	if !IsEncryptedBackup(content) || bytes.Contains(content, []byte("secret-value")) {
+		t.Fatal("expected the backup to be encrypted")
+	}
+	if format, _ := ValidateBackupFile(content); format != "encrypted" {
+		t.Errorf("expected the backup to be recognised as encrypted, got %q", format)
+	}
+
+	// The salt travels with the backup, so another instance can restore it
+	otherSvc, otherSnippets, _ := setupBackupService(t)
+	otherSvc.encryptionSalt = "other-salt"
+	if _, err := otherSvc.Import(ctx, content, models.ImportOptions{Strategy: "merge"}); !errors.Is(err, ErrPasswordRequired) {
+		t.Errorf("expected ErrPasswordRequired without a password, got %v", err)
+	}
+	if _, err := otherSvc.Import(ctx, content, models.ImportOptions{Strategy: "merge", Password: "wrong"}); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("expected ErrDecryptionFailed for a wrong password, got %v", err)
+	}
+	if _, err := otherSvc.Import(ctx, content, models.ImportOptions{Strategy: "merge", Password: "correct horse"}); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	restored, err := otherSnippets.List(ctx, models.SnippetFilter{Page: 1, Limit: 10})
+	if err != nil || len(restored.Data) != 1 || restored.Data[0].Title != "Deploy keys" {
+		t.Errorf("expected the snippet to be restored, got %+v (err %v)", restored, err)
+	}
+}
+
+func TestBackupService_DecryptLegacy(t *testing.T) {
+	backupSvc, _, _ := setupBackupService(t)
+
+	// Backups from before the salt was stored in them
+	key := pbkdf2.Key([]byte("pw"), []byte("test-salt"), legacyKDFIterations, 32, sha256.New)
+	sealed, err := sealGCM(key, marshalBackup(t))
+	if err != nil {
+		t.Fatalf("sealGCM failed: %v", err)
+	}
+	if _, err := backupSvc.Import(testutil.TestContext(), sealed, models.ImportOptions{DryRun: true, Password: "pw"}); err != nil {
+		t.Errorf("expected a legacy backup to decrypt, got %v", err)
+	}
+}
+
+func TestBackupService_Import_ReplaceKeepsOtherUsers(t *testing.T) {
+	backupSvc, snippetSvc, db := setupBackupService(t)
+	users := repository.NewUserRepository(db)
+	folders := repository.NewFolderRepository(db)
+
+	alice, err := users.Create(testutil.TestContext(), "alice", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	bob, err := users.Create(testutil.TestContext(), "bob", "hash", false)
+	if err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	aliceCtx := models.WithUserID(testutil.TestContext(), alice.ID)
+	bobCtx := models.WithUserID(testutil.TestContext(), bob.ID)
+
+	folder, err := folders.Create(bobCtx, &models.FolderInput{Name: "Bob's folder"})
+	if err != nil {
+		t.Fatalf("Create folder failed: %v", err)
+	}
+	bobSnippet, err := snippetSvc.Create(bobCtx, &models.SnippetInput{Title: "Bob's", Content: "b", Tags: []string{"bob-tag"}, FolderID: &folder.ID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := snippetSvc.Create(aliceCtx, &models.SnippetInput{Title: "Alice's", Content: "a", Tags: []string{"alice-tag"}}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Alice's export only holds her own snippets; replacing with it must
+	// leave Bob's alone
+	content, _, err := backupSvc.Export(aliceCtx, models.ExportOptions{Format: "json"})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if _, err := backupSvc.Import(aliceCtx, content, models.ImportOptions{Strategy: "replace"}); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	got, err := snippetSvc.GetByID(bobCtx, bobSnippet.ID)
+	if err != nil {
+		t.Fatalf("expected bob's snippet to survive, got %v", err)
+	}
+	if len(got.Tags) != 1 || got.Tags[0].Name != "bob-tag" {
+		t.Errorf("expected bob's tag to survive, got %+v", got.Tags)
+	}
+	if _, err := folders.GetByID(bobCtx, folder.ID); err != nil {
+		t.Errorf("expected bob's folder to survive, got %v", err)
+	}
+	var owner int64
+	if err := db.QueryRow("SELECT user_id FROM snippets WHERE id = ?", bobSnippet.ID).Scan(&owner); err != nil || owner != bob.ID {
+		t.Errorf("expected bob to keep the snippet, got owner %d, %v", owner, err)
+	}
+
+	var aliceCount int
+	if err := db.QueryRow("SELECT COUNT(*) FROM snippets WHERE user_id = ?", alice.ID).Scan(&aliceCount); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if aliceCount != 1 {
+		t.Errorf("expected alice's snippet to be restored once, got %d", aliceCount)
+	}
+}
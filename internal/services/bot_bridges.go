@@ -0,0 +1,277 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf16"
+)
+
+// telegramBridge long-polls the Telegram Bot API. The cursor is the next
+// update_id to fetch.
+type telegramBridge struct {
+	client  *http.Client
+	baseURL string // API root with the /bot<token> path
+}
+
+type telegramEntity struct {
+	Type     string `json:"type"`
+	Offset   int    `json:"offset"` // In UTF-16 code units
+	Length   int    `json:"length"`
+	Language string `json:"language"`
+}
+
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Text     string           `json:"text"`
+		Entities []telegramEntity `json:"entities"`
+	} `json:"message"`
+}
+
+func (b *telegramBridge) Poll(ctx context.Context, cursor string) ([]botMessage, string, error) {
+	query := url.Values{}
+	query.Set("timeout", strconv.Itoa(int(botPollTimeout.Seconds())))
+	query.Set("allowed_updates", `["message"]`)
+	if cursor != "" {
+		query.Set("offset", cursor)
+	}
+
+	var updates []telegramUpdate
+	if err := b.call(ctx, http.MethodGet, "getUpdates?"+query.Encode(), nil, &updates); err != nil {
+		return nil, cursor, err
+	}
+
+	var messages []botMessage
+	for _, update := range updates {
+		cursor = strconv.FormatInt(update.UpdateID+1, 10)
+		if update.Message == nil || update.Message.Text == "" {
+			continue
+		}
+		msg := botMessage{
+			Chat: strconv.FormatInt(update.Message.Chat.ID, 10),
+			Text: update.Message.Text,
+		}
+		msg.Blocks, msg.Caption = telegramBlocks(update.Message.Text, update.Message.Entities)
+		messages = append(messages, msg)
+	}
+	return messages, cursor, nil
+}
+
+func (b *telegramBridge) Send(ctx context.Context, chat, text string) error {
+	return b.call(ctx, http.MethodPost, "sendMessage", map[string]string{"chat_id": chat, "text": text}, nil)
+}
+
+// call sends a Bot API request and decodes its result into out
+func (b *telegramBridge) call(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+"/"+path, reader)
+	if err != nil {
+		return errors.New("invalid telegram request")
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("telegram request failed: %w", redactURL(err))
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var envelope struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(&envelope); err != nil {
+		return fmt.Errorf("telegram returned status %d", resp.StatusCode)
+	}
+	if !envelope.OK {
+		return fmt.Errorf("telegram: %s", envelope.Description)
+	}
+	if out != nil {
+		return json.Unmarshal(envelope.Result, out)
+	}
+	return nil
+}
+
+// telegramBlocks extracts code blocks from the pre entities Telegram clients
+// send for ``` fences, falling back to fences left in the text
+func telegramBlocks(text string, entities []telegramEntity) ([]codeBlock, string) {
+	units := utf16.Encode([]rune(text))
+	var blocks []codeBlock
+	var outside []uint16
+	last := 0
+	for _, e := range entities {
+		if e.Type != "pre" || e.Offset < last || e.Offset+e.Length > len(units) {
+			continue
+		}
+		outside = append(outside, units[last:e.Offset]...)
+		outside = append(outside, '\n')
+		blocks = append(blocks, codeBlock{
+			Language: e.Language,
+			Code:     string(utf16.Decode(units[e.Offset : e.Offset+e.Length])),
+		})
+		last = e.Offset + e.Length
+	}
+	if len(blocks) == 0 {
+		return parseFences(text)
+	}
+	outside = append(outside, units[last:]...)
+	return blocks, captionFrom(string(utf16.Decode(outside)))
+}
+
+// matrixBridge uses the Matrix client-server sync API. The cursor is the
+// next_batch token; the first sync only records it, so history isn't
+// imported.
+type matrixBridge struct {
+	client       *http.Client
+	homeserver   string
+	token        string
+	allowedRooms []string // Invites to these rooms are accepted
+	userID       string   // The bot's own user, whose messages are skipped
+}
+
+type matrixSync struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []struct {
+					Type    string `json:"type"`
+					Sender  string `json:"sender"`
+					Content struct {
+						MsgType string `json:"msgtype"`
+						Body    string `json:"body"`
+					} `json:"content"`
+				} `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+		Invite map[string]json.RawMessage `json:"invite"`
+	} `json:"rooms"`
+}
+
+func (b *matrixBridge) Poll(ctx context.Context, cursor string) ([]botMessage, string, error) {
+	if b.userID == "" {
+		var whoami struct {
+			UserID string `json:"user_id"`
+		}
+		if err := b.call(ctx, http.MethodGet, "/_matrix/client/v3/account/whoami", nil, &whoami); err != nil {
+			return nil, cursor, err
+		}
+		b.userID = whoami.UserID
+	}
+
+	query := url.Values{}
+	query.Set("timeout", strconv.Itoa(int(botPollTimeout.Milliseconds())))
+	if cursor != "" {
+		query.Set("since", cursor)
+	} else {
+		query.Set("filter", `{"room":{"timeline":{"limit":1}}}`)
+	}
+
+	var sync matrixSync
+	if err := b.call(ctx, http.MethodGet, "/_matrix/client/v3/sync?"+query.Encode(), nil, &sync); err != nil {
+		return nil, cursor, err
+	}
+
+	for room := range sync.Rooms.Invite {
+		if !slices.Contains(b.allowedRooms, room) {
+			continue
+		}
+		if err := b.call(ctx, http.MethodPost, "/_matrix/client/v3/join/"+url.PathEscape(room), struct{}{}, nil); err != nil {
+			return nil, cursor, err
+		}
+	}
+
+	var messages []botMessage
+	if cursor != "" {
+		for room, joined := range sync.Rooms.Join {
+			for _, event := range joined.Timeline.Events {
+				if event.Type != "m.room.message" || event.Content.MsgType != "m.text" || event.Sender == b.userID {
+					continue
+				}
+				msg := botMessage{Chat: room, Text: event.Content.Body}
+				msg.Blocks, msg.Caption = parseFences(event.Content.Body)
+				messages = append(messages, msg)
+			}
+		}
+	}
+	return messages, sync.NextBatch, nil
+}
+
+func (b *matrixBridge) Send(ctx context.Context, chat, text string) error {
+	txnID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(chat) + "/send/m.room.message/" + txnID
+	return b.call(ctx, http.MethodPut, path, map[string]string{"msgtype": "m.notice", "body": text}, nil)
+}
+
+// call sends a client-server API request and decodes the response into out
+func (b *matrixBridge) call(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, b.homeserver+path, reader)
+	if err != nil {
+		return fmt.Errorf("invalid matrix request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("matrix request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		var matrixErr struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&matrixErr)
+		if matrixErr.Error == "" {
+			matrixErr.Error = http.StatusText(resp.StatusCode)
+		}
+		return fmt.Errorf("matrix: %s (status %d)", matrixErr.Error, resp.StatusCode)
+	}
+	if out != nil {
+		return json.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(out)
+	}
+	return nil
+}
+
+// redactURL drops the request URL from a client error, since Telegram puts
+// the bot token in the path
+func redactURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/outbound"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+const (
+	// botPollTimeout is how long a long poll waits for new messages
+	botPollTimeout = 25 * time.Second
+	// botIdleInterval is how often a disabled or failing bot checks again
+	botIdleInterval = 30 * time.Second
+	// botFindLimit caps the results of a /find reply
+	botFindLimit = 5
+)
+
+const botHelp = `Send a code block to save it as a snippet. Text outside the block becomes the title.
+/find <query> searches your snippets.`
+
+// BotService bridges a Telegram or Matrix bot to snipo: code blocks sent to
+// the bot become snippets and /find queries run the quick search. It polls
+// in the background while enabled.
+type BotService struct {
+	repo           *repository.BotRepository
+	snippets       *SnippetService
+	encryptionSvc  *EncryptionService
+	telegramAPIURL string
+	httpClient     *http.Client
+	logger         *slog.Logger
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+}
+
+// NewBotService creates a new bot service
+func NewBotService(
+	repo *repository.BotRepository,
+	snippets *SnippetService,
+	encryptionSvc *EncryptionService,
+	logger *slog.Logger,
+) *BotService {
+	return &BotService{
+		repo:           repo,
+		snippets:       snippets,
+		encryptionSvc:  encryptionSvc,
+		telegramAPIURL: "https://api.telegram.org",
+		httpClient:     outbound.NewClient(outbound.Options{Timeout: botPollTimeout + 15*time.Second, AllowPrivate: true}),
+		logger:         logger,
+	}
+}
+
+// WithTelegramAPIURL overrides the Telegram Bot API root
+func (s *BotService) WithTelegramAPIURL(url string) *BotService {
+	s.telegramAPIURL = strings.TrimRight(url, "/")
+	return s
+}
+
+// GetConfig returns the bot configuration, or the defaults when none is saved
+func (s *BotService) GetConfig(ctx context.Context) (*models.BotConfig, error) {
+	config, err := s.repo.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &models.BotConfig{Platform: models.BotPlatformTelegram, AllowedChats: []string{}, Tags: []string{}}
+	}
+	return config, nil
+}
+
+// UpdateConfig validates and saves the bot configuration, encrypting a new
+// token. The running bot picks the change up on its next poll.
+func (s *BotService) UpdateConfig(ctx context.Context, input *models.BotConfigInput) (*models.BotConfig, error) {
+	errs := validation.ValidateBotConfigInput(input)
+
+	existing, err := s.repo.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := &models.BotConfig{
+		Enabled:       input.Enabled,
+		Platform:      input.Platform,
+		HomeserverURL: input.HomeserverURL,
+		AllowedChats:  input.AllowedChats,
+		Tags:          input.Tags,
+	}
+	switch {
+	case input.Token != "":
+		if config.TokenEncrypted, err = s.encryptionSvc.Encrypt(input.Token); err != nil {
+			return nil, fmt.Errorf("failed to encrypt bot token: %w", err)
+		}
+	case existing != nil:
+		config.TokenEncrypted = existing.TokenEncrypted
+	}
+	if config.Enabled && config.TokenEncrypted == "" {
+		errs = append(errs, validation.ValidationError{Field: "token", Message: "A bot token is required to enable the bot"})
+	}
+	if errs.HasErrors() {
+		return nil, errs
+	}
+
+	if err := s.repo.SaveConfig(ctx, config); err != nil {
+		return nil, err
+	}
+	s.logger.Info("bot config updated", "platform", config.Platform, "enabled", config.Enabled)
+	return s.GetConfig(ctx)
+}
+
+// DeleteConfig removes the bot configuration, stopping the bot
+func (s *BotService) DeleteConfig(ctx context.Context) error {
+	return s.repo.DeleteConfig(ctx)
+}
+
+// Start polls for bot messages in the background until ctx is cancelled or
+// Stop is called
+func (s *BotService) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.running = true
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			active, err := s.poll(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				s.logger.Warn("bot poll failed", "error", err)
+				message := err.Error()
+				if err := s.repo.SetLastError(ctx, &message); err != nil {
+					s.logger.Warn("failed to record bot error", "error", err)
+				}
+			}
+			if active && err == nil {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(botIdleInterval):
+			}
+		}
+	}()
+}
+
+// Stop stops the background polling and waits for it to finish
+func (s *BotService) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.cancel()
+	s.running = false
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// poll fetches and handles one batch of messages. active is false when the
+// bot is not configured or disabled.
+func (s *BotService) poll(ctx context.Context) (active bool, err error) {
+	config, err := s.repo.GetConfig(ctx)
+	if err != nil || config == nil || !config.Enabled || config.TokenEncrypted == "" {
+		return false, err
+	}
+
+	bridge, err := s.bridge(config)
+	if err != nil {
+		return true, err
+	}
+	messages, cursor, err := bridge.Poll(ctx, config.Cursor)
+	if err != nil {
+		return true, err
+	}
+
+	for _, msg := range messages {
+		s.handle(ctx, config, bridge, msg)
+	}
+	if cursor != config.Cursor {
+		if err := s.repo.SetCursor(ctx, cursor); err != nil {
+			return true, err
+		}
+	}
+	if config.LastError != nil {
+		if err := s.repo.SetLastError(ctx, nil); err != nil {
+			s.logger.Warn("failed to clear bot error", "error", err)
+		}
+	}
+	return true, nil
+}
+
+func (s *BotService) bridge(config *models.BotConfig) (botBridge, error) {
+	token, err := s.encryptionSvc.Decrypt(config.TokenEncrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt bot token: %w", err)
+	}
+
+	switch config.Platform {
+	case models.BotPlatformTelegram:
+		return &telegramBridge{client: s.httpClient, baseURL: s.telegramAPIURL + "/bot" + token}, nil
+	case models.BotPlatformMatrix:
+		return &matrixBridge{client: s.httpClient, homeserver: config.HomeserverURL, token: token, allowedRooms: config.AllowedChats}, nil
+	}
+	return nil, fmt.Errorf("unknown bot platform %q", config.Platform)
+}
+
+// handle answers a message from an allowed chat. Messages from other chats
+// are logged with their chat ID, so an admin can allow it, and ignored.
+func (s *BotService) handle(ctx context.Context, config *models.BotConfig, bridge botBridge, msg botMessage) {
+	if !slices.Contains(config.AllowedChats, msg.Chat) {
+		s.logger.Info("ignoring bot message from a chat that isn't allowed", "platform", config.Platform, "chat", msg.Chat)
+		return
+	}
+
+	reply := s.reply(ctx, config, msg)
+	if err := bridge.Send(ctx, msg.Chat, reply); err != nil {
+		s.logger.Warn("failed to send bot reply", "chat", msg.Chat, "error", err)
+	}
+}
+
+func (s *BotService) reply(ctx context.Context, config *models.BotConfig, msg botMessage) string {
+	command, args, _ := strings.Cut(strings.TrimSpace(msg.Text), " ")
+	// Telegram appends the bot name to commands in groups: /find@snipo_bot
+	command, _, _ = strings.Cut(command, "@")
+
+	switch command {
+	case "/start", "/help":
+		return botHelp
+	case "/find":
+		return s.find(ctx, strings.TrimSpace(args))
+	}
+
+	if len(msg.Blocks) == 0 {
+		return "No code block found.\n\n" + botHelp
+	}
+
+	title := msg.Caption
+	if title == "" {
+		title = "From " + config.Platform + " " + time.Now().UTC().Format("2006-01-02 15:04")
+	}
+	var saved []string
+	for i, block := range msg.Blocks {
+		input := &models.SnippetInput{
+			Title:    title,
+			Content:  block.Code,
+			Language: strings.ToLower(block.Language),
+			Tags:     append([]string{}, config.Tags...),
+		}
+		if len(msg.Blocks) > 1 {
+			input.Title = fmt.Sprintf("%s (%d)", title, i+1)
+		}
+		if !validation.IsAllowedLanguage(input.Language) {
+			input.Language = "plaintext"
+		}
+
+		snippet, err := s.snippets.Create(ctx, input)
+		if err != nil {
+			var verrs validation.ValidationErrors
+			if errors.As(err, &verrs) {
+				return "Couldn't save the snippet: " + verrs.Error()
+			}
+			s.logger.Error("failed to create snippet from bot message", "error", err)
+			return "Couldn't save the snippet, please try again later."
+		}
+		saved = append(saved, fmt.Sprintf("• %s (%s) — %s", snippet.Title, snippet.Language, snippet.ID))
+	}
+	return "Saved:\n" + strings.Join(saved, "\n")
+}
+
+func (s *BotService) find(ctx context.Context, query string) string {
+	if query == "" {
+		return "Usage: /find <query>"
+	}
+	results, err := s.snippets.Search(ctx, query, botFindLimit)
+	if err != nil {
+		return "Search failed, please try again later."
+	}
+	if len(results) == 0 {
+		return "No snippets match " + query
+	}
+
+	lines := make([]string, len(results))
+	for i, result := range results {
+		lines[i] = fmt.Sprintf("• %s (%s) — %s", result.Title, result.Language, result.ID)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// botMessage is a text message received by the bot
+type botMessage struct {
+	Chat    string // Telegram chat ID or Matrix room ID
+	Text    string
+	Caption string // First line of text outside code blocks, used as title
+	Blocks  []codeBlock
+}
+
+type codeBlock struct {
+	Language string
+	Code     string
+}
+
+// botBridge talks to one chat platform
+type botBridge interface {
+	// Poll waits for messages after cursor and returns them with the cursor
+	// to continue from
+	Poll(ctx context.Context, cursor string) ([]botMessage, string, error)
+	Send(ctx context.Context, chat, text string) error
+}
+
+// parseFences splits markdown text into fenced code blocks and a caption
+// taken from the first line outside them
+func parseFences(text string) (blocks []codeBlock, caption string) {
+	var outside []string
+	var current *codeBlock
+	var code []string
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case current == nil && strings.HasPrefix(trimmed, "```"):
+			// A block opened and closed on one line: ```code```
+			if rest := strings.TrimPrefix(trimmed, "```"); len(rest) > 3 && strings.HasSuffix(rest, "```") {
+				blocks = append(blocks, codeBlock{Code: strings.TrimSuffix(rest, "```")})
+				continue
+			}
+			current = &codeBlock{Language: strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))}
+			code = nil
+		case current != nil && trimmed == "```":
+			current.Code = strings.Join(code, "\n")
+			blocks = append(blocks, *current)
+			current = nil
+		case current != nil:
+			code = append(code, line)
+		default:
+			outside = append(outside, line)
+		}
+	}
+	// An unclosed fence runs to the end of the message
+	if current != nil {
+		current.Code = strings.Join(code, "\n")
+		blocks = append(blocks, *current)
+	}
+
+	return blocks, captionFrom(strings.Join(outside, "\n"))
+}
+
+// captionFrom returns the first non-empty line of text, cut to a valid title
+func captionFrom(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			if runes := []rune(line); len(runes) > 200 {
+				line = string(runes[:200])
+			}
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+// fakeTelegram serves getUpdates from a queue and records sendMessage calls
+type fakeTelegram struct {
+	mu      sync.Mutex
+	updates []string // Raw update objects, consumed by offset
+	offsets []string
+	sent    map[string][]string
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/botsecret-token/getUpdates":
+		f.offsets = append(f.offsets, r.URL.Query().Get("offset"))
+		_, _ = w.Write([]byte(`{"ok":true,"result":[` + strings.Join(f.updates, ",") + `]}`))
+		f.updates = nil
+	case r.URL.Path == "/botsecret-token/sendMessage":
+		var body struct {
+			ChatID string `json:"chat_id"`
+			Text   string `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.sent[body.ChatID] = append(f.sent[body.ChatID], body.Text)
+		_, _ = w.Write([]byte(`{"ok":true,"result":{}}`))
+	default:
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"ok":false,"description":"Unauthorized"}`))
+	}
+}
+
+func TestBotService(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	logger := testutil.TestLogger()
+	snippets := NewSnippetService(repository.NewSnippetRepository(db), logger).
+		WithTagRepo(repository.NewTagRepository(db))
+	encryptionSvc, err := NewEncryptionService(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("NewEncryptionService failed: %v", err)
+	}
+
+	telegram := &fakeTelegram{sent: map[string][]string{}}
+	server := httptest.NewServer(telegram)
+	defer server.Close()
+	svc := NewBotService(repository.NewBotRepository(db), snippets, encryptionSvc, logger).
+		WithTelegramAPIURL(server.URL)
+
+	t.Run("rejects invalid config", func(t *testing.T) {
+		_, err := svc.UpdateConfig(ctx, &models.BotConfigInput{Enabled: true, Platform: "irc", Tags: []string{"a/b"}})
+		var verrs validation.ValidationErrors
+		if !errors.As(err, &verrs) || len(verrs) != 3 {
+			t.Errorf("expected 3 validation errors, got %v", err)
+		}
+	})
+
+	config, err := svc.UpdateConfig(ctx, &models.BotConfigInput{
+		Enabled:      true,
+		Platform:     models.BotPlatformTelegram,
+		Token:        "secret-token",
+		AllowedChats: []string{"42"},
+		Tags:         []string{"chat"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if !config.HasToken {
+		t.Fatal("expected the token to be stored")
+	}
+
+	telegram.updates = []string{
+		`{"update_id":10,"message":{"chat":{"id":42},"text":"Reverse a list\nxs[::-1]",
+			"entities":[{"type":"pre","offset":15,"length":8,"language":"Python"}]}}`,
+		`{"update_id":11,"message":{"chat":{"id":7},"text":"/find list"}}`,
+		`{"update_id":12,"message":{"chat":{"id":42},"text":"/find@snipo_bot reverse"}}`,
+	}
+	if active, err := svc.poll(ctx); !active || err != nil {
+		t.Fatalf("poll returned active=%v err=%v", active, err)
+	}
+
+	results, err := snippets.Search(ctx, "reverse", 10)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected 1 snippet from the bot, got %d (%v)", len(results), err)
+	}
+	snippet, err := snippets.GetByID(ctx, results[0].ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if snippet.Title != "Reverse a list" || snippet.Content != "xs[::-1]" || snippet.Language != "python" {
+		t.Errorf("unexpected snippet: %q %q %q", snippet.Title, snippet.Content, snippet.Language)
+	}
+	if len(snippet.Tags) != 1 || snippet.Tags[0].Name != "chat" {
+		t.Errorf("expected the configured tag, got %v", snippet.Tags)
+	}
+
+	if _, ok := telegram.sent["7"]; ok {
+		t.Error("expected no reply to a chat that isn't allowed")
+	}
+	replies := telegram.sent["42"]
+	if len(replies) != 2 || !strings.HasPrefix(replies[0], "Saved:") || !strings.Contains(replies[1], snippet.ID) {
+		t.Errorf("unexpected replies: %q", replies)
+	}
+
+	// The next poll continues after the handled updates
+	if _, err := svc.poll(ctx); err != nil {
+		t.Fatalf("second poll failed: %v", err)
+	}
+	if len(telegram.offsets) != 2 || telegram.offsets[0] != "" || telegram.offsets[1] != "13" {
+		t.Errorf("unexpected offsets: %q", telegram.offsets)
+	}
+
+	t.Run("keeps the token when none is given", func(t *testing.T) {
+		config, err := svc.UpdateConfig(ctx, &models.BotConfigInput{Enabled: true, Platform: models.BotPlatformTelegram, AllowedChats: []string{"42"}})
+		if err != nil || !config.HasToken {
+			t.Fatalf("expected the stored token to be kept, got %+v (%v)", config, err)
+		}
+	})
+
+	t.Run("records poll errors", func(t *testing.T) {
+		if _, err := svc.UpdateConfig(ctx, &models.BotConfigInput{Enabled: true, Platform: models.BotPlatformTelegram, Token: "wrong"}); err != nil {
+			t.Fatalf("UpdateConfig failed: %v", err)
+		}
+		_, err := svc.poll(ctx)
+		if err == nil || strings.Contains(err.Error(), "wrong") {
+			t.Errorf("expected an error without the token, got %v", err)
+		}
+	})
+}
+
+func TestParseFences(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		caption string
+		blocks  []codeBlock
+	}{
+		{"no fence", "just text", "just text", nil},
+		{"fence with language", "Title\n```go\nfmt.Println()\n```\nmore", "Title", []codeBlock{{"go", "fmt.Println()"}}},
+		{"inline fence", "```ls -la```", "", []codeBlock{{"", "ls -la"}}},
+		{"unclosed fence", "```\na\nb", "", []codeBlock{{"", "a\nb"}}},
+		{"two fences", "```a\n1\n```\n```b\n2\n```", "", []codeBlock{{"a", "1"}, {"b", "2"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks, caption := parseFences(tt.text)
+			if caption != tt.caption {
+				t.Errorf("expected caption %q, got %q", tt.caption, caption)
+			}
+			if len(blocks) != len(tt.blocks) {
+				t.Fatalf("expected %d blocks, got %+v", len(tt.blocks), blocks)
+			}
+			for i := range blocks {
+				if blocks[i] != tt.blocks[i] {
+					t.Errorf("block %d: expected %+v, got %+v", i, tt.blocks[i], blocks[i])
+				}
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/MohamedElashri/snipo/internal/models"
 )
@@ -20,11 +21,16 @@ func CalculateSnippetChecksum(snippet *models.Snippet) (string, error) {
 	}
 
 	for _, file := range snippet.Files {
-		data["files"] = append(data["files"].([]map[string]string), map[string]string{
+		f := map[string]string{
 			"filename": file.Filename,
 			"content":  file.Content,
 			"language": file.Language,
-		})
+		}
+		// Only hashed when set so checksums of files without notes don't change
+		if file.Note != "" {
+			f["note"] = file.Note
+		}
+		data["files"] = append(data["files"].([]map[string]string), f)
 	}
 
 	sortedFiles := data["files"].([]map[string]string)
@@ -72,3 +78,31 @@ func CalculateGistChecksum(gist *models.GistResponse) (string, error) {
 	hash := sha256.Sum256(jsonData)
 	return hex.EncodeToString(hash[:]), nil
 }
+
+// CalculateContentHash hashes only the code of a snippet: the content of each
+// file in order, or content when there are no files. Line endings and
+// surrounding whitespace are normalized so the same code pasted twice
+// matches. Returns "" when there is no code.
+func CalculateContentHash(content string, files []string) string {
+	parts := files
+	if len(parts) == 0 {
+		parts = []string{content}
+	}
+
+	h := sha256.New()
+	empty := true
+	for i, part := range parts {
+		part = strings.TrimSpace(strings.ReplaceAll(part, "\r\n", "\n"))
+		if part != "" {
+			empty = false
+		}
+		if i > 0 {
+			h.Write([]byte{0})
+		}
+		h.Write([]byte(part))
+	}
+	if empty {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -8,10 +8,19 @@ import (
 	"github.com/MohamedElashri/snipo/internal/repository"
 )
 
+// ExpiredTokenGrace is how long expired API tokens are kept before the
+// cleanup task deletes them, so they stay listed and can still be rotated
+const ExpiredTokenGrace = 7 * 24 * time.Hour
+
 // CleanupService handles background cleanup tasks
 type CleanupService struct {
-	snippetRepo *repository.SnippetRepository
-	logger      *slog.Logger
+	snippetRepo  *repository.SnippetRepository
+	tokenRepo    *repository.TokenRepository
+	activityRepo *repository.ActivityRepository
+	tagRepo      *repository.TagRepository
+	folderRepo   *repository.FolderRepository
+	reportRepo   *repository.ImportReportRepository
+	logger       *slog.Logger
 }
 
 // NewCleanupService creates a new cleanup service
@@ -22,6 +31,36 @@ func NewCleanupService(snippetRepo *repository.SnippetRepository, logger *slog.L
 	}
 }
 
+// WithTokenRepo enables deletion of expired API tokens
+func (s *CleanupService) WithTokenRepo(tokenRepo *repository.TokenRepository) *CleanupService {
+	s.tokenRepo = tokenRepo
+	return s
+}
+
+// WithActivityRepo enables pruning of old snippet activity
+func (s *CleanupService) WithActivityRepo(activityRepo *repository.ActivityRepository) *CleanupService {
+	s.activityRepo = activityRepo
+	return s
+}
+
+// WithTagRepo enables deletion of tags left in the trash
+func (s *CleanupService) WithTagRepo(tagRepo *repository.TagRepository) *CleanupService {
+	s.tagRepo = tagRepo
+	return s
+}
+
+// WithFolderRepo enables deletion of folders left in the trash
+func (s *CleanupService) WithFolderRepo(folderRepo *repository.FolderRepository) *CleanupService {
+	s.folderRepo = folderRepo
+	return s
+}
+
+// WithImportReportRepo enables pruning of old import reports
+func (s *CleanupService) WithImportReportRepo(reportRepo *repository.ImportReportRepository) *CleanupService {
+	s.reportRepo = reportRepo
+	return s
+}
+
 // Start starts the cleanup service periodic task
 func (s *CleanupService) Start(ctx context.Context) {
 	s.logger.Info("starting cleanup service")
@@ -60,6 +99,29 @@ func (s *CleanupService) cleanup(ctx context.Context) error {
 		s.logger.Info("cleaned up deleted snippets", "count", count)
 	}
 
+	// Tags and folders follow the same 30 days
+	if s.tagRepo != nil {
+		tagCount, err := s.tagRepo.CleanupDeleted(ctx, 30)
+		if err != nil {
+			return err
+		}
+
+		if tagCount > 0 {
+			s.logger.Info("cleaned up deleted tags", "count", tagCount)
+		}
+	}
+
+	if s.folderRepo != nil {
+		folderCount, err := s.folderRepo.CleanupDeleted(ctx, 30)
+		if err != nil {
+			return err
+		}
+
+		if folderCount > 0 {
+			s.logger.Info("cleaned up deleted folders", "count", folderCount)
+		}
+	}
+
 	// Auto-archive expired snippets
 	archivedCount, err := s.snippetRepo.AutoArchiveExpired(ctx)
 	if err != nil {
@@ -70,5 +132,47 @@ func (s *CleanupService) cleanup(ctx context.Context) error {
 		s.logger.Info("auto-archived expired snippets", "count", archivedCount)
 	}
 
+	if s.tokenRepo != nil {
+		tokenCount, err := s.tokenRepo.DeleteExpired(ctx, ExpiredTokenGrace)
+		if err != nil {
+			return err
+		}
+
+		if tokenCount > 0 {
+			s.logger.Info("deleted expired API tokens", "count", tokenCount)
+		}
+
+		usageCount, err := s.tokenRepo.PruneUsage(ctx)
+		if err != nil {
+			return err
+		}
+
+		if usageCount > 0 {
+			s.logger.Info("pruned API token usage", "count", usageCount)
+		}
+	}
+
+	if s.activityRepo != nil {
+		activityCount, err := s.activityRepo.Prune(ctx)
+		if err != nil {
+			return err
+		}
+
+		if activityCount > 0 {
+			s.logger.Info("pruned snippet activity", "count", activityCount)
+		}
+	}
+
+	if s.reportRepo != nil {
+		reportCount, err := s.reportRepo.Prune(ctx)
+		if err != nil {
+			return err
+		}
+
+		if reportCount > 0 {
+			s.logger.Info("pruned import reports", "count", reportCount)
+		}
+	}
+
 	return nil
 }
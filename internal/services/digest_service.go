@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/mail"
+	"github.com/MohamedElashri/snipo/internal/markdown"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+)
+
+// ErrNoDigestRecipients is returned when the digest has nobody to go to
+var ErrNoDigestRecipients = errors.New("no digest recipients configured")
+
+// digestJob names the digest in job_runs
+const digestJob = "digest"
+
+// digestMaxActivity caps the snippet changes listed in a digest
+const digestMaxActivity = 50
+
+// BackupLister lists S3 backups; satisfied by S3SyncService
+type BackupLister interface {
+	ListBackups(ctx context.Context) ([]models.S3BackupInfo, error)
+}
+
+// DigestService e-mails a periodic summary of snippet changes, gist sync
+// conflicts and backups to the recipients in settings
+type DigestService struct {
+	activityRepo *repository.ActivityRepository
+	gistSyncRepo *repository.GistSyncRepository
+	settingsRepo *repository.SettingsRepository
+	jobRunRepo   *repository.JobRunRepository
+	mu           sync.Mutex
+	backups      BackupLister // nil unless S3 is set up
+	sender       mail.Sender
+	interval     time.Duration
+	logger       *slog.Logger
+}
+
+// NewDigestService creates a digest service sending through sender every interval
+func NewDigestService(activityRepo *repository.ActivityRepository, gistSyncRepo *repository.GistSyncRepository, settingsRepo *repository.SettingsRepository, jobRunRepo *repository.JobRunRepository, sender mail.Sender, interval time.Duration, logger *slog.Logger) *DigestService {
+	if interval <= 0 {
+		interval = 7 * 24 * time.Hour
+	}
+	return &DigestService{
+		activityRepo: activityRepo,
+		gistSyncRepo: gistSyncRepo,
+		settingsRepo: settingsRepo,
+		jobRunRepo:   jobRunRepo,
+		sender:       sender,
+		interval:     interval,
+		logger:       logger,
+	}
+}
+
+// WithBackups includes the state of S3 backups in the digest, or leaves
+// them out again if backups is nil. It may be called while the job runs.
+func (s *DigestService) WithBackups(backups BackupLister) *DigestService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backups = backups
+	return s
+}
+
+// Start checks hourly whether a digest is due until ctx is cancelled
+func (s *DigestService) Start(ctx context.Context) {
+	s.logger.Info("starting digest job", "interval", s.interval)
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.RunOnce(ctx); err != nil {
+				s.logger.Error("digest failed", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunOnce sends the digest if it is switched on and the interval has passed
+// since the last one, reporting whether it was sent
+func (s *DigestService) RunOnce(ctx context.Context) (bool, error) {
+	enabled, err := s.settingsRepo.GetBool(ctx, "digest_enabled")
+	if err != nil || !enabled {
+		return false, err
+	}
+	last, err := s.jobRunRepo.LastRun(ctx, digestJob)
+	if err != nil {
+		return false, err
+	}
+	if last != nil && time.Since(*last) < s.interval {
+		return false, nil
+	}
+
+	result, err := s.Send(ctx)
+	if errors.Is(err, ErrNoDigestRecipients) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s.logger.Info("digest sent", "recipients", len(result.Recipients), "changes", len(result.Digest.Activity))
+	return true, nil
+}
+
+// Preview returns the digest that would be sent now
+func (s *DigestService) Preview(ctx context.Context) (*models.Digest, error) {
+	since, err := s.since(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Build(ctx, since)
+}
+
+// Send e-mails the digest to the configured recipients now, whether or not
+// the digest is switched on, and restarts the interval
+func (s *DigestService) Send(ctx context.Context) (*models.DigestSendResult, error) {
+	recipients, err := s.recipients(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return nil, ErrNoDigestRecipients
+	}
+
+	since, err := s.since(ctx)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := s.Build(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	appName, err := s.settingsRepo.GetString(ctx, "app_name")
+	if err != nil {
+		return nil, err
+	}
+	msg, err := composeDigest(digest, appName)
+	if err != nil {
+		return nil, err
+	}
+	msg.To = recipients
+	if err := s.sender.Send(ctx, msg); err != nil {
+		return nil, fmt.Errorf("failed to send digest: %w", err)
+	}
+
+	if err := s.jobRunRepo.MarkRun(ctx, digestJob, digest.Until); err != nil {
+		return nil, err
+	}
+	return &models.DigestSendResult{Recipients: recipients, Digest: digest}, nil
+}
+
+// Build gathers the digest covering since until now
+func (s *DigestService) Build(ctx context.Context, since time.Time) (*models.Digest, error) {
+	digest := &models.Digest{Since: since, Until: time.Now().UTC()}
+
+	activity, err := s.activityRepo.Feed(ctx, models.ActivityFilter{Since: &since, Limit: digestMaxActivity})
+	if err != nil {
+		return nil, err
+	}
+	digest.Activity = activity
+
+	conflicts, err := s.gistSyncRepo.ListConflicts(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	digest.Conflicts = len(conflicts)
+
+	s.mu.Lock()
+	lister := s.backups
+	s.mu.Unlock()
+	if lister != nil {
+		digest.Backup = &models.DigestBackup{}
+		backups, err := lister.ListBackups(ctx)
+		if err != nil {
+			// A broken bucket is worth reporting, not a reason to skip the digest
+			digest.Backup.Error = err.Error()
+		}
+		digest.Backup.Count = len(backups)
+		for _, backup := range backups {
+			if digest.Backup.LastBackupAt == nil || backup.LastModified.After(*digest.Backup.LastBackupAt) {
+				last := backup.LastModified
+				digest.Backup.LastBackupAt = &last
+			}
+		}
+	}
+
+	return digest, nil
+}
+
+// since returns when the last digest was sent, or one interval ago
+func (s *DigestService) since(ctx context.Context) (time.Time, error) {
+	last, err := s.jobRunRepo.LastRun(ctx, digestJob)
+	if err != nil || last != nil {
+		return last.UTC(), err
+	}
+	return time.Now().UTC().Add(-s.interval), nil
+}
+
+func (s *DigestService) recipients(ctx context.Context) ([]string, error) {
+	value, err := s.settingsRepo.GetString(ctx, "digest_recipients")
+	if err != nil {
+		return nil, err
+	}
+	var recipients []string
+	for _, address := range strings.Split(value, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			recipients = append(recipients, address)
+		}
+	}
+	return recipients, nil
+}
+
+// composeDigest writes the digest as markdown, sent as the plain text part
+// and rendered for the HTML part
+func composeDigest(digest *models.Digest, appName string) (*mail.Message, error) {
+	const day = "Jan 2, 2006"
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s digest\n\n", markdownEscape(appName))
+	fmt.Fprintf(&b, "What happened from %s to %s.\n\n", digest.Since.Format(day), digest.Until.Format(day))
+
+	b.WriteString("## Snippets\n\n")
+	if len(digest.Activity) == 0 {
+		b.WriteString("No snippets were changed.\n\n")
+	}
+	for _, item := range digest.Activity {
+		fmt.Fprintf(&b, "- %s", markdownEscape(item.Summary))
+		if item.Actor.Username != "" {
+			fmt.Fprintf(&b, " by %s", markdownEscape(item.Actor.Username))
+		}
+		fmt.Fprintf(&b, " (%s)\n", item.LastAt.Format(day))
+	}
+	if len(digest.Activity) > 0 {
+		b.WriteString("\n")
+	}
+	if len(digest.Activity) == digestMaxActivity {
+		fmt.Fprintf(&b, "Only the latest %d changes are listed.\n\n", digestMaxActivity)
+	}
+
+	b.WriteString("## Gist sync\n\n")
+	switch digest.Conflicts {
+	case 0:
+		b.WriteString("No conflicts are waiting to be resolved.\n\n")
+	case 1:
+		b.WriteString("**1 conflict** is waiting to be resolved.\n\n")
+	default:
+		fmt.Fprintf(&b, "**%d conflicts** are waiting to be resolved.\n\n", digest.Conflicts)
+	}
+
+	if backup := digest.Backup; backup != nil {
+		b.WriteString("## Backups\n\n")
+		switch {
+		case backup.Error != "":
+			fmt.Fprintf(&b, "**S3 backups could not be listed:** %s\n\n", markdownEscape(backup.Error))
+		case backup.LastBackupAt == nil:
+			b.WriteString("**There are no S3 backups yet.**\n\n")
+		default:
+			fmt.Fprintf(&b, "The latest S3 backup is from %s (%d stored).\n\n", backup.LastBackupAt.Format(day), backup.Count)
+		}
+	}
+
+	b.WriteString("---\n\nYou get this e-mail because your address is a digest recipient in the settings. " +
+		"Remove it there, or switch the digest off, to unsubscribe.\n")
+
+	text := b.String()
+	html, err := markdown.Render(text, models.MarkdownHTMLEscape)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render digest: %w", err)
+	}
+	return &mail.Message{
+		Subject: fmt.Sprintf("%s digest: %d snippet changes", appName, len(digest.Activity)),
+		Text:    text,
+		HTML:    html,
+	}, nil
+}
+
+// markdownEscape backslash-escapes the characters that would make text
+// render as markdown
+func markdownEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune("\\`*_[]<>#|~!", r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
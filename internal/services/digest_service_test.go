@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/mail"
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+type fakeSender struct {
+	sent []*mail.Message
+}
+
+func (f *fakeSender) Send(_ context.Context, msg *mail.Message) error {
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+type failingBackups struct{}
+
+func (failingBackups) ListBackups(context.Context) ([]models.S3BackupInfo, error) {
+	return nil, errors.New("bucket gone")
+}
+
+func TestDigestService(t *testing.T) {
+	db := testutil.TestDB(t)
+	settingsRepo := repository.NewSettingsRepository(db)
+	sender := &fakeSender{}
+	digest := NewDigestService(repository.NewActivityRepository(db), repository.NewGistSyncRepository(db),
+		settingsRepo, repository.NewJobRunRepository(db), sender, 24*time.Hour, testutil.TestLogger()).
+		WithBackups(failingBackups{})
+	ctx := context.Background()
+
+	snippets := NewSnippetService(repository.NewSnippetRepository(db), testutil.TestLogger()).
+		WithActivityRepo(repository.NewActivityRepository(db))
+	if _, err := snippets.Create(testutil.TestContext(), &models.SnippetInput{Title: "deploy_*.sh", Content: "echo hi", Language: "bash"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Switched off by default
+	if sent, err := digest.RunOnce(ctx); err != nil || sent {
+		t.Fatalf("expected no digest while switched off, got %v, %v", sent, err)
+	}
+	if _, err := digest.Send(ctx); !errors.Is(err, ErrNoDigestRecipients) {
+		t.Fatalf("expected ErrNoDigestRecipients, got %v", err)
+	}
+
+	if err := settingsRepo.Set(ctx, "digest_enabled", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := settingsRepo.Set(ctx, "digest_recipients", "a@example.com,b@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if sent, err := digest.RunOnce(ctx); err != nil || !sent {
+		t.Fatalf("expected a digest to be sent, got %v, %v", sent, err)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(sender.sent))
+	}
+	msg := sender.sent[0]
+	if strings.Join(msg.To, " ") != "a@example.com b@example.com" {
+		t.Errorf("unexpected recipients %v", msg.To)
+	}
+	for _, want := range []string{`deploy\_\*.sh`, "No conflicts", "could not be listed:** bucket gone", "unsubscribe"} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("expected the text to contain %q:\n%s", want, msg.Text)
+		}
+	}
+	if !strings.Contains(msg.HTML, "deploy_*.sh") || strings.Contains(msg.HTML, "<em>") {
+		t.Errorf("expected the snippet title rendered literally, got %s", msg.HTML)
+	}
+
+	// Not due again until the interval has passed
+	if sent, err := digest.RunOnce(ctx); err != nil || sent {
+		t.Fatalf("expected no second digest within the interval, got %v, %v", sent, err)
+	}
+	preview, err := digest.Preview(ctx)
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if len(preview.Activity) != 0 {
+		t.Errorf("expected nothing new since the last digest, got %+v", preview.Activity)
+	}
+}
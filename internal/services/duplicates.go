@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// maxDuplicateMatches caps the snippets FindDuplicates returns
+const maxDuplicateMatches = 10
+
+// DuplicateContentError is returned when the reject_duplicate_content setting
+// is on and a snippet with the same code already exists
+type DuplicateContentError struct {
+	Existing models.DuplicateMatch
+}
+
+func (e *DuplicateContentError) Error() string {
+	return fmt.Sprintf("snippet %s already has this content", e.Existing.ID)
+}
+
+// FindDuplicates returns existing snippets with the same title, ignoring
+// case, or the same code as input, content matches first
+func (s *SnippetService) FindDuplicates(ctx context.Context, input *models.DuplicateCheckInput) ([]models.DuplicateMatch, error) {
+	if err := s.backfillContentHashes(ctx); err != nil {
+		return nil, err
+	}
+	hash := inputContentHash(input.Content, input.Files)
+	return s.repo.FindDuplicates(ctx, strings.TrimSpace(input.Title), hash, maxDuplicateMatches)
+}
+
+// CheckDuplicateContent returns a DuplicateContentError pointing at the
+// existing snippet when reject_duplicate_content is on and input's code
+// already exists. Imports and explicit duplicates don't go through it.
+func (s *SnippetService) CheckDuplicateContent(ctx context.Context, input *models.SnippetInput) error {
+	if s.settingsRepo == nil {
+		return nil
+	}
+	settings, err := s.settingsRepo.Get(ctx)
+	if err != nil {
+		s.logger.Warn("failed to load duplicate content setting", "error", err)
+		return nil
+	}
+	if !settings.RejectDuplicateContent {
+		return nil
+	}
+
+	hash := inputContentHash(input.Content, input.Files)
+	if hash == "" {
+		return nil
+	}
+	if err := s.backfillContentHashes(ctx); err != nil {
+		return err
+	}
+	matches, err := s.repo.FindDuplicates(ctx, "", hash, 1)
+	if err != nil {
+		return err
+	}
+	if len(matches) > 0 {
+		return &DuplicateContentError{Existing: matches[0]}
+	}
+	return nil
+}
+
+// backfillContentHashes computes the content hash of snippets created or
+// changed since the last lookup; writes reset it rather than recomputing it
+func (s *SnippetService) backfillContentHashes(ctx context.Context) error {
+	ids, err := s.repo.ListMissingContentHashes(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		snippet, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if snippet == nil {
+			continue
+		}
+		if s.fileRepo != nil {
+			if snippet.Files, err = s.fileRepo.GetBySnippetID(ctx, id); err != nil {
+				return err
+			}
+		}
+
+		files := make([]string, len(snippet.Files))
+		for i, f := range snippet.Files {
+			files[i] = f.Content
+		}
+		if err := s.repo.UpdateContentHash(ctx, id, CalculateContentHash(snippet.Content, files)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func inputContentHash(content string, files []models.SnippetFileInput) string {
+	contents := make([]string, len(files))
+	for i, f := range files {
+		contents[i] = f.Content
+	}
+	return CalculateContentHash(content, contents)
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestSnippetService_FindDuplicates(t *testing.T) {
+	db := testutil.TestDB(t)
+	settingsRepo := repository.NewSettingsRepository(db)
+	service := NewSnippetService(repository.NewSnippetRepository(db), testutil.TestLogger()).
+		WithFileRepo(repository.NewSnippetFileRepository(db)).
+		WithSettingsRepo(settingsRepo)
+	ctx := testutil.TestContext()
+
+	multi, err := service.Create(ctx, &models.SnippetInput{
+		Title: "Build",
+		Files: []models.SnippetFileInput{{Filename: "Makefile", Content: "all:\r\n\tgo build\r\n"}},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	single, err := service.Create(ctx, &models.SnippetInput{Title: "Other", Content: "echo hi"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	matches, err := service.FindDuplicates(ctx, &models.DuplicateCheckInput{Title: "OTHER", Content: "all:\n\tgo build"})
+	if err != nil {
+		t.Fatalf("FindDuplicates failed: %v", err)
+	}
+	if len(matches) != 2 || matches[0].ID != multi.ID || matches[0].MatchedBy != "content" ||
+		matches[1].ID != single.ID || matches[1].MatchedBy != "title" {
+		t.Errorf("unexpected matches %+v", matches)
+	}
+
+	// Updating resets the hash, so the old content no longer matches
+	if _, err := service.Update(ctx, single.ID, &models.SnippetInput{Title: "Other", Content: "echo bye"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	input := &models.SnippetInput{Title: "New", Content: "echo hi"}
+	if err := service.CheckDuplicateContent(ctx, input); err != nil {
+		t.Errorf("expected no rejection while the setting is off, got %v", err)
+	}
+	if err := settingsRepo.Set(ctx, "reject_duplicate_content", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.CheckDuplicateContent(ctx, input); err != nil {
+		t.Errorf("expected updated content to no longer match, got %v", err)
+	}
+
+	input.Content = "echo bye"
+	var duplicate *DuplicateContentError
+	if err := service.CheckDuplicateContent(ctx, input); !errors.As(err, &duplicate) || duplicate.Existing.ID != single.ID {
+		t.Errorf("expected a duplicate of %s, got %v", single.ID, err)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 )
@@ -45,23 +47,58 @@ func (s *EncryptionService) Encrypt(plaintext string) (string, error) {
 		return "", nil
 	}
 
-	block, err := aes.NewCipher(s.key)
+	ciphertext, err := sealGCM(s.key, []byte(plaintext))
 	if err != nil {
-		return "", fmt.Errorf("failed to create cipher: %w", err)
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// EncryptBytes encrypts binary data with the primary key using AES-256-GCM.
+// The nonce is prepended to the ciphertext.
+func (s *EncryptionService) EncryptBytes(plaintext []byte) ([]byte, error) {
+	return sealGCM(s.key, plaintext)
+}
+
+// DecryptBytes decrypts data from EncryptBytes, trying the fallback keys if
+// the primary key doesn't match
+func (s *EncryptionService) DecryptBytes(data []byte) ([]byte, error) {
+	plaintext, err := openGCM(s.key, data)
+	if err == nil {
+		return plaintext, nil
+	}
+	for _, fallbackKey := range s.fallbackKeys {
+		if plaintext, fallbackErr := openGCM(fallbackKey, data); fallbackErr == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to decrypt: %w", err)
+}
+
+// KeyID identifies the primary key without revealing it, so data encrypted
+// with it can be matched to the key later
+func (s *EncryptionService) KeyID() string {
+	sum := sha256.Sum256(append([]byte("snipo-key-id-v1:"), s.key...))
+	return hex.EncodeToString(sum[:8])
+}
+
+func sealGCM(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
 	}
 
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", fmt.Errorf("failed to create GCM: %w", err)
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
 	}
 
 	nonce := make([]byte, gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", fmt.Errorf("failed to generate nonce: %w", err)
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
 }
 
 // Decrypt decrypts ciphertext using AES-256-GCM
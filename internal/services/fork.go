@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/outbound"
+)
+
+// Fork errors
+var (
+	ErrInvalidForkURL     = errors.New("invalid fork URL")
+	ErrForkSourceNotFound = errors.New("source snippet not found or not public")
+)
+
+// maxForkResponseSize caps the public snippet payload read from a remote instance
+const maxForkResponseSize = 10 << 20
+
+// forkPathPattern matches share pages (/s/{id}) and the public API (/api/v1/snippets/public/{id}),
+// optionally behind a base path
+var forkPathPattern = regexp.MustCompile(`^(.*?)/(?:s|api/v1/snippets/public)/([A-Za-z0-9_-]+)/?$`)
+
+// newForkClient returns the client used to fetch fork sources, which only
+// reaches public addresses unless allowPrivate is set
+func newForkClient(allowPrivate bool) *http.Client {
+	return outbound.NewClient(outbound.Options{Timeout: 15 * time.Second, AllowPrivate: allowPrivate})
+}
+
+// parseForkURL resolves a public snippet URL into its API endpoint, canonical share URL and ID
+func parseForkURL(raw string) (apiURL, shareURL, id string, err error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "", "", ErrInvalidForkURL
+	}
+
+	m := forkPathPattern.FindStringSubmatch(u.Path)
+	if m == nil {
+		return "", "", "", ErrInvalidForkURL
+	}
+
+	base := u.Scheme + "://" + u.Host + m[1]
+	id = m[2]
+	return base + "/api/v1/snippets/public/" + id, base + "/s/" + id, id, nil
+}
+
+// Fork copies a public snippet from a share page or public API URL, possibly on
+// another instance, and records its origin as provenance
+func (s *SnippetService) Fork(ctx context.Context, rawURL string) (*models.Snippet, error) {
+	apiURL, shareURL, sourceID, err := parseForkURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := fetchPublicSnippet(ctx, s.forkClient, apiURL)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &models.SnippetInput{
+		Title:       source.Title,
+		Description: source.Description,
+		Content:     source.Content,
+		Language:    source.Language,
+		IsPublic:    false, // Forks are private by default
+	}
+	for _, f := range source.Files {
+		input.Files = append(input.Files, models.SnippetFileInput{
+			Filename: f.Filename,
+			Content:  f.Content,
+			Language: f.Language,
+			Note:     f.Note,
+		})
+	}
+
+	snippet, err := s.Create(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	provenance := &models.Provenance{
+		SourceURL: shareURL,
+		SourceID:  sourceID,
+		ForkedAt:  time.Now().UTC(),
+	}
+	if err := s.repo.SetProvenance(ctx, snippet.ID, provenance); err != nil {
+		s.logger.Warn("failed to record fork provenance", "id", snippet.ID, "error", err)
+	} else {
+		snippet.Provenance = provenance
+	}
+
+	s.logger.Info("snippet forked", "id", snippet.ID, "source", shareURL)
+	return snippet, nil
+}
+
+// fetchPublicSnippet retrieves a snippet from a public snippet API endpoint
+func fetchPublicSnippet(ctx context.Context, client *http.Client, apiURL string) (*models.Snippet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch source snippet: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrForkSourceNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch source snippet: unexpected status code %d", resp.StatusCode)
+	}
+
+	var envelope struct {
+		Data *models.Snippet `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxForkResponseSize)).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode source snippet: %w", err)
+	}
+	if envelope.Data == nil || envelope.Data.Title == "" {
+		return nil, ErrForkSourceNotFound
+	}
+
+	return envelope.Data, nil
+}
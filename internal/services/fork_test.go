@@ -0,0 +1,97 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestParseForkURL(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantAPI   string
+		wantShare string
+		wantErr   bool
+	}{
+		{"https://snipo.example.com/s/abc123", "https://snipo.example.com/api/v1/snippets/public/abc123", "https://snipo.example.com/s/abc123", false},
+		{"https://example.com/snipo/s/abc123/", "https://example.com/snipo/api/v1/snippets/public/abc123", "https://example.com/snipo/s/abc123", false},
+		{"http://localhost:8080/api/v1/snippets/public/abc123", "http://localhost:8080/api/v1/snippets/public/abc123", "http://localhost:8080/s/abc123", false},
+		{"ftp://example.com/s/abc123", "", "", true},
+		{"https://example.com/snippets/abc123", "", "", true},
+		{"not a url", "", "", true},
+	}
+
+	for _, tt := range tests {
+		apiURL, shareURL, _, err := parseForkURL(tt.input)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidForkURL) {
+				t.Errorf("parseForkURL(%q) expected ErrInvalidForkURL, got %v", tt.input, err)
+			}
+			continue
+		}
+		if err != nil || apiURL != tt.wantAPI || shareURL != tt.wantShare {
+			t.Errorf("parseForkURL(%q) = %q, %q, %v", tt.input, apiURL, shareURL, err)
+		}
+	}
+}
+
+func TestSnippetService_Fork(t *testing.T) {
+	db := testutil.TestDB(t)
+	svc := NewSnippetService(repository.NewSnippetRepository(db), testutil.TestLogger()).
+		WithFileRepo(repository.NewSnippetFileRepository(db))
+	ctx := testutil.TestContext()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/snippets/public/src123" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": models.Snippet{
+				ID:       "src123",
+				Title:    "Upstream",
+				Content:  "echo upstream",
+				Language: "bash",
+				IsPublic: true,
+				Files:    []models.SnippetFile{{Filename: "run.sh", Content: "echo upstream", Language: "bash"}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	// The test server is on loopback, which forks can't reach by default
+	if _, err := svc.Fork(ctx, server.URL+"/s/src123"); !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("expected loopback fork to be blocked, got %v", err)
+	}
+	svc.WithPrivateNetworks(true)
+
+	snippet, err := svc.Fork(ctx, server.URL+"/s/src123")
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+
+	if snippet.IsPublic {
+		t.Error("expected fork to be private")
+	}
+	if len(snippet.Files) != 1 || snippet.Files[0].Filename != "run.sh" {
+		t.Errorf("expected forked files, got %+v", snippet.Files)
+	}
+
+	got, err := svc.GetByID(ctx, snippet.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.Provenance == nil || got.Provenance.SourceID != "src123" || got.Provenance.SourceURL != server.URL+"/s/src123" {
+		t.Errorf("unexpected provenance: %+v", got.Provenance)
+	}
+
+	if _, err := svc.Fork(ctx, server.URL+"/s/missing"); !errors.Is(err, ErrForkSourceNotFound) {
+		t.Errorf("expected ErrForkSourceNotFound, got %v", err)
+	}
+}
@@ -25,6 +25,23 @@ func SnippetToGistRequest(snippet *models.Snippet) (*models.GistRequest, error)
 		IsArchived: snippet.IsArchived,
 	}
 
+	// Languages that differ from the one the filename implies would be lost
+	// on pull, so they travel in the metadata along with notes
+	for _, file := range snippet.Files {
+		var fm models.SnipoFileMetadata
+		if file.Language != getLanguageFromFilename(file.Filename) {
+			fm.Language = file.Language
+		}
+		fm.Note = file.Note
+		if fm == (models.SnipoFileMetadata{}) {
+			continue
+		}
+		if metadata.Files == nil {
+			metadata.Files = make(map[string]models.SnipoFileMetadata)
+		}
+		metadata.Files[file.Filename] = fm
+	}
+
 	if len(snippet.Tags) > maxGistTopics {
 		metadata.TagsOverflow = make([]string, 0)
 		for i := maxGistTopics; i < len(snippet.Tags); i++ {
@@ -114,17 +131,38 @@ func GistToSnippet(gist *models.GistResponse, existingSnippet *models.Snippet) (
 		snippet.CreatedAt = existingSnippet.CreatedAt
 	}
 
+	// Files already in snipo keep their ID, language and note unless the
+	// metadata says otherwise
+	existingFiles := make(map[string]models.SnippetFile)
+	if existingSnippet != nil {
+		for _, f := range existingSnippet.Files {
+			existingFiles[f.Filename] = f
+		}
+	}
+
 	// Process files (skip metadata file if it exists for backward compatibility)
 	for filename, file := range gist.Files {
 		if filename == metadataFilename {
 			continue
 		}
 
-		language := getLanguageFromFilename(filename)
 		snippetFile := models.SnippetFile{
 			Filename: filename,
 			Content:  file.Content,
-			Language: language,
+			Language: getLanguageFromFilename(filename),
+		}
+		if existing, ok := existingFiles[filename]; ok {
+			snippetFile.ID = existing.ID
+			snippetFile.Language = existing.Language
+			snippetFile.Note = existing.Note
+		}
+		if metadata != nil {
+			if fm, ok := metadata.Files[filename]; ok {
+				if fm.Language != "" {
+					snippetFile.Language = fm.Language
+				}
+				snippetFile.Note = fm.Note
+			}
 		}
 		snippet.Files = append(snippet.Files, snippetFile)
 	}
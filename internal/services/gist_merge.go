@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MohamedElashri/snipo/internal/diff"
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// gistTitle returns the snippet title from a gist description, without the
+// embedded metadata
+func gistTitle(description string) string {
+	title, _, _ := strings.Cut(description, "\n[snipo:")
+	return title
+}
+
+// baseVersionOf returns the title and files of a gist as synced, the base
+// later edits on both sides are merged against
+func baseVersionOf(gist *models.GistResponse) *models.GistBaseVersion {
+	base := &models.GistBaseVersion{
+		Title: gistTitle(gist.Description),
+		Files: make(map[string]string, len(gist.Files)),
+	}
+	for filename, file := range gist.Files {
+		if filename != metadataFilename {
+			base.Files[filename] = file.Content
+		}
+	}
+	return base
+}
+
+// mergeChanges merges the edits made to a snippet and to its gist since they
+// were last synced, and syncs the result both ways. It reports false, having
+// changed nothing, when there is no base version to merge against or the
+// edits overlap, which leaves a conflict for the user.
+func (s *GistSyncService) mergeChanges(ctx context.Context, mapping *models.SnippetGistMapping) (bool, error) {
+	base, err := s.syncRepo.GetBaseVersion(ctx, mapping.ID)
+	if err != nil || base == nil {
+		return false, err
+	}
+
+	snippet, err := s.snippetRepo.GetByID(ctx, mapping.SnippetID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get snippet: %w", err)
+	}
+	if snippet == nil {
+		return false, fmt.Errorf("snippet not found")
+	}
+	files, err := s.fileRepo.GetBySnippetID(ctx, mapping.SnippetID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get snippet files: %w", err)
+	}
+	snippet.Files = files
+
+	ours, err := SnippetToGistRequest(snippet)
+	if err != nil {
+		return false, fmt.Errorf("failed to convert snippet to gist: %w", err)
+	}
+	gist, err := s.githubClient.GetGist(ctx, mapping.GistID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get gist: %w", err)
+	}
+
+	oursVersion := &models.GistBaseVersion{Title: gistTitle(ours.Description), Files: make(map[string]string, len(ours.Files))}
+	for filename, file := range ours.Files {
+		oursVersion.Files[filename] = file.Content
+	}
+	merged, ok := mergeVersions(base, oursVersion, baseVersionOf(gist))
+	if !ok {
+		return false, nil
+	}
+
+	// The merge is written to the snippet as a pull would write the gist,
+	// keeping the snippet's own metadata, then pushed. The mapping is only
+	// updated by the push, so if that fails both sides still count as
+	// changed and the merge is tried again.
+	_, metadata, _ := strings.Cut(ours.Description, "\n[snipo:")
+	gist.Description = merged.Title + "\n[snipo:" + metadata
+	gist.Files = make(map[string]models.GistFile, len(merged.Files))
+	for filename, content := range merged.Files {
+		gist.Files[filename] = models.GistFile{Content: content}
+	}
+	if _, err := s.writeGistToSnippet(ctx, mapping, gist); err != nil {
+		return false, err
+	}
+	if err := s.SyncSnippetToGist(ctx, mapping.SnippetID); err != nil {
+		return false, err
+	}
+
+	s.logSuccess(ctx, mapping.SnippetID, mapping.GistID, models.SyncOpMerge, "Merged changes from snipo and GitHub")
+	return true, nil
+}
+
+// mergeVersions merges the snippet's (ours) and the gist's (theirs) edits
+// since base, file by file. A file added on one side is kept, and one
+// deleted on one side is dropped unless the other side edited it. ok is
+// false if any edits overlap.
+func mergeVersions(base, ours, theirs *models.GistBaseVersion) (merged *models.GistBaseVersion, ok bool) {
+	title, ok := diff.Merge3(base.Title, ours.Title, theirs.Title)
+	if !ok {
+		return nil, false
+	}
+	merged = &models.GistBaseVersion{Title: title, Files: make(map[string]string)}
+
+	filenames := make(map[string]bool)
+	for _, files := range []map[string]string{base.Files, ours.Files, theirs.Files} {
+		for filename := range files {
+			filenames[filename] = true
+		}
+	}
+	for filename := range filenames {
+		baseContent, inBase := base.Files[filename]
+		oursContent, inOurs := ours.Files[filename]
+		theirsContent, inTheirs := theirs.Files[filename]
+		switch {
+		case inOurs && inTheirs:
+			content, ok := diff.Merge3(baseContent, oursContent, theirsContent)
+			if !ok {
+				return nil, false
+			}
+			merged.Files[filename] = content
+		case inOurs:
+			if !inBase {
+				merged.Files[filename] = oursContent
+			} else if oursContent != baseContent {
+				return nil, false
+			}
+		case inTheirs:
+			if !inBase {
+				merged.Files[filename] = theirsContent
+			} else if theirsContent != baseContent {
+				return nil, false
+			}
+		}
+	}
+
+	// A gist needs at least one file
+	if len(merged.Files) == 0 {
+		return nil, false
+	}
+	return merged, true
+}
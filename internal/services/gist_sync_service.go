@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,6 +11,16 @@ import (
 	"github.com/MohamedElashri/snipo/internal/repository"
 )
 
+var (
+	// ErrGistSyncDisabled is returned when gist sync is switched off
+	ErrGistSyncDisabled = errors.New("gist sync is not enabled")
+	// ErrGistNotMapped is returned for gists no snippet is synced with
+	ErrGistNotMapped = errors.New("no snippet is synced with this gist")
+	// ErrConflictNotFound is returned for conflicts that don't exist or
+	// belong to another user's snippets
+	ErrConflictNotFound = errors.New("conflict not found")
+)
+
 // GistSyncService handles gist synchronization operations
 type GistSyncService struct {
 	githubClient  *GitHubClient
@@ -42,6 +53,9 @@ func (s *GistSyncService) SyncSnippetToGist(ctx context.Context, snippetID strin
 	if err != nil {
 		return fmt.Errorf("failed to get snippet: %w", err)
 	}
+	if snippet == nil {
+		return fmt.Errorf("snippet not found")
+	}
 
 	// Load snippet files for multi-file snippets
 	files, err := s.fileRepo.GetBySnippetID(ctx, snippetID)
@@ -86,6 +100,9 @@ func (s *GistSyncService) SyncSnippetToGist(ctx context.Context, snippetID strin
 		if err := s.syncRepo.CreateMapping(ctx, mapping); err != nil {
 			return fmt.Errorf("failed to create mapping: %w", err)
 		}
+		if err := s.syncRepo.SaveBaseVersion(ctx, mapping.ID, baseVersionOf(gist)); err != nil {
+			return err
+		}
 
 		s.logSuccess(ctx, snippetID, gist.ID, models.SyncOpCreate, "Gist created successfully")
 	} else {
@@ -107,6 +124,12 @@ func (s *GistSyncService) SyncSnippetToGist(ctx context.Context, snippetID strin
 		checksum, _ := CalculateSnippetChecksum(snippet)
 		gistChecksum, _ := CalculateGistChecksum(gist)
 
+		// The base goes first: a mapping marked synced with an older base
+		// would merge later edits against the wrong version
+		if err := s.syncRepo.SaveBaseVersion(ctx, mapping.ID, baseVersionOf(gist)); err != nil {
+			return err
+		}
+
 		mapping.SnipoChecksum = checksum
 		mapping.GistChecksum = gistChecksum
 		mapping.SyncStatus = models.SyncStatusSynced
@@ -144,14 +167,51 @@ func (s *GistSyncService) SyncGistToSnippet(ctx context.Context, gistID string)
 		return fmt.Errorf("failed to get gist: %w", err)
 	}
 
+	updatedSnippet, err := s.writeGistToSnippet(ctx, mapping, gist)
+	if err != nil {
+		return err
+	}
+
+	if err := s.syncRepo.SaveBaseVersion(ctx, mapping.ID, baseVersionOf(gist)); err != nil {
+		return err
+	}
+
+	gistChecksum, _ := CalculateGistChecksum(gist)
+
+	mapping.SnipoChecksum = *updatedSnippet.Checksum
+	mapping.GistChecksum = gistChecksum
+	mapping.SyncStatus = models.SyncStatusSynced
+	mapping.ErrorMessage = nil
+	now := time.Now()
+	mapping.LastSyncedAt = &now
+
+	if err := s.syncRepo.UpdateMapping(ctx, mapping); err != nil {
+		return fmt.Errorf("failed to update mapping: %w", err)
+	}
+
+	s.logSuccess(ctx, mapping.SnippetID, gistID, models.SyncOpSync, "Snippet updated from gist")
+	return nil
+}
+
+// writeGistToSnippet updates the mapped snippet and its files with the
+// content of gist, leaving the mapping to the caller
+func (s *GistSyncService) writeGistToSnippet(ctx context.Context, mapping *models.SnippetGistMapping, gist *models.GistResponse) (*models.Snippet, error) {
+	gistID := gist.ID
 	existingSnippet, err := s.snippetRepo.GetByID(ctx, mapping.SnippetID)
 	if err != nil {
-		return fmt.Errorf("failed to get snippet: %w", err)
+		return nil, fmt.Errorf("failed to get snippet: %w", err)
+	}
+	if existingSnippet != nil {
+		files, err := s.fileRepo.GetBySnippetID(ctx, mapping.SnippetID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get snippet files: %w", err)
+		}
+		existingSnippet.Files = files
 	}
 
 	snippet, err := GistToSnippet(gist, existingSnippet)
 	if err != nil {
-		return fmt.Errorf("failed to convert gist to snippet: %w", err)
+		return nil, fmt.Errorf("failed to convert gist to snippet: %w", err)
 	}
 
 	snippetInput := &models.SnippetInput{
@@ -166,34 +226,32 @@ func (s *GistSyncService) SyncGistToSnippet(ctx context.Context, gistID string)
 
 	for _, file := range snippet.Files {
 		snippetInput.Files = append(snippetInput.Files, models.SnippetFileInput{
+			ID:       file.ID,
 			Filename: file.Filename,
 			Content:  file.Content,
 			Language: file.Language,
+			Note:     file.Note,
 		})
 	}
 
 	updatedSnippet, err := s.snippetRepo.Update(ctx, mapping.SnippetID, snippetInput)
 	if err != nil {
 		s.logError(ctx, mapping.SnippetID, gistID, models.SyncOpUpdate, err)
-		return fmt.Errorf("failed to update snippet: %w", err)
+		return nil, fmt.Errorf("failed to update snippet: %w", err)
 	}
 
-	checksum, _ := CalculateSnippetChecksum(updatedSnippet)
-	gistChecksum, _ := CalculateGistChecksum(gist)
-
-	mapping.SnipoChecksum = checksum
-	mapping.GistChecksum = gistChecksum
-	mapping.SyncStatus = models.SyncStatusSynced
-	mapping.ErrorMessage = nil
-	now := time.Now()
-	mapping.LastSyncedAt = &now
-
-	if err := s.syncRepo.UpdateMapping(ctx, mapping); err != nil {
-		return fmt.Errorf("failed to update mapping: %w", err)
+	files, err := s.fileRepo.SyncFiles(ctx, mapping.SnippetID, snippetInput.Files)
+	if err != nil {
+		s.logError(ctx, mapping.SnippetID, gistID, models.SyncOpUpdate, err)
+		return nil, fmt.Errorf("failed to update snippet files: %w", err)
 	}
+	updatedSnippet.Files = files
 
-	s.logSuccess(ctx, mapping.SnippetID, gistID, models.SyncOpSync, "Snippet updated from gist")
-	return nil
+	checksum, _ := CalculateSnippetChecksum(updatedSnippet)
+	_ = s.snippetRepo.UpdateChecksum(ctx, mapping.SnippetID, checksum)
+	updatedSnippet.Checksum = &checksum
+
+	return updatedSnippet, nil
 }
 
 // DetectChanges detects what changed between snippet and gist
@@ -219,9 +277,9 @@ func (s *GistSyncService) DetectChanges(ctx context.Context, snippetID string) (
 		return models.NoSync, fmt.Errorf("failed to get gist: %w", err)
 	}
 
-	currentSnipoChecksum, err := CalculateSnippetChecksum(snippet)
+	currentSnipoChecksum, err := s.snippetChecksum(ctx, snippet)
 	if err != nil {
-		return models.NoSync, fmt.Errorf("failed to calculate snippet checksum: %w", err)
+		return models.NoSync, err
 	}
 
 	currentGistChecksum, err := CalculateGistChecksum(gist)
@@ -244,6 +302,27 @@ func (s *GistSyncService) DetectChanges(ctx context.Context, snippetID string) (
 	return models.Conflict, nil
 }
 
+// snippetChecksum returns the checksum persisted on write, recomputing and
+// backfilling it only when the stored value is missing or was invalidated
+func (s *GistSyncService) snippetChecksum(ctx context.Context, snippet *models.Snippet) (string, error) {
+	if snippet.Checksum != nil && *snippet.Checksum != "" {
+		return *snippet.Checksum, nil
+	}
+
+	files, err := s.fileRepo.GetBySnippetID(ctx, snippet.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get snippet files: %w", err)
+	}
+	snippet.Files = files
+
+	checksum, err := CalculateSnippetChecksum(snippet)
+	if err != nil {
+		return "", fmt.Errorf("failed to calculate snippet checksum: %w", err)
+	}
+	_ = s.snippetRepo.UpdateChecksum(ctx, snippet.ID, checksum)
+	return checksum, nil
+}
+
 // SyncAll syncs all enabled mappings
 func (s *GistSyncService) SyncAll(ctx context.Context) (*models.SyncResult, error) {
 	startTime := time.Now()
@@ -266,49 +345,32 @@ func (s *GistSyncService) SyncAll(ctx context.Context) (*models.SyncResult, erro
 
 	result.TotalProcessed = len(mappings)
 
-	for _, mapping := range mappings {
-		direction, err := s.DetectChanges(ctx, mapping.SnippetID)
-		if err != nil {
+	// Once the rate limit runs out every further request would fail too, so
+	// the rest are left for the next sync. Mappings synced longest ago come
+	// first, so they are picked up then.
+	for i, mapping := range mappings {
+		direction, err := s.syncMapping(ctx, mapping)
+		switch {
+		case err != nil:
 			result.Errors++
-			result.ErrorMessages = append(result.ErrorMessages, fmt.Sprintf("snippet %s: %v", mapping.SnippetID, err))
-			continue
-		}
-
-		switch direction {
-		case models.NoSync:
+			result.ErrorMessages = append(result.ErrorMessages, err.Error())
+		case direction == models.Conflict:
+			result.Conflicts++
+		default:
 			result.Synced++
-		case models.SnipoToGist:
-			if err := s.SyncSnippetToGist(ctx, mapping.SnippetID); err != nil {
-				result.Errors++
-				result.ErrorMessages = append(result.ErrorMessages, fmt.Sprintf("snippet %s: %v", mapping.SnippetID, err))
-			} else {
-				result.Synced++
-			}
-		case models.GistToSnipo:
-			if err := s.SyncGistToSnippet(ctx, mapping.GistID); err != nil {
-				result.Errors++
-				result.ErrorMessages = append(result.ErrorMessages, fmt.Sprintf("gist %s: %v", mapping.GistID, err))
-			} else {
-				result.Synced++
-			}
-		case models.GistDeleted:
-			if err := s.handleGistDeleted(ctx, mapping); err != nil {
-				result.Errors++
-				result.ErrorMessages = append(result.ErrorMessages, fmt.Sprintf("deleted gist %s: %v", mapping.GistID, err))
-			} else {
-				result.Synced++
-			}
-		case models.Conflict:
-			if err := s.handleConflict(ctx, mapping); err != nil {
-				result.Errors++
-				result.ErrorMessages = append(result.ErrorMessages, fmt.Sprintf("conflict %s: %v", mapping.SnippetID, err))
-			} else {
-				result.Conflicts++
-			}
+		}
+		if IsRateLimited(err) {
+			result.Skipped = len(mappings) - i - 1
+			break
 		}
 	}
 
 	result.Duration = time.Since(startTime).String()
+	if result.RateLimit = s.githubClient.RateLimit(); result.RateLimit != nil {
+		if err := s.syncRepo.UpdateRateLimit(ctx, result.RateLimit); err != nil {
+			return nil, err
+		}
+	}
 	if err := s.syncRepo.UpdateLastFullSyncTime(ctx); err != nil {
 		return nil, fmt.Errorf("failed to update last full sync time: %w", err)
 	}
@@ -316,6 +378,69 @@ func (s *GistSyncService) SyncAll(ctx context.Context) (*models.SyncResult, erro
 	return result, nil
 }
 
+// SyncGist syncs the snippet mapped to gistID right away, for the GitHub
+// webhook. Changes are handled as SyncAll handles them, so overlapping edits
+// on both sides still become a conflict. Mappings with sync disabled are left alone.
+func (s *GistSyncService) SyncGist(ctx context.Context, gistID string) (models.SyncDirection, error) {
+	config, err := s.syncRepo.GetConfig(ctx)
+	if err != nil {
+		return models.NoSync, fmt.Errorf("failed to get config: %w", err)
+	}
+	if config == nil || !config.Enabled {
+		return models.NoSync, ErrGistSyncDisabled
+	}
+
+	mapping, err := s.syncRepo.GetMappingByGistID(ctx, gistID)
+	if err != nil {
+		return models.NoSync, fmt.Errorf("failed to get mapping: %w", err)
+	}
+	if mapping == nil {
+		return models.NoSync, ErrGistNotMapped
+	}
+	if !mapping.SyncEnabled {
+		return models.NoSync, nil
+	}
+
+	return s.syncMapping(ctx, mapping)
+}
+
+// syncMapping brings one mapping up to date in whichever direction it
+// changed, returning that direction. Edits on both sides are merged when they
+// don't overlap.
+func (s *GistSyncService) syncMapping(ctx context.Context, mapping *models.SnippetGistMapping) (models.SyncDirection, error) {
+	direction, err := s.DetectChanges(ctx, mapping.SnippetID)
+	if err != nil {
+		return direction, fmt.Errorf("snippet %s: %w", mapping.SnippetID, err)
+	}
+
+	switch direction {
+	case models.SnipoToGist:
+		if err := s.SyncSnippetToGist(ctx, mapping.SnippetID); err != nil {
+			return direction, fmt.Errorf("snippet %s: %w", mapping.SnippetID, err)
+		}
+	case models.GistToSnipo:
+		if err := s.SyncGistToSnippet(ctx, mapping.GistID); err != nil {
+			return direction, fmt.Errorf("gist %s: %w", mapping.GistID, err)
+		}
+	case models.GistDeleted:
+		if err := s.handleGistDeleted(ctx, mapping); err != nil {
+			return direction, fmt.Errorf("deleted gist %s: %w", mapping.GistID, err)
+		}
+	case models.Conflict:
+		merged, err := s.mergeChanges(ctx, mapping)
+		if err != nil {
+			return direction, fmt.Errorf("merge %s: %w", mapping.SnippetID, err)
+		}
+		if merged {
+			return models.Merged, nil
+		}
+		if err := s.handleConflict(ctx, mapping); err != nil {
+			return direction, fmt.Errorf("conflict %s: %w", mapping.SnippetID, err)
+		}
+	}
+	return direction, nil
+}
+
 // handleConflict handles a sync conflict
 func (s *GistSyncService) handleConflict(ctx context.Context, mapping *models.SnippetGistMapping) error {
 	snippet, err := s.snippetRepo.GetByID(ctx, mapping.SnippetID)
@@ -400,7 +525,7 @@ func (s *GistSyncService) ResolveConflict(ctx context.Context, conflictID int64,
 		return fmt.Errorf("failed to get conflict: %w", err)
 	}
 	if conflict == nil {
-		return fmt.Errorf("conflict not found")
+		return ErrConflictNotFound
 	}
 
 	switch resolution {
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
 )
 
 func TestCalculateSnippetChecksum(t *testing.T) {
@@ -240,6 +242,58 @@ func TestGistToSnippet(t *testing.T) {
 	})
 }
 
+func TestGistRoundTrip_FileSettings(t *testing.T) {
+	snippet := &models.Snippet{
+		ID:    "snippet-123",
+		Title: "Build",
+		Files: []models.SnippetFile{
+			{Filename: "Justfile", Content: "build:", Language: "makefile", Note: "run with just"},
+			{Filename: "main.go", Content: "package main", Language: "go"},
+		},
+	}
+
+	req, err := SnippetToGistRequest(snippet)
+	if err != nil {
+		t.Fatalf("failed to convert snippet: %v", err)
+	}
+	gist := &models.GistResponse{Description: req.Description, Files: req.Files}
+
+	t.Run("metadata restores overrides", func(t *testing.T) {
+		pulled, err := GistToSnippet(gist, nil)
+		if err != nil {
+			t.Fatalf("failed to convert gist: %v", err)
+		}
+		files := make(map[string]models.SnippetFile)
+		for _, f := range pulled.Files {
+			files[f.Filename] = f
+		}
+		if f := files["Justfile"]; f.Language != "makefile" || f.Note != "run with just" {
+			t.Errorf("Justfile settings lost: language %q, note %q", f.Language, f.Note)
+		}
+		if f := files["main.go"]; f.Language != "go" || f.Note != "" {
+			t.Errorf("main.go changed: language %q, note %q", f.Language, f.Note)
+		}
+	})
+
+	t.Run("existing files kept without metadata", func(t *testing.T) {
+		plain := &models.GistResponse{
+			Description: "Build",
+			Files:       map[string]models.GistFile{"Justfile": {Content: "build: test"}},
+		}
+		existing := &models.Snippet{ID: "snippet-123", Files: []models.SnippetFile{
+			{ID: 7, Filename: "Justfile", Language: "makefile", Note: "run with just"},
+		}}
+		pulled, err := GistToSnippet(plain, existing)
+		if err != nil {
+			t.Fatalf("failed to convert gist: %v", err)
+		}
+		f := pulled.Files[0]
+		if f.ID != 7 || f.Language != "makefile" || f.Note != "run with just" || f.Content != "build: test" {
+			t.Errorf("unexpected file %+v", f)
+		}
+	})
+}
+
 func TestGetLanguageFromFilename(t *testing.T) {
 	tests := []struct {
 		filename string
@@ -342,3 +396,422 @@ func TestDetectChangesLogic(t *testing.T) {
 		}
 	})
 }
+
+func TestSnippetService_PersistsChecksum(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	repo := repository.NewSnippetRepository(db)
+	svc := NewSnippetService(repo, testutil.TestLogger()).
+		WithFileRepo(repository.NewSnippetFileRepository(db))
+
+	created, err := svc.Create(ctx, &models.SnippetInput{
+		Title: "Checksummed",
+		Files: []models.SnippetFileInput{{Filename: "main.go", Content: "package main", Language: "go"}},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	stored, err := repo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	want, _ := CalculateSnippetChecksum(created)
+	if stored.Checksum == nil || *stored.Checksum != want {
+		t.Fatalf("expected stored checksum %q, got %v", want, stored.Checksum)
+	}
+
+	// Updating without files must still checksum the existing files
+	updated, err := svc.Update(ctx, created.ID, &models.SnippetInput{Title: "Renamed", Content: "package main"})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if len(updated.Files) != 1 {
+		t.Fatalf("expected existing file to be kept, got %d", len(updated.Files))
+	}
+	stored, _ = repo.GetByID(ctx, created.ID)
+	if stored.Checksum == nil || *stored.Checksum == want {
+		t.Errorf("expected checksum to change after update, got %v", stored.Checksum)
+	}
+
+	// Writes outside the service invalidate the stored checksum
+	if _, err := repo.ToggleArchive(ctx, created.ID); err != nil {
+		t.Fatalf("ToggleArchive failed: %v", err)
+	}
+	stored, _ = repo.GetByID(ctx, created.ID)
+	if stored.Checksum != nil {
+		t.Errorf("expected checksum to be cleared, got %q", *stored.Checksum)
+	}
+}
+
+// newGistSyncFixture wires a GistSyncService to a test database and a fake
+// GitHub API, with sync enabled and one snippet already synced to a gist
+func newGistSyncFixture(t *testing.T) (*GistSyncService, *SnippetService, *testutil.FakeGitHub, *repository.GistSyncRepository, *models.Snippet) {
+	t.Helper()
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+
+	snippetRepo := repository.NewSnippetRepository(db)
+	fileRepo := repository.NewSnippetFileRepository(db)
+	syncRepo := repository.NewGistSyncRepository(db)
+	snippetSvc := NewSnippetService(snippetRepo, testutil.TestLogger()).WithFileRepo(fileRepo)
+
+	github := testutil.NewFakeGitHub(t)
+	client := NewGitHubClient("ghp_test").WithBaseURL(github.URL())
+	svc := NewGistSyncService(client, snippetRepo, fileRepo, syncRepo, nil)
+
+	if err := syncRepo.CreateOrUpdateConfig(ctx, &models.GistSyncConfig{
+		Enabled:                    true,
+		SyncIntervalMinutes:        15,
+		ConflictResolutionStrategy: models.ConflictStrategyManual,
+	}); err != nil {
+		t.Fatalf("CreateOrUpdateConfig failed: %v", err)
+	}
+
+	snippet, err := snippetSvc.Create(ctx, &models.SnippetInput{
+		Title: "Synced",
+		Files: []models.SnippetFileInput{{Filename: "main.go", Content: "package main", Language: "go"}},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := svc.SyncSnippetToGist(ctx, snippet.ID); err != nil {
+		t.Fatalf("SyncSnippetToGist failed: %v", err)
+	}
+	return svc, snippetSvc, github, syncRepo, snippet
+}
+
+func gistIDFor(t *testing.T, syncRepo *repository.GistSyncRepository, snippetID string) string {
+	t.Helper()
+	mapping, err := syncRepo.GetMapping(testutil.TestContext(), snippetID)
+	if err != nil || mapping == nil {
+		t.Fatalf("expected a mapping for snippet %s, got %v (err %v)", snippetID, mapping, err)
+	}
+	return mapping.GistID
+}
+
+func TestGistSyncService_SyncAll(t *testing.T) {
+	ctx := testutil.TestContext()
+	svc, snippetSvc, github, syncRepo, snippet := newGistSyncFixture(t)
+	gistID := gistIDFor(t, syncRepo, snippet.ID)
+
+	if github.GistCount() != 1 {
+		t.Fatalf("expected 1 gist, got %d", github.GistCount())
+	}
+
+	t.Run("nothing changed", func(t *testing.T) {
+		result, err := svc.SyncAll(ctx)
+		if err != nil {
+			t.Fatalf("SyncAll failed: %v", err)
+		}
+		if result.Synced != 1 || result.Errors != 0 || result.Conflicts != 0 {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("snippet changed", func(t *testing.T) {
+		if _, err := snippetSvc.Update(ctx, snippet.ID, &models.SnippetInput{
+			Title:   "Synced",
+			Content: "package main",
+			Files:   []models.SnippetFileInput{{Filename: "main.go", Content: "package main // local", Language: "go"}},
+		}); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+
+		result, err := svc.SyncAll(ctx)
+		if err != nil {
+			t.Fatalf("SyncAll failed: %v", err)
+		}
+		if result.Synced != 1 || result.Errors != 0 {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+		if got := github.Gist(gistID).Files["main.go"].Content; got != "package main // local" {
+			t.Errorf("expected gist to receive local edit, got %q", got)
+		}
+	})
+
+	t.Run("gist changed", func(t *testing.T) {
+		github.EditFile(gistID, "main.go", "package main // remote")
+
+		result, err := svc.SyncAll(ctx)
+		if err != nil {
+			t.Fatalf("SyncAll failed: %v", err)
+		}
+		if result.Synced != 1 || result.Errors != 0 {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+		updated, err := snippetSvc.GetByID(ctx, snippet.ID)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if len(updated.Files) != 1 || updated.Files[0].Content != "package main // remote" {
+			t.Errorf("expected snippet to receive remote edit, got %+v", updated.Files)
+		}
+
+		// The pulled change must not bounce back as a local change
+		if _, err := svc.SyncAll(ctx); err != nil {
+			t.Fatalf("SyncAll failed: %v", err)
+		}
+		direction, _ := svc.DetectChanges(ctx, snippet.ID)
+		if direction != models.NoSync {
+			t.Errorf("expected no pending changes after pull, got %v", direction)
+		}
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		github.SetRateLimit(0)
+		defer github.SetRateLimit(-1)
+
+		result, err := svc.SyncAll(ctx)
+		if err != nil {
+			t.Fatalf("SyncAll failed: %v", err)
+		}
+		if result.Errors != 1 || len(result.ErrorMessages) != 1 {
+			t.Fatalf("expected the rate-limited mapping to be reported, got %+v", result)
+		}
+		if !strings.Contains(result.ErrorMessages[0], "rate limit") {
+			t.Errorf("expected rate limit error, got %q", result.ErrorMessages[0])
+		}
+	})
+
+	t.Run("gist deleted", func(t *testing.T) {
+		github.RemoveGist(gistID)
+
+		result, err := svc.SyncAll(ctx)
+		if err != nil {
+			t.Fatalf("SyncAll failed: %v", err)
+		}
+		if result.Synced != 1 {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		mapping, _ := syncRepo.GetMapping(ctx, snippet.ID)
+		if mapping != nil {
+			t.Error("expected mapping to be removed")
+		}
+		if kept, _ := snippetSvc.GetByID(ctx, snippet.ID); kept == nil {
+			t.Error("expected snippet to be preserved")
+		}
+	})
+}
+
+func TestGistSyncService_SyncAll_RateLimit(t *testing.T) {
+	ctx := testutil.TestContext()
+	svc, snippetSvc, github, syncRepo, _ := newGistSyncFixture(t)
+	for _, title := range []string{"Second", "Third"} {
+		snippet, err := snippetSvc.Create(ctx, &models.SnippetInput{Title: title, Content: "echo " + title, Language: "bash"})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if err := svc.SyncSnippetToGist(ctx, snippet.ID); err != nil {
+			t.Fatalf("SyncSnippetToGist failed: %v", err)
+		}
+	}
+
+	// Enough quota for the first mapping only
+	github.SetRateLimit(1)
+	defer github.SetRateLimit(-1)
+	before := github.Requests()
+
+	result, err := svc.SyncAll(ctx)
+	if err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+	if result.Synced != 1 || result.Errors != 1 || result.Skipped != 1 {
+		t.Errorf("expected 1 synced, 1 failed and 1 skipped, got %+v", result)
+	}
+	if requests := github.Requests() - before; requests != 2 {
+		t.Errorf("expected the sync to stop at the rate limit after 2 requests, got %d", requests)
+	}
+
+	config, err := syncRepo.GetConfig(ctx)
+	if err != nil {
+		t.Fatalf("GetConfig failed: %v", err)
+	}
+	if config.RateLimit == nil || config.RateLimit.Remaining != 0 || config.RateLimit.Reset.IsZero() {
+		t.Errorf("expected the spent quota to be saved, got %+v", config.RateLimit)
+	}
+}
+
+func TestGistSyncService_Conflict(t *testing.T) {
+	ctx := testutil.TestContext()
+	svc, snippetSvc, github, syncRepo, snippet := newGistSyncFixture(t)
+	gistID := gistIDFor(t, syncRepo, snippet.ID)
+
+	if _, err := snippetSvc.Update(ctx, snippet.ID, &models.SnippetInput{
+		Title:   "Synced",
+		Content: "package main",
+		Files:   []models.SnippetFileInput{{Filename: "main.go", Content: "package main // local", Language: "go"}},
+	}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	github.EditFile(gistID, "main.go", "package main // remote")
+
+	result, err := svc.SyncAll(ctx)
+	if err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+	if result.Conflicts != 1 {
+		t.Fatalf("expected a conflict, got %+v", result)
+	}
+
+	conflicts, err := syncRepo.ListConflicts(ctx, false)
+	if err != nil || len(conflicts) != 1 {
+		t.Fatalf("expected 1 unresolved conflict, got %d (err %v)", len(conflicts), err)
+	}
+	mapping, _ := syncRepo.GetMapping(ctx, snippet.ID)
+	if mapping.SyncStatus != models.SyncStatusConflict {
+		t.Errorf("expected mapping status %q, got %q", models.SyncStatusConflict, mapping.SyncStatus)
+	}
+
+	if err := svc.ResolveConflict(ctx, conflicts[0].ID, models.ConflictStrategySnipoWins); err != nil {
+		t.Fatalf("ResolveConflict failed: %v", err)
+	}
+	if got := github.Gist(gistID).Files["main.go"].Content; got != "package main // local" {
+		t.Errorf("expected local version to win, got %q", got)
+	}
+	mapping, _ = syncRepo.GetMapping(ctx, snippet.ID)
+	if mapping.SyncStatus != models.SyncStatusSynced {
+		t.Errorf("expected mapping status %q, got %q", models.SyncStatusSynced, mapping.SyncStatus)
+	}
+}
+
+func TestGistSyncService_Merge(t *testing.T) {
+	ctx := testutil.TestContext()
+	svc, snippetSvc, github, syncRepo, snippet := newGistSyncFixture(t)
+	gistID := gistIDFor(t, syncRepo, snippet.ID)
+
+	update := func(content string) {
+		t.Helper()
+		if _, err := snippetSvc.Update(ctx, snippet.ID, &models.SnippetInput{
+			Title:   "Synced",
+			Content: "package main",
+			Files:   []models.SnippetFileInput{{Filename: "main.go", Content: content, Language: "go"}},
+		}); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+	update("package main\n\nfunc a() {}\n\nfunc b() {}\n")
+	if err := svc.SyncSnippetToGist(ctx, snippet.ID); err != nil {
+		t.Fatalf("SyncSnippetToGist failed: %v", err)
+	}
+
+	update("package main\n\nfunc a() { /* local */ }\n\nfunc b() {}\n")
+	github.EditFile(gistID, "main.go", "package main\n\nfunc a() {}\n\nfunc b() { /* remote */ }\n")
+	github.EditFile(gistID, "README.md", "# Synced\n")
+
+	direction, err := svc.SyncGist(ctx, gistID)
+	if err != nil {
+		t.Fatalf("SyncGist failed: %v", err)
+	}
+	if direction != models.Merged {
+		t.Fatalf("expected the edits to be merged, got %v", direction)
+	}
+
+	want := "package main\n\nfunc a() { /* local */ }\n\nfunc b() { /* remote */ }\n"
+	gist := github.Gist(gistID)
+	if got := gist.Files["main.go"].Content; got != want {
+		t.Errorf("expected gist to have both edits, got %q", got)
+	}
+	if got := gist.Files["README.md"].Content; got != "# Synced\n" {
+		t.Errorf("expected the file added on GitHub to be kept, got %q", got)
+	}
+	merged, err := snippetSvc.GetByID(ctx, snippet.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	contents := make(map[string]string)
+	for _, file := range merged.Files {
+		contents[file.Filename] = file.Content
+	}
+	if contents["main.go"] != want || contents["README.md"] != "# Synced\n" {
+		t.Errorf("expected snippet to have both edits, got %+v", contents)
+	}
+	if direction, _ := svc.DetectChanges(ctx, snippet.ID); direction != models.NoSync {
+		t.Errorf("expected no pending changes after merging, got %v", direction)
+	}
+
+	t.Run("overlapping edits", func(t *testing.T) {
+		update("package main\n\nfunc a() { /* local again */ }\n\nfunc b() { /* remote */ }\n")
+		github.EditFile(gistID, "main.go", "package main\n\nfunc a() { /* remote again */ }\n\nfunc b() { /* remote */ }\n")
+
+		result, err := svc.SyncAll(ctx)
+		if err != nil {
+			t.Fatalf("SyncAll failed: %v", err)
+		}
+		if result.Conflicts != 1 {
+			t.Fatalf("expected a conflict, got %+v", result)
+		}
+		if got := github.Gist(gistID).Files["main.go"].Content; !strings.Contains(got, "remote again") {
+			t.Errorf("expected gist to be left alone, got %q", got)
+		}
+	})
+}
+
+func TestMergeVersions(t *testing.T) {
+	base := &models.GistBaseVersion{Title: "Title", Files: map[string]string{"a.go": "a\n", "b.go": "b\n"}}
+	tests := []struct {
+		name   string
+		ours   map[string]string
+		theirs map[string]string
+		want   map[string]string // nil for a conflict
+	}{
+		{
+			name:   "deleted on one side",
+			ours:   map[string]string{"a.go": "a\n"},
+			theirs: map[string]string{"a.go": "a\n", "b.go": "b\n"},
+			want:   map[string]string{"a.go": "a\n"},
+		},
+		{
+			name:   "deleted on one side and edited on the other",
+			ours:   map[string]string{"a.go": "a\n"},
+			theirs: map[string]string{"a.go": "a\n", "b.go": "b2\n"},
+		},
+		{
+			name:   "added on both sides",
+			ours:   map[string]string{"a.go": "a\n", "b.go": "b\n", "c.go": "c\n"},
+			theirs: map[string]string{"a.go": "a\n", "b.go": "b\n", "d.go": "d\n"},
+			want:   map[string]string{"a.go": "a\n", "b.go": "b\n", "c.go": "c\n", "d.go": "d\n"},
+		},
+		{
+			name:   "added differently on both sides",
+			ours:   map[string]string{"a.go": "a\n", "b.go": "b\n", "c.go": "ours\n"},
+			theirs: map[string]string{"a.go": "a\n", "b.go": "b\n", "c.go": "theirs\n"},
+		},
+		{
+			name:   "every file deleted",
+			ours:   map[string]string{"b.go": "b\n"},
+			theirs: map[string]string{"a.go": "a\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, ok := mergeVersions(base,
+				&models.GistBaseVersion{Title: "Title", Files: tt.ours},
+				&models.GistBaseVersion{Title: "Title", Files: tt.theirs})
+			if tt.want == nil {
+				if ok {
+					t.Errorf("expected a conflict, got %+v", merged.Files)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("expected the versions to merge")
+			}
+			if len(merged.Files) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, merged.Files)
+			}
+			for filename, content := range tt.want {
+				if merged.Files[filename] != content {
+					t.Errorf("%s: expected %q, got %q", filename, content, merged.Files[filename])
+				}
+			}
+		})
+	}
+
+	if _, ok := mergeVersions(base,
+		&models.GistBaseVersion{Title: "Ours", Files: base.Files},
+		&models.GistBaseVersion{Title: "Theirs", Files: base.Files}); ok {
+		t.Error("expected different titles to conflict")
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/MohamedElashri/snipo/internal/metrics"
+	"github.com/MohamedElashri/snipo/internal/models"
 	"github.com/MohamedElashri/snipo/internal/repository"
 )
 
@@ -15,6 +17,10 @@ type GistSyncWorker struct {
 	snippetRepo   *repository.SnippetRepository
 	fileRepo      *repository.SnippetFileRepository
 	encryptionSvc *EncryptionService
+	githubAPIURL  string
+	features      repository.FeatureFlags
+	runs          *metrics.CounterVec // nil unless metrics are enabled
+	snippets      *metrics.CounterVec
 	logger        *slog.Logger
 	stopCh        chan struct{}
 	wg            sync.WaitGroup
@@ -40,6 +46,28 @@ func NewGistSyncWorker(
 	}
 }
 
+// WithGitHubAPIURL overrides the GitHub API root used for syncing
+func (w *GistSyncWorker) WithGitHubAPIURL(url string) *GistSyncWorker {
+	w.githubAPIURL = url
+	return w
+}
+
+// WithFeatureFlags pauses automatic syncing while gist sync is switched off
+// in settings
+func (w *GistSyncWorker) WithFeatureFlags(flags repository.FeatureFlags) *GistSyncWorker {
+	w.features = flags
+	return w
+}
+
+// WithMetrics counts sync runs and their per-snippet results in reg
+func (w *GistSyncWorker) WithMetrics(reg *metrics.Registry) *GistSyncWorker {
+	w.runs = reg.Counter("snipo_gist_sync_runs_total",
+		"Automatic gist sync runs by result (ok or error).", "result")
+	w.snippets = reg.Counter("snipo_gist_sync_snippets_total",
+		"Snippets handled by automatic gist sync runs, by result (synced, conflict or error).", "result")
+	return w
+}
+
 // Start begins the background sync worker
 func (w *GistSyncWorker) Start(ctx context.Context) error {
 	w.mu.Lock()
@@ -98,6 +126,12 @@ func (w *GistSyncWorker) run(ctx context.Context) {
 
 // performSync executes a sync cycle
 func (w *GistSyncWorker) performSync(ctx context.Context) {
+	if w.features != nil {
+		if enabled, err := w.features.FeatureEnabled(ctx, models.FeatureGistSync); err != nil || !enabled {
+			return
+		}
+	}
+
 	config, err := w.syncRepo.GetConfig(ctx)
 	if err != nil {
 		w.logger.Error("failed to get sync config", "error", err)
@@ -129,22 +163,37 @@ func (w *GistSyncWorker) performSync(ctx context.Context) {
 		return
 	}
 
-	githubClient := NewGitHubClient(token)
+	githubClient := NewGitHubClient(token).WithBaseURL(w.githubAPIURL)
 	syncService := NewGistSyncService(githubClient, w.snippetRepo, w.fileRepo, w.syncRepo, w.encryptionSvc)
 
 	result, err := syncService.SyncAll(ctx)
 	if err != nil {
+		if w.runs != nil {
+			w.runs.Inc("error")
+		}
 		w.logger.Error("sync failed", "error", err)
 		return
 	}
+	if w.runs != nil {
+		w.runs.Inc("ok")
+		w.snippets.Add(float64(result.Synced), "synced")
+		w.snippets.Add(float64(result.Conflicts), "conflict")
+		w.snippets.Add(float64(result.Errors), "error")
+	}
 
 	w.logger.Info("automatic sync completed",
 		"total", result.TotalProcessed,
 		"synced", result.Synced,
 		"conflicts", result.Conflicts,
 		"errors", result.Errors,
+		"skipped", result.Skipped,
 		"duration", result.Duration,
 	)
+	if result.Skipped > 0 && result.RateLimit != nil {
+		w.logger.Warn("GitHub rate limit reached, remaining snippets sync next time",
+			"skipped", result.Skipped,
+			"reset", result.RateLimit.Reset)
+	}
 }
 
 // IsRunning returns whether the worker is currently running
@@ -3,19 +3,39 @@ package services
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/outbound"
 )
 
 const (
 	githubAPIBaseURL = "https://api.github.com"
 	githubAPIVersion = "2022-11-28"
+
+	// githubMaxRetries is how often a request is retried after a rate limit
+	// or server error
+	githubMaxRetries = 3
+	// githubRetryBase is the first backoff; it doubles with each retry
+	githubRetryBase = time.Second
+	// githubMaxRetryWait is the longest wait for a rate limit to reset.
+	// Longer ones fail with a RateLimitError instead of blocking the sync.
+	githubMaxRetryWait = time.Minute
+
+	// githubGistsPerPage is the largest page GitHub serves when listing gists
+	githubGistsPerPage = 100
 )
 
 // GistNotFoundError indicates a gist was not found (deleted or never existed)
@@ -33,25 +53,60 @@ func IsGistNotFound(err error) bool {
 	return errors.As(err, &notFound)
 }
 
-// GitHubClient handles GitHub API operations
+// RateLimitError indicates GitHub's rate limit is spent until Reset
+type RateLimitError struct {
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Reset.IsZero() {
+		return "GitHub API rate limit exceeded"
+	}
+	return fmt.Sprintf("GitHub API rate limit exceeded until %s", e.Reset.UTC().Format(time.RFC3339))
+}
+
+// IsRateLimited checks if an error is a RateLimitError
+func IsRateLimited(err error) bool {
+	var limited *RateLimitError
+	return errors.As(err, &limited)
+}
+
+// GitHubClient handles GitHub API operations. Requests that hit a rate
+// limit or a server error are retried with jittered backoff.
 type GitHubClient struct {
 	token      string
+	baseURL    string
 	httpClient *http.Client
+	sleep      func(ctx context.Context, d time.Duration) error
+
+	mu        sync.Mutex
+	rateLimit *models.GitHubRateLimit // As of the last response, nil before one reported it
 }
 
-// NewGitHubClient creates a new GitHub API client
+// NewGitHubClient creates a new GitHub API client. The API URL is set by
+// the operator and may be an internal GitHub Enterprise server, so private
+// addresses are allowed.
 func NewGitHubClient(token string) *GitHubClient {
 	return &GitHubClient{
-		token: token,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		token:      token,
+		baseURL:    githubAPIBaseURL,
+		httpClient: outbound.NewClient(outbound.Options{Timeout: 30 * time.Second, AllowPrivate: true}),
+		sleep:      sleepContext,
 	}
 }
 
+// WithBaseURL points the client at a different API root, such as GitHub
+// Enterprise or a test server. An empty URL keeps the default.
+func (c *GitHubClient) WithBaseURL(baseURL string) *GitHubClient {
+	if baseURL = strings.TrimRight(baseURL, "/"); baseURL != "" {
+		c.baseURL = baseURL
+	}
+	return c
+}
+
 // CreateGist creates a new gist
 func (c *GitHubClient) CreateGist(ctx context.Context, req *models.GistRequest) (*models.GistResponse, error) {
-	url := fmt.Sprintf("%s/gists", githubAPIBaseURL)
+	url := fmt.Sprintf("%s/gists", c.baseURL)
 
 	body, err := json.Marshal(req)
 	if err != nil {
@@ -65,7 +120,7 @@ func (c *GitHubClient) CreateGist(ctx context.Context, req *models.GistRequest)
 
 	c.setHeaders(httpReq)
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -86,7 +141,7 @@ func (c *GitHubClient) CreateGist(ctx context.Context, req *models.GistRequest)
 
 // UpdateGist updates an existing gist
 func (c *GitHubClient) UpdateGist(ctx context.Context, gistID string, req *models.GistRequest) (*models.GistResponse, error) {
-	url := fmt.Sprintf("%s/gists/%s", githubAPIBaseURL, gistID)
+	url := fmt.Sprintf("%s/gists/%s", c.baseURL, gistID)
 
 	body, err := json.Marshal(req)
 	if err != nil {
@@ -100,7 +155,7 @@ func (c *GitHubClient) UpdateGist(ctx context.Context, gistID string, req *model
 
 	c.setHeaders(httpReq)
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -124,7 +179,7 @@ func (c *GitHubClient) UpdateGist(ctx context.Context, gistID string, req *model
 
 // GetGist retrieves a gist by ID
 func (c *GitHubClient) GetGist(ctx context.Context, gistID string) (*models.GistResponse, error) {
-	url := fmt.Sprintf("%s/gists/%s", githubAPIBaseURL, gistID)
+	url := fmt.Sprintf("%s/gists/%s", c.baseURL, gistID)
 
 	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -133,7 +188,7 @@ func (c *GitHubClient) GetGist(ctx context.Context, gistID string) (*models.Gist
 
 	c.setHeaders(httpReq)
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -157,7 +212,7 @@ func (c *GitHubClient) GetGist(ctx context.Context, gistID string) (*models.Gist
 
 // DeleteGist deletes a gist
 func (c *GitHubClient) DeleteGist(ctx context.Context, gistID string) error {
-	url := fmt.Sprintf("%s/gists/%s", githubAPIBaseURL, gistID)
+	url := fmt.Sprintf("%s/gists/%s", c.baseURL, gistID)
 
 	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
@@ -166,7 +221,7 @@ func (c *GitHubClient) DeleteGist(ctx context.Context, gistID string) error {
 
 	c.setHeaders(httpReq)
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(httpReq)
 	if err != nil {
 		return fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -180,39 +235,81 @@ func (c *GitHubClient) DeleteGist(ctx context.Context, gistID string) error {
 	return nil
 }
 
-// ListGists retrieves all gists for the authenticated user
+// ListGists retrieves all gists for the authenticated user, following the
+// Link header through every page
 func (c *GitHubClient) ListGists(ctx context.Context) ([]*models.GistResponse, error) {
-	url := fmt.Sprintf("%s/gists", githubAPIBaseURL)
+	url := fmt.Sprintf("%s/gists?per_page=%d", c.baseURL, githubGistsPerPage)
+	seen := make(map[string]bool)
+
+	var gists []*models.GistResponse
+	for url != "" {
+		// The token goes with every request, so pages are only fetched from
+		// the API itself, and each once
+		if seen[url] || !strings.HasPrefix(url, c.baseURL+"/") {
+			return nil, fmt.Errorf("unexpected next page %q", url)
+		}
+		seen[url] = true
+
+		page, next, err := c.listGistsPage(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		gists = append(gists, page...)
+		url = next
+	}
 
+	return gists, nil
+}
+
+// listGistsPage fetches one page of gists and returns the URL of the next,
+// or "" on the last page
+func (c *GitHubClient) listGistsPage(ctx context.Context, url string) ([]*models.GistResponse, string, error) {
 	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	c.setHeaders(httpReq)
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, "", fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, "", fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
 	var gists []*models.GistResponse
 	if err := json.NewDecoder(resp.Body).Decode(&gists); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, "", fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return gists, nil
+	return gists, nextPageURL(resp.Header.Get("Link")), nil
+}
+
+// nextPageURL returns the rel="next" target of a Link header such as
+// `<https://api.github.com/gists?page=2>; rel="next", <...>; rel="last"`
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+		if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.ReplaceAll(strings.TrimSpace(param), " ", "") == `rel="next"` {
+				return strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+			}
+		}
+	}
+	return ""
 }
 
 // GetAuthenticatedUser retrieves the authenticated user's information
 func (c *GitHubClient) GetAuthenticatedUser(ctx context.Context) (string, error) {
-	url := fmt.Sprintf("%s/user", githubAPIBaseURL)
+	url := fmt.Sprintf("%s/user", c.baseURL)
 
 	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -221,7 +318,7 @@ func (c *GitHubClient) GetAuthenticatedUser(ctx context.Context) (string, error)
 
 	c.setHeaders(httpReq)
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(httpReq)
 	if err != nil {
 		return "", fmt.Errorf("failed to execute request (network error): %w", err)
 	}
@@ -242,6 +339,372 @@ func (c *GitHubClient) GetAuthenticatedUser(ctx context.Context) (string, error)
 	return user.Login, nil
 }
 
+// GetRepo retrieves a repository, returning nil if it does not exist
+func (c *GitHubClient) GetRepo(ctx context.Context, owner, name string) (*models.GitHubRepo, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s", c.baseURL, owner, name)
+
+	var repo models.GitHubRepo
+	status, err := c.doJSON(ctx, "GET", url, nil, &repo, http.StatusOK, http.StatusNotFound)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	return &repo, nil
+}
+
+// CreateRepo creates a repository for the authenticated user, initialized with an empty commit
+func (c *GitHubClient) CreateRepo(ctx context.Context, name, description string, private bool) (*models.GitHubRepo, error) {
+	url := fmt.Sprintf("%s/user/repos", c.baseURL)
+	body := map[string]interface{}{
+		"name":        name,
+		"description": description,
+		"private":     private,
+		"auto_init":   true,
+	}
+
+	var repo models.GitHubRepo
+	if _, err := c.doJSON(ctx, "POST", url, body, &repo, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// GetBranchHead returns the commit SHA a branch points to, or "" if the
+// repository has no commits yet
+func (c *GitHubClient) GetBranchHead(ctx context.Context, owner, repo, branch string) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/git/ref/heads/%s", c.baseURL, owner, repo, branch)
+
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	status, err := c.doJSON(ctx, "GET", url, nil, &ref, http.StatusOK, http.StatusConflict)
+	if err != nil {
+		return "", err
+	}
+	if status == http.StatusConflict {
+		return "", nil
+	}
+	return ref.Object.SHA, nil
+}
+
+// GetFile returns the content of a file on a branch, and false if the file
+// does not exist
+func (c *GitHubClient) GetFile(ctx context.Context, owner, repo, branch, path string) (string, bool, error) {
+	fileURL := fmt.Sprintf("%s/repos/%s/%s/contents/%s?ref=%s", c.baseURL, owner, repo, path, url.QueryEscape(branch))
+
+	var file struct {
+		Content string `json:"content"`
+	}
+	status, err := c.doJSON(ctx, "GET", fileURL, nil, &file, http.StatusOK, http.StatusNotFound)
+	if err != nil {
+		return "", false, err
+	}
+	if status == http.StatusNotFound {
+		return "", false, nil
+	}
+	content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to decode file: %w", err)
+	}
+	return string(content), true, nil
+}
+
+// CommitFiles writes files to a branch in a single commit and returns the new
+// commit SHA. Other files on the branch are kept, except those listed in
+// remove.
+func (c *GitHubClient) CommitFiles(ctx context.Context, owner, repo, branch, message string, files map[string]string, remove []string) (string, error) {
+	parentSHA, err := c.GetBranchHead(ctx, owner, repo, branch)
+	if err != nil {
+		return "", fmt.Errorf("failed to get branch head: %w", err)
+	}
+	if parentSHA == "" {
+		parentSHA, err = c.createFirstCommit(ctx, owner, repo, branch, message, files)
+		if err != nil {
+			return "", fmt.Errorf("failed to create first commit: %w", err)
+		}
+	}
+
+	var parent struct {
+		Tree struct {
+			SHA string `json:"sha"`
+		} `json:"tree"`
+	}
+	parentURL := fmt.Sprintf("%s/repos/%s/%s/git/commits/%s", c.baseURL, owner, repo, parentSHA)
+	if _, err := c.doJSON(ctx, "GET", parentURL, nil, &parent, http.StatusOK); err != nil {
+		return "", fmt.Errorf("failed to get head commit: %w", err)
+	}
+
+	entries := make([]map[string]interface{}, 0, len(files)+len(remove))
+	for path, content := range files {
+		entries = append(entries, map[string]interface{}{"path": path, "mode": "100644", "type": "blob", "content": content})
+	}
+	if len(remove) > 0 {
+		existing, err := c.treePaths(ctx, owner, repo, parent.Tree.SHA)
+		if err != nil {
+			return "", fmt.Errorf("failed to list files: %w", err)
+		}
+		// A null SHA deletes the path, which GitHub refuses for paths that
+		// are already gone
+		for _, path := range remove {
+			if _, ok := files[path]; !ok && existing[path] {
+				entries = append(entries, map[string]interface{}{"path": path, "mode": "100644", "type": "blob", "sha": nil})
+			}
+		}
+	}
+
+	var tree struct {
+		SHA string `json:"sha"`
+	}
+	treeURL := fmt.Sprintf("%s/repos/%s/%s/git/trees", c.baseURL, owner, repo)
+	treeBody := map[string]interface{}{"base_tree": parent.Tree.SHA, "tree": entries}
+	if _, err := c.doJSON(ctx, "POST", treeURL, treeBody, &tree, http.StatusCreated); err != nil {
+		return "", fmt.Errorf("failed to create tree: %w", err)
+	}
+
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	commitURL := fmt.Sprintf("%s/repos/%s/%s/git/commits", c.baseURL, owner, repo)
+	commitBody := map[string]interface{}{
+		"message": message,
+		"tree":    tree.SHA,
+		"parents": []string{parentSHA},
+	}
+	if _, err := c.doJSON(ctx, "POST", commitURL, commitBody, &commit, http.StatusCreated); err != nil {
+		return "", fmt.Errorf("failed to create commit: %w", err)
+	}
+
+	refURL := fmt.Sprintf("%s/repos/%s/%s/git/refs/heads/%s", c.baseURL, owner, repo, branch)
+	if _, err := c.doJSON(ctx, "PATCH", refURL, map[string]interface{}{"sha": commit.SHA}, nil, http.StatusOK); err != nil {
+		return "", fmt.Errorf("failed to update branch: %w", err)
+	}
+
+	return commit.SHA, nil
+}
+
+// createFirstCommit adds one of files to a repository without commits and
+// returns the new commit SHA. The Git data API refuses to work on an empty
+// repository, but the contents API can create its first commit.
+func (c *GitHubClient) createFirstCommit(ctx context.Context, owner, repo, branch, message string, files map[string]string) (string, error) {
+	path := "README.md"
+	if _, ok := files[path]; !ok {
+		paths := make([]string, 0, len(files))
+		for p := range files {
+			paths = append(paths, p)
+		}
+		if len(paths) == 0 {
+			return "", errors.New("no files to commit")
+		}
+		sort.Strings(paths)
+		path = paths[0]
+	}
+
+	var result struct {
+		Commit struct {
+			SHA string `json:"sha"`
+		} `json:"commit"`
+	}
+	contentsURL := fmt.Sprintf("%s/repos/%s/%s/contents/%s", c.baseURL, owner, repo, path)
+	body := map[string]interface{}{
+		"message": message,
+		"content": base64.StdEncoding.EncodeToString([]byte(files[path])),
+		"branch":  branch,
+	}
+	if _, err := c.doJSON(ctx, "PUT", contentsURL, body, &result, http.StatusCreated); err != nil {
+		return "", err
+	}
+	return result.Commit.SHA, nil
+}
+
+// treePaths returns the paths of all files in a tree
+func (c *GitHubClient) treePaths(ctx context.Context, owner, repo, treeSHA string) (map[string]bool, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/git/trees/%s?recursive=1", c.baseURL, owner, repo, treeSHA)
+
+	var tree struct {
+		Tree []struct {
+			Path string `json:"path"`
+			Type string `json:"type"`
+		} `json:"tree"`
+	}
+	if _, err := c.doJSON(ctx, "GET", url, nil, &tree, http.StatusOK); err != nil {
+		return nil, err
+	}
+	paths := make(map[string]bool, len(tree.Tree))
+	for _, entry := range tree.Tree {
+		if entry.Type == "blob" {
+			paths[entry.Path] = true
+		}
+	}
+	return paths, nil
+}
+
+// doJSON sends a request with an optional JSON body and decodes the response into out.
+// Any status outside expected is returned as an error.
+func (c *GitHubClient) doJSON(ctx context.Context, method, url string, body, out interface{}, expected ...int) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.setHeaders(httpReq)
+
+	resp, err := c.do(httpReq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	ok := false
+	for _, code := range expected {
+		if resp.StatusCode == code {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+
+	return resp.StatusCode, nil
+}
+
+// RateLimit returns the API quota GitHub reported on the last response, or
+// nil if none did yet
+func (c *GitHubClient) RateLimit() *models.GitHubRateLimit {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rateLimit == nil {
+		return nil
+	}
+	limit := *c.rateLimit
+	return &limit
+}
+
+// do sends req, retrying rate-limited requests and server errors. A rate
+// limit that resets too far ahead to wait for is returned as a
+// RateLimitError.
+func (c *GitHubClient) do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		c.recordRateLimit(resp.Header)
+
+		wait, limited, retry := retryDelay(resp, attempt)
+		if !retry {
+			return resp, nil
+		}
+		if wait > githubMaxRetryWait || attempt == githubMaxRetries {
+			if !limited {
+				return resp, nil
+			}
+			_ = resp.Body.Close()
+			return nil, &RateLimitError{Reset: rateLimitReset(resp.Header)}
+		}
+
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if err := c.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryDelay decides whether a response is worth retrying and after how
+// long. GitHub answers 403 or 429 when a rate limit is hit, with
+// Retry-After for secondary limits and X-RateLimit-Reset for the hourly
+// quota; other 403s are permission errors and final.
+func retryDelay(resp *http.Response, attempt int) (wait time.Duration, limited, retry bool) {
+	switch {
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		limited = resp.StatusCode == http.StatusTooManyRequests ||
+			resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0"
+		if !limited {
+			return 0, false, false
+		}
+	case resp.StatusCode >= 500:
+	default:
+		return 0, false, false
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, limited, true
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset := rateLimitReset(resp.Header); !reset.IsZero() {
+			return time.Until(reset) + time.Second, limited, true
+		}
+	}
+
+	// Exponential backoff with jitter, so parallel clients spread out
+	backoff := githubRetryBase << attempt
+	return backoff/2 + rand.N(backoff/2+1), limited, true
+}
+
+// rateLimitReset reads X-RateLimit-Reset, a Unix time, or returns zero
+func rateLimitReset(header http.Header) time.Time {
+	seconds, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// recordRateLimit keeps the quota a response reports
+func (c *GitHubClient) recordRateLimit(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit := &models.GitHubRateLimit{Remaining: remaining, Reset: rateLimitReset(header), CheckedAt: time.Now().UTC()}
+	limit.Limit, _ = strconv.Atoi(header.Get("X-RateLimit-Limit"))
+
+	c.mu.Lock()
+	c.rateLimit = limit
+	c.mu.Unlock()
+}
+
+// sleepContext waits for d, or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 // setHeaders sets common headers for GitHub API requests
 func (c *GitHubClient) setHeaders(req *http.Request) {
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
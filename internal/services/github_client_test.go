@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestGitHubClient_Retries(t *testing.T) {
+	// respond answers each request with the next of statuses, repeating the last
+	respond := func(t *testing.T, statuses []int, header func(w http.ResponseWriter, status int)) (*GitHubClient, *atomic.Int32, *[]time.Duration) {
+		t.Helper()
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := int(calls.Add(1)) - 1
+			status := statuses[min(n, len(statuses)-1)]
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(100-n))
+			if header != nil {
+				header(w, status)
+			}
+			w.WriteHeader(status)
+			if status == http.StatusOK {
+				_, _ = w.Write([]byte(`{"login":"octocat"}`))
+			}
+		}))
+		t.Cleanup(server.Close)
+
+		var waits []time.Duration
+		client := NewGitHubClient("token").WithBaseURL(server.URL)
+		client.sleep = func(ctx context.Context, d time.Duration) error {
+			waits = append(waits, d)
+			return nil
+		}
+		return client, &calls, &waits
+	}
+
+	t.Run("server errors are retried with backoff", func(t *testing.T) {
+		client, calls, waits := respond(t, []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK}, nil)
+		login, err := client.GetAuthenticatedUser(context.Background())
+		if err != nil || login != "octocat" {
+			t.Fatalf("expected the third attempt to succeed, got %q, %v", login, err)
+		}
+		if calls.Load() != 3 || len(*waits) != 2 {
+			t.Fatalf("expected 3 requests and 2 waits, got %d and %v", calls.Load(), *waits)
+		}
+		if w := (*waits)[1]; w < githubRetryBase || w > 2*githubRetryBase {
+			t.Errorf("expected the second backoff between 1s and 2s, got %v", w)
+		}
+		if limit := client.RateLimit(); limit == nil || limit.Limit != 5000 || limit.Remaining != 98 {
+			t.Errorf("expected the quota of the last response, got %+v", limit)
+		}
+	})
+
+	t.Run("retry-after is honoured", func(t *testing.T) {
+		client, _, waits := respond(t, []int{http.StatusTooManyRequests, http.StatusOK}, func(w http.ResponseWriter, status int) {
+			if status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "3")
+			}
+		})
+		if _, err := client.GetAuthenticatedUser(context.Background()); err != nil {
+			t.Fatalf("expected the retry to succeed: %v", err)
+		}
+		if len(*waits) != 1 || (*waits)[0] != 3*time.Second {
+			t.Errorf("expected one 3s wait, got %v", *waits)
+		}
+	})
+
+	t.Run("permission errors are not retried", func(t *testing.T) {
+		client, calls, _ := respond(t, []int{http.StatusForbidden}, nil)
+		if _, err := client.GetAuthenticatedUser(context.Background()); err == nil || IsRateLimited(err) {
+			t.Fatalf("expected a plain error, got %v", err)
+		}
+		if calls.Load() != 1 {
+			t.Errorf("expected 1 request, got %d", calls.Load())
+		}
+	})
+
+	t.Run("distant resets fail fast", func(t *testing.T) {
+		reset := time.Now().Add(time.Hour).Truncate(time.Second)
+		client, calls, waits := respond(t, []int{http.StatusForbidden}, func(w http.ResponseWriter, status int) {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		})
+		_, err := client.GetAuthenticatedUser(context.Background())
+		if !IsRateLimited(err) {
+			t.Fatalf("expected a rate limit error, got %v", err)
+		}
+		if calls.Load() != 1 || len(*waits) != 0 {
+			t.Errorf("expected no retries, got %d requests and waits %v", calls.Load(), *waits)
+		}
+		if limit := client.RateLimit(); limit == nil || limit.Remaining != 0 || !limit.Reset.Equal(reset) {
+			t.Errorf("expected the spent quota to be recorded, got %+v", limit)
+		}
+	})
+
+	t.Run("retries are bounded", func(t *testing.T) {
+		client, calls, _ := respond(t, []int{http.StatusInternalServerError}, nil)
+		if _, err := client.GetAuthenticatedUser(context.Background()); err == nil {
+			t.Fatal("expected an error")
+		}
+		if calls.Load() != githubMaxRetries+1 {
+			t.Errorf("expected %d requests, got %d", githubMaxRetries+1, calls.Load())
+		}
+	})
+}
+
+func TestGitHubClient_ListGists(t *testing.T) {
+	ctx := context.Background()
+	github := testutil.NewFakeGitHub(t)
+	client := NewGitHubClient("token").WithBaseURL(github.URL())
+
+	for i := range 230 {
+		name := fmt.Sprintf("snippet%d.go", i)
+		if _, err := client.CreateGist(ctx, &models.GistRequest{Files: map[string]models.GistFile{name: {Content: "package main"}}}); err != nil {
+			t.Fatalf("failed to create gist: %v", err)
+		}
+	}
+
+	before := github.Requests()
+	gists, err := client.ListGists(ctx)
+	if err != nil {
+		t.Fatalf("failed to list gists: %v", err)
+	}
+	if len(gists) != 230 {
+		t.Fatalf("expected all 230 gists, got %d", len(gists))
+	}
+	seen := make(map[string]bool)
+	for _, gist := range gists {
+		seen[gist.ID] = true
+	}
+	if len(seen) != 230 {
+		t.Errorf("expected 230 distinct gists, got %d", len(seen))
+	}
+	if pages := github.Requests() - before; pages != 3 {
+		t.Errorf("expected 3 pages of 100, got %d requests", pages)
+	}
+
+	t.Run("next pages stay on the API", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Link", `<https://elsewhere.example/gists?page=2>; rel="next"`)
+			_, _ = w.Write([]byte(`[]`))
+		}))
+		t.Cleanup(server.Close)
+
+		if _, err := NewGitHubClient("token").WithBaseURL(server.URL).ListGists(ctx); err == nil {
+			t.Error("expected an error for a next page on another host")
+		}
+	})
+}
+
+func TestNextPageURL(t *testing.T) {
+	tests := map[string]string{
+		"": "",
+		`<https://api.github.com/gists?page=2>; rel="next", <https://api.github.com/gists?page=5>; rel="last"`:  "https://api.github.com/gists?page=2",
+		`<https://api.github.com/gists?page=1>; rel="prev", <https://api.github.com/gists?page=3>; rel="next"`:  "https://api.github.com/gists?page=3",
+		`<https://api.github.com/gists?page=1>; rel="first", <https://api.github.com/gists?page=4>; rel="prev"`: "",
+	}
+	for link, want := range tests {
+		if got := nextPageURL(link); got != want {
+			t.Errorf("nextPageURL(%q) = %q, want %q", link, got, want)
+		}
+	}
+}
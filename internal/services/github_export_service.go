@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+)
+
+// ErrInvalidRepoName is returned when an export target is not a valid GitHub repository name
+var ErrInvalidRepoName = errors.New("invalid repository name")
+
+var repoNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,100}$`)
+
+// exportManifest lists the files an export wrote, so the next export to the
+// same repository can remove snippets that are gone without touching files
+// it didn't write
+const exportManifest = ".snipo-export"
+
+// GitHubExportService publishes snippets to a GitHub repository as plain files
+type GitHubExportService struct {
+	githubClient *GitHubClient
+	snippetSvc   SnippetManager
+	folderRepo   *repository.FolderRepository
+	logger       *slog.Logger
+}
+
+// NewGitHubExportService creates a new GitHub export service
+func NewGitHubExportService(
+	githubClient *GitHubClient,
+	snippetSvc SnippetManager,
+	folderRepo *repository.FolderRepository,
+	logger *slog.Logger,
+) *GitHubExportService {
+	return &GitHubExportService{
+		githubClient: githubClient,
+		snippetSvc:   snippetSvc,
+		folderRepo:   folderRepo,
+		logger:       logger,
+	}
+}
+
+// Export writes one file per snippet, organized by folder, plus a README index to the
+// target repository in a single commit. The repository is created if it does not exist.
+// Files of earlier exports whose snippets are gone are removed; anything else in the
+// repository is kept.
+func (s *GitHubExportService) Export(ctx context.Context, username string, req models.GitHubExportRequest) (*models.GitHubExportResult, error) {
+	owner, name, err := parseRepoName(req.Repo, username)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := s.githubClient.GetRepo(ctx, owner, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository: %w", err)
+	}
+
+	created := false
+	if repo == nil {
+		if !strings.EqualFold(owner, username) {
+			return nil, fmt.Errorf("repository %s/%s not found", owner, name)
+		}
+		repo, err = s.githubClient.CreateRepo(ctx, name, req.Description, !req.Public)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create repository: %w", err)
+		}
+		created = true
+	}
+
+	all, err := listAllSnippets(ctx, s.snippetSvc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snippets: %w", err)
+	}
+
+	snippets := make([]models.Snippet, 0, len(all))
+	for _, snippet := range all {
+		if snippet.IsArchived || (req.PublicOnly && !snippet.IsPublic) {
+			continue
+		}
+		snippets = append(snippets, snippet)
+	}
+
+	folderPaths := map[int64]string{}
+	if s.folderRepo != nil {
+		folders, err := s.folderRepo.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list folders: %w", err)
+		}
+		folderPaths = buildFolderPaths(folders)
+	}
+
+	files := buildExportTree(snippets, folderPaths, name)
+
+	message := req.Message
+	if message == "" {
+		message = fmt.Sprintf("Publish %d snippets from snipo", len(snippets))
+	}
+
+	branch := repo.DefaultBranch
+	if branch == "" {
+		branch = "main"
+	}
+
+	var previous string
+	if !created {
+		previous, _, err = s.githubClient.GetFile(ctx, owner, name, branch, exportManifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read export manifest: %w", err)
+		}
+	}
+	var remove []string
+	for _, path := range strings.Split(previous, "\n") {
+		if _, ok := files[path]; path != "" && !ok {
+			remove = append(remove, path)
+		}
+	}
+	files[exportManifest] = buildExportManifest(files)
+
+	sha, err := s.githubClient.CommitFiles(ctx, owner, name, branch, message, files, remove)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit files: %w", err)
+	}
+
+	s.logger.Info("snippets exported to GitHub",
+		"repo", owner+"/"+name,
+		"snippets", len(snippets),
+		"files", len(files),
+		"created", created,
+	)
+
+	return &models.GitHubExportResult{
+		Repo:         owner + "/" + name,
+		RepoURL:      repo.HTMLURL,
+		Branch:       branch,
+		CommitSHA:    sha,
+		RepoCreated:  created,
+		FilesWritten: len(files),
+		Snippets:     len(snippets),
+	}, nil
+}
+
+// parseRepoName splits "owner/name" (or just "name", owned by the default owner)
+func parseRepoName(repo, defaultOwner string) (string, string, error) {
+	owner, name := defaultOwner, strings.TrimSpace(repo)
+	if i := strings.Index(name, "/"); i >= 0 {
+		owner, name = name[:i], name[i+1:]
+	}
+	if owner == "" || !repoNamePattern.MatchString(owner) || !repoNamePattern.MatchString(name) {
+		return "", "", ErrInvalidRepoName
+	}
+	return owner, name, nil
+}
+
+// buildFolderPaths maps every folder ID to its slash-separated path from the root
+func buildFolderPaths(folders []models.Folder) map[int64]string {
+	byID := make(map[int64]models.Folder, len(folders))
+	for _, f := range folders {
+		byID[f.ID] = f
+	}
+
+	paths := make(map[int64]string, len(folders))
+	for _, f := range folders {
+		var segments []string
+		current, ok := f, true
+		// Bound the walk so a corrupted parent chain cannot loop forever
+		for depth := 0; ok && depth < len(folders); depth++ {
+			segments = append([]string{safeSegment(current.Name, "folder")}, segments...)
+			if current.ParentID == nil {
+				break
+			}
+			current, ok = byID[*current.ParentID]
+		}
+		paths[f.ID] = strings.Join(segments, "/")
+	}
+	return paths
+}
+
+// buildExportTree lays out snippets as repository files and adds a README index
+func buildExportTree(snippets []models.Snippet, folderPaths map[int64]string, title string) map[string]string {
+	files := make(map[string]string)
+	used := make(map[string]bool)
+
+	type indexEntry struct {
+		title, description, path string
+	}
+	sections := make(map[string][]indexEntry)
+
+	for _, snippet := range snippets {
+		dir := ""
+		if len(snippet.Folders) > 0 {
+			dir = folderPaths[snippet.Folders[0].ID]
+		}
+
+		base := safeSegment(snippet.Title, "untitled")
+		if used[joinPath(dir, base)] {
+			base = base + "-" + snippet.ID
+		}
+		used[joinPath(dir, base)] = true
+
+		var link string
+		if len(snippet.Files) == 0 {
+			link = joinPath(dir, base+"."+getExtension(snippet.Language))
+			files[link] = snippet.Content
+		} else {
+			link = joinPath(dir, base)
+			for _, f := range snippet.Files {
+				files[joinPath(link, safeSegment(f.Filename, "file"))] = f.Content
+			}
+		}
+
+		sections[dir] = append(sections[dir], indexEntry{
+			title:       snippet.Title,
+			description: snippet.Description,
+			path:        link,
+		})
+	}
+
+	dirs := make([]string, 0, len(sections))
+	for dir := range sections {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var readme strings.Builder
+	fmt.Fprintf(&readme, "# %s\n\n%d snippets published from snipo.\n", title, len(snippets))
+	for _, dir := range dirs {
+		heading := dir
+		if heading == "" {
+			heading = "Unfiled"
+		}
+		fmt.Fprintf(&readme, "\n## %s\n\n", heading)
+
+		entries := sections[dir]
+		sort.Slice(entries, func(i, j int) bool { return entries[i].title < entries[j].title })
+		for _, e := range entries {
+			fmt.Fprintf(&readme, "- [%s](%s)", e.title, escapePath(e.path))
+			if e.description != "" {
+				fmt.Fprintf(&readme, " - %s", strings.ReplaceAll(e.description, "\n", " "))
+			}
+			readme.WriteString("\n")
+		}
+	}
+	files["README.md"] = readme.String()
+
+	return files
+}
+
+// buildExportManifest lists the paths of files, one per line
+func buildExportManifest(files map[string]string) string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, "\n") + "\n"
+}
+
+// safeSegment turns a name into a single path segment, falling back when nothing usable remains
+func safeSegment(name, fallback string) string {
+	segment := strings.TrimSpace(sanitizeFilename(name))
+	if strings.Trim(segment, ".") == "" {
+		return fallback
+	}
+	return segment
+}
+
+// joinPath joins repository path segments, ignoring empty ones
+func joinPath(parts ...string) string {
+	nonEmpty := parts[:0:0]
+	for _, p := range parts {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, "/")
+}
+
+// escapePath URL-escapes each segment of a repository path for use in markdown links
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
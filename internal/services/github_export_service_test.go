@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestParseRepoName(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantOwner string
+		wantName  string
+		wantErr   bool
+	}{
+		{"snippets", "me", "snippets", false},
+		{"org/snippets", "org", "snippets", false},
+		{"  my.snippets_1 ", "me", "my.snippets_1", false},
+		{"", "", "", true},
+		{"org/", "", "", true},
+		{"a/b/c", "", "", true},
+		{"bad name", "", "", true},
+	}
+
+	for _, tt := range tests {
+		owner, name, err := parseRepoName(tt.input, "me")
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidRepoName) {
+				t.Errorf("parseRepoName(%q) expected ErrInvalidRepoName, got %v", tt.input, err)
+			}
+			continue
+		}
+		if err != nil || owner != tt.wantOwner || name != tt.wantName {
+			t.Errorf("parseRepoName(%q) = %q, %q, %v", tt.input, owner, name, err)
+		}
+	}
+}
+
+func TestBuildExportTree(t *testing.T) {
+	parent := int64(1)
+	folderPaths := buildFolderPaths([]models.Folder{
+		{ID: 1, Name: "Go"},
+		{ID: 2, Name: "HTTP", ParentID: &parent},
+	})
+	if folderPaths[2] != "Go/HTTP" {
+		t.Fatalf("expected nested folder path, got %q", folderPaths[2])
+	}
+
+	snippets := []models.Snippet{
+		{ID: "a", Title: "Server", Language: "go", Content: "package main", Folders: []models.Folder{{ID: 2}}},
+		{ID: "b", Title: "Server", Language: "go", Content: "package other", Folders: []models.Folder{{ID: 2}}},
+		{ID: "c", Title: "Multi", Description: "two files", Files: []models.SnippetFile{
+			{Filename: "a.py", Content: "print(1)"},
+			{Filename: "../b.py", Content: "print(2)"},
+		}},
+	}
+
+	files := buildExportTree(snippets, folderPaths, "snippets")
+
+	want := map[string]string{
+		"Go/HTTP/Server.go":   "package main",
+		"Go/HTTP/Server-b.go": "package other",
+		"Multi/a.py":          "print(1)",
+		"Multi/.._b.py":       "print(2)",
+	}
+	for path, content := range want {
+		if files[path] != content {
+			t.Errorf("expected %s to contain %q, got %q", path, content, files[path])
+		}
+	}
+
+	readme := files["README.md"]
+	if !strings.HasPrefix(readme, "# snippets\n") {
+		t.Errorf("unexpected README header: %q", readme)
+	}
+	for _, line := range []string{"## Go/HTTP", "## Unfiled", "- [Multi](Multi) - two files", "- [Server](Go/HTTP/Server-b.go)"} {
+		if !strings.Contains(readme, line) {
+			t.Errorf("README missing %q:\n%s", line, readme)
+		}
+	}
+}
+
+func TestGitHubExportService_Export(t *testing.T) {
+	_, snippetSvc, _ := setupBackupService(t)
+	ctx := testutil.TestContext()
+	ids := make(map[string]string)
+	for _, title := range []string{"Alpha", "Beta"} {
+		snippet, err := snippetSvc.Create(ctx, &models.SnippetInput{Title: title, Content: title + " content", Language: "go"})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		ids[title] = snippet.ID
+	}
+
+	gh := testutil.NewFakeGitHub(t)
+	exportSvc := NewGitHubExportService(NewGitHubClient("token").WithBaseURL(gh.URL()), snippetSvc, nil, testutil.TestLogger())
+	export := func(t *testing.T, repo string) *models.GitHubExportResult {
+		t.Helper()
+		result, err := exportSvc.Export(ctx, testutil.FakeGitHubLogin, models.GitHubExportRequest{Repo: repo})
+		if err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		return result
+	}
+
+	t.Run("creates a missing repository", func(t *testing.T) {
+		result := export(t, "new")
+		if !result.RepoCreated || result.Snippets != 2 {
+			t.Errorf("expected a new repository with 2 snippets, got %+v", result)
+		}
+		files := gh.RepoFiles("new")
+		if files["Alpha.go"] != "Alpha content" || files[exportManifest] == "" {
+			t.Errorf("expected the snippets and a manifest, got %v", files)
+		}
+	})
+
+	t.Run("keeps files it didn't write", func(t *testing.T) {
+		gh.AddRepo("existing", map[string]string{
+			"LICENSE":                  "MIT",
+			".github/workflows/ci.yml": "on: push",
+			"Beta.go":                  "hand-written",
+		})
+		export(t, "existing")
+
+		// Beta.go is taken over by the export, so it goes with its snippet
+		if err := snippetSvc.Delete(ctx, ids["Beta"], true); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		export(t, "existing")
+
+		files := gh.RepoFiles("existing")
+		if files["LICENSE"] != "MIT" || files[".github/workflows/ci.yml"] != "on: push" {
+			t.Errorf("expected the existing files to be kept, got %v", files)
+		}
+		if _, ok := files["Beta.go"]; ok {
+			t.Error("expected the deleted snippet's file to be removed")
+		}
+		if files["Alpha.go"] != "Alpha content" {
+			t.Errorf("expected Alpha.go to be exported, got %v", files)
+		}
+		if gh.RepoCommits("existing") != 3 {
+			t.Errorf("expected one commit per export, got %d commits", gh.RepoCommits("existing"))
+		}
+	})
+
+	t.Run("fills an empty repository", func(t *testing.T) {
+		gh.AddRepo("empty", nil)
+		result := export(t, "empty")
+		if result.RepoCreated {
+			t.Error("expected the existing repository to be used")
+		}
+		files := gh.RepoFiles("empty")
+		if files["Alpha.go"] != "Alpha content" || !strings.HasPrefix(files["README.md"], "# empty\n") {
+			t.Errorf("expected the export in the empty repository, got %v", files)
+		}
+	})
+}
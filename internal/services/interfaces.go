@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/storage"
+)
+
+// SnippetManager is the snippet business logic consumed by handlers and other
+// services. SnippetService is the default implementation; wrappers (caching,
+// demo restrictions) and test fakes can implement it instead.
+type SnippetManager interface {
+	Create(ctx context.Context, input *models.SnippetInput) (*models.Snippet, error)
+	GetByID(ctx context.Context, id string) (*models.Snippet, error)
+	GetByIDPublic(ctx context.Context, id string) (*models.Snippet, error)
+	Update(ctx context.Context, id string, input *models.SnippetInput) (*models.Snippet, error)
+	Upsert(ctx context.Context, externalID string, input *models.SnippetInput) (*models.Snippet, bool, error)
+	Delete(ctx context.Context, id string, permanent bool) error
+	Restore(ctx context.Context, id string) error
+	Bulk(ctx context.Context, input *models.BulkSnippetInput) (*models.BulkSnippetResult, error)
+	List(ctx context.Context, filter models.SnippetFilter) (*models.SnippetListResponse, error)
+	Neighbors(ctx context.Context, id string, filter models.SnippetFilter) (*models.SnippetNeighbors, error)
+	FindDuplicates(ctx context.Context, input *models.DuplicateCheckInput) ([]models.DuplicateMatch, error)
+	CheckDuplicateContent(ctx context.Context, input *models.SnippetInput) error
+	ListPublic(ctx context.Context) ([]models.Snippet, error)
+	ToggleFavorite(ctx context.Context, id string) (*models.Snippet, error)
+	ToggleArchive(ctx context.Context, id string) (*models.Snippet, error)
+	RecordUse(ctx context.Context, id string) (*models.Snippet, error)
+	RecordPublicView(ctx context.Context, id, referrerDomain string) error
+	GetViewStats(ctx context.Context, id string) (*models.SnippetViewStats, error)
+	Search(ctx context.Context, query string, limit int) ([]models.SearchResult, error)
+	ExportSearchIndex(ctx context.Context, since *time.Time) (*models.SearchIndexExport, error)
+	Duplicate(ctx context.Context, id string) (*models.Snippet, error)
+	Fork(ctx context.Context, rawURL string) (*models.Snippet, error)
+	GetHistory(ctx context.Context, id string, limit int) ([]models.SnippetHistory, error)
+	RestoreFromHistory(ctx context.Context, snippetID string, historyID int64) (*models.Snippet, error)
+	DiffHistory(ctx context.Context, snippetID string, fromID, toID int64) (*models.HistoryDiff, error)
+	Activity(ctx context.Context, filter models.ActivityFilter) ([]models.ActivityItem, error)
+	SnippetActivity(ctx context.Context, id string, filter models.ActivityFilter) ([]models.ActivityItem, error)
+}
+
+var _ SnippetManager = (*SnippetService)(nil)
+
+// BackupStorage is the object storage S3SyncService keeps backups in.
+// storage.S3Storage is the default implementation; Download returns
+// storage.ErrNotFound for missing objects.
+type BackupStorage interface {
+	Upload(ctx context.Context, key string, content []byte, contentType string) error
+	UploadWithProgress(ctx context.Context, key string, content []byte, contentType string, progress storage.ProgressFunc) error
+	Download(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]storage.ObjectInfo, error)
+	GetPresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+var (
+	_ BackupStorage = (*storage.S3Storage)(nil)
+	_ BackupStorage = (*storage.SFTPStorage)(nil)
+)
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// ErrUnknownMaintenanceTask is returned for a task name that isn't one of
+// models.MaintenanceTasks
+var ErrUnknownMaintenanceTask = errors.New("unknown maintenance task")
+
+// maxIntegrityProblems caps the problems integrity_check reports
+const maxIntegrityProblems = 100
+
+// MaintenanceService runs database upkeep — VACUUM, ANALYZE, integrity
+// checks, search index merges and WAL checkpoints — for the db maintain
+// command and the admin endpoint
+type MaintenanceService struct {
+	db         *sql.DB
+	path       string // Database file, for the size of its WAL
+	replicated bool
+	logger     *slog.Logger
+	mu         sync.Mutex // one run at a time
+}
+
+// NewMaintenanceService creates a maintenance service for the database at
+// path
+func NewMaintenanceService(db *sql.DB, path string, logger *slog.Logger) *MaintenanceService {
+	return &MaintenanceService{db: db, path: path, logger: logger}
+}
+
+// WithReplication skips WAL checkpoints, which the replicator runs itself
+// so no change leaves the WAL before it's shipped
+func (s *MaintenanceService) WithReplication(replicated bool) *MaintenanceService {
+	s.replicated = replicated
+	return s
+}
+
+// Run runs the tasks, all of them if none are given, in the order of
+// models.MaintenanceTasks. A task failing doesn't stop the ones after it;
+// the report says which failed.
+func (s *MaintenanceService) Run(ctx context.Context, tasks []string) (*models.MaintenanceReport, error) {
+	for _, task := range tasks {
+		if !slices.Contains(models.MaintenanceTasks, task) {
+			return nil, fmt.Errorf("%w %q", ErrUnknownMaintenanceTask, task)
+		}
+	}
+	if len(tasks) == 0 {
+		tasks = models.MaintenanceTasks
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := time.Now()
+	report := &models.MaintenanceReport{Tasks: []models.MaintenanceTaskResult{}}
+	var err error
+	if report.Before, err = s.size(ctx); err != nil {
+		return nil, err
+	}
+
+	for _, task := range models.MaintenanceTasks {
+		if !slices.Contains(tasks, task) {
+			continue
+		}
+		taskStart := time.Now()
+		result := s.runTask(ctx, task)
+		result.Task = task
+		result.Duration = time.Since(taskStart).Round(time.Millisecond).String()
+		if result.Status == models.MaintenanceFailed {
+			s.logger.Warn("database maintenance task failed", "task", task, "detail", result.Detail)
+		}
+		report.Tasks = append(report.Tasks, result)
+	}
+
+	if report.After, err = s.size(ctx); err != nil {
+		return nil, err
+	}
+	report.Duration = time.Since(start).Round(time.Millisecond).String()
+	s.logger.Info("database maintenance finished",
+		"tasks", tasks,
+		"bytes_before", report.Before.Bytes,
+		"bytes_after", report.After.Bytes,
+		"duration", report.Duration)
+	return report, nil
+}
+
+func (s *MaintenanceService) runTask(ctx context.Context, task string) models.MaintenanceTaskResult {
+	var err error
+	switch task {
+	case models.MaintenanceIntegrityCheck:
+		return s.integrityCheck(ctx)
+	case models.MaintenanceFTSOptimize:
+		err = s.optimizeSearchIndex(ctx)
+	case models.MaintenanceAnalyze:
+		_, err = s.db.ExecContext(ctx, "ANALYZE")
+	case models.MaintenanceVacuum:
+		_, err = s.db.ExecContext(ctx, "VACUUM")
+	case models.MaintenanceWALCheckpoint:
+		return s.checkpoint(ctx)
+	}
+	if err != nil {
+		return models.MaintenanceTaskResult{Status: models.MaintenanceFailed, Detail: err.Error()}
+	}
+	return models.MaintenanceTaskResult{Status: models.MaintenanceOK}
+}
+
+// integrityCheck runs PRAGMA integrity_check, which returns "ok" or the
+// problems it found
+func (s *MaintenanceService) integrityCheck(ctx context.Context) models.MaintenanceTaskResult {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("PRAGMA integrity_check(%d)", maxIntegrityProblems))
+	if err != nil {
+		return models.MaintenanceTaskResult{Status: models.MaintenanceFailed, Detail: err.Error()}
+	}
+	defer func() { _ = rows.Close() }()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return models.MaintenanceTaskResult{Status: models.MaintenanceFailed, Detail: err.Error()}
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.MaintenanceTaskResult{Status: models.MaintenanceFailed, Detail: err.Error()}
+	}
+	if len(problems) > 0 {
+		return models.MaintenanceTaskResult{
+			Status:   models.MaintenanceFailed,
+			Detail:   fmt.Sprintf("found %d problems; restore a backup", len(problems)),
+			Problems: problems,
+		}
+	}
+	return models.MaintenanceTaskResult{Status: models.MaintenanceOK}
+}
+
+// optimizeSearchIndex merges the segments of the full-text indexes, which
+// grow with every edit, into one
+func (s *MaintenanceService) optimizeSearchIndex(ctx context.Context) error {
+	tables := []string{"snippets_fts"}
+	var trigram int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'snippets_trigram'`).Scan(&trigram); err != nil {
+		return err
+	}
+	if trigram > 0 {
+		tables = append(tables, "snippets_trigram")
+	}
+	for _, table := range tables {
+		if _, err := s.db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s(%s) VALUES ('optimize')", table, table)); err != nil {
+			return fmt.Errorf("failed to optimize %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// checkpoint copies the WAL into the database file and truncates it
+func (s *MaintenanceService) checkpoint(ctx context.Context) models.MaintenanceTaskResult {
+	if s.replicated {
+		return models.MaintenanceTaskResult{Status: models.MaintenanceSkipped, Detail: "replication runs the checkpoints"}
+	}
+	var busy, frames, copied int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &frames, &copied); err != nil {
+		return models.MaintenanceTaskResult{Status: models.MaintenanceFailed, Detail: err.Error()}
+	}
+	switch {
+	case frames == -1:
+		return models.MaintenanceTaskResult{Status: models.MaintenanceSkipped, Detail: "the database isn't in WAL mode"}
+	case busy != 0:
+		return models.MaintenanceTaskResult{Status: models.MaintenanceFailed, Detail: "readers or writers kept the WAL from being truncated; try again"}
+	}
+	return models.MaintenanceTaskResult{Status: models.MaintenanceOK}
+}
+
+// size measures the database file from its page counts, which also works
+// for in-memory databases, and the WAL from its file
+func (s *MaintenanceService) size(ctx context.Context) (models.DatabaseSize, error) {
+	var pages, free, pageSize int64
+	for query, dest := range map[string]*int64{
+		"PRAGMA page_count":     &pages,
+		"PRAGMA freelist_count": &free,
+		"PRAGMA page_size":      &pageSize,
+	} {
+		if err := s.db.QueryRowContext(ctx, query).Scan(dest); err != nil {
+			return models.DatabaseSize{}, fmt.Errorf("failed to measure database: %w", err)
+		}
+	}
+
+	size := models.DatabaseSize{Bytes: pages * pageSize, FreeBytes: free * pageSize}
+	if s.path != "" && !strings.HasPrefix(s.path, ":memory:") {
+		if info, err := os.Stat(s.path + "-wal"); err == nil {
+			size.WALBytes = info.Size()
+		}
+	}
+	return size, nil
+}
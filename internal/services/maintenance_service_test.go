@@ -0,0 +1,116 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestMaintenanceService_Run(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	snippetRepo := repository.NewSnippetRepository(db)
+	for _, title := range []string{"Docker prune", "Docker logs"} {
+		if _, err := snippetRepo.Create(ctx, &models.SnippetInput{Title: title, Content: strings.Repeat("docker ", 2000)}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	svc := NewMaintenanceService(db, ":memory:", testutil.TestLogger())
+
+	report, err := svc.Run(ctx, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(report.Tasks) != len(models.MaintenanceTasks) {
+		t.Fatalf("expected every task to run, got %+v", report.Tasks)
+	}
+	for i, task := range report.Tasks {
+		if task.Task != models.MaintenanceTasks[i] {
+			t.Errorf("expected task %d to be %s, got %s", i, models.MaintenanceTasks[i], task.Task)
+		}
+	}
+	// An in-memory database has no WAL to checkpoint
+	for _, task := range report.Tasks[:len(report.Tasks)-1] {
+		if task.Status != models.MaintenanceOK {
+			t.Errorf("expected %s to succeed, got %+v", task.Task, task)
+		}
+	}
+	if last := report.Tasks[len(report.Tasks)-1]; last.Status != models.MaintenanceSkipped {
+		t.Errorf("expected the checkpoint to be skipped, got %+v", last)
+	}
+	if report.Failed() {
+		t.Error("expected no failures")
+	}
+	if report.Before.Bytes == 0 || report.After.FreeBytes != 0 {
+		t.Errorf("expected sizes before and after a vacuum, got %+v and %+v", report.Before, report.After)
+	}
+
+	// Search still works after merging the index
+	results, err := snippetRepo.Search(ctx, "docker", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("expected 2 results after optimizing, got %d", len(results))
+	}
+}
+
+func TestMaintenanceService_SelectedTasks(t *testing.T) {
+	svc := NewMaintenanceService(testutil.TestDB(t), ":memory:", testutil.TestLogger())
+	ctx := testutil.TestContext()
+
+	report, err := svc.Run(ctx, []string{models.MaintenanceVacuum, models.MaintenanceIntegrityCheck})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(report.Tasks) != 2 || report.Tasks[0].Task != models.MaintenanceIntegrityCheck || report.Tasks[1].Task != models.MaintenanceVacuum {
+		t.Errorf("expected the two tasks in order, got %+v", report.Tasks)
+	}
+
+	if _, err := svc.Run(ctx, []string{"defrag"}); !errors.Is(err, ErrUnknownMaintenanceTask) {
+		t.Errorf("expected ErrUnknownMaintenanceTask, got %v", err)
+	}
+}
+
+func TestMaintenanceService_Checkpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snipo.db")
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=wal_autocheckpoint(0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	db.SetMaxOpenConns(1)
+	ctx := testutil.TestContext()
+	if _, err := db.ExecContext(ctx, "CREATE TABLE notes (body TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO notes VALUES (?)", strings.Repeat("x", 64<<10)); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewMaintenanceService(db, path, testutil.TestLogger())
+	report, err := svc.Run(ctx, []string{models.MaintenanceWALCheckpoint})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Tasks[0].Status != models.MaintenanceOK {
+		t.Fatalf("expected the checkpoint to succeed, got %+v", report.Tasks[0])
+	}
+	if report.Before.WALBytes == 0 || report.After.WALBytes != 0 {
+		t.Errorf("expected the WAL to be truncated, got %d then %d bytes", report.Before.WALBytes, report.After.WALBytes)
+	}
+
+	report, err = svc.WithReplication(true).Run(ctx, []string{models.MaintenanceWALCheckpoint})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Tasks[0].Status != models.MaintenanceSkipped {
+		t.Errorf("expected the checkpoint to be left to replication, got %+v", report.Tasks[0])
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/outbound"
+	"github.com/MohamedElashri/snipo/internal/repository"
+)
+
+// PublishEvent is the payload sent to publish webhooks
+type PublishEvent struct {
+	Event       string    `json:"event"`
+	SnippetID   string    `json:"snippet_id"`
+	PublicToken string    `json:"public_token"` // Key of the share link, /s/{public_token}
+	Title       string    `json:"title"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// PublishScheduler makes snippets public once their publish_at time passes
+type PublishScheduler struct {
+	snippetRepo *repository.SnippetRepository
+	webhookURLs []string
+	interval    time.Duration
+	httpClient  *http.Client
+	logger      *slog.Logger
+}
+
+// NewPublishScheduler creates a new publish scheduler
+func NewPublishScheduler(snippetRepo *repository.SnippetRepository, interval time.Duration, webhookURLs []string, logger *slog.Logger) *PublishScheduler {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &PublishScheduler{
+		snippetRepo: snippetRepo,
+		webhookURLs: webhookURLs,
+		interval:    interval,
+		httpClient:  outbound.NewClient(outbound.Options{Timeout: 10 * time.Second, AllowPrivate: true}),
+		logger:      logger,
+	}
+}
+
+// Start runs the scheduler until ctx is cancelled
+func (s *PublishScheduler) Start(ctx context.Context) {
+	s.logger.Info("starting publish scheduler", "interval", s.interval, "webhooks", len(s.webhookURLs))
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.RunOnce(ctx); err != nil {
+				s.logger.Error("publish task failed", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunOnce publishes all due snippets and notifies webhooks, returning the number published
+func (s *PublishScheduler) RunOnce(ctx context.Context) (int, error) {
+	published, err := s.snippetRepo.PublishScheduled(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	for _, snippet := range published {
+		s.logger.Info("scheduled snippet published", "id", snippet.ID, "title", snippet.Title)
+		s.notify(ctx, PublishEvent{
+			Event:       "snippet.published",
+			SnippetID:   snippet.ID,
+			PublicToken: *snippet.PublicToken,
+			Title:       snippet.Title,
+			PublishedAt: now,
+		})
+	}
+
+	return len(published), nil
+}
+
+// notify posts the event to every configured webhook; failures are logged, not retried
+func (s *PublishScheduler) notify(ctx context.Context, event PublishEvent) {
+	if len(s.webhookURLs) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		s.logger.Warn("failed to marshal publish event", "error", err)
+		return
+	}
+
+	for _, url := range s.webhookURLs {
+		if err := s.post(ctx, url, body); err != nil {
+			s.logger.Warn("publish webhook failed", "url", url, "snippet_id", event.SnippetID, "error", err)
+		}
+	}
+}
+
+func (s *PublishScheduler) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "snipo")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
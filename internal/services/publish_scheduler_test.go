@@ -0,0 +1,103 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestSnippetService_PublishAt(t *testing.T) {
+	db := testutil.TestDB(t)
+	svc := NewSnippetService(repository.NewSnippetRepository(db), testutil.TestLogger())
+	ctx := testutil.TestContext()
+
+	future := time.Now().Add(time.Hour)
+	scheduled, err := svc.Create(ctx, &models.SnippetInput{
+		Title:     "Scheduled",
+		Content:   "x",
+		IsPublic:  true,
+		PublishAt: &future,
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if scheduled.IsPublic || scheduled.PublishAt == nil {
+		t.Errorf("expected future publish_at to keep snippet private, got public=%v publish_at=%v", scheduled.IsPublic, scheduled.PublishAt)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	immediate, err := svc.Create(ctx, &models.SnippetInput{Title: "Immediate", Content: "x", PublishAt: &past})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !immediate.IsPublic || immediate.PublishAt != nil {
+		t.Errorf("expected past publish_at to publish immediately, got public=%v publish_at=%v", immediate.IsPublic, immediate.PublishAt)
+	}
+}
+
+func TestPublishScheduler_RunOnce(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := repository.NewSnippetRepository(db)
+	svc := NewSnippetService(repo, testutil.TestLogger())
+	ctx := testutil.TestContext()
+
+	var events []PublishEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event PublishEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("failed to decode webhook body: %v", err)
+		}
+		events = append(events, event)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	future := time.Now().Add(time.Hour)
+	due, err := svc.Create(ctx, &models.SnippetInput{Title: "Due", Content: "x", PublishAt: &future})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := svc.Create(ctx, &models.SnippetInput{Title: "Later", Content: "x", PublishAt: &future}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Move the first snippet's schedule into the past
+	if _, err := db.Exec("UPDATE snippets SET publish_at = ? WHERE id = ?", time.Now().UTC().Add(-time.Minute), due.ID); err != nil {
+		t.Fatalf("failed to backdate publish_at: %v", err)
+	}
+
+	scheduler := NewPublishScheduler(repo, time.Minute, []string{server.URL}, testutil.TestLogger())
+	count, err := scheduler.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 snippet published, got %d", count)
+	}
+
+	got, err := repo.GetByID(ctx, due.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if !got.IsPublic || got.PublishAt != nil {
+		t.Errorf("expected snippet to be public with publish_at cleared, got public=%v publish_at=%v", got.IsPublic, got.PublishAt)
+	}
+
+	if len(events) != 1 || events[0].SnippetID != due.ID || events[0].Event != "snippet.published" {
+		t.Errorf("unexpected webhook events: %+v", events)
+	}
+	if got.PublicToken == nil || len(events) == 1 && events[0].PublicToken != *got.PublicToken {
+		t.Errorf("expected the event to carry the new public token %v, got %+v", got.PublicToken, events)
+	}
+
+	// A second run has nothing left to publish
+	if count, _ := scheduler.RunOnce(ctx); count != 0 {
+		t.Errorf("expected no snippets on second run, got %d", count)
+	}
+}
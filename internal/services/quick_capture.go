@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// QuickCaptureWindow is how long a repeated capture of the same content
+// returns the snippet saved the first time instead of a new one
+const QuickCaptureWindow = 5 * time.Minute
+
+// quickTitleLength keeps inferred titles short enough for the snippet list
+const quickTitleLength = 80
+
+// QuickCaptureService saves bare text as snippets for shell aliases and
+// hotkeys, inferring the title and language
+type QuickCaptureService struct {
+	snippets *SnippetService
+	logger   *slog.Logger
+	window   time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	recent map[string]quickCapture
+}
+
+// quickCapture remembers a capture for the dedupe window
+type quickCapture struct {
+	snippetID string
+	at        time.Time
+}
+
+// NewQuickCaptureService creates a new quick capture service
+func NewQuickCaptureService(snippets *SnippetService, logger *slog.Logger) *QuickCaptureService {
+	return &QuickCaptureService{
+		snippets: snippets,
+		logger:   logger,
+		window:   QuickCaptureWindow,
+		now:      time.Now,
+		recent:   make(map[string]quickCapture),
+	}
+}
+
+// Capture saves content as a snippet. Capturing the same content again
+// within the dedupe window returns the earlier snippet with duplicate set,
+// so a retried or double-fired alias doesn't leave copies behind.
+func (s *QuickCaptureService) Capture(ctx context.Context, content string) (snippet *models.Snippet, duplicate bool, err error) {
+	content = strings.TrimRight(content, " \t\r\n")
+	userID, _ := models.UserIDFromContext(ctx)
+	key := fmt.Sprintf("%d:%d:%s", userID, models.WorkspaceIDFromContext(ctx), CalculateContentHash(content, nil))
+
+	if existing := s.lookup(key); existing != "" {
+		snippet, err := s.snippets.GetByID(ctx, existing)
+		if err == nil && snippet.DeletedAt == nil {
+			return snippet, true, nil
+		}
+	}
+
+	snippet, err = s.snippets.Create(ctx, &models.SnippetInput{
+		Title:    quickTitle(content, s.now()),
+		Content:  content,
+		Language: guessLanguage(content),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	s.mu.Lock()
+	s.recent[key] = quickCapture{snippetID: snippet.ID, at: s.now()}
+	s.mu.Unlock()
+
+	s.logger.Info("snippet quick captured", "id", snippet.ID, "language", snippet.Language)
+	return snippet, false, nil
+}
+
+// lookup returns the snippet captured under key within the window, dropping
+// expired captures as it goes
+func (s *QuickCaptureService) lookup(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := s.now().Add(-s.window)
+	for k, capture := range s.recent {
+		if capture.at.Before(cutoff) {
+			delete(s.recent, k)
+		}
+	}
+	return s.recent[key].snippetID
+}
+
+// commentMarkers are stripped from the line a title is taken from
+var commentMarkers = []string{"<!--", "-->", "/*", "*/", "//", "--", "#", ";;", "*"}
+
+// quickTitle uses the first line of content that isn't a shebang, without
+// comment markers, or the capture time when there is none
+func quickTitle(content string, now time.Time) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#!") {
+			continue
+		}
+		for _, marker := range commentMarkers {
+			line = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, marker), marker))
+		}
+		if line == "" {
+			continue
+		}
+		if runes := []rune(line); len(runes) > quickTitleLength {
+			line = strings.TrimSpace(string(runes[:quickTitleLength-1])) + "…"
+		}
+		return line
+	}
+	return "Quick capture " + now.UTC().Format("2006-01-02 15:04")
+}
+
+// shebangLanguages maps interpreters named on a #! line to languages
+var shebangLanguages = map[string]string{
+	"bash": "bash", "sh": "bash", "zsh": "bash", "dash": "bash",
+	"python": "python", "python3": "python", "node": "javascript", "deno": "typescript",
+	"ruby": "ruby", "perl": "perl", "php": "php", "lua": "lua", "pwsh": "powershell",
+	"Rscript": "r", "elixir": "elixir",
+}
+
+// languageHints are checked in order; the first pattern found in the
+// content picks the language
+var languageHints = []struct {
+	language string
+	pattern  *regexp.Regexp
+}{
+	{"php", regexp.MustCompile(`^<\?php`)},
+	{"go", regexp.MustCompile(`(?m)^package \w+\s*$|^func \w*\(|:= `)},
+	{"rust", regexp.MustCompile(`(?m)^\s*(pub )?fn \w+|^use \w+::|let mut `)},
+	{"dockerfile", regexp.MustCompile(`(?m)^FROM \S+`)},
+	{"html", regexp.MustCompile(`(?i)^<!doctype html|<html[\s>]|<(div|body|head|span)[\s>]`)},
+	{"xml", regexp.MustCompile(`^<\?xml|^<\w+[^>]*>`)},
+	{"python", regexp.MustCompile(`(?m)^\s*(def \w+\(.*\):|class \w+.*:|import \w+$|from [\w.]+ import )`)},
+	{"typescript", regexp.MustCompile(`(?m)^\s*(interface \w+ \{|type \w+ = |export (interface|type) )|: (string|number|boolean)\b`)},
+	{"javascript", regexp.MustCompile(`(?m)^\s*(const|let|var) \w+ = |function \w*\(|=> \{|console\.log\(|require\(`)},
+	{"sql", regexp.MustCompile(`(?im)^\s*(select .+ from|insert into|update \w+ set|create (table|index|view)|delete from|alter table)\b`)},
+	{"css", regexp.MustCompile(`(?m)^[.#]?[\w-]+(\s*[,>]\s*[.#]?[\w-]+)*\s*\{\s*$`)},
+	{"yaml", regexp.MustCompile(`(?m)^---\s*$|^\w[\w-]*:\s*$|^\w[\w-]*: \S`)},
+	{"bash", regexp.MustCompile(`(?m)^\s*(\$ |sudo |apt(-get)? |brew |export \w+=|if \[|for \w+ in |echo |cd |ls |grep |curl |docker |kubectl |git )`)},
+}
+
+// guessLanguage infers the language of bare content from a shebang, JSON
+// syntax or common keywords, falling back to plaintext
+func guessLanguage(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "#!") {
+		fields := strings.Fields(strings.SplitN(trimmed, "\n", 2)[0][2:])
+		if len(fields) > 0 {
+			interpreter := fields[0][strings.LastIndex(fields[0], "/")+1:]
+			if interpreter == "env" && len(fields) > 1 {
+				interpreter = fields[1]
+			}
+			if language, ok := shebangLanguages[interpreter]; ok {
+				return language
+			}
+		}
+	}
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return "json"
+	}
+	for _, hint := range languageHints {
+		if hint.pattern.MatchString(trimmed) {
+			return hint.language
+		}
+	}
+	return "plaintext"
+}
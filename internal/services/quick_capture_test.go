@@ -0,0 +1,87 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestGuessLanguage(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"#!/usr/bin/env python3\nprint('hi')", "python"},
+		{"#!/bin/sh\nls", "bash"},
+		{`{"a": [1, 2]}`, "json"},
+		{"package main\n\nfunc main() {}", "go"},
+		{"def handler(event):\n    return event", "python"},
+		{"const x = require('fs')", "javascript"},
+		{"SELECT id FROM users WHERE active = 1;", "sql"},
+		{"FROM alpine:3\nRUN apk add curl", "dockerfile"},
+		{"docker compose up -d", "bash"},
+		{"remember to buy milk", "plaintext"},
+	}
+	for _, tt := range tests {
+		if got := guessLanguage(tt.content); got != tt.want {
+			t.Errorf("guessLanguage(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestQuickTitle(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"#!/bin/bash\n# Rotate the logs\nlogrotate -f /etc/logrotate.conf", "Rotate the logs"},
+		{"\n\n// Retry with backoff */\nfor {}", "Retry with backoff"},
+		{"#!/bin/sh\n\n", "Quick capture 2024-01-15 09:30"},
+	}
+	for _, tt := range tests {
+		if got := quickTitle(tt.content, now); got != tt.want {
+			t.Errorf("quickTitle(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestQuickCaptureService_Dedupe(t *testing.T) {
+	db := testutil.TestDB(t)
+	snippets := NewSnippetService(repository.NewSnippetRepository(db), testutil.TestLogger())
+	service := NewQuickCaptureService(snippets, testutil.TestLogger())
+	now := time.Now()
+	service.now = func() time.Time { return now }
+	ctx := testutil.TestContext()
+
+	first, duplicate, err := service.Capture(ctx, "kubectl get pods -A\n")
+	if err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	if duplicate || first.Language != "bash" || first.Title != "kubectl get pods -A" {
+		t.Fatalf("unexpected first capture %+v (duplicate=%v)", first, duplicate)
+	}
+
+	again, duplicate, err := service.Capture(ctx, "kubectl get pods -A")
+	if err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	if !duplicate || again.ID != first.ID {
+		t.Errorf("expected repeat within the window to return %s, got %s (duplicate=%v)", first.ID, again.ID, duplicate)
+	}
+
+	now = now.Add(QuickCaptureWindow + time.Second)
+	later, duplicate, err := service.Capture(ctx, "kubectl get pods -A")
+	if err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	if duplicate || later.ID == first.ID {
+		t.Error("expected a new snippet once the window has passed")
+	}
+
+	if _, _, err := service.Capture(ctx, "  \n"); err == nil {
+		t.Error("expected empty content to be rejected")
+	}
+}
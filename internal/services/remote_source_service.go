@@ -0,0 +1,371 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/outbound"
+	"github.com/MohamedElashri/snipo/internal/repository"
+)
+
+// Remote source errors
+var (
+	ErrRemoteSourceNotFound = errors.New("remote source not found")
+	ErrInvalidRemoteSource  = errors.New("invalid remote source")
+)
+
+// maxRemoteFeedSize caps the public feed payload read from a remote instance
+const maxRemoteFeedSize = 50 << 20
+
+// RemoteSourceService mirrors public snippets from other snipo instances into
+// read-only local folders
+type RemoteSourceService struct {
+	repo        *repository.RemoteSourceRepository
+	snippetRepo *repository.SnippetRepository
+	fileRepo    *repository.SnippetFileRepository
+	folderRepo  *repository.FolderRepository
+	httpClient  *http.Client
+	logger      *slog.Logger
+}
+
+// NewRemoteSourceService creates a new remote source service. Sources are
+// added by admins, so instances on the local network can be reached.
+func NewRemoteSourceService(
+	repo *repository.RemoteSourceRepository,
+	snippetRepo *repository.SnippetRepository,
+	fileRepo *repository.SnippetFileRepository,
+	folderRepo *repository.FolderRepository,
+	logger *slog.Logger,
+) *RemoteSourceService {
+	return &RemoteSourceService{
+		repo:        repo,
+		snippetRepo: snippetRepo,
+		fileRepo:    fileRepo,
+		folderRepo:  folderRepo,
+		httpClient:  outbound.NewClient(outbound.Options{Timeout: 30 * time.Second, AllowPrivate: true}),
+		logger:      logger,
+	}
+}
+
+// List returns all configured remote sources
+func (s *RemoteSourceService) List(ctx context.Context) ([]models.RemoteSource, error) {
+	return s.repo.List(ctx)
+}
+
+// Create validates and adds a remote source. The URL is the instance root,
+// including any base path (e.g. https://snipo.example.com/snipo).
+func (s *RemoteSourceService) Create(ctx context.Context, input *models.RemoteSourceInput) (*models.RemoteSource, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	input.URL = strings.TrimRight(strings.TrimSpace(input.URL), "/")
+
+	u, err := url.Parse(input.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an http(s) instance URL", ErrInvalidRemoteSource)
+	}
+	if input.Name == "" {
+		input.Name = u.Host
+	}
+	if len(input.Name) > 100 {
+		return nil, fmt.Errorf("%w: name must be at most 100 characters", ErrInvalidRemoteSource)
+	}
+
+	return s.repo.Create(ctx, input)
+}
+
+// Delete removes a remote source along with its mirrored snippets and folder
+func (s *RemoteSourceService) Delete(ctx context.Context, id int64) error {
+	source, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if source == nil {
+		return ErrRemoteSourceNotFound
+	}
+
+	mappings, err := s.repo.ListSnippets(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, m := range mappings {
+		if err := s.snippetRepo.Delete(ctx, m.SnippetID, true); err != nil {
+			s.logger.Warn("failed to delete mirrored snippet", "id", m.SnippetID, "error", err)
+		}
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	if source.FolderID != nil {
+		if err := s.folderRepo.Delete(ctx, *source.FolderID, models.FolderDeleteMoveToParent); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			s.logger.Warn("failed to delete remote source folder", "folder_id", *source.FolderID, "error", err)
+		}
+	}
+
+	s.logger.Info("remote source deleted", "id", id, "snippets", len(mappings))
+	return nil
+}
+
+// SyncAll pulls every enabled remote source, logging failures per source
+func (s *RemoteSourceService) SyncAll(ctx context.Context) {
+	sources, err := s.repo.List(ctx)
+	if err != nil {
+		s.logger.Error("failed to list remote sources", "error", err)
+		return
+	}
+
+	for _, source := range sources {
+		if !source.Enabled {
+			continue
+		}
+		if _, err := s.Sync(ctx, source.ID); err != nil {
+			s.logger.Warn("remote source sync failed", "id", source.ID, "url", source.URL, "error", err)
+		}
+	}
+}
+
+// Start periodically syncs all enabled remote sources until ctx is cancelled
+func (s *RemoteSourceService) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	s.logger.Info("starting remote source sync", "interval", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.SyncAll(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Sync pulls the public feed of a remote source and reconciles the local mirror
+func (s *RemoteSourceService) Sync(ctx context.Context, id int64) (*models.RemoteSyncResult, error) {
+	source, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, ErrRemoteSourceNotFound
+	}
+
+	result, etag, folderID, syncErr := s.sync(ctx, source)
+	if syncErr != nil {
+		etag, folderID = source.ETag, source.FolderID
+	}
+	if err := s.repo.UpdateSyncState(ctx, id, etag, folderID, syncErr); err != nil {
+		s.logger.Warn("failed to record remote sync state", "id", id, "error", err)
+	}
+	if syncErr != nil {
+		return nil, syncErr
+	}
+
+	s.logger.Info("remote source synced",
+		"id", id,
+		"not_modified", result.NotModified,
+		"created", result.Created,
+		"updated", result.Updated,
+		"removed", result.Removed,
+	)
+	return result, nil
+}
+
+func (s *RemoteSourceService) sync(ctx context.Context, source *models.RemoteSource) (*models.RemoteSyncResult, string, *int64, error) {
+	result := &models.RemoteSyncResult{SourceID: source.ID}
+
+	snippets, etag, notModified, err := s.fetchFeed(ctx, source)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if notModified {
+		result.NotModified = true
+		return result, source.ETag, source.FolderID, nil
+	}
+
+	folderID, err := s.ensureFolder(ctx, source)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	mappings, err := s.repo.ListSnippets(ctx, source.ID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	existing := make(map[string]models.RemoteSnippet, len(mappings))
+	for _, m := range mappings {
+		existing[m.RemoteID] = m
+	}
+
+	seen := make(map[string]bool, len(snippets))
+	for _, remote := range snippets {
+		if remote.ID == "" || seen[remote.ID] {
+			continue
+		}
+		seen[remote.ID] = true
+
+		mapping, ok := existing[remote.ID]
+		if ok && mapping.RemoteUpdatedAt.Equal(remote.UpdatedAt) {
+			result.Unchanged++
+			continue
+		}
+
+		snippetID, created, err := s.upsertMirror(ctx, source, folderID, remote, mapping.SnippetID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if err := s.repo.UpsertSnippet(ctx, &models.RemoteSnippet{
+			SourceID:        source.ID,
+			RemoteID:        remote.ID,
+			SnippetID:       snippetID,
+			RemoteUpdatedAt: remote.UpdatedAt,
+		}); err != nil {
+			return nil, "", nil, err
+		}
+		if created {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+
+	// Remove mirrors of snippets that are no longer public upstream
+	for remoteID, m := range existing {
+		if seen[remoteID] {
+			continue
+		}
+		if err := s.snippetRepo.Delete(ctx, m.SnippetID, true); err != nil {
+			s.logger.Warn("failed to delete mirrored snippet", "id", m.SnippetID, "error", err)
+		}
+		if err := s.repo.DeleteSnippet(ctx, source.ID, remoteID); err != nil {
+			return nil, "", nil, err
+		}
+		result.Removed++
+	}
+
+	return result, etag, &folderID, nil
+}
+
+// fetchFeed retrieves the public feed, returning notModified when the ETag still matches
+func (s *RemoteSourceService) fetchFeed(ctx context.Context, source *models.RemoteSource) ([]models.Snippet, string, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL+"/api/v1/snippets/public", nil)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if source.ETag != "" {
+		req.Header.Set("If-None-Match", source.ETag)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to fetch remote feed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, source.ETag, true, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", false, fmt.Errorf("failed to fetch remote feed: unexpected status code %d", resp.StatusCode)
+	}
+
+	var envelope struct {
+		Data []models.Snippet `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRemoteFeedSize)).Decode(&envelope); err != nil {
+		return nil, "", false, fmt.Errorf("failed to decode remote feed: %w", err)
+	}
+
+	return envelope.Data, resp.Header.Get("ETag"), false, nil
+}
+
+// ensureFolder returns the source's mirror folder, creating it if needed
+func (s *RemoteSourceService) ensureFolder(ctx context.Context, source *models.RemoteSource) (int64, error) {
+	if source.FolderID != nil {
+		folder, err := s.folderRepo.GetByID(ctx, *source.FolderID)
+		if err != nil {
+			return 0, err
+		}
+		if folder != nil {
+			return folder.ID, nil
+		}
+	}
+
+	folder, err := s.folderRepo.Create(ctx, &models.FolderInput{Name: source.Name})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create remote source folder: %w", err)
+	}
+	return folder.ID, nil
+}
+
+// upsertMirror creates or refreshes the local copy of a remote snippet and
+// returns its ID and whether it was newly created
+func (s *RemoteSourceService) upsertMirror(ctx context.Context, source *models.RemoteSource, folderID int64, remote models.Snippet, snippetID string) (string, bool, error) {
+	input := &models.SnippetInput{
+		Title:       remote.Title,
+		Description: remote.Description,
+		Content:     remote.Content,
+		Language:    remote.Language,
+		IsPublic:    false, // Mirrors are never republished
+	}
+	files := make([]models.SnippetFileInput, 0, len(remote.Files))
+	for _, f := range remote.Files {
+		files = append(files, models.SnippetFileInput{Filename: f.Filename, Content: f.Content, Language: f.Language, Note: f.Note})
+	}
+
+	var snippet *models.Snippet
+	var err error
+	created := false
+	if snippetID != "" {
+		snippet, err = s.snippetRepo.Update(ctx, snippetID, input)
+		if err != nil {
+			return "", false, err
+		}
+	}
+	if snippet == nil {
+		// New remote snippet, or the local mirror was removed out of band
+		snippet, err = s.snippetRepo.Create(ctx, input)
+		if err != nil {
+			return "", false, err
+		}
+		created = true
+
+		if err := s.folderRepo.SetSnippetFolder(ctx, snippet.ID, &folderID); err != nil {
+			return "", false, err
+		}
+		if err := s.snippetRepo.SetProvenance(ctx, snippet.ID, &models.Provenance{
+			SourceURL: source.URL + "/s/" + remote.ID,
+			SourceID:  remote.ID,
+			ForkedAt:  time.Now().UTC(),
+		}); err != nil {
+			return "", false, err
+		}
+	}
+
+	// Replace files wholesale; remote file IDs are meaningless locally
+	if err := s.fileRepo.DeleteBySnippetID(ctx, snippet.ID); err != nil {
+		return "", false, err
+	}
+	if _, err := s.fileRepo.SyncFiles(ctx, snippet.ID, files); err != nil {
+		return "", false, err
+	}
+
+	return snippet.ID, created, nil
+}
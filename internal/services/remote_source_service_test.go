@@ -0,0 +1,178 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+// fakeFeed serves a public snippet feed with ETag support
+type fakeFeed struct {
+	mu       sync.Mutex
+	snippets []models.Snippet
+	etag     string
+	requests int
+}
+
+func (f *fakeFeed) set(etag string, snippets ...models.Snippet) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.etag, f.snippets = etag, snippets
+}
+
+func (f *fakeFeed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	if r.URL.Path != "/api/v1/snippets/public" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("ETag", f.etag)
+	if r.Header.Get("If-None-Match") == f.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": f.snippets})
+}
+
+func TestRemoteSourceService_Sync(t *testing.T) {
+	db := testutil.TestDB(t)
+	logger := testutil.TestLogger()
+	ctx := testutil.TestContext()
+
+	snippetRepo := repository.NewSnippetRepository(db)
+	fileRepo := repository.NewSnippetFileRepository(db)
+	folderRepo := repository.NewFolderRepository(db)
+	remoteRepo := repository.NewRemoteSourceRepository(db)
+	svc := NewRemoteSourceService(remoteRepo, snippetRepo, fileRepo, folderRepo, logger)
+	snippetSvc := NewSnippetService(snippetRepo, logger).
+		WithFileRepo(fileRepo).
+		WithFolderRepo(folderRepo).
+		WithRemoteSourceRepo(remoteRepo)
+
+	feed := &fakeFeed{}
+	server := httptest.NewServer(feed)
+	defer server.Close()
+
+	t1 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed.set(`"v1"`,
+		models.Snippet{ID: "r1", Title: "One", Content: "1", Language: "go", UpdatedAt: t1,
+			Files: []models.SnippetFile{{Filename: "one.go", Content: "1", Language: "go"}}},
+		models.Snippet{ID: "r2", Title: "Two", Content: "2", Language: "bash", UpdatedAt: t1},
+	)
+
+	source, err := svc.Create(ctx, &models.RemoteSourceInput{Name: "Team", URL: server.URL + "/"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	result, err := svc.Sync(ctx, source.ID)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Created != 2 {
+		t.Fatalf("expected 2 snippets created, got %+v", result)
+	}
+
+	source, _ = remoteRepo.GetByID(ctx, source.ID)
+	if source.FolderID == nil || source.SnippetCount != 2 {
+		t.Fatalf("expected folder and 2 mirrored snippets, got %+v", source)
+	}
+
+	mappings, _ := remoteRepo.ListSnippets(ctx, source.ID)
+	var mirrorID string
+	for _, m := range mappings {
+		if m.RemoteID == "r1" {
+			mirrorID = m.SnippetID
+		}
+	}
+	mirror, err := snippetSvc.GetByID(ctx, mirrorID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if mirror.IsPublic || len(mirror.Files) != 1 || len(mirror.Folders) != 1 || mirror.Folders[0].ID != *source.FolderID {
+		t.Errorf("unexpected mirror: public=%v files=%d folders=%+v", mirror.IsPublic, len(mirror.Files), mirror.Folders)
+	}
+	if mirror.Provenance == nil || mirror.Provenance.SourceID != "r1" {
+		t.Errorf("expected provenance for mirror, got %+v", mirror.Provenance)
+	}
+
+	// Mirrors are read-only
+	if _, err := snippetSvc.Update(ctx, mirrorID, &models.SnippetInput{Title: "Edited", Content: "x"}); !errors.Is(err, ErrSnippetReadOnly) {
+		t.Errorf("expected ErrSnippetReadOnly on update, got %v", err)
+	}
+	if err := snippetSvc.Delete(ctx, mirrorID, true); !errors.Is(err, ErrSnippetReadOnly) {
+		t.Errorf("expected ErrSnippetReadOnly on delete, got %v", err)
+	}
+
+	// Unchanged feed is answered with 304
+	result, err = svc.Sync(ctx, source.ID)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if !result.NotModified {
+		t.Errorf("expected not modified, got %+v", result)
+	}
+
+	// r1 changes, r2 is no longer public
+	feed.set(`"v2"`,
+		models.Snippet{ID: "r1", Title: "One v2", Content: "1", Language: "go", UpdatedAt: t1.Add(time.Hour)},
+	)
+	result, err = svc.Sync(ctx, source.ID)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Updated != 1 || result.Removed != 1 {
+		t.Errorf("expected 1 update and 1 removal, got %+v", result)
+	}
+
+	mirror, _ = snippetSvc.GetByID(ctx, mirrorID)
+	if mirror.Title != "One v2" || len(mirror.Files) != 0 {
+		t.Errorf("expected refreshed mirror, got title=%q files=%d", mirror.Title, len(mirror.Files))
+	}
+
+	// Deleting the source removes its mirrors
+	if err := svc.Delete(ctx, source.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM snippets").Scan(&count); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected mirrored snippets to be deleted, found %d", count)
+	}
+}
+
+func TestRemoteSourceService_CreateValidation(t *testing.T) {
+	db := testutil.TestDB(t)
+	svc := NewRemoteSourceService(repository.NewRemoteSourceRepository(db), repository.NewSnippetRepository(db),
+		repository.NewSnippetFileRepository(db), repository.NewFolderRepository(db), testutil.TestLogger())
+	ctx := testutil.TestContext()
+
+	if _, err := svc.Create(ctx, &models.RemoteSourceInput{URL: "ftp://example.com"}); !errors.Is(err, ErrInvalidRemoteSource) {
+		t.Errorf("expected ErrInvalidRemoteSource, got %v", err)
+	}
+
+	source, err := svc.Create(ctx, &models.RemoteSourceInput{URL: "https://snipo.example.com"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if source.Name != "snipo.example.com" || !source.Enabled {
+		t.Errorf("expected defaults from URL, got %+v", source)
+	}
+
+	if _, err := svc.Create(ctx, &models.RemoteSourceInput{URL: "https://snipo.example.com/"}); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Errorf("expected ErrAlreadyExists, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+// Report errors
+var (
+	ErrReportNotFound    = errors.New("report not found")
+	ErrInvalidResolution = errors.New("action must be 'dismiss' or 'unpublish'")
+)
+
+// ReportService handles abuse reports against public snippets and the
+// admin moderation queue
+type ReportService struct {
+	repo         *repository.ReportRepository
+	snippetRepo  *repository.SnippetRepository
+	settingsRepo *repository.SettingsRepository
+	logger       *slog.Logger
+
+	shareLinksByID bool
+}
+
+// NewReportService creates a new report service
+func NewReportService(
+	repo *repository.ReportRepository,
+	snippetRepo *repository.SnippetRepository,
+	settingsRepo *repository.SettingsRepository,
+	logger *slog.Logger,
+) *ReportService {
+	return &ReportService{
+		repo:         repo,
+		snippetRepo:  snippetRepo,
+		settingsRepo: settingsRepo,
+		logger:       logger,
+
+		shareLinksByID: true,
+	}
+}
+
+// WithShareLinksByID sets whether snippets can be reported by ID as well as
+// by public token, as SnippetService.WithShareLinksByID does for viewing them
+func (s *ReportService) WithShareLinksByID(allowed bool) *ReportService {
+	s.shareLinksByID = allowed
+	return s
+}
+
+// Submit files a report against a public snippet. Once the number of open
+// reports reaches the configured threshold the snippet is unpublished until
+// the admin reviews it. key is the one in the snippet's share link.
+func (s *ReportService) Submit(ctx context.Context, key string, input *models.ReportInput) (*models.Report, error) {
+	if errs := validation.ValidateReportInput(input); errs.HasErrors() {
+		return nil, errs
+	}
+
+	// Only public snippets can be reported, so reports cannot be used to
+	// probe for private snippet IDs
+	snippetID, err := s.snippetRepo.PublicID(ctx, key, s.shareLinksByID)
+	if err != nil {
+		return nil, err
+	}
+	if snippetID == "" {
+		return nil, ErrSnippetNotFound
+	}
+
+	report, err := s.repo.Create(ctx, snippetID, input)
+	if err != nil {
+		s.logger.Error("failed to create report", "snippet_id", snippetID, "error", err)
+		return nil, err
+	}
+	s.logger.Info("snippet reported", "snippet_id", snippetID, "reason", input.Reason)
+
+	settings, err := s.settingsRepo.Get(ctx)
+	if err != nil {
+		s.logger.Warn("failed to load report threshold", "error", err)
+		return report, nil
+	}
+	if settings.ReportUnpublishThreshold <= 0 {
+		return report, nil
+	}
+
+	open, err := s.repo.CountOpen(ctx, snippetID)
+	if err != nil {
+		s.logger.Warn("failed to count reports", "snippet_id", snippetID, "error", err)
+		return report, nil
+	}
+	if open >= settings.ReportUnpublishThreshold {
+		// Reports stay open so the admin still reviews the snippet
+		if err := s.snippetRepo.Unpublish(ctx, snippetID); err != nil {
+			s.logger.Error("failed to auto-unpublish reported snippet", "snippet_id", snippetID, "error", err)
+		} else {
+			s.logger.Warn("snippet unpublished after reports", "snippet_id", snippetID, "reports", open)
+		}
+	}
+
+	return report, nil
+}
+
+// List returns reports with the given status, or all reports if status is empty
+func (s *ReportService) List(ctx context.Context, status string) ([]models.Report, error) {
+	return s.repo.List(ctx, status)
+}
+
+// Resolve applies the admin's decision to an open report
+func (s *ReportService) Resolve(ctx context.Context, id int64, action string) (*models.Report, error) {
+	report, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil || report.Status != models.ReportStatusOpen {
+		return nil, ErrReportNotFound
+	}
+
+	switch action {
+	case "dismiss":
+		if err := s.repo.Resolve(ctx, id, models.ReportStatusDismissed); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, ErrReportNotFound
+			}
+			return nil, err
+		}
+	case "unpublish":
+		if err := s.snippetRepo.Unpublish(ctx, report.SnippetID); err != nil {
+			return nil, err
+		}
+		if err := s.repo.ResolveAllForSnippet(ctx, report.SnippetID, models.ReportStatusActioned); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidResolution
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+func TestReportService(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	snippetRepo := repository.NewSnippetRepository(db)
+	settingsRepo := repository.NewSettingsRepository(db)
+	svc := NewReportService(repository.NewReportRepository(db), snippetRepo, settingsRepo, testutil.TestLogger())
+
+	create := func(title string, public bool) *models.Snippet {
+		t.Helper()
+		snippet, err := snippetRepo.Create(ctx, &models.SnippetInput{Title: title, Content: "x", Language: "go", IsPublic: public})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		return snippet
+	}
+	isPublic := func(id string) bool {
+		t.Helper()
+		snippet, err := snippetRepo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		return snippet.IsPublic
+	}
+
+	t.Run("rejects invalid reason", func(t *testing.T) {
+		snippet := create("Invalid", true)
+		_, err := svc.Submit(ctx, snippet.ID, &models.ReportInput{Reason: "boring"})
+		var validationErrs validation.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			t.Errorf("expected validation error, got %v", err)
+		}
+	})
+
+	t.Run("private snippets cannot be reported", func(t *testing.T) {
+		snippet := create("Private", false)
+		_, err := svc.Submit(ctx, snippet.ID, &models.ReportInput{Reason: "spam"})
+		if !errors.Is(err, ErrSnippetNotFound) {
+			t.Errorf("expected ErrSnippetNotFound, got %v", err)
+		}
+	})
+
+	t.Run("threshold unpublishes", func(t *testing.T) {
+		settings, err := settingsRepo.Get(ctx)
+		if err != nil {
+			t.Fatalf("Get settings failed: %v", err)
+		}
+		if _, err := settingsRepo.Update(ctx, &models.SettingsInput{
+			TrashEnabled:             settings.TrashEnabled,
+			HistoryEnabled:           settings.HistoryEnabled,
+			ReportUnpublishThreshold: 2,
+		}); err != nil {
+			t.Fatalf("Update settings failed: %v", err)
+		}
+		t.Cleanup(func() {
+			_, _ = settingsRepo.Update(ctx, &models.SettingsInput{TrashEnabled: settings.TrashEnabled, HistoryEnabled: settings.HistoryEnabled})
+		})
+
+		snippet := create("Spammy", true)
+		if _, err := svc.Submit(ctx, snippet.ID, &models.ReportInput{Reason: "spam"}); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+		if !isPublic(snippet.ID) {
+			t.Fatal("expected snippet to stay public below the threshold")
+		}
+		if _, err := svc.Submit(ctx, snippet.ID, &models.ReportInput{Reason: "Malware", Details: "drops a binary"}); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+		if isPublic(snippet.ID) {
+			t.Error("expected snippet to be unpublished at the threshold")
+		}
+
+		// Reports stay in the queue for review
+		open, err := svc.List(ctx, models.ReportStatusOpen)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(open) != 2 || open[1].Reason != "malware" || open[0].SnippetTitle != "Spammy" {
+			t.Errorf("expected both reports open, got %+v", open)
+		}
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		snippet := create("Reported", true)
+		first, err := svc.Submit(ctx, snippet.ID, &models.ReportInput{Reason: "harassment"})
+		if err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+		second, err := svc.Submit(ctx, snippet.ID, &models.ReportInput{Reason: "other"})
+		if err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+
+		if _, err := svc.Resolve(ctx, first.ID, "ignore"); !errors.Is(err, ErrInvalidResolution) {
+			t.Errorf("expected ErrInvalidResolution, got %v", err)
+		}
+
+		dismissed, err := svc.Resolve(ctx, first.ID, "dismiss")
+		if err != nil {
+			t.Fatalf("Resolve dismiss failed: %v", err)
+		}
+		if dismissed.Status != models.ReportStatusDismissed || dismissed.ResolvedAt == nil || !isPublic(snippet.ID) {
+			t.Errorf("expected dismissed report and public snippet, got %+v", dismissed)
+		}
+		if _, err := svc.Resolve(ctx, first.ID, "dismiss"); !errors.Is(err, ErrReportNotFound) {
+			t.Errorf("expected resolved report to leave the queue, got %v", err)
+		}
+
+		actioned, err := svc.Resolve(ctx, second.ID, "unpublish")
+		if err != nil {
+			t.Fatalf("Resolve unpublish failed: %v", err)
+		}
+		if actioned.Status != models.ReportStatusActioned || isPublic(snippet.ID) {
+			t.Errorf("expected actioned report and private snippet, got %+v", actioned)
+		}
+	})
+	t.Run("reports by public token", func(t *testing.T) {
+		snippet := create("Token", true)
+		report, err := svc.Submit(ctx, *snippet.PublicToken, &models.ReportInput{Reason: "spam"})
+		if err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+		if report.SnippetID != snippet.ID {
+			t.Errorf("expected the report to name snippet %s, got %s", snippet.ID, report.SnippetID)
+		}
+
+		svc.WithShareLinksByID(false)
+		defer svc.WithShareLinksByID(true)
+		if _, err := svc.Submit(ctx, snippet.ID, &models.ReportInput{Reason: "spam"}); !errors.Is(err, ErrSnippetNotFound) {
+			t.Errorf("expected the ID to be refused without share links by ID, got %v", err)
+		}
+	})
+}
@@ -1,25 +1,66 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/MohamedElashri/snipo/internal/models"
 	"github.com/MohamedElashri/snipo/internal/storage"
+	"github.com/MohamedElashri/snipo/internal/version"
+)
+
+// metadataSuffix is appended to a backup's key for its metadata object
+const metadataSuffix = ".meta.json"
+
+// envelopeMagic starts backups sealed with envelope encryption. It's followed
+// by the big-endian length of a JSON envelopeHeader and the ciphertext, so a
+// backup carries its wrapped data key and can be restored without metadata.
+const envelopeMagic = "SNIPOENV1"
+
+// envelopeHeader holds the data key of an envelope-encrypted backup
+type envelopeHeader struct {
+	KeyID      string `json:"key_id"`
+	WrappedKey string `json:"wrapped_key"`
+}
+
+// S3 backup errors
+var (
+	ErrSyncInProgress   = errors.New("a backup upload is already in progress")
+	ErrBackupNotFound   = errors.New("backup not found")
+	ErrBackupKeyMissing = errors.New("backup is encrypted but its wrapped data key is missing")
+)
+
+// Presigned download links last between a minute and the seven days S3
+// allows
+const (
+	MinPresignExpiry = time.Minute
+	MaxPresignExpiry = 7 * 24 * time.Hour
 )
 
 // S3SyncService handles S3 backup operations
 type S3SyncService struct {
-	storage   *storage.S3Storage
-	backupSvc *BackupService
-	logger    *slog.Logger
+	storage    BackupStorage
+	backupSvc  *BackupService
+	logger     *slog.Logger
+	encryption *EncryptionService // Set to encrypt backups before upload
+
+	mu       sync.Mutex
+	progress *models.S3UploadProgress // Latest upload, nil before the first one
 }
 
 // NewS3SyncService creates a new S3 sync service
-func NewS3SyncService(storage *storage.S3Storage, backupSvc *BackupService, logger *slog.Logger) *S3SyncService {
+func NewS3SyncService(storage BackupStorage, backupSvc *BackupService, logger *slog.Logger) *S3SyncService {
 	return &S3SyncService{
 		storage:   storage,
 		backupSvc: backupSvc,
@@ -27,15 +68,106 @@ func NewS3SyncService(storage *storage.S3Storage, backupSvc *BackupService, logg
 	}
 }
 
+// WithEnvelopeEncryption encrypts every uploaded backup with a fresh data
+// key, which is stored in the backup's metadata wrapped by the master key of
+// enc. The bucket then never holds readable snippet content, and restoring
+// needs the same master key (or one of its fallback keys).
+func (s *S3SyncService) WithEnvelopeEncryption(enc *EncryptionService) *S3SyncService {
+	s.encryption = enc
+	return s
+}
+
+// sealBackup encrypts content with a new data key and prefixes it with the
+// wrapped key, which is also recorded in metadata
+func (s *S3SyncService) sealBackup(content []byte, metadata *models.BackupMetadata) ([]byte, error) {
+	dataKey, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := sealGCM(dataKey, content)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := s.encryption.EncryptBytes(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	header, err := json.Marshal(envelopeHeader{
+		KeyID:      s.encryption.KeyID(),
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(envelopeMagic)+4+len(header)+len(sealed))
+	out = append(out, envelopeMagic...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(header)))
+	out = append(out, header...)
+	out = append(out, sealed...)
+
+	metadata.KeyID = s.encryption.KeyID()
+	metadata.WrappedKey = base64.StdEncoding.EncodeToString(wrapped)
+	metadata.Size = int64(len(out))
+	return out, nil
+}
+
+// splitEnvelope returns the header and ciphertext of a backup sealed by
+// sealBackup. ok is false for backups without the header.
+func splitEnvelope(content []byte) (header envelopeHeader, sealed []byte, ok bool, err error) {
+	rest, ok := bytes.CutPrefix(content, []byte(envelopeMagic))
+	if !ok {
+		return header, nil, false, nil
+	}
+	if len(rest) < 4 || uint64(binary.BigEndian.Uint32(rest)) > uint64(len(rest)-4) {
+		return header, nil, true, errors.New("truncated encryption header")
+	}
+	size := binary.BigEndian.Uint32(rest)
+	if err := json.Unmarshal(rest[4:4+size], &header); err != nil {
+		return header, nil, true, fmt.Errorf("invalid encryption header: %w", err)
+	}
+	return header, rest[4+size:], true, nil
+}
+
+// openBackup decrypts a backup uploaded with envelope encryption, using the
+// data key wrapped under keyID
+func (s *S3SyncService) openBackup(content []byte, keyID, wrappedKey string) ([]byte, error) {
+	if s.encryption == nil {
+		return nil, fmt.Errorf("backup is encrypted with key %s but encryption is not available", keyID)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(wrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wrapped data key: %w", err)
+	}
+	dataKey, err := s.encryption.DecryptBytes(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("backup is encrypted with key %s, which this server doesn't have: %w", keyID, err)
+	}
+	content, err = openGCM(dataKey, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt backup: %w", err)
+	}
+	return content, nil
+}
+
 // SyncToS3 uploads a backup to S3
 func (s *S3SyncService) SyncToS3(ctx context.Context, opts models.ExportOptions) (*models.S3SyncResult, error) {
 	result := &models.S3SyncResult{
 		StartedAt: time.Now().UTC(),
 	}
 
+	s.mu.Lock()
+	if s.progress != nil && s.progress.Running {
+		s.mu.Unlock()
+		return nil, ErrSyncInProgress
+	}
+	s.progress = &models.S3UploadProgress{Running: true, StartedAt: result.StartedAt}
+	s.mu.Unlock()
+
 	// Create backup
-	content, filename, err := s.backupSvc.Export(ctx, opts)
+	content, filename, metadata, err := s.backupSvc.ExportWithMetadata(ctx, opts)
 	if err != nil {
+		s.finishProgress(err)
 		return nil, fmt.Errorf("failed to create backup: %w", err)
 	}
 
@@ -48,14 +180,45 @@ func (s *S3SyncService) SyncToS3(ctx context.Context, opts models.ExportOptions)
 		contentType = "application/octet-stream"
 	}
 
+	if s.encryption != nil {
+		content, err = s.sealBackup(content, metadata)
+		if err != nil {
+			s.finishProgress(err)
+			return nil, fmt.Errorf("failed to encrypt backup: %w", err)
+		}
+		contentType = "application/octet-stream"
+	}
+
 	// Upload to S3
 	key := "backups/" + filename
-	if err := s.storage.Upload(ctx, key, content, contentType); err != nil {
+	s.mu.Lock()
+	s.progress.Key = key
+	s.progress.TotalBytes = int64(len(content))
+	s.mu.Unlock()
+	err = s.storage.UploadWithProgress(ctx, key, content, contentType, func(sent, total int64) {
+		s.mu.Lock()
+		s.progress.SentBytes = sent
+		s.mu.Unlock()
+	})
+	s.finishProgress(err)
+	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("failed to upload: %v", err))
 		result.FinishedAt = time.Now().UTC()
 		return result, fmt.Errorf("failed to upload backup: %w", err)
 	}
 
+	// Metadata lets the restore picker describe backups without downloading
+	// them; a backup without it can still be restored, as encrypted ones
+	// carry their wrapped data key
+	metadata.AppVersion = version.Current
+	if metaJSON, err := json.Marshal(metadata); err != nil {
+		s.logger.Warn("failed to encode backup metadata", "key", key, "error", err)
+	} else if err := s.storage.Upload(ctx, key+metadataSuffix, metaJSON, "application/json"); err != nil {
+		s.logger.Warn("failed to upload backup metadata", "key", key, "error", err)
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to upload metadata: %v", err))
+	}
+
+	result.Key = key
 	result.Uploaded = 1
 	result.FinishedAt = time.Now().UTC()
 
@@ -68,31 +231,117 @@ func (s *S3SyncService) SyncToS3(ctx context.Context, opts models.ExportOptions)
 	return result, nil
 }
 
-// ListBackups returns all backups stored in S3
+// finishProgress marks the running upload as done
+func (s *S3SyncService) finishProgress(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	s.progress.Running = false
+	s.progress.FinishedAt = &now
+	if err != nil {
+		s.progress.Error = err.Error()
+	}
+}
+
+// UploadProgress returns the state of the running or most recent backup
+// upload, or nil if none happened since startup
+func (s *S3SyncService) UploadProgress() *models.S3UploadProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.progress == nil {
+		return nil
+	}
+	progress := *s.progress
+	return &progress
+}
+
+// ListBackups returns all backups stored in S3, newest first, with their
+// metadata when it was stored
 func (s *S3SyncService) ListBackups(ctx context.Context) ([]models.S3BackupInfo, error) {
 	objects, err := s.storage.List(ctx, "backups/")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list backups: %w", err)
 	}
 
+	hasMetadata := make(map[string]bool)
+	for _, obj := range objects {
+		if backupKey, ok := strings.CutSuffix(obj.Key, metadataSuffix); ok {
+			hasMetadata[backupKey] = true
+		}
+	}
+
 	var backups []models.S3BackupInfo
 	for _, obj := range objects {
-		backups = append(backups, models.S3BackupInfo{
+		if strings.HasSuffix(obj.Key, metadataSuffix) {
+			continue
+		}
+		info := models.S3BackupInfo{
 			Key:          obj.Key,
 			Size:         obj.Size,
 			LastModified: obj.LastModified,
-		})
+			StorageClass: obj.StorageClass,
+		}
+		if hasMetadata[obj.Key] {
+			metadata, err := s.getMetadata(ctx, obj.Key)
+			if err != nil {
+				s.logger.Warn("failed to read backup metadata", "key", obj.Key, "error", err)
+			}
+			info.Metadata = metadata
+		}
+		backups = append(backups, info)
 	}
 
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backupTime(backups[i]).After(backupTime(backups[j]))
+	})
+
 	return backups, nil
 }
 
-// RestoreFromS3 downloads and restores a backup from S3
+// backupTime is when a backup was taken, falling back to the upload time
+func backupTime(b models.S3BackupInfo) time.Time {
+	if b.Metadata != nil && !b.Metadata.CreatedAt.IsZero() {
+		return b.Metadata.CreatedAt
+	}
+	return b.LastModified
+}
+
+// getMetadata returns a backup's metadata, or nil if none was stored
+func (s *S3SyncService) getMetadata(ctx context.Context, key string) (*models.BackupMetadata, error) {
+	content, err := s.storage.Download(ctx, key+metadataSuffix)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata models.BackupMetadata
+	if err := json.Unmarshal(content, &metadata); err != nil {
+		return nil, fmt.Errorf("invalid backup metadata: %w", err)
+	}
+	return &metadata, nil
+}
+
+// RestoreFromS3 downloads and restores a backup from S3. With opts.DryRun
+// it only reports what the restore would change.
 func (s *S3SyncService) RestoreFromS3(ctx context.Context, key string, opts models.ImportOptions) (*models.S3RestoreResult, error) {
 	result := &models.S3RestoreResult{
 		StartedAt: time.Now().UTC(),
 	}
 
+	// Refuse backups this version can't read before touching the live data.
+	// Backups uploaded before metadata existed are checked by the import.
+	metadata, err := s.getMetadata(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup metadata: %w", err)
+	}
+	if metadata != nil {
+		if err := CheckBackupCompatibility(metadata.BackupVersion); err != nil {
+			return nil, err
+		}
+	}
+
 	// Download backup from S3
 	content, err := s.storage.Download(ctx, key)
 	if err != nil {
@@ -101,7 +350,15 @@ func (s *S3SyncService) RestoreFromS3(ctx context.Context, key string, opts mode
 		return result, fmt.Errorf("failed to download backup: %w", err)
 	}
 
+	content, err = s.unsealBackup(key, content, metadata)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		result.FinishedAt = time.Now().UTC()
+		return result, err
+	}
+
 	// Import backup
+	opts.Source = models.ImportSourceS3
 	importResult, err := s.backupSvc.Import(ctx, content, opts)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("failed to import: %v", err))
@@ -109,13 +366,22 @@ func (s *S3SyncService) RestoreFromS3(ctx context.Context, key string, opts mode
 		return result, fmt.Errorf("failed to import backup: %w", err)
 	}
 
-	result.Restored = importResult.SnippetsImported + importResult.TagsImported + importResult.FoldersImported
+	if importResult.DryRun {
+		result.DryRun = true
+		result.Plan = importResult.Plan
+		result.FinishedAt = time.Now().UTC()
+		return result, nil
+	}
+
+	result.Restored = importResult.SnippetsImported + importResult.SnippetsUpdated + importResult.TagsImported + importResult.FoldersImported
 	result.Errors = append(result.Errors, importResult.Errors...)
+	result.ReportID = importResult.ReportID
 	result.FinishedAt = time.Now().UTC()
 
 	s.logger.Info("backup restored from S3",
 		"key", key,
 		"snippets", importResult.SnippetsImported,
+		"updated", importResult.SnippetsUpdated,
 		"tags", importResult.TagsImported,
 		"folders", importResult.FoldersImported,
 		"duration", result.FinishedAt.Sub(result.StartedAt),
@@ -124,21 +390,79 @@ func (s *S3SyncService) RestoreFromS3(ctx context.Context, key string, opts mode
 	return result, nil
 }
 
-// DeleteBackup removes a backup from S3
+// unsealBackup decrypts a downloaded backup if it was uploaded with envelope
+// encryption. Backups sealed before the data key was stored in the object
+// only have it in their metadata.
+func (s *S3SyncService) unsealBackup(key string, content []byte, metadata *models.BackupMetadata) ([]byte, error) {
+	header, sealed, ok, err := splitEnvelope(content)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return s.openBackup(sealed, header.KeyID, header.WrappedKey)
+	}
+	if metadata != nil && metadata.WrappedKey != "" {
+		return s.openBackup(content, metadata.KeyID, metadata.WrappedKey)
+	}
+
+	// Password-encrypted backups keep their .enc name, so anything else
+	// that isn't a plain backup was sealed and lost its metadata
+	if !strings.HasSuffix(key, ".enc") {
+		if format, err := ValidateBackupFile(content); err == nil && format == "encrypted" {
+			return nil, fmt.Errorf("%w: its metadata object %s is missing", ErrBackupKeyMissing, key+metadataSuffix)
+		}
+	}
+	return content, nil
+}
+
+// DeleteBackup removes a backup and its metadata from S3
 func (s *S3SyncService) DeleteBackup(ctx context.Context, key string) error {
 	if err := s.storage.Delete(ctx, key); err != nil {
 		return fmt.Errorf("failed to delete backup: %w", err)
 	}
+	if !strings.HasSuffix(key, metadataSuffix) {
+		if err := s.storage.Delete(ctx, key+metadataSuffix); err != nil {
+			s.logger.Warn("failed to delete backup metadata", "key", key, "error", err)
+		}
+	}
 
 	s.logger.Info("backup deleted from S3", "key", key)
 	return nil
 }
 
-// GetBackupURL generates a presigned URL for downloading a backup
-func (s *S3SyncService) GetBackupURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+// PresignBackup returns a link that downloads a backup from the bucket for
+// expiry, so large backups don't have to pass through the server
+func (s *S3SyncService) PresignBackup(ctx context.Context, key string, expiry time.Duration) (*models.S3PresignedURL, error) {
+	if strings.HasSuffix(key, metadataSuffix) {
+		return nil, ErrBackupNotFound
+	}
+
+	objects, err := s.storage.List(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up backup: %w", err)
+	}
+	i := slices.IndexFunc(objects, func(obj storage.ObjectInfo) bool { return obj.Key == key })
+	if i < 0 {
+		return nil, ErrBackupNotFound
+	}
+
+	metadata, err := s.getMetadata(ctx, key)
+	if err != nil {
+		s.logger.Warn("failed to read backup metadata", "key", key, "error", err)
+	}
+
+	expiresAt := time.Now().UTC().Add(expiry)
 	url, err := s.storage.GetPresignedURL(ctx, key, expiry)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
+		return nil, fmt.Errorf("failed to generate presigned URL: %w", err)
 	}
-	return url, nil
+
+	s.logger.Info("presigned backup download", "key", key, "expires_at", expiresAt)
+	return &models.S3PresignedURL{
+		Key:       key,
+		URL:       url,
+		Size:      objects[i].Size,
+		ExpiresAt: expiresAt,
+		Metadata:  metadata,
+	}, nil
 }
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/storage"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+	"github.com/MohamedElashri/snipo/internal/version"
+)
+
+// memoryStorage is an in-memory BackupStorage
+type memoryStorage struct {
+	objects map[string][]byte
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{objects: make(map[string][]byte)}
+}
+
+func (m *memoryStorage) Upload(_ context.Context, key string, content []byte, _ string) error {
+	m.objects[key] = content
+	return nil
+}
+
+func (m *memoryStorage) UploadWithProgress(ctx context.Context, key string, content []byte, contentType string, progress storage.ProgressFunc) error {
+	if err := m.Upload(ctx, key, content, contentType); err != nil {
+		return err
+	}
+	progress(int64(len(content)), int64(len(content)))
+	return nil
+}
+
+func (m *memoryStorage) Download(_ context.Context, key string) ([]byte, error) {
+	content, ok := m.objects[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return content, nil
+}
+
+func (m *memoryStorage) Delete(_ context.Context, key string) error {
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memoryStorage) List(_ context.Context, prefix string) ([]storage.ObjectInfo, error) {
+	var objects []storage.ObjectInfo
+	for key, content := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, storage.ObjectInfo{Key: key, Size: int64(len(content)), LastModified: time.Now()})
+		}
+	}
+	return objects, nil
+}
+
+func (m *memoryStorage) GetPresignedURL(_ context.Context, key string, _ time.Duration) (string, error) {
+	return "https://example.com/" + key, nil
+}
+
+func TestS3SyncService_BackupMetadata(t *testing.T) {
+	backupSvc, snippetSvc, _ := setupBackupService(t)
+	ctx := testutil.TestContext()
+	store := newMemoryStorage()
+	syncSvc := NewS3SyncService(store, backupSvc, testutil.TestLogger())
+
+	for _, title := range []string{"One", "Two"} {
+		if _, err := snippetSvc.Create(ctx, &models.SnippetInput{Title: title, Content: "x", Language: "go"}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	result, err := syncSvc.SyncToS3(ctx, models.ExportOptions{Format: "json"})
+	if err != nil {
+		t.Fatalf("SyncToS3 failed: %v", err)
+	}
+	if _, ok := store.objects[result.Key+metadataSuffix]; !ok {
+		t.Fatalf("expected metadata next to %s", result.Key)
+	}
+	if progress := syncSvc.UploadProgress(); progress == nil || progress.Running || progress.Key != result.Key ||
+		progress.SentBytes != progress.TotalBytes || progress.FinishedAt == nil {
+		t.Errorf("expected finished upload progress, got %+v", progress)
+	}
+
+	backups, err := syncSvc.ListBackups(ctx)
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("expected metadata objects to be hidden, got %d backups", len(backups))
+	}
+	meta := backups[0].Metadata
+	if meta == nil {
+		t.Fatal("expected backup metadata")
+	}
+	if meta.SnippetCount != 2 || meta.AppVersion != version.Current || meta.BackupVersion != BackupVersion || meta.Encrypted {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+	if meta.Size != int64(len(store.objects[result.Key])) {
+		t.Errorf("expected size %d, got %d", len(store.objects[result.Key]), meta.Size)
+	}
+
+	t.Run("restores compatible backups", func(t *testing.T) {
+		if _, err := syncSvc.RestoreFromS3(ctx, result.Key, models.ImportOptions{Strategy: "merge"}); err != nil {
+			t.Fatalf("RestoreFromS3 failed: %v", err)
+		}
+	})
+
+	t.Run("previews a restore without writing", func(t *testing.T) {
+		restore, err := syncSvc.RestoreFromS3(ctx, result.Key, models.ImportOptions{Strategy: "replace", DryRun: true})
+		if err != nil {
+			t.Fatalf("RestoreFromS3 failed: %v", err)
+		}
+		if !restore.DryRun || restore.Plan == nil || restore.Plan.SnippetsToCreate != 2 || restore.Restored != 0 {
+			t.Errorf("expected a plan creating 2 snippets, got %+v (plan %+v)", restore, restore.Plan)
+		}
+		if n, _ := snippetSvc.List(ctx, models.SnippetFilter{Page: 1, Limit: 10}); n.Pagination.Total != 2 {
+			t.Errorf("expected snippets to be untouched, got %d", n.Pagination.Total)
+		}
+	})
+
+	t.Run("rejects incompatible backups", func(t *testing.T) {
+		future := *meta
+		future.BackupVersion = "2.0"
+		content, _ := json.Marshal(future)
+		store.objects[result.Key+metadataSuffix] = content
+
+		_, err := syncSvc.RestoreFromS3(ctx, result.Key, models.ImportOptions{Strategy: "replace"})
+		if !errors.Is(err, ErrIncompatibleBackup) {
+			t.Fatalf("expected ErrIncompatibleBackup, got %v", err)
+		}
+		if n, _ := snippetSvc.List(ctx, models.SnippetFilter{Page: 1, Limit: 10}); n.Pagination.Total != 2 {
+			t.Errorf("expected snippets to be untouched, got %d", n.Pagination.Total)
+		}
+	})
+
+	t.Run("presigns existing backups only", func(t *testing.T) {
+		link, err := syncSvc.PresignBackup(ctx, result.Key, time.Hour)
+		if err != nil {
+			t.Fatalf("PresignBackup failed: %v", err)
+		}
+		if link.URL != "https://example.com/"+result.Key || link.Size != int64(len(store.objects[result.Key])) || link.Metadata == nil {
+			t.Errorf("unexpected link: %+v", link)
+		}
+		if until := time.Until(link.ExpiresAt); until < 59*time.Minute || until > time.Hour {
+			t.Errorf("expected the link to expire in an hour, got %v", until)
+		}
+
+		for _, key := range []string{"backups/missing.json", result.Key + metadataSuffix, strings.TrimSuffix(result.Key, ".json")} {
+			if _, err := syncSvc.PresignBackup(ctx, key, time.Hour); !errors.Is(err, ErrBackupNotFound) {
+				t.Errorf("expected ErrBackupNotFound for %s, got %v", key, err)
+			}
+		}
+	})
+
+	t.Run("delete removes metadata", func(t *testing.T) {
+		if err := syncSvc.DeleteBackup(ctx, result.Key); err != nil {
+			t.Fatalf("DeleteBackup failed: %v", err)
+		}
+		if len(store.objects) != 0 {
+			t.Errorf("expected no objects left, got %d", len(store.objects))
+		}
+	})
+}
+
+func TestS3SyncService_EnvelopeEncryption(t *testing.T) {
+	backupSvc, snippetSvc, _ := setupBackupService(t)
+	ctx := testutil.TestContext()
+	store := newMemoryStorage()
+
+	oldKey, _ := GenerateKey()
+	oldEnc, _ := NewEncryptionService(oldKey)
+	syncSvc := NewS3SyncService(store, backupSvc, testutil.TestLogger()).WithEnvelopeEncryption(oldEnc)
+
+	if _, err := snippetSvc.Create(ctx, &models.SnippetInput{Title: "Secret", Content: "top-secret-content", Language: "go"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	result, err := syncSvc.SyncToS3(ctx, models.ExportOptions{Format: "json"})
+	if err != nil {
+		t.Fatalf("SyncToS3 failed: %v", err)
+	}
+
+	uploaded := store.objects[result.Key]
+	if strings.Contains(string(uploaded), "top-secret-content") {
+		t.Fatal("expected the uploaded backup to be encrypted")
+	}
+	backups, _ := syncSvc.ListBackups(ctx)
+	meta := backups[0].Metadata
+	if meta == nil || meta.KeyID != oldEnc.KeyID() || meta.WrappedKey == "" || meta.Size != int64(len(uploaded)) {
+		t.Fatalf("expected key ID and wrapped key in metadata, got %+v", meta)
+	}
+
+	t.Run("restores after key rotation", func(t *testing.T) {
+		newKey, _ := GenerateKey()
+		rotated, _ := NewEncryptionServiceWithFallback(newKey, oldKey)
+		restoreSvc := NewS3SyncService(store, backupSvc, testutil.TestLogger()).WithEnvelopeEncryption(rotated)
+		if _, err := restoreSvc.RestoreFromS3(ctx, result.Key, models.ImportOptions{Strategy: "replace"}); err != nil {
+			t.Fatalf("RestoreFromS3 failed: %v", err)
+		}
+	})
+
+	t.Run("fails without the master key", func(t *testing.T) {
+		otherKey, _ := GenerateKey()
+		other, _ := NewEncryptionService(otherKey)
+		restoreSvc := NewS3SyncService(store, backupSvc, testutil.TestLogger()).WithEnvelopeEncryption(other)
+		_, err := restoreSvc.RestoreFromS3(ctx, result.Key, models.ImportOptions{Strategy: "replace"})
+		if err == nil || !strings.Contains(err.Error(), meta.KeyID) {
+			t.Fatalf("expected an error naming key %s, got %v", meta.KeyID, err)
+		}
+	})
+	t.Run("restores without metadata", func(t *testing.T) {
+		if !strings.HasPrefix(string(uploaded), envelopeMagic) {
+			t.Fatal("expected the wrapped data key in the uploaded backup")
+		}
+		delete(store.objects, result.Key+metadataSuffix)
+		if _, err := syncSvc.RestoreFromS3(ctx, result.Key, models.ImportOptions{Strategy: "replace"}); err != nil {
+			t.Fatalf("RestoreFromS3 failed: %v", err)
+		}
+	})
+
+	t.Run("older backups keep the key only in metadata", func(t *testing.T) {
+		content, filename, legacyMeta, err := backupSvc.ExportWithMetadata(ctx, models.ExportOptions{Format: "json"})
+		if err != nil {
+			t.Fatalf("ExportWithMetadata failed: %v", err)
+		}
+		dataKey, _ := GenerateKey()
+		sealed, _ := sealGCM(dataKey, content)
+		wrapped, _ := oldEnc.EncryptBytes(dataKey)
+		legacyMeta.KeyID = oldEnc.KeyID()
+		legacyMeta.WrappedKey = base64.StdEncoding.EncodeToString(wrapped)
+		metaJSON, _ := json.Marshal(legacyMeta)
+
+		key := "backups/legacy-" + filename
+		store.objects[key] = sealed
+		store.objects[key+metadataSuffix] = metaJSON
+		if _, err := syncSvc.RestoreFromS3(ctx, key, models.ImportOptions{Strategy: "replace"}); err != nil {
+			t.Fatalf("RestoreFromS3 failed: %v", err)
+		}
+
+		// Without its metadata the key is gone, which restore says plainly
+		delete(store.objects, key+metadataSuffix)
+		if _, err := syncSvc.RestoreFromS3(ctx, key, models.ImportOptions{Strategy: "replace"}); !errors.Is(err, ErrBackupKeyMissing) {
+			t.Fatalf("expected ErrBackupKeyMissing, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+)
+
+// SearchIndexService checks and rebuilds the full-text search index, for the
+// reindex-fts command and the admin endpoints
+type SearchIndexService struct {
+	snippetRepo  *repository.SnippetRepository
+	settingsRepo *repository.SettingsRepository
+	logger       *slog.Logger
+	mu           sync.Mutex // one rebuild at a time
+}
+
+// NewSearchIndexService creates a new search index service
+func NewSearchIndexService(snippetRepo *repository.SnippetRepository, settingsRepo *repository.SettingsRepository, logger *slog.Logger) *SearchIndexService {
+	return &SearchIndexService{
+		snippetRepo:  snippetRepo,
+		settingsRepo: settingsRepo,
+		logger:       logger,
+	}
+}
+
+// Status compares the search index with the snippets table
+func (s *SearchIndexService) Status(ctx context.Context) (*models.SearchIndexStatus, error) {
+	return s.snippetRepo.CheckSearchIndex(ctx)
+}
+
+// Rebuild recreates the search index from the snippets table with the
+// search_tokenizer setting, then checks it
+func (s *SearchIndexService) Rebuild(ctx context.Context) (*models.SearchIndexStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokenizer, err := s.settingsRepo.GetString(ctx, "search_tokenizer")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read search tokenizer: %w", err)
+	}
+
+	start := time.Now()
+	if err := s.snippetRepo.RebuildSearchIndex(ctx, tokenizer); err != nil {
+		return nil, err
+	}
+
+	status, err := s.snippetRepo.CheckSearchIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !status.Consistent {
+		return status, fmt.Errorf("search index has %d rows for %d snippets after rebuilding", status.Indexed, status.Snippets)
+	}
+
+	s.logger.Info("search index rebuilt", "tokenizer", status.Tokenizer, "snippets", status.Snippets, "duration", time.Since(start))
+	return status, nil
+}
+
+// WarnIfInconsistent logs a warning when the search index has fallen out of
+// step with the snippets table, which makes searches miss snippets
+func (s *SearchIndexService) WarnIfInconsistent(ctx context.Context) {
+	status, err := s.Status(ctx)
+	if err != nil {
+		s.logger.Warn("failed to check search index", "error", err)
+		return
+	}
+	if !status.Consistent {
+		s.logger.Warn("search index is out of date, searches may miss snippets",
+			"snippets", status.Snippets,
+			"indexed", status.Indexed,
+			"recommendation", "Run snipo reindex-fts or POST /api/v1/admin/search-index/rebuild")
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestSearchIndexService(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	snippetRepo := repository.NewSnippetRepository(db)
+	svc := NewSearchIndexService(snippetRepo, repository.NewSettingsRepository(db), testutil.TestLogger())
+
+	for _, title := range []string{"Docker prune", "Docker logs"} {
+		if _, err := snippetRepo.Create(ctx, &models.SnippetInput{Title: title, Content: "docker"}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	status, err := svc.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if !status.Consistent || status.Snippets != 2 || status.Indexed != 2 {
+		t.Fatalf("expected a consistent index of 2 snippets, got %+v", status)
+	}
+
+	// An index emptied behind snipo's back, as after restoring a database
+	// file copied mid-write, finds nothing
+	if _, err := db.ExecContext(ctx, `INSERT INTO snippets_fts(snippets_fts) VALUES('delete-all')`); err != nil {
+		t.Fatalf("failed to empty the index: %v", err)
+	}
+	status, err = svc.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Consistent || status.Indexed != 0 {
+		t.Fatalf("expected an inconsistent empty index, got %+v", status)
+	}
+
+	if _, err := db.ExecContext(ctx, `INSERT OR REPLACE INTO app_settings (key, value) VALUES ('search_tokenizer', 'code')`); err != nil {
+		t.Fatalf("failed to set tokenizer: %v", err)
+	}
+	status, err = svc.Rebuild(ctx)
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if !status.Consistent || status.Indexed != 2 || status.Tokenizer != models.SearchTokenizerCode {
+		t.Fatalf("expected a consistent code index of 2 snippets, got %+v", status)
+	}
+	if status.TrigramIndexed == nil || *status.TrigramIndexed != 2 {
+		t.Errorf("expected 2 trigram rows, got %v", status.TrigramIndexed)
+	}
+
+	results, err := snippetRepo.Search(ctx, "docker", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("expected 2 results after rebuilding, got %d", len(results))
+	}
+}
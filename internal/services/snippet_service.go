@@ -6,75 +6,179 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/cache"
+	"github.com/MohamedElashri/snipo/internal/diff"
+	"github.com/MohamedElashri/snipo/internal/markdown"
 	"github.com/MohamedElashri/snipo/internal/models"
 	"github.com/MohamedElashri/snipo/internal/repository"
 	"github.com/MohamedElashri/snipo/internal/validation"
 )
 
+// publicCacheEntries caps the public snippets kept in memory
+const publicCacheEntries = 10000
+
+// publicFeedKey is the feed's key in the feed cache
+const publicFeedKey = "feed"
+
 // Common errors
 var (
 	ErrSnippetNotFound = errors.New("snippet not found")
 	ErrValidation      = errors.New("validation error")
+	ErrSnippetReadOnly = errors.New("snippet is a read-only remote mirror")
+	ErrHistoryNotFound = errors.New("history entry not found")
 )
 
 // SnippetService handles snippet business logic
 type SnippetService struct {
-	repo               *repository.SnippetRepository
-	tagRepo            *repository.TagRepository
-	folderRepo         *repository.FolderRepository
-	fileRepo           *repository.SnippetFileRepository
-	historyRepo        *repository.HistoryRepository
-	settingsRepo       *repository.SettingsRepository
+	repo               repository.SnippetStore
+	tagRepo            repository.TagStore
+	folderRepo         repository.FolderStore
+	fileRepo           repository.SnippetFileStore
+	historyRepo        repository.HistoryStore
+	activityRepo       repository.ActivityStore
+	settingsRepo       repository.SettingsStore
+	remoteRepo         repository.RemoteSnippetChecker
+	forkClient         *http.Client
+	publicCache        *cache.Cache[publicSnippet]    // nil unless WithPublicCache
+	feedCache          *cache.Cache[[]models.Snippet] // nil unless WithPublicCache
+	shareLinksByID     bool
 	logger             *slog.Logger
 	maxFilesPerSnippet int
 }
 
 // NewSnippetService creates a new snippet service
-func NewSnippetService(repo *repository.SnippetRepository, logger *slog.Logger) *SnippetService {
+func NewSnippetService(repo repository.SnippetStore, logger *slog.Logger) *SnippetService {
 	return &SnippetService{
 		repo:               repo,
+		forkClient:         newForkClient(false),
 		logger:             logger,
 		maxFilesPerSnippet: 10, // Default
+		shareLinksByID:     true,
 	}
 }
 
+// publicSnippet is a public snippet as share links show it, with the ID
+// needed to count views
+type publicSnippet struct {
+	id      string
+	snippet *models.Snippet
+}
+
 // WithTagRepo adds tag repository to the service
-func (s *SnippetService) WithTagRepo(tagRepo *repository.TagRepository) *SnippetService {
+func (s *SnippetService) WithTagRepo(tagRepo repository.TagStore) *SnippetService {
 	s.tagRepo = tagRepo
 	return s
 }
 
 // WithFolderRepo adds folder repository to the service
-func (s *SnippetService) WithFolderRepo(folderRepo *repository.FolderRepository) *SnippetService {
+func (s *SnippetService) WithFolderRepo(folderRepo repository.FolderStore) *SnippetService {
 	s.folderRepo = folderRepo
 	return s
 }
 
 // WithFileRepo adds file repository to the service
-func (s *SnippetService) WithFileRepo(fileRepo *repository.SnippetFileRepository) *SnippetService {
+func (s *SnippetService) WithFileRepo(fileRepo repository.SnippetFileStore) *SnippetService {
 	s.fileRepo = fileRepo
 	return s
 }
 
 // WithHistoryRepo adds history repository to the service
-func (s *SnippetService) WithHistoryRepo(historyRepo *repository.HistoryRepository) *SnippetService {
+func (s *SnippetService) WithHistoryRepo(historyRepo repository.HistoryStore) *SnippetService {
 	s.historyRepo = historyRepo
 	return s
 }
 
+// WithActivityRepo records changes to snippets for the activity feed
+func (s *SnippetService) WithActivityRepo(activityRepo repository.ActivityStore) *SnippetService {
+	s.activityRepo = activityRepo
+	return s
+}
+
 // WithSettingsRepo adds settings repository to the service
-func (s *SnippetService) WithSettingsRepo(settingsRepo *repository.SettingsRepository) *SnippetService {
+func (s *SnippetService) WithSettingsRepo(settingsRepo repository.SettingsStore) *SnippetService {
 	s.settingsRepo = settingsRepo
 	return s
 }
 
+// WithRemoteSourceRepo adds remote source repository so mirrored snippets stay read-only
+func (s *SnippetService) WithRemoteSourceRepo(remoteRepo repository.RemoteSnippetChecker) *SnippetService {
+	s.remoteRepo = remoteRepo
+	return s
+}
+
 // WithMaxFiles sets the maximum files per snippet
 func (s *SnippetService) WithMaxFiles(max int) *SnippetService {
 	s.maxFilesPerSnippet = max
 	return s
 }
 
+// WithPrivateNetworks lets forks fetch from loopback and private addresses
+func (s *SnippetService) WithPrivateNetworks(allowed bool) *SnippetService {
+	s.forkClient = newForkClient(allowed)
+	return s
+}
+
+// WithPublicCache keeps public snippets and the public feed in memory for
+// ttl, so a popular share link doesn't read the database on every request.
+// Changes made through the service show up right away; changes made
+// elsewhere, such as scheduled publishing, within ttl.
+func (s *SnippetService) WithPublicCache(ttl time.Duration) *SnippetService {
+	if ttl > 0 {
+		s.publicCache = cache.New[publicSnippet](ttl, publicCacheEntries)
+		s.feedCache = cache.New[[]models.Snippet](ttl, 1)
+	}
+	return s
+}
+
+// PublicCacheStats returns the public cache's hits and database loads, both
+// zero without the cache
+func (s *SnippetService) PublicCacheStats() (hits, loads int64) {
+	if s.publicCache == nil {
+		return 0, 0
+	}
+	hits, loads = s.publicCache.Stats()
+	feedHits, feedLoads := s.feedCache.Stats()
+	return hits + feedHits, loads + feedLoads
+}
+
+// WithShareLinksByID sets whether public links may use a snippet's ID instead
+// of its public token, so links shared before tokens existed keep working
+func (s *SnippetService) WithShareLinksByID(allowed bool) *SnippetService {
+	s.shareLinksByID = allowed
+	return s
+}
+
+// forgetPublic empties the public cache after a change. Snippets are cached
+// by the key in their link, not their ID, so there is no telling which
+// entries the change affects.
+func (s *SnippetService) forgetPublic() {
+	if s.publicCache == nil {
+		return
+	}
+	s.publicCache.Clear()
+	s.feedCache.Clear()
+}
+
+// checkWritable returns ErrSnippetReadOnly for snippets mirrored from a remote source
+func (s *SnippetService) checkWritable(ctx context.Context, id string) error {
+	if s.remoteRepo == nil {
+		return nil
+	}
+	remote, err := s.remoteRepo.IsRemoteSnippet(ctx, id)
+	if err != nil {
+		return err
+	}
+	if remote {
+		return ErrSnippetReadOnly
+	}
+	return nil
+}
+
 // isHistoryEnabled checks if history tracking is enabled in settings
 func (s *SnippetService) isHistoryEnabled(ctx context.Context) bool {
 	if s.historyRepo == nil || s.settingsRepo == nil {
@@ -113,6 +217,43 @@ func (s *SnippetService) saveHistory(ctx context.Context, snippet *models.Snippe
 	return nil
 }
 
+// recordActivity logs a change to a snippet for the activity feed
+func (s *SnippetService) recordActivity(ctx context.Context, snippetID, title, action string) {
+	// Every change worth recording may change what the public sees
+	s.forgetPublic()
+
+	if s.activityRepo == nil {
+		return
+	}
+	if err := s.activityRepo.Record(ctx, snippetID, title, action); err != nil {
+		s.logger.Warn("failed to record activity", "id", snippetID, "action", action, "error", err)
+	}
+}
+
+// snippetTitles looks up the titles of snippets about to be deleted, so
+// their activity can still name them. It returns nil without an activity
+// repository.
+func (s *SnippetService) snippetTitles(ctx context.Context, ids []string) map[string]string {
+	if s.activityRepo == nil {
+		return nil
+	}
+	titles := make(map[string]string, len(ids))
+	for _, id := range ids {
+		if snippet, _ := s.repo.GetByID(ctx, id); snippet != nil {
+			titles[id] = snippet.Title
+		}
+	}
+	return titles
+}
+
+// deleteAction tells whether a deleted snippet went to the trash or is gone
+func (s *SnippetService) deleteAction(ctx context.Context, id string) string {
+	if snippet, _ := s.repo.GetByID(ctx, id); snippet != nil {
+		return models.ActivityTrashed
+	}
+	return models.ActivityDeleted
+}
+
 // Create creates a new snippet
 func (s *SnippetService) Create(ctx context.Context, input *models.SnippetInput) (*models.Snippet, error) {
 	// Validate input
@@ -120,6 +261,8 @@ func (s *SnippetService) Create(ctx context.Context, input *models.SnippetInput)
 		return nil, errs
 	}
 
+	applyPublishSchedule(input)
+
 	snippet, err := s.repo.Create(ctx, input)
 	if err != nil {
 		s.logger.Error("failed to create snippet", "error", err)
@@ -163,15 +306,50 @@ func (s *SnippetService) Create(ctx context.Context, input *models.SnippetInput)
 		}
 	}
 
+	s.storeChecksum(ctx, snippet)
+
 	// Save to history if enabled
 	if err := s.saveHistory(ctx, snippet, "create"); err != nil {
 		s.logger.Warn("failed to save creation to history", "id", snippet.ID, "error", err)
 	}
 
+	s.recordActivity(ctx, snippet.ID, snippet.Title, models.ActivityCreated)
+
 	s.logger.Info("snippet created", "id", snippet.ID, "title", snippet.Title)
 	return snippet, nil
 }
 
+// storeChecksum persists the snippet checksum so gist sync can compare against it
+// without re-serializing the snippet. Expects snippet.Files to be loaded.
+func (s *SnippetService) storeChecksum(ctx context.Context, snippet *models.Snippet) {
+	checksum, err := CalculateSnippetChecksum(snippet)
+	if err != nil {
+		s.logger.Warn("failed to calculate snippet checksum", "id", snippet.ID, "error", err)
+		return
+	}
+	if err := s.repo.UpdateChecksum(ctx, snippet.ID, checksum); err != nil {
+		s.logger.Warn("failed to store snippet checksum", "id", snippet.ID, "error", err)
+		return
+	}
+	snippet.Checksum = &checksum
+}
+
+// applyPublishSchedule keeps snippets with a future publish_at private until the
+// scheduler publishes them, and publishes right away when the time has passed
+func applyPublishSchedule(input *models.SnippetInput) {
+	if input.PublishAt == nil {
+		return
+	}
+	if !input.PublishAt.After(time.Now()) {
+		input.IsPublic = true
+		input.PublishAt = nil
+		return
+	}
+	publishAt := input.PublishAt.UTC()
+	input.PublishAt = &publishAt
+	input.IsPublic = false
+}
+
 // GetByID retrieves a snippet by ID
 func (s *SnippetService) GetByID(ctx context.Context, id string) (*models.Snippet, error) {
 	snippet, err := s.repo.GetByID(ctx, id)
@@ -205,31 +383,143 @@ func (s *SnippetService) GetByID(ctx context.Context, id string) (*models.Snippe
 	return snippet, nil
 }
 
-// GetByIDPublic retrieves a public snippet by ID and increments view count
-func (s *SnippetService) GetByIDPublic(ctx context.Context, id string) (*models.Snippet, error) {
-	snippet, err := s.repo.GetByID(ctx, id)
+// GetByIDPublic retrieves a public snippet by the key in its share link and
+// increments view count. The key is the snippet's public token, which the
+// snippet's ID is replaced with, or with WithShareLinksByID its ID. The
+// snippet may come from the public cache and must not be modified.
+func (s *SnippetService) GetByIDPublic(ctx context.Context, key string) (*models.Snippet, error) {
+	public, err := s.getPublic(ctx, key)
 	if err != nil {
 		return nil, err
 	}
 
-	if snippet == nil || !snippet.IsPublic {
-		return nil, ErrSnippetNotFound
-	}
-
 	// Increment view count asynchronously
 	go func() {
-		if err := s.repo.IncrementViewCount(context.Background(), id); err != nil {
-			s.logger.Warn("failed to increment view count", "id", id, "error", err)
+		if err := s.repo.IncrementViewCount(context.Background(), public.id); err != nil {
+			s.logger.Warn("failed to increment view count", "id", public.id, "error", err)
 		}
 	}()
 
+	return public.snippet, nil
+}
+
+// LookupPublic retrieves a public snippet like GetByIDPublic without counting
+// a view, for describing a share link rather than showing it
+func (s *SnippetService) LookupPublic(ctx context.Context, key string) (*models.Snippet, error) {
+	public, err := s.getPublic(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return public.snippet, nil
+}
+
+func (s *SnippetService) getPublic(ctx context.Context, key string) (publicSnippet, error) {
+	if s.publicCache != nil {
+		// A shared load must not fail because the request that started it went away
+		loadCtx := context.WithoutCancel(ctx)
+		return s.publicCache.Get(key, func() (publicSnippet, error) {
+			return s.loadPublic(loadCtx, key)
+		})
+	}
+	return s.loadPublic(ctx, key)
+}
+
+// loadPublic reads a public snippet with its files and rendered markdown
+func (s *SnippetService) loadPublic(ctx context.Context, key string) (publicSnippet, error) {
+	id, err := s.repo.PublicID(ctx, key, s.shareLinksByID)
+	if err != nil {
+		return publicSnippet{}, err
+	}
+	if id == "" {
+		return publicSnippet{}, ErrSnippetNotFound
+	}
+
+	snippet, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return publicSnippet{}, err
+	}
+
+	if snippet == nil || !snippet.IsPublic {
+		return publicSnippet{}, ErrSnippetNotFound
+	}
+
 	// Fetch files for public view
 	if s.fileRepo != nil {
 		files, _ := s.fileRepo.GetBySnippetID(ctx, id)
 		snippet.Files = files
 	}
 
-	return snippet, nil
+	s.renderPublicMarkdown(ctx, snippet)
+	publicView(snippet)
+
+	return publicSnippet{id: id, snippet: snippet}, nil
+}
+
+// publicView hides what share links shouldn't reveal: the snippet's ID is
+// replaced with its public token, and its files don't name it either
+func publicView(snippet *models.Snippet) {
+	if snippet.PublicToken != nil {
+		snippet.ID = *snippet.PublicToken
+	}
+	snippet.PublicToken = nil
+	for i := range snippet.Files {
+		snippet.Files[i].SnippetID = ""
+	}
+}
+
+// markdownPolicy returns the configured handling of raw HTML in markdown
+func (s *SnippetService) markdownPolicy(ctx context.Context) string {
+	if s.settingsRepo != nil {
+		if settings, err := s.settingsRepo.Get(ctx); err == nil && settings.MarkdownHTMLPolicy != "" {
+			return settings.MarkdownHTMLPolicy
+		}
+	}
+	return models.MarkdownHTMLSanitize
+}
+
+// RenderMarkdown renders markdown with the same pipeline and raw HTML policy
+// as public pages, so every client shows the same output
+func (s *SnippetService) RenderMarkdown(ctx context.Context, input *models.MarkdownRenderInput) (*models.MarkdownRenderResult, error) {
+	if errs := validation.ValidateMarkdownRenderInput(input); errs.HasErrors() {
+		return nil, errs
+	}
+
+	policy := s.markdownPolicy(ctx)
+	html, err := markdown.RenderWithOptions(input.Content, policy, markdown.Options{
+		LineNumbers: input.LineNumbers,
+		HeadingIDs:  input.HeadingAnchors,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.MarkdownRenderResult{HTML: html, Policy: policy}, nil
+}
+
+// renderPublicMarkdown renders markdown content server-side so raw HTML is
+// handled by the configured policy rather than trusted by the browser
+func (s *SnippetService) renderPublicMarkdown(ctx context.Context, snippet *models.Snippet) {
+	policy := s.markdownPolicy(ctx)
+
+	render := func(content string) string {
+		html, err := markdown.Render(content, policy)
+		if err != nil {
+			s.logger.Warn("failed to render markdown", "id", snippet.ID, "error", err)
+			return ""
+		}
+		return html
+	}
+
+	if len(snippet.Files) == 0 {
+		if snippet.Language == "markdown" {
+			snippet.RenderedHTML = render(snippet.Content)
+		}
+		return
+	}
+	for i := range snippet.Files {
+		if snippet.Files[i].Language == "markdown" {
+			snippet.Files[i].RenderedHTML = render(snippet.Files[i].Content)
+		}
+	}
 }
 
 // Update updates an existing snippet
@@ -239,6 +529,12 @@ func (s *SnippetService) Update(ctx context.Context, id string, input *models.Sn
 		return nil, errs
 	}
 
+	applyPublishSchedule(input)
+
+	if err := s.checkWritable(ctx, id); err != nil {
+		return nil, err
+	}
+
 	// Check if snippet exists and get current state for history
 	existing, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -296,14 +592,68 @@ func (s *SnippetService) Update(ctx context.Context, id string, input *models.Sn
 		} else {
 			snippet.Files = syncedFiles
 		}
+	} else if s.fileRepo != nil {
+		files, _ := s.fileRepo.GetBySnippetID(ctx, id)
+		snippet.Files = files
 	}
 
+	s.storeChecksum(ctx, snippet)
+
+	s.recordActivity(ctx, id, snippet.Title, models.ActivityUpdated)
+
 	s.logger.Info("snippet updated", "id", id)
 	return snippet, nil
 }
 
+// Upsert updates the snippet tagged with externalID, or creates and tags a
+// new one, so declarative tools can apply the same input repeatedly without
+// duplicating it. A trashed match is restored. created reports which
+// happened.
+func (s *SnippetService) Upsert(ctx context.Context, externalID string, input *models.SnippetInput) (snippet *models.Snippet, created bool, err error) {
+	if errs := validation.ValidateExternalID(externalID); errs.HasErrors() {
+		return nil, false, errs
+	}
+
+	existing, err := s.repo.GetByExternalID(ctx, externalID)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		if existing.DeletedAt != nil {
+			if err := s.repo.Restore(ctx, existing.ID); err != nil {
+				return nil, false, err
+			}
+		}
+		snippet, err := s.Update(ctx, existing.ID, input)
+		return snippet, false, err
+	}
+
+	snippet, err = s.Create(ctx, input)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.repo.SetExternalID(ctx, snippet.ID, externalID); err != nil {
+		// A concurrent upsert claimed the ID first: drop ours and update theirs
+		if delErr := s.repo.Delete(ctx, snippet.ID, true); delErr != nil {
+			s.logger.Warn("failed to remove duplicate upserted snippet", "id", snippet.ID, "error", delErr)
+		}
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return s.Upsert(ctx, externalID, input)
+		}
+		return nil, false, err
+	}
+	snippet.ExternalID = &externalID
+
+	return snippet, true, nil
+}
+
 // Delete removes a snippet
 func (s *SnippetService) Delete(ctx context.Context, id string, permanent bool) error {
+	if err := s.checkWritable(ctx, id); err != nil {
+		return err
+	}
+
+	titles := s.snippetTitles(ctx, []string{id})
 	err := s.repo.Delete(ctx, id, permanent)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -313,6 +663,12 @@ func (s *SnippetService) Delete(ctx context.Context, id string, permanent bool)
 		return err
 	}
 
+	if titles != nil {
+		s.recordActivity(ctx, id, titles[id], s.deleteAction(ctx, id))
+	} else {
+		s.forgetPublic()
+	}
+
 	s.logger.Info("snippet deleted", "id", id)
 	return nil
 }
@@ -328,10 +684,117 @@ func (s *SnippetService) Restore(ctx context.Context, id string) error {
 		return err
 	}
 
+	if titles := s.snippetTitles(ctx, []string{id}); titles != nil {
+		s.recordActivity(ctx, id, titles[id], models.ActivityRestored)
+	} else {
+		s.forgetPublic()
+	}
+
 	s.logger.Info("snippet restored", "id", id)
 	return nil
 }
 
+// Bulk applies one action to many snippets atomically. Mirrored snippets
+// can't be changed, and any missing snippet fails the whole batch.
+func (s *SnippetService) Bulk(ctx context.Context, input *models.BulkSnippetInput) (*models.BulkSnippetResult, error) {
+	if errs := validation.ValidateBulkSnippetInput(input); errs.HasErrors() {
+		return nil, errs
+	}
+	if errs := s.checkBulkFolders(ctx, input); errs.HasErrors() {
+		return nil, errs
+	}
+
+	if input.Action != models.BulkRestore {
+		for _, id := range input.IDs {
+			if err := s.checkWritable(ctx, id); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for i := range input.Snippets {
+		applyPublishSchedule(&input.Snippets[i])
+		if len(input.Snippets[i].Files) > s.maxFilesPerSnippet {
+			input.Snippets[i].Files = input.Snippets[i].Files[:s.maxFilesPerSnippet]
+		}
+	}
+
+	titles := s.snippetTitles(ctx, input.IDs)
+	result, err := s.repo.Bulk(ctx, input)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSnippetNotFound
+		}
+		s.logger.Error("failed to apply bulk action", "action", input.Action, "error", err)
+		return nil, err
+	}
+
+	for i := range result.Snippets {
+		snippet := &result.Snippets[i]
+		if s.tagRepo != nil {
+			snippet.Tags, _ = s.tagRepo.GetSnippetTags(ctx, snippet.ID)
+		}
+		if s.folderRepo != nil {
+			snippet.Folders, _ = s.folderRepo.GetSnippetFolders(ctx, snippet.ID)
+		}
+		s.storeChecksum(ctx, snippet)
+		if err := s.saveHistory(ctx, snippet, "create"); err != nil {
+			s.logger.Warn("failed to save creation to history", "id", snippet.ID, "error", err)
+		}
+		s.recordActivity(ctx, snippet.ID, snippet.Title, models.ActivityCreated)
+	}
+	if titles != nil && input.Action != models.BulkCreate {
+		for _, id := range result.IDs {
+			s.recordActivity(ctx, id, titles[id], s.bulkActivity(ctx, input.Action, id))
+		}
+	} else {
+		s.forgetPublic()
+	}
+
+	s.logger.Info("bulk snippet action applied", "action", result.Action, "affected", result.Affected)
+	return result, nil
+}
+
+// bulkActivity returns the activity a bulk action records for a snippet
+func (s *SnippetService) bulkActivity(ctx context.Context, action, id string) string {
+	switch action {
+	case models.BulkDelete:
+		return s.deleteAction(ctx, id)
+	case models.BulkRestore:
+		return models.ActivityRestored
+	case models.BulkArchive:
+		return models.ActivityArchived
+	case models.BulkUnarchive:
+		return models.ActivityUnarchived
+	default:
+		return models.ActivityUpdated
+	}
+}
+
+// checkBulkFolders reports folders named by a bulk request that don't exist
+func (s *SnippetService) checkBulkFolders(ctx context.Context, input *models.BulkSnippetInput) validation.ValidationErrors {
+	var errs validation.ValidationErrors
+	if s.folderRepo == nil {
+		return errs
+	}
+	missing := func(id *int64) bool {
+		if id == nil {
+			return false
+		}
+		_, err := s.folderRepo.GetByID(ctx, *id)
+		return errors.Is(err, repository.ErrNotFound)
+	}
+
+	if input.Action == models.BulkMove && missing(input.FolderID) {
+		errs = append(errs, validation.ValidationError{Field: "folder_id", Message: "Folder not found"})
+	}
+	for i := range input.Snippets {
+		if missing(input.Snippets[i].FolderID) {
+			errs = append(errs, validation.ValidationError{Field: fmt.Sprintf("snippets[%d].folder_id", i), Message: "Folder not found"})
+		}
+	}
+	return errs
+}
+
 // List retrieves snippets with filtering and pagination
 func (s *SnippetService) List(ctx context.Context, filter models.SnippetFilter) (*models.SnippetListResponse, error) {
 	// Apply defaults
@@ -378,6 +841,83 @@ func (s *SnippetService) List(ctx context.Context, filter models.SnippetFilter)
 	return response, nil
 }
 
+// Neighbors returns the previous and next snippets around id under the same
+// filter and sort order List uses
+func (s *SnippetService) Neighbors(ctx context.Context, id string, filter models.SnippetFilter) (*models.SnippetNeighbors, error) {
+	neighbors, err := s.repo.Neighbors(ctx, id, filter)
+	if err != nil {
+		return nil, err
+	}
+	if neighbors == nil {
+		return nil, ErrSnippetNotFound
+	}
+	return neighbors, nil
+}
+
+// ListPublic retrieves every public, non-archived snippet with its files and tags.
+// Folders are omitted since they describe the owner's private organization.
+// The list may come from the public cache and must not be modified.
+func (s *SnippetService) ListPublic(ctx context.Context) ([]models.Snippet, error) {
+	if s.feedCache != nil {
+		loadCtx := context.WithoutCancel(ctx)
+		return s.feedCache.Get(publicFeedKey, func() ([]models.Snippet, error) {
+			return s.listPublic(loadCtx)
+		})
+	}
+	return s.listPublic(ctx)
+}
+
+// PublicFeedEntries returns the most recently updated public snippets, at
+// most limit of them and only those tagged tag if it isn't empty, with their
+// markdown rendered like on the share pages
+func (s *SnippetService) PublicFeedEntries(ctx context.Context, tag string, limit int) ([]models.Snippet, error) {
+	snippets, err := s.ListPublic(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []models.Snippet{}
+	for _, snippet := range snippets {
+		if len(entries) == limit {
+			break
+		}
+		if tag != "" && !slices.ContainsFunc(snippet.Tags, func(t models.Tag) bool { return strings.EqualFold(t.Name, tag) }) {
+			continue
+		}
+		// The list is shared through the cache, so render into a copy
+		snippet.Files = slices.Clone(snippet.Files)
+		s.renderPublicMarkdown(ctx, &snippet)
+		entries = append(entries, snippet)
+	}
+	return entries, nil
+}
+
+func (s *SnippetService) listPublic(ctx context.Context) ([]models.Snippet, error) {
+	isPublic := true
+	snippets := []models.Snippet{}
+	for page := 1; ; page++ {
+		resp, err := s.List(ctx, models.SnippetFilter{
+			IsPublic:  &isPublic,
+			Page:      page,
+			Limit:     100,
+			SortBy:    "updated_at",
+			SortOrder: "desc",
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, snippet := range resp.Data {
+			snippet.Folders = nil
+			publicView(&snippet)
+			snippets = append(snippets, snippet)
+		}
+		if len(resp.Data) < 100 {
+			break
+		}
+	}
+	return snippets, nil
+}
+
 // ToggleFavorite toggles the favorite status of a snippet
 func (s *SnippetService) ToggleFavorite(ctx context.Context, id string) (*models.Snippet, error) {
 	snippet, err := s.repo.ToggleFavorite(ctx, id)
@@ -406,14 +946,73 @@ func (s *SnippetService) ToggleArchive(ctx context.Context, id string) (*models.
 		return nil, ErrSnippetNotFound
 	}
 
+	action := models.ActivityUnarchived
+	if snippet.IsArchived {
+		action = models.ActivityArchived
+	}
+	s.recordActivity(ctx, id, snippet.Title, action)
+
 	s.logger.Info("snippet archive toggled", "id", id, "is_archived", snippet.IsArchived)
 	return snippet, nil
 }
 
-// Search performs full-text search on snippets
-func (s *SnippetService) Search(ctx context.Context, query string, limit int) ([]models.Snippet, error) {
+// RecordUse counts a copy or other use of a snippet
+func (s *SnippetService) RecordUse(ctx context.Context, id string) (*models.Snippet, error) {
+	snippet, err := s.repo.RecordUse(ctx, id)
+	if err != nil {
+		s.logger.Error("failed to record snippet use", "id", id, "error", err)
+		return nil, err
+	}
+
+	if snippet == nil {
+		return nil, ErrSnippetNotFound
+	}
+
+	return snippet, nil
+}
+
+// RecordPublicView counts a visit to the share page with the given key,
+// tallying the referring domain if there is one. Visits to snippets that are
+// not public are ignored.
+func (s *SnippetService) RecordPublicView(ctx context.Context, key, referrerDomain string) error {
+	id, err := s.repo.PublicID(ctx, key, s.shareLinksByID)
+	if err != nil || id == "" {
+		return err
+	}
+	if err := s.repo.RecordPublicView(ctx, id, referrerDomain); err != nil {
+		s.logger.Warn("failed to record public view", "id", id, "error", err)
+		return err
+	}
+	return nil
+}
+
+// GetViewStats returns a snippet's view counters and referring domains
+func (s *SnippetService) GetViewStats(ctx context.Context, id string) (*models.SnippetViewStats, error) {
+	snippet, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if snippet == nil {
+		return nil, ErrSnippetNotFound
+	}
+
+	referrers, err := s.repo.GetReferrers(ctx, id)
+	if err != nil {
+		s.logger.Error("failed to get referrers", "id", id, "error", err)
+		return nil, err
+	}
+
+	return &models.SnippetViewStats{
+		ViewCount:       snippet.ViewCount,
+		PublicViewCount: snippet.PublicViewCount,
+		Referrers:       referrers,
+	}, nil
+}
+
+// Search performs ranked full-text search on snippets
+func (s *SnippetService) Search(ctx context.Context, query string, limit int) ([]models.SearchResult, error) {
 	if query == "" {
-		return []models.Snippet{}, nil
+		return []models.SearchResult{}, nil
 	}
 
 	snippets, err := s.repo.Search(ctx, query, limit)
@@ -425,6 +1024,17 @@ func (s *SnippetService) Search(ctx context.Context, query string, limit int) ([
 	return snippets, nil
 }
 
+// ExportSearchIndex returns the titles and tags of the visible snippets for
+// local filtering, or only the changes from since on
+func (s *SnippetService) ExportSearchIndex(ctx context.Context, since *time.Time) (*models.SearchIndexExport, error) {
+	export, err := s.repo.ExportSearchIndex(ctx, since)
+	if err != nil {
+		s.logger.Error("failed to export search index", "error", err)
+		return nil, err
+	}
+	return export, nil
+}
+
 // Duplicate creates a copy of an existing snippet
 func (s *SnippetService) Duplicate(ctx context.Context, id string) (*models.Snippet, error) {
 	existing, err := s.repo.GetByID(ctx, id)
@@ -470,6 +1080,144 @@ func (s *SnippetService) GetHistory(ctx context.Context, id string, limit int) (
 	return history, nil
 }
 
+// Activity returns the activity feed of the snippets visible in ctx
+func (s *SnippetService) Activity(ctx context.Context, filter models.ActivityFilter) ([]models.ActivityItem, error) {
+	if s.activityRepo == nil {
+		return nil, fmt.Errorf("activity repository not configured")
+	}
+	return s.activityRepo.Feed(ctx, filter)
+}
+
+// SnippetActivity returns the activity feed of one snippet
+func (s *SnippetService) SnippetActivity(ctx context.Context, id string, filter models.ActivityFilter) ([]models.ActivityItem, error) {
+	snippet, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if snippet == nil {
+		return nil, ErrSnippetNotFound
+	}
+
+	filter.SnippetID = id
+	return s.Activity(ctx, filter)
+}
+
+// DiffHistory compares two history entries of a snippet, returning the
+// changed fields and a unified diff for each file
+func (s *SnippetService) DiffHistory(ctx context.Context, snippetID string, fromID, toID int64) (*models.HistoryDiff, error) {
+	if s.historyRepo == nil {
+		return nil, fmt.Errorf("history repository not configured")
+	}
+
+	snippet, err := s.repo.GetByID(ctx, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	if snippet == nil {
+		return nil, ErrSnippetNotFound
+	}
+
+	from, err := s.snippetHistoryEntry(ctx, snippetID, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.snippetHistoryEntry(ctx, snippetID, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.HistoryDiff{
+		SnippetID: snippetID,
+		FromID:    fromID,
+		ToID:      toID,
+		Fields:    historyFieldChanges(from, to),
+		Files:     historyFileDiffs(from, to),
+	}, nil
+}
+
+// snippetHistoryEntry loads a history entry, treating entries of other
+// snippets as missing
+func (s *SnippetService) snippetHistoryEntry(ctx context.Context, snippetID string, historyID int64) (*models.SnippetHistory, error) {
+	entry, err := s.historyRepo.GetHistoryByID(ctx, historyID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil || entry.SnippetID != snippetID {
+		return nil, ErrHistoryNotFound
+	}
+	return entry, nil
+}
+
+func historyFieldChanges(from, to *models.SnippetHistory) []models.FieldChange {
+	changes := []models.FieldChange{}
+	add := func(field string, a, b any) {
+		if a != b {
+			changes = append(changes, models.FieldChange{Field: field, From: a, To: b})
+		}
+	}
+	add("title", from.Title, to.Title)
+	add("description", from.Description, to.Description)
+	add("language", from.Language, to.Language)
+	add("is_public", from.IsPublic, to.IsPublic)
+	add("is_archived", from.IsArchived, to.IsArchived)
+	add("is_favorite", from.IsFavorite, to.IsFavorite)
+	return changes
+}
+
+// historyFiles returns an entry's files by name. Entries saved before
+// multi-file snippets have no files, so their content stands in as one.
+func historyFiles(h *models.SnippetHistory) ([]string, map[string]string) {
+	if len(h.Files) == 0 {
+		return []string{"content"}, map[string]string{"content": h.Content}
+	}
+	names := make([]string, 0, len(h.Files))
+	contents := make(map[string]string, len(h.Files))
+	for _, f := range h.Files {
+		if _, ok := contents[f.Filename]; !ok {
+			names = append(names, f.Filename)
+		}
+		contents[f.Filename] = f.Content
+	}
+	return names, contents
+}
+
+// historyFileDiffs diffs files matched by name, listing the newer entry's
+// files in order followed by the ones it removed
+func historyFileDiffs(from, to *models.SnippetHistory) []models.FileDiff {
+	fromNames, fromFiles := historyFiles(from)
+	toNames, toFiles := historyFiles(to)
+
+	diffs := make([]models.FileDiff, 0, len(toNames))
+	for _, name := range toNames {
+		old, ok := fromFiles[name]
+		if !ok {
+			diffs = append(diffs, models.FileDiff{
+				Filename: name,
+				Status:   models.FileDiffAdded,
+				Diff:     diff.Unified("/dev/null", "b/"+name, "", toFiles[name], diff.DefaultContext),
+			})
+			continue
+		}
+		d := diff.Unified("a/"+name, "b/"+name, old, toFiles[name], diff.DefaultContext)
+		status := models.FileDiffModified
+		if d == "" {
+			status = models.FileDiffUnchanged
+		}
+		diffs = append(diffs, models.FileDiff{Filename: name, Status: status, Diff: d})
+	}
+	for _, name := range fromNames {
+		if _, ok := toFiles[name]; ok {
+			continue
+		}
+		diffs = append(diffs, models.FileDiff{
+			Filename: name,
+			Status:   models.FileDiffRemoved,
+			Diff:     diff.Unified("a/"+name, "/dev/null", fromFiles[name], "", diff.DefaultContext),
+		})
+	}
+	return diffs
+}
+
 // RestoreFromHistory restores a snippet from a specific history entry
 func (s *SnippetService) RestoreFromHistory(ctx context.Context, snippetID string, historyID int64) (*models.Snippet, error) {
 	if s.historyRepo == nil {
@@ -536,6 +1284,7 @@ func (s *SnippetService) RestoreFromHistory(ctx context.Context, snippetID strin
 				Filename: hf.Filename,
 				Content:  hf.Content,
 				Language: hf.Language,
+				Note:     hf.Note,
 			}
 		}
 
@@ -545,8 +1294,13 @@ func (s *SnippetService) RestoreFromHistory(ctx context.Context, snippetID strin
 		} else {
 			snippet.Files = restoredFiles
 		}
+	} else if s.fileRepo != nil {
+		files, _ := s.fileRepo.GetBySnippetID(ctx, snippetID)
+		snippet.Files = files
 	}
 
+	s.storeChecksum(ctx, snippet)
+
 	// Fetch tags and folders
 	if s.tagRepo != nil {
 		tags, _ := s.tagRepo.GetSnippetTags(ctx, snippetID)
@@ -557,6 +1311,8 @@ func (s *SnippetService) RestoreFromHistory(ctx context.Context, snippetID strin
 		snippet.Folders = folders
 	}
 
+	s.recordActivity(ctx, snippetID, snippet.Title, models.ActivityReverted)
+
 	s.logger.Info("snippet restored from history", "id", snippetID, "history_id", historyID)
 	return snippet, nil
 }
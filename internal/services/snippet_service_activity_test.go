@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestSnippetService_Activity(t *testing.T) {
+	db := testutil.TestDB(t)
+	snippetRepo := repository.NewSnippetRepository(db)
+	service := NewSnippetService(snippetRepo, testutil.TestLogger()).
+		WithActivityRepo(repository.NewActivityRepository(db))
+	ctx := testutil.TestContext()
+
+	input := &models.SnippetInput{Title: "deploy.sh", Content: "echo hi", Language: "bash"}
+	snippet, err := service.Create(ctx, input)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for range 3 {
+		if _, err := service.Update(ctx, snippet.ID, input); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+	if _, err := service.ToggleArchive(ctx, snippet.ID); err != nil {
+		t.Fatalf("ToggleArchive failed: %v", err)
+	}
+	if err := service.Delete(ctx, snippet.ID, false); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := service.Delete(ctx, snippet.ID, true); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	items, err := service.Activity(ctx, models.ActivityFilter{})
+	if err != nil {
+		t.Fatalf("Activity failed: %v", err)
+	}
+	want := []struct {
+		action string
+		count  int
+	}{
+		{models.ActivityDeleted, 1},
+		{models.ActivityTrashed, 1},
+		{models.ActivityArchived, 1},
+		{models.ActivityUpdated, 3},
+		{models.ActivityCreated, 1},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("expected %d items, got %+v", len(want), items)
+	}
+	for i, w := range want {
+		if items[i].Action != w.action || items[i].Count != w.count || items[i].Title != "deploy.sh" {
+			t.Errorf("item %d: expected %s x%d, got %+v", i, w.action, w.count, items[i])
+		}
+	}
+	if items[3].Summary != `Updated "deploy.sh" 3 times` {
+		t.Errorf("unexpected summary %q", items[3].Summary)
+	}
+
+	// The snippet is gone, so its own feed is too
+	if _, err := service.SnippetActivity(ctx, snippet.ID, models.ActivityFilter{}); !errors.Is(err, ErrSnippetNotFound) {
+		t.Errorf("expected ErrSnippetNotFound for a deleted snippet, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/MohamedElashri/snipo/internal/models"
@@ -495,3 +496,106 @@ func TestSnippetService_Update_CreatesHistoryEntry(t *testing.T) {
 		t.Errorf("expected history count to increase by 1, from %d to %d, got %d", initialCount, initialCount+1, newCount)
 	}
 }
+
+func TestSnippetService_DiffHistory(t *testing.T) {
+	db := testutil.TestDB(t)
+	snippetRepo := repository.NewSnippetRepository(db)
+	historyRepo := repository.NewHistoryRepository(db)
+	fileRepo := repository.NewSnippetFileRepository(db)
+	settingsRepo := repository.NewSettingsRepository(db)
+	logger := testutil.TestLogger()
+
+	service := NewSnippetService(snippetRepo, logger).
+		WithFileRepo(fileRepo).
+		WithHistoryRepo(historyRepo).
+		WithSettingsRepo(settingsRepo)
+
+	ctx := testutil.TestContext()
+
+	snippet, err := service.Create(ctx, &models.SnippetInput{
+		Title:    "Diff Test",
+		Language: "go",
+		Files: []models.SnippetFileInput{
+			{Filename: "main.go", Content: "package main\n\nfunc main() {\n\tprintln(\"v1\")\n}\n", Language: "go"},
+			{Filename: "utils.go", Content: "package main\n", Language: "go"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// History is saved before each update, so the second update records v2
+	versions := []*models.SnippetInput{
+		{
+			Title:    "Diff Test v2",
+			Language: "go",
+			Files: []models.SnippetFileInput{
+				{Filename: "main.go", Content: "package main\n\nfunc main() {\n\tprintln(\"v2\")\n}\n", Language: "go"},
+				{Filename: "extra.go", Content: "package main\n\nvar x = 1\n", Language: "go"},
+			},
+		},
+		{Title: "Diff Test v3", Content: "v3", Language: "go"},
+	}
+	for _, input := range versions {
+		if _, err := service.Update(ctx, snippet.ID, input); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+
+	history, err := historyRepo.GetSnippetHistory(ctx, snippet.ID, 50)
+	if err != nil {
+		t.Fatalf("GetSnippetHistory failed: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("expected 3 history entries, got %d", len(history))
+	}
+	newest, oldest := history[0], history[len(history)-1]
+
+	result, err := service.DiffHistory(ctx, snippet.ID, oldest.ID, newest.ID)
+	if err != nil {
+		t.Fatalf("DiffHistory failed: %v", err)
+	}
+
+	if len(result.Fields) != 1 || result.Fields[0].Field != "title" || result.Fields[0].To != "Diff Test v2" {
+		t.Errorf("expected only the title to change, got %+v", result.Fields)
+	}
+
+	want := map[string]string{
+		"main.go":  models.FileDiffModified,
+		"extra.go": models.FileDiffAdded,
+		"utils.go": models.FileDiffRemoved,
+	}
+	if len(result.Files) != len(want) {
+		t.Fatalf("expected %d file diffs, got %+v", len(want), result.Files)
+	}
+	for _, f := range result.Files {
+		if f.Status != want[f.Filename] {
+			t.Errorf("expected %s to be %s, got %s", f.Filename, want[f.Filename], f.Status)
+		}
+	}
+	if d := result.Files[0].Diff; result.Files[0].Filename != "main.go" ||
+		d != "--- a/main.go\n+++ b/main.go\n@@ -1,5 +1,5 @@\n package main\n \n func main() {\n-\tprintln(\"v1\")\n+\tprintln(\"v2\")\n }\n" {
+		t.Errorf("unexpected main.go diff:\n%s", d)
+	}
+
+	// Identical entries produce no changes
+	result, err = service.DiffHistory(ctx, snippet.ID, oldest.ID, oldest.ID)
+	if err != nil {
+		t.Fatalf("DiffHistory failed: %v", err)
+	}
+	if len(result.Fields) != 0 {
+		t.Errorf("expected no field changes, got %+v", result.Fields)
+	}
+	for _, f := range result.Files {
+		if f.Status != models.FileDiffUnchanged || f.Diff != "" {
+			t.Errorf("expected %s to be unchanged, got %+v", f.Filename, f)
+		}
+	}
+
+	if _, err := service.DiffHistory(ctx, snippet.ID, oldest.ID, 99999); !errors.Is(err, ErrHistoryNotFound) {
+		t.Errorf("expected ErrHistoryNotFound, got %v", err)
+	}
+	if _, err := service.DiffHistory(ctx, "nonexistent-id", oldest.ID, newest.ID); !errors.Is(err, ErrSnippetNotFound) {
+		t.Errorf("expected ErrSnippetNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestSnippetService_GetByIDPublic_RendersMarkdown(t *testing.T) {
+	db := testutil.TestDB(t)
+	service := NewSnippetService(repository.NewSnippetRepository(db), testutil.TestLogger()).
+		WithFileRepo(repository.NewSnippetFileRepository(db)).
+		WithSettingsRepo(repository.NewSettingsRepository(db))
+	ctx := testutil.TestContext()
+
+	snippet, err := service.Create(ctx, &models.SnippetInput{
+		Title:    "Readme",
+		IsPublic: true,
+		Files: []models.SnippetFileInput{
+			{Filename: "README.md", Content: "# Hi\n\n<img src=x onerror=\"alert(1)\">", Language: "markdown"},
+			{Filename: "main.go", Content: "package main", Language: "go"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	public, err := service.GetByIDPublic(ctx, snippet.ID)
+	if err != nil {
+		t.Fatalf("GetByIDPublic failed: %v", err)
+	}
+	if len(public.Files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(public.Files))
+	}
+	for _, f := range public.Files {
+		switch f.Language {
+		case "markdown":
+			if !strings.Contains(f.RenderedHTML, "<h1") || strings.Contains(f.RenderedHTML, "onerror") {
+				t.Errorf("expected sanitized HTML, got %q", f.RenderedHTML)
+			}
+		default:
+			if f.RenderedHTML != "" {
+				t.Errorf("expected no rendered HTML for %s", f.Filename)
+			}
+		}
+	}
+
+	// The escape policy drops the raw HTML entirely
+	if err := repository.NewSettingsRepository(db).Set(ctx, "markdown_html_policy", models.MarkdownHTMLEscape); err != nil {
+		t.Fatalf("failed to update policy: %v", err)
+	}
+	public, err = service.GetByIDPublic(ctx, snippet.ID)
+	if err != nil {
+		t.Fatalf("GetByIDPublic failed: %v", err)
+	}
+	for _, f := range public.Files {
+		if f.Language == "markdown" && strings.Contains(f.RenderedHTML, "<img") {
+			t.Errorf("expected raw HTML to be dropped, got %q", f.RenderedHTML)
+		}
+	}
+}
+
+func TestSnippetService_RenderMarkdown(t *testing.T) {
+	db := testutil.TestDB(t)
+	settingsRepo := repository.NewSettingsRepository(db)
+	service := NewSnippetService(repository.NewSnippetRepository(db), testutil.TestLogger()).
+		WithSettingsRepo(settingsRepo)
+	ctx := testutil.TestContext()
+
+	input := &models.MarkdownRenderInput{Content: "# Hi\n\n<img src=x onerror=\"alert(1)\">\n\n```sh\nls\n```\n", LineNumbers: true, HeadingAnchors: true}
+	result, err := service.RenderMarkdown(ctx, input)
+	if err != nil {
+		t.Fatalf("RenderMarkdown failed: %v", err)
+	}
+	if result.Policy != models.MarkdownHTMLSanitize || strings.Contains(result.HTML, "onerror") {
+		t.Errorf("expected sanitized HTML, got %+v", result)
+	}
+	if !strings.Contains(result.HTML, `<h1 id="hi">`) || !strings.Contains(result.HTML, `<span class="line-number">1</span>ls`) {
+		t.Errorf("expected heading anchors and line numbers, got %q", result.HTML)
+	}
+
+	// The public pages' policy applies here too
+	if err := settingsRepo.Set(ctx, "markdown_html_policy", models.MarkdownHTMLEscape); err != nil {
+		t.Fatalf("failed to update policy: %v", err)
+	}
+	result, err = service.RenderMarkdown(ctx, input)
+	if err != nil {
+		t.Fatalf("RenderMarkdown failed: %v", err)
+	}
+	if result.Policy != models.MarkdownHTMLEscape || strings.Contains(result.HTML, "<img") {
+		t.Errorf("expected raw HTML to be dropped, got %+v", result)
+	}
+
+	if _, err := service.RenderMarkdown(ctx, &models.MarkdownRenderInput{Content: strings.Repeat("x", 1024*1024+1)}); err == nil {
+		t.Error("expected content over 1MB to be rejected")
+	}
+}
+
+func TestSnippetService_PublicCache(t *testing.T) {
+	db := testutil.TestDB(t)
+	service := NewSnippetService(repository.NewSnippetRepository(db), testutil.TestLogger()).
+		WithActivityRepo(repository.NewActivityRepository(db)).
+		WithPublicCache(time.Minute)
+	ctx := testutil.TestContext()
+
+	input := &models.SnippetInput{Title: "cached", Content: "v1", Language: "plaintext", IsPublic: true}
+	snippet, err := service.Create(ctx, input)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := service.GetByIDPublic(ctx, snippet.ID); err != nil {
+		t.Fatalf("GetByIDPublic failed: %v", err)
+	}
+	if feed, err := service.ListPublic(ctx); err != nil || len(feed) != 1 {
+		t.Fatalf("expected 1 public snippet, got %d (%v)", len(feed), err)
+	}
+
+	// Changes behind the service's back wait for the TTL
+	if _, err := db.Exec(`UPDATE snippets SET content = 'direct' WHERE id = ?`, snippet.ID); err != nil {
+		t.Fatal(err)
+	}
+	if cached, _ := service.GetByIDPublic(ctx, snippet.ID); cached.Content != "v1" {
+		t.Errorf("expected the cached content, got %q", cached.Content)
+	}
+	if hits, loads := service.PublicCacheStats(); hits != 1 || loads != 2 {
+		t.Errorf("expected 1 hit and 2 loads, got %d and %d", hits, loads)
+	}
+
+	// Changes through the service show up at once
+	input.Content = "v2"
+	if _, err := service.Update(ctx, snippet.ID, input); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if fresh, _ := service.GetByIDPublic(ctx, snippet.ID); fresh.Content != "v2" {
+		t.Errorf("expected the updated content, got %q", fresh.Content)
+	}
+	input.IsPublic = false
+	if _, err := service.Update(ctx, snippet.ID, input); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, err := service.GetByIDPublic(ctx, snippet.ID); !errors.Is(err, ErrSnippetNotFound) {
+		t.Errorf("expected a snippet made private to disappear, got %v", err)
+	}
+	if feed, _ := service.ListPublic(ctx); len(feed) != 0 {
+		t.Errorf("expected an empty public feed, got %d", len(feed))
+	}
+}
+
+func TestSnippetService_GetByIDPublic_HidesID(t *testing.T) {
+	db := testutil.TestDB(t)
+	service := NewSnippetService(repository.NewSnippetRepository(db), testutil.TestLogger()).
+		WithFileRepo(repository.NewSnippetFileRepository(db))
+	ctx := testutil.TestContext()
+
+	snippet, err := service.Create(ctx, &models.SnippetInput{
+		Title:    "Shared",
+		IsPublic: true,
+		Files:    []models.SnippetFileInput{{Filename: "main.go", Content: "package main", Language: "go"}},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	token := *snippet.PublicToken
+
+	public, err := service.GetByIDPublic(ctx, token)
+	if err != nil {
+		t.Fatalf("GetByIDPublic failed: %v", err)
+	}
+	if public.ID != token || public.PublicToken != nil {
+		t.Errorf("expected the ID to be replaced with the token, got %q", public.ID)
+	}
+	if len(public.Files) != 1 || public.Files[0].SnippetID != "" {
+		t.Errorf("expected files not to name the snippet, got %+v", public.Files)
+	}
+
+	feed, err := service.ListPublic(ctx)
+	if err != nil || len(feed) != 1 || feed[0].ID != token {
+		t.Errorf("expected the feed to list the snippet by token, got %+v (%v)", feed, err)
+	}
+
+	if _, err := service.GetByIDPublic(ctx, snippet.ID); err != nil {
+		t.Errorf("expected the ID to work by default, got %v", err)
+	}
+	service.WithShareLinksByID(false)
+	if _, err := service.GetByIDPublic(ctx, snippet.ID); !errors.Is(err, ErrSnippetNotFound) {
+		t.Errorf("expected the ID to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestSnippetService_Upsert(t *testing.T) {
+	db := testutil.TestDB(t)
+	repo := repository.NewSnippetRepository(db)
+	service := NewSnippetService(repo, testutil.TestLogger()).
+		WithTagRepo(repository.NewTagRepository(db))
+	ctx := testutil.TestContext()
+
+	first, created, err := service.Upsert(ctx, "runbook", &models.SnippetInput{Title: "Runbook", Content: "v1", Tags: []string{"ops"}})
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if !created || first.ExternalID == nil || *first.ExternalID != "runbook" {
+		t.Fatalf("expected a new snippet tagged runbook, got created=%v %+v", created, first.ExternalID)
+	}
+
+	if err := service.Delete(ctx, first.ID, false); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	second, created, err := service.Upsert(ctx, "runbook", &models.SnippetInput{Title: "Runbook", Content: "v2", Tags: []string{"ops"}})
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if created || second.ID != first.ID || second.Content != "v2" || second.DeletedAt != nil {
+		t.Errorf("expected %s to be restored and updated, got created=%v %+v", first.ID, created, second)
+	}
+
+	// External IDs are per user
+	other, created, err := service.Upsert(models.WithUserID(ctx, 7), "runbook", &models.SnippetInput{Title: "Runbook", Content: "mine"})
+	if err != nil {
+		t.Fatalf("Upsert as another user failed: %v", err)
+	}
+	if !created || other.ID == first.ID {
+		t.Errorf("expected another user's upsert to create a separate snippet")
+	}
+
+	if _, _, err := service.Upsert(ctx, " ", &models.SnippetInput{Title: "Blank", Content: "x"}); err == nil {
+		t.Error("expected a blank external ID to be rejected")
+	}
+}
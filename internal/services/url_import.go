@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/outbound"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+// URL import errors
+var (
+	ErrBlockedAddress = outbound.ErrBlockedAddress
+	ErrNotTextFile    = errors.New("file is not UTF-8 text")
+	ErrFileTooLarge   = errors.New("file is larger than 1MB")
+)
+
+const (
+	// maxImportFileSize matches the snippet content limit
+	maxImportFileSize = 1024 * 1024
+	// importFetchTimeout bounds each download, redirects included
+	importFetchTimeout = 10 * time.Second
+	// importConcurrency is how many URLs are fetched at once
+	importConcurrency = 4
+)
+
+// URLImportService snapshots raw files from the web as snippets
+type URLImportService struct {
+	snippets *SnippetService
+	reports  *repository.ImportReportRepository
+	client   *http.Client
+	logger   *slog.Logger
+	now      func() time.Time
+}
+
+// NewURLImportService creates a new URL import service. Downloads only
+// connect to public addresses unless private networks are allowed.
+func NewURLImportService(snippets *SnippetService, logger *slog.Logger) *URLImportService {
+	return &URLImportService{
+		snippets: snippets,
+		client:   outbound.NewClient(outbound.Options{Timeout: importFetchTimeout}),
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// WithPrivateNetworks lets imports reach loopback and private addresses
+func (s *URLImportService) WithPrivateNetworks(allowed bool) *URLImportService {
+	s.client = outbound.NewClient(outbound.Options{Timeout: importFetchTimeout, AllowPrivate: allowed})
+	return s
+}
+
+// WithImportReports saves a report of every import, readable at
+// /api/v1/imports/{id}/report
+func (s *URLImportService) WithImportReports(repo *repository.ImportReportRepository) *URLImportService {
+	s.reports = repo
+	return s
+}
+
+// Import fetches every URL and creates a snippet from each one that is a
+// text file within the size limit. Failures are reported per URL and don't
+// stop the others.
+func (s *URLImportService) Import(ctx context.Context, input *models.URLImportInput) (*models.URLImportResult, error) {
+	if errs := validation.ValidateURLImportInput(input); errs.HasErrors() {
+		return nil, errs
+	}
+
+	// Downloads run concurrently; snippets are created in order afterwards
+	contents := make([]string, len(input.URLs))
+	fetchErrs := make([]error, len(input.URLs))
+	sem := make(chan struct{}, importConcurrency)
+	var wg sync.WaitGroup
+	for i, raw := range input.URLs {
+		wg.Add(1)
+		go func(i int, raw string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			contents[i], fetchErrs[i] = s.fetch(ctx, rawFileURL(raw))
+		}(i, raw)
+	}
+	wg.Wait()
+
+	result := &models.URLImportResult{Items: make([]models.URLImportItem, len(input.URLs))}
+	for i, raw := range input.URLs {
+		item := &result.Items[i]
+		item.URL = raw
+		if fetchErrs[i] != nil {
+			item.Error = fetchErrs[i].Error()
+			result.Failed++
+			continue
+		}
+
+		snippet, err := s.create(ctx, raw, contents[i], input)
+		if err != nil {
+			var validationErrs validation.ValidationErrors
+			if !errors.As(err, &validationErrs) {
+				s.logger.Error("failed to create imported snippet", "url", raw, "error", err)
+			}
+			item.Error = err.Error()
+			result.Failed++
+			continue
+		}
+		item.Snippet = snippet
+		result.Created++
+	}
+
+	s.logger.Info("URLs imported", "created", result.Created, "failed", result.Failed)
+	s.saveReport(ctx, result)
+	return result, nil
+}
+
+// saveReport keeps the outcome of an import and sets its report ID, logging
+// rather than failing when it can't be saved
+func (s *URLImportService) saveReport(ctx context.Context, result *models.URLImportResult) {
+	if s.reports == nil {
+		return
+	}
+
+	report := &models.ImportReport{
+		Source:  models.ImportSourceURLs,
+		Created: result.Created,
+		Failed:  result.Failed,
+		Items:   make([]models.ImportItemResult, len(result.Items)),
+	}
+	for i, item := range result.Items {
+		report.Items[i] = models.ImportItemResult{SourceID: item.URL, Action: "failed", Error: item.Error}
+		if item.Snippet != nil {
+			report.Items[i].Action = "created"
+			report.Items[i].Title = item.Snippet.Title
+			report.Items[i].SnippetID = item.Snippet.ID
+		}
+	}
+	if err := s.reports.Create(ctx, report); err != nil {
+		s.logger.Warn("failed to save import report", "error", err)
+		return
+	}
+	result.ReportID = report.ID
+}
+
+// create saves one downloaded file and records the URL it came from
+func (s *URLImportService) create(ctx context.Context, rawURL, content string, input *models.URLImportInput) (*models.Snippet, error) {
+	name := importFileName(rawURL)
+	language := getLanguageFromFilename(name)
+	if language == "plaintext" {
+		language = guessLanguage(content)
+	}
+	if !validation.IsAllowedLanguage(language) {
+		language = "plaintext"
+	}
+	title := name
+	if title == "" {
+		title = quickTitle(content, s.now())
+	}
+
+	snippet, err := s.snippets.Create(ctx, &models.SnippetInput{
+		Title:    title,
+		Content:  content,
+		Language: language,
+		Tags:     append([]string{}, input.Tags...),
+		FolderID: input.FolderID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	provenance := &models.Provenance{SourceURL: rawURL, ForkedAt: s.now().UTC()}
+	if err := s.snippets.repo.SetProvenance(ctx, snippet.ID, provenance); err != nil {
+		s.logger.Warn("failed to record import provenance", "id", snippet.ID, "error", err)
+	} else {
+		snippet.Provenance = provenance
+	}
+	return snippet, nil
+}
+
+// fetch downloads a text file of at most maxImportFileSize bytes
+func (s *URLImportService) fetch(ctx context.Context, rawURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	req.Header.Set("Accept", "text/plain, */*;q=0.5")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrBlockedAddress) {
+			return "", ErrBlockedAddress
+		}
+		return "", fmt.Errorf("fetch failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetch failed: unexpected status code %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxImportFileSize {
+		return "", ErrFileTooLarge
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxImportFileSize+1))
+	if err != nil {
+		return "", fmt.Errorf("fetch failed: %w", err)
+	}
+	if len(body) > maxImportFileSize {
+		return "", ErrFileTooLarge
+	}
+	if !utf8.Valid(body) || strings.ContainsRune(string(body), 0) {
+		return "", ErrNotTextFile
+	}
+	return string(body), nil
+}
+
+// rawFileURL points links to rendered pages on common hosts at the raw
+// file instead, so a copied browser URL imports the code rather than HTML
+func rawFileURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	switch strings.ToLower(u.Host) {
+	case "github.com", "www.github.com":
+		// /{owner}/{repo}/blob/{ref}/{path...}
+		if len(parts) >= 5 && parts[2] == "blob" {
+			return "https://raw.githubusercontent.com/" + strings.Join(append(parts[:2:2], parts[3:]...), "/")
+		}
+	case "gist.github.com":
+		// /{owner}/{id} serves the first file raw at /raw
+		if len(parts) == 2 {
+			return "https://gist.githubusercontent.com/" + parts[0] + "/" + parts[1] + "/raw"
+		}
+	case "pastebin.com", "www.pastebin.com":
+		if len(parts) == 1 && parts[0] != "" {
+			return "https://pastebin.com/raw/" + parts[0]
+		}
+	}
+	return raw
+}
+
+// importFileName is the last path segment of a URL when it looks like a
+// file name
+func importFileName(raw string) string {
+	u, err := url.Parse(rawFileURL(raw))
+	if err != nil {
+		return ""
+	}
+	name := path.Base(u.Path)
+	if name == "/" || name == "." || name == "raw" || !strings.Contains(name, ".") {
+		return ""
+	}
+	if runes := []rune(name); len(runes) > 200 {
+		name = string(runes[:200])
+	}
+	return name
+}
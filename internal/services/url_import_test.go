@@ -0,0 +1,93 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestRawFileURL(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/o/r/blob/main/cmd/app/main.go": "https://raw.githubusercontent.com/o/r/main/cmd/app/main.go",
+		"https://gist.github.com/o/abc123":                 "https://gist.githubusercontent.com/o/abc123/raw",
+		"https://pastebin.com/xYz12":                       "https://pastebin.com/raw/xYz12",
+		"https://example.com/install.sh":                   "https://example.com/install.sh",
+		"https://github.com/o/r":                           "https://github.com/o/r",
+	}
+	for in, want := range tests {
+		if got := rawFileURL(in); got != want {
+			t.Errorf("rawFileURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestURLImportService_Import(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/scripts/backup.py":
+			_, _ = w.Write([]byte("import shutil\nshutil.copy('a', 'b')\n"))
+		case "/big.txt":
+			_, _ = w.Write([]byte(strings.Repeat("x", maxImportFileSize+1)))
+		case "/logo.png":
+			_, _ = w.Write([]byte{0x89, 'P', 'N', 'G', 0, 0})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	db := testutil.TestDB(t)
+	snippets := NewSnippetService(repository.NewSnippetRepository(db), testutil.TestLogger()).
+		WithTagRepo(repository.NewTagRepository(db))
+	service := NewURLImportService(snippets, testutil.TestLogger())
+	ctx := testutil.TestContext()
+
+	// The test server is on loopback, which the default client refuses
+	result, err := service.Import(ctx, &models.URLImportInput{URLs: []string{server.URL + "/scripts/backup.py"}})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Failed != 1 || !strings.Contains(result.Items[0].Error, ErrBlockedAddress.Error()) {
+		t.Fatalf("expected loopback fetch to be blocked, got %+v", result.Items)
+	}
+
+	service.client = server.Client()
+	result, err = service.Import(ctx, &models.URLImportInput{
+		URLs: []string{server.URL + "/scripts/backup.py", server.URL + "/big.txt", server.URL + "/logo.png", server.URL + "/missing.go"},
+		Tags: []string{"imported"},
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Created != 1 || result.Failed != 3 {
+		t.Fatalf("expected 1 created and 3 failed, got %+v", result)
+	}
+
+	snippet := result.Items[0].Snippet
+	if snippet == nil || snippet.Title != "backup.py" || snippet.Language != "python" {
+		t.Fatalf("unexpected imported snippet %+v", snippet)
+	}
+	if snippet.Provenance == nil || snippet.Provenance.SourceURL != server.URL+"/scripts/backup.py" {
+		t.Errorf("expected provenance to record the source URL, got %+v", snippet.Provenance)
+	}
+	if len(snippet.Tags) != 1 || snippet.Tags[0].Name != "imported" {
+		t.Errorf("expected the imported tag, got %+v", snippet.Tags)
+	}
+	for i, want := range []error{ErrFileTooLarge, ErrNotTextFile} {
+		if result.Items[i+1].Error != want.Error() {
+			t.Errorf("item %d: expected %q, got %q", i+1, want, result.Items[i+1].Error)
+		}
+	}
+	if !strings.Contains(result.Items[3].Error, "404") {
+		t.Errorf("expected a 404 error, got %q", result.Items[3].Error)
+	}
+
+	if _, err := service.Import(ctx, &models.URLImportInput{URLs: []string{"file:///etc/passwd"}}); err == nil {
+		t.Error("expected non-http URLs to be rejected")
+	}
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+// Inbound webhook errors
+var (
+	ErrWebhookNotFound       = errors.New("webhook not found")
+	ErrWebhookUnauthorized   = errors.New("invalid webhook secret")
+	ErrInvalidWebhookPayload = errors.New("invalid webhook payload")
+)
+
+// MaxWebhookPayloadSize caps a delivery body, matching the snippet content limit
+const MaxWebhookPayloadSize = 1024 * 1024
+
+// InboundWebhookService turns payloads POSTed by external tools into snippets
+type InboundWebhookService struct {
+	repo       *repository.InboundWebhookRepository
+	snippets   *SnippetService
+	folderRepo *repository.FolderRepository
+	logger     *slog.Logger
+}
+
+// NewInboundWebhookService creates a new inbound webhook service
+func NewInboundWebhookService(
+	repo *repository.InboundWebhookRepository,
+	snippets *SnippetService,
+	folderRepo *repository.FolderRepository,
+	logger *slog.Logger,
+) *InboundWebhookService {
+	return &InboundWebhookService{
+		repo:       repo,
+		snippets:   snippets,
+		folderRepo: folderRepo,
+		logger:     logger,
+	}
+}
+
+// List returns all inbound webhooks
+func (s *InboundWebhookService) List(ctx context.Context) ([]models.InboundWebhook, error) {
+	return s.repo.List(ctx)
+}
+
+// Create validates and adds an inbound webhook, returning its secret
+func (s *InboundWebhookService) Create(ctx context.Context, input *models.InboundWebhookInput) (*models.InboundWebhookWithSecret, error) {
+	errs := validation.ValidateInboundWebhookInput(input)
+	if input.FolderID != nil {
+		if _, err := s.folderRepo.GetByID(ctx, *input.FolderID); err != nil {
+			if !errors.Is(err, repository.ErrNotFound) {
+				return nil, err
+			}
+			errs = append(errs, validation.ValidationError{Field: "folder_id", Message: "Folder does not exist"})
+		}
+	}
+	if errs.HasErrors() {
+		return nil, errs
+	}
+
+	hook, secret, err := s.repo.Create(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("inbound webhook created", "id", hook.ID, "name", hook.Name)
+	return &models.InboundWebhookWithSecret{InboundWebhook: *hook, Secret: secret}, nil
+}
+
+// Delete removes an inbound webhook
+func (s *InboundWebhookService) Delete(ctx context.Context, id int64) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrWebhookNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// RotateSecret issues a new secret, invalidating the old one at once
+func (s *InboundWebhookService) RotateSecret(ctx context.Context, id int64) (*models.InboundWebhookWithSecret, error) {
+	secret, err := s.repo.RotateSecret(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	hook, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.InboundWebhookWithSecret{InboundWebhook: *hook, Secret: secret}, nil
+}
+
+// Receive creates a snippet from a delivery to the webhook with the given
+// slug. Disabled webhooks look the same as unknown ones to callers.
+func (s *InboundWebhookService) Receive(ctx context.Context, slug, secret string, delivery *models.InboundDelivery) (*models.Snippet, error) {
+	hook, err := s.repo.GetBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	if !hook.Enabled {
+		return nil, ErrWebhookNotFound
+	}
+	if secret == "" || !s.repo.VerifySecret(hook, secret) {
+		return nil, ErrWebhookUnauthorized
+	}
+
+	input, err := deliveryInput(hook, delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	snippet, err := s.snippets.Create(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.RecordDelivery(ctx, hook.ID); err != nil {
+		s.logger.Warn("failed to record webhook delivery", "id", hook.ID, "error", err)
+	}
+	s.logger.Info("snippet created from inbound webhook", "webhook_id", hook.ID, "snippet_id", snippet.ID)
+	return snippet, nil
+}
+
+// webhookSnippet is the JSON shape a delivery can use to set snippet fields
+// directly; any other JSON is stored as the snippet content
+type webhookSnippet struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Content     *string  `json:"content"`
+	Language    string   `json:"language"`
+	Tags        []string `json:"tags"`
+}
+
+// deliveryInput builds the snippet input for a delivery. Query parameters
+// take precedence over the payload, which takes precedence over the webhook
+// presets; preset tags are always added.
+func deliveryInput(hook *models.InboundWebhook, delivery *models.InboundDelivery) (*models.SnippetInput, error) {
+	if len(bytes.TrimSpace(delivery.Body)) == 0 {
+		return nil, fmt.Errorf("%w: payload is empty", ErrInvalidWebhookPayload)
+	}
+
+	input := &models.SnippetInput{
+		Language: hook.Language,
+		Tags:     append([]string{}, hook.Tags...),
+		FolderID: hook.FolderID,
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(delivery.ContentType)
+	isJSON := mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+
+	var payload webhookSnippet
+	switch {
+	case isJSON && json.Unmarshal(delivery.Body, &payload) == nil && payload.Content != nil:
+		input.Title = payload.Title
+		input.Description = payload.Description
+		input.Content = *payload.Content
+		if payload.Language != "" {
+			input.Language = payload.Language
+		}
+		for _, tag := range payload.Tags {
+			if !containsFold(input.Tags, tag) {
+				input.Tags = append(input.Tags, tag)
+			}
+		}
+
+	case isJSON:
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, delivery.Body, "", "  "); err != nil {
+			return nil, fmt.Errorf("%w: body is not valid JSON", ErrInvalidWebhookPayload)
+		}
+		input.Content = pretty.String()
+		input.Language = "json"
+
+	default:
+		if !utf8.Valid(delivery.Body) {
+			return nil, fmt.Errorf("%w: body must be UTF-8 text", ErrInvalidWebhookPayload)
+		}
+		input.Content = string(delivery.Body)
+	}
+
+	if delivery.Title != "" {
+		input.Title = delivery.Title
+	}
+	if delivery.Language != "" {
+		input.Language = delivery.Language
+	}
+	if strings.TrimSpace(input.Title) == "" {
+		input.Title = hook.Name + " " + time.Now().UTC().Format("2006-01-02 15:04:05")
+	}
+
+	return input, nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
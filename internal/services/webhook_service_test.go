@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+func TestInboundWebhookService(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	logger := testutil.TestLogger()
+	tagRepo := repository.NewTagRepository(db)
+	folderRepo := repository.NewFolderRepository(db)
+	snippets := NewSnippetService(repository.NewSnippetRepository(db), logger).
+		WithTagRepo(tagRepo).
+		WithFolderRepo(folderRepo)
+	svc := NewInboundWebhookService(repository.NewInboundWebhookRepository(db), snippets, folderRepo, logger)
+
+	folder, err := folderRepo.Create(ctx, &models.FolderInput{Name: "Alerts"})
+	if err != nil {
+		t.Fatalf("Create folder failed: %v", err)
+	}
+	hook, err := svc.Create(ctx, &models.InboundWebhookInput{
+		Name:     "CI failures",
+		FolderID: &folder.ID,
+		Tags:     []string{" ci ", "", "failure"},
+		Language: "Shell",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if hook.Secret == "" || hook.Path != "/api/v1/hooks/"+hook.Slug {
+		t.Fatalf("unexpected webhook: %+v", hook)
+	}
+	if len(hook.Tags) != 2 || hook.Language != "shell" {
+		t.Errorf("expected normalized presets, got tags %v language %q", hook.Tags, hook.Language)
+	}
+
+	receive := func(contentType, body string) (*models.Snippet, error) {
+		return svc.Receive(ctx, hook.Slug, hook.Secret, &models.InboundDelivery{ContentType: contentType, Body: []byte(body)})
+	}
+
+	t.Run("rejects invalid input", func(t *testing.T) {
+		missing := int64(9999)
+		_, err := svc.Create(ctx, &models.InboundWebhookInput{Name: " ", FolderID: &missing, Tags: []string{"a,b"}})
+		var validationErrs validation.ValidationErrors
+		if !errors.As(err, &validationErrs) || len(validationErrs) != 3 {
+			t.Errorf("expected 3 validation errors, got %v", err)
+		}
+	})
+
+	t.Run("text payload", func(t *testing.T) {
+		snippet, err := receive("text/plain", "exit status 1\n")
+		if err != nil {
+			t.Fatalf("Receive failed: %v", err)
+		}
+		if snippet.Content != "exit status 1\n" || snippet.Language != "shell" {
+			t.Errorf("unexpected snippet: %+v", snippet)
+		}
+		if !strings.HasPrefix(snippet.Title, "CI failures ") {
+			t.Errorf("expected default title, got %q", snippet.Title)
+		}
+		if len(snippet.Tags) != 2 || len(snippet.Folders) != 1 || snippet.Folders[0].ID != folder.ID {
+			t.Errorf("expected preset tags and folder, got %v %v", snippet.Tags, snippet.Folders)
+		}
+	})
+
+	t.Run("snippet fields payload", func(t *testing.T) {
+		snippet, err := receive("application/json; charset=utf-8",
+			`{"title":"Build #12","content":"panic: boom","language":"go","tags":["build","CI"]}`)
+		if err != nil {
+			t.Fatalf("Receive failed: %v", err)
+		}
+		if snippet.Title != "Build #12" || snippet.Content != "panic: boom" || snippet.Language != "go" {
+			t.Errorf("unexpected snippet: %+v", snippet)
+		}
+		if len(snippet.Tags) != 3 {
+			t.Errorf("expected preset and payload tags merged, got %v", snippet.Tags)
+		}
+	})
+
+	t.Run("other JSON payload", func(t *testing.T) {
+		snippet, err := svc.Receive(ctx, hook.Slug, hook.Secret, &models.InboundDelivery{
+			ContentType: "application/json",
+			Body:        []byte(`{"alert":"disk full","value":97}`),
+			Title:       "Disk alert",
+		})
+		if err != nil {
+			t.Fatalf("Receive failed: %v", err)
+		}
+		if snippet.Title != "Disk alert" || snippet.Language != "json" || !strings.Contains(snippet.Content, "\n  \"alert\"") {
+			t.Errorf("expected the pretty-printed payload, got %+v", snippet)
+		}
+	})
+
+	t.Run("invalid payloads", func(t *testing.T) {
+		for _, tc := range []struct{ contentType, body string }{
+			{"text/plain", "  \n"},
+			{"text/plain", "\xff\xfe"},
+			{"application/json", "{not json"},
+		} {
+			if _, err := receive(tc.contentType, tc.body); !errors.Is(err, ErrInvalidWebhookPayload) {
+				t.Errorf("%q: expected ErrInvalidWebhookPayload, got %v", tc.body, err)
+			}
+		}
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		for _, secret := range []string{"", "nope"} {
+			_, err := svc.Receive(ctx, hook.Slug, secret, &models.InboundDelivery{Body: []byte("x")})
+			if !errors.Is(err, ErrWebhookUnauthorized) {
+				t.Errorf("expected ErrWebhookUnauthorized, got %v", err)
+			}
+		}
+	})
+
+	t.Run("rotate secret", func(t *testing.T) {
+		rotated, err := svc.RotateSecret(ctx, hook.ID)
+		if err != nil {
+			t.Fatalf("RotateSecret failed: %v", err)
+		}
+		if _, err := receive("text/plain", "old"); !errors.Is(err, ErrWebhookUnauthorized) {
+			t.Errorf("expected the old secret to be rejected, got %v", err)
+		}
+		hook.Secret = rotated.Secret
+		if _, err := receive("text/plain", "new"); err != nil {
+			t.Errorf("Receive with rotated secret failed: %v", err)
+		}
+		if rotated.ReceivedCount == 0 {
+			t.Error("expected deliveries to be counted")
+		}
+	})
+
+	t.Run("disabled webhook is not found", func(t *testing.T) {
+		disabled := false
+		off, err := svc.Create(ctx, &models.InboundWebhookInput{Name: "Off", Enabled: &disabled})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		_, err = svc.Receive(ctx, off.Slug, off.Secret, &models.InboundDelivery{Body: []byte("x")})
+		if !errors.Is(err, ErrWebhookNotFound) {
+			t.Errorf("expected ErrWebhookNotFound, got %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := svc.Delete(ctx, hook.ID); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if err := svc.Delete(ctx, hook.ID); !errors.Is(err, ErrWebhookNotFound) {
+			t.Errorf("expected ErrWebhookNotFound, got %v", err)
+		}
+		if _, err := receive("text/plain", "x"); !errors.Is(err, ErrWebhookNotFound) {
+			t.Errorf("expected ErrWebhookNotFound, got %v", err)
+		}
+	})
+}
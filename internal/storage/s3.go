@@ -7,15 +7,39 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// Multipart upload tuning. Content of at least MultipartThreshold bytes is
+// uploaded in parts of PartSize (S3 requires at least 5 MiB for all but the
+// last part), each retried up to maxPartAttempts times.
+const (
+	MultipartThreshold = 16 << 20
+	PartSize           = 8 << 20
+	maxPartAttempts    = 4
+)
+
+// partRetryDelay is the wait before the first retry of a failed part; it
+// doubles on every further attempt
+var partRetryDelay = time.Second
+
+// ProgressFunc is called as an upload advances with the bytes sent so far
+// and the total size
+type ProgressFunc func(sent, total int64)
+
 // S3Config holds S3 storage configuration
 type S3Config struct {
 	Endpoint        string
@@ -24,12 +48,21 @@ type S3Config struct {
 	Bucket          string
 	Region          string
 	UseSSL          bool
+	// Proxy picks the proxy for a request; nil uses the environment
+	Proxy func(*http.Request) (*url.URL, error)
+	// StorageClass and Tags are set on objects uploaded with
+	// UploadWithProgress, so bucket lifecycle rules can act on backups.
+	// An empty StorageClass uses the bucket's default.
+	StorageClass string
+	Tags         map[string]string
 }
 
 // S3Storage provides S3-compatible object storage operations
 type S3Storage struct {
-	client *s3.Client
-	bucket string
+	client       *s3.Client
+	bucket       string
+	storageClass types.StorageClass
+	tagging      *string // URL-encoded tags, nil for none
 }
 
 // ObjectInfo represents information about an S3 object
@@ -37,10 +70,24 @@ type ObjectInfo struct {
 	Key          string
 	Size         int64
 	LastModified time.Time
+	StorageClass string
 }
 
 // NewS3Storage creates a new S3 storage client
 func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	storageClass := types.StorageClass(cfg.StorageClass)
+	if storageClass != "" && !slices.Contains(storageClass.Values(), storageClass) {
+		return nil, fmt.Errorf("unknown storage class %q", cfg.StorageClass)
+	}
+	var tagging *string
+	if len(cfg.Tags) > 0 {
+		tags := url.Values{}
+		for key, value := range cfg.Tags {
+			tags.Set(key, value)
+		}
+		tagging = aws.String(tags.Encode())
+	}
+
 	// Build endpoint URL
 	scheme := "https"
 	if !cfg.UseSSL {
@@ -49,14 +96,20 @@ func NewS3Storage(cfg S3Config) (*S3Storage, error) {
 	endpointURL := fmt.Sprintf("%s://%s", scheme, cfg.Endpoint)
 
 	// Load AWS config
-	awsCfg, err := config.LoadDefaultConfig(context.Background(),
+	opts := []func(*config.LoadOptions) error{
 		config.WithRegion(cfg.Region),
 		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
 			cfg.AccessKeyID,
 			cfg.SecretAccessKey,
 			"",
 		)),
-	)
+	}
+	if cfg.Proxy != nil {
+		opts = append(opts, config.WithHTTPClient(awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+			tr.Proxy = cfg.Proxy
+		})))
+	}
+	awsCfg, err := config.LoadDefaultConfig(context.Background(), opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
@@ -87,7 +140,7 @@ func NewS3Storage(cfg S3Config) (*S3Storage, error) {
 		}
 	}
 
-	return &S3Storage{client: client, bucket: cfg.Bucket}, nil
+	return &S3Storage{client: client, bucket: cfg.Bucket, storageClass: storageClass, tagging: tagging}, nil
 }
 
 // Upload uploads content to S3
@@ -101,6 +154,116 @@ func (s *S3Storage) Upload(ctx context.Context, key string, content []byte, cont
 	return err
 }
 
+// UploadWithProgress uploads content to S3 with the configured storage class
+// and tags, switching to a multipart upload for large content so a failed
+// part is retried on its own instead of restarting the whole upload. A
+// multipart upload that can't be completed is aborted, leaving no partial
+// object behind. progress may be nil.
+func (s *S3Storage) UploadWithProgress(ctx context.Context, key string, content []byte, contentType string, progress ProgressFunc) error {
+	total := int64(len(content))
+	if progress == nil {
+		progress = func(int64, int64) {}
+	}
+	if total < MultipartThreshold {
+		if _, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:       aws.String(s.bucket),
+			Key:          aws.String(key),
+			Body:         bytes.NewReader(content),
+			ContentType:  aws.String(contentType),
+			StorageClass: s.storageClass,
+			Tagging:      s.tagging,
+		}); err != nil {
+			return err
+		}
+		progress(total, total)
+		return nil
+	}
+
+	upload, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:       aws.String(s.bucket),
+		Key:          aws.String(key),
+		ContentType:  aws.String(contentType),
+		StorageClass: s.storageClass,
+		Tagging:      s.tagging,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+
+	parts, err := s.uploadParts(ctx, key, upload.UploadId, content, progress)
+	if err == nil {
+		_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(s.bucket),
+			Key:             aws.String(key),
+			UploadId:        upload.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to complete multipart upload: %w", err)
+		}
+	}
+	if err != nil {
+		// Use a fresh context so the abort still happens after a cancellation
+		abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if _, abortErr := s.client.AbortMultipartUpload(abortCtx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(s.bucket),
+			Key:      aws.String(key),
+			UploadId: upload.UploadId,
+		}); abortErr != nil {
+			slog.Error("failed to abort multipart upload", "key", key, "error", abortErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// uploadParts uploads content in PartSize chunks, retrying failed parts
+func (s *S3Storage) uploadParts(ctx context.Context, key string, uploadID *string, content []byte, progress ProgressFunc) ([]types.CompletedPart, error) {
+	total := int64(len(content))
+	var parts []types.CompletedPart
+	var sent int64
+	for number := int32(1); sent < total; number++ {
+		chunk := content[sent:min(sent+PartSize, total)]
+
+		var etag *string
+		var err error
+		delay := partRetryDelay
+		for attempt := 1; attempt <= maxPartAttempts; attempt++ {
+			var out *s3.UploadPartOutput
+			out, err = s.client.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:     aws.String(s.bucket),
+				Key:        aws.String(key),
+				UploadId:   uploadID,
+				PartNumber: aws.Int32(number),
+				Body:       bytes.NewReader(chunk),
+			})
+			if err == nil {
+				etag = out.ETag
+				break
+			}
+			if attempt == maxPartAttempts || ctx.Err() != nil {
+				break
+			}
+			slog.Warn("retrying failed upload part", "key", key, "part", number, "attempt", attempt, "error", err)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload part %d: %w", number, err)
+		}
+
+		parts = append(parts, types.CompletedPart{ETag: etag, PartNumber: aws.Int32(number)})
+		sent += int64(len(chunk))
+		progress(sent, total)
+	}
+	return parts, nil
+}
+
 // UploadReader uploads content from a reader to S3
 func (s *S3Storage) UploadReader(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error {
 	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
@@ -120,6 +283,14 @@ func (s *S3Storage) Download(ctx context.Context, key string) ([]byte, error) {
 		Key:    aws.String(key),
 	})
 	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrNotFound
+		}
+		var archived *types.InvalidObjectState
+		if errors.As(err, &archived) {
+			return nil, fmt.Errorf("object is archived in storage class %s and must be restored in the bucket first: %w", archived.StorageClass, err)
+		}
 		return nil, fmt.Errorf("failed to get object: %w", err)
 	}
 	defer func() {
@@ -165,6 +336,7 @@ func (s *S3Storage) List(ctx context.Context, prefix string) ([]ObjectInfo, erro
 				Key:          aws.ToString(obj.Key),
 				Size:         aws.ToInt64(obj.Size),
 				LastModified: aws.ToTime(obj.LastModified),
+				StorageClass: string(obj.StorageClass),
 			})
 		}
 	}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 implements the handful of S3 calls used for multipart uploads
+type fakeS3 struct {
+	mu        sync.Mutex
+	parts     map[int][]byte
+	objects   map[string][]byte
+	headers   map[string]http.Header // Headers of the request creating each object
+	failPart  int                    // Part number that fails
+	failTimes int                    // How many attempts of failPart fail
+	aborted   bool
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	switch {
+	case r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.parts = make(map[int][]byte)
+		f.record(key, r)
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>%s</Key><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>", key)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		if number == f.failPart && f.failTimes > 0 {
+			f.failTimes--
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "<Error><Code>Flaky</Code><Message>connection reset</Message></Error>")
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		f.parts[number] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, number))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		var content []byte
+		for i := 1; i <= len(f.parts); i++ {
+			content = append(content, f.parts[i]...)
+		}
+		f.objects[key] = content
+		fmt.Fprintf(w, "<CompleteMultipartUploadResult><Bucket>bucket</Bucket><Key>%s</Key><ETag>\"done\"</ETag></CompleteMultipartUploadResult>", key)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		f.aborted = true
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[key] = body
+		f.record(key, r)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (f *fakeS3) record(key string, r *http.Request) {
+	if f.headers == nil {
+		f.headers = make(map[string]http.Header)
+	}
+	f.headers[key] = r.Header.Clone()
+}
+
+func newTestStorage(t *testing.T, fake *fakeS3) *S3Storage {
+	return newTestStorageWith(t, fake, S3Config{})
+}
+
+// newTestStorageWith creates storage for fake with the storage class and
+// tags of cfg
+func newTestStorageWith(t *testing.T, fake *fakeS3, cfg S3Config) *S3Storage {
+	t.Helper()
+	t.Setenv("AWS_REQUEST_CHECKSUM_CALCULATION", "when_required")
+	t.Setenv("AWS_MAX_ATTEMPTS", "1") // Leave retries to UploadWithProgress
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	s, err := NewS3Storage(S3Config{
+		Endpoint:        strings.TrimPrefix(server.URL, "http://"),
+		AccessKeyID:     "test",
+		SecretAccessKey: "test",
+		Bucket:          "bucket",
+		Region:          "us-east-1",
+		StorageClass:    cfg.StorageClass,
+		Tags:            cfg.Tags,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage failed: %v", err)
+	}
+	return s
+}
+
+func TestUploadWithProgress(t *testing.T) {
+	partRetryDelay = time.Millisecond
+	content := bytes.Repeat([]byte("snipo"), (MultipartThreshold+PartSize/2)/5)
+	ctx := context.Background()
+
+	t.Run("small content is a single put", func(t *testing.T) {
+		fake := &fakeS3{objects: make(map[string][]byte)}
+		s := newTestStorage(t, fake)
+
+		var calls int
+		if err := s.UploadWithProgress(ctx, "small.json", []byte("{}"), "application/json", func(sent, total int64) {
+			calls++
+		}); err != nil {
+			t.Fatalf("upload failed: %v", err)
+		}
+		if string(fake.objects["small.json"]) != "{}" || calls != 1 {
+			t.Errorf("expected one put and one progress call, got %q and %d calls", fake.objects["small.json"], calls)
+		}
+	})
+
+	t.Run("large content retries failed parts", func(t *testing.T) {
+		fake := &fakeS3{objects: make(map[string][]byte), failPart: 2, failTimes: maxPartAttempts - 1}
+		s := newTestStorage(t, fake)
+
+		var progress []int64
+		if err := s.UploadWithProgress(ctx, "large.json", content, "application/json", func(sent, total int64) {
+			if total != int64(len(content)) {
+				t.Errorf("expected total %d, got %d", len(content), total)
+			}
+			progress = append(progress, sent)
+		}); err != nil {
+			t.Fatalf("upload failed: %v", err)
+		}
+		if !bytes.Equal(fake.objects["large.json"], content) {
+			t.Errorf("uploaded object doesn't match, got %d bytes", len(fake.objects["large.json"]))
+		}
+		if len(progress) != 3 || progress[2] != int64(len(content)) {
+			t.Errorf("expected progress after each of 3 parts, got %v", progress)
+		}
+	})
+
+	t.Run("aborts when a part keeps failing", func(t *testing.T) {
+		fake := &fakeS3{objects: make(map[string][]byte), failPart: 2, failTimes: maxPartAttempts}
+		s := newTestStorage(t, fake)
+
+		if err := s.UploadWithProgress(ctx, "large.json", content, "application/json", nil); err == nil {
+			t.Fatal("expected upload to fail")
+		}
+		if !fake.aborted {
+			t.Error("expected the multipart upload to be aborted")
+		}
+		if _, ok := fake.objects["large.json"]; ok {
+			t.Error("expected no object to be left behind")
+		}
+	})
+}
+
+func TestUploadWithProgress_StorageClassAndTags(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	s := newTestStorageWith(t, fake, S3Config{
+		StorageClass: "STANDARD_IA",
+		Tags:         map[string]string{"retention": "90d", "team": "platform & ops"},
+	})
+
+	large := bytes.Repeat([]byte("snipo"), MultipartThreshold/5+1)
+	for _, key := range []string{"small.json", "large.json"} {
+		content := []byte("{}")
+		if key == "large.json" {
+			content = large
+		}
+		if err := s.UploadWithProgress(ctx, key, content, "application/json", nil); err != nil {
+			t.Fatalf("upload of %s failed: %v", key, err)
+		}
+		header := fake.headers[key]
+		if got := header.Get("X-Amz-Storage-Class"); got != "STANDARD_IA" {
+			t.Errorf("%s: expected storage class STANDARD_IA, got %q", key, got)
+		}
+		if got := header.Get("X-Amz-Tagging"); got != "retention=90d&team=platform+%26+ops" {
+			t.Errorf("%s: expected tags, got %q", key, got)
+		}
+	}
+
+	// Other objects, like backup metadata, keep the bucket defaults
+	if err := s.Upload(ctx, "small.json.meta.json", []byte("{}"), "application/json"); err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if got := fake.headers["small.json.meta.json"].Get("X-Amz-Storage-Class"); got != "" {
+		t.Errorf("expected no storage class for plain uploads, got %q", got)
+	}
+
+	if _, err := NewS3Storage(S3Config{Endpoint: "localhost", Bucket: "bucket", StorageClass: "COLD"}); err == nil {
+		t.Error("expected an unknown storage class to be refused")
+	}
+}
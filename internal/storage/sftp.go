@@ -0,0 +1,424 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrPresignUnsupported is returned by storage that can't hand out download
+// links
+var ErrPresignUnsupported = errors.New("storage does not support download links")
+
+const (
+	// partSuffix marks files still being uploaded, which List hides
+	partSuffix = ".part"
+	// sftpTimeout bounds connecting and the SSH handshake
+	sftpTimeout = 30 * time.Second
+	// sftpIdleTimeout is how long an unused connection is kept open
+	sftpIdleTimeout = time.Minute
+)
+
+// SFTPConfig holds SFTP storage configuration
+type SFTPConfig struct {
+	Host                 string // host or host:port, port 22 when none is given
+	User                 string
+	Password             string
+	PrivateKey           string // PEM, as in ~/.ssh/id_ed25519
+	PrivateKeyPassphrase string
+	// HostKey pins the server's host key: a public key as ssh-keyscan
+	// prints it, with or without the host name, or a SHA256: fingerprint
+	HostKey string
+	// Path is the directory keys are relative to. Relative paths start at
+	// the login directory.
+	Path string
+}
+
+// SFTPStorage keeps backups as files on an SSH server. Keys are paths below
+// the configured directory; uploads are written under a temporary name and
+// renamed when complete, so a failed upload leaves no partial file.
+type SFTPStorage struct {
+	addr   string
+	config *ssh.ClientConfig
+	root   string
+
+	mu     sync.Mutex
+	conn   *ssh.Client
+	client *sftpClient
+	idle   *time.Timer
+}
+
+// NewSFTPStorage creates SFTP storage and checks it by connecting and
+// creating the directory if needed
+func NewSFTPStorage(cfg SFTPConfig) (*SFTPStorage, error) {
+	if cfg.Host == "" || cfg.User == "" {
+		return nil, errors.New("host and user are required")
+	}
+	hostKeyCallback, algorithms, err := pinnedHostKey(cfg.HostKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth []ssh.AuthMethod
+	if cfg.PrivateKey != "" {
+		var signer ssh.Signer
+		if cfg.PrivateKeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(cfg.PrivateKey), []byte(cfg.PrivateKeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(cfg.PrivateKey))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if cfg.Password != "" {
+		auth = append(auth, ssh.Password(cfg.Password))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("a password or private key is required")
+	}
+
+	addr := cfg.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+	root := path.Clean(cfg.Path)
+	if cfg.Path == "" {
+		root = "."
+	}
+
+	s := &SFTPStorage{
+		addr: addr,
+		config: &ssh.ClientConfig{
+			User:              cfg.User,
+			Auth:              auth,
+			HostKeyCallback:   hostKeyCallback,
+			HostKeyAlgorithms: algorithms,
+			Timeout:           sftpTimeout,
+		},
+		root: root,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sftpTimeout)
+	defer cancel()
+	if err := s.do(ctx, func(c *sftpClient) error { return mkdirAll(c, s.root) }); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// pinnedHostKey returns a host key callback accepting only the key hostKey
+// describes, and the host key algorithms to ask the server for
+func pinnedHostKey(hostKey string) (ssh.HostKeyCallback, []string, error) {
+	hostKey = strings.TrimSpace(hostKey)
+	if hostKey == "" {
+		return nil, nil, errors.New("the server's host key is required")
+	}
+
+	if strings.HasPrefix(hostKey, "SHA256:") {
+		return func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			if got := ssh.FingerprintSHA256(key); got != hostKey {
+				return fmt.Errorf("host key %s does not match %s", got, hostKey)
+			}
+			return nil
+		}, nil, nil
+	}
+
+	want, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+	if err != nil {
+		// ssh-keyscan prints the host name first
+		if _, rest, ok := strings.Cut(hostKey, " "); ok {
+			want, _, _, _, err = ssh.ParseAuthorizedKey([]byte(rest))
+		}
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid host key: %w", err)
+	}
+
+	algorithms := []string{want.Type()}
+	if want.Type() == ssh.KeyAlgoRSA {
+		algorithms = []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return ssh.FixedHostKey(want), algorithms, nil
+}
+
+// do runs fn with a connected client, connecting first if needed. The
+// server may have dropped a connection kept open since the last call, so
+// when fn fails on one for any reason other than an SFTP status, it is
+// retried once on a new connection. ctx ending closes the connection.
+func (s *SFTPStorage) do(ctx context.Context, fn func(c *sftpClient) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.idle != nil {
+		s.idle.Stop()
+	}
+	defer func() {
+		s.idle = time.AfterFunc(sftpIdleTimeout, s.closeIdle)
+	}()
+
+	for attempt := 0; ; attempt++ {
+		reused := s.client != nil
+		if !reused {
+			if err := s.connect(ctx); err != nil {
+				return err
+			}
+		}
+
+		conn := s.conn
+		stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+		err := fn(s.client)
+		interrupted := !stop()
+		if err == nil {
+			return nil
+		}
+		var status *sftpStatusError
+		if errors.As(err, &status) && !interrupted {
+			return err
+		}
+
+		s.disconnect()
+		if interrupted {
+			return ctx.Err()
+		}
+		if !reused || attempt > 0 {
+			return err
+		}
+	}
+}
+
+func (s *SFTPStorage) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: sftpTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", s.addr, err)
+	}
+	_ = netConn.SetDeadline(time.Now().Add(sftpTimeout))
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, s.addr, s.config)
+	if err != nil {
+		_ = netConn.Close()
+		return fmt.Errorf("failed to connect to %s: %w", s.addr, err)
+	}
+	_ = netConn.SetDeadline(time.Time{})
+	conn := ssh.NewClient(sshConn, chans, reqs)
+
+	client, err := openSFTP(conn)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to start SFTP on %s: %w", s.addr, err)
+	}
+	s.conn, s.client = conn, client
+	return nil
+}
+
+// openSFTP starts the sftp subsystem in a new session of conn
+func openSFTP(conn *ssh.Client) (*sftpClient, error) {
+	session, err := conn.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	w, err := session.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		return nil, err
+	}
+	return newSFTPClient(w, r)
+}
+
+func (s *SFTPStorage) disconnect() {
+	if s.conn != nil {
+		_ = s.client.Close()
+		_ = s.conn.Close()
+		s.conn, s.client = nil, nil
+	}
+}
+
+func (s *SFTPStorage) closeIdle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disconnect()
+}
+
+// path returns the path of key on the server. Keys can't leave the
+// configured directory.
+func (s *SFTPStorage) path(key string) (string, error) {
+	if !fs.ValidPath(key) || key == "." {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return path.Join(s.root, key), nil
+}
+
+// mkdirAll creates dir and any missing parents
+func mkdirAll(c *sftpClient, dir string) error {
+	if dir == "." || dir == "/" {
+		return nil
+	}
+	attrs, err := c.Stat(dir)
+	if err == nil {
+		if attrs.hasMode && !attrs.isDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+		return nil
+	}
+	if !isNoSuchFile(err) {
+		return err
+	}
+
+	if err := mkdirAll(c, path.Dir(dir)); err != nil {
+		return err
+	}
+	if err := c.Mkdir(dir); err != nil {
+		if _, statErr := c.Stat(dir); statErr == nil {
+			return nil // Created meanwhile
+		}
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	return nil
+}
+
+// Upload writes content to key
+func (s *SFTPStorage) Upload(ctx context.Context, key string, content []byte, contentType string) error {
+	return s.UploadWithProgress(ctx, key, content, contentType, nil)
+}
+
+// UploadWithProgress writes content to key, reporting progress after every
+// chunk. contentType is not stored.
+func (s *SFTPStorage) UploadWithProgress(ctx context.Context, key string, content []byte, _ string, progress ProgressFunc) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	total := int64(len(content))
+
+	return s.do(ctx, func(c *sftpClient) error {
+		if err := mkdirAll(c, path.Dir(p)); err != nil {
+			return err
+		}
+		part := p + partSuffix
+		err := c.WriteFile(part, content, func(sent int64) {
+			if progress != nil {
+				progress(sent, total)
+			}
+		})
+		if err != nil {
+			_ = c.Remove(part)
+			return fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+		// SFTP renames don't replace existing files
+		if err := c.Remove(p); err != nil && !isNoSuchFile(err) {
+			return fmt.Errorf("failed to replace %s: %w", key, err)
+		}
+		if err := c.Rename(part, p); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+		return nil
+	})
+}
+
+// Download retrieves the content of key
+func (s *SFTPStorage) Download(ctx context.Context, key string) ([]byte, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var content []byte
+	err = s.do(ctx, func(c *sftpClient) error {
+		var readErr error
+		content, readErr = c.ReadFile(p)
+		return readErr
+	})
+	if isNoSuchFile(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	return content, nil
+}
+
+// Delete removes key. Keys that don't exist are ignored.
+func (s *SFTPStorage) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = s.do(ctx, func(c *sftpClient) error { return c.Remove(p) })
+	if err != nil && !isNoSuchFile(err) {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// List returns the files with keys starting with prefix, sorted by key
+func (s *SFTPStorage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	dir := strings.TrimSuffix(prefix[:strings.LastIndex(prefix, "/")+1], "/")
+	if dir == "" {
+		dir = "."
+	}
+	if !fs.ValidPath(dir) {
+		return nil, fmt.Errorf("invalid prefix %q", prefix)
+	}
+
+	var objects []ObjectInfo
+	err := s.do(ctx, func(c *sftpClient) error {
+		objects = nil
+		return s.walk(c, dir, prefix, &objects)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// walk adds the files below dir with keys starting with prefix to objects
+func (s *SFTPStorage) walk(c *sftpClient, dir, prefix string, objects *[]ObjectInfo) error {
+	entries, err := c.ReadDir(path.Join(s.root, dir))
+	if isNoSuchFile(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		key := path.Join(dir, entry.name)
+		switch {
+		case entry.attrs.isDir():
+			if strings.HasPrefix(key+"/", prefix) || strings.HasPrefix(prefix, key+"/") {
+				if err := s.walk(c, key, prefix, objects); err != nil {
+					return err
+				}
+			}
+		case strings.HasPrefix(key, prefix) && !strings.HasSuffix(key, partSuffix):
+			*objects = append(*objects, ObjectInfo{
+				Key:          key,
+				Size:         int64(entry.attrs.size),
+				LastModified: entry.attrs.mtime,
+			})
+		}
+	}
+	return nil
+}
+
+// GetPresignedURL returns ErrPresignUnsupported: an SSH server has no
+// download links to hand out
+func (s *SFTPStorage) GetPresignedURL(_ context.Context, _ string, _ time.Duration) (string, error) {
+	return "", ErrPresignUnsupported
+}
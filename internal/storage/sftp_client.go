@@ -0,0 +1,393 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// The subset of SFTP version 3 (draft-ietf-secsh-filexfer-02) sftpClient
+// speaks: enough to write, read, list, rename and remove files
+const (
+	sftpVersion = 3
+
+	fxpInit      = 1
+	fxpVersion   = 2
+	fxpOpen      = 3
+	fxpClose     = 4
+	fxpRead      = 5
+	fxpWrite     = 6
+	fxpOpendir   = 11
+	fxpReaddir   = 12
+	fxpRemove    = 13
+	fxpMkdir     = 14
+	fxpStat      = 17
+	fxpRename    = 18
+	fxpStatus    = 101
+	fxpHandle    = 102
+	fxpData      = 103
+	fxpName      = 104
+	fxpAttrs     = 105
+	fxfRead      = 0x01
+	fxfWrite     = 0x02
+	fxfCreat     = 0x08
+	fxfTrunc     = 0x10
+	fxOK         = 0
+	fxEOF        = 1
+	fxNoSuchFile = 2
+
+	attrSize        = 0x00000001
+	attrUIDGID      = 0x00000002
+	attrPermissions = 0x00000004
+	attrACModTime   = 0x00000008
+	attrExtended    = 0x80000000
+
+	// Servers must accept packets of 34000 bytes; 32 KiB of data leaves
+	// room for the header
+	sftpChunkSize = 32 << 10
+	// Far above any reply to the requests sent, to reject garbage lengths
+	maxSFTPPacket = 256 << 10
+)
+
+// sftpStatusError is an SSH_FXP_STATUS reply other than OK
+type sftpStatusError struct {
+	code uint32
+	msg  string
+}
+
+func (e *sftpStatusError) Error() string {
+	if e.msg != "" {
+		return fmt.Sprintf("sftp: %s (code %d)", e.msg, e.code)
+	}
+	return fmt.Sprintf("sftp: status code %d", e.code)
+}
+
+// isNoSuchFile reports whether err is the server saying a path doesn't exist
+func isNoSuchFile(err error) bool {
+	var status *sftpStatusError
+	return errors.As(err, &status) && status.code == fxNoSuchFile
+}
+
+// sftpAttrs are the file attributes sftpClient reads
+type sftpAttrs struct {
+	size    uint64
+	mode    uint32
+	hasMode bool
+	mtime   time.Time
+}
+
+func (a sftpAttrs) isDir() bool {
+	return a.hasMode && a.mode&0o170000 == 0o040000
+}
+
+// sftpEntry is a directory entry
+type sftpEntry struct {
+	name  string
+	attrs sftpAttrs
+}
+
+// sftpClient sends one request at a time over an SFTP channel. It is safe
+// for concurrent use.
+type sftpClient struct {
+	mu     sync.Mutex
+	w      io.WriteCloser
+	r      io.Reader
+	nextID uint32
+}
+
+// newSFTPClient negotiates version 3 over the channel of an "sftp" subsystem
+func newSFTPClient(w io.WriteCloser, r io.Reader) (*sftpClient, error) {
+	c := &sftpClient{w: w, r: r}
+	if err := c.writePacket(fxpInit, binary.BigEndian.AppendUint32(nil, sftpVersion)); err != nil {
+		return nil, err
+	}
+	typ, payload, err := c.readPacket()
+	if err != nil {
+		return nil, err
+	}
+	if typ != fxpVersion || len(payload) < 4 {
+		return nil, fmt.Errorf("sftp: unexpected reply %d to init", typ)
+	}
+	if v := binary.BigEndian.Uint32(payload); v < sftpVersion {
+		return nil, fmt.Errorf("sftp: server speaks version %d, need %d", v, sftpVersion)
+	}
+	return c, nil
+}
+
+func (c *sftpClient) Close() error {
+	return c.w.Close()
+}
+
+func (c *sftpClient) writePacket(typ byte, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(make([]byte, 0, 5+len(payload)), uint32(1+len(payload)))
+	packet = append(packet, typ)
+	_, err := c.w.Write(append(packet, payload...))
+	return err
+}
+
+func (c *sftpClient) readPacket() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > maxSFTPPacket {
+		return 0, nil, fmt.Errorf("sftp: bad packet length %d", length)
+	}
+	payload := make([]byte, length-1)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[4], payload, nil
+}
+
+// request sends a request of typ with args after its ID and returns the
+// type and payload of the reply, less the ID. STATUS replies other than OK
+// are returned as *sftpStatusError.
+func (c *sftpClient) request(typ byte, args []byte) (byte, []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	id := c.nextID
+	if err := c.writePacket(typ, append(binary.BigEndian.AppendUint32(nil, id), args...)); err != nil {
+		return 0, nil, err
+	}
+	replyType, payload, err := c.readPacket()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(payload) < 4 || binary.BigEndian.Uint32(payload) != id {
+		return 0, nil, fmt.Errorf("sftp: reply to request %d out of order", id)
+	}
+	payload = payload[4:]
+
+	if replyType == fxpStatus {
+		d := sftpDecoder{buf: payload}
+		code := d.uint32()
+		msg := d.string()
+		if d.err != nil {
+			return 0, nil, d.err
+		}
+		if code != fxOK {
+			return 0, nil, &sftpStatusError{code: code, msg: msg}
+		}
+	}
+	return replyType, payload, nil
+}
+
+// expect sends a request and checks its reply is of type want
+func (c *sftpClient) expect(typ byte, args []byte, want byte) (*sftpDecoder, error) {
+	replyType, payload, err := c.request(typ, args)
+	if err != nil {
+		return nil, err
+	}
+	if replyType != want {
+		return nil, fmt.Errorf("sftp: unexpected reply %d to request %d", replyType, typ)
+	}
+	return &sftpDecoder{buf: payload}, nil
+}
+
+func (c *sftpClient) handle(typ byte, args []byte) (string, error) {
+	d, err := c.expect(typ, args, fxpHandle)
+	if err != nil {
+		return "", err
+	}
+	handle := d.string()
+	return handle, d.err
+}
+
+func (c *sftpClient) closeHandle(handle string) error {
+	_, err := c.expect(fxpClose, appendString(nil, handle), fxpStatus)
+	return err
+}
+
+// Stat returns the attributes of path, following links
+func (c *sftpClient) Stat(path string) (sftpAttrs, error) {
+	d, err := c.expect(fxpStat, appendString(nil, path), fxpAttrs)
+	if err != nil {
+		return sftpAttrs{}, err
+	}
+	attrs := d.attrs()
+	return attrs, d.err
+}
+
+// Mkdir creates the directory path
+func (c *sftpClient) Mkdir(path string) error {
+	args := binary.BigEndian.AppendUint32(appendString(nil, path), 0)
+	_, err := c.expect(fxpMkdir, args, fxpStatus)
+	return err
+}
+
+// Remove deletes the file path
+func (c *sftpClient) Remove(path string) error {
+	_, err := c.expect(fxpRemove, appendString(nil, path), fxpStatus)
+	return err
+}
+
+// Rename moves oldPath to newPath, which must not exist
+func (c *sftpClient) Rename(oldPath, newPath string) error {
+	_, err := c.expect(fxpRename, appendString(appendString(nil, oldPath), newPath), fxpStatus)
+	return err
+}
+
+// WriteFile creates or truncates path and writes content to it, calling
+// progress after every chunk
+func (c *sftpClient) WriteFile(path string, content []byte, progress func(sent int64)) error {
+	args := binary.BigEndian.AppendUint32(appendString(nil, path), fxfWrite|fxfCreat|fxfTrunc)
+	handle, err := c.handle(fxpOpen, binary.BigEndian.AppendUint32(args, 0))
+	if err != nil {
+		return err
+	}
+
+	for offset := 0; offset < len(content); offset += sftpChunkSize {
+		chunk := content[offset:min(offset+sftpChunkSize, len(content))]
+		args := binary.BigEndian.AppendUint64(appendString(nil, handle), uint64(offset))
+		if _, err := c.expect(fxpWrite, appendString(args, string(chunk)), fxpStatus); err != nil {
+			_ = c.closeHandle(handle)
+			return err
+		}
+		if progress != nil {
+			progress(int64(offset + len(chunk)))
+		}
+	}
+	return c.closeHandle(handle)
+}
+
+// ReadFile returns the content of path
+func (c *sftpClient) ReadFile(path string) ([]byte, error) {
+	args := binary.BigEndian.AppendUint32(appendString(nil, path), fxfRead)
+	handle, err := c.handle(fxpOpen, binary.BigEndian.AppendUint32(args, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	var content []byte
+	for {
+		args := binary.BigEndian.AppendUint64(appendString(nil, handle), uint64(len(content)))
+		d, err := c.expect(fxpRead, binary.BigEndian.AppendUint32(args, sftpChunkSize), fxpData)
+		var status *sftpStatusError
+		if errors.As(err, &status) && status.code == fxEOF {
+			break
+		}
+		if err != nil {
+			_ = c.closeHandle(handle)
+			return nil, err
+		}
+		data := d.string()
+		if d.err != nil {
+			_ = c.closeHandle(handle)
+			return nil, d.err
+		}
+		if len(data) == 0 {
+			break
+		}
+		content = append(content, data...)
+	}
+	return content, c.closeHandle(handle)
+}
+
+// ReadDir lists the entries of the directory path, without "." and ".."
+func (c *sftpClient) ReadDir(path string) ([]sftpEntry, error) {
+	handle, err := c.handle(fxpOpendir, appendString(nil, path))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []sftpEntry
+	for {
+		d, err := c.expect(fxpReaddir, appendString(nil, handle), fxpName)
+		var status *sftpStatusError
+		if errors.As(err, &status) && status.code == fxEOF {
+			break
+		}
+		if err != nil {
+			_ = c.closeHandle(handle)
+			return nil, err
+		}
+		for n := d.uint32(); n > 0 && d.err == nil; n-- {
+			name := d.string()
+			_ = d.string() // Long name, as ls -l would print it
+			attrs := d.attrs()
+			if name != "." && name != ".." {
+				entries = append(entries, sftpEntry{name: name, attrs: attrs})
+			}
+		}
+		if d.err != nil {
+			_ = c.closeHandle(handle)
+			return nil, d.err
+		}
+	}
+	return entries, c.closeHandle(handle)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// sftpDecoder reads the fields of a reply, remembering the first error
+type sftpDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *sftpDecoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.buf) < n {
+		d.err = errors.New("sftp: reply too short")
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *sftpDecoder) uint32() uint32 {
+	if b := d.take(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *sftpDecoder) uint64() uint64 {
+	if b := d.take(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *sftpDecoder) string() string {
+	return string(d.take(int(d.uint32())))
+}
+
+func (d *sftpDecoder) attrs() sftpAttrs {
+	var attrs sftpAttrs
+	flags := d.uint32()
+	if flags&attrSize != 0 {
+		attrs.size = d.uint64()
+	}
+	if flags&attrUIDGID != 0 {
+		d.take(8)
+	}
+	if flags&attrPermissions != 0 {
+		attrs.mode = d.uint32()
+		attrs.hasMode = true
+	}
+	if flags&attrACModTime != 0 {
+		d.uint32() // Access time
+		attrs.mtime = time.Unix(int64(d.uint32()), 0).UTC()
+	}
+	if flags&attrExtended != 0 {
+		for n := d.uint32(); n > 0 && d.err == nil; n-- {
+			d.string()
+			d.string()
+		}
+	}
+	return attrs
+}
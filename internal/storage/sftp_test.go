@@ -0,0 +1,401 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeSFTPServer serves a directory over SFTP, accepting the password
+// "secret" and the public key clientKey
+type fakeSFTPServer struct {
+	dir       string
+	addr      string
+	hostKey   ssh.PublicKey
+	clientKey ed25519.PrivateKey
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newFakeSFTPServer(t *testing.T) *fakeSFTPServer {
+	t.Helper()
+	hostSigner, err := ssh.NewSignerFromKey(mustEd25519Key(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientPriv := mustEd25519Key(t)
+	clientSigner, err := ssh.NewSignerFromKey(clientPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) != "secret" {
+				return nil, errors.New("wrong password")
+			}
+			return nil, nil
+		},
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if !bytes.Equal(key.Marshal(), clientSigner.PublicKey().Marshal()) {
+				return nil, errors.New("unknown key")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	f := &fakeSFTPServer{dir: t.TempDir(), addr: listener.Addr().String(), hostKey: hostSigner.PublicKey(), clientKey: clientPriv}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns = append(f.conns, conn)
+			f.mu.Unlock()
+			go f.serveConn(conn, config)
+		}
+	}()
+	return f
+}
+
+// dropConnections closes every connection, as a server restart would
+func (f *fakeSFTPServer) dropConnections() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, conn := range f.conns {
+		_ = conn.Close()
+	}
+	f.conns = nil
+}
+
+func (f *fakeSFTPServer) serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "sessions only")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				_ = req.Reply(ok, nil)
+				if ok {
+					go f.serveSFTP(channel)
+				}
+			}
+		}()
+	}
+}
+
+// serveSFTP answers the requests sftpClient sends, with paths resolved
+// against f.dir
+func (f *fakeSFTPServer) serveSFTP(channel ssh.Channel) {
+	defer func() { _ = channel.Close() }()
+	files := make(map[string]*os.File)
+	dirs := make(map[string][]os.DirEntry)
+	nextHandle := 0
+
+	reply := func(typ byte, payload []byte) {
+		packet := binary.BigEndian.AppendUint32(nil, uint32(1+len(payload)))
+		_, _ = channel.Write(append(append(packet, typ), payload...))
+	}
+	status := func(id []byte, err error) {
+		code := uint32(fxOK)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			code = fxNoSuchFile
+		case err == io.EOF:
+			code = fxEOF
+		case err != nil:
+			code = 4
+		}
+		reply(fxpStatus, appendString(appendString(binary.BigEndian.AppendUint32(id, code), ""), ""))
+	}
+	attrs := func(b []byte, info os.FileInfo) []byte {
+		mode := uint32(0o100644)
+		if info.IsDir() {
+			mode = 0o040755
+		}
+		b = binary.BigEndian.AppendUint32(b, attrSize|attrPermissions|attrACModTime)
+		b = binary.BigEndian.AppendUint64(b, uint64(info.Size()))
+		b = binary.BigEndian.AppendUint32(b, mode)
+		b = binary.BigEndian.AppendUint32(b, uint32(info.ModTime().Unix()))
+		return binary.BigEndian.AppendUint32(b, uint32(info.ModTime().Unix()))
+	}
+
+	c := &sftpClient{r: channel}
+	for {
+		typ, payload, err := c.readPacket()
+		if err != nil {
+			return
+		}
+		if typ == fxpInit {
+			reply(fxpVersion, binary.BigEndian.AppendUint32(nil, sftpVersion))
+			continue
+		}
+		id := append([]byte(nil), payload[:4]...)
+		d := &sftpDecoder{buf: payload[4:]}
+		local := func(p string) string { return filepath.Join(f.dir, filepath.FromSlash(p)) }
+
+		switch typ {
+		case fxpOpen:
+			name := d.string()
+			flags := d.uint32()
+			var file *os.File
+			if flags&fxfWrite != 0 {
+				file, err = os.Create(local(name))
+			} else {
+				file, err = os.Open(local(name))
+			}
+			if err != nil {
+				status(id, err)
+				continue
+			}
+			nextHandle++
+			handle := string(rune('a' + nextHandle))
+			files[handle] = file
+			reply(fxpHandle, appendString(id, handle))
+		case fxpClose:
+			handle := d.string()
+			if file, ok := files[handle]; ok {
+				_ = file.Close()
+				delete(files, handle)
+			}
+			delete(dirs, handle)
+			status(id, nil)
+		case fxpWrite:
+			handle := d.string()
+			offset := d.uint64()
+			_, err := files[handle].WriteAt([]byte(d.string()), int64(offset))
+			status(id, err)
+		case fxpRead:
+			handle := d.string()
+			offset := d.uint64()
+			buf := make([]byte, d.uint32())
+			n, err := files[handle].ReadAt(buf, int64(offset))
+			if n == 0 {
+				status(id, err)
+				continue
+			}
+			reply(fxpData, appendString(id, string(buf[:n])))
+		case fxpStat:
+			info, err := os.Stat(local(d.string()))
+			if err != nil {
+				status(id, err)
+				continue
+			}
+			reply(fxpAttrs, attrs(id, info))
+		case fxpMkdir:
+			status(id, os.Mkdir(local(d.string()), 0o755))
+		case fxpRemove:
+			status(id, os.Remove(local(d.string())))
+		case fxpRename:
+			oldPath := local(d.string())
+			newPath := local(d.string())
+			if _, err := os.Stat(newPath); err == nil {
+				status(id, os.ErrExist)
+				continue
+			}
+			status(id, os.Rename(oldPath, newPath))
+		case fxpOpendir:
+			entries, err := os.ReadDir(local(d.string()))
+			if err != nil {
+				status(id, err)
+				continue
+			}
+			nextHandle++
+			handle := string(rune('a' + nextHandle))
+			dirs[handle] = entries
+			reply(fxpHandle, appendString(id, handle))
+		case fxpReaddir:
+			handle := d.string()
+			entries := dirs[handle]
+			if len(entries) == 0 {
+				status(id, io.EOF)
+				continue
+			}
+			dirs[handle] = nil
+			b := binary.BigEndian.AppendUint32(id, uint32(len(entries)))
+			for _, entry := range entries {
+				info, _ := entry.Info()
+				b = attrs(appendString(appendString(b, entry.Name()), entry.Name()), info)
+			}
+			reply(fxpName, b)
+		default:
+			status(id, errors.New("unsupported"))
+		}
+	}
+}
+
+func TestSFTPStorage(t *testing.T) {
+	server := newFakeSFTPServer(t)
+	ctx := context.Background()
+
+	// Pinned as ssh-keyscan prints it
+	hostKey := "[127.0.0.1] " + string(ssh.MarshalAuthorizedKey(server.hostKey))
+	store, err := NewSFTPStorage(SFTPConfig{Host: server.addr, User: "backup", Password: "secret", HostKey: hostKey, Path: "snipo/data"})
+	if err != nil {
+		t.Fatalf("NewSFTPStorage failed: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(server.dir, "snipo", "data")); err != nil || !info.IsDir() {
+		t.Fatalf("expected the directory to be created, got %v", err)
+	}
+
+	content := bytes.Repeat([]byte("snippet "), 10000) // Several chunks
+	var sent, total int64
+	if err := store.UploadWithProgress(ctx, "backups/one.json", content, "application/json", func(s, t int64) { sent, total = s, t }); err != nil {
+		t.Fatalf("UploadWithProgress failed: %v", err)
+	}
+	if sent != int64(len(content)) || total != int64(len(content)) {
+		t.Errorf("expected progress to reach %d, got %d of %d", len(content), sent, total)
+	}
+	if err := store.Upload(ctx, "backups/one.json.meta.json", []byte("{}"), "application/json"); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	// Left behind by an interrupted upload
+	if err := os.WriteFile(filepath.Join(server.dir, "snipo", "data", "backups", "two.json.part"), []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.Download(ctx, "backups/one.json")
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("expected the uploaded content back, got %d bytes (err %v)", len(got), err)
+	}
+	if _, err := store.Download(ctx, "backups/missing.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	objects, err := store.List(ctx, "backups/")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(objects) != 2 || objects[0].Key != "backups/one.json" || objects[0].Size != int64(len(content)) ||
+		objects[1].Key != "backups/one.json.meta.json" || objects[0].LastModified.IsZero() {
+		t.Errorf("unexpected listing: %+v", objects)
+	}
+	if objects, _ := store.List(ctx, "backups/one.json.m"); len(objects) != 1 {
+		t.Errorf("expected the prefix to select one file, got %+v", objects)
+	}
+
+	t.Run("replaces existing files", func(t *testing.T) {
+		if err := store.Upload(ctx, "backups/one.json", []byte("v2"), "application/json"); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+		if got, _ := store.Download(ctx, "backups/one.json"); string(got) != "v2" {
+			t.Errorf("expected the new content, got %q", got)
+		}
+	})
+
+	t.Run("reconnects after the server drops the connection", func(t *testing.T) {
+		server.dropConnections()
+		if _, err := store.Download(ctx, "backups/one.json"); err != nil {
+			t.Errorf("Download failed: %v", err)
+		}
+	})
+
+	t.Run("keys stay in the directory", func(t *testing.T) {
+		for _, key := range []string{"../escape", "/etc/passwd", "backups/../../x"} {
+			if err := store.Upload(ctx, key, []byte("x"), ""); err == nil {
+				t.Errorf("expected %q to be refused", key)
+			}
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := store.Delete(ctx, "backups/one.json"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if err := store.Delete(ctx, "backups/one.json"); err != nil {
+			t.Errorf("expected deleting a missing file to succeed, got %v", err)
+		}
+		if _, err := store.Download(ctx, "backups/one.json"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound after delete, got %v", err)
+		}
+	})
+
+	if _, err := store.GetPresignedURL(ctx, "backups/one.json.meta.json", 0); !errors.Is(err, ErrPresignUnsupported) {
+		t.Errorf("expected ErrPresignUnsupported, got %v", err)
+	}
+}
+
+func TestNewSFTPStorage_Auth(t *testing.T) {
+	server := newFakeSFTPServer(t)
+	block, err := ssh.MarshalPrivateKey(mustEd25519Key(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey := string(pem.EncodeToMemory(block))
+
+	clientBlock, err := ssh.MarshalPrivateKeyWithPassphrase(server.clientKey, "", []byte("unlock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKey := string(pem.EncodeToMemory(clientBlock))
+	hostKey := string(ssh.MarshalAuthorizedKey(server.hostKey))
+
+	tests := []struct {
+		name    string
+		cfg     SFTPConfig
+		wantErr string
+	}{
+		{"private key", SFTPConfig{PrivateKey: clientKey, PrivateKeyPassphrase: "unlock", HostKey: hostKey}, ""},
+		{"fingerprint", SFTPConfig{Password: "secret", HostKey: ssh.FingerprintSHA256(server.hostKey)}, ""},
+		{"wrong password", SFTPConfig{Password: "guess", HostKey: hostKey}, "unable to authenticate"},
+		{"unknown key", SFTPConfig{PrivateKey: otherKey, HostKey: hostKey}, "unable to authenticate"},
+		{"other host key", SFTPConfig{Password: "secret", HostKey: "SHA256:bm90IHRoZSBzZXJ2ZXIncyBrZXk"}, "does not match"},
+		{"no host key", SFTPConfig{Password: "secret"}, "host key is required"},
+		{"no credentials", SFTPConfig{HostKey: hostKey}, "password or private key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Host, tt.cfg.User = server.addr, "backup"
+			_, err := NewSFTPStorage(tt.cfg)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("NewSFTPStorage failed: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func mustEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
@@ -0,0 +1,182 @@
+// Package telemetry counts how often each API route is used, for the usage
+// report admins read at /api/v1/admin/usage. Counting is opt-in with
+// SNIPO_TELEMETRY_ENABLED, and the counts stay in the instance's own
+// database: nothing is sent anywhere.
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// RetentionDays is how many days of counts are kept, and the longest report
+const RetentionDays = 90
+
+// flushInterval is how often counts are written to the store, so requests
+// don't each cost a write
+const flushInterval = time.Minute
+
+const dayLayout = "2006-01-02"
+
+// Store keeps the daily counts
+type Store interface {
+	AddUsage(ctx context.Context, day string, counts map[string]int) error
+	UsageSince(ctx context.Context, day string) ([]models.UsageCount, error)
+	PruneUsage(ctx context.Context, day string) error
+}
+
+type usageKey struct {
+	day   string
+	route string
+}
+
+// Recorder counts requests in memory and flushes them to a Store. It is safe
+// for concurrent use.
+type Recorder struct {
+	store  Store
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu     sync.Mutex
+	counts map[usageKey]int
+}
+
+// NewRecorder creates a recorder writing to store
+func NewRecorder(store Store, logger *slog.Logger) *Recorder {
+	return &Recorder{
+		store:  store,
+		logger: logger,
+		now:    time.Now,
+		counts: make(map[usageKey]int),
+	}
+}
+
+// Record counts one request to route, a method and route pattern such as
+// "GET /api/v1/snippets/{id}"
+func (r *Recorder) Record(route string) {
+	day := r.now().UTC().Format(dayLayout)
+	r.mu.Lock()
+	r.counts[usageKey{day: day, route: route}]++
+	r.mu.Unlock()
+}
+
+// Start flushes the counts every minute and prunes days past the retention
+// once a day, until ctx is done
+func (r *Recorder) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		var pruned string
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Flush(ctx); err != nil {
+					r.logger.Warn("failed to save usage counts", "error", err)
+				}
+				if today := r.now().UTC().Format(dayLayout); today != pruned {
+					if err := r.store.PruneUsage(ctx, r.firstDay(RetentionDays)); err != nil {
+						r.logger.Warn("failed to prune usage counts", "error", err)
+					} else {
+						pruned = today
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Flush writes the counts recorded since the last flush. Counts that fail to
+// save are kept for the next one.
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	pending := r.counts
+	r.counts = make(map[usageKey]int)
+	r.mu.Unlock()
+
+	byDay := make(map[string]map[string]int)
+	for key, count := range pending {
+		if byDay[key.day] == nil {
+			byDay[key.day] = make(map[string]int)
+		}
+		byDay[key.day][key.route] = count
+	}
+
+	for day, counts := range byDay {
+		if err := r.store.AddUsage(ctx, day, counts); err != nil {
+			r.mu.Lock()
+			for key, count := range pending {
+				r.counts[key] += count
+			}
+			r.mu.Unlock()
+			return err
+		}
+		for route := range counts {
+			delete(pending, usageKey{day: day, route: route})
+		}
+	}
+	return nil
+}
+
+// Report returns the usage of the last days days, today included
+func (r *Recorder) Report(ctx context.Context, days int) (*models.UsageReport, error) {
+	if err := r.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	since := r.firstDay(days)
+	routes, err := r.store.UsageSince(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UsageReport{
+		Enabled:  true,
+		Days:     days,
+		Since:    since,
+		Features: Features(routes),
+		Routes:   routes,
+	}, nil
+}
+
+// firstDay returns the first of the last days days
+func (r *Recorder) firstDay(days int) string {
+	return r.now().UTC().AddDate(0, 0, 1-days).Format(dayLayout)
+}
+
+// Features sums route counts by API area, the first path segment after
+// /api/v1, most used first
+func Features(routes []models.UsageCount) []models.UsageCount {
+	totals := make(map[string]int)
+	for _, route := range routes {
+		totals[Feature(route.Name)] += route.Count
+	}
+
+	features := make([]models.UsageCount, 0, len(totals))
+	for name, count := range totals {
+		features = append(features, models.UsageCount{Name: name, Count: count})
+	}
+	sort.Slice(features, func(i, j int) bool {
+		if features[i].Count != features[j].Count {
+			return features[i].Count > features[j].Count
+		}
+		return features[i].Name < features[j].Name
+	})
+	return features
+}
+
+// Feature returns the API area of a route, "snippets" for
+// "GET /api/v1/snippets/{id}"
+func Feature(route string) string {
+	_, path, _ := strings.Cut(route, " ")
+	path = strings.TrimPrefix(path, "/api/v1/")
+	area, _, _ := strings.Cut(path, "/")
+	return area
+}
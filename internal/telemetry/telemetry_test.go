@@ -0,0 +1,95 @@
+package telemetry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func TestRecorder(t *testing.T) {
+	db := testutil.TestDB(t)
+	ctx := testutil.TestContext()
+	store := repository.NewUsageRepository(db)
+	rec := NewRecorder(store, testutil.TestLogger())
+
+	now := time.Date(2026, 3, 10, 23, 59, 0, 0, time.UTC)
+	rec.now = func() time.Time { return now }
+
+	rec.Record("GET /api/v1/snippets/{id}")
+	rec.Record("GET /api/v1/snippets/{id}")
+	rec.Record("POST /api/v1/backup/export")
+	if err := rec.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	// Counts of the next day add up with the saved ones
+	now = now.Add(time.Minute)
+	rec.Record("GET /api/v1/snippets/{id}")
+	rec.Record("GET /api/v1/tags")
+
+	report, err := rec.Report(ctx, 30)
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if !report.Enabled || report.Days != 30 || report.Since != "2026-02-10" {
+		t.Errorf("unexpected report window: %+v", report)
+	}
+	wantRoutes := []models.UsageCount{
+		{Name: "GET /api/v1/snippets/{id}", Count: 3},
+		{Name: "GET /api/v1/tags", Count: 1},
+		{Name: "POST /api/v1/backup/export", Count: 1},
+	}
+	if len(report.Routes) != len(wantRoutes) {
+		t.Fatalf("expected routes %v, got %v", wantRoutes, report.Routes)
+	}
+	for i, want := range wantRoutes {
+		if report.Routes[i] != want {
+			t.Errorf("route %d: expected %v, got %v", i, want, report.Routes[i])
+		}
+	}
+	wantFeatures := []models.UsageCount{{Name: "snippets", Count: 3}, {Name: "backup", Count: 1}, {Name: "tags", Count: 1}}
+	for i, want := range wantFeatures {
+		if i >= len(report.Features) || report.Features[i] != want {
+			t.Errorf("expected features %v, got %v", wantFeatures, report.Features)
+			break
+		}
+	}
+
+	// A one-day report only covers today
+	report, err = rec.Report(ctx, 1)
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if len(report.Routes) != 2 || report.Routes[0].Count != 1 {
+		t.Errorf("expected today's 2 routes once each, got %v", report.Routes)
+	}
+
+	// Days past the retention are pruned
+	if err := store.PruneUsage(ctx, "2026-03-11"); err != nil {
+		t.Fatalf("PruneUsage failed: %v", err)
+	}
+	report, err = rec.Report(ctx, RetentionDays)
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if len(report.Routes) != 2 {
+		t.Errorf("expected only today's routes after pruning, got %v", report.Routes)
+	}
+}
+
+func TestFeature(t *testing.T) {
+	tests := map[string]string{
+		"GET /api/v1/snippets/{id}":                "snippets",
+		"POST /api/v1/backup/s3/sync":              "backup",
+		"GET /api/v1/tags":                         "tags",
+		"POST /api/v1/integrations/slack/command/": "integrations",
+	}
+	for route, want := range tests {
+		if got := Feature(route); got != want {
+			t.Errorf("Feature(%q) = %q, want %q", route, got, want)
+		}
+	}
+}
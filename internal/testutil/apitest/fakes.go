@@ -0,0 +1,1127 @@
+package apitest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/MohamedElashri/snipo/internal/services"
+	"github.com/MohamedElashri/snipo/internal/validation"
+)
+
+var (
+	_ services.SnippetManager  = (*SnippetManager)(nil)
+	_ repository.TagStore      = (*TagStore)(nil)
+	_ repository.FolderStore   = (*FolderStore)(nil)
+	_ repository.SettingsStore = (*SettingsStore)(nil)
+	_ repository.FeatureFlags  = (*SettingsStore)(nil)
+)
+
+// SnippetManager is an in-memory services.SnippetManager
+type SnippetManager struct {
+	mu       sync.Mutex
+	snippets map[string]*models.Snippet
+	order    []string
+	nextID   int
+
+	// RejectDuplicateContent mirrors the reject_duplicate_content setting
+	RejectDuplicateContent bool
+}
+
+// NewSnippetManager creates an empty in-memory snippet manager
+func NewSnippetManager() *SnippetManager {
+	return &SnippetManager{snippets: make(map[string]*models.Snippet)}
+}
+
+// Create validates and stores a snippet
+func (m *SnippetManager) Create(ctx context.Context, input *models.SnippetInput) (*models.Snippet, error) {
+	if errs := validation.ValidateSnippetInput(input); errs.HasErrors() {
+		return nil, errs
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	now := time.Now().UTC()
+	snippet := &models.Snippet{
+		ID:        fmt.Sprintf("snippet-%d", m.nextID),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	applySnippetInput(snippet, input)
+
+	m.snippets[snippet.ID] = snippet
+	m.order = append(m.order, snippet.ID)
+	return copySnippet(snippet), nil
+}
+
+// GetByID returns a snippet or services.ErrSnippetNotFound
+func (m *SnippetManager) GetByID(ctx context.Context, id string) (*models.Snippet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snippet, ok := m.snippets[id]
+	if !ok {
+		return nil, services.ErrSnippetNotFound
+	}
+	return copySnippet(snippet), nil
+}
+
+// GetByIDPublic returns a public snippet and counts the view
+func (m *SnippetManager) GetByIDPublic(ctx context.Context, id string) (*models.Snippet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snippet, ok := m.snippets[id]
+	if !ok || !snippet.IsPublic {
+		return nil, services.ErrSnippetNotFound
+	}
+	snippet.ViewCount++
+	return copySnippet(snippet), nil
+}
+
+// Update validates and replaces a snippet's fields
+func (m *SnippetManager) Update(ctx context.Context, id string, input *models.SnippetInput) (*models.Snippet, error) {
+	if errs := validation.ValidateSnippetInput(input); errs.HasErrors() {
+		return nil, errs
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snippet, ok := m.snippets[id]
+	if !ok {
+		return nil, services.ErrSnippetNotFound
+	}
+	applySnippetInput(snippet, input)
+	snippet.UpdatedAt = time.Now().UTC()
+	return copySnippet(snippet), nil
+}
+
+// Upsert updates the snippet with externalID or creates one tagged with it
+func (m *SnippetManager) Upsert(ctx context.Context, externalID string, input *models.SnippetInput) (*models.Snippet, bool, error) {
+	if errs := validation.ValidateExternalID(externalID); errs.HasErrors() {
+		return nil, false, errs
+	}
+
+	m.mu.Lock()
+	var existing string
+	for _, snippet := range m.snippets {
+		if snippet.ExternalID != nil && *snippet.ExternalID == externalID {
+			existing = snippet.ID
+		}
+	}
+	m.mu.Unlock()
+
+	if existing != "" {
+		snippet, err := m.Update(ctx, existing, input)
+		return snippet, false, err
+	}
+
+	snippet, err := m.Create(ctx, input)
+	if err != nil {
+		return nil, false, err
+	}
+	m.mu.Lock()
+	m.snippets[snippet.ID].ExternalID = &externalID
+	m.mu.Unlock()
+	snippet.ExternalID = &externalID
+	return snippet, true, nil
+}
+
+// Delete removes a snippet; soft deletes only mark it
+func (m *SnippetManager) Delete(ctx context.Context, id string, permanent bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snippet, ok := m.snippets[id]
+	if !ok {
+		return services.ErrSnippetNotFound
+	}
+	if !permanent {
+		now := time.Now().UTC()
+		snippet.DeletedAt = &now
+		return nil
+	}
+	delete(m.snippets, id)
+	for i, existing := range m.order {
+		if existing == id {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Restore clears a soft delete
+func (m *SnippetManager) Restore(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snippet, ok := m.snippets[id]
+	if !ok || snippet.DeletedAt == nil {
+		return services.ErrSnippetNotFound
+	}
+	snippet.DeletedAt = nil
+	return nil
+}
+
+// Bulk validates and applies a bulk action, changing nothing unless every
+// snippet exists
+func (m *SnippetManager) Bulk(ctx context.Context, input *models.BulkSnippetInput) (*models.BulkSnippetResult, error) {
+	if errs := validation.ValidateBulkSnippetInput(input); errs.HasErrors() {
+		return nil, errs
+	}
+
+	if input.Action == models.BulkCreate {
+		result := &models.BulkSnippetResult{Action: input.Action, IDs: []string{}}
+		for i := range input.Snippets {
+			snippet, err := m.Create(ctx, &input.Snippets[i])
+			if err != nil {
+				return nil, err
+			}
+			result.IDs = append(result.IDs, snippet.ID)
+			result.Snippets = append(result.Snippets, *snippet)
+		}
+		result.Affected = len(result.IDs)
+		return result, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range input.IDs {
+		snippet, ok := m.snippets[id]
+		trashed := ok && snippet.DeletedAt != nil
+		if !ok || (input.Action == models.BulkRestore && !trashed) || (input.Action == models.BulkDelete && !input.Permanent && trashed) {
+			return nil, services.ErrSnippetNotFound
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, id := range input.IDs {
+		snippet := m.snippets[id]
+		switch input.Action {
+		case models.BulkUpdate:
+			if input.Set.IsPublic != nil {
+				snippet.IsPublic = *input.Set.IsPublic
+			}
+			if input.Set.IsFavorite != nil {
+				snippet.IsFavorite = *input.Set.IsFavorite
+			}
+		case models.BulkDelete:
+			if input.Permanent {
+				delete(m.snippets, id)
+				m.order = slices.DeleteFunc(m.order, func(existing string) bool { return existing == id })
+			} else {
+				snippet.DeletedAt = &now
+			}
+		case models.BulkRestore:
+			snippet.DeletedAt = nil
+		case models.BulkArchive:
+			snippet.IsArchived = true
+			snippet.IsPublic = false
+		case models.BulkUnarchive:
+			snippet.IsArchived = false
+		case models.BulkTag:
+			snippet.Tags = slices.DeleteFunc(snippet.Tags, func(t models.Tag) bool { return slices.Contains(input.RemoveTags, t.Name) })
+			for _, name := range input.AddTags {
+				if !slices.ContainsFunc(snippet.Tags, func(t models.Tag) bool { return t.Name == name }) {
+					snippet.Tags = append(snippet.Tags, models.Tag{Name: name})
+				}
+			}
+		case models.BulkMove:
+			snippet.Folders = nil
+			if input.FolderID != nil {
+				snippet.Folders = []models.Folder{{ID: *input.FolderID}}
+			}
+		}
+		snippet.UpdatedAt = now
+	}
+
+	return &models.BulkSnippetResult{Action: input.Action, Affected: len(input.IDs), IDs: input.IDs}, nil
+}
+
+// List pages through snippets in creation order, honoring the query and
+// favorite/archived/deleted filters
+func (m *SnippetManager) List(ctx context.Context, filter models.SnippetFilter) (*models.SnippetListResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	matched := make([]models.Snippet, 0)
+	for _, id := range m.order {
+		s := m.snippets[id]
+		if filter.Query != "" && !strings.Contains(strings.ToLower(s.Title), strings.ToLower(filter.Query)) {
+			continue
+		}
+		if filter.IsFavorite != nil && s.IsFavorite != *filter.IsFavorite {
+			continue
+		}
+		if filter.IsArchived != nil && s.IsArchived != *filter.IsArchived {
+			continue
+		}
+		deleted := filter.IsDeleted != nil && *filter.IsDeleted
+		if (s.DeletedAt != nil) != deleted {
+			continue
+		}
+		matched = append(matched, *copySnippet(s))
+	}
+
+	page, limit := filter.Page, filter.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	start := (page - 1) * limit
+	if start > len(matched) {
+		start = len(matched)
+	}
+	end := start + limit
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	return &models.SnippetListResponse{
+		Data: matched[start:end],
+		Pagination: models.Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      len(matched),
+			TotalPages: (len(matched) + limit - 1) / limit,
+		},
+	}, nil
+}
+
+// ListPublic returns every public, non-archived snippet
+func (m *SnippetManager) ListPublic(ctx context.Context) ([]models.Snippet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	public := make([]models.Snippet, 0)
+	for _, id := range m.order {
+		s := m.snippets[id]
+		if s.IsPublic && !s.IsArchived && s.DeletedAt == nil {
+			public = append(public, *copySnippet(s))
+		}
+	}
+	return public, nil
+}
+
+// ToggleFavorite flips the favorite flag
+func (m *SnippetManager) ToggleFavorite(ctx context.Context, id string) (*models.Snippet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snippet, ok := m.snippets[id]
+	if !ok {
+		return nil, services.ErrSnippetNotFound
+	}
+	snippet.IsFavorite = !snippet.IsFavorite
+	return copySnippet(snippet), nil
+}
+
+// ToggleArchive flips the archived flag; archiving also unpublishes
+func (m *SnippetManager) ToggleArchive(ctx context.Context, id string) (*models.Snippet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snippet, ok := m.snippets[id]
+	if !ok {
+		return nil, services.ErrSnippetNotFound
+	}
+	snippet.IsArchived = !snippet.IsArchived
+	if snippet.IsArchived {
+		snippet.IsPublic = false
+	}
+	return copySnippet(snippet), nil
+}
+
+// RecordUse increments the use count and stamps the last use
+func (m *SnippetManager) RecordUse(ctx context.Context, id string) (*models.Snippet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snippet, ok := m.snippets[id]
+	if !ok || snippet.DeletedAt != nil {
+		return nil, services.ErrSnippetNotFound
+	}
+	now := time.Now()
+	snippet.UseCount++
+	snippet.LastUsedAt = &now
+	return copySnippet(snippet), nil
+}
+
+// RecordPublicView counts a share page visit to a public snippet. Referrers
+// are not tracked by the fake.
+func (m *SnippetManager) RecordPublicView(ctx context.Context, id, referrerDomain string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if snippet, ok := m.snippets[id]; ok && snippet.IsPublic && snippet.DeletedAt == nil {
+		snippet.PublicViewCount++
+	}
+	return nil
+}
+
+// GetViewStats returns the view counters of a snippet
+func (m *SnippetManager) GetViewStats(ctx context.Context, id string) (*models.SnippetViewStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snippet, ok := m.snippets[id]
+	if !ok {
+		return nil, services.ErrSnippetNotFound
+	}
+	return &models.SnippetViewStats{
+		ViewCount:       snippet.ViewCount,
+		PublicViewCount: snippet.PublicViewCount,
+		Referrers:       []models.ReferrerStat{},
+	}, nil
+}
+
+// Search matches the query against titles, descriptions and content,
+// without ranking or highlights
+func (m *SnippetManager) Search(ctx context.Context, query string, limit int) ([]models.SearchResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	results := make([]models.SearchResult, 0)
+	if query == "" {
+		return results, nil
+	}
+	q := strings.ToLower(query)
+	for _, id := range m.order {
+		s := m.snippets[id]
+		if s.DeletedAt != nil {
+			continue
+		}
+		text := strings.ToLower(s.Title + " " + s.Description + " " + s.Content)
+		if strings.Contains(text, q) {
+			results = append(results, models.SearchResult{Snippet: *copySnippet(s)})
+		}
+		if limit > 0 && len(results) == limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+// ExportSearchIndex lists the snippets outside the trash updated from since
+// on, without tracking removals
+func (m *SnippetManager) ExportSearchIndex(ctx context.Context, since *time.Time) (*models.SearchIndexExport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	export := &models.SearchIndexExport{
+		Version:     models.SearchIndexVersion,
+		Fields:      models.SearchIndexFields,
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		Since:       since,
+		Documents:   []models.SearchIndexDocument{},
+		Removed:     []string{},
+	}
+	for _, id := range m.order {
+		s := m.snippets[id]
+		if s.DeletedAt != nil || (since != nil && s.UpdatedAt.Before(*since)) {
+			continue
+		}
+		doc := models.SearchIndexDocument{
+			ID: s.ID, Title: s.Title, Language: s.Language, Tags: []string{}, Folders: []string{},
+			IsFavorite: s.IsFavorite, IsArchived: s.IsArchived, UpdatedAt: s.UpdatedAt,
+		}
+		for _, tag := range s.Tags {
+			doc.Tags = append(doc.Tags, tag.Name)
+		}
+		for _, folder := range s.Folders {
+			doc.Folders = append(doc.Folders, folder.Name)
+		}
+		export.Documents = append(export.Documents, doc)
+	}
+	return export, nil
+}
+
+// Neighbors locates a snippet in the list List would return for filter
+func (m *SnippetManager) Neighbors(ctx context.Context, id string, filter models.SnippetFilter) (*models.SnippetNeighbors, error) {
+	filter.Page, filter.Limit = 1, 1<<30
+	result, err := m.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i, s := range result.Data {
+		if s.ID != id {
+			continue
+		}
+		neighbors := &models.SnippetNeighbors{Position: i + 1, Total: len(result.Data)}
+		if i > 0 {
+			neighbors.PrevID = &result.Data[i-1].ID
+		}
+		if i < len(result.Data)-1 {
+			neighbors.NextID = &result.Data[i+1].ID
+		}
+		return neighbors, nil
+	}
+	return nil, services.ErrSnippetNotFound
+}
+
+// FindDuplicates returns snippets with the same title or code as input
+func (m *SnippetManager) FindDuplicates(ctx context.Context, input *models.DuplicateCheckInput) ([]models.DuplicateMatch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	title := strings.TrimSpace(input.Title)
+	hash := fakeContentHash(input.Content, input.Files)
+	var byContent, byTitle []models.DuplicateMatch
+	for _, id := range m.order {
+		s := m.snippets[id]
+		if s.DeletedAt != nil {
+			continue
+		}
+		match := models.DuplicateMatch{ID: s.ID, Title: s.Title, UpdatedAt: s.UpdatedAt}
+		switch {
+		case hash != "" && snippetContentHash(s) == hash:
+			match.MatchedBy = "content"
+			byContent = append(byContent, match)
+		case title != "" && strings.EqualFold(s.Title, title):
+			match.MatchedBy = "title"
+			byTitle = append(byTitle, match)
+		}
+	}
+	return append(append([]models.DuplicateMatch{}, byContent...), byTitle...), nil
+}
+
+// CheckDuplicateContent rejects input when RejectDuplicateContent is set and
+// a snippet with the same code exists
+func (m *SnippetManager) CheckDuplicateContent(ctx context.Context, input *models.SnippetInput) error {
+	if !m.RejectDuplicateContent {
+		return nil
+	}
+	matches, err := m.FindDuplicates(ctx, &models.DuplicateCheckInput{Content: input.Content, Files: input.Files})
+	if err != nil {
+		return err
+	}
+	if len(matches) > 0 && matches[0].MatchedBy == "content" {
+		return &services.DuplicateContentError{Existing: matches[0]}
+	}
+	return nil
+}
+
+func fakeContentHash(content string, files []models.SnippetFileInput) string {
+	contents := make([]string, len(files))
+	for i, f := range files {
+		contents[i] = f.Content
+	}
+	return services.CalculateContentHash(content, contents)
+}
+
+func snippetContentHash(s *models.Snippet) string {
+	contents := make([]string, len(s.Files))
+	for i, f := range s.Files {
+		contents[i] = f.Content
+	}
+	return services.CalculateContentHash(s.Content, contents)
+}
+
+// Duplicate stores a private copy of a snippet
+func (m *SnippetManager) Duplicate(ctx context.Context, id string) (*models.Snippet, error) {
+	existing, err := m.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return m.Create(ctx, &models.SnippetInput{
+		Title:       existing.Title + " (copy)",
+		Description: existing.Description,
+		Content:     existing.Content,
+		Language:    existing.Language,
+	})
+}
+
+// Fork always fails since the fake has no network access
+func (m *SnippetManager) Fork(ctx context.Context, rawURL string) (*models.Snippet, error) {
+	return nil, services.ErrForkSourceNotFound
+}
+
+// GetHistory returns no history for existing snippets
+func (m *SnippetManager) GetHistory(ctx context.Context, id string, limit int) ([]models.SnippetHistory, error) {
+	if _, err := m.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return []models.SnippetHistory{}, nil
+}
+
+// RestoreFromHistory always fails since the fake keeps no history
+func (m *SnippetManager) RestoreFromHistory(ctx context.Context, snippetID string, historyID int64) (*models.Snippet, error) {
+	if _, err := m.GetByID(ctx, snippetID); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("history entry not found")
+}
+
+// DiffHistory reports missing entries since the fake keeps no history
+func (m *SnippetManager) DiffHistory(ctx context.Context, snippetID string, fromID, toID int64) (*models.HistoryDiff, error) {
+	if _, err := m.GetByID(ctx, snippetID); err != nil {
+		return nil, err
+	}
+	return nil, services.ErrHistoryNotFound
+}
+
+// Activity returns no activity since the fake keeps no log
+func (m *SnippetManager) Activity(ctx context.Context, filter models.ActivityFilter) ([]models.ActivityItem, error) {
+	return []models.ActivityItem{}, nil
+}
+
+// SnippetActivity returns no activity for existing snippets
+func (m *SnippetManager) SnippetActivity(ctx context.Context, id string, filter models.ActivityFilter) ([]models.ActivityItem, error) {
+	if _, err := m.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return []models.ActivityItem{}, nil
+}
+
+func applySnippetInput(snippet *models.Snippet, input *models.SnippetInput) {
+	snippet.Title = input.Title
+	snippet.Description = input.Description
+	snippet.Content = input.Content
+	snippet.Language = input.Language
+	snippet.IsPublic = input.IsPublic
+	snippet.IsArchived = input.IsArchived
+
+	snippet.Files = make([]models.SnippetFile, 0, len(input.Files))
+	for i, f := range input.Files {
+		snippet.Files = append(snippet.Files, models.SnippetFile{
+			ID:        int64(i + 1),
+			SnippetID: snippet.ID,
+			Filename:  f.Filename,
+			Content:   f.Content,
+			Language:  f.Language,
+			SortOrder: i,
+		})
+	}
+}
+
+func copySnippet(s *models.Snippet) *models.Snippet {
+	c := *s
+	c.Files = append([]models.SnippetFile(nil), s.Files...)
+	c.Tags = append([]models.Tag(nil), s.Tags...)
+	c.Folders = append([]models.Folder(nil), s.Folders...)
+	return &c
+}
+
+// TagStore is an in-memory repository.TagStore
+type TagStore struct {
+	mu          sync.Mutex
+	tags        map[int64]*models.Tag
+	snippetTags map[string][]string
+	nextID      int64
+}
+
+// NewTagStore creates an empty in-memory tag store
+func NewTagStore() *TagStore {
+	return &TagStore{
+		tags:        make(map[int64]*models.Tag),
+		snippetTags: make(map[string][]string),
+	}
+}
+
+// Create stores a tag, assigning a default palette color when it has none
+func (s *TagStore) Create(ctx context.Context, input *models.TagInput) (*models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tags {
+		if t.Name == input.Name {
+			return nil, repository.ErrAlreadyExists
+		}
+	}
+	s.nextID++
+	tag := &models.Tag{ID: s.nextID, Name: input.Name, Color: fakeTagColor(input), CreatedAt: time.Now().UTC()}
+	s.tags[tag.ID] = tag
+	c := *tag
+	return &c, nil
+}
+
+func fakeTagColor(input *models.TagInput) string {
+	if input.Color != "" {
+		return input.Color
+	}
+	return models.TagColor(input.Name, models.DefaultTagPalette)
+}
+
+// GetByID returns a tag or repository.ErrNotFound
+func (s *TagStore) GetByID(ctx context.Context, id int64) (*models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag, ok := s.tags[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	c := *tag
+	return &c, nil
+}
+
+// GetByName returns a tag or repository.ErrNotFound
+func (s *TagStore) GetByName(ctx context.Context, name string) (*models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tag := range s.tags {
+		if tag.Name == name {
+			c := *tag
+			return &c, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// List returns all tags sorted by name
+func (s *TagStore) List(ctx context.Context) ([]models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tags := make([]models.Tag, 0, len(s.tags))
+	for _, tag := range s.tags {
+		tags = append(tags, *tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+// Update replaces a tag's name and color
+func (s *TagStore) Update(ctx context.Context, id int64, input *models.TagInput) (*models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag, ok := s.tags[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	tag.Name = input.Name
+	tag.Color = fakeTagColor(input)
+	c := *tag
+	return &c, nil
+}
+
+// Delete removes a tag and its snippet associations
+func (s *TagStore) Delete(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag, ok := s.tags[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	delete(s.tags, id)
+	for snippetID, names := range s.snippetTags {
+		kept := names[:0]
+		for _, name := range names {
+			if name != tag.Name {
+				kept = append(kept, name)
+			}
+		}
+		s.snippetTags[snippetID] = kept
+	}
+	return nil
+}
+
+// ListDeleted returns no tags: the fake deletes tags as if the trash were
+// disabled
+func (s *TagStore) ListDeleted(ctx context.Context) ([]models.Tag, error) {
+	return []models.Tag{}, nil
+}
+
+// Restore finds no tag in the trash
+func (s *TagStore) Restore(ctx context.Context, id int64) error {
+	return repository.ErrNotFound
+}
+
+// Purge finds no tag in the trash
+func (s *TagStore) Purge(ctx context.Context, id int64) error {
+	return repository.ErrNotFound
+}
+
+// GetSnippetTags returns the tags attached to a snippet
+func (s *TagStore) GetSnippetTags(ctx context.Context, snippetID string) ([]models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tags := make([]models.Tag, 0)
+	for _, name := range s.snippetTags[snippetID] {
+		for _, tag := range s.tags {
+			if tag.Name == name {
+				tags = append(tags, *tag)
+			}
+		}
+	}
+	return tags, nil
+}
+
+// SetSnippetTags replaces a snippet's tags, creating missing ones
+func (s *TagStore) SetSnippetTags(ctx context.Context, snippetID string, tagNames []string) error {
+	for _, name := range tagNames {
+		if _, err := s.GetByName(ctx, name); err == repository.ErrNotFound {
+			if _, err := s.Create(ctx, &models.TagInput{Name: name}); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snippetTags[snippetID] = append([]string(nil), tagNames...)
+	return nil
+}
+
+// GetTagSnippetCount counts snippets carrying a tag
+func (s *TagStore) GetTagSnippetCount(ctx context.Context, tagID int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag, ok := s.tags[tagID]
+	if !ok {
+		return 0, nil
+	}
+	count := 0
+	for _, names := range s.snippetTags {
+		for _, name := range names {
+			if name == tag.Name {
+				count++
+				break
+			}
+		}
+	}
+	return count, nil
+}
+
+// FolderStore is an in-memory repository.FolderStore
+type FolderStore struct {
+	mu             sync.Mutex
+	folders        map[int64]*models.Folder
+	snippetFolders map[string]int64
+	access         map[int64]models.FolderAccess
+	nextID         int64
+}
+
+// NewFolderStore creates an empty in-memory folder store
+func NewFolderStore() *FolderStore {
+	return &FolderStore{
+		folders:        make(map[int64]*models.Folder),
+		snippetFolders: make(map[string]int64),
+		access:         make(map[int64]models.FolderAccess),
+	}
+}
+
+// Create stores a folder
+func (s *FolderStore) Create(ctx context.Context, input *models.FolderInput) (*models.Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	folder := &models.Folder{
+		ID:        s.nextID,
+		Name:      input.Name,
+		ParentID:  input.ParentID,
+		Icon:      input.Icon,
+		SortOrder: input.SortOrder,
+		CreatedAt: time.Now().UTC(),
+	}
+	s.folders[folder.ID] = folder
+	c := *folder
+	return &c, nil
+}
+
+// GetByID returns a folder or repository.ErrNotFound
+func (s *FolderStore) GetByID(ctx context.Context, id int64) (*models.Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folder, ok := s.folders[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	c := *folder
+	return &c, nil
+}
+
+// List returns all folders sorted by sort order and name
+func (s *FolderStore) List(ctx context.Context) ([]models.Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sorted(func(*models.Folder) bool { return true }), nil
+}
+
+// ListTree returns root folders with their children nested
+func (s *FolderStore) ListTree(ctx context.Context) ([]models.Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.children(nil), nil
+}
+
+func (s *FolderStore) children(parentID *int64) []models.Folder {
+	folders := s.sorted(func(f *models.Folder) bool {
+		if parentID == nil {
+			return f.ParentID == nil
+		}
+		return f.ParentID != nil && *f.ParentID == *parentID
+	})
+	for i := range folders {
+		folders[i].Children = s.children(&folders[i].ID)
+	}
+	return folders
+}
+
+func (s *FolderStore) sorted(keep func(*models.Folder) bool) []models.Folder {
+	folders := make([]models.Folder, 0)
+	for _, f := range s.folders {
+		if keep(f) {
+			folders = append(folders, *f)
+		}
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		if folders[i].SortOrder != folders[j].SortOrder {
+			return folders[i].SortOrder < folders[j].SortOrder
+		}
+		return folders[i].Name < folders[j].Name
+	})
+	return folders
+}
+
+// Update replaces a folder's fields
+func (s *FolderStore) Update(ctx context.Context, id int64, input *models.FolderInput) (*models.Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folder, ok := s.folders[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	folder.Name = input.Name
+	folder.ParentID = input.ParentID
+	folder.Icon = input.Icon
+	folder.SortOrder = input.SortOrder
+	c := *folder
+	return &c, nil
+}
+
+// Delete removes a folder according to mode. Snippets live in a separate
+// fake, so the trash mode only drops the subtree's folder associations.
+func (s *FolderStore) Delete(ctx context.Context, id int64, mode string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folder, ok := s.folders[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	switch mode {
+	case models.FolderDeleteMoveToParent, models.FolderDeleteOrphan:
+		newParentID := folder.ParentID
+		if mode == models.FolderDeleteOrphan {
+			newParentID = nil
+		}
+		for _, f := range s.folders {
+			if f.ParentID != nil && *f.ParentID == id {
+				f.ParentID = newParentID
+			}
+		}
+		for snippetID, folderID := range s.snippetFolders {
+			if folderID != id {
+				continue
+			}
+			if newParentID != nil {
+				s.snippetFolders[snippetID] = *newParentID
+			} else {
+				delete(s.snippetFolders, snippetID)
+			}
+		}
+		delete(s.folders, id)
+	case models.FolderDeleteTrash:
+		s.deleteSubtree(id)
+	default:
+		return fmt.Errorf("unknown folder delete mode: %s", mode)
+	}
+	return nil
+}
+
+func (s *FolderStore) deleteSubtree(id int64) {
+	for childID, f := range s.folders {
+		if f.ParentID != nil && *f.ParentID == id {
+			s.deleteSubtree(childID)
+		}
+	}
+	for snippetID, folderID := range s.snippetFolders {
+		if folderID == id {
+			delete(s.snippetFolders, snippetID)
+		}
+	}
+	delete(s.folders, id)
+}
+
+// ListDeleted returns no folders: the fake deletes folders as if the trash
+// were disabled
+func (s *FolderStore) ListDeleted(ctx context.Context) ([]models.Folder, error) {
+	return []models.Folder{}, nil
+}
+
+// Restore finds no folder in the trash
+func (s *FolderStore) Restore(ctx context.Context, id int64) error {
+	return repository.ErrNotFound
+}
+
+// Purge finds no folder in the trash
+func (s *FolderStore) Purge(ctx context.Context, id int64) error {
+	return repository.ErrNotFound
+}
+
+// Move re-parents a folder, rejecting moves into its own subtree with the
+// same error the SQL repository returns
+func (s *FolderStore) Move(ctx context.Context, id int64, newParentID *int64) (*models.Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folder, ok := s.folders[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	for current := newParentID; current != nil; {
+		if *current == id {
+			return nil, fmt.Errorf("cannot move folder: would create circular reference")
+		}
+		parent, ok := s.folders[*current]
+		if !ok {
+			break
+		}
+		current = parent.ParentID
+	}
+	folder.ParentID = newParentID
+	c := *folder
+	return &c, nil
+}
+
+// GetFolderSnippetCount counts snippets directly in a folder
+func (s *FolderStore) GetFolderSnippetCount(ctx context.Context, folderID int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, id := range s.snippetFolders {
+		if id == folderID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetSnippetFolders returns the folder a snippet belongs to, if any
+func (s *FolderStore) GetSnippetFolders(ctx context.Context, snippetID string) ([]models.Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folders := make([]models.Folder, 0, 1)
+	if id, ok := s.snippetFolders[snippetID]; ok {
+		if folder, ok := s.folders[id]; ok {
+			folders = append(folders, *folder)
+		}
+	}
+	return folders, nil
+}
+
+// SetSnippetFolder moves a snippet into a folder, or out of all folders when nil
+func (s *FolderStore) SetSnippetFolder(ctx context.Context, snippetID string, folderID *int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if folderID == nil {
+		delete(s.snippetFolders, snippetID)
+		return nil
+	}
+	s.snippetFolders[snippetID] = *folderID
+	return nil
+}
+
+// GetAccess returns who a folder is restricted to
+func (s *FolderStore) GetAccess(ctx context.Context, id int64) (*models.FolderAccess, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.folders[id]; !ok {
+		return nil, repository.ErrNotFound
+	}
+	access := models.FolderAccess{UserIDs: []int64{}, Roles: []string{}}
+	if stored, ok := s.access[id]; ok {
+		access.UserIDs = append(access.UserIDs, stored.UserIDs...)
+		access.Roles = append(access.Roles, stored.Roles...)
+	}
+	return &access, nil
+}
+
+// SetAccess replaces who a folder is restricted to. Nothing is hidden.
+func (s *FolderStore) SetAccess(ctx context.Context, id int64, access *models.FolderAccess) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.folders[id]; !ok {
+		return repository.ErrNotFound
+	}
+	s.access[id] = *access
+	return nil
+}
+
+// SettingsStore is an in-memory repository.SettingsStore
+type SettingsStore struct {
+	mu       sync.Mutex
+	settings models.Settings
+}
+
+// NewSettingsStore creates a settings store holding the given settings
+func NewSettingsStore(settings models.Settings) *SettingsStore {
+	return &SettingsStore{settings: settings}
+}
+
+// Get returns the current settings
+func (s *SettingsStore) Get(ctx context.Context) (*models.Settings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.settings
+	return &c, nil
+}
+
+// FeatureEnabled reports a runtime feature flag; features not in the
+// settings are enabled
+func (s *SettingsStore) FeatureEnabled(ctx context.Context, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	enabled, ok := s.settings.Features[name]
+	return !ok || enabled, nil
+}
+
+// Update copies every field the input shares with the settings by JSON name.
+// Write-only secrets are not part of models.Settings and are dropped.
+func (s *SettingsStore) Update(ctx context.Context, input *models.SettingsInput) (*models.Settings, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := json.Unmarshal(data, &s.settings); err != nil {
+		return nil, err
+	}
+	s.settings.UpdatedAt = time.Now().UTC()
+	c := s.settings
+	return &c, nil
+}
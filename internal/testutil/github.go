@@ -0,0 +1,656 @@
+package testutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+)
+
+// FakeGitHubLogin is the user the fake GitHub API authenticates every token as
+const FakeGitHubLogin = "snipo-test"
+
+// FakeGitHub is an in-memory stand-in for the GitHub Gist API and the parts
+// of the repository API used by exports. Point a GitHubClient at URL() with
+// WithBaseURL to exercise sync code without the network. Gists can be edited
+// or removed behind the client's back to simulate changes made on GitHub.
+type FakeGitHub struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	gists     map[string]*models.GistResponse
+	repos     map[string]*fakeRepo
+	nextID    int
+	requests  int
+	remaining int // requests left before rate limiting, -1 for unlimited
+}
+
+// fakeRepo is a repository with a single branch, main. Trees are flat maps
+// from file path to content.
+type fakeRepo struct {
+	head    string // "" until the first commit
+	commits map[string]fakeCommit
+	trees   map[string]map[string]string
+}
+
+type fakeCommit struct {
+	Tree    string
+	Parents []string
+}
+
+// NewFakeGitHub starts a fake GitHub API server that is closed when the test completes
+func NewFakeGitHub(t *testing.T) *FakeGitHub {
+	t.Helper()
+
+	f := &FakeGitHub{
+		gists:     make(map[string]*models.GistResponse),
+		repos:     make(map[string]*fakeRepo),
+		remaining: -1,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /user", f.getUser)
+	mux.HandleFunc("GET /gists", f.listGists)
+	mux.HandleFunc("POST /gists", f.createGist)
+	mux.HandleFunc("GET /gists/{id}", f.getGist)
+	mux.HandleFunc("PATCH /gists/{id}", f.updateGist)
+	mux.HandleFunc("DELETE /gists/{id}", f.deleteGist)
+	mux.HandleFunc("POST /user/repos", f.createRepo)
+	mux.HandleFunc("GET /repos/{owner}/{repo}", f.getRepo)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/contents/{path...}", f.getContents)
+	mux.HandleFunc("PUT /repos/{owner}/{repo}/contents/{path...}", f.putContents)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/git/ref/heads/{branch}", f.getRef)
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}/git/refs/heads/{branch}", f.updateRef)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/git/commits/{sha}", f.getCommit)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/git/commits", f.createCommit)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/git/trees/{sha}", f.getTree)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/git/trees", f.createTree)
+
+	f.server = httptest.NewServer(f.rateLimit(mux))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// URL returns the API base URL of the fake server
+func (f *FakeGitHub) URL() string {
+	return f.server.URL
+}
+
+// Requests returns the number of API requests received, including rate-limited ones
+func (f *FakeGitHub) Requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+// SetRateLimit allows n more requests before the API answers 403 with
+// X-RateLimit-Remaining: 0, as GitHub does. A negative n removes the limit.
+func (f *FakeGitHub) SetRateLimit(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.remaining = n
+}
+
+// GistCount returns the number of gists stored
+func (f *FakeGitHub) GistCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.gists)
+}
+
+// Gist returns a copy of a stored gist, or nil if it does not exist
+func (f *FakeGitHub) Gist(id string) *models.GistResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	gist, ok := f.gists[id]
+	if !ok {
+		return nil
+	}
+	return copyGist(gist)
+}
+
+// EditFile changes a file's content as if it were edited on GitHub
+func (f *FakeGitHub) EditFile(id, filename, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	gist, ok := f.gists[id]
+	if !ok {
+		return
+	}
+	name := filename
+	gist.Files[filename] = models.GistFile{Filename: &name, Content: content}
+	gist.UpdatedAt = time.Now().UTC()
+}
+
+// RemoveGist deletes a gist as if it were deleted on GitHub
+func (f *FakeGitHub) RemoveGist(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.gists, id)
+}
+
+// AddRepo creates a repository owned by FakeGitHubLogin whose main branch
+// holds files in one commit, or no commits at all if files is empty
+func (f *FakeGitHub) AddRepo(name string, files map[string]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	repo := &fakeRepo{commits: make(map[string]fakeCommit), trees: make(map[string]map[string]string)}
+	if len(files) > 0 {
+		repo.head = f.commitLocked(repo, files, nil)
+	}
+	f.repos[FakeGitHubLogin+"/"+name] = repo
+}
+
+// RepoFiles returns the files on a repository's main branch, or nil if the
+// repository does not exist
+func (f *FakeGitHub) RepoFiles(name string) map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	repo, ok := f.repos[FakeGitHubLogin+"/"+name]
+	if !ok {
+		return nil
+	}
+	files := make(map[string]string)
+	if repo.head != "" {
+		for path, content := range repo.trees[repo.commits[repo.head].Tree] {
+			files[path] = content
+		}
+	}
+	return files
+}
+
+// RepoCommits returns the number of commits on a repository's main branch
+func (f *FakeGitHub) RepoCommits(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	repo, ok := f.repos[FakeGitHubLogin+"/"+name]
+	if !ok {
+		return 0
+	}
+	n := 0
+	for sha := repo.head; sha != ""; {
+		n++
+		parents := repo.commits[sha].Parents
+		if len(parents) == 0 {
+			break
+		}
+		sha = parents[0]
+	}
+	return n
+}
+
+// rateLimit counts requests and rejects them once the configured budget is spent
+func (f *FakeGitHub) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.requests++
+		limited := f.remaining == 0
+		if f.remaining > 0 {
+			f.remaining--
+		}
+		remaining := f.remaining
+		f.mu.Unlock()
+
+		if limited {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+			writeGitHubJSON(w, http.StatusForbidden, map[string]string{
+				"message":           "API rate limit exceeded",
+				"documentation_url": "https://docs.github.com/rest/overview/resources-in-the-rest-api#rate-limiting",
+			})
+			return
+		}
+		if remaining >= 0 {
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (f *FakeGitHub) getUser(w http.ResponseWriter, r *http.Request) {
+	writeGitHubJSON(w, http.StatusOK, models.GistOwner{Login: FakeGitHubLogin, ID: 1})
+}
+
+// listGists pages like GitHub: per_page gists (30 by default, at most 100)
+// from page, oldest first, with a Link header to the next page
+func (f *FakeGitHub) listGists(w http.ResponseWriter, r *http.Request) {
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = 30
+	}
+	perPage = min(perPage, 100)
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	f.mu.Lock()
+	gists := make([]*models.GistResponse, 0, len(f.gists))
+	for _, gist := range f.gists {
+		gists = append(gists, copyGist(gist))
+	}
+	f.mu.Unlock()
+	sort.Slice(gists, func(i, j int) bool {
+		if len(gists[i].ID) != len(gists[j].ID) {
+			return len(gists[i].ID) < len(gists[j].ID)
+		}
+		return gists[i].ID < gists[j].ID
+	})
+
+	start := min((page-1)*perPage, len(gists))
+	end := min(start+perPage, len(gists))
+	if end < len(gists) {
+		w.Header().Set("Link", fmt.Sprintf(`<%s/gists?per_page=%d&page=%d>; rel="next"`, f.server.URL, perPage, page+1))
+	}
+	writeGitHubJSON(w, http.StatusOK, gists[start:end])
+}
+
+func (f *FakeGitHub) createGist(w http.ResponseWriter, r *http.Request) {
+	var req models.GistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Files) == 0 {
+		writeGitHubJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Validation Failed"})
+		return
+	}
+
+	f.mu.Lock()
+	f.nextID++
+	id := fmt.Sprintf("gist%d", f.nextID)
+	now := time.Now().UTC()
+	gist := &models.GistResponse{
+		ID:          id,
+		URL:         f.server.URL + "/gists/" + id,
+		HTMLURL:     "https://gist.github.com/" + FakeGitHubLogin + "/" + id,
+		Description: req.Description,
+		Public:      req.Public,
+		Files:       make(map[string]models.GistFile),
+		Owner:       &models.GistOwner{Login: FakeGitHubLogin, ID: 1},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	mergeGistFiles(gist, req.Files)
+	f.gists[id] = gist
+	resp := copyGist(gist)
+	f.mu.Unlock()
+
+	writeGitHubJSON(w, http.StatusCreated, resp)
+}
+
+func (f *FakeGitHub) getGist(w http.ResponseWriter, r *http.Request) {
+	gist := f.Gist(r.PathValue("id"))
+	if gist == nil {
+		writeGitHubJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+	writeGitHubJSON(w, http.StatusOK, gist)
+}
+
+// updateGist follows GitHub's PATCH semantics: files in the request are added
+// or replaced, and files not mentioned are left untouched
+func (f *FakeGitHub) updateGist(w http.ResponseWriter, r *http.Request) {
+	var req models.GistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeGitHubJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Validation Failed"})
+		return
+	}
+
+	f.mu.Lock()
+	gist, ok := f.gists[r.PathValue("id")]
+	if !ok {
+		f.mu.Unlock()
+		writeGitHubJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+	gist.Description = req.Description
+	mergeGistFiles(gist, req.Files)
+	gist.UpdatedAt = time.Now().UTC()
+	resp := copyGist(gist)
+	f.mu.Unlock()
+
+	writeGitHubJSON(w, http.StatusOK, resp)
+}
+
+func (f *FakeGitHub) deleteGist(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	_, ok := f.gists[r.PathValue("id")]
+	delete(f.gists, r.PathValue("id"))
+	f.mu.Unlock()
+
+	if !ok {
+		writeGitHubJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *FakeGitHub) createRepo(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name     string `json:"name"`
+		Private  bool   `json:"private"`
+		AutoInit bool   `json:"auto_init"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		writeGitHubJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Validation Failed"})
+		return
+	}
+
+	f.mu.Lock()
+	key := FakeGitHubLogin + "/" + req.Name
+	if _, ok := f.repos[key]; ok {
+		f.mu.Unlock()
+		writeGitHubJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "name already exists on this account"})
+		return
+	}
+	repo := &fakeRepo{commits: make(map[string]fakeCommit), trees: make(map[string]map[string]string)}
+	if req.AutoInit {
+		repo.head = f.commitLocked(repo, map[string]string{"README.md": "# " + req.Name + "\n"}, nil)
+	}
+	f.repos[key] = repo
+	f.mu.Unlock()
+
+	writeGitHubJSON(w, http.StatusCreated, fakeRepoResponse(FakeGitHubLogin, req.Name, req.Private))
+}
+
+func (f *FakeGitHub) getRepo(w http.ResponseWriter, r *http.Request) {
+	if _, ok := f.lookupRepo(w, r); !ok {
+		return
+	}
+	f.mu.Unlock()
+	writeGitHubJSON(w, http.StatusOK, fakeRepoResponse(r.PathValue("owner"), r.PathValue("repo"), true))
+}
+
+// getContents returns a file on the main branch, base64 encoded in lines as
+// GitHub does
+func (f *FakeGitHub) getContents(w http.ResponseWriter, r *http.Request) {
+	repo, ok := f.lookupRepo(w, r)
+	if !ok {
+		return
+	}
+	content, found := "", false
+	if repo.head != "" {
+		content, found = repo.trees[repo.commits[repo.head].Tree][r.PathValue("path")]
+	}
+	f.mu.Unlock()
+
+	if !found {
+		writeGitHubJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte(content))
+	var lines []string
+	for len(encoded) > 60 {
+		lines = append(lines, encoded[:60])
+		encoded = encoded[60:]
+	}
+	lines = append(lines, encoded)
+	writeGitHubJSON(w, http.StatusOK, map[string]string{
+		"path":     r.PathValue("path"),
+		"encoding": "base64",
+		"content":  strings.Join(lines, "\n") + "\n",
+	})
+}
+
+// putContents commits a single file on top of the main branch
+func (f *FakeGitHub) putContents(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeGitHubJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Validation Failed"})
+		return
+	}
+	content, err := base64.StdEncoding.DecodeString(req.Content)
+	if err != nil {
+		writeGitHubJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "content is not valid Base64"})
+		return
+	}
+
+	repo, ok := f.lookupRepo(w, r)
+	if !ok {
+		return
+	}
+	files := make(map[string]string)
+	var parents []string
+	if repo.head != "" {
+		for path, c := range repo.trees[repo.commits[repo.head].Tree] {
+			files[path] = c
+		}
+		parents = []string{repo.head}
+	}
+	files[r.PathValue("path")] = string(content)
+	repo.head = f.commitLocked(repo, files, parents)
+	sha := repo.head
+	f.mu.Unlock()
+
+	writeGitHubJSON(w, http.StatusCreated, map[string]interface{}{"commit": map[string]string{"sha": sha}})
+}
+
+// getRef answers 409 for a repository without commits, as GitHub does for
+// every Git data request on an empty repository
+func (f *FakeGitHub) getRef(w http.ResponseWriter, r *http.Request) {
+	repo, ok := f.lookupGitRepo(w, r)
+	if !ok {
+		return
+	}
+	head := repo.head
+	f.mu.Unlock()
+
+	if r.PathValue("branch") != "main" {
+		writeGitHubJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+	writeGitHubJSON(w, http.StatusOK, map[string]interface{}{"object": map[string]string{"sha": head}})
+}
+
+func (f *FakeGitHub) updateRef(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SHA string `json:"sha"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeGitHubJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Validation Failed"})
+		return
+	}
+
+	repo, ok := f.lookupGitRepo(w, r)
+	if !ok {
+		return
+	}
+	commit, exists := repo.commits[req.SHA]
+	if !exists || r.PathValue("branch") != "main" || !slices.Contains(commit.Parents, repo.head) {
+		f.mu.Unlock()
+		writeGitHubJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Update is not a fast forward"})
+		return
+	}
+	repo.head = req.SHA
+	f.mu.Unlock()
+
+	writeGitHubJSON(w, http.StatusOK, map[string]interface{}{"object": map[string]string{"sha": req.SHA}})
+}
+
+func (f *FakeGitHub) getCommit(w http.ResponseWriter, r *http.Request) {
+	repo, ok := f.lookupGitRepo(w, r)
+	if !ok {
+		return
+	}
+	commit, exists := repo.commits[r.PathValue("sha")]
+	f.mu.Unlock()
+
+	if !exists {
+		writeGitHubJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+	writeGitHubJSON(w, http.StatusOK, map[string]interface{}{
+		"sha":  r.PathValue("sha"),
+		"tree": map[string]string{"sha": commit.Tree},
+	})
+}
+
+func (f *FakeGitHub) createCommit(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Tree    string   `json:"tree"`
+		Parents []string `json:"parents"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeGitHubJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Validation Failed"})
+		return
+	}
+
+	repo, ok := f.lookupGitRepo(w, r)
+	if !ok {
+		return
+	}
+	if _, exists := repo.trees[req.Tree]; !exists {
+		f.mu.Unlock()
+		writeGitHubJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Tree SHA does not exist"})
+		return
+	}
+	f.nextID++
+	sha := fmt.Sprintf("commit%d", f.nextID)
+	repo.commits[sha] = fakeCommit{Tree: req.Tree, Parents: req.Parents}
+	f.mu.Unlock()
+
+	writeGitHubJSON(w, http.StatusCreated, map[string]string{"sha": sha})
+}
+
+// getTree lists every file of a tree, as with recursive=1
+func (f *FakeGitHub) getTree(w http.ResponseWriter, r *http.Request) {
+	repo, ok := f.lookupGitRepo(w, r)
+	if !ok {
+		return
+	}
+	tree, exists := repo.trees[r.PathValue("sha")]
+	entries := make([]map[string]string, 0, len(tree))
+	for path := range tree {
+		entries = append(entries, map[string]string{"path": path, "type": "blob"})
+	}
+	f.mu.Unlock()
+
+	if !exists {
+		writeGitHubJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+	writeGitHubJSON(w, http.StatusOK, map[string]interface{}{"sha": r.PathValue("sha"), "tree": entries})
+}
+
+// createTree applies entries to base_tree, or to an empty tree without one.
+// An entry with a null sha deletes its path, which must exist.
+func (f *FakeGitHub) createTree(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		BaseTree string                   `json:"base_tree"`
+		Tree     []map[string]interface{} `json:"tree"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeGitHubJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Validation Failed"})
+		return
+	}
+
+	repo, ok := f.lookupGitRepo(w, r)
+	if !ok {
+		return
+	}
+	files := make(map[string]string)
+	for path, content := range repo.trees[req.BaseTree] {
+		files[path] = content
+	}
+	for _, entry := range req.Tree {
+		path, _ := entry["path"].(string)
+		sha, hasSHA := entry["sha"]
+		if content, ok := entry["content"].(string); ok {
+			files[path] = content
+		} else if hasSHA && sha == nil {
+			if _, exists := files[path]; !exists {
+				f.mu.Unlock()
+				writeGitHubJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "GitRPC::BadObjectState"})
+				return
+			}
+			delete(files, path)
+		}
+	}
+	f.nextID++
+	sha := fmt.Sprintf("tree%d", f.nextID)
+	repo.trees[sha] = files
+	f.mu.Unlock()
+
+	writeGitHubJSON(w, http.StatusCreated, map[string]string{"sha": sha})
+}
+
+// lookupRepo returns the repository named in the request with f.mu held, or
+// answers 404 and returns false
+func (f *FakeGitHub) lookupRepo(w http.ResponseWriter, r *http.Request) (*fakeRepo, bool) {
+	f.mu.Lock()
+	repo, ok := f.repos[r.PathValue("owner")+"/"+r.PathValue("repo")]
+	if !ok {
+		f.mu.Unlock()
+		writeGitHubJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return nil, false
+	}
+	return repo, true
+}
+
+// lookupGitRepo is lookupRepo for the Git data API, which answers 409 for
+// repositories without commits
+func (f *FakeGitHub) lookupGitRepo(w http.ResponseWriter, r *http.Request) (*fakeRepo, bool) {
+	repo, ok := f.lookupRepo(w, r)
+	if ok && repo.head == "" {
+		f.mu.Unlock()
+		writeGitHubJSON(w, http.StatusConflict, map[string]string{"message": "Git Repository is empty."})
+		return nil, false
+	}
+	return repo, ok
+}
+
+// commitLocked stores files as a new commit of repo and returns its SHA.
+// f.mu must be held.
+func (f *FakeGitHub) commitLocked(repo *fakeRepo, files map[string]string, parents []string) string {
+	f.nextID++
+	tree := fmt.Sprintf("tree%d", f.nextID)
+	repo.trees[tree] = files
+	sha := fmt.Sprintf("commit%d", f.nextID)
+	repo.commits[sha] = fakeCommit{Tree: tree, Parents: parents}
+	return sha
+}
+
+func fakeRepoResponse(owner, name string, private bool) models.GitHubRepo {
+	return models.GitHubRepo{
+		Name:          name,
+		FullName:      owner + "/" + name,
+		HTMLURL:       "https://github.com/" + owner + "/" + name,
+		Private:       private,
+		DefaultBranch: "main",
+	}
+}
+
+func mergeGistFiles(gist *models.GistResponse, files map[string]models.GistFile) {
+	for key, file := range files {
+		name := key
+		if file.Filename != nil && *file.Filename != "" {
+			name = *file.Filename
+			delete(gist.Files, key)
+		}
+		gist.Files[name] = models.GistFile{Filename: &name, Content: file.Content}
+	}
+}
+
+func copyGist(gist *models.GistResponse) *models.GistResponse {
+	c := *gist
+	c.Files = make(map[string]models.GistFile, len(gist.Files))
+	for name, file := range gist.Files {
+		c.Files[name] = file
+	}
+	return &c
+}
+
+func writeGitHubJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
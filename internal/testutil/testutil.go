@@ -49,12 +49,22 @@ func runTestMigrations(db *sql.DB) error {
 			is_public INTEGER DEFAULT 0,
 			is_archived INTEGER DEFAULT 0,
 			view_count INTEGER DEFAULT 0,
+			use_count INTEGER DEFAULT 0,
+			public_view_count INTEGER DEFAULT 0,
+			last_used_at DATETIME DEFAULT NULL,
 			s3_key TEXT DEFAULT NULL,
 			checksum TEXT DEFAULT NULL,
+			content_hash TEXT DEFAULT NULL,
 			expires_at DATETIME DEFAULT NULL,
+			publish_at DATETIME DEFAULT NULL,
+			provenance TEXT DEFAULT NULL,
+			external_id TEXT DEFAULT NULL,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			deleted_at DATETIME DEFAULT NULL
+			deleted_at DATETIME DEFAULT NULL,
+			user_id INTEGER NOT NULL DEFAULT 0,
+			workspace_id INTEGER NOT NULL DEFAULT 0,
+			public_token TEXT DEFAULT NULL
 		);
 
 		-- Settings table
@@ -88,17 +98,29 @@ func runTestMigrations(db *sql.DB) error {
 			editor_enable_live_autocompletion INTEGER DEFAULT 0,
 			markdown_font_size INTEGER DEFAULT 14,
 			exclude_first_line_on_copy INTEGER DEFAULT 0,
+			report_unpublish_threshold INTEGER DEFAULT 0,
+			markdown_html_policy TEXT DEFAULT 'sanitize',
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		);
 		INSERT OR IGNORE INTO settings (id, archive_enabled, trash_enabled, history_enabled) VALUES (1, 0, 1, 1);
 
+		-- Settings values (key-value store)
+		CREATE TABLE IF NOT EXISTS app_settings (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
 		-- Tags table
 		CREATE TABLE IF NOT EXISTS tags (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT UNIQUE NOT NULL,
+			user_id INTEGER NOT NULL DEFAULT 0,
+			workspace_id INTEGER NOT NULL DEFAULT 0,
+			name TEXT NOT NULL,
 			color TEXT DEFAULT '#6366f1',
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			deleted_at DATETIME DEFAULT NULL
 		);
 
 		-- Snippet-Tag junction table
@@ -118,6 +140,9 @@ func runTestMigrations(db *sql.DB) error {
 			icon TEXT DEFAULT 'folder',
 			sort_order INTEGER DEFAULT 0,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			user_id INTEGER NOT NULL DEFAULT 0,
+			workspace_id INTEGER NOT NULL DEFAULT 0,
+			deleted_at DATETIME DEFAULT NULL,
 			FOREIGN KEY (parent_id) REFERENCES folders(id) ON DELETE CASCADE
 		);
 
@@ -130,15 +155,76 @@ func runTestMigrations(db *sql.DB) error {
 			FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE CASCADE
 		);
 
+		-- Folder access lists
+		CREATE TABLE IF NOT EXISTS folder_access (
+			folder_id INTEGER NOT NULL REFERENCES folders(id) ON DELETE CASCADE,
+			user_id INTEGER NOT NULL DEFAULT 0,
+			role TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (folder_id, user_id, role)
+		);
+
+		-- Snippet activity log
+		CREATE TABLE IF NOT EXISTS activity (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			snippet_id TEXT NOT NULL,
+			title TEXT NOT NULL DEFAULT '',
+			action TEXT NOT NULL,
+			user_id INTEGER NOT NULL DEFAULT 0,
+			workspace_id INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- Background job runs
+		CREATE TABLE IF NOT EXISTS job_runs (
+			name TEXT PRIMARY KEY,
+			last_run_at DATETIME NOT NULL
+		);
+
+		-- Import reports
+		CREATE TABLE IF NOT EXISTS import_reports (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			source TEXT NOT NULL,
+			created INTEGER NOT NULL DEFAULT 0,
+			updated INTEGER NOT NULL DEFAULT 0,
+			skipped INTEGER NOT NULL DEFAULT 0,
+			failed INTEGER NOT NULL DEFAULT 0,
+			report TEXT NOT NULL DEFAULT '{}',
+			user_id INTEGER NOT NULL DEFAULT 0,
+			workspace_id INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS usage_counts (
+			day TEXT NOT NULL,
+			route TEXT NOT NULL,
+			count INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (day, route)
+		);
+
 		-- API tokens
 		CREATE TABLE IF NOT EXISTS api_tokens (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			name TEXT NOT NULL,
 			token_hash TEXT UNIQUE NOT NULL,
 			permissions TEXT DEFAULT 'read',
+			scopes TEXT DEFAULT '',
 			last_used_at DATETIME DEFAULT NULL,
 			expires_at DATETIME DEFAULT NULL,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			user_id INTEGER NOT NULL DEFAULT 0,
+			workspace_id INTEGER NOT NULL DEFAULT 0,
+			request_count INTEGER NOT NULL DEFAULT 0,
+			last_used_ip TEXT DEFAULT NULL
+		);
+
+		-- API token usage per day and client address
+		CREATE TABLE IF NOT EXISTS api_token_usage (
+			token_id INTEGER NOT NULL REFERENCES api_tokens(id) ON DELETE CASCADE,
+			day TEXT NOT NULL,
+			ip TEXT NOT NULL DEFAULT '',
+			requests INTEGER NOT NULL DEFAULT 0,
+			last_seen_at DATETIME NOT NULL,
+			PRIMARY KEY (token_id, day, ip)
 		);
 
 		-- Sessions table
@@ -146,6 +232,17 @@ func runTestMigrations(db *sql.DB) error {
 			id TEXT PRIMARY KEY,
 			token_hash TEXT UNIQUE NOT NULL,
 			expires_at DATETIME NOT NULL,
+			remember INTEGER DEFAULT 0 NOT NULL,
+			max_expires_at DATETIME DEFAULT NULL,
+			country TEXT DEFAULT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			user_id INTEGER NOT NULL DEFAULT 0
+		);
+
+		-- Failed logins for the admin overview
+		CREATE TABLE IF NOT EXISTS login_failures (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			country TEXT DEFAULT NULL,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		);
 
@@ -156,6 +253,7 @@ func runTestMigrations(db *sql.DB) error {
 			filename TEXT NOT NULL,
 			content TEXT DEFAULT '',
 			language TEXT DEFAULT 'plaintext',
+			note TEXT NOT NULL DEFAULT '',
 			sort_order INTEGER DEFAULT 0,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
@@ -185,12 +283,188 @@ func runTestMigrations(db *sql.DB) error {
 			filename TEXT NOT NULL,
 			content TEXT NOT NULL,
 			language TEXT NOT NULL,
+			note TEXT NOT NULL DEFAULT '',
 			sort_order INTEGER DEFAULT 0,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (history_id) REFERENCES snippet_history(id) ON DELETE CASCADE,
 			FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
 		);
 
+		-- Other snipo instances whose public snippets are mirrored locally
+		CREATE TABLE IF NOT EXISTS remote_sources (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			url TEXT NOT NULL UNIQUE,
+			folder_id INTEGER DEFAULT NULL,
+			enabled INTEGER DEFAULT 1,
+			etag TEXT DEFAULT '',
+			last_synced_at DATETIME DEFAULT NULL,
+			last_error TEXT DEFAULT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE SET NULL
+		);
+
+		-- Mapping between remote snippets and their local read-only mirrors
+		CREATE TABLE IF NOT EXISTS remote_snippets (
+			source_id INTEGER NOT NULL,
+			remote_id TEXT NOT NULL,
+			snippet_id TEXT NOT NULL,
+			remote_updated_at DATETIME,
+			PRIMARY KEY (source_id, remote_id),
+			FOREIGN KEY (source_id) REFERENCES remote_sources(id) ON DELETE CASCADE,
+			FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_remote_snippets_snippet ON remote_snippets(snippet_id);
+
+		-- Gist sync configuration (single row)
+		CREATE TABLE IF NOT EXISTS gist_sync_config (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			enabled INTEGER DEFAULT 0,
+			github_token_encrypted TEXT,
+			github_username TEXT,
+			auto_sync_enabled INTEGER DEFAULT 1,
+			sync_interval_minutes INTEGER DEFAULT 15,
+			conflict_strategy TEXT DEFAULT 'manual',
+			last_full_sync_at DATETIME,
+			rate_limit TEXT DEFAULT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- Snippet to Gist mappings
+		CREATE TABLE IF NOT EXISTS snippet_gist_mappings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			snippet_id TEXT NOT NULL UNIQUE,
+			gist_id TEXT NOT NULL UNIQUE,
+			gist_url TEXT NOT NULL,
+			sync_enabled INTEGER DEFAULT 1,
+			last_synced_at DATETIME,
+			snipo_checksum TEXT,
+			gist_checksum TEXT,
+			sync_status TEXT DEFAULT 'synced',
+			error_message TEXT,
+			base_version TEXT DEFAULT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+		);
+
+		-- Conflicts requiring manual resolution
+		CREATE TABLE IF NOT EXISTS gist_sync_conflicts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			snippet_id TEXT NOT NULL,
+			gist_id TEXT NOT NULL,
+			snipo_version TEXT,
+			gist_version TEXT,
+			resolved INTEGER DEFAULT 0,
+			resolution_choice TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			resolved_at DATETIME,
+			FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+		);
+
+		-- Sync operation logs
+		CREATE TABLE IF NOT EXISTS gist_sync_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			snippet_id TEXT,
+			gist_id TEXT,
+			operation TEXT NOT NULL,
+			status TEXT NOT NULL,
+			message TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- Referring domains of share page visits
+		CREATE TABLE IF NOT EXISTS snippet_referrers (
+			snippet_id TEXT NOT NULL,
+			domain TEXT NOT NULL,
+			count INTEGER NOT NULL DEFAULT 0,
+			last_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (snippet_id, domain),
+			FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+		);
+
+		-- Abuse reports against public snippets
+		CREATE TABLE IF NOT EXISTS snippet_reports (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			snippet_id TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			details TEXT DEFAULT '',
+			status TEXT NOT NULL DEFAULT 'open',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			resolved_at DATETIME DEFAULT NULL,
+			FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+		);
+
+		-- Endpoints that turn POSTed payloads into snippets
+		CREATE TABLE IF NOT EXISTS inbound_webhooks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			slug TEXT NOT NULL UNIQUE,
+			secret_hash TEXT NOT NULL,
+			folder_id INTEGER DEFAULT NULL,
+			tags TEXT NOT NULL DEFAULT '',
+			language TEXT NOT NULL DEFAULT 'plaintext',
+			enabled INTEGER NOT NULL DEFAULT 1,
+			received_count INTEGER NOT NULL DEFAULT 0,
+			last_received_at DATETIME DEFAULT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE SET NULL
+		);
+
+		-- Chat bot bridge configuration
+		CREATE TABLE IF NOT EXISTS bot_config (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			enabled INTEGER NOT NULL DEFAULT 0,
+			platform TEXT NOT NULL DEFAULT 'telegram',
+			token_encrypted TEXT NOT NULL DEFAULT '',
+			homeserver_url TEXT NOT NULL DEFAULT '',
+			allowed_chats TEXT NOT NULL DEFAULT '',
+			tags TEXT NOT NULL DEFAULT '',
+			cursor TEXT NOT NULL DEFAULT '',
+			last_error TEXT DEFAULT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- User accounts
+		CREATE TABLE IF NOT EXISTS users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT NOT NULL UNIQUE COLLATE NOCASE,
+			password_hash TEXT NOT NULL,
+			is_admin INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- Workspaces
+		CREATE TABLE IF NOT EXISTS workspaces (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			slug TEXT NOT NULL UNIQUE COLLATE NOCASE,
+			name TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS workspace_members (
+			workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+			user_id INTEGER NOT NULL,
+			role TEXT NOT NULL DEFAULT 'editor',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (workspace_id, user_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS workspace_settings (
+			workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+			key TEXT NOT NULL,
+			value TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (workspace_id, key)
+		);
+
 		-- Indexes
 		CREATE INDEX IF NOT EXISTS idx_snippets_language ON snippets(language);
 		CREATE INDEX IF NOT EXISTS idx_snippets_favorite ON snippets(is_favorite);
@@ -198,11 +472,30 @@ func runTestMigrations(db *sql.DB) error {
 		CREATE INDEX IF NOT EXISTS idx_snippets_archived ON snippets(is_archived);
 		CREATE INDEX IF NOT EXISTS idx_snippets_deleted ON snippets(deleted_at);
 		CREATE INDEX IF NOT EXISTS idx_snippets_expires_at ON snippets(expires_at);
+		CREATE INDEX IF NOT EXISTS idx_snippets_publish_at ON snippets(publish_at);
 		CREATE INDEX IF NOT EXISTS idx_snippets_created ON snippets(created_at DESC);
 		CREATE INDEX IF NOT EXISTS idx_snippets_updated ON snippets(updated_at DESC);
+		CREATE INDEX IF NOT EXISTS idx_snippets_content_hash ON snippets(content_hash);
 		CREATE INDEX IF NOT EXISTS idx_tags_name ON tags(name);
 		CREATE INDEX IF NOT EXISTS idx_folders_parent ON folders(parent_id);
+		CREATE INDEX IF NOT EXISTS idx_snippets_user ON snippets(user_id);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_snippets_external_id ON snippets(user_id, external_id) WHERE external_id IS NOT NULL AND workspace_id = 0;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_snippets_workspace_external_id ON snippets(workspace_id, external_id) WHERE external_id IS NOT NULL AND workspace_id != 0;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags(user_id, name) WHERE workspace_id = 0;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_workspace_name ON tags(workspace_id, name) WHERE workspace_id != 0;
+		CREATE INDEX IF NOT EXISTS idx_folders_user ON folders(user_id);
+		CREATE INDEX IF NOT EXISTS idx_snippets_workspace ON snippets(workspace_id);
+		CREATE INDEX IF NOT EXISTS idx_folders_workspace ON folders(workspace_id);
+		CREATE INDEX IF NOT EXISTS idx_tags_deleted_at ON tags(deleted_at);
+		CREATE INDEX IF NOT EXISTS idx_folders_deleted_at ON folders(deleted_at);
+		CREATE INDEX IF NOT EXISTS idx_workspace_members_user ON workspace_members(user_id);
+		CREATE INDEX IF NOT EXISTS idx_api_token_usage_day ON api_token_usage(day);
+		CREATE INDEX IF NOT EXISTS idx_activity_workspace ON activity(workspace_id, user_id);
+		CREATE INDEX IF NOT EXISTS idx_activity_snippet ON activity(snippet_id);
+		CREATE INDEX IF NOT EXISTS idx_activity_created ON activity(created_at);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_snippets_public_token ON snippets(public_token);
 		CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at);
+		CREATE INDEX IF NOT EXISTS idx_login_failures_created ON login_failures(created_at);
 		CREATE INDEX IF NOT EXISTS idx_snippet_files_snippet ON snippet_files(snippet_id);
 		CREATE INDEX IF NOT EXISTS idx_snippet_history_snippet_id ON snippet_history(snippet_id);
 		CREATE INDEX IF NOT EXISTS idx_snippet_history_created ON snippet_history(created_at DESC);
@@ -211,7 +504,7 @@ func runTestMigrations(db *sql.DB) error {
 
 		-- Full-text search
 		CREATE VIRTUAL TABLE IF NOT EXISTS snippets_fts USING fts5(
-			snippet_id,
+			id UNINDEXED,
 			title,
 			description,
 			content,
@@ -219,21 +512,32 @@ func runTestMigrations(db *sql.DB) error {
 			content_rowid='rowid'
 		);
 
+		-- Public token triggers
+		CREATE TRIGGER IF NOT EXISTS snippets_public_token_ai AFTER INSERT ON snippets
+		WHEN NEW.is_public = 1 AND NEW.public_token IS NULL BEGIN
+			UPDATE snippets SET public_token = lower(hex(randomblob(12))) WHERE rowid = NEW.rowid;
+		END;
+
+		CREATE TRIGGER IF NOT EXISTS snippets_public_token_au AFTER UPDATE OF is_public ON snippets
+		WHEN NEW.is_public = 1 AND OLD.is_public = 0 AND NEW.public_token IS OLD.public_token BEGIN
+			UPDATE snippets SET public_token = lower(hex(randomblob(12))) WHERE rowid = NEW.rowid;
+		END;
+
 		-- FTS triggers
 		CREATE TRIGGER IF NOT EXISTS snippets_ai AFTER INSERT ON snippets BEGIN
-			INSERT INTO snippets_fts(rowid, snippet_id, title, description, content)
+			INSERT INTO snippets_fts(rowid, id, title, description, content)
 			VALUES (NEW.rowid, NEW.id, NEW.title, NEW.description, NEW.content);
 		END;
 
 		CREATE TRIGGER IF NOT EXISTS snippets_ad AFTER DELETE ON snippets BEGIN
-			INSERT INTO snippets_fts(snippets_fts, rowid, snippet_id, title, description, content)
+			INSERT INTO snippets_fts(snippets_fts, rowid, id, title, description, content)
 			VALUES('delete', OLD.rowid, OLD.id, OLD.title, OLD.description, OLD.content);
 		END;
 
 		CREATE TRIGGER IF NOT EXISTS snippets_au AFTER UPDATE ON snippets BEGIN
-			INSERT INTO snippets_fts(snippets_fts, rowid, snippet_id, title, description, content)
+			INSERT INTO snippets_fts(snippets_fts, rowid, id, title, description, content)
 			VALUES('delete', OLD.rowid, OLD.id, OLD.title, OLD.description, OLD.content);
-			INSERT INTO snippets_fts(rowid, snippet_id, title, description, content)
+			INSERT INTO snippets_fts(rowid, id, title, description, content)
 			VALUES (NEW.rowid, NEW.id, NEW.title, NEW.description, NEW.content);
 		END;
 	`
@@ -1,7 +1,11 @@
 package validation
 
 import (
+	"fmt"
+	"net/mail"
+	"net/url"
 	"regexp"
+	"slices"
 	"strings"
 	"unicode/utf8"
 
@@ -80,6 +84,10 @@ func ValidateSnippetInput(input *models.SnippetInput) ValidationErrors {
 		if len(file.Content) > 1024*1024 { // 1MB limit per file
 			errs = append(errs, ValidationError{Field: "files", Message: "File content must be less than 1MB each"})
 		}
+		input.Files[i].Note = strings.TrimSpace(file.Note)
+		if utf8.RuneCountInString(input.Files[i].Note) > 1000 {
+			errs = append(errs, ValidationError{Field: "files", Message: "File notes must be less than 1000 characters"})
+		}
 		// Validate file language
 		lang := strings.ToLower(strings.TrimSpace(file.Language))
 		if lang == "" {
@@ -127,6 +135,11 @@ func ValidateSnippetInput(input *models.SnippetInput) ValidationErrors {
 	return errs
 }
 
+// IsAllowedLanguage reports whether lang is a supported language identifier
+func IsAllowedLanguage(lang string) bool {
+	return allowedLanguages[lang]
+}
+
 // GetAllowedLanguages returns a list of allowed language identifiers
 func GetAllowedLanguages() []string {
 	languages := make([]string, 0, len(allowedLanguages))
@@ -138,7 +151,7 @@ func GetAllowedLanguages() []string {
 
 // Allowed editor themes
 var allowedEditorThemes = map[string]bool{
-	"chaos": true, "clouds": true, "clouds_midnight": true, "cobalt": true,
+	"auto": true, "chaos": true, "clouds": true, "clouds_midnight": true, "cobalt": true,
 	"crimson_editor": true, "dawn": true, "dracula": true, "dreamweaver": true,
 	"eclipse": true, "github": true, "gob": true, "gruvbox": true, "idle_fingers": true,
 	"iplastic": true, "katzenmilch": true, "kr_theme": true, "kuroir": true,
@@ -200,6 +213,55 @@ func ValidateSettingsInput(input *models.SettingsInput) ValidationErrors {
 		errs = append(errs, ValidationError{Field: "default_language", Message: "Invalid default language"})
 	}
 
+	// Report threshold validation (0 disables auto-unpublish)
+	if input.ReportUnpublishThreshold < 0 || input.ReportUnpublishThreshold > 1000 {
+		errs = append(errs, ValidationError{Field: "report_unpublish_threshold", Message: "Report threshold must be between 0 and 1000"})
+	}
+
+	// Markdown HTML policy validation (empty keeps the safe default)
+	input.MarkdownHTMLPolicy = strings.ToLower(strings.TrimSpace(input.MarkdownHTMLPolicy))
+	switch input.MarkdownHTMLPolicy {
+	case "":
+		input.MarkdownHTMLPolicy = models.MarkdownHTMLSanitize
+	case models.MarkdownHTMLSanitize, models.MarkdownHTMLEscape, models.MarkdownHTMLAllow:
+	default:
+		errs = append(errs, ValidationError{Field: "markdown_html_policy", Message: "Markdown HTML policy must be 'sanitize', 'escape' or 'allow'"})
+	}
+
+	// Search tokenizer validation (empty keeps the default)
+	input.SearchTokenizer = strings.ToLower(strings.TrimSpace(input.SearchTokenizer))
+	switch input.SearchTokenizer {
+	case "":
+		input.SearchTokenizer = models.SearchTokenizerDefault
+	case models.SearchTokenizerDefault, models.SearchTokenizerCode:
+	default:
+		errs = append(errs, ValidationError{Field: "search_tokenizer", Message: "Search tokenizer must be 'unicode61' or 'code'"})
+	}
+
+	// Tag palette validation (empty restores the default palette)
+	if palette, ok := normalizeTagPalette(input.TagPalette); ok {
+		input.TagPalette = palette
+	} else {
+		errs = append(errs, ValidationError{Field: "tag_palette", Message: "Tag palette must be up to 32 comma-separated #rrggbb colors"})
+	}
+
+	// Digest recipients validation
+	if recipients, ok := normalizeRecipients(input.DigestRecipients); ok {
+		input.DigestRecipients = recipients
+	} else {
+		errs = append(errs, ValidationError{Field: "digest_recipients", Message: fmt.Sprintf("Digest recipients must be up to %d comma-separated e-mail addresses", maxDigestRecipients)})
+	}
+	if input.DigestEnabled && input.DigestRecipients == "" {
+		errs = append(errs, ValidationError{Field: "digest_recipients", Message: "The digest needs at least one recipient"})
+	}
+
+	// Feature flags validation (only known features can be toggled)
+	for name := range input.Features {
+		if !slices.Contains(models.RuntimeFeatures, name) {
+			errs = append(errs, ValidationError{Field: "features", Message: "Unknown feature: " + name})
+		}
+	}
+
 	// S3 configuration validation
 	if input.S3Enabled {
 		input.S3Endpoint = strings.TrimSpace(input.S3Endpoint)
@@ -220,6 +282,189 @@ func ValidateSettingsInput(input *models.SettingsInput) ValidationErrors {
 	return errs
 }
 
+var hexColorRegex = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// maxTagPaletteColors caps the tag_palette setting
+const maxTagPaletteColors = 32
+
+// normalizeTagPalette lowercases and trims a comma-separated palette,
+// returning the default palette for an empty one and false if any color is
+// invalid
+func normalizeTagPalette(value string) (string, bool) {
+	if strings.TrimSpace(value) == "" {
+		return strings.Join(models.DefaultTagPalette, ","), true
+	}
+	colors := strings.Split(value, ",")
+	if len(colors) > maxTagPaletteColors {
+		return "", false
+	}
+	for i, color := range colors {
+		colors[i] = strings.ToLower(strings.TrimSpace(color))
+		if !hexColorRegex.MatchString(colors[i]) {
+			return "", false
+		}
+	}
+	return strings.Join(colors, ","), true
+}
+
+// maxDigestRecipients caps the addresses the digest is sent to
+const maxDigestRecipients = 20
+
+// normalizeRecipients trims a comma-separated list of e-mail addresses down
+// to the bare addresses, returning false if any is invalid
+func normalizeRecipients(value string) (string, bool) {
+	if strings.TrimSpace(value) == "" {
+		return "", true
+	}
+	addresses := strings.Split(value, ",")
+	if len(addresses) > maxDigestRecipients {
+		return "", false
+	}
+	for i, address := range addresses {
+		parsed, err := mail.ParseAddress(strings.TrimSpace(address))
+		if err != nil {
+			return "", false
+		}
+		addresses[i] = parsed.Address
+	}
+	return strings.Join(addresses, ","), true
+}
+
+// allowedReportReasons are the reasons a public snippet can be reported for
+var allowedReportReasons = map[string]bool{
+	"spam":       true,
+	"malware":    true,
+	"illegal":    true,
+	"harassment": true,
+	"other":      true,
+}
+
+// ValidateReportInput validates an abuse report
+func ValidateReportInput(input *models.ReportInput) ValidationErrors {
+	var errs ValidationErrors
+
+	input.Reason = strings.ToLower(strings.TrimSpace(input.Reason))
+	if !allowedReportReasons[input.Reason] {
+		errs = append(errs, ValidationError{Field: "reason", Message: "Reason must be one of spam, malware, illegal, harassment or other"})
+	}
+
+	input.Details = strings.TrimSpace(input.Details)
+	if utf8.RuneCountInString(input.Details) > 1000 {
+		errs = append(errs, ValidationError{Field: "details", Message: "Details must be less than 1000 characters"})
+	}
+
+	return errs
+}
+
+// Limits on a single bulk request
+const (
+	maxBulkIDs      = 500
+	maxBulkSnippets = 100
+)
+
+// ValidateBulkSnippetInput validates a bulk snippet request, dropping
+// duplicate IDs and tags
+func ValidateBulkSnippetInput(input *models.BulkSnippetInput) ValidationErrors {
+	var errs ValidationErrors
+
+	input.Action = strings.ToLower(strings.TrimSpace(input.Action))
+	switch input.Action {
+	case models.BulkCreate:
+		if len(input.Snippets) == 0 {
+			errs = append(errs, ValidationError{Field: "snippets", Message: "At least one snippet is required"})
+		} else if len(input.Snippets) > maxBulkSnippets {
+			errs = append(errs, ValidationError{Field: "snippets", Message: fmt.Sprintf("At most %d snippets can be created at once", maxBulkSnippets)})
+		}
+		for i := range input.Snippets {
+			for _, e := range ValidateSnippetInput(&input.Snippets[i]) {
+				e.Field = fmt.Sprintf("snippets[%d].%s", i, e.Field)
+				errs = append(errs, e)
+			}
+		}
+		return errs
+
+	case models.BulkUpdate:
+		if input.Set == nil || (input.Set.IsPublic == nil && input.Set.IsFavorite == nil) {
+			errs = append(errs, ValidationError{Field: "set", Message: "At least one field to update is required"})
+		}
+
+	case models.BulkTag:
+		input.AddTags = uniqueTrimmed(input.AddTags)
+		input.RemoveTags = uniqueTrimmed(input.RemoveTags)
+		if len(input.AddTags) == 0 && len(input.RemoveTags) == 0 {
+			errs = append(errs, ValidationError{Field: "add_tags", Message: "Tags to add or remove are required"})
+		}
+		for _, tag := range input.AddTags {
+			if tagErrs := ValidateTagInput(tag); tagErrs.HasErrors() {
+				errs = append(errs, ValidationError{Field: "add_tags", Message: tagErrs[0].Message})
+				break
+			}
+		}
+
+	case models.BulkDelete, models.BulkRestore, models.BulkArchive, models.BulkUnarchive, models.BulkMove:
+
+	default:
+		errs = append(errs, ValidationError{Field: "action", Message: "Action must be one of create, update, delete, restore, archive, unarchive, tag or move"})
+		return errs
+	}
+
+	input.IDs = uniqueTrimmed(input.IDs)
+	if len(input.IDs) == 0 {
+		errs = append(errs, ValidationError{Field: "ids", Message: "At least one snippet ID is required"})
+	} else if len(input.IDs) > maxBulkIDs {
+		errs = append(errs, ValidationError{Field: "ids", Message: fmt.Sprintf("At most %d snippets can be changed at once", maxBulkIDs)})
+	}
+
+	return errs
+}
+
+// maxImportURLs caps the URLs fetched by one import request
+const maxImportURLs = 20
+
+// ValidateURLImportInput validates a URL import request, dropping repeated
+// URLs and tags
+func ValidateURLImportInput(input *models.URLImportInput) ValidationErrors {
+	var errs ValidationErrors
+
+	input.URLs = uniqueTrimmed(input.URLs)
+	if len(input.URLs) == 0 {
+		errs = append(errs, ValidationError{Field: "urls", Message: "At least one URL is required"})
+	} else if len(input.URLs) > maxImportURLs {
+		errs = append(errs, ValidationError{Field: "urls", Message: fmt.Sprintf("At most %d URLs can be imported at once", maxImportURLs)})
+	}
+	for i, raw := range input.URLs {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, ValidationError{Field: fmt.Sprintf("urls[%d]", i), Message: "Must be an http or https URL"})
+		}
+	}
+
+	input.Tags = uniqueTrimmed(input.Tags)
+	for _, tag := range input.Tags {
+		if tagErrs := ValidateTagInput(tag); tagErrs.HasErrors() {
+			errs = append(errs, ValidationError{Field: "tags", Message: tagErrs[0].Message})
+			break
+		}
+	}
+
+	return errs
+}
+
+// uniqueTrimmed trims values and drops empty and repeated ones, keeping order
+func uniqueTrimmed(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := values[:0]
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}
+
 // ValidateTagInput validates tag input
 func ValidateTagInput(name string) ValidationErrors {
 	var errs ValidationErrors
@@ -264,6 +509,214 @@ func ValidateTokenInput(name string) ValidationErrors {
 	return errs
 }
 
+// usernameRegex validates usernames - letters, numbers, dots, hyphens and underscores
+var usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.\-]+$`)
+
+// ValidateUserInput validates user account input, trimming the username
+func ValidateUserInput(input *models.UserInput) ValidationErrors {
+	var errs ValidationErrors
+
+	input.Username = strings.TrimSpace(input.Username)
+	if input.Username == "" {
+		errs = append(errs, ValidationError{Field: "username", Message: "Username is required"})
+	} else if len(input.Username) > 50 {
+		errs = append(errs, ValidationError{Field: "username", Message: "Username must be less than 50 characters"})
+	} else if !usernameRegex.MatchString(input.Username) {
+		errs = append(errs, ValidationError{Field: "username", Message: "Username can only contain letters, numbers, dots, hyphens and underscores"})
+	}
+
+	if utf8.RuneCountInString(input.Password) < 8 {
+		errs = append(errs, ValidationError{Field: "password", Message: "Password must be at least 8 characters"})
+	}
+
+	return errs
+}
+
+// workspaceSlugRegex allows slugs that also work as a DNS label, so a
+// workspace can be selected by subdomain
+var workspaceSlugRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// ValidateWorkspaceInput validates workspace input. The slug is lowercased.
+func ValidateWorkspaceInput(input *models.WorkspaceInput) ValidationErrors {
+	var errs ValidationErrors
+
+	input.Slug = strings.ToLower(strings.TrimSpace(input.Slug))
+	if input.Slug == "" {
+		errs = append(errs, ValidationError{Field: "slug", Message: "Slug is required"})
+	} else if len(input.Slug) > 63 {
+		errs = append(errs, ValidationError{Field: "slug", Message: "Slug must be at most 63 characters"})
+	} else if !workspaceSlugRegex.MatchString(input.Slug) {
+		errs = append(errs, ValidationError{Field: "slug", Message: "Slug can only contain lowercase letters, numbers and inner hyphens"})
+	}
+
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		errs = append(errs, ValidationError{Field: "name", Message: "Name is required"})
+	} else if utf8.RuneCountInString(input.Name) > 100 {
+		errs = append(errs, ValidationError{Field: "name", Message: "Name must be less than 100 characters"})
+	}
+
+	return errs
+}
+
+// ValidateWorkspaceMemberInput validates a member's role, which defaults to
+// editor
+func ValidateWorkspaceMemberInput(input *models.WorkspaceMemberInput) ValidationErrors {
+	var errs ValidationErrors
+
+	input.Role = strings.ToLower(strings.TrimSpace(input.Role))
+	if input.Role == "" {
+		input.Role = models.WorkspaceRoleEditor
+	} else if !slices.Contains(models.WorkspaceRoles, input.Role) {
+		errs = append(errs, ValidationError{Field: "role", Message: "Role must be one of: " + strings.Join(models.WorkspaceRoles, ", ")})
+	}
+
+	return errs
+}
+
+// ValidateFolderAccess validates who a folder is restricted to. Duplicates
+// are dropped and roles lowercased.
+func ValidateFolderAccess(input *models.FolderAccess) ValidationErrors {
+	var errs ValidationErrors
+
+	if len(input.UserIDs)+len(input.Roles) > 100 {
+		errs = append(errs, ValidationError{Field: "user_ids", Message: "A folder can be restricted to at most 100 users and roles"})
+	}
+
+	for _, id := range input.UserIDs {
+		if id <= 0 {
+			errs = append(errs, ValidationError{Field: "user_ids", Message: "User IDs must be positive"})
+			break
+		}
+	}
+	slices.Sort(input.UserIDs)
+	input.UserIDs = slices.Compact(input.UserIDs)
+
+	for i, role := range input.Roles {
+		input.Roles[i] = strings.ToLower(strings.TrimSpace(role))
+		if !slices.Contains(models.WorkspaceRoles, input.Roles[i]) {
+			errs = append(errs, ValidationError{Field: "roles", Message: "Roles must be any of: " + strings.Join(models.WorkspaceRoles, ", ")})
+			break
+		}
+	}
+	slices.Sort(input.Roles)
+	input.Roles = slices.Compact(input.Roles)
+
+	return errs
+}
+
+// ValidateMarkdownRenderInput validates markdown sent for rendering
+func ValidateMarkdownRenderInput(input *models.MarkdownRenderInput) ValidationErrors {
+	var errs ValidationErrors
+	if len(input.Content) > 1024*1024 { // Same limit as snippet content
+		errs = append(errs, ValidationError{Field: "content", Message: "Content must be less than 1MB"})
+	}
+	return errs
+}
+
+// ValidateExternalID validates the caller-chosen key of a snippet upsert
+func ValidateExternalID(externalID string) ValidationErrors {
+	var errs ValidationErrors
+
+	if strings.TrimSpace(externalID) == "" {
+		errs = append(errs, ValidationError{Field: "external_id", Message: "External ID is required"})
+	} else if utf8.RuneCountInString(externalID) > 200 {
+		errs = append(errs, ValidationError{Field: "external_id", Message: "External ID must be less than 200 characters"})
+	}
+
+	return errs
+}
+
+// ValidateInboundWebhookInput validates inbound webhook input, normalizing
+// the name, preset tags and default language
+func ValidateInboundWebhookInput(input *models.InboundWebhookInput) ValidationErrors {
+	var errs ValidationErrors
+
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		errs = append(errs, ValidationError{Field: "name", Message: "Webhook name is required"})
+	} else if utf8.RuneCountInString(input.Name) > 100 {
+		errs = append(errs, ValidationError{Field: "name", Message: "Webhook name must be less than 100 characters"})
+	}
+
+	input.Language = strings.ToLower(strings.TrimSpace(input.Language))
+	if input.Language == "" {
+		input.Language = "plaintext"
+	} else if !allowedLanguages[input.Language] {
+		errs = append(errs, ValidationError{Field: "language", Message: "Invalid language"})
+	}
+
+	tags := make([]string, 0, len(input.Tags))
+	for _, tag := range input.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if len(tag) > 50 {
+			errs = append(errs, ValidationError{Field: "tags", Message: "Tag name must be less than 50 characters"})
+		} else if !tagRegex.MatchString(tag) {
+			errs = append(errs, ValidationError{Field: "tags", Message: "Tag can only contain letters, numbers, spaces, underscores, hyphens, dots, and hash symbols"})
+		}
+		tags = append(tags, tag)
+	}
+	input.Tags = tags
+
+	return errs
+}
+
+// ValidateBotConfigInput validates chat bot configuration, normalizing the
+// platform, allowed chats and tags. Whether a token is needed depends on the
+// stored config, so the service checks that.
+func ValidateBotConfigInput(input *models.BotConfigInput) ValidationErrors {
+	var errs ValidationErrors
+
+	input.Platform = strings.ToLower(strings.TrimSpace(input.Platform))
+	switch input.Platform {
+	case models.BotPlatformTelegram:
+		input.HomeserverURL = ""
+	case models.BotPlatformMatrix:
+		input.HomeserverURL = strings.TrimRight(strings.TrimSpace(input.HomeserverURL), "/")
+		u, err := url.Parse(input.HomeserverURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, ValidationError{Field: "homeserver_url", Message: "Homeserver URL must be an http(s) URL"})
+		}
+	default:
+		errs = append(errs, ValidationError{Field: "platform", Message: "Platform must be telegram or matrix"})
+	}
+
+	input.Token = strings.TrimSpace(input.Token)
+
+	chats := make([]string, 0, len(input.AllowedChats))
+	for _, chat := range input.AllowedChats {
+		chat = strings.TrimSpace(chat)
+		if chat == "" {
+			continue
+		}
+		if strings.Contains(chat, ",") {
+			errs = append(errs, ValidationError{Field: "allowed_chats", Message: "Chat IDs can't contain commas"})
+		}
+		chats = append(chats, chat)
+	}
+	input.AllowedChats = chats
+
+	tags := make([]string, 0, len(input.Tags))
+	for _, tag := range input.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if len(tag) > 50 {
+			errs = append(errs, ValidationError{Field: "tags", Message: "Tag name must be less than 50 characters"})
+		} else if !tagRegex.MatchString(tag) {
+			errs = append(errs, ValidationError{Field: "tags", Message: "Tag can only contain letters, numbers, spaces, underscores, hyphens, dots, and hash symbols"})
+		}
+		tags = append(tags, tag)
+	}
+	input.Tags = tags
+
+	return errs
+}
+
 // SanitizeFilename removes or replaces problematic characters in filenames
 func SanitizeFilename(filename string) string {
 	filename = strings.TrimSpace(filename)
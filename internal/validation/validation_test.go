@@ -326,6 +326,25 @@ func TestValidateSnippetInput_FileInvalidLanguage(t *testing.T) {
 	}
 }
 
+func TestValidateSnippetInput_FileNote(t *testing.T) {
+	input := &models.SnippetInput{
+		Title:    "Valid Title",
+		Language: "plaintext",
+		Files: []models.SnippetFileInput{
+			{Filename: "a.txt", Content: "content", Note: "  entry point  "},
+			{Filename: "b.txt", Content: "content", Note: strings.Repeat("x", 1001)},
+		},
+	}
+
+	errs := ValidateSnippetInput(input)
+	if input.Files[0].Note != "entry point" {
+		t.Errorf("expected note to be trimmed, got %q", input.Files[0].Note)
+	}
+	if len(errs) != 1 || errs[0].Field != "files" {
+		t.Errorf("expected one error for the long note, got %v", errs)
+	}
+}
+
 func TestValidateSnippetInput_TrimWhitespace(t *testing.T) {
 	input := &models.SnippetInput{
 		Title:       "  Trimmed Title  ",
@@ -421,6 +440,61 @@ func TestValidateSettingsInput_Valid(t *testing.T) {
 	}
 }
 
+func TestValidateSettingsInput_TagPalette(t *testing.T) {
+	input := &models.SettingsInput{TagPalette: " #AABBCC , #112233"}
+	if errs := ValidateSettingsInput(input); errs.HasErrors() {
+		t.Fatalf("expected no errors, got: %v", errs)
+	}
+	if input.TagPalette != "#aabbcc,#112233" {
+		t.Errorf("expected normalized palette, got %q", input.TagPalette)
+	}
+
+	input = &models.SettingsInput{}
+	ValidateSettingsInput(input)
+	if input.TagPalette != strings.Join(models.DefaultTagPalette, ",") {
+		t.Errorf("expected default palette, got %q", input.TagPalette)
+	}
+
+	for _, palette := range []string{"red", "#abc", "#aabbcc,,#112233"} {
+		errs := ValidateSettingsInput(&models.SettingsInput{TagPalette: palette})
+		if len(errs) != 1 || errs[0].Field != "tag_palette" {
+			t.Errorf("expected tag_palette error for %q, got %v", palette, errs)
+		}
+	}
+}
+
+func TestValidateBulkSnippetInput(t *testing.T) {
+	input := &models.BulkSnippetInput{Action: " TAG ", IDs: []string{"a", " a", "b", ""}, AddTags: []string{"go", "go "}}
+	if errs := ValidateBulkSnippetInput(input); errs.HasErrors() {
+		t.Fatalf("expected no errors, got: %v", errs)
+	}
+	if input.Action != models.BulkTag || strings.Join(input.IDs, ",") != "a,b" || strings.Join(input.AddTags, ",") != "go" {
+		t.Errorf("expected normalized input, got %+v", input)
+	}
+
+	tests := []struct {
+		name  string
+		input models.BulkSnippetInput
+		field string
+	}{
+		{"unknown action", models.BulkSnippetInput{Action: "rename", IDs: []string{"a"}}, "action"},
+		{"no ids", models.BulkSnippetInput{Action: models.BulkDelete}, "ids"},
+		{"empty update", models.BulkSnippetInput{Action: models.BulkUpdate, IDs: []string{"a"}, Set: &models.BulkSnippetPatch{}}, "set"},
+		{"no tags", models.BulkSnippetInput{Action: models.BulkTag, IDs: []string{"a"}}, "add_tags"},
+		{"bad tag", models.BulkSnippetInput{Action: models.BulkTag, IDs: []string{"a"}, AddTags: []string{"a/b"}}, "add_tags"},
+		{"no snippets", models.BulkSnippetInput{Action: models.BulkCreate}, "snippets"},
+		{"invalid snippet", models.BulkSnippetInput{Action: models.BulkCreate, Snippets: []models.SnippetInput{{Content: "x"}}}, "snippets[0].title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateBulkSnippetInput(&tt.input)
+			if len(errs) != 1 || errs[0].Field != tt.field {
+				t.Errorf("expected one %s error, got %v", tt.field, errs)
+			}
+		})
+	}
+}
+
 func TestValidateSettingsInput_InvalidTheme(t *testing.T) {
 	input := &models.SettingsInput{
 		Theme: "invalid-theme",
@@ -465,6 +539,38 @@ func TestValidateSettingsInput_InvalidEditorTheme(t *testing.T) {
 	}
 }
 
+func TestValidateSettingsInput_AutoEditorTheme(t *testing.T) {
+	// "auto" is the column default, so saving untouched settings must accept it
+	input := &models.SettingsInput{
+		EditorTheme: "auto",
+	}
+
+	if errs := ValidateSettingsInput(input); errs.HasErrors() {
+		t.Errorf("expected 'auto' editor theme to be valid, got %v", errs)
+	}
+}
+
+func TestValidateSettingsInput_MarkdownHTMLPolicy(t *testing.T) {
+	// Clients that predate the setting send nothing and get the safe default
+	input := &models.SettingsInput{}
+	if errs := ValidateSettingsInput(input); errs.HasErrors() {
+		t.Fatalf("expected empty policy to be valid, got %v", errs)
+	}
+	if input.MarkdownHTMLPolicy != models.MarkdownHTMLSanitize {
+		t.Errorf("expected default policy %q, got %q", models.MarkdownHTMLSanitize, input.MarkdownHTMLPolicy)
+	}
+
+	input = &models.SettingsInput{MarkdownHTMLPolicy: " Escape "}
+	if errs := ValidateSettingsInput(input); errs.HasErrors() || input.MarkdownHTMLPolicy != models.MarkdownHTMLEscape {
+		t.Errorf("expected policy to be normalized to escape, got %q (%v)", input.MarkdownHTMLPolicy, errs)
+	}
+
+	input = &models.SettingsInput{MarkdownHTMLPolicy: "unsafe"}
+	if errs := ValidateSettingsInput(input); !errs.HasErrors() {
+		t.Error("expected unknown policy to be rejected")
+	}
+}
+
 func TestValidateSettingsInput_FontSizeBoundaries(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -678,6 +784,34 @@ func TestValidateTokenInput(t *testing.T) {
 	}
 }
 
+// TestValidateReportInput tests abuse report validation
+func TestValidateReportInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   models.ReportInput
+		wantErr bool
+	}{
+		{"valid reason", models.ReportInput{Reason: "spam"}, false},
+		{"reason is normalized", models.ReportInput{Reason: " Malware "}, false},
+		{"with details", models.ReportInput{Reason: "other", Details: "phishing link"}, false},
+		{"missing reason", models.ReportInput{}, true},
+		{"unknown reason", models.ReportInput{Reason: "dislike"}, true},
+		{"details too long", models.ReportInput{Reason: "other", Details: strings.Repeat("a", 1001)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateReportInput(&tt.input)
+			if tt.wantErr && !errs.HasErrors() {
+				t.Errorf("expected error for %+v", tt.input)
+			}
+			if !tt.wantErr && errs.HasErrors() {
+				t.Errorf("unexpected error for %+v: %v", tt.input, errs)
+			}
+		})
+	}
+}
+
 // TestValidateFilename tests filename validation
 func TestValidateFilename(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,126 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/services"
+)
+
+// PublicSnippetReader loads public snippets by the key in their share links
+type PublicSnippetReader interface {
+	GetByIDPublic(ctx context.Context, key string) (*models.Snippet, error)
+}
+
+// embedCSP lets any site frame the embed page, which runs only the bundled
+// Prism scripts
+var embedCSP = strings.Join([]string{
+	"default-src 'none'",
+	"script-src 'self'",
+	"style-src 'self' 'unsafe-inline'",
+	"img-src 'self' data:",
+	"font-src 'self'",
+	"frame-ancestors *",
+	"base-uri 'none'",
+	"form-action 'none'",
+}, "; ")
+
+// prismLanguages maps snippet languages to the bundled Prism grammars,
+// like the editor's copy-as-HTML
+var prismLanguages = map[string]string{
+	"javascript": "javascript", "js": "javascript",
+	"python": "python", "py": "python",
+	"go": "go", "golang": "go",
+	"bash": "bash", "sh": "bash", "shell": "bash", "zsh": "bash",
+	"powershell": "powershell", "ps": "powershell", "ps1": "powershell",
+	"sql":  "sql",
+	"json": "json",
+	"yaml": "yaml", "yml": "yaml",
+	"markdown": "markdown", "md": "markdown",
+	"cuda": "cuda", "cu": "cuda",
+}
+
+// EmbedData is the data for the embed page
+type EmbedData struct {
+	BasePath string
+	Title    string
+	ShareURL string // Share page, opened from the embed's footer
+	Dark     bool
+	Files    []EmbedFile
+}
+
+// EmbedFile is one file shown on the embed page
+type EmbedFile struct {
+	Filename string
+	Language string        // Prism grammar, "plain" without one
+	Content  string        // Code, when HTML is empty
+	HTML     template.HTML // Rendered markdown
+}
+
+// WithPublicSnippets enables the embed page
+func (h *Handler) WithPublicSnippets(reader PublicSnippetReader) *Handler {
+	h.publicSnippets = reader
+	return h
+}
+
+// Embed serves a public snippet as a bare, highlighted page for iframes on
+// other sites. ?file= shows one file of a multi-file snippet and
+// ?theme=dark switches to a dark theme.
+func (h *Handler) Embed(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	snippet, err := h.publicSnippets.GetByIDPublic(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrSnippetNotFound) {
+			h.NotFound(w, r)
+			return
+		}
+		h.ErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+	if h.publicViews != nil {
+		// Visits through an embed count as share page visits, with the
+		// embedding site as the referrer
+		_ = h.publicViews.RecordPublicView(r.Context(), id, referrerDomain(r))
+	}
+
+	data := EmbedData{
+		BasePath: h.basePath,
+		Title:    snippet.Title,
+		ShareURL: h.basePath + "/s/" + id,
+		Dark:     r.URL.Query().Get("theme") == "dark",
+	}
+	if len(snippet.Files) == 0 {
+		data.Files = []EmbedFile{embedFile(snippet.Title, snippet.Language, snippet.Content, snippet.RenderedHTML)}
+	}
+	only := r.URL.Query().Get("file")
+	for _, file := range snippet.Files {
+		if only == "" || file.Filename == only {
+			data.Files = append(data.Files, embedFile(file.Filename, file.Language, file.Content, file.RenderedHTML))
+		}
+	}
+	if len(data.Files) == 0 {
+		h.NotFound(w, r)
+		return
+	}
+
+	w.Header().Del("X-Frame-Options")
+	w.Header().Set("Content-Security-Policy", embedCSP)
+	h.renderStatus(w, http.StatusOK, "embed.html", "embed.html", data)
+}
+
+// embedFile prepares a file for the embed page. rendered is markdown the
+// service already rendered under the HTML policy.
+func embedFile(filename, language, content, rendered string) EmbedFile {
+	file := EmbedFile{Filename: filename, Content: content, HTML: template.HTML(rendered)} // Sanitized by the markdown policy
+	file.Language = prismLanguages[strings.ToLower(language)]
+	if file.Language == "" {
+		file.Language = "plain"
+	}
+	return file
+}
@@ -0,0 +1,78 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/MohamedElashri/snipo/internal/models"
+	"github.com/MohamedElashri/snipo/internal/services"
+)
+
+// snippetReader serves one public snippet under its share key
+type snippetReader struct {
+	key     string
+	snippet *models.Snippet
+}
+
+func (s snippetReader) GetByIDPublic(_ context.Context, key string) (*models.Snippet, error) {
+	if key != s.key {
+		return nil, services.ErrSnippetNotFound
+	}
+	return s.snippet, nil
+}
+
+func TestEmbed(t *testing.T) {
+	reader := snippetReader{key: "tok", snippet: &models.Snippet{
+		Title: "Setup",
+		Files: []models.SnippetFile{
+			{Filename: "run.sh", Language: "shell", Content: "echo <hi>"},
+			{Filename: "README.md", Language: "markdown", Content: "# Run", RenderedHTML: "<h1>Run</h1>"},
+		},
+	}}
+	h := (&Handler{templateFS: templatesFS, basePath: "/snipo"}).WithPublicSnippets(reader)
+	router := chi.NewRouter()
+	router.Get("/embed/{id}", h.Embed)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		// Set by the security middleware for every page
+		rec.Header().Set("X-Frame-Options", "DENY")
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/embed/tok")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, body)
+	}
+	if rec.Header().Get("X-Frame-Options") != "" || !strings.Contains(rec.Header().Get("Content-Security-Policy"), "frame-ancestors *") {
+		t.Errorf("expected the page to be framable, got %v", rec.Header())
+	}
+	for _, want := range []string{
+		`<code class="language-bash">echo &lt;hi&gt;</code>`,
+		"<h1>Run</h1>",
+		"run.sh",
+		`href="/snipo/s/tok"`,
+		"/snipo/static/vendor/js/prism.min.js",
+		"/snipo/static/vendor/css/prism.min.css",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected the page to contain %q:\n%s", want, body)
+		}
+	}
+
+	body = get("/embed/tok?file=run.sh&theme=dark").Body.String()
+	if strings.Contains(body, "<h1>Run</h1>") || strings.Contains(body, `class="embed-filename"`) {
+		t.Errorf("expected only run.sh without a file name header:\n%s", body)
+	}
+	if !strings.Contains(body, "prism-tomorrow.min.css") {
+		t.Error("expected the dark theme")
+	}
+}
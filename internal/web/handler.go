@@ -1,15 +1,23 @@
 package web
 
 import (
+	"bytes"
 	"context"
 	"embed"
+	"fmt"
 	"html/template"
 	"io/fs"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"path/filepath"
+	"strings"
 
+	"github.com/MohamedElashri/snipo/internal/api/middleware"
 	"github.com/MohamedElashri/snipo/internal/auth"
 	"github.com/MohamedElashri/snipo/internal/repository"
+	"github.com/go-chi/chi/v5"
 )
 
 //go:embed templates/*.html templates/components/*.html
@@ -20,12 +28,22 @@ var staticFS embed.FS
 
 // Handler handles web page requests
 type Handler struct {
-	templates    *template.Template
-	authService  *auth.Service
-	settingsRepo *repository.SettingsRepository
-	demoMode     bool
-	basePath     string
-	version      string
+	templates      *template.Template
+	templateFS     fs.FS // Embedded templates, overlaid by a theme if set
+	themed         bool
+	logger         *slog.Logger
+	authService    *auth.Service
+	settingsRepo   *repository.SettingsRepository
+	demoMode       bool
+	basePath       string
+	version        string
+	publicViews    PublicViewRecorder
+	publicSnippets PublicSnippetReader // Snippets shown on embed pages
+}
+
+// PublicViewRecorder counts visits to public snippet share pages
+type PublicViewRecorder interface {
+	RecordPublicView(ctx context.Context, id, referrerDomain string) error
 }
 
 // NewHandler creates a new web handler
@@ -38,6 +56,7 @@ func NewHandler(authService *auth.Service, settingsRepo *repository.SettingsRepo
 
 	return &Handler{
 		templates:    tmpl,
+		templateFS:   templatesFS,
 		authService:  authService,
 		settingsRepo: settingsRepo,
 		demoMode:     false,
@@ -58,11 +77,10 @@ func (h *Handler) WithBasePath(basePath string) *Handler {
 	return h
 }
 
-// StaticHandler returns a handler for static files
-func StaticHandler(basePath string) http.Handler {
-	staticContent, _ := fs.Sub(staticFS, "static")
-	prefix := basePath + "/static/"
-	return http.StripPrefix(prefix, http.FileServer(http.FS(staticContent)))
+// WithPublicViews enables counting share page visits
+func (h *Handler) WithPublicViews(recorder PublicViewRecorder) *Handler {
+	h.publicViews = recorder
+	return h
 }
 
 // PageData holds data passed to templates
@@ -72,6 +90,12 @@ type PageData struct {
 	BasePath     string
 	Version      string
 	AuthDisabled bool
+	RememberMe   bool   // Login page offers "Remember me"
+	MultiUser    bool   // Login page asks for a username
+	ErrorMessage string // Shown on error pages
+	RequestID    string // Quoted on error pages for bug reports
+	FeedURL      string // Atom feed advertised to feed readers
+	OEmbedURL    string // oEmbed description of a share page, for sites embedding it
 }
 
 // Index serves the main application page
@@ -99,6 +123,7 @@ func (h *Handler) Index(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, h.basePath+"/login", http.StatusSeeOther)
 		return
 	}
+	h.authService.ResignSessionCookie(w, r)
 
 	data := PageData{Title: "Snippets", DemoMode: h.demoMode, BasePath: h.basePath, Version: h.version, AuthDisabled: h.authService.IsAuthDisabled()}
 	h.render(w, "layout.html", "index.html", data)
@@ -128,31 +153,125 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data := PageData{Title: "Login", DemoMode: h.demoMode, BasePath: h.basePath, Version: h.version, AuthDisabled: h.authService.IsAuthDisabled()}
+	data := PageData{Title: "Login", DemoMode: h.demoMode, BasePath: h.basePath, Version: h.version, AuthDisabled: h.authService.IsAuthDisabled(), RememberMe: h.authService.RememberMeEnabled(), MultiUser: h.authService.MultiUserEnabled()}
 	h.render(w, "layout.html", "login.html", data)
 }
 
 // PublicSnippet serves the public snippet view page (no auth required)
 func (h *Handler) PublicSnippet(w http.ResponseWriter, r *http.Request) {
-	data := PageData{Title: "Shared Snippet", DemoMode: h.demoMode, BasePath: h.basePath, Version: h.version, AuthDisabled: h.authService.IsAuthDisabled()}
+	// Signed-in visitors are usually the owner checking the link, so only
+	// anonymous visits count as public views
+	if h.publicViews != nil {
+		token := auth.GetSessionFromRequest(r)
+		if token == "" || !h.authService.ValidateSession(token) {
+			// Best effort: a failed count must not break the page
+			_ = h.publicViews.RecordPublicView(r.Context(), chi.URLParam(r, "id"), referrerDomain(r))
+		}
+	}
+
+	origin := requestOrigin(r)
+	data := PageData{Title: "Shared Snippet", DemoMode: h.demoMode, BasePath: h.basePath, Version: h.version, AuthDisabled: h.authService.IsAuthDisabled(), FeedURL: h.basePath + "/feed.xml",
+		OEmbedURL: origin + h.basePath + "/oembed?url=" + url.QueryEscape(origin+r.URL.Path)}
 	h.render(w, "layout.html", "public.html", data)
 }
 
+// ErrorPage renders an HTML error page showing the request ID
+func (h *Handler) ErrorPage(w http.ResponseWriter, r *http.Request, status int, message string) {
+	data := PageData{
+		Title:        http.StatusText(status),
+		DemoMode:     h.demoMode,
+		BasePath:     h.basePath,
+		Version:      h.version,
+		AuthDisabled: h.authService.IsAuthDisabled(),
+		ErrorMessage: message,
+		RequestID:    middleware.GetRequestID(r.Context()),
+	}
+	h.renderStatus(w, status, "layout.html", "error.html", data)
+}
+
+// NotFound serves the page for unknown web routes
+func (h *Handler) NotFound(w http.ResponseWriter, r *http.Request) {
+	h.ErrorPage(w, r, http.StatusNotFound, "The page you're looking for doesn't exist.")
+}
+
+// MethodNotAllowed serves the page for web routes requested with the wrong method
+func (h *Handler) MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	h.ErrorPage(w, r, http.StatusMethodNotAllowed, "This page can't be requested that way.")
+}
+
+// requestOrigin returns the scheme and host the page was requested with,
+// taking the scheme from X-Forwarded-Proto behind a reverse proxy like the
+// API's links do
+func requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	} else if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// referrerDomain returns the host of the page that linked to this request,
+// or "" when there is none or it is this instance itself
+func referrerDomain(r *http.Request) string {
+	ref, err := url.Parse(r.Referer())
+	if err != nil || ref.Hostname() == "" {
+		return ""
+	}
+
+	domain := strings.ToLower(strings.TrimPrefix(ref.Hostname(), "www."))
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if domain == strings.ToLower(strings.TrimPrefix(host, "www.")) {
+		return ""
+	}
+	return domain
+}
+
 // render renders a template with layout
 func (h *Handler) render(w http.ResponseWriter, layout, content string, data interface{}) {
-	// Create a new template that combines layout, content, and components
-	tmpl, err := template.ParseFS(templatesFS,
+	h.renderStatus(w, http.StatusOK, layout, content, data)
+}
+
+// renderStatus renders a template with layout and the given status code
+func (h *Handler) renderStatus(w http.ResponseWriter, status int, layout, content string, data interface{}) {
+	buf, err := h.execute(h.templateFS, layout, content, data)
+	if err != nil && h.themed {
+		h.logger.Debug("theme template failed, using built-in page", "page", content, "error", err)
+		buf, err = h.execute(templatesFS, layout, content, data)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
+}
+
+// parse combines layout, content and components from fsys into one template
+func (h *Handler) parse(fsys fs.FS, layout, content string) (*template.Template, error) {
+	return template.ParseFS(fsys,
 		filepath.Join("templates", layout),
 		filepath.Join("templates", content),
 		"templates/components/*.html",
 	)
+}
+
+// execute renders a page from fsys into a buffer so a failure leaves the
+// response untouched
+func (h *Handler) execute(fsys fs.FS, layout, content string, data interface{}) (*bytes.Buffer, error) {
+	tmpl, err := h.parse(fsys, layout, content)
 	if err != nil {
-		http.Error(w, "Template parse error: "+err.Error(), http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("Template parse error: %w", err)
 	}
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := tmpl.ExecuteTemplate(w, layout, data); err != nil {
-		http.Error(w, "Template execute error: "+err.Error(), http.StatusInternalServerError)
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, layout, data); err != nil {
+		return nil, fmt.Errorf("Template execute error: %w", err)
 	}
+	return &buf, nil
 }
@@ -1,35 +1,81 @@
 // Login form component
+
+// Find a nonce so that SHA-256("<challenge>:<nonce>") starts with at least
+// difficulty zero bits. The server asks for this after repeated failed logins.
+async function solveChallenge(challenge, difficulty) {
+  const encoder = new TextEncoder();
+  for (let nonce = 0; ; nonce++) {
+    const response = `${challenge}:${nonce}`;
+    const digest = new Uint8Array(await crypto.subtle.digest('SHA-256', encoder.encode(response)));
+    let bits = 0;
+    for (const byte of digest) {
+      if (byte === 0) {
+        bits += 8;
+        continue;
+      }
+      bits += Math.clz32(byte) - 24;
+      break;
+    }
+    if (bits >= difficulty) {
+      return response;
+    }
+  }
+}
+
 export function initLoginForm(Alpine) {
   Alpine.data('loginForm', () => ({
+    username: '',
     password: '',
+    rememberMe: false,
     error: '',
     loading: false,
+    verifying: false,
 
     async login() {
       this.loading = true;
       this.error = '';
 
       try {
-        const result = await window.api.post('/api/v1/auth/login', { password: this.password });
+        const basePath = window.SNIPO_CONFIG?.basePath || '';
+        const send = (headers = {}) => fetch(`${basePath}/api/v1/auth/login`, {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json', ...headers },
+          credentials: 'include',
+          body: JSON.stringify({ username: this.username, password: this.password, remember_me: this.rememberMe })
+        });
+
+        let response = await send();
+        let json = await response.json();
+
+        // Solve the proof-of-work challenge and retry once
+        if (json.error?.code === 'CHALLENGE_REQUIRED') {
+          const challenge = response.headers.get('X-Snipo-Challenge');
+          const difficulty = parseInt(response.headers.get('X-Snipo-Challenge-Difficulty'), 10);
+          this.verifying = true;
+          const solution = await solveChallenge(challenge, difficulty);
+          this.verifying = false;
+          response = await send({ 'X-Snipo-Challenge-Response': solution });
+          json = await response.json();
+        }
 
         // Handle error response format: { error: { code, message } }
-        if (result && result.error) {
-          this.error = result.error.message || 'Invalid password';
+        if (json.error) {
+          this.error = json.error.message || 'Invalid password';
           return;
         }
 
         // Handle success response
-        if (result && result.success) {
-          const basePath = window.SNIPO_CONFIG?.basePath || '';
+        if (json.data?.success) {
           window.location.href = basePath + '/';
         } else {
-          this.error = result?.message || 'Invalid password';
+          this.error = 'Invalid password';
         }
       } catch (err) {
         this.error = 'Connection error';
+      } finally {
+        this.verifying = false;
+        this.loading = false;
       }
-
-      this.loading = false;
     }
   }));
 }
@@ -24,6 +24,10 @@ export function initPublicSnippet(Alpine) {
     errorMessage: '',
     activeFileIndex: 0,
     isAuthenticated: false,
+    showSource: false,
+    showReport: false,
+    reportSubmitting: false,
+    report: { reason: 'spam', details: '' },
 
     async init() {
       const path = window.location.pathname;
@@ -149,6 +153,31 @@ export function initPublicSnippet(Alpine) {
       }
     },
 
+    async submitReport() {
+      this.reportSubmitting = true;
+      try {
+        const basePath = window.SNIPO_CONFIG?.basePath || '';
+        const response = await fetch(`${basePath}/s/${this.snippet.id}/report`, {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify(this.report)
+        });
+
+        if (response.ok) {
+          showToast('Thanks, the report was sent to the owner');
+          this.showReport = false;
+          this.report = { reason: 'spam', details: '' };
+        } else {
+          const json = await response.json();
+          showToast(json.error?.message || 'Failed to send report', 'error');
+        }
+      } catch (err) {
+        showToast('Failed to send report', 'error');
+      } finally {
+        this.reportSubmitting = false;
+      }
+    },
+
     getFiles() {
       if (!this.snippet) return [];
       // If snippet has files array with content, use that
@@ -159,7 +188,8 @@ export function initPublicSnippet(Alpine) {
       return [{
         filename: 'snippet.' + (this.snippet.language || 'txt'),
         content: this.snippet.content || '',
-        language: this.snippet.language || 'plaintext'
+        language: this.snippet.language || 'plaintext',
+        rendered_html: this.snippet.rendered_html || ''
       }];
     },
 
@@ -180,6 +210,16 @@ export function initPublicSnippet(Alpine) {
       return files[this.activeFileIndex]?.language || 'plaintext';
     },
 
+    // Markdown arrives already rendered and sanitized by the server
+    getCurrentRenderedHTML() {
+      const files = this.getFiles();
+      return files[this.activeFileIndex]?.rendered_html || '';
+    },
+
+    showRendered() {
+      return !this.showSource && this.getCurrentRenderedHTML() !== '';
+    },
+
     getCurrentFilename() {
       const files = this.getFiles();
       if (files.length === 0) return 'snippet.txt';
@@ -91,7 +91,7 @@ export function initSnippetsApp(Alpine) {
 
     aceEditor: null,
     aceIgnoreChange: false,
-    settings: { archive_enabled: false, history_enabled: true, trash_enabled: true },
+    settings: { archive_enabled: false, history_enabled: true, trash_enabled: true, features: {} },
 
     // Lifecycle
     async init() {
@@ -155,20 +155,34 @@ export function initSnippetsApp(Alpine) {
 
     // Core methods
     async loadSnippets() {
-      const params = new URLSearchParams();
+      const params = this.listParams();
       params.set('page', this.pagination.page);
       params.set('limit', this.pagination.limit);
 
+      const result = await api.get(`/api/v1/snippets?${params}`);
+      if (result) {
+        this.snippets = result.data || [];
+        this.pagination = result.pagination || this.pagination;
+        this.$nextTick(() => highlightAll());
+      }
+    },
+
+    // Filter and sort parameters of the current list, shared with the
+    // neighbors lookup used for j/k navigation
+    listParams() {
+      const params = new URLSearchParams();
+
       // Handle sorting
       if (this.sortBy) {
         if (this.sortBy === 'title_desc') {
-          params.set('sort', 'title');
-          params.set('order', 'desc');
+          params.set('sort_by', 'title');
+          params.set('sort_order', 'desc');
         } else if (this.sortBy === 'title') {
-          params.set('sort', 'title');
-          params.set('order', 'asc');
+          params.set('sort_by', 'title');
+          params.set('sort_order', 'asc');
         } else {
-          params.set('sort', this.sortBy);
+          params.set('sort_by', this.sortBy);
+          params.set('sort_order', 'desc');
         }
       }
 
@@ -179,13 +193,7 @@ export function initSnippetsApp(Alpine) {
       if (this.filter.isFavorite !== null) params.set('favorite', this.filter.isFavorite);
       if (this.filter.isArchived !== null) params.set('is_archived', this.filter.isArchived);
       if (this.filter.isDeleted !== null) params.set('is_deleted', this.filter.isDeleted);
-
-      const result = await api.get(`/api/v1/snippets?${params}`);
-      if (result) {
-        this.snippets = result.data || [];
-        this.pagination = result.pagination || this.pagination;
-        this.$nextTick(() => highlightAll());
-      }
+      return params;
     },
 
     async loadTags() {
@@ -451,6 +459,7 @@ export function initSnippetsApp(Alpine) {
 
         await navigator.clipboard.writeText(contentToCopy);
         showToast('Copied to clipboard');
+        this.markSnippetUsed(snippet);
       } catch (err) {
         showToast('Failed to copy', 'error');
       }
@@ -470,6 +479,7 @@ export function initSnippetsApp(Alpine) {
 
         await navigator.clipboard.writeText(contentToCopy);
         showToast(`Copied ${file.filename} to clipboard`);
+        this.markSnippetUsed(snippet);
       } catch (err) {
         showToast('Failed to copy', 'error');
       }
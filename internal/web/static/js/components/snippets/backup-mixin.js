@@ -55,7 +55,8 @@ export const backupMixin = {
     this.backupLoading = false;
   },
 
-  async importBackup() {
+  // With dryRun, only reports what the import would change
+  async importBackup(dryRun = false) {
     if (!this.backupFile) {
       showToast('Please select a backup file', 'error');
       return;
@@ -71,6 +72,9 @@ export const backupMixin = {
       if (this.importOptions.password) {
         formData.append('password', this.importOptions.password);
       }
+      if (dryRun) {
+        formData.append('dry_run', 'true');
+      }
 
       const basePath = window.SNIPO_CONFIG?.basePath || '';
       const response = await fetch(`${basePath}/api/v1/backup/import`, {
@@ -86,6 +90,10 @@ export const backupMixin = {
       }
 
       this.importResult = result;
+      if (result.dry_run) {
+        this.backupLoading = false;
+        return;
+      }
       this.backupFile = null;
 
       await Promise.all([
@@ -126,8 +134,20 @@ export const backupMixin = {
     }
   },
 
+  // Percentage of the running S3 upload, or null when unknown
+  s3UploadPercent() {
+    const upload = this.s3Status?.upload;
+    if (!upload?.running || !upload.total_bytes) return null;
+    return Math.floor((upload.sent_bytes / upload.total_bytes) * 100);
+  },
+
   async syncToS3() {
     this.backupLoading = true;
+    // Large backups are uploaded in parts; poll the status for progress
+    const poll = setInterval(async () => {
+      const status = await api.get('/api/v1/backup/s3/status').catch(() => null);
+      if (status) this.s3Status = status;
+    }, 1000);
     try {
       const result = await api.post('/api/v1/backup/s3/sync', {
         format: this.backupOptions.format,
@@ -143,6 +163,7 @@ export const backupMixin = {
     } catch (err) {
       showToast(err.message || 'Failed to sync to S3', 'error');
     }
+    clearInterval(poll);
     this.backupLoading = false;
   },
 
@@ -173,6 +194,20 @@ export const backupMixin = {
     this.backupLoading = false;
   },
 
+  // Downloads straight from the bucket with a short-lived link
+  async downloadS3Backup(key) {
+    try {
+      const result = await api.get(`/api/v1/backup/s3/presign?key=${encodeURIComponent(key)}`);
+      if (result?.url) {
+        window.location.href = result.url;
+      } else {
+        throw new Error(result?.error?.message || 'Download failed');
+      }
+    } catch (err) {
+      showToast(err.message || 'Failed to download backup', 'error');
+    }
+  },
+
   async deleteS3Backup(key) {
     if (!confirm('Delete this backup from S3?')) return;
 
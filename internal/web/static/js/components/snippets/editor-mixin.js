@@ -8,7 +8,7 @@ import { isArabicText, isPredominantlyArabic, sanitizeHTML } from '../../utils/h
 export const editorMixin = {
   // Editor operations (imported from original app.js)
   // This file contains editor-related methods and state
-  // Methods: viewSnippet, editSnippet, newSnippet, saveSnippet, startEditing, cancelEditing, etc.
+  // Methods: viewSnippet, viewNeighbor, editSnippet, newSnippet, saveSnippet, startEditing, cancelEditing, etc.
 
   async viewSnippet(snippet) {
     const result = await api.get(`/api/v1/snippets/${snippet.id}`);
@@ -16,6 +16,7 @@ export const editorMixin = {
       this.editingSnippet = {
         ...result,
         expires_at: this.resolveToShorthand(result.expires_at),
+        publish_at: this.toLocalDateTime(result.publish_at),
         tags: (result.tags || []).map(t => t.name),
         folder_id: result.folders?.[0]?.id || null,
         files: result.files || []
@@ -31,6 +32,14 @@ export const editorMixin = {
     }
   },
 
+  // Open the previous (-1) or next (1) snippet of the current list
+  async viewNeighbor(direction) {
+    if (!this.editingSnippet?.id) return;
+    const result = await api.get(`/api/v1/snippets/${this.editingSnippet.id}/neighbors?${this.listParams()}`);
+    const id = direction < 0 ? result?.prev_id : result?.next_id;
+    if (id) await this.viewSnippet({ id });
+  },
+
   newSnippet() {
     // Calculate default expiration if auto-archive is enabled and default days is set
     let defaultExpires = '';
@@ -51,6 +60,7 @@ export const editorMixin = {
       is_public: false,
       is_favorite: false,
       expires_at: defaultExpires,
+      publish_at: '',
       sync_to_gist: this.gistConfig?.auto_sync_enabled || false,
       files: [{
         id: 0,
@@ -82,6 +92,7 @@ export const editorMixin = {
       this.editingSnippet = {
         ...result,
         expires_at: this.resolveToShorthand(result.expires_at),
+        publish_at: this.toLocalDateTime(result.publish_at),
         tags: (result.tags || []).map(t => t.name),
         folder_id: result.folders?.[0]?.id || null,
         files: result.files || []
@@ -152,7 +163,8 @@ export const editorMixin = {
           id: f.id || 0,
           filename: f.filename,
           content: f.content,
-          language: f.language
+          language: f.language,
+          note: f.note || ''
         }));
       }
 
@@ -178,6 +190,7 @@ export const editorMixin = {
         is_public: this.editingSnippet.is_public || false,
         is_archived: this.editingSnippet.is_archived || false,
         expires_at: expiresAt,
+        publish_at: this.editingSnippet.publish_at ? new Date(this.editingSnippet.publish_at).toISOString() : null,
         files: files
       };
 
@@ -185,6 +198,7 @@ export const editorMixin = {
       if (this.editingSnippet.id) {
         result = await api.put(`/api/v1/snippets/${this.editingSnippet.id}`, data);
       } else {
+        if (!(await this.confirmNoDuplicates(data))) return;
         result = await api.post('/api/v1/snippets', data);
       }
 
@@ -223,6 +237,20 @@ export const editorMixin = {
     }
   },
 
+  // Warns before creating a snippet that matches an existing one by title or content
+  async confirmNoDuplicates(data) {
+    const matches = await api.post('/api/v1/snippets/check-duplicates', {
+      title: data.title,
+      content: data.content,
+      files: data.files || []
+    });
+    if (!Array.isArray(matches) || matches.length === 0) return true;
+
+    const match = matches[0];
+    const what = match.matched_by === 'content' ? 'the same content' : 'the same title';
+    return confirm(`"${match.title}" already has ${what}. Create this snippet anyway?`);
+  },
+
   cancelEdit() {
     this.showEditor = false;
     this.isEditing = false;
@@ -253,6 +281,7 @@ export const editorMixin = {
       is_public: false,
       is_favorite: false,
       expires_at: '',
+      publish_at: '',
       sync_to_gist: false,
       files: [{
         id: 0,
@@ -338,11 +367,24 @@ export const editorMixin = {
 
       await navigator.clipboard.writeText(contentToCopy);
       showToast('Copied to clipboard');
+      this.markSnippetUsed(snippet);
     } catch (err) {
       showToast('Failed to copy', 'error');
     }
   },
 
+  // Record a copy so frequently used snippets can be sorted first.
+  // Fire-and-forget: a failure here must not affect the copy itself.
+  markSnippetUsed(snippet) {
+    if (!snippet?.id) return;
+    api.post(`/api/v1/snippets/${snippet.id}/used`).then(result => {
+      if (result && !result.error) {
+        snippet.use_count = result.use_count;
+        snippet.last_used_at = result.last_used_at;
+      }
+    }).catch(() => {});
+  },
+
   async copyAsRichText(snippet) {
     try {
       if (typeof Prism === 'undefined') {
@@ -404,12 +446,12 @@ export const editorMixin = {
   },
 
   async copyShareLink(snippet) {
-    if (!snippet?.id || !snippet?.is_public) {
+    if (!snippet?.public_token || !snippet?.is_public) {
       showToast('Snippet must be public to share', 'warning');
       return;
     }
     try {
-      const shareUrl = `${window.location.origin}/s/${snippet.id}`;
+      const shareUrl = `${window.location.origin}/s/${snippet.public_token}`;
       await navigator.clipboard.writeText(shareUrl);
       showToast('Share link copied to clipboard');
     } catch (err) {
@@ -778,6 +820,14 @@ export const editorMixin = {
   },
 
   // Expiration helpers
+  // Convert an ISO timestamp to the local "YYYY-MM-DDTHH:MM" format used by datetime-local inputs
+  toLocalDateTime(iso) {
+    if (!iso || isNaN(Date.parse(iso))) return '';
+    const d = new Date(iso);
+    d.setMinutes(d.getMinutes() - d.getTimezoneOffset());
+    return d.toISOString().slice(0, 16);
+  },
+
   todayDate() {
     const d = new Date();
     return d.toISOString().split('T')[0];
@@ -259,6 +259,15 @@ export const fileManagerMixin = {
     this.scheduleAutoSave();
   },
 
+  updateActiveFileNote(note) {
+    const files = this._ensureEditableFiles();
+    if (files.length === 0) {
+      return;
+    }
+    files[this.activeFileIndex].note = note;
+    this.scheduleAutoSave();
+  },
+
   updateActiveFilename(filename) {
     // Sanitize filename to remove spaces
     filename = sanitizeFilename(filename);
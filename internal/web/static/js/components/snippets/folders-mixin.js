@@ -44,7 +44,7 @@ export const foldersMixin = {
   },
 
   async deleteFolder(folder) {
-    if (!confirm(`Delete folder "${folder.name}"? Snippets in this folder will not be deleted.`)) return;
+    if (!confirm(`Delete folder "${folder.name}"? Its subfolders and snippets will move to the parent folder.`)) return;
 
     const result = await api.delete(`/api/v1/folders/${folder.id}`);
     if (!result || !result.error) {
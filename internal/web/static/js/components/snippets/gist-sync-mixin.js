@@ -10,7 +10,8 @@ export const gistSyncMixin = {
     auto_sync_enabled: true,
     sync_interval_minutes: 15,
     conflict_resolution_strategy: 'manual',
-    last_full_sync_at: ''
+    last_full_sync_at: '',
+    rate_limit: null
   },
   gistTokenInput: '',
   gistTestingConnection: false,
@@ -35,11 +36,27 @@ export const gistSyncMixin = {
 
     if (result && !result.error) {
       showToast(`Connected as ${result.username}`, 'success');
+      if (result.rate_limit) {
+        this.gistConfig.rate_limit = result.rate_limit;
+      }
     } else {
       showToast(result?.error?.message || 'Connection failed', 'error');
     }
   },
 
+  // gistRateLimitText describes the GitHub API quota left, and when a spent
+  // quota comes back
+  gistRateLimitText() {
+    const limit = this.gistConfig.rate_limit;
+    if (!limit) return '';
+    const reset = new Date(limit.reset).toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' });
+    if (limit.remaining === 0) {
+      return `GitHub API limit reached, sync resumes after ${reset}`;
+    }
+    const of = limit.limit ? ` of ${limit.limit}` : '';
+    return `GitHub API: ${limit.remaining}${of} requests left until ${reset}`;
+  },
+
   async saveGistConfig() {
     const payload = {
       enabled: this.gistConfig.enabled,
@@ -112,9 +129,15 @@ export const gistSyncMixin = {
       const result = await api.post('/api/v1/gist/sync/all');
 
       if (result && !result.error) {
-        const message = result.synced > 0 || result.conflicts > 0 || result.errors > 0
+        let message = result.synced > 0 || result.conflicts > 0 || result.errors > 0
           ? `Sync complete: ${result.synced} synced, ${result.conflicts} conflicts, ${result.errors} errors`
           : 'No snippets are synced yet. Use "Enable Sync for All" first.';
+        if (result.skipped > 0) {
+          message += `. GitHub rate limit reached, ${result.skipped} left for the next sync`;
+        }
+        if (result.rate_limit) {
+          this.gistConfig.rate_limit = result.rate_limit;
+        }
         showToast(message, result.synced > 0 ? 'success' : 'info');
         await this.loadGistMappings();
         await this.loadGistConflicts();
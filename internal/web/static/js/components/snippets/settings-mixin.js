@@ -8,7 +8,15 @@ export const settingsMixin = {
   settingsTab: 'general',
   showFontSizeHelp: false,
   apiTokens: [],
-  newToken: { name: '', permissions: 'read', expires_in_days: 30 },
+  newToken: { name: '', permissions: 'read', scopes: [], expires_in_days: 30 },
+  tokenScopes: [
+    { value: 'snippets:read', label: 'Read snippets' },
+    { value: 'snippets:write', label: 'Write snippets' },
+    { value: 'tags:write', label: 'Manage tags' },
+    { value: 'folders:write', label: 'Manage folders' },
+    { value: 'backup:run', label: 'Run backups' },
+    { value: 'sync:manage', label: 'Manage gist sync' }
+  ],
   createdToken: null,
   tokenPasswordAction: null, // 'create' or 'delete'
   tokenPassword: '',
@@ -16,6 +24,7 @@ export const settingsMixin = {
   customCssChanged: false,
   showDisableLoginPassword: false,
   disableLoginPassword: '',
+  reports: [],
 
   async openSettings() {
     this.showSettings = true;
@@ -57,6 +66,7 @@ export const settingsMixin = {
     this.pendingTokenData = {
       name: this.newToken.name,
       permissions: this.newToken.permissions,
+      scopes: this.newToken.scopes,
       expires_in_days: parseInt(this.newToken.expires_in_days) || null
     };
 
@@ -109,6 +119,7 @@ export const settingsMixin = {
       ...this.pendingTokenData || {
         name: this.newToken.name,
         permissions: this.newToken.permissions,
+        scopes: this.newToken.scopes,
         expires_in_days: parseInt(this.newToken.expires_in_days) || null
       }
     };
@@ -124,7 +135,7 @@ export const settingsMixin = {
 
     if (result && !result.error) {
       this.createdToken = result.token;
-      this.newToken = { name: '', permissions: 'read', expires_in_days: 30 };
+      this.newToken = { name: '', permissions: 'read', scopes: [], expires_in_days: 30 };
       await this.loadApiTokens();
       showToast('API token created');
     } else {
@@ -153,6 +164,22 @@ export const settingsMixin = {
     }
   },
 
+  async loadReports() {
+    const result = await api.get('/api/v1/reports');
+    this.reports = Array.isArray(result) ? result : [];
+  },
+
+  async resolveReport(reportId, action) {
+    if (action === 'unpublish' && !confirm('Make this snippet private? All open reports against it will be closed.')) {
+      return;
+    }
+    const result = await api.post(`/api/v1/reports/${reportId}/resolve`, { action });
+    if (result && !result.error) {
+      showToast(action === 'unpublish' ? 'Snippet unpublished' : 'Report dismissed');
+      await this.loadReports();
+    }
+  },
+
   formatTokenDate(dateStr) {
     if (!dateStr) return 'Never';
     return new Date(dateStr).toLocaleDateString();
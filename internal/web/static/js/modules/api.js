@@ -61,6 +61,10 @@ export const api = {
     
     // Handle error responses: { error: { code, message, details } }
     if (json && json.error) {
+      // Server errors quote the request ID so users can report it
+      if (response.status >= 500 && json.error.request_id) {
+        json.error.message = `${json.error.message} (request ID: ${json.error.request_id})`;
+      }
       // Return error in the format frontend expects
       return { error: json.error };
     }
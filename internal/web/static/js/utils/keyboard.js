@@ -45,6 +45,17 @@ export function initKeyboardShortcuts() {
       }
     }
 
+    // j/k: Next/previous snippet while viewing one
+    if ((e.key === 'j' || e.key === 'k') && !e.ctrlKey && !e.metaKey && !e.altKey &&
+        !['INPUT', 'TEXTAREA', 'SELECT'].includes(e.target.tagName) && !e.target.isContentEditable) {
+      const app = Alpine.$data(document.querySelector('[x-data="snippetsApp()"]'));
+      if (app?.showEditor && !app?.isEditing) {
+        e.preventDefault();
+        app.viewNeighbor(e.key === 'j' ? 1 : -1);
+        return false;
+      }
+    }
+
     // Escape: Close editor/modal
     if (e.key === 'Escape' || e.key === 'Esc') {
       const app = Alpine.$data(document.querySelector('[x-data="snippetsApp()"]'));
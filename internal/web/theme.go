@@ -0,0 +1,93 @@
+package web
+
+import (
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+)
+
+// overlayFS serves files from a theme directory and falls back to the
+// embedded files for anything the theme doesn't override
+type overlayFS struct {
+	theme fs.FS
+	base  fs.FS
+}
+
+// Open opens name from the theme if it has it, otherwise from the base
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if f, err := o.theme.Open(name); err == nil {
+		return f, nil
+	}
+	return o.base.Open(name)
+}
+
+// ReadDir merges the theme's entries over the base's so globs such as
+// templates/components/*.html see both
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	baseEntries, baseErr := fs.ReadDir(o.base, name)
+	themeEntries, err := fs.ReadDir(o.theme, name)
+	if err != nil {
+		return baseEntries, baseErr
+	}
+
+	merged := make(map[string]fs.DirEntry, len(baseEntries)+len(themeEntries))
+	for _, e := range baseEntries {
+		merged[e.Name()] = e
+	}
+	for _, e := range themeEntries {
+		merged[e.Name()] = e
+	}
+	entries := make([]fs.DirEntry, 0, len(merged))
+	for _, e := range merged {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// themed overlays dir on base, or returns base when no theme is set
+func themed(base fs.FS, dir string) fs.FS {
+	if dir == "" {
+		return base
+	}
+	return overlayFS{theme: os.DirFS(dir), base: base}
+}
+
+// pages are the content templates rendered inside layout.html
+var pages = []string{"index.html", "login.html", "public.html", "error.html"}
+
+// WithTheme serves templates from dir/templates ahead of the built-in ones.
+// Pages are still parsed on every request so theme edits show up without a
+// restart; a page whose theme templates fail to parse or execute falls back
+// to the built-in templates.
+func (h *Handler) WithTheme(dir string, logger *slog.Logger) *Handler {
+	if dir == "" {
+		return h
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		logger.Error("theme directory not found, using built-in templates", "dir", dir)
+		return h
+	}
+
+	h.templateFS = themed(templatesFS, dir)
+	h.themed = true
+	h.logger = logger
+
+	for _, page := range pages {
+		if _, err := h.parse(h.templateFS, "layout.html", page); err != nil {
+			logger.Warn("theme template failed to parse, using built-in page", "page", page, "error", err)
+		}
+	}
+	logger.Info("custom theme enabled", "dir", dir)
+	return h
+}
+
+// StaticHandler returns a handler for static files. Files in
+// themeDir/static take precedence over the built-in ones.
+func StaticHandler(basePath, themeDir string) http.Handler {
+	staticContent, _ := fs.Sub(themed(staticFS, themeDir), "static")
+	prefix := basePath + "/static/"
+	return http.StripPrefix(prefix, http.FileServer(http.FS(staticContent)))
+}
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MohamedElashri/snipo/internal/testutil"
+)
+
+func writeThemeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func renderPage(h *Handler, content string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.renderStatus(rec, http.StatusOK, "layout.html", content, PageData{Title: "Shared Snippet"})
+	return rec
+}
+
+func TestTheme_OverridesTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeThemeFile(t, dir, "templates/public.html", `{{define "content"}}<main class="my-theme">custom</main>{{end}}`)
+
+	h := (&Handler{templateFS: templatesFS}).WithTheme(dir, testutil.TestLogger())
+
+	rec := renderPage(h, "public.html")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `class="my-theme"`) {
+		t.Fatalf("expected themed public page, got %d: %s", rec.Code, rec.Body.String())
+	}
+	// Templates the theme doesn't override still come from the built-in set
+	if !strings.Contains(rec.Body.String(), "<html") {
+		t.Error("expected built-in layout around the themed page")
+	}
+}
+
+func TestTheme_FallsBackOnBrokenTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeThemeFile(t, dir, "templates/public.html", `{{define "content"}}{{.Missing}`)
+	writeThemeFile(t, dir, "templates/error.html", `{{define "content"}}{{.NoSuchField}}{{end}}`)
+
+	h := (&Handler{templateFS: templatesFS}).WithTheme(dir, testutil.TestLogger())
+
+	for _, page := range []string{"public.html", "error.html"} {
+		rec := renderPage(h, page)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "public-snippet-container") {
+			t.Errorf("expected built-in %s after theme failure, got %d: %s", page, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestTheme_OverridesStaticFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeThemeFile(t, dir, "static/css/theme.css", "body { color: teal; }")
+
+	handler := StaticHandler("", dir)
+	for path, want := range map[string]string{
+		"/static/css/theme.css": "color: teal",
+		"/static/favicon.ico":   "",
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		body, _ := io.ReadAll(rec.Body)
+		if rec.Code != http.StatusOK || !strings.Contains(string(body), want) {
+			t.Errorf("GET %s: expected 200 containing %q, got %d", path, want, rec.Code)
+		}
+	}
+}
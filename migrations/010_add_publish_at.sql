@@ -0,0 +1,8 @@
+-- Snipo Migration: Add Scheduled Publishing
+-- Version: 10
+
+-- Add publish_at column to snippets (nullable, cleared once published)
+ALTER TABLE snippets ADD COLUMN publish_at DATETIME DEFAULT NULL;
+
+-- Index for publish_at to speed up the publish scheduler
+CREATE INDEX IF NOT EXISTS idx_snippets_publish_at ON snippets(publish_at);
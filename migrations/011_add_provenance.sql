@@ -0,0 +1,5 @@
+-- Snipo Migration: Add Fork Provenance
+-- Version: 11
+
+-- Add provenance column to snippets (JSON with source URL/ID of forked snippets)
+ALTER TABLE snippets ADD COLUMN provenance TEXT DEFAULT NULL;
@@ -0,0 +1,30 @@
+-- Snipo Migration: Add Remote Sources (Federation)
+-- Version: 12
+
+-- Other snipo instances whose public snippets are mirrored locally
+CREATE TABLE IF NOT EXISTS remote_sources (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    folder_id INTEGER DEFAULT NULL,
+    enabled INTEGER DEFAULT 1,
+    etag TEXT DEFAULT '',
+    last_synced_at DATETIME DEFAULT NULL,
+    last_error TEXT DEFAULT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE SET NULL
+);
+
+-- Mapping between remote snippets and their local read-only mirrors
+CREATE TABLE IF NOT EXISTS remote_snippets (
+    source_id INTEGER NOT NULL,
+    remote_id TEXT NOT NULL,
+    snippet_id TEXT NOT NULL,
+    remote_updated_at DATETIME,
+    PRIMARY KEY (source_id, remote_id),
+    FOREIGN KEY (source_id) REFERENCES remote_sources(id) ON DELETE CASCADE,
+    FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_remote_snippets_snippet ON remote_snippets(snippet_id);
@@ -0,0 +1,9 @@
+-- Snipo Migration: Add Usage Tracking
+-- Version: 13
+
+-- Count of copies/uses and when the snippet was last used (distinct from views)
+ALTER TABLE snippets ADD COLUMN use_count INTEGER DEFAULT 0;
+ALTER TABLE snippets ADD COLUMN last_used_at DATETIME DEFAULT NULL;
+
+-- Index for sorting by recent use
+CREATE INDEX IF NOT EXISTS idx_snippets_last_used_at ON snippets(last_used_at);
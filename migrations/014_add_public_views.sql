@@ -0,0 +1,15 @@
+-- Snipo Migration: Add Public Views
+-- Version: 14
+
+-- Share page visits by signed-out visitors, separate from view_count
+ALTER TABLE snippets ADD COLUMN public_view_count INTEGER DEFAULT 0;
+
+-- Referring domains of share page visits
+CREATE TABLE IF NOT EXISTS snippet_referrers (
+    snippet_id TEXT NOT NULL,
+    domain TEXT NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0,
+    last_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (snippet_id, domain),
+    FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
@@ -0,0 +1,20 @@
+-- Snipo Migration: Add Abuse Reports
+-- Version: 15
+
+-- Abuse reports against public snippets, reviewed by the admin
+CREATE TABLE IF NOT EXISTS snippet_reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    snippet_id TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    details TEXT DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    resolved_at DATETIME DEFAULT NULL,
+    FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_snippet_reports_status ON snippet_reports(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_snippet_reports_snippet ON snippet_reports(snippet_id);
+
+-- Open reports needed to unpublish a snippet automatically (0 = never)
+ALTER TABLE settings ADD COLUMN report_unpublish_threshold INTEGER DEFAULT 0 NOT NULL;
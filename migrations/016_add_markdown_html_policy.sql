@@ -0,0 +1,5 @@
+-- Snipo Migration: Add Markdown HTML Policy
+-- Version: 16
+
+-- How raw HTML in public markdown is handled: sanitize, escape or allow
+ALTER TABLE settings ADD COLUMN markdown_html_policy TEXT DEFAULT 'sanitize' NOT NULL;
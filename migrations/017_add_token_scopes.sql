@@ -0,0 +1,5 @@
+-- Snipo Migration: Add Token Scopes
+-- Version: 17
+
+-- Comma-separated scopes; empty means the permission level alone applies
+ALTER TABLE api_tokens ADD COLUMN scopes TEXT DEFAULT '' NOT NULL;
@@ -0,0 +1,6 @@
+-- Snipo Migration: Add Remember-Me Sessions
+-- Version: 18
+
+-- Remembered sessions extend expires_at on use, up to max_expires_at
+ALTER TABLE sessions ADD COLUMN remember INTEGER DEFAULT 0 NOT NULL;
+ALTER TABLE sessions ADD COLUMN max_expires_at DATETIME DEFAULT NULL;
@@ -0,0 +1,43 @@
+-- Snipo Migration: Add Key-Value Settings
+-- Version: 19
+
+-- Settings as key-value pairs, so new settings don't need a migration.
+-- The settings row is kept for its created_at and updated_at.
+CREATE TABLE IF NOT EXISTS app_settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Carry over the values saved so far
+INSERT OR IGNORE INTO app_settings (key, value)
+SELECT 'app_name', CAST(app_name AS TEXT) FROM settings WHERE id = 1 AND app_name IS NOT NULL
+UNION ALL SELECT 'custom_css', CAST(custom_css AS TEXT) FROM settings WHERE id = 1 AND custom_css IS NOT NULL
+UNION ALL SELECT 'theme', CAST(theme AS TEXT) FROM settings WHERE id = 1 AND theme IS NOT NULL
+UNION ALL SELECT 'default_language', CAST(default_language AS TEXT) FROM settings WHERE id = 1 AND default_language IS NOT NULL
+UNION ALL SELECT 's3_enabled', CAST(s3_enabled AS TEXT) FROM settings WHERE id = 1 AND s3_enabled IS NOT NULL
+UNION ALL SELECT 's3_endpoint', CAST(s3_endpoint AS TEXT) FROM settings WHERE id = 1 AND s3_endpoint IS NOT NULL
+UNION ALL SELECT 's3_bucket', CAST(s3_bucket AS TEXT) FROM settings WHERE id = 1 AND s3_bucket IS NOT NULL
+UNION ALL SELECT 's3_region', CAST(s3_region AS TEXT) FROM settings WHERE id = 1 AND s3_region IS NOT NULL
+UNION ALL SELECT 'backup_encryption_enabled', CAST(backup_encryption_enabled AS TEXT) FROM settings WHERE id = 1 AND backup_encryption_enabled IS NOT NULL
+UNION ALL SELECT 'archive_enabled', CAST(archive_enabled AS TEXT) FROM settings WHERE id = 1 AND archive_enabled IS NOT NULL
+UNION ALL SELECT 'trash_enabled', CAST(trash_enabled AS TEXT) FROM settings WHERE id = 1 AND trash_enabled IS NOT NULL
+UNION ALL SELECT 'history_enabled', CAST(history_enabled AS TEXT) FROM settings WHERE id = 1 AND history_enabled IS NOT NULL
+UNION ALL SELECT 'auto_archive_enabled', CAST(auto_archive_enabled AS TEXT) FROM settings WHERE id = 1 AND auto_archive_enabled IS NOT NULL
+UNION ALL SELECT 'default_expiration_days', CAST(default_expiration_days AS TEXT) FROM settings WHERE id = 1 AND default_expiration_days IS NOT NULL
+UNION ALL SELECT 'disable_login', CAST(disable_login AS TEXT) FROM settings WHERE id = 1 AND disable_login IS NOT NULL
+UNION ALL SELECT 'editor_font_size', CAST(editor_font_size AS TEXT) FROM settings WHERE id = 1 AND editor_font_size IS NOT NULL
+UNION ALL SELECT 'editor_tab_size', CAST(editor_tab_size AS TEXT) FROM settings WHERE id = 1 AND editor_tab_size IS NOT NULL
+UNION ALL SELECT 'editor_theme', CAST(editor_theme AS TEXT) FROM settings WHERE id = 1 AND editor_theme IS NOT NULL
+UNION ALL SELECT 'editor_word_wrap', CAST(editor_word_wrap AS TEXT) FROM settings WHERE id = 1 AND editor_word_wrap IS NOT NULL
+UNION ALL SELECT 'editor_show_print_margin', CAST(editor_show_print_margin AS TEXT) FROM settings WHERE id = 1 AND editor_show_print_margin IS NOT NULL
+UNION ALL SELECT 'editor_show_gutter', CAST(editor_show_gutter AS TEXT) FROM settings WHERE id = 1 AND editor_show_gutter IS NOT NULL
+UNION ALL SELECT 'editor_show_indent_guides', CAST(editor_show_indent_guides AS TEXT) FROM settings WHERE id = 1 AND editor_show_indent_guides IS NOT NULL
+UNION ALL SELECT 'editor_highlight_active_line', CAST(editor_highlight_active_line AS TEXT) FROM settings WHERE id = 1 AND editor_highlight_active_line IS NOT NULL
+UNION ALL SELECT 'editor_use_soft_tabs', CAST(editor_use_soft_tabs AS TEXT) FROM settings WHERE id = 1 AND editor_use_soft_tabs IS NOT NULL
+UNION ALL SELECT 'editor_enable_snippets', CAST(editor_enable_snippets AS TEXT) FROM settings WHERE id = 1 AND editor_enable_snippets IS NOT NULL
+UNION ALL SELECT 'editor_enable_live_autocompletion', CAST(editor_enable_live_autocompletion AS TEXT) FROM settings WHERE id = 1 AND editor_enable_live_autocompletion IS NOT NULL
+UNION ALL SELECT 'markdown_font_size', CAST(markdown_font_size AS TEXT) FROM settings WHERE id = 1 AND markdown_font_size IS NOT NULL
+UNION ALL SELECT 'exclude_first_line_on_copy', CAST(exclude_first_line_on_copy AS TEXT) FROM settings WHERE id = 1 AND exclude_first_line_on_copy IS NOT NULL
+UNION ALL SELECT 'report_unpublish_threshold', CAST(report_unpublish_threshold AS TEXT) FROM settings WHERE id = 1 AND report_unpublish_threshold IS NOT NULL
+UNION ALL SELECT 'markdown_html_policy', CAST(markdown_html_policy AS TEXT) FROM settings WHERE id = 1 AND markdown_html_policy IS NOT NULL;
@@ -0,0 +1,14 @@
+-- Snipo Migration: Add Login Insights
+-- Version: 20
+
+-- Country of the client, from the optional GeoIP database
+ALTER TABLE sessions ADD COLUMN country TEXT DEFAULT NULL;
+
+-- Failed logins, kept for 30 days for the admin overview
+CREATE TABLE IF NOT EXISTS login_failures (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    country TEXT DEFAULT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_failures_created ON login_failures(created_at);
@@ -0,0 +1,6 @@
+-- Snipo Migration: Add Snippet File Notes
+-- Version: 21
+
+-- Optional per-file note, kept with file history too
+ALTER TABLE snippet_files ADD COLUMN note TEXT NOT NULL DEFAULT '';
+ALTER TABLE snippet_files_history ADD COLUMN note TEXT NOT NULL DEFAULT '';
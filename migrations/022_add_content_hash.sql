@@ -0,0 +1,8 @@
+-- Snipo Migration: Add Content Hash
+-- Version: 22
+
+-- Hash of the snippet's code only, used to find duplicates. Filled in by the
+-- application; NULL until first computed.
+ALTER TABLE snippets ADD COLUMN content_hash TEXT DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_snippets_content_hash ON snippets(content_hash);
@@ -2,11 +2,14 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"os"
 	"strings"
 
+	"github.com/MohamedElashri/snipo/tui/internal/api"
 	"github.com/MohamedElashri/snipo/tui/internal/app"
+	"github.com/MohamedElashri/snipo/tui/internal/cache"
 	"github.com/MohamedElashri/snipo/tui/internal/config"
 )
 
@@ -24,6 +27,12 @@ func main() {
 				os.Exit(1)
 			}
 			return
+		case "sync":
+			if err := runSync(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
 		case "version", "-v", "--version":
 			fmt.Printf("Snippy %s (%s)\n", Version, Commit)
 			return
@@ -71,3 +80,51 @@ func runConfigWizard() error {
 	fmt.Println("Configuration saved successfully!")
 	return nil
 }
+
+// runSync uploads the edits made offline and refreshes the offline cache
+func runSync(args []string) error {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	force := flags.Bool("force", false, "overwrite server changes with conflicting local edits")
+	discard := flags.Bool("discard", false, "drop local edits that conflict with server changes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *force && *discard {
+		return fmt.Errorf("--force and --discard can't be used together")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if !cfg.IsConfigured() {
+		return fmt.Errorf("snippy is not configured. Please run 'snippy config' first")
+	}
+	store, err := cache.Open()
+	if err != nil {
+		return err
+	}
+
+	resolve := cache.KeepConflicts
+	switch {
+	case *force:
+		resolve = cache.ForceConflicts
+	case *discard:
+		resolve = cache.DiscardConflicts
+	}
+
+	report, err := cache.Sync(api.NewClient(cfg.ServerURL, cfg.APIKey), store, resolve)
+	if report != nil {
+		fmt.Printf("Pushed %d local change(s), downloaded %d snippet(s)\n", report.Pushed, report.Pulled)
+		if report.Discarded > 0 {
+			fmt.Printf("Discarded %d conflicting change(s)\n", report.Discarded)
+		}
+		for _, c := range report.Conflicts {
+			fmt.Printf("Conflict: %q %s\n", c.Title, c.Reason)
+		}
+		if len(report.Conflicts) > 0 {
+			fmt.Println("Run 'snippy sync --force' to keep your edits or 'snippy sync --discard' to keep the server's")
+		}
+	}
+	return err
+}
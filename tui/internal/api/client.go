@@ -3,19 +3,29 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
+// apiVersion is the server API version the client is written for, sent as
+// Accept-Version so a server that changed incompatibly refuses the requests
+const apiVersion = "1.0"
+
 type Client struct {
 	baseURL    string
 	apiKey     string
 	httpClient *http.Client
+
+	mu         sync.Mutex
+	notices    []string        // Deprecation warnings not shown yet
+	seenNotice map[string]bool // Warnings already queued, to show each once
 }
 
 func NewClient(baseURL, apiKey string) *Client {
@@ -28,6 +38,28 @@ func NewClient(baseURL, apiKey string) *Client {
 	}
 }
 
+// IsConnectionError reports whether err means the server couldn't be reached,
+// as opposed to the server answering with an error
+func IsConnectionError(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// statusError keeps the HTTP status of an error response
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string { return e.err.Error() }
+func (e *statusError) Unwrap() error { return e.err }
+
+// IsNotFound reports whether the server answered err with 404 Not Found
+func IsNotFound(err error) bool {
+	var se *statusError
+	return errors.As(err, &se) && se.status == http.StatusNotFound
+}
+
 func (c *Client) doRequest(method, path string, body interface{}, result interface{}) error {
 	var reqBody io.Reader
 	if body != nil {
@@ -46,6 +78,7 @@ func (c *Client) doRequest(method, path string, body interface{}, result interfa
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Version", apiVersion)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -53,15 +86,24 @@ func (c *Client) doRequest(method, path string, body interface{}, result interfa
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if resp.Header.Get("Deprecation") != "" {
+		c.addNotice(method, path, resp.Header.Get("Warning"))
+	}
+
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode >= 400 {
+		// Quote the request ID so it can be matched against the server logs
+		requestID := resp.Header.Get("X-Request-ID")
 		var errResp ErrorResponse
 		if err := json.Unmarshal(respBody, &errResp); err != nil {
-			return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+			return &statusError{resp.StatusCode, withRequestID(fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody)), requestID)}
+		}
+		if errResp.Error.RequestID != "" {
+			requestID = errResp.Error.RequestID
 		}
 		errMsg := errResp.Error.Message
 		if errResp.Error.Details != nil {
@@ -77,7 +119,7 @@ func (c *Client) doRequest(method, path string, body interface{}, result interfa
 				errMsg = fmt.Sprintf("%s: %v", errMsg, errResp.Error.Details)
 			}
 		}
-		return fmt.Errorf("API error: %s", errMsg)
+		return &statusError{resp.StatusCode, withRequestID(fmt.Errorf("API error: %s", errMsg), requestID)}
 	}
 
 	if result != nil && len(respBody) > 0 {
@@ -89,6 +131,50 @@ func (c *Client) doRequest(method, path string, body interface{}, result interfa
 	return nil
 }
 
+// addNotice queues the warning the server sent for a deprecated endpoint
+func (c *Client) addNotice(method, path, warning string) {
+	// The text is the quoted part of `299 snipo "..."`
+	if _, text, ok := strings.Cut(warning, `"`); ok {
+		warning = strings.TrimSuffix(text, `"`)
+	}
+	if warning == "" {
+		endpoint, _, _ := strings.Cut(path, "?")
+		warning = fmt.Sprintf("%s %s is deprecated", method, endpoint)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.seenNotice[warning] {
+		return
+	}
+	if c.seenNotice == nil {
+		c.seenNotice = make(map[string]bool)
+	}
+	c.seenNotice[warning] = true
+	c.notices = append(c.notices, warning)
+}
+
+// DeprecationNotice returns the next deprecation warning from the server not
+// shown yet, or "" when there is none. Each warning is returned once.
+func (c *Client) DeprecationNotice() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.notices) == 0 {
+		return ""
+	}
+	notice := c.notices[0]
+	c.notices = c.notices[1:]
+	return notice
+}
+
+// withRequestID appends the server's request ID to an API error
+func withRequestID(err error, requestID string) error {
+	if requestID == "" {
+		return err
+	}
+	return fmt.Errorf("%w (request ID: %s)", err, requestID)
+}
+
 func (c *Client) Health() (*HealthResponse, error) {
 	var response struct {
 		Data HealthResponse `json:"data"`
@@ -100,33 +186,20 @@ func (c *Client) Health() (*HealthResponse, error) {
 	return &response.Data, nil
 }
 
-func (c *Client) ListSnippets(page, limit int, query string, tagIDs, folderIDs []int, language string, favorite, archived *bool) ([]Snippet, *Pagination, error) {
-	params := url.Values{}
-	if page > 0 {
-		params.Set("page", strconv.Itoa(page))
-	}
-	if limit > 0 {
-		params.Set("limit", strconv.Itoa(limit))
-	}
-	if query != "" {
-		params.Set("q", query)
-	}
-	if language != "" {
-		params.Set("language", language)
-	}
-	if favorite != nil {
-		params.Set("favorite", strconv.FormatBool(*favorite))
-	}
-	if archived != nil {
-		params.Set("is_archived", strconv.FormatBool(*archived))
-	}
-	for _, id := range tagIDs {
-		params.Add("tag_ids", strconv.Itoa(id))
+// SystemVersion returns the server's version and enabled features. Servers
+// older than the endpoint answer with a not found error.
+func (c *Client) SystemVersion() (*SystemVersion, error) {
+	var response struct {
+		Data SystemVersion `json:"data"`
 	}
-	for _, id := range folderIDs {
-		params.Add("folder_ids", strconv.Itoa(id))
+	if err := c.doRequest("GET", "/api/v1/system/version", nil, &response); err != nil {
+		return nil, err
 	}
+	return &response.Data, nil
+}
 
+func (c *Client) ListSnippets(opts ListOptions) ([]Snippet, *Pagination, error) {
+	params := opts.values()
 	path := "/api/v1/snippets"
 	if len(params) > 0 {
 		path += "?" + params.Encode()
@@ -150,6 +223,63 @@ func (c *Client) ListSnippets(page, limit int, query string, tagIDs, folderIDs [
 	return snippets, &response.Pagination, nil
 }
 
+// GetNeighbors returns the snippets before and after id in the list opts
+// selects. Paging options are ignored.
+func (c *Client) GetNeighbors(id string, opts ListOptions) (*Neighbors, error) {
+	opts.Page, opts.Limit = 0, 0
+	path := fmt.Sprintf("/api/v1/snippets/%s/neighbors", id)
+	if params := opts.values(); len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	var response struct {
+		Data Neighbors `json:"data"`
+	}
+	if err := c.doRequest("GET", path, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// values encodes the options as list query parameters
+func (opts ListOptions) values() url.Values {
+	params := url.Values{}
+	if opts.Page > 0 {
+		params.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Query != "" {
+		params.Set("q", opts.Query)
+	}
+	if opts.Language != "" {
+		params.Set("language", opts.Language)
+	}
+	if opts.Favorite != nil {
+		params.Set("favorite", strconv.FormatBool(*opts.Favorite))
+	}
+	if opts.Archived != nil {
+		params.Set("is_archived", strconv.FormatBool(*opts.Archived))
+	}
+	if opts.Deleted != nil {
+		params.Set("is_deleted", strconv.FormatBool(*opts.Deleted))
+	}
+	if opts.SortBy != "" {
+		params.Set("sort_by", opts.SortBy)
+	}
+	if opts.SortOrder != "" {
+		params.Set("sort_order", opts.SortOrder)
+	}
+	for _, id := range opts.TagIDs {
+		params.Add("tag_ids", strconv.Itoa(id))
+	}
+	for _, id := range opts.FolderIDs {
+		params.Add("folder_ids", strconv.Itoa(id))
+	}
+	return params
+}
+
 func (c *Client) GetSnippet(id string) (*Snippet, error) {
 	var response APIResponse
 	if err := c.doRequest("GET", fmt.Sprintf("/api/v1/snippets/%s", id), nil, &response); err != nil {
@@ -211,6 +341,84 @@ func (c *Client) DeleteSnippet(id string) error {
 	return c.doRequest("DELETE", fmt.Sprintf("/api/v1/snippets/%s", id), nil, nil)
 }
 
+// DeleteSnippetPermanently deletes a snippet without moving it to the trash
+func (c *Client) DeleteSnippetPermanently(id string) error {
+	return c.doRequest("DELETE", fmt.Sprintf("/api/v1/snippets/%s?permanent=true", id), nil, nil)
+}
+
+// RestoreSnippet moves a snippet out of the trash
+func (c *Client) RestoreSnippet(id string) error {
+	return c.doRequest("POST", fmt.Sprintf("/api/v1/snippets/%s/restore", id), nil, nil)
+}
+
+// SyncGists runs a full GitHub Gist sync on the server
+func (c *Client) SyncGists() (*SyncResult, error) {
+	var response struct {
+		Data SyncResult `json:"data"`
+	}
+	if err := c.doRequest("POST", "/api/v1/gist/sync/all", nil, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// ToggleArchive archives a snippet, or unarchives an archived one
+func (c *Client) ToggleArchive(id string) (*Snippet, error) {
+	var response struct {
+		Data Snippet `json:"data"`
+	}
+	if err := c.doRequest("POST", fmt.Sprintf("/api/v1/snippets/%s/archive", id), nil, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// SearchSnippets runs a full-text search and returns up to limit matches;
+// limit 0 uses the server default
+func (c *Client) SearchSnippets(query string, limit int) ([]Snippet, error) {
+	params := url.Values{"q": {query}}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	var response struct {
+		Data []Snippet `json:"data"`
+	}
+	if err := c.doRequest("GET", "/api/v1/snippets/search?"+params.Encode(), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// GetHistory returns a snippet's saved versions, newest first; limit 0 uses
+// the server default
+func (c *Client) GetHistory(id string, limit int) ([]HistoryEntry, error) {
+	path := fmt.Sprintf("/api/v1/snippets/%s/history", id)
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+
+	var response struct {
+		Data []HistoryEntry `json:"data"`
+	}
+	if err := c.doRequest("GET", path, nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// RestoreFromHistory replaces a snippet with one of its saved versions
+func (c *Client) RestoreFromHistory(id string, historyID int64) (*Snippet, error) {
+	var response struct {
+		Data Snippet `json:"data"`
+	}
+	path := fmt.Sprintf("/api/v1/snippets/%s/history/%d/restore", id, historyID)
+	if err := c.doRequest("POST", path, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
 func (c *Client) ToggleFavorite(id string) (*Snippet, error) {
 	var response APIResponse
 	if err := c.doRequest("POST", fmt.Sprintf("/api/v1/snippets/%s/favorite", id), nil, &response); err != nil {
@@ -33,6 +33,30 @@ type ListResponse struct {
 	Meta       Meta        `json:"meta"`
 }
 
+// ListOptions filters and orders a snippet listing. Zero values are omitted
+// from the request so the server defaults apply.
+type ListOptions struct {
+	Page      int
+	Limit     int
+	Query     string
+	TagIDs    []int
+	FolderIDs []int
+	Language  string
+	Favorite  *bool
+	Archived  *bool
+	Deleted   *bool  // true lists the trash
+	SortBy    string // e.g. updated_at, title, view_count, use_count, last_used
+	SortOrder string // asc or desc
+}
+
+// Neighbors locates a snippet within a filtered list
+type Neighbors struct {
+	PrevID   *string `json:"prev_id"`
+	NextID   *string `json:"next_id"`
+	Position int     `json:"position"`
+	Total    int     `json:"total"`
+}
+
 type ErrorResponse struct {
 	Error struct {
 		Code      string      `json:"code"`
@@ -44,20 +68,62 @@ type ErrorResponse struct {
 }
 
 type Snippet struct {
-	ID          string    `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Language    string    `json:"language"`
-	Content     string    `json:"content"`
-	IsFavorite  bool      `json:"is_favorite"`
-	IsArchived  bool      `json:"is_archived"`
-	IsPublic    bool      `json:"is_public"`
-	ViewCount   int       `json:"view_count"`
-	FolderID    *int      `json:"folder_id"`
-	Tags        []Tag     `json:"tags"`
-	Files       []File    `json:"files,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Language    string     `json:"language"`
+	Content     string     `json:"content"`
+	IsFavorite  bool       `json:"is_favorite"`
+	IsArchived  bool       `json:"is_archived"`
+	IsPublic    bool       `json:"is_public"`
+	ViewCount   int        `json:"view_count"`
+	UseCount    int        `json:"use_count"`
+	FolderID    *int       `json:"folder_id"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	Tags        []Tag      `json:"tags"`
+	Folders     []Folder   `json:"folders,omitempty"`
+	Files       []File     `json:"files,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Secret is set when the content and files were decrypted locally; they
+	// are encrypted again before being saved
+	Secret bool `json:"-"`
+}
+
+// Input returns an update that keeps every field of the snippet as it is.
+// Files are left out so the server keeps them unchanged.
+func (s *Snippet) Input() SnippetInput {
+	tags := make([]string, 0, len(s.Tags))
+	for _, tag := range s.Tags {
+		tags = append(tags, tag.Name)
+	}
+	input := SnippetInput{
+		Title:       s.Title,
+		Description: s.Description,
+		Language:    s.Language,
+		Content:     s.Content,
+		Tags:        tags,
+		IsPublic:    s.IsPublic,
+		IsArchived:  s.IsArchived,
+		ExpiresAt:   s.ExpiresAt,
+		PublishAt:   s.PublishAt,
+		Secret:      s.Secret,
+	}
+	if id := s.Folder(); id != nil {
+		folderID := int64(*id)
+		input.FolderID = &folderID
+	}
+	return input
+}
+
+// Folder returns the ID of the folder the snippet is filed in, if any
+func (s *Snippet) Folder() *int {
+	if len(s.Folders) > 0 {
+		return &s.Folders[0].ID
+	}
+	return s.FolderID
 }
 
 type File struct {
@@ -66,11 +132,36 @@ type File struct {
 	Filename  string    `json:"filename"`
 	Content   string    `json:"content"`
 	Language  string    `json:"language"`
+	Note      string    `json:"note,omitempty"`
 	Size      int       `json:"size"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// HistoryEntry is a saved version of a snippet
+type HistoryEntry struct {
+	ID          int64         `json:"id"`
+	SnippetID   string        `json:"snippet_id"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Content     string        `json:"content"`
+	Language    string        `json:"language"`
+	IsFavorite  bool          `json:"is_favorite"`
+	IsPublic    bool          `json:"is_public"`
+	IsArchived  bool          `json:"is_archived"`
+	ChangeType  string        `json:"change_type"` // create, update or delete
+	CreatedAt   time.Time     `json:"created_at"`
+	Files       []HistoryFile `json:"files,omitempty"`
+}
+
+// HistoryFile is a file as it was in a saved version
+type HistoryFile struct {
+	Filename string `json:"filename"`
+	Content  string `json:"content"`
+	Language string `json:"language"`
+	Note     string `json:"note,omitempty"`
+}
+
 type Tag struct {
 	ID        int       `json:"id"`
 	Name      string    `json:"name"`
@@ -92,17 +183,21 @@ type SnippetInput struct {
 	Description string      `json:"description,omitempty"`
 	Language    string      `json:"language"`
 	Content     string      `json:"content"`
-	Tags        []string    `json:"tags,omitempty"`
+	Tags        []string    `json:"tags"` // nil keeps the current tags, empty removes them
 	FolderID    *int64      `json:"folder_id,omitempty"`
 	IsPublic    bool        `json:"is_public"`
 	IsArchived  bool        `json:"is_archived,omitempty"`
+	ExpiresAt   *time.Time  `json:"expires_at,omitempty"`
+	PublishAt   *time.Time  `json:"publish_at,omitempty"`
 	Files       []FileInput `json:"files,omitempty"`
+	Secret      bool        `json:"-"` // Encrypt content and files before sending
 }
 
 type FileInput struct {
 	Filename string `json:"filename"`
 	Content  string `json:"content"`
 	Language string `json:"language"`
+	Note     string `json:"note,omitempty"`
 }
 
 type TagInput struct {
@@ -115,9 +210,28 @@ type FolderInput struct {
 	ParentID *int   `json:"parent_id,omitempty"`
 }
 
+// SyncResult summarises a gist sync run
+type SyncResult struct {
+	TotalProcessed int `json:"total_processed"`
+	Synced         int `json:"synced"`
+	Conflicts      int `json:"conflicts"`
+	Errors         int `json:"errors"`
+}
+
 type HealthResponse struct {
 	Status   string          `json:"status"`
 	Database string          `json:"database"`
 	Version  string          `json:"version"`
 	Features map[string]bool `json:"features"`
 }
+
+// SystemVersion describes the server's build and the features it has enabled
+type SystemVersion struct {
+	Version       string          `json:"version"`
+	Commit        string          `json:"commit"`
+	BuildDate     string          `json:"build_date"`
+	GoVersion     string          `json:"go_version"`
+	APIVersion    string          `json:"api_version"`
+	SchemaVersion int             `json:"schema_version"`
+	Features      map[string]bool `json:"features"`
+}
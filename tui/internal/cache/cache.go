@@ -0,0 +1,455 @@
+// Package cache keeps a local copy of the snippet list and the edits made
+// while the server was unreachable, so snippy keeps working offline until
+// `snippy sync` uploads them.
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MohamedElashri/snipo/tui/internal/api"
+	"github.com/MohamedElashri/snipo/tui/internal/config"
+)
+
+// localPrefix marks the IDs of snippets created offline; the server assigns
+// the real ID when they are synced
+const localPrefix = "local-"
+
+// IsLocal reports whether id belongs to a snippet created offline that
+// hasn't been synced yet
+func IsLocal(id string) bool {
+	return strings.HasPrefix(id, localPrefix)
+}
+
+// Op is the kind of a queued change
+type Op string
+
+const (
+	OpCreate Op = "create"
+	OpUpdate Op = "update"
+	OpDelete Op = "delete"
+)
+
+// Change is an edit made offline, waiting to be sent to the server
+type Change struct {
+	Op       Op                `json:"op"`
+	ID       string            `json:"id"`
+	Title    string            `json:"title"`
+	Input    *api.SnippetInput `json:"input,omitempty"`
+	Base     time.Time         `json:"base"` // Server updated_at the edit was made against; zero for creates
+	QueuedAt time.Time         `json:"queued_at"`
+}
+
+// Store is the offline cache. It is safe for concurrent use.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	data storeData
+}
+
+type storeData struct {
+	Snippets []api.Snippet `json:"snippets"` // Server copies as last seen
+	Pending  []Change      `json:"pending,omitempty"`
+	SyncedAt time.Time     `json:"synced_at"`
+}
+
+// Path returns the cache file location, next to the config file
+func Path() (string, error) {
+	configPath, err := config.GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "offline.json"), nil
+}
+
+// Open loads the cache, starting an empty one when there is no file yet
+func Open() (*Store, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read offline cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.data); err != nil {
+		return nil, fmt.Errorf("failed to parse offline cache: %w", err)
+	}
+	return s, nil
+}
+
+// save writes the cache; the caller holds the lock
+func (s *Store) save() error {
+	data, err := json.Marshal(s.data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offline cache: %w", err)
+	}
+	// Write then rename so a crash never leaves a truncated cache behind
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write offline cache: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write offline cache: %w", err)
+	}
+	return nil
+}
+
+// Put records server copies of snippets, as loaded while online
+func (s *Store) Put(snippets ...api.Snippet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, snippet := range snippets {
+		s.put(snippet)
+	}
+	return s.save()
+}
+
+func (s *Store) put(snippet api.Snippet) {
+	for i, cached := range s.data.Snippets {
+		if cached.ID != snippet.ID {
+			continue
+		}
+		// List results leave files out; keep the ones loaded with the snippet
+		if len(snippet.Files) == 0 && cached.UpdatedAt.Equal(snippet.UpdatedAt) {
+			snippet.Files = cached.Files
+		}
+		s.data.Snippets[i] = snippet
+		return
+	}
+	s.data.Snippets = append(s.data.Snippets, snippet)
+}
+
+// Remove drops a snippet that was deleted on the server
+func (s *Store) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(id)
+	return s.save()
+}
+
+func (s *Store) remove(id string) {
+	for i, cached := range s.data.Snippets {
+		if cached.ID == id {
+			s.data.Snippets = append(s.data.Snippets[:i], s.data.Snippets[i+1:]...)
+			return
+		}
+	}
+}
+
+// Pending returns the queued changes, oldest first
+func (s *Store) Pending() []Change {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Change(nil), s.data.Pending...)
+}
+
+// QueueCreate records a snippet created offline and returns its local copy
+func (s *Store) QueueCreate(input api.SnippetInput) (*api.Snippet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	change := Change{
+		Op:       OpCreate,
+		ID:       fmt.Sprintf("%s%d", localPrefix, now.UnixNano()),
+		Title:    input.Title,
+		Input:    &input,
+		QueuedAt: now,
+	}
+	s.data.Pending = append(s.data.Pending, change)
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	snippet := apply(api.Snippet{ID: change.ID, CreatedAt: now}, change)
+	return &snippet, nil
+}
+
+// QueueUpdate records an offline edit of snippet and returns its local copy.
+// Edits to a snippet that already has queued changes replace them, keeping
+// the version the first edit was made against.
+func (s *Store) QueueUpdate(snippet api.Snippet, input api.SnippetInput) (*api.Snippet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	change := Change{
+		Op:       OpUpdate,
+		ID:       snippet.ID,
+		Title:    input.Title,
+		Input:    &input,
+		Base:     snippet.UpdatedAt,
+		QueuedAt: now,
+	}
+	if i := s.pendingIndex(snippet.ID); i >= 0 {
+		prev := s.data.Pending[i]
+		change.Base = prev.Base
+		if prev.Op == OpCreate {
+			change.Op = OpCreate
+		}
+		s.data.Pending[i] = change
+	} else {
+		s.data.Pending = append(s.data.Pending, change)
+	}
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	updated := apply(snippet, change)
+	return &updated, nil
+}
+
+// QueueDelete records an offline delete. Deleting a snippet created offline
+// just drops it.
+func (s *Store) QueueDelete(snippet api.Snippet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change := Change{
+		Op:       OpDelete,
+		ID:       snippet.ID,
+		Title:    snippet.Title,
+		Base:     snippet.UpdatedAt,
+		QueuedAt: time.Now(),
+	}
+	if i := s.pendingIndex(snippet.ID); i >= 0 {
+		prev := s.data.Pending[i]
+		s.data.Pending = append(s.data.Pending[:i], s.data.Pending[i+1:]...)
+		if prev.Op == OpCreate {
+			return s.save()
+		}
+		change.Base = prev.Base
+	}
+	s.data.Pending = append(s.data.Pending, change)
+	return s.save()
+}
+
+func (s *Store) pendingIndex(id string) int {
+	for i, change := range s.data.Pending {
+		if change.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get returns the local copy of a snippet, with queued changes applied
+func (s *Store) Get(id string) (*api.Snippet, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, snippet := range s.local() {
+		if snippet.ID == id {
+			return &snippet, true
+		}
+	}
+	return nil, false
+}
+
+// local returns the cached snippets as they look with queued changes
+// applied; the caller holds the lock
+func (s *Store) local() []api.Snippet {
+	snippets := append([]api.Snippet(nil), s.data.Snippets...)
+	for _, change := range s.data.Pending {
+		switch change.Op {
+		case OpCreate:
+			snippets = append(snippets, apply(api.Snippet{ID: change.ID, CreatedAt: change.QueuedAt}, change))
+		case OpUpdate:
+			for i := range snippets {
+				if snippets[i].ID == change.ID {
+					snippets[i] = apply(snippets[i], change)
+				}
+			}
+		case OpDelete:
+			for i := range snippets {
+				if snippets[i].ID == change.ID {
+					snippets = append(snippets[:i], snippets[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	return snippets
+}
+
+// apply returns snippet with a queued create or update applied
+func apply(snippet api.Snippet, change Change) api.Snippet {
+	in := change.Input
+	if in == nil {
+		return snippet
+	}
+
+	snippet.Title = in.Title
+	snippet.Description = in.Description
+	snippet.Language = in.Language
+	snippet.Content = in.Content
+	snippet.IsPublic = in.IsPublic
+	snippet.IsArchived = in.IsArchived
+	snippet.ExpiresAt = in.ExpiresAt
+	snippet.PublishAt = in.PublishAt
+	snippet.UpdatedAt = change.QueuedAt
+
+	if in.Tags != nil {
+		known := make(map[string]api.Tag, len(snippet.Tags))
+		for _, tag := range snippet.Tags {
+			known[tag.Name] = tag
+		}
+		tags := make([]api.Tag, 0, len(in.Tags))
+		for _, name := range in.Tags {
+			tag, ok := known[name]
+			if !ok {
+				tag = api.Tag{Name: name}
+			}
+			tags = append(tags, tag)
+		}
+		snippet.Tags = tags
+	}
+	if in.FolderID != nil {
+		id := int(*in.FolderID)
+		snippet.FolderID = &id
+		snippet.Folders = nil
+	}
+	if in.Files != nil {
+		files := make([]api.File, 0, len(in.Files))
+		for _, f := range in.Files {
+			files = append(files, api.File{
+				SnippetID: snippet.ID,
+				Filename:  f.Filename,
+				Content:   f.Content,
+				Language:  f.Language,
+				Note:      f.Note,
+				Size:      len(f.Content),
+			})
+		}
+		snippet.Files = files
+	}
+	return snippet
+}
+
+// List filters, sorts and pages the local snippets the way the server
+// would for opts. The search is a plain substring match on every word.
+func (s *Store) List(opts api.ListOptions) ([]api.Snippet, *api.Pagination) {
+	s.mu.Lock()
+	all := s.local()
+	s.mu.Unlock()
+
+	var matched []api.Snippet
+	for _, snippet := range all {
+		if matches(snippet, opts) {
+			matched = append(matched, snippet)
+		}
+	}
+	sortSnippets(matched, opts.SortBy, opts.SortOrder)
+
+	page, limit := max(opts.Page, 1), opts.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	total := len(matched)
+	start := min((page-1)*limit, total)
+	end := min(start+limit, total)
+
+	pagination := &api.Pagination{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: (total + limit - 1) / limit,
+	}
+	return matched[start:end], pagination
+}
+
+func matches(snippet api.Snippet, opts api.ListOptions) bool {
+	// Trashed snippets aren't cached
+	if opts.Deleted != nil && *opts.Deleted {
+		return false
+	}
+	archived := opts.Archived != nil && *opts.Archived
+	if snippet.IsArchived != archived {
+		return false
+	}
+	if opts.Favorite != nil && snippet.IsFavorite != *opts.Favorite {
+		return false
+	}
+	if opts.Language != "" && !strings.EqualFold(snippet.Language, opts.Language) {
+		return false
+	}
+	if len(opts.TagIDs) > 0 && !hasAnyTag(snippet, opts.TagIDs) {
+		return false
+	}
+	if len(opts.FolderIDs) > 0 {
+		folder := snippet.Folder()
+		if folder == nil || !containsInt(opts.FolderIDs, *folder) {
+			return false
+		}
+	}
+	if opts.Query != "" {
+		text := strings.ToLower(searchText(snippet))
+		for _, word := range strings.Fields(strings.ToLower(opts.Query)) {
+			if !strings.Contains(text, word) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func hasAnyTag(snippet api.Snippet, ids []int) bool {
+	for _, tag := range snippet.Tags {
+		if containsInt(ids, tag.ID) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func searchText(snippet api.Snippet) string {
+	parts := []string{snippet.Title, snippet.Description, snippet.Content}
+	for _, f := range snippet.Files {
+		parts = append(parts, f.Filename, f.Content)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// sortSnippets orders snippets like the list endpoint. last_used isn't
+// cached, so it falls back to updated_at.
+func sortSnippets(snippets []api.Snippet, by, order string) {
+	less := func(a, b api.Snippet) bool { return a.UpdatedAt.Before(b.UpdatedAt) }
+	switch by {
+	case "created_at":
+		less = func(a, b api.Snippet) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	case "title":
+		less = func(a, b api.Snippet) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) }
+	case "view_count":
+		less = func(a, b api.Snippet) bool { return a.ViewCount < b.ViewCount }
+	case "use_count":
+		less = func(a, b api.Snippet) bool { return a.UseCount < b.UseCount }
+	}
+	desc := order != "asc"
+	sort.SliceStable(snippets, func(i, j int) bool {
+		if desc {
+			return less(snippets[j], snippets[i])
+		}
+		return less(snippets[i], snippets[j])
+	})
+}
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/tui/internal/api"
+)
+
+// openTestStore opens an empty cache under a temporary home directory
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	s, err := Open()
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	return s
+}
+
+func TestOfflineQueue(t *testing.T) {
+	now := time.Now()
+	server := []api.Snippet{
+		{ID: "a", Title: "alpha", Content: "a", CreatedAt: now.Add(-3 * time.Hour), UpdatedAt: now.Add(-3 * time.Hour)},
+		{ID: "b", Title: "beta", Content: "b", CreatedAt: now.Add(-2 * time.Hour), UpdatedAt: now.Add(-2 * time.Hour)},
+		{ID: "c", Title: "gamma", Content: "c", CreatedAt: now.Add(-1 * time.Hour), UpdatedAt: now.Add(-1 * time.Hour)},
+	}
+
+	create := func(t *testing.T, s *Store, title string) *api.Snippet {
+		t.Helper()
+		snippet, err := s.QueueCreate(api.SnippetInput{Title: title, Content: title})
+		if err != nil {
+			t.Fatalf("QueueCreate failed: %v", err)
+		}
+		return snippet
+	}
+	update := func(t *testing.T, s *Store, snippet api.Snippet, title string) *api.Snippet {
+		t.Helper()
+		input := snippet.Input()
+		input.Title = title
+		updated, err := s.QueueUpdate(snippet, input)
+		if err != nil {
+			t.Fatalf("QueueUpdate failed: %v", err)
+		}
+		return updated
+	}
+	remove := func(t *testing.T, s *Store, snippet api.Snippet) {
+		t.Helper()
+		if err := s.QueueDelete(snippet); err != nil {
+			t.Fatalf("QueueDelete failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		run         func(t *testing.T, s *Store)
+		opts        api.ListOptions
+		wantPending []Op
+		wantTitles  []string
+	}{
+		{
+			name:       "nothing queued",
+			wantTitles: []string{"gamma", "beta", "alpha"},
+		},
+		{
+			name: "create then delete while offline",
+			run: func(t *testing.T, s *Store) {
+				draft := create(t, s, "draft")
+				remove(t, s, *draft)
+			},
+			wantTitles: []string{"gamma", "beta", "alpha"},
+		},
+		{
+			name: "update of an offline-created snippet",
+			run: func(t *testing.T, s *Store) {
+				draft := create(t, s, "draft")
+				if !IsLocal(draft.ID) {
+					t.Errorf("expected a local ID, got %q", draft.ID)
+				}
+				updated := update(t, s, *draft, "final")
+				if updated.ID != draft.ID {
+					t.Errorf("expected the local ID to be kept, got %q", updated.ID)
+				}
+			},
+			wantPending: []Op{OpCreate},
+			wantTitles:  []string{"final", "gamma", "beta", "alpha"},
+		},
+		{
+			name: "updates of a server snippet are merged",
+			run: func(t *testing.T, s *Store) {
+				first := update(t, s, server[0], "alpha v2")
+				update(t, s, *first, "alpha v3")
+				if pending := s.Pending(); len(pending) == 1 && !pending[0].Base.Equal(server[0].UpdatedAt) {
+					t.Errorf("expected the base of the first edit, got %v", pending[0].Base)
+				}
+			},
+			wantPending: []Op{OpUpdate},
+			wantTitles:  []string{"alpha v3", "gamma", "beta"},
+		},
+		{
+			name: "update then delete of a server snippet",
+			run: func(t *testing.T, s *Store) {
+				updated := update(t, s, server[1], "beta v2")
+				remove(t, s, *updated)
+				if pending := s.Pending(); len(pending) == 1 && !pending[0].Base.Equal(server[1].UpdatedAt) {
+					t.Errorf("expected the delete to keep the base of the edit, got %v", pending[0].Base)
+				}
+			},
+			wantPending: []Op{OpDelete},
+			wantTitles:  []string{"gamma", "alpha"},
+		},
+		{
+			name: "list order after the merge",
+			run: func(t *testing.T, s *Store) {
+				create(t, s, "new")
+				update(t, s, server[0], "alpha v2")
+			},
+			wantPending: []Op{OpCreate, OpUpdate},
+			wantTitles:  []string{"alpha v2", "new", "gamma", "beta"},
+		},
+		{
+			name: "list order by title after the merge",
+			run: func(t *testing.T, s *Store) {
+				create(t, s, "delta")
+				update(t, s, server[2], "aardvark")
+			},
+			opts:        api.ListOptions{SortBy: "title", SortOrder: "asc"},
+			wantPending: []Op{OpCreate, OpUpdate},
+			wantTitles:  []string{"aardvark", "alpha", "beta", "delta"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := openTestStore(t)
+			if err := s.Put(server...); err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+			if tt.run != nil {
+				tt.run(t, s)
+			}
+
+			// The queue survives a restart
+			reopened, err := Open()
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			var ops []Op
+			for _, change := range reopened.Pending() {
+				ops = append(ops, change.Op)
+			}
+			if !slices.Equal(ops, tt.wantPending) {
+				t.Errorf("expected pending %v, got %v", tt.wantPending, ops)
+			}
+
+			list, pagination := reopened.List(tt.opts)
+			var titles []string
+			for _, snippet := range list {
+				titles = append(titles, snippet.Title)
+			}
+			if !slices.Equal(titles, tt.wantTitles) {
+				t.Errorf("expected %v, got %v", tt.wantTitles, titles)
+			}
+			if pagination.Total != len(tt.wantTitles) {
+				t.Errorf("expected a total of %d, got %d", len(tt.wantTitles), pagination.Total)
+			}
+		})
+	}
+}
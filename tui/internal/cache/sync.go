@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MohamedElashri/snipo/tui/internal/api"
+)
+
+// pullPageSize is the page size used to download the snippet list
+const pullPageSize = 100
+
+// Resolution says what to do with a change whose snippet was edited on the
+// server since it was queued
+type Resolution int
+
+const (
+	KeepConflicts    Resolution = iota // Leave the change queued for a later sync
+	ForceConflicts                     // Overwrite the server copy
+	DiscardConflicts                   // Drop the change and keep the server copy
+)
+
+// Conflict is a queued change that wasn't pushed
+type Conflict struct {
+	ID     string
+	Title  string
+	Reason string
+}
+
+// Report summarises a sync run
+type Report struct {
+	Pushed    int
+	Discarded int
+	Pulled    int
+	Conflicts []Conflict
+}
+
+// Sync pushes the queued changes to the server, oldest first, then replaces
+// the cached snippets with the server's. A change to a snippet that was
+// edited or deleted on the server since it was queued is a conflict and is
+// handled as resolve says. Sync stops at the first error, keeping the
+// changes not yet pushed.
+func Sync(client *api.Client, s *Store, resolve Resolution) (*Report, error) {
+	report := &Report{}
+
+	for _, change := range s.Pending() {
+		pushed, reason, err := push(client, change, resolve)
+		if err != nil {
+			return report, fmt.Errorf("failed to sync %q: %w", change.Title, err)
+		}
+		switch {
+		case pushed:
+			report.Pushed++
+		case resolve == DiscardConflicts:
+			report.Discarded++
+		default:
+			report.Conflicts = append(report.Conflicts, Conflict{ID: change.ID, Title: change.Title, Reason: reason})
+			continue
+		}
+		if err := s.dequeue(change); err != nil {
+			return report, err
+		}
+	}
+
+	pulled, err := pull(client, s)
+	if err != nil {
+		return report, err
+	}
+	report.Pulled = pulled
+	return report, nil
+}
+
+// push sends one change. It reports false with a reason when the change
+// conflicts with the server copy and wasn't forced.
+func push(client *api.Client, change Change, resolve Resolution) (pushed bool, reason string, err error) {
+	if change.Op == OpCreate {
+		_, err := client.CreateSnippet(*change.Input)
+		return err == nil, "", err
+	}
+
+	current, err := client.GetSnippet(change.ID)
+	switch {
+	case api.IsNotFound(err):
+		if change.Op == OpDelete {
+			return true, "", nil // Already gone
+		}
+		if resolve != ForceConflicts {
+			return false, "deleted on the server", nil
+		}
+		// Recreate it from the local copy
+		_, err := client.CreateSnippet(*change.Input)
+		return err == nil, "", err
+	case err != nil:
+		return false, "", err
+	case !current.UpdatedAt.Equal(change.Base) && resolve != ForceConflicts:
+		return false, "changed on the server " + current.UpdatedAt.Local().Format(time.DateTime), nil
+	}
+
+	if change.Op == OpDelete {
+		err = client.DeleteSnippet(change.ID)
+	} else {
+		_, err = client.UpdateSnippet(change.ID, *change.Input)
+	}
+	return err == nil, "", err
+}
+
+// dequeue removes a change once it has been handled
+func (s *Store) dequeue(change Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.pendingIndex(change.ID); i >= 0 {
+		s.data.Pending = append(s.data.Pending[:i], s.data.Pending[i+1:]...)
+	}
+	return s.save()
+}
+
+// pull downloads every active and archived snippet, fetching the full copy
+// of those that are new or changed since they were cached, and returns how
+// many were fetched
+func pull(client *api.Client, s *Store) (int, error) {
+	s.mu.Lock()
+	cached := make(map[string]api.Snippet, len(s.data.Snippets))
+	for _, snippet := range s.data.Snippets {
+		cached[snippet.ID] = snippet
+	}
+	s.mu.Unlock()
+
+	var snippets []api.Snippet
+	fetched := 0
+	for _, archived := range []bool{false, true} {
+		for page := 1; ; page++ {
+			opts := api.ListOptions{Page: page, Limit: pullPageSize, Archived: &archived}
+			list, _, err := client.ListSnippets(opts)
+			if err != nil {
+				return fetched, fmt.Errorf("failed to download snippets: %w", err)
+			}
+			for _, item := range list {
+				if old, ok := cached[item.ID]; ok && old.UpdatedAt.Equal(item.UpdatedAt) {
+					snippets = append(snippets, old)
+					continue
+				}
+				full, err := client.GetSnippet(item.ID)
+				if err != nil {
+					return fetched, fmt.Errorf("failed to download %q: %w", item.Title, err)
+				}
+				snippets = append(snippets, *full)
+				fetched++
+			}
+			if len(list) < pullPageSize {
+				break
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Snippets = snippets
+	s.data.SyncedAt = time.Now()
+	return fetched, s.save()
+}
@@ -5,11 +5,26 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 )
 
+// DefaultTabWidth is the tab stop used to display code when tab_width isn't set
+const DefaultTabWidth = 4
+
+// DefaultRefreshInterval is how often the list is reloaded in the background
+// when refresh_interval isn't set
+const DefaultRefreshInterval = 30 * time.Second
+
+// minRefreshInterval keeps a small refresh_interval from hammering the server
+const minRefreshInterval = 5 * time.Second
+
 type Config struct {
 	ServerURL string `json:"server_url"`
 	APIKey    string `json:"api_key"`
+	TabWidth  int    `json:"tab_width,omitempty"` // Columns per tab stop in code views
+
+	// Seconds between background list refreshes; negative turns them off
+	RefreshInterval int `json:"refresh_interval,omitempty"`
 }
 
 func GetConfigPath() (string, error) {
@@ -66,6 +81,26 @@ func (c *Config) Save() error {
 	return nil
 }
 
+// Tabs returns the tab stop width, falling back to DefaultTabWidth when unset
+// or out of range
+func (c *Config) Tabs() int {
+	if c.TabWidth < 1 || c.TabWidth > 16 {
+		return DefaultTabWidth
+	}
+	return c.TabWidth
+}
+
+// Refresh returns the background refresh interval, or 0 when it is disabled
+func (c *Config) Refresh() time.Duration {
+	switch {
+	case c.RefreshInterval < 0:
+		return 0
+	case c.RefreshInterval == 0:
+		return DefaultRefreshInterval
+	}
+	return max(time.Duration(c.RefreshInterval)*time.Second, minRefreshInterval)
+}
+
 func (c *Config) IsConfigured() bool {
 	return c.ServerURL != "" && c.APIKey != ""
 }
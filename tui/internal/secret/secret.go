@@ -0,0 +1,204 @@
+// Package secret encrypts snippet content on the client with a passphrase,
+// so the server only ever stores ciphertext for secret snippets.
+//
+// Encrypted text is an armored block holding a version byte, the PBKDF2
+// salt, the AES-GCM nonce and the ciphertext. Each session encrypts with one
+// salt so the key is derived once; decrypting caches a key per salt seen.
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	header = "-----BEGIN SNIPPY SECRET-----"
+	footer = "-----END SNIPPY SECRET-----"
+
+	version    = 1
+	saltSize   = 16
+	keySize    = 32
+	iterations = 100000
+	lineWidth  = 64
+)
+
+var (
+	// ErrLocked is returned when encrypting or decrypting before a
+	// passphrase was entered
+	ErrLocked = errors.New("secret snippets are locked")
+
+	// ErrWrongPassphrase is returned when text can't be decrypted with the
+	// session passphrase
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+)
+
+// IsEncrypted reports whether text is an encrypted block
+func IsEncrypted(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), header)
+}
+
+// Session holds the passphrase for as long as snippy runs. It is safe for
+// concurrent use.
+type Session struct {
+	mu         sync.Mutex
+	passphrase string
+	salt       []byte            // Salt used to encrypt in this session
+	keys       map[string][]byte // Derived keys by salt
+}
+
+// NewSession returns a locked session
+func NewSession() *Session {
+	return &Session{}
+}
+
+// Unlock sets the passphrase, forgetting keys derived from an earlier one
+func (s *Session) Unlock(passphrase string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.passphrase = passphrase
+	s.salt = nil
+	s.keys = make(map[string][]byte)
+}
+
+// Lock forgets the passphrase and every key derived from it
+func (s *Session) Lock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.passphrase = ""
+	s.salt = nil
+	s.keys = nil
+}
+
+// Unlocked reports whether a passphrase was entered
+func (s *Session) Unlocked() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.passphrase != ""
+}
+
+// key returns the key for salt, deriving it on first use; the caller holds
+// the lock
+func (s *Session) key(salt []byte) ([]byte, error) {
+	if key, ok := s.keys[string(salt)]; ok {
+		return key, nil
+	}
+	key, err := pbkdf2.Key(sha256.New, s.passphrase, salt, iterations, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	s.keys[string(salt)] = key
+	return key, nil
+}
+
+// Encrypt returns plaintext as an encrypted block
+func (s *Session) Encrypt(plaintext string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.passphrase == "" {
+		return "", ErrLocked
+	}
+
+	if s.salt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return "", fmt.Errorf("failed to generate salt: %w", err)
+		}
+		s.salt = salt
+	}
+	key, err := s.key(s.salt)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	payload := append([]byte{version}, s.salt...)
+	payload = append(payload, nonce...)
+	payload = gcm.Seal(payload, nonce, []byte(plaintext), nil)
+	return armor(payload), nil
+}
+
+// Decrypt returns the plaintext of an encrypted block
+func (s *Session) Decrypt(text string) (string, error) {
+	payload, err := unarmor(text)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.passphrase == "" {
+		return "", ErrLocked
+	}
+
+	salt, rest := payload[1:1+saltSize], payload[1+saltSize:]
+	key, err := s.key(salt)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(rest) < gcm.NonceSize() {
+		return "", errors.New("encrypted text is truncated")
+	}
+	plaintext, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrWrongPassphrase
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// armor wraps payload in the header and footer lines
+func armor(payload []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(payload)
+	var b strings.Builder
+	b.WriteString(header + "\n")
+	for len(encoded) > lineWidth {
+		b.WriteString(encoded[:lineWidth] + "\n")
+		encoded = encoded[lineWidth:]
+	}
+	b.WriteString(encoded + "\n")
+	b.WriteString(footer)
+	return b.String()
+}
+
+// unarmor decodes an encrypted block and checks its version
+func unarmor(text string) ([]byte, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, header) || !strings.HasSuffix(text, footer) {
+		return nil, errors.New("text is not an encrypted block")
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(text, header), footer)
+	payload, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(body), ""))
+	if err != nil {
+		return nil, fmt.Errorf("encrypted block is corrupt: %w", err)
+	}
+	if len(payload) < 1+saltSize || payload[0] != version {
+		return nil, errors.New("encrypted block has an unsupported version")
+	}
+	return payload, nil
+}
@@ -0,0 +1,136 @@
+package secret
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func unlocked(passphrase string) *Session {
+	s := NewSession()
+	s.Unlock(passphrase)
+	return s
+}
+
+func TestRoundTrip(t *testing.T) {
+	s := unlocked("correct horse")
+	for _, plaintext := range []string{
+		"",
+		"echo hello",
+		"line one\nline two\n",
+		"ünïcödé ✓ " + strings.Repeat("long ", 100),
+	} {
+		encrypted, err := s.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt failed: %v", err)
+		}
+		if !IsEncrypted(encrypted) {
+			t.Errorf("expected an encrypted block, got %q", encrypted)
+		}
+		if plaintext != "" && strings.Contains(encrypted, plaintext) {
+			t.Errorf("expected the plaintext to be hidden, got %q", encrypted)
+		}
+		for _, line := range strings.Split(encrypted, "\n") {
+			if len(line) > lineWidth && line != header && line != footer {
+				t.Errorf("expected lines of at most %d characters, got %d", lineWidth, len(line))
+			}
+		}
+
+		// Another session with the same passphrase derives the same key
+		for _, session := range []*Session{s, unlocked("correct horse")} {
+			decrypted, err := session.Decrypt(encrypted)
+			if err != nil {
+				t.Fatalf("Decrypt failed: %v", err)
+			}
+			if decrypted != plaintext {
+				t.Errorf("expected %q, got %q", plaintext, decrypted)
+			}
+		}
+	}
+
+	// The same text encrypts differently every time
+	first, _ := s.Encrypt("same")
+	second, _ := s.Encrypt("same")
+	if first == second {
+		t.Error("expected a fresh nonce for every encryption")
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	encrypted, err := unlocked("correct horse").Encrypt("secret")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if _, err := unlocked("battery staple").Decrypt(encrypted); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+
+	// Unlocking again with another passphrase forgets the keys of the first
+	s := unlocked("correct horse")
+	if _, err := s.Decrypt(encrypted); err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	s.Unlock("battery staple")
+	if _, err := s.Decrypt(encrypted); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase after changing the passphrase, got %v", err)
+	}
+}
+
+func TestLocked(t *testing.T) {
+	s := NewSession()
+	if _, err := s.Encrypt("secret"); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked encrypting, got %v", err)
+	}
+
+	s.Unlock("correct horse")
+	encrypted, err := s.Encrypt("secret")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	s.Lock()
+	if s.Unlocked() {
+		t.Error("expected the session to be locked")
+	}
+	if _, err := s.Decrypt(encrypted); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked decrypting, got %v", err)
+	}
+}
+
+func TestDamagedCiphertext(t *testing.T) {
+	s := unlocked("correct horse")
+	encrypted, err := s.Encrypt("echo hello")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	payload, err := unarmor(encrypted)
+	if err != nil {
+		t.Fatalf("unarmor failed: %v", err)
+	}
+	flip := func(i int) string {
+		damaged := append([]byte(nil), payload...)
+		damaged[i] ^= 0x01
+		return armor(damaged)
+	}
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"no footer", strings.TrimSuffix(encrypted, footer)},
+		{"not base64", header + "\n!!!\n" + footer},
+		{"only the version", armor(payload[:1])},
+		{"truncated nonce", armor(payload[:1+saltSize+4])},
+		{"truncated ciphertext", armor(payload[:len(payload)-4])},
+		{"unknown version", flip(0)},
+		{"tampered salt", flip(1)},
+		{"tampered nonce", flip(1 + saltSize)},
+		{"tampered ciphertext", flip(len(payload) - 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if plaintext, err := s.Decrypt(tt.text); err == nil {
+				t.Errorf("expected an error, got %q", plaintext)
+			}
+		})
+	}
+}
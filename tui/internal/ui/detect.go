@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+// shebangLanguages maps script interpreters to snippet languages
+var shebangLanguages = map[string]string{
+	"bash":    "bash",
+	"sh":      "bash",
+	"zsh":     "shell",
+	"python":  "python",
+	"python3": "python",
+	"node":    "javascript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+	"lua":     "lua",
+	"pwsh":    "powershell",
+	"Rscript": "r",
+}
+
+// contentPatterns are tried in order when neither a shebang nor chroma
+// recognises the content; the first match wins
+var contentPatterns = []struct {
+	language string
+	pattern  *regexp.Regexp
+}{
+	{"php", regexp.MustCompile(`^\s*<\?php`)},
+	{"go", regexp.MustCompile(`(?m)^package \w+\s*$|^func (\(\w+ \*?\w+\) )?\w+\(`)},
+	{"rust", regexp.MustCompile(`(?m)^\s*(pub )?fn \w+|let mut |^use \w+::`)},
+	{"python", regexp.MustCompile(`(?m)^\s*(def \w+\(.*\):|class \w+.*:|from [\w.]+ import |import \w+$)`)},
+	{"cpp", regexp.MustCompile(`(?m)^#include <(iostream|vector|string|map)>|std::`)},
+	{"c", regexp.MustCompile(`(?m)^#include [<"]`)},
+	{"java", regexp.MustCompile(`(?m)^\s*public (static )?(class|void|interface) `)},
+	{"typescript", regexp.MustCompile(`(?m)^\s*(interface \w+ \{|type \w+ = |.*: (string|number|boolean)[;,)=])`)},
+	{"javascript", regexp.MustCompile(`(?m)^\s*(const|let|var) \w+ = |=> \{|console\.log\(|require\(`)},
+	{"dockerfile", regexp.MustCompile(`(?m)^FROM \S+`)},
+	{"sql", regexp.MustCompile(`(?i)^\s*(SELECT .+ FROM|INSERT INTO|UPDATE \w+ SET|CREATE TABLE|DELETE FROM)`)},
+	{"html", regexp.MustCompile(`(?i)^\s*(<!DOCTYPE html|<html)`)},
+	{"xml", regexp.MustCompile(`^\s*<\?xml`)},
+	{"yaml", regexp.MustCompile(`(?m)^(---\s*$|[\w-]+:( .*)?$)`)},
+	{"markdown", regexp.MustCompile(`(?m)^#{1,6} \S|^\s*[-*] \[[ x]\] `)},
+	{"bash", regexp.MustCompile(`(?m)^\s*(echo |export \w+=|if \[|sudo |apt(-get)? |cd |ls )`)},
+}
+
+// DetectLanguage guesses the language of pasted content, returning "" when
+// unsure. Only languages in allowed are returned unless allowed is empty.
+func DetectLanguage(content string, allowed []string) string {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return ""
+	}
+	ok := func(language string) bool {
+		return language != "" && (len(allowed) == 0 || slices.Contains(allowed, language))
+	}
+
+	// A shebang is the most reliable hint
+	if first, _, _ := strings.Cut(content, "\n"); strings.HasPrefix(first, "#!") {
+		fields := strings.Fields(strings.TrimPrefix(first, "#!"))
+		if len(fields) > 0 {
+			interpreter := path.Base(fields[0])
+			if interpreter == "env" && len(fields) > 1 {
+				interpreter = fields[1]
+			}
+			if language := shebangLanguages[interpreter]; ok(language) {
+				return language
+			}
+		}
+	}
+
+	if (content[0] == '{' || content[0] == '[') && json.Valid([]byte(content)) && ok("json") {
+		return "json"
+	}
+
+	if lexer := lexers.Analyse(content); lexer != nil {
+		for _, alias := range lexer.Config().Aliases {
+			if ok(alias) {
+				return alias
+			}
+		}
+	}
+
+	for _, p := range contentPatterns {
+		if p.pattern.MatchString(content) && ok(p.language) {
+			return p.language
+		}
+	}
+	return ""
+}
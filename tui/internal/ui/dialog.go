@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/MohamedElashri/snipo/tui/internal/api"
+	"github.com/MohamedElashri/snipo/tui/internal/cache"
+)
+
+// undoWindow is how long the undo toast stays after a destructive action
+const undoWindow = 6 * time.Second
+
+var (
+	dialogStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("1")). // Red (ANSI 1) - destructive
+			Padding(0, 2)
+
+	toastStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("0")). // Black text (ANSI 0)
+			Background(lipgloss.Color("3")). // Yellow background (ANSI 3)
+			Padding(0, 1)
+)
+
+// confirmDialog asks for y/n before running a destructive action
+type confirmDialog struct {
+	prompt string
+	action tea.Cmd
+}
+
+// update handles a key while the dialog is open, returning the action once
+// confirmed and whether the dialog should close
+func (d *confirmDialog) update(msg tea.KeyMsg) (cmd tea.Cmd, closed bool) {
+	switch msg.String() {
+	case "y", "Y", "enter":
+		return d.action, true
+	case "n", "N", "esc", "q":
+		return nil, true
+	}
+	return nil, false
+}
+
+func (d *confirmDialog) view() string {
+	return dialogStyle.Render(d.prompt + "\n\n" + renderHelpText("y confirm • n cancel"))
+}
+
+// toast is a short-lived notice, optionally offering to undo the action
+// that raised it
+type toast struct {
+	id      int
+	message string
+	undo    tea.Cmd
+}
+
+type toastExpiredMsg struct{ id int }
+
+// showToast replaces any current toast and schedules its removal
+func (m *Model) showToast(message string, undo tea.Cmd) tea.Cmd {
+	m.toastSeq++
+	m.toast = &toast{id: m.toastSeq, message: message, undo: undo}
+	id := m.toastSeq
+	return tea.Tick(undoWindow, func(time.Time) tea.Msg { return toastExpiredMsg{id: id} })
+}
+
+func (t *toast) view() string {
+	text := t.message
+	if t.undo != nil {
+		text += " • u undo"
+	}
+	return toastStyle.Render(text)
+}
+
+// snippetDeletedMsg reports a deleted snippet so it can be restored from
+// the trash during the undo window
+type snippetDeletedMsg struct{ snippet api.Snippet }
+
+// confirmDelete asks before moving a snippet to the trash
+func (m *Model) confirmDelete(snippet api.Snippet) {
+	m.confirm = &confirmDialog{
+		prompt: fmt.Sprintf("Delete %q?", snippet.Title),
+		action: deleteSnippet(m.client, m.cache, snippet),
+	}
+}
+
+// deleteSnippet moves a snippet to the trash, queuing the delete in the
+// offline cache when the server can't be reached. Offline deletes can't be
+// undone from the toast.
+func deleteSnippet(client *api.Client, store *cache.Store, snippet api.Snippet) tea.Cmd {
+	return func() tea.Msg {
+		if store == nil || !cache.IsLocal(snippet.ID) {
+			err := client.DeleteSnippet(snippet.ID)
+			if err == nil {
+				if store != nil {
+					_ = store.Remove(snippet.ID)
+				}
+				return snippetDeletedMsg{snippet: snippet}
+			}
+			if store == nil || !api.IsConnectionError(err) {
+				return errMsg{err}
+			}
+		}
+		if err := store.QueueDelete(snippet); err != nil {
+			return errMsg{err}
+		}
+		return successMsg{message: offlineSaved("Deleted", snippet.Title)}
+	}
+}
+
+// restoreSnippet undoes a delete; it fails when the trash is disabled and
+// the snippet is already gone
+func restoreSnippet(client *api.Client, snippet api.Snippet) tea.Cmd {
+	return func() tea.Msg {
+		if err := client.RestoreSnippet(snippet.ID); err != nil {
+			return errMsg{fmt.Errorf("could not undo: %w", err)}
+		}
+		return successMsg{message: fmt.Sprintf("Restored snippet: %s", snippet.Title)}
+	}
+}
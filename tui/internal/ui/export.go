@@ -0,0 +1,184 @@
+package ui
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MohamedElashri/snipo/tui/internal/api"
+)
+
+// exportPageSize is the page size used to fetch every snippet in a list
+const exportPageSize = 100
+
+// exportedSnippet is one snippet in an export. Content and files are only
+// filled in when the export includes content.
+type exportedSnippet struct {
+	ID          string         `json:"id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Language    string         `json:"language"`
+	Tags        []string       `json:"tags"`
+	Folder      string         `json:"folder,omitempty"`
+	IsFavorite  bool           `json:"is_favorite"`
+	IsPublic    bool           `json:"is_public"`
+	IsArchived  bool           `json:"is_archived"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	Content     string         `json:"content,omitempty"`
+	Files       []exportedFile `json:"files,omitempty"`
+}
+
+type exportedFile struct {
+	Filename string `json:"filename"`
+	Language string `json:"language"`
+	Content  string `json:"content"`
+}
+
+// exportCommands are the formats offered when exporting the list
+func (m Model) exportCommands() []paletteCommand {
+	formats := []struct {
+		label       string
+		format      string
+		withContent bool
+	}{
+		{"CSV, metadata only", "csv", false},
+		{"CSV, with content", "csv", true},
+		{"JSON, metadata only", "json", false},
+		{"JSON, with content", "json", true},
+	}
+	commands := make([]paletteCommand, 0, len(formats))
+	for _, f := range formats {
+		commands = append(commands, paletteCommand{"Export list as " + f.label, func(m *Model) tea.Cmd {
+			return exportList(m.client, m.listOptions(1), f.format, f.withContent)
+		}})
+	}
+	return commands
+}
+
+// exportList fetches every snippet the list's filters select, not just the
+// page on screen, and writes them to a timestamped file in the working
+// directory
+func exportList(client *api.Client, opts api.ListOptions, format string, withContent bool) tea.Cmd {
+	return func() tea.Msg {
+		var snippets []api.Snippet
+		opts.Limit = exportPageSize
+		for opts.Page = 1; ; opts.Page++ {
+			page, _, err := client.ListSnippets(opts)
+			if err != nil {
+				return errMsg{fmt.Errorf("export failed: %w", err)}
+			}
+			snippets = append(snippets, page...)
+			if len(page) < exportPageSize {
+				break
+			}
+		}
+
+		name := fmt.Sprintf("snippets-%s.%s", time.Now().Format("20060102-150405"), format)
+		path, err := filepath.Abs(name)
+		if err != nil {
+			path = name
+		}
+		if err := writeExport(path, snippets, format, withContent); err != nil {
+			return errMsg{fmt.Errorf("export failed: %w", err)}
+		}
+		return noticeMsg{message: fmt.Sprintf("Exported %d snippets to %s", len(snippets), path)}
+	}
+}
+
+func writeExport(path string, snippets []api.Snippet, format string, withContent bool) error {
+	exported := make([]exportedSnippet, 0, len(snippets))
+	for _, s := range snippets {
+		exported = append(exported, exportSnippet(s, withContent))
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(exported)
+	} else {
+		err = writeExportCSV(f, exported, withContent)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func exportSnippet(s api.Snippet, withContent bool) exportedSnippet {
+	e := exportedSnippet{
+		ID:          s.ID,
+		Title:       s.Title,
+		Description: s.Description,
+		Language:    s.Language,
+		Tags:        make([]string, 0, len(s.Tags)),
+		IsFavorite:  s.IsFavorite,
+		IsPublic:    s.IsPublic,
+		IsArchived:  s.IsArchived,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+	for _, t := range s.Tags {
+		e.Tags = append(e.Tags, t.Name)
+	}
+	if len(s.Folders) > 0 {
+		e.Folder = s.Folders[0].Name
+	}
+	if withContent {
+		e.Content = s.Content
+		for _, file := range s.Files {
+			e.Files = append(e.Files, exportedFile{Filename: file.Filename, Language: file.Language, Content: file.Content})
+		}
+	}
+	return e
+}
+
+// writeExportCSV writes one row per snippet. Tags are joined with ";" and the
+// files of multi-file snippets share the content column, each headed by its
+// name.
+func writeExportCSV(f *os.File, snippets []exportedSnippet, withContent bool) error {
+	w := csv.NewWriter(f)
+	header := []string{"id", "title", "description", "language", "tags", "folder", "favorite", "public", "archived", "created_at", "updated_at"}
+	if withContent {
+		header = append(header, "content")
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, s := range snippets {
+		row := []string{
+			s.ID, s.Title, s.Description, s.Language, strings.Join(s.Tags, ";"), s.Folder,
+			strconv.FormatBool(s.IsFavorite), strconv.FormatBool(s.IsPublic), strconv.FormatBool(s.IsArchived),
+			s.CreatedAt.Format(time.RFC3339), s.UpdatedAt.Format(time.RFC3339),
+		}
+		if withContent {
+			content := s.Content
+			if len(s.Files) > 0 {
+				parts := make([]string, 0, len(s.Files))
+				for _, file := range s.Files {
+					parts = append(parts, fmt.Sprintf("--- %s ---\n%s", file.Filename, file.Content))
+				}
+				content = strings.Join(parts, "\n\n")
+			}
+			row = append(row, content)
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
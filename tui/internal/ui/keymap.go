@@ -0,0 +1,200 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// keyHelp documents a key binding. The status bar shows the hint bindings of
+// the current view and the help overlay lists them all, so both stay in step
+// with this table.
+type keyHelp struct {
+	keys  string
+	desc  string
+	views []ViewMode
+	hint  bool // Shown in the status bar
+}
+
+var (
+	inList     = []ViewMode{ViewList}
+	inDetail   = []ViewMode{ViewDetail}
+	inBrowse   = []ViewMode{ViewList, ViewDetail}
+	inForm     = []ViewMode{ViewCreate, ViewEdit}
+	inSearch   = []ViewMode{ViewSearch}
+	inSettings = []ViewMode{ViewSettings}
+	everywhere = []ViewMode{ViewList, ViewDetail, ViewHelp}
+)
+
+var keymap = []keyHelp{
+	{"↑/k", "up", inList, false},
+	{"↓/j", "down", inList, false},
+	{"←/h", "previous page", inList, false},
+	{"→/l", "next page", inList, true},
+	{"enter", "view", inList, true},
+	{"e", "edit", inBrowse, true},
+	{"n", "new", inList, true},
+	{"v", "new from clipboard, detecting its language", inList, false},
+	{"/", "search", inList, true},
+	{"o", "cycle sort order", inList, true},
+	{"r", "refresh", inList, false},
+	{"X", "export the list to CSV or JSON", inList, false},
+	{"s", "settings (server and API key)", inList, false},
+
+	{"↑/k", "scroll up", inDetail, false},
+	{"↓/j", "scroll down", inDetail, false},
+	{"←/h", "previous file", inDetail, false},
+	{"→/l", "next file", inDetail, false},
+	{"n/p", "next / previous snippet", inDetail, true},
+	{"c", "copy", inDetail, true},
+	{"P", "toggle public", inDetail, false},
+	{"E", "encrypt or decrypt the snippet with the passphrase", inDetail, false},
+	{"m", "move to folder", inDetail, true},
+	{"t", "choose tags", inDetail, true},
+	{"esc", "back", inDetail, true},
+
+	{"f", "toggle favorite", inBrowse, false},
+	{"d", "delete", inBrowse, true},
+	{"u", "undo a delete while its notice shows", inBrowse, false},
+	{"L", "unlock or lock secret snippets", inBrowse, false},
+
+	{"tab", "next field", inForm, true},
+	{"shift+tab", "previous field", inForm, false},
+	{"↑/↓", "cycle languages", inForm, false},
+	{"ctrl+e", "external editor", inForm, true},
+	{"ctrl+s", "save", inForm, true},
+	{"esc", "cancel", inForm, true},
+
+	{"enter", "search", inSearch, true},
+	{"esc", "cancel", inSearch, true},
+
+	{"tab/shift+tab", "navigate", inSettings, true},
+	{"ctrl+s", "save", inSettings, true},
+	{"esc", "cancel", inSettings, true},
+
+	{"ctrl+k", "command palette", inBrowse, true},
+	{"?", "toggle help", everywhere, true},
+	{"q", "quit", everywhere, true},
+	{"ctrl+c", "force quit", []ViewMode{ViewList, ViewDetail, ViewCreate, ViewEdit, ViewSearch, ViewSettings, ViewHelp}, false},
+}
+
+// helpSections orders the help overlay
+var helpSections = []struct {
+	title string
+	view  ViewMode
+}{
+	{"Snippet list", ViewList},
+	{"Snippet detail", ViewDetail},
+	{"Create / edit", ViewCreate},
+	{"Search", ViewSearch},
+	{"Settings", ViewSettings},
+	{"Everywhere", ViewHelp},
+}
+
+var modeLabels = map[ViewMode]string{
+	ViewList:     "LIST",
+	ViewDetail:   "DETAIL",
+	ViewCreate:   "NEW",
+	ViewEdit:     "EDIT",
+	ViewSearch:   "SEARCH",
+	ViewSettings: "SETTINGS",
+	ViewHelp:     "HELP",
+}
+
+var (
+	modeBadgeStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("0")). // Black text (ANSI 0)
+			Background(lipgloss.Color("5")). // Magenta background (ANSI 5)
+			Bold(true).
+			Padding(0, 1)
+
+	onlineStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("2")) // Green (ANSI 2)
+	offlineStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1")) // Red (ANSI 1)
+)
+
+// keyHints returns the status bar hints for a view
+func keyHints(view ViewMode) string {
+	var hints []string
+	for _, k := range keymap {
+		if k.hint && slices.Contains(k.views, view) {
+			hints = append(hints, k.keys+" "+k.desc)
+		}
+	}
+	return strings.Join(hints, " • ")
+}
+
+// statusBar shows the mode, list state, connection and key hints
+func (m Model) statusBar() string {
+	parts := []string{modeBadgeStyle.Render(modeLabels[m.mode])}
+
+	switch m.mode {
+	case ViewList:
+		parts = append(parts, fmt.Sprintf("page %d/%d", m.currentPage, max(m.totalPages, 1)))
+	case ViewDetail:
+		if m.detailSnippet != nil && len(m.detailSnippet.Files) > 1 {
+			parts = append(parts, fmt.Sprintf("file %d/%d", m.selectedFileIdx+1, len(m.detailSnippet.Files)))
+		}
+	}
+	if m.mode == ViewList || m.mode == ViewDetail {
+		parts = append(parts, "sort: "+sortModes[m.sortIdx].label)
+		if m.searchQuery != "" {
+			parts = append(parts, fmt.Sprintf("search: %q", m.searchQuery))
+		}
+		if len(m.filterTags) > 0 {
+			parts = append(parts, fmt.Sprintf("tags: %d", len(m.filterTags)))
+		}
+		if m.showArchived {
+			parts = append(parts, "archived")
+		}
+	}
+
+	if m.secrets.Unlocked() {
+		parts = append(parts, "🔓 secrets unlocked")
+	}
+	if m.updatedCount > 0 && m.mode == ViewList {
+		parts = append(parts, onlineStyle.Render(m.updatedNotice()))
+	}
+	if m.offline {
+		parts = append(parts, offlineStyle.Render("○ offline"))
+	} else {
+		parts = append(parts, onlineStyle.Render("● connected"))
+	}
+
+	var s strings.Builder
+	s.WriteString(strings.Join(parts, dimmedStyle.Render(" │ ")))
+	// Open pickers and dialogs show their own keys
+	if m.picker == nil && m.confirm == nil && m.palette == nil && m.passphrase == nil {
+		s.WriteString("\n")
+		s.WriteString(helpStyle.UnsetMarginTop().Width(m.width).Render(renderHelpText(keyHints(m.mode))))
+	}
+	return s.String()
+}
+
+func (m Model) viewHelp() string {
+	var s strings.Builder
+
+	s.WriteString(headerStyle.Render("Snippy - Help"))
+	s.WriteString("\n")
+
+	for _, section := range helpSections {
+		s.WriteString("\n")
+		s.WriteString(subtitleStyle.UnsetMarginLeft().Render(section.title))
+		s.WriteString("\n")
+		for _, k := range keymap {
+			if !slices.Contains(k.views, section.view) {
+				continue
+			}
+			// Global bindings are listed once, under "Everywhere"
+			if section.view != ViewHelp && slices.Contains(k.views, ViewHelp) {
+				continue
+			}
+			fmt.Fprintf(&s, "  %s  %s\n",
+				selectedItemStyle.Width(16).Render(k.keys),
+				normalItemStyle.Render(k.desc))
+		}
+	}
+
+	return s.String()
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/atotto/clipboard"
 
 	"github.com/MohamedElashri/snipo/tui/internal/api"
+	"github.com/MohamedElashri/snipo/tui/internal/cache"
 	"github.com/MohamedElashri/snipo/tui/internal/config"
+	"github.com/MohamedElashri/snipo/tui/internal/secret"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -30,6 +32,7 @@ const (
 
 type Model struct {
 	client  *api.Client
+	cache   *cache.Store // Offline copy; nil when it couldn't be opened
 	config  *config.Config
 	mode    ViewMode
 	width   int
@@ -37,16 +40,30 @@ type Model struct {
 	err     error
 	message string
 
-	snippets    []api.Snippet
-	selectedIdx int
-	currentPage int
-	totalPages  int
-	searchQuery string
-	filterTags  []int
+	snippets     []api.Snippet
+	selectedIdx  int
+	currentPage  int
+	totalPages   int
+	searchQuery  string
+	filterTags   []int
+	sortIdx      int
+	showArchived bool // List archived snippets instead of active ones
 
 	detailSnippet   *api.Snippet
 	detailScroll    int
 	selectedFileIdx int
+	picker          *picker // Folder or tag picker open over the detail view
+	palette         *palette
+	passphrase      *passphrasePrompt
+	secrets         *secret.Session // Passphrase for secret snippets, kept until quit
+
+	confirm  *confirmDialog // Pending destructive action awaiting y/n
+	toast    *toast
+	toastSeq int
+
+	offline      bool     // Last request could not reach the server
+	helpFrom     ViewMode // View to return to when the help overlay closes
+	updatedCount int      // Snippets changed by background refreshes since the last key press
 
 	tags    []api.Tag
 	folders []api.Folder
@@ -63,17 +80,45 @@ type Model struct {
 	quitting bool
 }
 
+// sortModes are the list orderings cycled with "o"
+var sortModes = []struct {
+	label string
+	by    string
+	order string
+}{
+	{"last modified", "updated_at", "desc"},
+	{"date created", "created_at", "desc"},
+	{"title A-Z", "title", "asc"},
+	{"title Z-A", "title", "desc"},
+	{"most viewed", "view_count", "desc"},
+	{"most used", "use_count", "desc"},
+	{"recently used", "last_used", "desc"},
+}
+
 type errMsg struct{ err error }
 type successMsg struct{ message string }
+type noticeMsg struct{ message string } // Status line text that keeps the current view
 type copyResultMsg struct {
 	message string
 	err     error
 }
+type clipboardReadMsg struct {
+	content string
+	err     error
+}
 type snippetsLoadedMsg struct {
 	snippets   []api.Snippet
 	pagination *api.Pagination
+	offline    bool // Served from the offline cache
+}
+type snippetLoadedMsg struct {
+	snippet *api.Snippet
+	offline bool
+}
+type snippetSavedMsg struct { // Metadata change saved from the detail view
+	snippet *api.Snippet
+	message string
 }
-type snippetLoadedMsg struct{ snippet *api.Snippet }
 type tagsLoadedMsg struct{ tags []api.Tag }
 type foldersLoadedMsg struct{ folders []api.Folder }
 type languagesLoadedMsg struct{ languages []string }
@@ -82,9 +127,13 @@ func (e errMsg) Error() string { return e.err.Error() }
 
 func NewModel(cfg *config.Config) Model {
 	client := api.NewClient(cfg.ServerURL, cfg.APIKey)
+	// Without the cache snippy still works, just not offline
+	store, _ := cache.Open()
 
 	return Model{
 		client:           client,
+		cache:            store,
+		secrets:          secret.NewSession(),
 		config:           cfg,
 		mode:             ViewList,
 		snippets:         []api.Snippet{},
@@ -96,29 +145,92 @@ func NewModel(cfg *config.Config) Model {
 
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
-		loadSnippets(m.client, 1, 20, "", nil, nil, "", nil, nil),
+		loadSnippets(m.client, m.cache, m.listOptions(1)),
 		loadTags(m.client),
 		loadFolders(m.client),
 		loadLanguages(m.client),
+		m.scheduleRefresh(),
 	)
 }
 
-func loadSnippets(client *api.Client, page, limit int, query string, tagIDs, folderIDs []int, language string, favorite, archived *bool) tea.Cmd {
+// listOptions builds the list request for a page from the current search,
+// tag filter and sort mode
+func (m Model) listOptions(page int) api.ListOptions {
+	sort := sortModes[m.sortIdx]
+	opts := api.ListOptions{
+		Page:      page,
+		Limit:     20,
+		Query:     m.searchQuery,
+		TagIDs:    m.filterTags,
+		SortBy:    sort.by,
+		SortOrder: sort.order,
+	}
+	if m.showArchived {
+		archived := true
+		opts.Archived = &archived
+	}
+	return opts
+}
+
+// loadSnippets loads a page of the list, falling back to the offline cache
+// when the server can't be reached
+func loadSnippets(client *api.Client, store *cache.Store, opts api.ListOptions) tea.Cmd {
 	return func() tea.Msg {
-		snippets, pagination, err := client.ListSnippets(page, limit, query, tagIDs, folderIDs, language, favorite, archived)
+		snippets, pagination, err := client.ListSnippets(opts)
 		if err != nil {
+			if store != nil && api.IsConnectionError(err) {
+				snippets, pagination := store.List(opts)
+				return snippetsLoadedMsg{snippets: snippets, pagination: pagination, offline: true}
+			}
 			return errMsg{err}
 		}
+		if store != nil {
+			_ = store.Put(snippets...)
+		}
 		return snippetsLoadedMsg{snippets: snippets, pagination: pagination}
 	}
 }
 
-func loadSnippet(client *api.Client, id string) tea.Cmd {
+// loadNeighbor opens the snippet before (-1) or after (1) id in the list
+// opts selects
+func loadNeighbor(client *api.Client, id string, opts api.ListOptions, direction int) tea.Cmd {
+	return func() tea.Msg {
+		neighbors, err := client.GetNeighbors(id, opts)
+		if err != nil {
+			return errMsg{err}
+		}
+		target := neighbors.NextID
+		if direction < 0 {
+			target = neighbors.PrevID
+		}
+		if target == nil {
+			return noticeMsg{message: "No more snippets"}
+		}
+		return loadSnippet(client, nil, *target)()
+	}
+}
+
+// loadSnippet opens a snippet, from the offline cache when it was created
+// offline or the server can't be reached
+func loadSnippet(client *api.Client, store *cache.Store, id string) tea.Cmd {
 	return func() tea.Msg {
+		if store != nil && cache.IsLocal(id) {
+			if snippet, ok := store.Get(id); ok {
+				return snippetLoadedMsg{snippet: snippet, offline: true}
+			}
+		}
 		snippet, err := client.GetSnippet(id)
 		if err != nil {
+			if store != nil && api.IsConnectionError(err) {
+				if snippet, ok := store.Get(id); ok {
+					return snippetLoadedMsg{snippet: snippet, offline: true}
+				}
+			}
 			return errMsg{err}
 		}
+		if store != nil {
+			_ = store.Put(*snippet)
+		}
 		return snippetLoadedMsg{snippet: snippet}
 	}
 }
@@ -153,33 +265,64 @@ func loadLanguages(client *api.Client) tea.Cmd {
 	}
 }
 
-func createSnippet(client *api.Client, input api.SnippetInput) tea.Cmd {
+func createSnippet(client *api.Client, store *cache.Store, input api.SnippetInput) tea.Cmd {
 	return func() tea.Msg {
 		snippet, err := client.CreateSnippet(input)
 		if err != nil {
+			if store != nil && api.IsConnectionError(err) {
+				if _, err := store.QueueCreate(input); err != nil {
+					return errMsg{err}
+				}
+				return successMsg{message: offlineSaved("Created", input.Title)}
+			}
 			return errMsg{err}
 		}
 		return successMsg{message: fmt.Sprintf("Created snippet: %s", snippet.Title)}
 	}
 }
 
-func updateSnippet(client *api.Client, id string, input api.SnippetInput) tea.Cmd {
+func updateSnippet(client *api.Client, store *cache.Store, snippet api.Snippet, input api.SnippetInput) tea.Cmd {
+	return func() tea.Msg {
+		updated, offline, err := putSnippet(client, store, snippet, input)
+		if err != nil {
+			return errMsg{err}
+		}
+		if offline {
+			return successMsg{message: offlineSaved("Updated", updated.Title)}
+		}
+		return successMsg{message: fmt.Sprintf("Updated snippet: %s", updated.Title)}
+	}
+}
+
+// saveSnippet sends a complete update from the detail view, which stays open
+func saveSnippet(client *api.Client, store *cache.Store, snippet api.Snippet, input api.SnippetInput, message string) tea.Cmd {
 	return func() tea.Msg {
-		snippet, err := client.UpdateSnippet(id, input)
+		updated, offline, err := putSnippet(client, store, snippet, input)
 		if err != nil {
 			return errMsg{err}
 		}
-		return successMsg{message: fmt.Sprintf("Updated snippet: %s", snippet.Title)}
+		if offline {
+			message += " offline; run snippy sync to upload"
+		}
+		return snippetSavedMsg{snippet: updated, message: message}
 	}
 }
 
-func deleteSnippet(client *api.Client, id string) tea.Cmd {
+// toggleDetailFavorite toggles the favorite flag of the snippet open in the
+// detail view. The server answers without tags and files, so only the flag
+// is copied onto the snippet shown.
+func toggleDetailFavorite(client *api.Client, snippet api.Snippet) tea.Cmd {
 	return func() tea.Msg {
-		err := client.DeleteSnippet(id)
+		updated, err := client.ToggleFavorite(snippet.ID)
 		if err != nil {
 			return errMsg{err}
 		}
-		return successMsg{message: "Snippet deleted successfully"}
+		snippet.IsFavorite = updated.IsFavorite
+		message := "Removed from favorites"
+		if snippet.IsFavorite {
+			message = "Added to favorites"
+		}
+		return snippetSavedMsg{snippet: &snippet, message: message}
 	}
 }
 
@@ -221,24 +364,67 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case tea.KeyMsg:
+		m.updatedCount = 0
+		if msg.String() != "ctrl+c" {
+			if m.confirm != nil {
+				cmd, closed := m.confirm.update(msg)
+				if closed {
+					m.confirm = nil
+				}
+				return m, cmd
+			}
+			if m.passphrase != nil {
+				return m.updatePassphrase(msg)
+			}
+			if m.palette != nil {
+				chosen, closed, cmd := m.palette.update(msg)
+				if closed {
+					m.palette = nil
+				}
+				if chosen != nil {
+					m.err = nil
+					return m, chosen.run(&m)
+				}
+				return m, cmd
+			}
+			if msg.String() == "L" && (m.mode == ViewList || m.mode == ViewDetail) && m.picker == nil {
+				return m, m.toggleSecrets()
+			}
+			if msg.String() == "ctrl+k" && (m.mode == ViewList || m.mode == ViewDetail) && m.picker == nil {
+				m.palette = newPalette("Commands", m.paletteCommands())
+				return m, nil
+			}
+			if msg.String() == "u" && m.toast != nil && m.toast.undo != nil && (m.mode == ViewList || m.mode == ViewDetail) && m.picker == nil {
+				undo := m.toast.undo
+				m.toast = nil
+				return m, undo
+			}
+		}
+
 		switch msg.String() {
 		case "ctrl+c":
 			m.quitting = true
 			return m, tea.Quit
 
 		case "q":
-			if m.mode == ViewList || m.mode == ViewDetail || m.mode == ViewHelp {
+			if (m.mode == ViewList || m.mode == ViewDetail || m.mode == ViewHelp) && m.picker == nil {
 				m.quitting = true
 				return m, tea.Quit
 			}
 
 		case "?":
-			if m.mode != ViewHelp {
+			if m.picker != nil {
+				break
+			}
+			switch m.mode {
+			case ViewList, ViewDetail:
+				m.helpFrom = m.mode
 				m.mode = ViewHelp
-			} else {
-				m.mode = ViewList
+				return m, nil
+			case ViewHelp:
+				m.mode = m.helpFrom
+				return m, nil
 			}
-			return m, nil
 		}
 
 		switch m.mode {
@@ -253,6 +439,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case ViewSettings:
 			return m.updateSettings(msg)
 		case ViewHelp:
+			if msg.String() == "esc" {
+				m.mode = m.helpFrom
+			}
 			return m, nil
 		}
 
@@ -265,6 +454,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case snippetsLoadedMsg:
+		m.offline = msg.offline
 		m.snippets = msg.snippets
 		if msg.pagination != nil {
 			m.currentPage = msg.pagination.Page
@@ -272,16 +462,28 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.selectedIdx = 0
 		m.detailSnippet = nil // Clear detail snippet when loading list
+		if notice := m.client.DeprecationNotice(); notice != "" {
+			m.message = "Server: " + notice
+		}
 
 	case snippetLoadedMsg:
+		m.offline = msg.offline
 		m.detailSnippet = msg.snippet
 		m.detailScroll = 0    // Reset scroll when loading new snippet
 		m.selectedFileIdx = 0 // Reset file selection
+		if err := m.openSecret(m.detailSnippet); err != nil {
+			m.err = err
+		}
 
 		if m.autoEdit {
 			m.autoEdit = false
-			m.mode = ViewEdit
-			m.initEditForm(m.detailSnippet)
+			if lockedSecret(m.detailSnippet) {
+				m.mode = ViewDetail
+				m.err = errSecretLocked
+			} else {
+				m.mode = ViewEdit
+				m.initEditForm(m.detailSnippet)
+			}
 		} else if m.mode == ViewList {
 			for i, s := range m.snippets {
 				if s.ID == msg.snippet.ID {
@@ -291,6 +493,30 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+	case snippetDeletedMsg:
+		m.mode = ViewList
+		m.detailSnippet = nil
+		m.message = ""
+		cmds = append(cmds,
+			m.showToast(fmt.Sprintf("Deleted %q", msg.snippet.Title), restoreSnippet(m.client, msg.snippet)),
+			loadSnippets(m.client, m.cache, m.listOptions(m.currentPage)))
+
+	case refreshTickMsg:
+		cmds = append(cmds, m.handleRefreshTick())
+
+	case listRefreshedMsg:
+		m.applyRefresh(msg)
+
+	case toastExpiredMsg:
+		if m.toast != nil && m.toast.id == msg.id {
+			m.toast = nil
+		}
+
+	case snippetSavedMsg:
+		m.detailSnippet = msg.snippet
+		m.message = msg.message
+		m.err = m.openSecret(m.detailSnippet)
+
 	case tagsLoadedMsg:
 		m.tags = msg.tags
 
@@ -307,7 +533,21 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case successMsg:
 		m.message = msg.message
 		m.mode = ViewList
-		cmds = append(cmds, loadSnippets(m.client, m.currentPage, 20, m.searchQuery, m.filterTags, nil, "", nil, nil))
+		cmds = append(cmds, loadSnippets(m.client, m.cache, m.listOptions(m.currentPage)))
+
+	case noticeMsg:
+		m.message = msg.message
+
+	case clipboardReadMsg:
+		switch {
+		case msg.err != nil:
+			m.err = msg.err
+		case strings.TrimSpace(msg.content) == "":
+			m.message = "Clipboard is empty"
+		default:
+			m.err = nil
+			m.initCreateFromClipboard(msg.content)
+		}
 
 	case copyResultMsg:
 		if msg.err != nil {
@@ -319,6 +559,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case errMsg:
 		m.err = msg.err
+		m.offline = api.IsConnectionError(msg.err)
 	}
 
 	return m, tea.Batch(cmds...)
@@ -345,13 +586,13 @@ func (m Model) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if len(m.snippets) > 0 {
 			m.mode = ViewDetail
 			m.autoEdit = false
-			return m, loadSnippet(m.client, m.snippets[m.selectedIdx].ID)
+			return m, loadSnippet(m.client, m.cache, m.snippets[m.selectedIdx].ID)
 		}
 
 	case "e":
 		if len(m.snippets) > 0 {
 			m.autoEdit = true
-			return m, loadSnippet(m.client, m.snippets[m.selectedIdx].ID)
+			return m, loadSnippet(m.client, m.cache, m.snippets[m.selectedIdx].ID)
 		}
 
 	case "/":
@@ -359,18 +600,24 @@ func (m Model) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.initSearchForm()
 
 	case "r":
-		return m, loadSnippets(m.client, m.currentPage, 20, m.searchQuery, m.filterTags, nil, "", nil, nil)
+		return m, loadSnippets(m.client, m.cache, m.listOptions(m.currentPage))
+
+	case "o":
+		m.sortIdx = (m.sortIdx + 1) % len(sortModes)
+		m.currentPage = 1
+		m.message = "Sorted by " + sortModes[m.sortIdx].label
+		return m, loadSnippets(m.client, m.cache, m.listOptions(1))
 
 	case "right", "l":
 		if m.currentPage < m.totalPages {
 			m.currentPage++
-			return m, loadSnippets(m.client, m.currentPage, 20, m.searchQuery, m.filterTags, nil, "", nil, nil)
+			return m, loadSnippets(m.client, m.cache, m.listOptions(m.currentPage))
 		}
 
 	case "left", "h":
 		if m.currentPage > 1 {
 			m.currentPage--
-			return m, loadSnippets(m.client, m.currentPage, 20, m.searchQuery, m.filterTags, nil, "", nil, nil)
+			return m, loadSnippets(m.client, m.cache, m.listOptions(m.currentPage))
 		}
 
 	case "n":
@@ -378,6 +625,13 @@ func (m Model) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.initCreateForm()
 		return m, nil
 
+	case "v":
+		return m, readClipboard
+
+	case "X":
+		m.palette = newPalette("Export list", m.exportCommands())
+		return m, nil
+
 	case "f":
 		if len(m.snippets) > 0 {
 			return m, toggleFavorite(m.client, m.snippets[m.selectedIdx].ID)
@@ -385,7 +639,7 @@ func (m Model) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "d", "x":
 		if len(m.snippets) > 0 {
-			return m, deleteSnippet(m.client, m.snippets[m.selectedIdx].ID)
+			m.confirmDelete(m.snippets[m.selectedIdx])
 		}
 	}
 
@@ -435,22 +689,16 @@ func (m Model) calculateMaxScroll() int {
 	var currentFilename string
 	var highlightLanguage string
 
+	highlightLanguage = m.detailSnippet.Language
 	if len(m.detailSnippet.Files) > 0 && m.selectedFileIdx < len(m.detailSnippet.Files) {
-		content = m.detailSnippet.Files[m.selectedFileIdx].Content
-		currentFilename = m.detailSnippet.Files[m.selectedFileIdx].Filename
+		file := m.detailSnippet.Files[m.selectedFileIdx]
+		content = file.Content
+		currentFilename = file.Filename
+		highlightLanguage = fileLanguage(file, highlightLanguage)
 	} else {
 		content = m.detailSnippet.Content
 	}
 
-	// Determine language
-	highlightLanguage = m.detailSnippet.Language
-	if currentFilename != "" {
-		fileLanguage := GetLanguageFromFilename(currentFilename)
-		if fileLanguage != "" {
-			highlightLanguage = fileLanguage
-		}
-	}
-
 	// Calculate render width
 	renderWidth := m.width - 8
 	if renderWidth < 40 {
@@ -458,7 +706,7 @@ func (m Model) calculateMaxScroll() int {
 	}
 
 	// Render content
-	renderedContent := RenderContent(content, highlightLanguage, currentFilename, renderWidth)
+	renderedContent := RenderContent(expandTabs(content, m.config.Tabs()), highlightLanguage, currentFilename, renderWidth)
 
 	// Wrap content to match what's displayed
 	maxContentWidth := renderWidth - 4
@@ -466,7 +714,7 @@ func (m Model) calculateMaxScroll() int {
 	contentLines := strings.Split(wrappedContent, "\n")
 
 	// Calculate available height
-	availableHeight := m.height - 16
+	availableHeight := m.height - 17
 	if availableHeight < 5 {
 		availableHeight = 5
 	}
@@ -481,6 +729,10 @@ func (m Model) calculateMaxScroll() int {
 }
 
 func (m Model) updateDetail(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.picker != nil {
+		return m.updatePicker(msg)
+	}
+
 	switch msg.String() {
 	case "esc", "backspace":
 		m.mode = ViewList
@@ -515,22 +767,103 @@ func (m Model) updateDetail(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 		}
 
-	case "c":
+	case "n", "p":
 		if m.detailSnippet != nil {
+			direction := 1
+			if msg.String() == "p" {
+				direction = -1
+			}
+			return m, loadNeighbor(m.client, m.detailSnippet.ID, m.listOptions(0), direction)
+		}
+
+	case "c":
+		if lockedSecret(m.detailSnippet) {
+			m.err = errSecretLocked
+		} else if m.detailSnippet != nil {
 			return m, copyToClipboard(m.detailSnippet.Content)
 		}
 
 	case "e":
-		if m.detailSnippet != nil {
+		if lockedSecret(m.detailSnippet) {
+			m.err = errSecretLocked
+		} else if m.detailSnippet != nil {
 			m.mode = ViewEdit
 			m.initEditForm(m.detailSnippet)
 			return m, nil
 		}
+
+	case "f":
+		if m.detailSnippet != nil {
+			return m, toggleDetailFavorite(m.client, *m.detailSnippet)
+		}
+
+	case "d":
+		if m.detailSnippet != nil {
+			m.confirmDelete(*m.detailSnippet)
+		}
+
+	case "P":
+		if m.detailSnippet != nil {
+			input := m.detailSnippet.Input()
+			input.IsPublic = !input.IsPublic
+			message := "Snippet is now private"
+			if input.IsPublic {
+				message = "Snippet is now public"
+			}
+			return m, m.saveDetail(input, message)
+		}
+
+	case "E":
+		return m, m.toggleEncryption()
+
+	case "m":
+		if m.detailSnippet != nil {
+			m.picker = newFolderPicker(m.folders, m.detailSnippet.Folder())
+			return m, textinput.Blink
+		}
+
+	case "t":
+		if m.detailSnippet != nil {
+			m.picker = newTagPicker(m.tags, m.detailSnippet.Tags)
+			return m, textinput.Blink
+		}
 	}
 
 	return m, nil
 }
 
+// updatePicker routes keys to the open picker and saves its choice
+func (m Model) updatePicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	done, cancelled, cmd := m.picker.update(msg)
+	if cancelled {
+		m.picker = nil
+		return m, nil
+	}
+	if !done || m.detailSnippet == nil {
+		return m, cmd
+	}
+
+	input := m.detailSnippet.Input()
+	var cmds []tea.Cmd
+	switch m.picker.kind {
+	case pickFolder:
+		input.FolderID = nil
+		if id := m.picker.selectedFolder(); id != nil {
+			folderID := int64(*id)
+			input.FolderID = &folderID
+		}
+		cmds = append(cmds, m.saveDetail(input, "Folder updated"))
+	case pickTags:
+		input.Tags = m.picker.selectedTags()
+		// Reload tags afterwards to pick up any the save created
+		if save := m.saveDetail(input, "Tags updated"); save != nil {
+			cmds = append(cmds, tea.Sequence(save, loadTags(m.client)))
+		}
+	}
+	m.picker = nil
+	return m, tea.Batch(cmds...)
+}
+
 func (m *Model) initCreateForm() {
 	m.inputs = make([]textinput.Model, 4)
 
@@ -969,19 +1302,29 @@ func (m Model) submitForm() (tea.Model, tea.Cmd) {
 
 	content := strings.TrimSpace(m.textarea.Value())
 
-	input := api.SnippetInput{
-		Title:       title,
-		Description: description,
-		Language:    language,
-		Content:     content,
-		IsPublic:    false, // Default for now
-		Tags:        finalTags,
-	}
-
 	if m.mode == ViewCreate {
-		return m, createSnippet(m.client, input)
+		return m, createSnippet(m.client, m.cache, api.SnippetInput{
+			Title:       title,
+			Description: description,
+			Language:    language,
+			Content:     content,
+			Tags:        finalTags,
+		})
 	} else if m.mode == ViewEdit && m.detailSnippet != nil {
-		return m, updateSnippet(m.client, m.detailSnippet.ID, input)
+		// Start from the stored snippet so fields the form doesn't show,
+		// such as its folder and visibility, are kept
+		input := m.detailSnippet.Input()
+		input.Title = title
+		input.Description = description
+		input.Language = language
+		input.Content = content
+		input.Tags = append([]string{}, finalTags...)
+		sealed, err := sealInput(m.secrets, input)
+		if err != nil {
+			m.err = err
+			return m, nil
+		}
+		return m, updateSnippet(m.client, m.cache, *m.detailSnippet, sealed)
 	}
 
 	return m, nil
@@ -1001,7 +1344,7 @@ func (m Model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.searchQuery = strings.TrimSpace(m.inputs[0].Value())
 		m.mode = ViewList
 		m.currentPage = 1
-		return m, loadSnippets(m.client, 1, 20, m.searchQuery, m.filterTags, nil, "", nil, nil)
+		return m, loadSnippets(m.client, m.cache, m.listOptions(1))
 	}
 
 	m.inputs[0], cmd = m.inputs[0].Update(msg)
@@ -1075,7 +1418,10 @@ func (m Model) saveSettings() (tea.Model, tea.Cmd) {
 	m.message = "Settings saved successfully"
 	m.mode = ViewList
 
-	return m, loadSnippets(m.client, 1, 20, "", nil, nil, "", nil, nil)
+	// Reload unfiltered from the new server, keeping the sort order
+	opts := m.listOptions(1)
+	opts.Query, opts.TagIDs = "", nil
+	return m, loadSnippets(m.client, m.cache, opts)
 }
 
 func copyToClipboard(content string) tea.Cmd {
@@ -1088,6 +1434,29 @@ func copyToClipboard(content string) tea.Cmd {
 	}
 }
 
+// readClipboard reads the system clipboard for a new snippet
+func readClipboard() tea.Msg {
+	content, err := clipboard.ReadAll()
+	if err != nil {
+		return clipboardReadMsg{err: fmt.Errorf("failed to read clipboard: %w", err)}
+	}
+	return clipboardReadMsg{content: content}
+}
+
+// initCreateFromClipboard opens the create form with content pre-filled and
+// its language detected, leaving the title focused
+func (m *Model) initCreateFromClipboard(content string) {
+	m.mode = ViewCreate
+	m.initCreateForm()
+	m.textarea.SetValue(content)
+	if language := DetectLanguage(content, m.allowedLanguages); language != "" {
+		m.inputs[1].SetValue(language)
+		m.message = "Pasted from clipboard as " + language
+	} else {
+		m.message = "Pasted from clipboard"
+	}
+}
+
 func (m Model) View() string {
 	if m.quitting {
 		return "Goodbye!\n"
@@ -1099,7 +1468,7 @@ func (m Model) View() string {
 	if m.mode == ViewList || m.mode == ViewSearch || m.mode == ViewSettings || m.mode == ViewHelp {
 		s.WriteString(titleStyle.Render("Snippy"))
 		s.WriteString("\n")
-		s.WriteString(subtitleStyle.Render(fmt.Sprintf("Connected to: %s", m.config.ServerURL)))
+		s.WriteString(subtitleStyle.Render(m.config.ServerURL))
 		s.WriteString("\n\n")
 	}
 
@@ -1114,6 +1483,26 @@ func (m Model) View() string {
 		s.WriteString("\n\n")
 	}
 
+	if m.toast != nil {
+		s.WriteString(m.toast.view())
+		s.WriteString("\n\n")
+	}
+
+	if m.confirm != nil {
+		s.WriteString(m.confirm.view())
+		s.WriteString("\n\n")
+	}
+
+	if m.palette != nil {
+		s.WriteString(m.palette.view(m.width - 8))
+		s.WriteString("\n\n")
+	}
+
+	if m.passphrase != nil {
+		s.WriteString(m.passphrase.view(m.width - 8))
+		s.WriteString("\n\n")
+	}
+
 	switch m.mode {
 	case ViewList:
 		s.WriteString(m.viewList())
@@ -1131,13 +1520,20 @@ func (m Model) View() string {
 		s.WriteString(m.viewSettings())
 	}
 
+	s.WriteString("\n")
+	s.WriteString(m.statusBar())
+
 	return s.String()
 }
 
 func (m Model) viewList() string {
 	var s strings.Builder
 
-	s.WriteString(headerStyle.Render(fmt.Sprintf("Snippets (Page %d/%d)", m.currentPage, m.totalPages)))
+	if m.showArchived {
+		s.WriteString(headerStyle.Render("Archived snippets"))
+	} else {
+		s.WriteString(headerStyle.Render("Snippets"))
+	}
 	s.WriteString("\n\n")
 
 	if len(m.snippets) == 0 {
@@ -1166,7 +1562,7 @@ func (m Model) viewList() string {
 		if len(snippet.Tags) > 0 {
 			var tagStrs []string
 			for _, tag := range snippet.Tags {
-				tagStrs = append(tagStrs, tagStyle.Render(tag.Name))
+				tagStrs = append(tagStrs, renderTag(tag))
 			}
 			tags = " " + strings.Join(tagStrs, "")
 		}
@@ -1176,14 +1572,16 @@ func (m Model) viewList() string {
 			lang = " " + languageStyle.Render("["+snippet.Language+"]")
 		}
 
-		line := fmt.Sprintf("%s%s%s%s%s", cursor, favorite, snippet.Title, lang, tags)
+		lock := ""
+		if isEncrypted(snippet) {
+			lock = "🔒 "
+		}
+
+		line := fmt.Sprintf("%s%s%s%s%s%s", cursor, favorite, lock, snippet.Title, lang, tags)
 		s.WriteString(style.Render(line))
 		s.WriteString("\n")
 	}
 
-	s.WriteString("\n")
-	s.WriteString(helpStyle.Width(m.width).Render(renderHelpText("↑/k up • ↓/j down • ←/h prev page • →/l next page • enter view • e edit • n new • / search • s settings • r refresh • q quit • ? help")))
-
 	return s.String()
 }
 
@@ -1213,15 +1611,31 @@ func (m Model) viewDetail() string {
 	if len(m.detailSnippet.Tags) > 0 {
 		var tagStrs []string
 		for _, tag := range m.detailSnippet.Tags {
-			tagStrs = append(tagStrs, tagStyle.Render(tag.Name))
+			tagStrs = append(tagStrs, renderTag(tag))
 		}
 		metadata = append(metadata, "Tags: "+strings.Join(tagStrs, " "))
 	}
 
+	if id := m.detailSnippet.Folder(); id != nil {
+		for _, f := range m.folders {
+			if f.ID == *id {
+				metadata = append(metadata, "Folder: "+f.Name)
+				break
+			}
+		}
+	}
+
 	if m.detailSnippet.IsPublic {
 		metadata = append(metadata, dimmedStyle.Render("Public"))
 	}
 
+	switch {
+	case m.detailSnippet.Secret:
+		metadata = append(metadata, "🔓 Secret, decrypted locally")
+	case lockedSecret(m.detailSnippet):
+		metadata = append(metadata, "🔒 Secret")
+	}
+
 	if len(metadata) > 0 {
 		s.WriteString(dimmedStyle.Render(strings.Join(metadata, " • ")))
 		s.WriteString("\n")
@@ -1238,6 +1652,7 @@ func (m Model) viewDetail() string {
 	// Multi-file snippet support
 	var content string
 	var currentFilename string
+	highlightLanguage := m.detailSnippet.Language
 
 	if len(m.detailSnippet.Files) > 0 {
 		// Multi-file snippet - show file tabs with clear separator
@@ -1254,22 +1669,23 @@ func (m Model) viewDetail() string {
 		s.WriteString("\n\n")
 
 		if m.selectedFileIdx < len(m.detailSnippet.Files) {
-			content = m.detailSnippet.Files[m.selectedFileIdx].Content
-			currentFilename = m.detailSnippet.Files[m.selectedFileIdx].Filename
+			file := m.detailSnippet.Files[m.selectedFileIdx]
+			content = file.Content
+			currentFilename = file.Filename
+			// Determine the language for syntax highlighting or markdown rendering
+			highlightLanguage = fileLanguage(file, highlightLanguage)
+			if file.Note != "" {
+				s.WriteString(dimmedStyle.Render(file.Note))
+				s.WriteString("\n\n")
+			}
 		}
 	} else {
 		// Single-file snippet
 		content = m.detailSnippet.Content
 	}
-
-	// Determine the language for syntax highlighting or markdown rendering
-	highlightLanguage := m.detailSnippet.Language
-	if currentFilename != "" {
-		// If we have a filename, try to get language from it
-		fileLanguage := GetLanguageFromFilename(currentFilename)
-		if fileLanguage != "" {
-			highlightLanguage = fileLanguage
-		}
+	if lockedSecret(m.detailSnippet) {
+		content = "This snippet is encrypted. Press L to enter the passphrase."
+		highlightLanguage, currentFilename = "", ""
 	}
 
 	// Calculate available width for rendering (accounting for padding and margins)
@@ -1279,7 +1695,7 @@ func (m Model) viewDetail() string {
 	}
 
 	// Apply markdown rendering or syntax highlighting based on content type
-	renderedContent := RenderContent(content, highlightLanguage, currentFilename, renderWidth)
+	renderedContent := RenderContent(expandTabs(content, m.config.Tabs()), highlightLanguage, currentFilename, renderWidth)
 
 	// Wrap long lines to prevent horizontal scrolling and maintain consistent width
 	maxContentWidth := renderWidth - 4 // Leave some margin
@@ -1287,7 +1703,7 @@ func (m Model) viewDetail() string {
 
 	// Handle scrolling for large content
 	contentLines := strings.Split(wrappedContent, "\n")
-	availableHeight := m.height - 16 // Reserve more space for file tabs
+	availableHeight := m.height - 17 // Reserve more space for file tabs
 
 	if availableHeight < 5 {
 		availableHeight = 5
@@ -1339,13 +1755,13 @@ func (m Model) viewDetail() string {
 		s.WriteString(dimmedStyle.Render(scrollInfo))
 	}
 
-	s.WriteString("\n\n")
+	s.WriteString("\n")
 
-	helpText := "↑/k up • ↓/j down • esc back • e edit • c copy • q quit"
-	if len(m.detailSnippet.Files) > 1 {
-		helpText = "←/h prev file • →/l next file • " + helpText
+	if m.picker != nil {
+		s.WriteString("\n")
+		s.WriteString(m.picker.view(m.width - 8))
+		s.WriteString("\n")
 	}
-	s.WriteString(helpStyle.Width(m.width).Render(renderHelpText(helpText)))
 
 	return s.String()
 }
@@ -1399,8 +1815,6 @@ func (m Model) viewCreateForm() string {
 	}
 
 	formContent.WriteString(m.textarea.View())
-	formContent.WriteString("\n\n")
-	formContent.WriteString(helpStyle.Width(m.width - 8).Render(renderHelpText("tab next field • ctrl+e open external editor • ctrl+s save • esc cancel")))
 
 	s.WriteString(borderStyle.Render(formContent.String()))
 	return s.String()
@@ -1455,8 +1869,6 @@ func (m Model) viewEditForm() string {
 	}
 
 	formContent.WriteString(m.textarea.View())
-	formContent.WriteString("\n\n")
-	formContent.WriteString(helpStyle.Width(m.width - 8).Render(renderHelpText("tab next field • ctrl+e open external editor • ctrl+s save • esc cancel")))
 
 	s.WriteString(borderStyle.Render(formContent.String()))
 	return s.String()
@@ -1469,9 +1881,7 @@ func (m Model) viewSearchForm() string {
 	s.WriteString("\n\n")
 
 	s.WriteString(m.inputs[0].View())
-	s.WriteString("\n\n")
-
-	s.WriteString(helpStyle.Width(m.width).Render(renderHelpText("enter search • esc cancel")))
+	s.WriteString("\n")
 
 	return s.String()
 }
@@ -1515,45 +1925,19 @@ func (m Model) viewSettings() string {
 	}
 	s.WriteString("\n")
 	s.WriteString(dimmedStyle.Render("─────────────────────────────────────────────────────────"))
-
-	s.WriteString("\n\n")
-	s.WriteString(helpStyle.Width(m.width - 4).Render(renderHelpText("tab/shift+tab navigate • ctrl+s save • esc cancel")))
+	s.WriteString("\n")
 
 	return s.String()
 }
 
-func (m Model) viewHelp() string {
-	var s strings.Builder
-
-	s.WriteString(headerStyle.Render("Snippy - Help"))
-	s.WriteString("\n\n")
-
-	help := []struct {
-		key  string
-		desc string
-	}{
-		{"↑/k", "Move up in list"},
-		{"↓/j", "Move down in list"},
-		{"←/h", "Previous page / Previous file (in detail view)"},
-		{"→/l", "Next page / Next file (in detail view)"},
-		{"enter", "View selected snippet"},
-		{"/", "Search snippets"},
-		{"s", "Settings (change server/API key)"},
-		{"r", "Refresh list"},
-		{"c", "Copy content to clipboard (in detail view)"},
-		{"esc", "Go back / Cancel"},
-		{"?", "Toggle this help screen"},
-		{"q", "Quit application"},
-	}
-
-	for _, h := range help {
-		fmt.Fprintf(&s, "  %s  %s\n",
-			selectedItemStyle.Render(h.key),
-			normalItemStyle.Render(h.desc))
+// fileLanguage returns the language to render a file with: its stored
+// language unless that is plain text, then the one its filename implies
+func fileLanguage(file api.File, fallback string) string {
+	if file.Language != "" && file.Language != "plaintext" {
+		return file.Language
 	}
-
-	s.WriteString("\n")
-	s.WriteString(helpStyle.Width(m.width).Render(renderHelpText("? close help")))
-
-	return s.String()
+	if lang := GetLanguageFromFilename(file.Filename); lang != "" {
+		return lang
+	}
+	return fallback
 }
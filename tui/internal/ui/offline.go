@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/MohamedElashri/snipo/tui/internal/api"
+	"github.com/MohamedElashri/snipo/tui/internal/cache"
+)
+
+// putSnippet updates a snippet on the server. When the server can't be
+// reached, or the snippet was created offline and isn't on the server yet,
+// the edit is queued in the offline cache instead and offline is true.
+func putSnippet(client *api.Client, store *cache.Store, snippet api.Snippet, input api.SnippetInput) (updated *api.Snippet, offline bool, err error) {
+	if store == nil || !cache.IsLocal(snippet.ID) {
+		updated, err := client.UpdateSnippet(snippet.ID, input)
+		if err == nil {
+			if store != nil {
+				_ = store.Put(*updated)
+			}
+			return updated, false, nil
+		}
+		if store == nil || !api.IsConnectionError(err) {
+			return nil, false, err
+		}
+	}
+
+	updated, err = store.QueueUpdate(snippet, input)
+	if err != nil {
+		return nil, false, err
+	}
+	return updated, true, nil
+}
+
+// offlineSaved describes an edit that was queued for the next sync
+func offlineSaved(action, title string) string {
+	return fmt.Sprintf("%s %q offline; run snippy sync to upload", action, title)
+}
@@ -0,0 +1,255 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MohamedElashri/snipo/tui/internal/api"
+)
+
+// paletteCommand is an action offered by the command palette
+type paletteCommand struct {
+	label string
+	run   func(m *Model) tea.Cmd
+}
+
+// palette is the ctrl+k command list, filtered by fuzzy matching what is
+// typed
+type palette struct {
+	title    string
+	commands []paletteCommand
+	matches  []int // Indices into commands, best match first
+	cursor   int
+	filter   textinput.Model
+}
+
+func newPalette(title string, commands []paletteCommand) *palette {
+	filter := textinput.New()
+	filter.Placeholder = "Type a command"
+	filter.CharLimit = 50
+	filter.Focus()
+	p := &palette{title: title, commands: commands, filter: filter}
+	p.match()
+	return p
+}
+
+// paletteCommands lists the actions available from the current state. Tag
+// commands are generated from the loaded tags so they can be found by name.
+func (m Model) paletteCommands() []paletteCommand {
+	commands := []paletteCommand{
+		{"New snippet", func(m *Model) tea.Cmd {
+			m.mode = ViewCreate
+			m.initCreateForm()
+			return nil
+		}},
+		{"New snippet from clipboard", func(m *Model) tea.Cmd { return readClipboard }},
+		{"Search snippets", func(m *Model) tea.Cmd {
+			m.mode = ViewSearch
+			m.initSearchForm()
+			return nil
+		}},
+	}
+
+	secretsLabel := "Unlock secret snippets"
+	if m.secrets.Unlocked() {
+		secretsLabel = "Lock secret snippets"
+	}
+	commands = append(commands, paletteCommand{secretsLabel, func(m *Model) tea.Cmd { return m.toggleSecrets() }})
+	if m.mode == ViewDetail && m.detailSnippet != nil {
+		encryptLabel := "Encrypt snippet"
+		if m.detailSnippet.Secret || lockedSecret(m.detailSnippet) {
+			encryptLabel = "Decrypt snippet"
+		}
+		commands = append(commands, paletteCommand{encryptLabel, func(m *Model) tea.Cmd { return m.toggleEncryption() }})
+	}
+
+	archiveLabel := "Show archived snippets"
+	if m.showArchived {
+		archiveLabel = "Show active snippets"
+	}
+	commands = append(commands, paletteCommand{archiveLabel, func(m *Model) tea.Cmd {
+		m.showArchived = !m.showArchived
+		return m.reloadList()
+	}})
+
+	if len(m.filterTags) > 0 {
+		commands = append(commands, paletteCommand{"Clear tag filter", func(m *Model) tea.Cmd {
+			m.filterTags = nil
+			return m.reloadList()
+		}})
+	}
+	for _, tag := range m.tags {
+		id := tag.ID
+		commands = append(commands, paletteCommand{"Go to tag: " + tag.Name, func(m *Model) tea.Cmd {
+			m.filterTags = []int{id}
+			return m.reloadList()
+		}})
+	}
+
+	return append(commands,
+		paletteCommand{"Export list...", func(m *Model) tea.Cmd {
+			m.palette = newPalette("Export list", m.exportCommands())
+			return nil
+		}},
+		paletteCommand{"Sync gists now", func(m *Model) tea.Cmd { return syncGists(m.client) }},
+		paletteCommand{"Refresh", func(m *Model) tea.Cmd {
+			return loadSnippets(m.client, m.cache, m.listOptions(m.currentPage))
+		}},
+		paletteCommand{"Settings", func(m *Model) tea.Cmd {
+			m.mode = ViewSettings
+			m.initSettingsForm()
+			return nil
+		}},
+		paletteCommand{"Help", func(m *Model) tea.Cmd {
+			m.helpFrom = m.mode
+			m.mode = ViewHelp
+			return nil
+		}},
+	)
+}
+
+// reloadList returns to the first page of the list after its filters change
+func (m *Model) reloadList() tea.Cmd {
+	m.mode = ViewList
+	m.detailSnippet = nil
+	m.currentPage = 1
+	return loadSnippets(m.client, m.cache, m.listOptions(1))
+}
+
+func syncGists(client *api.Client) tea.Cmd {
+	return func() tea.Msg {
+		result, err := client.SyncGists()
+		if err != nil {
+			return errMsg{fmt.Errorf("gist sync failed: %w", err)}
+		}
+		msg := fmt.Sprintf("Synced %d of %d snippets with gists", result.Synced, result.TotalProcessed)
+		if result.Conflicts > 0 || result.Errors > 0 {
+			msg += fmt.Sprintf(" (%d conflicts, %d errors)", result.Conflicts, result.Errors)
+		}
+		return noticeMsg{message: msg}
+	}
+}
+
+// match ranks the commands against the filter, keeping the listed order for
+// equal scores
+func (p *palette) match() {
+	query := strings.TrimSpace(p.filter.Value())
+	type scored struct{ idx, score int }
+	var found []scored
+	for i, c := range p.commands {
+		if score, ok := fuzzyScore(query, c.label); ok {
+			found = append(found, scored{i, score})
+		}
+	}
+	sort.SliceStable(found, func(a, b int) bool { return found[a].score > found[b].score })
+
+	p.matches = p.matches[:0]
+	for _, f := range found {
+		p.matches = append(p.matches, f.idx)
+	}
+	if p.cursor >= len(p.matches) {
+		p.cursor = 0
+	}
+}
+
+// fuzzyScore reports whether the letters of query appear in order in label,
+// ignoring case. Matches that run together or start words score higher.
+func fuzzyScore(query, label string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	if len(q) == 0 {
+		return 0, true
+	}
+	l := []rune(strings.ToLower(label))
+
+	score, qi, prev := 0, 0, -2
+	for li := 0; li < len(l) && qi < len(q); li++ {
+		if l[li] != q[qi] {
+			continue
+		}
+		score++
+		if li == prev+1 {
+			score += 2
+		}
+		if li == 0 || !unicode.IsLetter(l[li-1]) && !unicode.IsDigit(l[li-1]) {
+			score += 3
+		}
+		prev = li
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	return score, true
+}
+
+// update handles a key, returning the chosen command or reporting that the
+// palette was closed
+func (p *palette) update(msg tea.KeyMsg) (chosen *paletteCommand, closed bool, cmd tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+k":
+		return nil, true, nil
+
+	case "up", "ctrl+p":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+		return nil, false, nil
+
+	case "down", "ctrl+n":
+		if p.cursor < len(p.matches)-1 {
+			p.cursor++
+		}
+		return nil, false, nil
+
+	case "enter":
+		if p.cursor < len(p.matches) {
+			return &p.commands[p.matches[p.cursor]], true, nil
+		}
+		return nil, false, nil
+	}
+
+	p.filter, cmd = p.filter.Update(msg)
+	p.match()
+	return nil, false, cmd
+}
+
+func (p *palette) view(width int) string {
+	var s strings.Builder
+
+	s.WriteString(headerStyle.Render(p.title))
+	s.WriteString("\n")
+	s.WriteString(p.filter.View())
+	s.WriteString("\n\n")
+
+	if len(p.matches) == 0 {
+		s.WriteString(dimmedStyle.Render("No matching commands"))
+		s.WriteString("\n")
+	}
+
+	// Keep the cursor in a window of at most 10 rows
+	start := 0
+	if p.cursor >= 10 {
+		start = p.cursor - 9
+	}
+	for row, i := range p.matches {
+		if row < start || row >= start+10 {
+			continue
+		}
+		if row == p.cursor {
+			s.WriteString(selectedItemStyle.Render("▶ " + p.commands[i].label))
+		} else {
+			s.WriteString(normalItemStyle.Render("  " + p.commands[i].label))
+		}
+		s.WriteString("\n")
+	}
+
+	s.WriteString("\n")
+	s.WriteString(helpStyle.Width(width).Render(renderHelpText("↑/↓ move • enter run • esc close")))
+
+	return borderStyle.Render(s.String())
+}
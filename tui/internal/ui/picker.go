@@ -0,0 +1,263 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MohamedElashri/snipo/tui/internal/api"
+)
+
+type pickerKind int
+
+const (
+	pickFolder pickerKind = iota // Single choice, including no folder
+	pickTags                     // Multiple choice, typing a new name creates a tag
+)
+
+// pickerItem is one choice in a picker
+type pickerItem struct {
+	label    string
+	folderID *int
+	selected bool
+	isNew    bool // Tag that will be created on save
+}
+
+// picker is a filterable list shown over the detail view to change a
+// snippet's folder or tags
+type picker struct {
+	kind   pickerKind
+	title  string
+	items  []pickerItem
+	cursor int // Index into visible()
+	filter textinput.Model
+}
+
+func newPicker(kind pickerKind, title string, items []pickerItem) *picker {
+	filter := textinput.New()
+	filter.Placeholder = "Type to filter"
+	if kind == pickTags {
+		filter.Placeholder = "Type to filter or create a tag"
+	}
+	filter.CharLimit = 50
+	filter.Focus()
+	return &picker{kind: kind, title: title, items: items, filter: filter}
+}
+
+// newFolderPicker lists every folder by its path, with the snippet's current
+// folder selected
+func newFolderPicker(folders []api.Folder, current *int) *picker {
+	byID := make(map[int]api.Folder, len(folders))
+	for _, f := range folders {
+		byID[f.ID] = f
+	}
+	path := func(f api.Folder) string {
+		parts := []string{f.Name}
+		for seen := map[int]bool{f.ID: true}; f.ParentID != nil && !seen[*f.ParentID]; {
+			parent, ok := byID[*f.ParentID]
+			if !ok {
+				break
+			}
+			seen[parent.ID] = true
+			parts = append([]string{parent.Name}, parts...)
+			f = parent
+		}
+		return strings.Join(parts, " / ")
+	}
+
+	items := []pickerItem{{label: "(No folder)", selected: current == nil}}
+	for _, f := range folders {
+		id := f.ID
+		items = append(items, pickerItem{
+			label:    path(f),
+			folderID: &id,
+			selected: current != nil && *current == id,
+		})
+	}
+	p := newPicker(pickFolder, "Move to folder", items)
+	for i, item := range items {
+		if item.selected {
+			p.cursor = i
+		}
+	}
+	return p
+}
+
+// newTagPicker lists every tag with the snippet's tags selected
+func newTagPicker(tags []api.Tag, current []api.Tag) *picker {
+	selected := make(map[string]bool, len(current))
+	for _, t := range current {
+		selected[strings.ToLower(t.Name)] = true
+	}
+	items := make([]pickerItem, 0, len(tags))
+	for _, t := range tags {
+		items = append(items, pickerItem{label: t.Name, selected: selected[strings.ToLower(t.Name)]})
+	}
+	return newPicker(pickTags, "Tags", items)
+}
+
+// visible returns the indices of the items matching the filter
+func (p *picker) visible() []int {
+	query := strings.ToLower(strings.TrimSpace(p.filter.Value()))
+	var idx []int
+	for i, item := range p.items {
+		if query == "" || strings.Contains(strings.ToLower(item.label), query) {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// update handles a key and reports whether the picker was confirmed or
+// cancelled
+func (p *picker) update(msg tea.KeyMsg) (done, cancelled bool, cmd tea.Cmd) {
+	visible := p.visible()
+
+	switch msg.String() {
+	case "esc":
+		return false, true, nil
+
+	case "up", "ctrl+p":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+		return false, false, nil
+
+	case "down", "ctrl+n":
+		if p.cursor < len(visible)-1 {
+			p.cursor++
+		}
+		return false, false, nil
+
+	case " ":
+		// Space toggles a tag unless it is part of a name being typed
+		if p.kind == pickTags && p.filter.Value() == "" {
+			if p.cursor < len(visible) {
+				item := &p.items[visible[p.cursor]]
+				item.selected = !item.selected
+			}
+			return false, false, nil
+		}
+
+	case "enter":
+		if p.kind == pickFolder {
+			if p.cursor < len(visible) {
+				for i := range p.items {
+					p.items[i].selected = i == visible[p.cursor]
+				}
+				return true, false, nil
+			}
+			return false, false, nil
+		}
+
+		name := strings.TrimSpace(p.filter.Value())
+		if name == "" {
+			return true, false, nil
+		}
+		p.toggleOrCreate(name)
+		p.filter.SetValue("")
+		p.cursor = 0
+		return false, false, nil
+	}
+
+	p.filter, cmd = p.filter.Update(msg)
+	if p.cursor >= len(p.visible()) {
+		p.cursor = 0
+	}
+	return false, false, cmd
+}
+
+// toggleOrCreate selects the tag called name, adding it if it doesn't exist
+func (p *picker) toggleOrCreate(name string) {
+	for i := range p.items {
+		if strings.EqualFold(p.items[i].label, name) {
+			p.items[i].selected = !p.items[i].selected
+			return
+		}
+	}
+	p.items = append(p.items, pickerItem{label: strings.ToLower(name), selected: true, isNew: true})
+}
+
+// selectedFolder returns the chosen folder ID, nil for no folder
+func (p *picker) selectedFolder() *int {
+	for _, item := range p.items {
+		if item.selected {
+			return item.folderID
+		}
+	}
+	return nil
+}
+
+// selectedTags returns the names of the chosen tags, never nil so that an
+// empty choice removes every tag
+func (p *picker) selectedTags() []string {
+	tags := []string{}
+	for _, item := range p.items {
+		if item.selected {
+			tags = append(tags, item.label)
+		}
+	}
+	return tags
+}
+
+func (p *picker) view(width int) string {
+	var s strings.Builder
+
+	s.WriteString(headerStyle.Render(p.title))
+	s.WriteString("\n")
+	s.WriteString(p.filter.View())
+	s.WriteString("\n\n")
+
+	visible := p.visible()
+	if len(visible) == 0 {
+		if p.kind == pickTags {
+			s.WriteString(dimmedStyle.Render(fmt.Sprintf("Press enter to create %q", strings.ToLower(strings.TrimSpace(p.filter.Value())))))
+		} else {
+			s.WriteString(dimmedStyle.Render("No matching folders"))
+		}
+		s.WriteString("\n")
+	}
+
+	// Keep the cursor in a window of at most 10 rows
+	start := 0
+	if p.cursor >= 10 {
+		start = p.cursor - 9
+	}
+	for row, i := range visible {
+		if row < start || row >= start+10 {
+			continue
+		}
+		item := p.items[i]
+		mark := "( )"
+		if p.kind == pickTags {
+			mark = "[ ]"
+			if item.selected {
+				mark = "[x]"
+			}
+		} else if item.selected {
+			mark = "(•)"
+		}
+		label := item.label
+		if item.isNew {
+			label = newTagStyle.Render(label)
+		}
+		line := fmt.Sprintf("%s %s", mark, label)
+		if row == p.cursor {
+			s.WriteString(selectedItemStyle.Render("▶ " + line))
+		} else {
+			s.WriteString(normalItemStyle.Render("  " + line))
+		}
+		s.WriteString("\n")
+	}
+
+	help := "↑/↓ move • enter choose • esc cancel"
+	if p.kind == pickTags {
+		help = "↑/↓ move • space toggle • enter on a name add/toggle it • enter save • esc cancel"
+	}
+	s.WriteString("\n")
+	s.WriteString(helpStyle.Width(width).Render(renderHelpText(help)))
+
+	return borderStyle.Render(s.String())
+}
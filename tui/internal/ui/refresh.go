@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/MohamedElashri/snipo/tui/internal/api"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// refreshTickMsg asks for a background reload of the visible list page
+type refreshTickMsg struct{}
+
+// listRefreshedMsg carries a background reload. opts is the request it
+// answers, so results for a page or search the user has since left are
+// dropped.
+type listRefreshedMsg struct {
+	opts       api.ListOptions
+	snippets   []api.Snippet
+	pagination *api.Pagination
+	err        error
+}
+
+// scheduleRefresh starts the next background refresh, or nothing when
+// refresh_interval disables it
+func (m Model) scheduleRefresh() tea.Cmd {
+	interval := m.config.Refresh()
+	if interval <= 0 {
+		return nil
+	}
+	return tea.Tick(interval, func(time.Time) tea.Msg { return refreshTickMsg{} })
+}
+
+// refreshList reloads a list page without touching the error line, so a
+// failed poll only flips the connection indicator
+func refreshList(client *api.Client, opts api.ListOptions) tea.Cmd {
+	return func() tea.Msg {
+		snippets, pagination, err := client.ListSnippets(opts)
+		return listRefreshedMsg{opts: opts, snippets: snippets, pagination: pagination, err: err}
+	}
+}
+
+// handleRefreshTick polls only while the list is idle on screen; forms,
+// dialogs, the palette and the detail view are left alone
+func (m Model) handleRefreshTick() tea.Cmd {
+	next := m.scheduleRefresh()
+	if m.mode != ViewList || m.picker != nil || m.confirm != nil || m.palette != nil {
+		return next
+	}
+	return tea.Batch(refreshList(m.client, m.listOptions(m.currentPage)), next)
+}
+
+// applyRefresh swaps in a refreshed page, keeping the cursor on the same
+// snippet and counting the snippets that are new or changed
+func (m *Model) applyRefresh(msg listRefreshedMsg) {
+	if msg.err != nil {
+		m.offline = api.IsConnectionError(msg.err)
+		return
+	}
+	m.offline = false
+	if m.mode != ViewList || !reflect.DeepEqual(msg.opts, m.listOptions(m.currentPage)) {
+		return
+	}
+
+	previous := make(map[string]time.Time, len(m.snippets))
+	for _, s := range m.snippets {
+		previous[s.ID] = s.UpdatedAt
+	}
+	changed := 0
+	for _, s := range msg.snippets {
+		if at, ok := previous[s.ID]; !ok || !at.Equal(s.UpdatedAt) {
+			changed++
+		}
+	}
+
+	var selectedID string
+	if m.selectedIdx < len(m.snippets) {
+		selectedID = m.snippets[m.selectedIdx].ID
+	}
+	m.snippets = msg.snippets
+	if msg.pagination != nil {
+		m.totalPages = msg.pagination.TotalPages
+	}
+	m.selectedIdx = min(m.selectedIdx, max(len(m.snippets)-1, 0))
+	for i, s := range m.snippets {
+		if s.ID == selectedID {
+			m.selectedIdx = i
+			break
+		}
+	}
+
+	if changed > 0 {
+		m.updatedCount += changed
+	}
+}
+
+// updatedNotice describes the snippets a background refresh changed
+func (m Model) updatedNotice() string {
+	if m.updatedCount == 1 {
+		return "↻ 1 snippet updated"
+	}
+	return fmt.Sprintf("↻ %d snippets updated", m.updatedCount)
+}
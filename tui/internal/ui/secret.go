@@ -0,0 +1,264 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/MohamedElashri/snipo/tui/internal/api"
+	"github.com/MohamedElashri/snipo/tui/internal/secret"
+)
+
+// errSecretLocked asks for the passphrase before touching a secret snippet
+var errSecretLocked = errors.New("snippet is encrypted; press L to enter the passphrase")
+
+// passphrasePrompt asks for the secret passphrase, twice when it is about to
+// encrypt something so a typo can't lock a snippet away
+type passphrasePrompt struct {
+	input   textinput.Model
+	confirm bool
+	first   string // First entry while waiting for the repeat
+	err     string
+	then    func(m *Model) tea.Cmd // Runs once the session is unlocked
+}
+
+func newPassphrasePrompt(confirm bool, then func(m *Model) tea.Cmd) *passphrasePrompt {
+	input := textinput.New()
+	input.Placeholder = "Passphrase"
+	input.EchoMode = textinput.EchoPassword
+	input.EchoCharacter = '•'
+	input.CharLimit = 256
+	input.Focus()
+	return &passphrasePrompt{input: input, confirm: confirm, then: then}
+}
+
+// update handles a key, returning the passphrase once entered and whether
+// the prompt should close
+func (p *passphrasePrompt) update(msg tea.KeyMsg) (passphrase string, closed bool, cmd tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		return "", true, nil
+
+	case "enter":
+		value := p.input.Value()
+		p.input.SetValue("")
+		switch {
+		case value == "":
+			return "", false, nil
+		case !p.confirm:
+			return value, true, nil
+		case p.first == "":
+			p.first = value
+			p.err = ""
+			return "", false, nil
+		case value != p.first:
+			p.first = ""
+			p.err = "Passphrases don't match, try again"
+			return "", false, nil
+		}
+		return value, true, nil
+	}
+
+	p.input, cmd = p.input.Update(msg)
+	return "", false, cmd
+}
+
+func (p *passphrasePrompt) view(width int) string {
+	var s strings.Builder
+
+	title := "Unlock secret snippets"
+	if p.first != "" {
+		title = "Repeat passphrase"
+	}
+	s.WriteString(headerStyle.Render(title))
+	s.WriteString("\n")
+	s.WriteString(p.input.View())
+	s.WriteString("\n")
+	if p.err != "" {
+		s.WriteString(errorStyle.Render(p.err))
+		s.WriteString("\n")
+	}
+
+	s.WriteString("\n")
+	s.WriteString(helpStyle.Width(width).Render(renderHelpText(
+		"The passphrase stays in memory until snippy quits • enter confirm • esc cancel")))
+
+	return borderStyle.Render(s.String())
+}
+
+// updatePassphrase routes keys to the open prompt and unlocks the session
+// with the entered passphrase
+func (m Model) updatePassphrase(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	passphrase, closed, cmd := m.passphrase.update(msg)
+	if !closed {
+		return m, cmd
+	}
+	then := m.passphrase.then
+	m.passphrase = nil
+	if passphrase == "" {
+		return m, nil
+	}
+
+	m.secrets.Unlock(passphrase)
+	// Check the passphrase against the open snippet when it is encrypted
+	if m.detailSnippet != nil && isEncrypted(*m.detailSnippet) {
+		if err := m.openSecret(m.detailSnippet); err != nil {
+			m.secrets.Lock()
+			m.err = err
+			return m, nil
+		}
+	}
+	m.err = nil
+	m.message = "Secret snippets unlocked"
+	if then != nil {
+		return m, then(&m)
+	}
+	return m, nil
+}
+
+// unlockSecrets opens the passphrase prompt, then runs then
+func (m *Model) unlockSecrets(confirm bool, then func(m *Model) tea.Cmd) tea.Cmd {
+	m.passphrase = newPassphrasePrompt(confirm, then)
+	return textinput.Blink
+}
+
+// toggleSecrets locks an unlocked session, showing the open snippet
+// encrypted again, or asks for the passphrase
+func (m *Model) toggleSecrets() tea.Cmd {
+	if !m.secrets.Unlocked() {
+		return m.unlockSecrets(false, nil)
+	}
+	m.secrets.Lock()
+	m.message = "Secret snippets locked"
+	if m.detailSnippet != nil && m.detailSnippet.Secret {
+		return loadSnippet(m.client, m.cache, m.detailSnippet.ID)
+	}
+	return nil
+}
+
+// toggleEncryption encrypts the open snippet, or stores it as plain text
+// again when it is already secret
+func (m *Model) toggleEncryption() tea.Cmd {
+	if m.detailSnippet == nil {
+		return nil
+	}
+	if !m.secrets.Unlocked() {
+		// Repeat the passphrase when it will be used to encrypt
+		return m.unlockSecrets(!isEncrypted(*m.detailSnippet), func(m *Model) tea.Cmd {
+			return m.toggleEncryption()
+		})
+	}
+
+	// Still encrypted with the session unlocked means another passphrase
+	if lockedSecret(m.detailSnippet) {
+		m.err = fmt.Errorf("snippet is encrypted with a different passphrase")
+		return nil
+	}
+
+	snippet := *m.detailSnippet
+	input := snippet.Input()
+	input.Secret = !snippet.Secret
+	for _, f := range snippet.Files {
+		input.Files = append(input.Files, api.FileInput{
+			Filename: f.Filename,
+			Content:  f.Content,
+			Language: f.Language,
+			Note:     f.Note,
+		})
+	}
+	message := "Snippet encrypted"
+	if !input.Secret {
+		message = "Snippet decrypted"
+	}
+	return m.saveDetail(input, message)
+}
+
+// saveDetail saves an update from the detail view, encrypting it first when
+// the snippet is secret
+func (m *Model) saveDetail(input api.SnippetInput, message string) tea.Cmd {
+	sealed, err := sealInput(m.secrets, input)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	return saveSnippet(m.client, m.cache, *m.detailSnippet, sealed, message)
+}
+
+// sealInput encrypts the content and files of a secret snippet's update
+func sealInput(secrets *secret.Session, input api.SnippetInput) (api.SnippetInput, error) {
+	if !input.Secret {
+		return input, nil
+	}
+
+	var err error
+	if input.Content, err = secrets.Encrypt(input.Content); err != nil {
+		return input, fmt.Errorf("failed to encrypt snippet: %w", err)
+	}
+	files := make([]api.FileInput, len(input.Files))
+	for i, f := range input.Files {
+		if f.Content, err = secrets.Encrypt(f.Content); err != nil {
+			return input, fmt.Errorf("failed to encrypt %s: %w", f.Filename, err)
+		}
+		files[i] = f
+	}
+	if input.Files != nil {
+		input.Files = files
+	}
+	return input, nil
+}
+
+// openSecret decrypts an encrypted snippet in place when the session is
+// unlocked; locked snippets are left encrypted
+func (m *Model) openSecret(snippet *api.Snippet) error {
+	if !isEncrypted(*snippet) || !m.secrets.Unlocked() {
+		return nil
+	}
+
+	content := snippet.Content
+	if secret.IsEncrypted(content) {
+		var err error
+		if content, err = m.secrets.Decrypt(content); err != nil {
+			return fmt.Errorf("failed to decrypt snippet: %w", err)
+		}
+	}
+	files := make([]api.File, len(snippet.Files))
+	for i, f := range snippet.Files {
+		if secret.IsEncrypted(f.Content) {
+			plain, err := m.secrets.Decrypt(f.Content)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", f.Filename, err)
+			}
+			f.Content = plain
+		}
+		files[i] = f
+	}
+
+	snippet.Content = content
+	if snippet.Files != nil {
+		snippet.Files = files
+	}
+	snippet.Secret = true
+	return nil
+}
+
+// isEncrypted reports whether a snippet holds encrypted content or files
+func isEncrypted(snippet api.Snippet) bool {
+	if secret.IsEncrypted(snippet.Content) {
+		return true
+	}
+	for _, f := range snippet.Files {
+		if secret.IsEncrypted(f.Content) {
+			return true
+		}
+	}
+	return false
+}
+
+// lockedSecret reports whether a snippet is encrypted and can't be shown
+// until the session is unlocked
+func lockedSecret(snippet *api.Snippet) bool {
+	return snippet != nil && !snippet.Secret && isEncrypted(*snippet)
+}
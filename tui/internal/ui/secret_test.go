@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MohamedElashri/snipo/tui/internal/api"
+	"github.com/MohamedElashri/snipo/tui/internal/cache"
+	"github.com/MohamedElashri/snipo/tui/internal/secret"
+)
+
+func TestSecretEditsStayEncryptedOffline(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	store, err := cache.Open()
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	secrets := secret.NewSession()
+	secrets.Unlock("correct horse")
+
+	const content, fileContent = "export TOKEN=plain-content", "password=plain-file"
+	input := api.SnippetInput{
+		Title:   "deploy keys",
+		Content: content,
+		Files:   []api.FileInput{{Filename: "env", Content: fileContent}},
+		Secret:  true,
+	}
+
+	// A locked session refuses to seal instead of passing plaintext on
+	if _, err := sealInput(secret.NewSession(), input); !errors.Is(err, secret.ErrLocked) {
+		t.Errorf("expected ErrLocked from a locked session, got %v", err)
+	}
+
+	sealed, err := sealInput(secrets, input)
+	if err != nil {
+		t.Fatalf("sealInput failed: %v", err)
+	}
+	server := api.Snippet{ID: "a", Title: "deploy keys", UpdatedAt: time.Now().Add(-time.Hour)}
+	if err := store.Put(server); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	updated, err := store.QueueUpdate(server, sealed)
+	if err != nil {
+		t.Fatalf("QueueUpdate failed: %v", err)
+	}
+	created, err := store.QueueCreate(sealed)
+	if err != nil {
+		t.Fatalf("QueueCreate failed: %v", err)
+	}
+
+	path, err := cache.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plaintext := range []string{content, fileContent} {
+		if strings.Contains(string(data), plaintext) {
+			t.Errorf("expected %q to be encrypted in the offline cache", plaintext)
+		}
+	}
+
+	// The queued copies decrypt back to what was typed
+	for _, snippet := range []*api.Snippet{updated, created} {
+		local, ok := store.Get(snippet.ID)
+		if !ok {
+			t.Fatalf("expected %s in the cache", snippet.ID)
+		}
+		if !isEncrypted(*local) {
+			t.Errorf("expected %s to stay encrypted in the cache", snippet.ID)
+		}
+		decrypted, err := secrets.Decrypt(local.Content)
+		if err != nil || decrypted != content {
+			t.Errorf("expected %q, got %q, %v", content, decrypted, err)
+		}
+		if len(local.Files) != 1 {
+			t.Fatalf("expected 1 file, got %d", len(local.Files))
+		}
+		decrypted, err = secrets.Decrypt(local.Files[0].Content)
+		if err != nil || decrypted != fileContent {
+			t.Errorf("expected %q, got %q, %v", fileContent, decrypted, err)
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package ui
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+
+	"github.com/MohamedElashri/snipo/tui/internal/api"
 )
 
 var (
@@ -47,7 +50,7 @@ var (
 
 	tagStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("0")). // Black text (ANSI 0)
-			Background(lipgloss.Color("4")). // Blue background (ANSI 4)
+			Background(lipgloss.Color("4")). // Blue background (ANSI 4); renderTag uses the tag's own color
 			Padding(0, 1).
 			MarginRight(1)
 
@@ -109,3 +112,50 @@ func renderHelpText(text string) string {
 
 	return strings.Join(renderedParts, shortcutDescStyle.Render(" • "))
 }
+
+// ansiTagColors are the ANSI colors tag backgrounds are mapped to, with the
+// xterm RGB values used to find the nearest one. Black, greys and white are
+// left out so the black tag text stays readable.
+var ansiTagColors = []struct {
+	code    string
+	r, g, b int
+}{
+	{"1", 205, 0, 0},     // Red
+	{"2", 0, 205, 0},     // Green
+	{"3", 205, 205, 0},   // Yellow
+	{"4", 0, 0, 238},     // Blue
+	{"5", 205, 0, 205},   // Magenta
+	{"6", 0, 205, 205},   // Cyan
+	{"7", 229, 229, 229}, // White/Light Grey
+	{"9", 255, 0, 0},     // Bright Red
+	{"10", 0, 255, 0},    // Bright Green
+	{"11", 255, 255, 0},  // Bright Yellow
+	{"12", 92, 92, 255},  // Bright Blue
+	{"13", 255, 0, 255},  // Bright Magenta
+	{"14", 0, 255, 255},  // Bright Cyan
+}
+
+// nearestANSIColor maps a server-assigned #rrggbb tag color to the closest
+// ANSI color so the TUI keeps to the terminal's palette; anything else falls
+// back to blue
+func nearestANSIColor(hex string) lipgloss.Color {
+	rgb, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	if len(hex) != 7 || hex[0] != '#' || err != nil {
+		return lipgloss.Color("4")
+	}
+	r, g, b := int(rgb>>16), int(rgb>>8&0xff), int(rgb&0xff)
+
+	best, bestDist := "4", -1
+	for _, c := range ansiTagColors {
+		dist := (r-c.r)*(r-c.r) + (g-c.g)*(g-c.g) + (b-c.b)*(b-c.b)
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = c.code, dist
+		}
+	}
+	return lipgloss.Color(best)
+}
+
+// renderTag renders a tag badge in its color
+func renderTag(tag api.Tag) string {
+	return tagStyle.Background(nearestANSIColor(tag.Color)).Render(tag.Name)
+}
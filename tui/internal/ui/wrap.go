@@ -2,9 +2,12 @@ package ui
 
 import (
 	"strings"
+
+	"github.com/mattn/go-runewidth"
 )
 
-// visualWidth calculates the visual width of a string (accounting for ANSI codes)
+// visualWidth calculates the visual width of a string (accounting for ANSI
+// codes and for wide and zero-width characters)
 func visualWidth(s string) int {
 	// Simple ANSI stripping - count actual visible characters
 	inEscape := false
@@ -21,12 +24,39 @@ func visualWidth(s string) int {
 			}
 			continue
 		}
-		width++
+		width += runewidth.RuneWidth(r)
 	}
 
 	return width
 }
 
+// expandTabs replaces tabs with spaces up to the next tab stop, measuring
+// columns by display width so tabs after wide characters still line up
+func expandTabs(content string, tabWidth int) string {
+	if tabWidth <= 0 || !strings.Contains(content, "\t") {
+		return content
+	}
+
+	var b strings.Builder
+	b.Grow(len(content))
+	column := 0
+	for _, r := range content {
+		switch r {
+		case '\t':
+			spaces := tabWidth - column%tabWidth
+			b.WriteString(strings.Repeat(" ", spaces))
+			column += spaces
+		case '\n':
+			b.WriteRune(r)
+			column = 0
+		default:
+			b.WriteRune(r)
+			column += runewidth.RuneWidth(r)
+		}
+	}
+	return b.String()
+}
+
 // wrapLine wraps a single line at the specified width, preserving ANSI codes
 func wrapLine(line string, maxWidth int) []string {
 	if maxWidth <= 0 {
@@ -63,8 +93,10 @@ func wrapLine(line string, maxWidth int) []string {
 			continue
 		}
 
-		// Regular character
-		if currentWidth >= maxWidth {
+		// Regular character; a wide character that would overflow moves to
+		// the next line whole rather than being split
+		w := runewidth.RuneWidth(r)
+		if w > 0 && currentWidth+w > maxWidth && currentWidth > 0 {
 			// Start new line, preserve formatting by adding escape sequences
 			result = append(result, currentLine.String())
 			currentLine.Reset()
@@ -72,7 +104,7 @@ func wrapLine(line string, maxWidth int) []string {
 		}
 
 		currentLine.WriteRune(r)
-		currentWidth++
+		currentWidth += w
 	}
 
 	if currentLine.Len() > 0 {